@@ -0,0 +1,291 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"sync"
+)
+
+// AckListenerConfig holds options for ListenWithAck
+type AckListenerConfig struct {
+	// Consumer is the name under which checkpoint is stored. Different consumers of the same channel must have
+	// different names. Required.
+	Consumer string
+	// Store persists checkpoints. Required.
+	Store CheckpointStore
+	// Window is maximum number of delivered but not acknowledged events. When window is full listener stops reading
+	// blocks until some of them are acknowledged. Default is 1.
+	Window int
+	// StartBlock is the block from which to start when there is no checkpoint for consumer
+	StartBlock uint64
+	// FullBlock selects full block events instead of filtered ones
+	FullBlock bool
+}
+
+// AckEvent is block event that must be acknowledged after it is processed
+type AckEvent struct {
+	EventBlockResponse
+	ack func() error
+}
+
+// Ack marks event as processed. Checkpoint advances only over continuous sequence of acknowledged blocks, so events
+// can be acknowledged in any order. Error is returned when checkpoint cannot be saved.
+func (e *AckEvent) Ack() error {
+	if e.ack == nil {
+		return nil
+	}
+	return e.ack()
+}
+
+// AckAll acknowledge batch of events
+func AckAll(events ...*AckEvent) error {
+	for _, e := range events {
+		if err := e.Ack(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListenWithAck listen for blocks in channel starting from the checkpoint of the consumer, and send them to events.
+// Unlike ListenForFullBlock checkpoint is not advanced automatically, every event must be acknowledged with Ack.
+// After restart delivery starts from the first not acknowledged block, so events are never lost, but events that
+// were processed and not acknowledged will be delivered again.
+// Event with Error is send when listening fails, after it no more events are send.
+// ErrCheckpointStoreRequired or ErrConsumerRequired is returned when config has no Store or Consumer.
+func (c *FabricClient) ListenWithAck(ctx context.Context, identity Identity, eventPeer, channelId string,
+	config AckListenerConfig, events chan<- *AckEvent) error {
+	if config.Store == nil {
+		return ErrCheckpointStoreRequired
+	}
+	if config.Consumer == "" {
+		return ErrConsumerRequired
+	}
+	ep, ok := c.getEventPeer(eventPeer)
+	if !ok {
+		return ErrPeerNameNotFound
+	}
+	next, ok, err := config.Store.Load(channelId, config.Consumer)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		next = config.StartBlock
+	}
+	listenerType := EventTypeFiltered
+	if config.FullBlock {
+		listenerType = EventTypeFullBlock
+	}
+	listener, err := NewEventListener(ctx, c.cryptoSuite(identity, channelId), identity, *ep, channelId, listenerType)
+	if err != nil {
+		return err
+	}
+	listener.FullBlock = config.FullBlock
+	listener.Decoders = c.EventDecoders
+	listener.Archive = c.BlockArchive
+	if err := listener.SeekRange(next, math.MaxUint64); err != nil {
+		return err
+	}
+	window := config.Window
+	if window < 1 {
+		window = 1
+	}
+	t := &ackTracker{
+		store:     config.Store,
+		channelId: channelId,
+		consumer:  config.Consumer,
+		next:      next,
+		acked:     make(map[uint64]bool),
+		slots:     make(chan struct{}, window),
+	}
+	blocks := make(chan EventBlockResponse, 1)
+	listener.Listen(blocks)
+	go func() {
+		for {
+			var block EventBlockResponse
+			select {
+			case <-ctx.Done():
+				return
+			case block = <-blocks:
+			}
+			event := &AckEvent{EventBlockResponse: block}
+			if block.Error == nil {
+				select {
+				case t.slots <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				number := block.BlockHeight
+				event.ack = func() error { return t.ack(number) }
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+			if block.Error != nil {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// ackTracker tracks acknowledged blocks and advance checkpoint
+type ackTracker struct {
+	store     CheckpointStore
+	channelId string
+	consumer  string
+	mu        sync.Mutex
+	next      uint64
+	acked     map[uint64]bool
+	slots     chan struct{}
+}
+
+func (t *ackTracker) ack(number uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if number < t.next || t.acked[number] {
+		return nil
+	}
+	t.acked[number] = true
+	<-t.slots
+	advanced := false
+	for t.acked[t.next] {
+		delete(t.acked, t.next)
+		t.next++
+		advanced = true
+	}
+	if !advanced {
+		return nil
+	}
+	return t.store.Save(t.channelId, t.consumer, t.next)
+}
+
+// ChaincodeEventAckConfig holds options for ListenChaincodeEventsWithAck. Checkpoint, window and start block are
+// the same as in AckListenerConfig, window counts blocks. Full blocks are always received, because filtered
+// blocks have no event payloads.
+type ChaincodeEventAckConfig struct {
+	AckListenerConfig
+	// ChainCode selects events of one chaincode, all chaincodes when empty
+	ChainCode string
+	// EventName is regular expression event names must match, all events when nil
+	EventName *regexp.Regexp
+}
+
+// AckChaincodeEvent is chaincode event of valid transaction that must be acknowledged after it is processed
+type AckChaincodeEvent struct {
+	// Error is set when listening fails, after it no more events are send
+	Error       error
+	ChannelId   string
+	BlockHeight uint64
+	TxId        string
+	ChainCodeId string
+	// Index is position of event among selected events of block. BlockHeight and Index identify event, consumers
+	// that must process event exactly once can use them to skip redelivered events.
+	Index int
+	Event EventBlockResponseTransactionEvent
+	ack   func() error
+}
+
+// Ack marks event as processed. Block is acknowledged when all its selected events are acknowledged. Error is
+// returned when checkpoint cannot be saved.
+func (e *AckChaincodeEvent) Ack() error {
+	if e.ack == nil {
+		return nil
+	}
+	return e.ack()
+}
+
+// ListenChaincodeEventsWithAck is ListenWithAck for single chaincode events. Events of valid transactions that
+// match config are send to events one by one. After restart delivery starts from the first block with not
+// acknowledged event, so events are delivered at least once. Blocks without selected events are acknowledged
+// automatically.
+func (c *FabricClient) ListenChaincodeEventsWithAck(ctx context.Context, identity Identity, eventPeer, channelId string,
+	config ChaincodeEventAckConfig, events chan<- *AckChaincodeEvent) error {
+	listenerConfig := config.AckListenerConfig
+	listenerConfig.FullBlock = true
+	blocks := make(chan *AckEvent)
+	if err := c.ListenWithAck(ctx, identity, eventPeer, channelId, listenerConfig, blocks); err != nil {
+		return err
+	}
+	go func() {
+		for {
+			var block *AckEvent
+			select {
+			case <-ctx.Done():
+				return
+			case block = <-blocks:
+			}
+			if block.Error != nil {
+				select {
+				case events <- &AckChaincodeEvent{Error: block.Error, ChannelId: channelId}:
+				case <-ctx.Done():
+				}
+				return
+			}
+			selected := config.selectEvents(block)
+			if len(selected) == 0 {
+				if err := block.Ack(); err != nil {
+					select {
+					case events <- &AckChaincodeEvent{Error: err, ChannelId: channelId, BlockHeight: block.BlockHeight}:
+					case <-ctx.Done():
+					}
+					return
+				}
+				continue
+			}
+			var mu sync.Mutex
+			pending := make(map[int]bool, len(selected))
+			for _, e := range selected {
+				pending[e.Index] = true
+			}
+			for _, e := range selected {
+				index := e.Index
+				e.ack = func() error {
+					mu.Lock()
+					if !pending[index] {
+						mu.Unlock()
+						return nil
+					}
+					delete(pending, index)
+					done := len(pending) == 0
+					mu.Unlock()
+					if !done {
+						return nil
+					}
+					return block.Ack()
+				}
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// selectEvents returns events of valid transactions in block that match config
+func (config ChaincodeEventAckConfig) selectEvents(block *AckEvent) []*AckChaincodeEvent {
+	var selected []*AckChaincodeEvent
+	for _, tx := range block.Transactions {
+		if tx.Status != "VALID" || (config.ChainCode != "" && tx.ChainCodeId != config.ChainCode) {
+			continue
+		}
+		for _, e := range tx.Events {
+			if e.Name == "" || (config.EventName != nil && !config.EventName.MatchString(e.Name)) {
+				continue
+			}
+			selected = append(selected, &AckChaincodeEvent{ChannelId: block.ChannelId, BlockHeight: block.BlockHeight,
+				TxId: tx.Id, ChainCodeId: tx.ChainCodeId, Index: len(selected), Event: e})
+		}
+	}
+	return selected
+}
@@ -0,0 +1,308 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kinds of anomalies reported by AnomalyDetector
+const (
+	// AnomalyDivergentPayload means peer often returns response different from other peers
+	AnomalyDivergentPayload = "divergent_payload"
+	// AnomalyDisconnects means peer often cannot be reached
+	AnomalyDisconnects = "disconnects"
+	// AnomalyFlapping means peer often alternates between successful and failed responses
+	AnomalyFlapping = "flapping"
+	// AnomalyLagging means peer block height is behind other peers
+	AnomalyLagging = "lagging"
+	// AnomalyDivergentHeight means peer has different block hash than other peers at the same height
+	AnomalyDivergentHeight = "divergent_height"
+)
+
+// AnomalyConfig holds thresholds for AnomalyDetector. Zero values are replaced with defaults.
+type AnomalyConfig struct {
+	// Window is number of latest observations kept per peer, default 100
+	Window int
+	// MinObservations is number of observations needed before ratios are checked, default 10
+	MinObservations int
+	// DivergentRatio is ratio of responses different from majority that is reported, default 0.1
+	DivergentRatio float64
+	// DisconnectRatio is ratio of connection failures that is reported, default 0.2
+	DisconnectRatio float64
+	// FlapRatio is ratio of changes between success and failure that is reported, default 0.3
+	FlapRatio float64
+	// MaxLag is number of blocks peer can be behind before it is reported, default 5
+	MaxLag uint64
+}
+
+// AnomalyFinding is single anomaly found for peer
+type AnomalyFinding struct {
+	Peer   string
+	Kind   string
+	Detail string
+	Time   time.Time
+}
+
+// PeerAnomalyStats are counters collected for peer in current window
+type PeerAnomalyStats struct {
+	Observations int
+	Failures     int
+	Divergent    int
+	Disconnects  int
+	Flaps        int
+	Height       uint64
+}
+
+type peerObservation struct {
+	failed     bool
+	divergent  bool
+	disconnect bool
+}
+
+// AnomalyDetector collects per peer results of endorsements and chain info checks and finds peers that behave
+// differently from the rest. Set it in FabricClient.AnomalyDetector to collect results of QueryWithContext,
+// InvokeWithContext and MonitorChainInfo automatically, or feed it with Observe* methods.
+type AnomalyDetector struct {
+	config       AnomalyConfig
+	mu           sync.Mutex
+	observations map[string][]peerObservation
+	chainInfo    *ChainInfoReport
+}
+
+// NewAnomalyDetector creates detector with config
+func NewAnomalyDetector(config AnomalyConfig) *AnomalyDetector {
+	if config.Window <= 0 {
+		config.Window = 100
+	}
+	if config.MinObservations <= 0 {
+		config.MinObservations = 10
+	}
+	if config.DivergentRatio <= 0 {
+		config.DivergentRatio = 0.1
+	}
+	if config.DisconnectRatio <= 0 {
+		config.DisconnectRatio = 0.2
+	}
+	if config.FlapRatio <= 0 {
+		config.FlapRatio = 0.3
+	}
+	if config.MaxLag == 0 {
+		config.MaxLag = 5
+	}
+	return &AnomalyDetector{config: config, observations: make(map[string][]peerObservation)}
+}
+
+// ObserveEndorsements records responses from peers for the same proposal. Peers whose successful response differs
+// from the response of most peers are counted as divergent.
+func (d *AnomalyDetector) ObserveEndorsements(responses []*PeerResponse) {
+	var payloads [][]byte
+	var counts []int
+	for _, r := range responses {
+		if r.Err != nil || r.Response == nil {
+			continue
+		}
+		found := false
+		for i, p := range payloads {
+			if bytes.Equal(p, r.Response.Payload) {
+				counts[i]++
+				found = true
+				break
+			}
+		}
+		if !found {
+			payloads = append(payloads, r.Response.Payload)
+			counts = append(counts, 1)
+		}
+	}
+	var majority []byte
+	best := 0
+	for i, c := range counts {
+		if c > best {
+			best, majority = c, payloads[i]
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, r := range responses {
+		o := peerObservation{failed: r.Err != nil || r.Response == nil}
+		if o.failed {
+			o.disconnect = IsConnectionError(r.Err) || IsTimeout(r.Err)
+		} else {
+			o.divergent = len(payloads) > 1 && !bytes.Equal(r.Response.Payload, majority)
+		}
+		d.add(r.Name, o)
+	}
+}
+
+// ObserveChainInfo records heights and block hashes from chain info report
+func (d *AnomalyDetector) ObserveChainInfo(report ChainInfoReport) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.chainInfo = &report
+	for _, p := range report.Peers {
+		o := peerObservation{failed: p.Error != nil}
+		if o.failed {
+			o.disconnect = IsConnectionError(p.Error) || IsTimeout(p.Error)
+		}
+		d.add(p.PeerName, o)
+	}
+}
+
+// add appends observation to peer window. Must be called with lock held.
+func (d *AnomalyDetector) add(peer string, o peerObservation) {
+	obs := append(d.observations[peer], o)
+	if len(obs) > d.config.Window {
+		obs = obs[len(obs)-d.config.Window:]
+	}
+	d.observations[peer] = obs
+}
+
+// Stats returns counters for all observed peers
+func (d *AnomalyDetector) Stats() map[string]PeerAnomalyStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stats := make(map[string]PeerAnomalyStats, len(d.observations))
+	for peer, obs := range d.observations {
+		s := PeerAnomalyStats{Observations: len(obs)}
+		for i, o := range obs {
+			if o.failed {
+				s.Failures++
+			}
+			if o.divergent {
+				s.Divergent++
+			}
+			if o.disconnect {
+				s.Disconnects++
+			}
+			if i > 0 && o.failed != obs[i-1].failed {
+				s.Flaps++
+			}
+		}
+		stats[peer] = s
+	}
+	if d.chainInfo != nil {
+		for _, p := range d.chainInfo.Peers {
+			if p.Error == nil {
+				s := stats[p.PeerName]
+				s.Height = p.Info.GetHeight()
+				stats[p.PeerName] = s
+			}
+		}
+	}
+	return stats
+}
+
+// anomalyKinds are all kinds reported by AnomalyDetector
+var anomalyKinds = []string{AnomalyDivergentPayload, AnomalyDisconnects, AnomalyFlapping, AnomalyLagging,
+	AnomalyDivergentHeight}
+
+// Findings analyze collected observations and returns anomalies sorted by peer. State of every kind of anomaly for
+// every observed peer is reported to Metrics.
+func (d *AnomalyDetector) Findings() []AnomalyFinding {
+	now := time.Now()
+	var findings []AnomalyFinding
+	add := func(peer, kind, format string, args ...interface{}) {
+		findings = append(findings, AnomalyFinding{Peer: peer, Kind: kind, Detail: fmt.Sprintf(format, args...), Time: now})
+	}
+	stats := d.Stats()
+	for peer, s := range stats {
+		if s.Observations < d.config.MinObservations {
+			continue
+		}
+		n := float64(s.Observations)
+		if r := float64(s.Divergent) / n; r >= d.config.DivergentRatio {
+			add(peer, AnomalyDivergentPayload, "%d of %d responses differ from majority", s.Divergent, s.Observations)
+		}
+		if r := float64(s.Disconnects) / n; r >= d.config.DisconnectRatio {
+			add(peer, AnomalyDisconnects, "%d of %d requests failed to connect", s.Disconnects, s.Observations)
+		}
+		if r := float64(s.Flaps) / n; r >= d.config.FlapRatio {
+			add(peer, AnomalyFlapping, "status changed %d times in %d requests", s.Flaps, s.Observations)
+		}
+	}
+	d.mu.Lock()
+	report := d.chainInfo
+	d.mu.Unlock()
+	if report != nil {
+		hashes := make(map[uint64][]*QueryChannelInfoResponse)
+		for _, p := range report.Peers {
+			if p.Error != nil {
+				continue
+			}
+			if report.MaxHeight-p.Info.GetHeight() > d.config.MaxLag {
+				add(p.PeerName, AnomalyLagging, "height %d is behind %d", p.Info.GetHeight(), report.MaxHeight)
+			}
+			hashes[p.Info.GetHeight()] = append(hashes[p.Info.GetHeight()], p)
+		}
+		for height, peers := range hashes {
+			for _, p := range peers[1:] {
+				if !bytes.Equal(p.Info.CurrentBlockHash, peers[0].Info.CurrentBlockHash) {
+					add(p.PeerName, AnomalyDivergentHeight, "block hash at height %d differs from peer %s", height, peers[0].PeerName)
+				}
+			}
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Peer != findings[j].Peer {
+			return findings[i].Peer < findings[j].Peer
+		}
+		return findings[i].Kind < findings[j].Kind
+	})
+	reportAnomalies(stats, findings)
+	return findings
+}
+
+// reportAnomalies sets state of all kinds of anomalies of observed peers in metrics
+func reportAnomalies(stats map[string]PeerAnomalyStats, findings []AnomalyFinding) {
+	active := make(map[string]bool, len(findings))
+	for _, f := range findings {
+		active[f.Peer+"/"+f.Kind] = true
+	}
+	m := metrics()
+	for peer := range stats {
+		for _, kind := range anomalyKinds {
+			m.PeerAnomaly(peer, kind, active[peer+"/"+kind])
+		}
+	}
+}
+
+// Run analyze observations on every interval and send findings to channel when there are any.
+// It blocks until ctx is canceled.
+func (d *AnomalyDetector) Run(ctx context.Context, interval time.Duration, findings chan<- []AnomalyFinding) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if f := d.Findings(); len(f) > 0 {
+				select {
+				case findings <- f:
+				case <-ctx.Done():
+					return
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *FabricClient) observeEndorsements(responses []*PeerResponse) {
+	if c.AnomalyDetector != nil {
+		c.AnomalyDetector.ObserveEndorsements(responses)
+	}
+}
+
+func (c *FabricClient) observeChainInfo(report ChainInfoReport) {
+	if c.AnomalyDetector != nil {
+		c.AnomalyDetector.ObserveChainInfo(report)
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/CognitionFoundry/gohfc/blockparser"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+// ArchiveConfig configures where blocks are searched when peers do not have them anymore (for example because
+// their ledger was bootstrapped from snapshot or blocks were pruned)
+type ArchiveConfig struct {
+	// Peers are names of archive peers from peers section that keep full ledger
+	Peers []string `yaml:"peers"`
+	// BlockStoreUrl is base url of HTTP block store, see HTTPBlockStore
+	BlockStoreUrl string `yaml:"blockStoreUrl"`
+}
+
+// BlockStore is external storage of blocks used as the last fallback for block queries by number
+type BlockStore interface {
+	GetBlock(channelId string, number uint64) (*common.Block, error)
+}
+
+// HTTPBlockStore gets protobuf encoded blocks with GET {Url}/{channelId}/{number}
+type HTTPBlockStore struct {
+	Url string
+	// Client is used for requests, http.DefaultClient if nil
+	Client *http.Client
+}
+
+// GetBlock downloads block from store
+func (s *HTTPBlockStore) GetBlock(channelId string, number uint64) (*common.Block, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u := strings.TrimRight(s.Url, "/") + "/" + url.PathEscape(channelId) + "/" + strconv.FormatUint(number, 10)
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("block store returned status %d for block %d in channel %s", resp.StatusCode, number, channelId)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	block := new(common.Block)
+	if err := proto.Unmarshal(data, block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// blockStorePeerName is used as PeerName in QueryBlockResponse when block comes from BlockStore
+const blockStorePeerName = "blockstore"
+
+// blockNotFoundMessages are errors of peer block index QSCC returns with status 500 when block is not in ledger
+var blockNotFoundMessages = []string{
+	"no such block number",
+	"no such block hash",
+	"entry not found in index",
+	"ledger is bootstrapped from a snapshot",
+}
+
+// isBlockNotFound returns true when qscc or deliver error means that peer does not have the block
+func isBlockNotFound(err error) bool {
+	var deliver *DeliverError
+	if errors.As(err, &deliver) {
+		return deliver.Status == common.Status_NOT_FOUND
+	}
+	var endorsement *EndorsementError
+	if !errors.As(err, &endorsement) || endorsement.Err != nil {
+		return false
+	}
+	switch endorsement.Status {
+	case int32(common.Status_NOT_FOUND):
+		return true
+	case int32(common.Status_INTERNAL_SERVER_ERROR):
+		msg := strings.ToLower(endorsement.Message)
+		for _, m := range blockNotFoundMessages {
+			if strings.Contains(msg, m) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fallbackBlocks replace responses where block is not found with block from archive peers or block store.
+// Responses that cannot be replaced keep the original error.
+func (c *FabricClient) fallbackBlocks(identity Identity, channelId string, args []string, argBytes []byte, response []*QueryBlockResponse) {
+	var missing []*QueryBlockResponse
+	for _, r := range response {
+		if isBlockNotFound(r.Error) {
+			missing = append(missing, r)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	c.mu.RLock()
+	archivePeers, store := c.archivePeers, c.BlockStore
+	c.mu.RUnlock()
+	found := c.blockFromArchive(identity, channelId, args, argBytes, archivePeers)
+	if found == nil && store != nil && args[0] == "GetBlockByNumber" {
+		number, err := strconv.ParseUint(args[2], 10, 64)
+		if err == nil {
+			found = &QueryBlockResponse{PeerName: blockStorePeerName}
+			found.RawBlock, found.Error = store.GetBlock(channelId, number)
+			if found.Error == nil {
+				found.Error = verifyStoredBlock(channelId, number, found.RawBlock)
+			}
+			if found.Error == nil {
+				found.Block, found.Error = blockparser.ParseBlock(found.RawBlock)
+			}
+			if found.Error != nil {
+				found = nil
+			}
+		}
+	}
+	if found == nil {
+		return
+	}
+	for _, r := range missing {
+		*r = *found
+	}
+}
+
+// verifyStoredBlock checks that block from BlockStore has requested number and its data matches data hash
+func verifyStoredBlock(channelId string, number uint64, block *common.Block) error {
+	if err := verifyBlockData(channelId, block); err != nil {
+		return err
+	}
+	if block.Header.Number != number {
+		return &BlockVerificationError{ChannelId: channelId, Number: number,
+			Reason: fmt.Sprintf("block store returned block %d", block.Header.Number)}
+	}
+	return nil
+}
+
+// blockFromArchive returns the first successful response from archive peers, nil if there is none
+func (c *FabricClient) blockFromArchive(identity Identity, channelId string, args []string, argBytes []byte, peers []string) *QueryBlockResponse {
+	if len(peers) == 0 {
+		return nil
+	}
+	r, err := c.queryQscc(identity, channelId, args, argBytes, peers)
+	if err != nil {
+		return nil
+	}
+	for _, qbr := range decodeBlockResponses(r) {
+		if qbr.Error == nil {
+			return qbr
+		}
+	}
+	return nil
+}
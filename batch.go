@@ -0,0 +1,76 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+// BatchResult is the result of single transaction from InvokeBatch
+type BatchResult struct {
+	// Index is the position of the chaincode invocation in the batch
+	Index  int
+	TxID   string
+	Status common.Status
+	Error  error
+}
+
+// InvokeBatch endorse and send to orderer many independent transactions using pool of workers.
+// Results are returned in the same order as chainCodes, failure of one transaction does not stop the others.
+// If workers is less than 1, single worker is used. When ctx is done transactions that are not started yet fail
+// with ctx error.
+// Same as Invoke success means that transaction is accepted from orderer, not that it is committed.
+// Transactions in the batch must not depend on each other, because they can be ordered in any order and
+// transactions touching the same keys will fail with MVCC conflict.
+// All transactions are sent to orderer over single broadcast stream, unless ctx already has one, see
+// WithBroadcastStream.
+func (c *FabricClient) InvokeBatch(ctx context.Context, identity Identity, chainCodes []ChainCode, peers []string,
+	orderer string, workers int) []*BatchResult {
+	if workers < 1 {
+		workers = 1
+	}
+	if _, ok := ctx.Value(broadcastStreamKey{}).(*BroadcastStream); !ok && len(chainCodes) > 1 {
+		_, ordererName := c.applyDefaults(peers, orderer, true)
+		// when stream cannot be opened every transaction opens own stream and reports the error
+		if stream, err := c.NewBroadcastStream(ctx, ordererName); err == nil {
+			defer stream.Close(context.Background())
+			ctx = WithBroadcastStream(ctx, stream)
+		}
+	}
+	results := make([]*BatchResult, len(chainCodes))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result := &BatchResult{Index: i}
+				if err := ctx.Err(); err != nil {
+					result.Error = err
+					results[i] = result
+					continue
+				}
+				resp, err := c.InvokeWithContext(ctx, identity, chainCodes[i], peers, orderer)
+				if err != nil {
+					result.Error = err
+				} else {
+					result.TxID = resp.TxID
+					result.Status = resp.Status
+				}
+				results[i] = result
+			}
+		}()
+	}
+	for i := range chainCodes {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,383 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+// BlockArchive stores blocks received by event listeners. Set FabricClient.BlockArchive (or EventListener.Archive)
+// and every full block is written to archive before it is delivered, so archive is built incrementally without
+// fetching blocks again. Implementations must verify block integrity before storing it.
+type BlockArchive interface {
+	PutBlock(channelId string, block *common.Block) error
+}
+
+// BlockVerificationError is returned when block data hash or previous block hash does not match
+type BlockVerificationError struct {
+	ChannelId string
+	Number    uint64
+	Reason    string
+}
+
+func (e *BlockVerificationError) Error() string {
+	return fmt.Sprintf("block %d in channel %s cannot be archived: %s", e.Number, e.ChannelId, e.Reason)
+}
+
+// blockHeaderBytes encodes block header the same way as Fabric does for hashing and signing
+func blockHeaderBytes(header *common.BlockHeader) ([]byte, error) {
+	return asn1.Marshal(struct {
+		Number       *big.Int
+		PreviousHash []byte
+		DataHash     []byte
+	}{new(big.Int).SetUint64(header.Number), header.PreviousHash, header.DataHash})
+}
+
+// blockHeaderHash computes hash of block header, it is PreviousHash of the next block
+func blockHeaderHash(header *common.BlockHeader) ([]byte, error) {
+	raw, err := blockHeaderBytes(header)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(raw)
+	return hash[:], nil
+}
+
+// verifyBlockData checks that block data hash in header matches block data
+func verifyBlockData(channelId string, block *common.Block) error {
+	if block == nil || block.Header == nil || block.Data == nil {
+		return &BlockVerificationError{ChannelId: channelId, Reason: "block is empty"}
+	}
+	hash := sha256.Sum256(bytes.Join(block.Data.Data, nil))
+	if !bytes.Equal(hash[:], block.Header.DataHash) {
+		return &BlockVerificationError{ChannelId: channelId, Number: block.Header.Number, Reason: "data hash does not match"}
+	}
+	return nil
+}
+
+// archiveChain tracks the last archived block of a channel and verifies that next block links to it
+type archiveChain struct {
+	number uint64
+	hash   []byte
+}
+
+// verify checks block against last archived block. Returns false when block is already archived.
+func (c *archiveChain) verify(channelId string, block *common.Block) (bool, error) {
+	if c.hash == nil {
+		return true, nil
+	}
+	if block.Header.Number <= c.number {
+		return false, nil
+	}
+	if block.Header.Number == c.number+1 && !bytes.Equal(block.Header.PreviousHash, c.hash) {
+		return false, &BlockVerificationError{ChannelId: channelId, Number: block.Header.Number,
+			Reason: fmt.Sprintf("previous hash does not match hash of block %d", c.number)}
+	}
+	return true, nil
+}
+
+func (c *archiveChain) advance(block *common.Block) error {
+	hash, err := blockHeaderHash(block.Header)
+	if err != nil {
+		return err
+	}
+	c.number, c.hash = block.Header.Number, hash
+	return nil
+}
+
+// defaultSegmentSize is the default size of FileBlockArchive segment
+const defaultSegmentSize = 64 << 20
+
+// FileBlockArchive appends blocks to segment files in Dir/<channelId>/. Segment file is named by the number of its
+// first block, new segment is started when current one is larger than MaxSegmentSize or when blocks are not
+// consecutive. Blocks that are already archived are skipped. FileBlockArchive also implements BlockStore.
+type FileBlockArchive struct {
+	Dir string
+	// MaxSegmentSize is segment size in bytes after which new segment is started, 64 MiB if 0
+	MaxSegmentSize int64
+	// Sync flushes every block to disk before PutBlock returns
+	Sync bool
+
+	mu       sync.Mutex
+	channels map[string]*fileArchiveChannel
+}
+
+type fileArchiveChannel struct {
+	archiveChain
+	file *os.File
+	size int64
+}
+
+const segmentSuffix = ".blocks"
+
+// PutBlock verifies block and appends it to the current segment
+func (a *FileBlockArchive) PutBlock(channelId string, block *common.Block) error {
+	if err := verifyBlockData(channelId, block); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ch, err := a.channel(channelId)
+	if err != nil {
+		return err
+	}
+	store, err := ch.verify(channelId, block)
+	if err != nil || !store {
+		return err
+	}
+	raw, err := proto.Marshal(block)
+	if err != nil {
+		return err
+	}
+	maxSize := a.MaxSegmentSize
+	if maxSize <= 0 {
+		maxSize = defaultSegmentSize
+	}
+	if ch.file == nil || ch.size >= maxSize || block.Header.Number != ch.number+1 {
+		if err := a.startSegment(channelId, ch, block.Header.Number); err != nil {
+			return err
+		}
+	}
+	record := make([]byte, 4+len(raw))
+	binary.BigEndian.PutUint32(record, uint32(len(raw)))
+	copy(record[4:], raw)
+	if _, err := ch.file.Write(record); err != nil {
+		return err
+	}
+	if a.Sync {
+		if err := ch.file.Sync(); err != nil {
+			return err
+		}
+	}
+	ch.size += int64(len(record))
+	return ch.advance(block)
+}
+
+// GetBlock reads block from archive
+func (a *FileBlockArchive) GetBlock(channelId string, number uint64) (*common.Block, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	segments, err := a.segments(channelId)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(segments) - 1; i >= 0; i-- {
+		if segments[i] > number {
+			continue
+		}
+		var found *common.Block
+		err := readSegment(a.segmentPath(channelId, segments[i]), func(block *common.Block) bool {
+			if block.Header.Number == number {
+				found = block
+				return false
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		if found != nil {
+			return found, nil
+		}
+	}
+	return nil, fmt.Errorf("block %d in channel %s not found in archive", number, channelId)
+}
+
+// Close closes open segment files
+func (a *FileBlockArchive) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var firstErr error
+	for _, ch := range a.channels {
+		if ch.file != nil {
+			if err := ch.file.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			ch.file = nil
+		}
+	}
+	return firstErr
+}
+
+// channel returns state of channel, reading the last archived block from the last segment on first use
+func (a *FileBlockArchive) channel(channelId string) (*fileArchiveChannel, error) {
+	if ch, ok := a.channels[channelId]; ok {
+		return ch, nil
+	}
+	if err := os.MkdirAll(filepath.Join(a.Dir, channelId), 0755); err != nil {
+		return nil, err
+	}
+	ch := new(fileArchiveChannel)
+	segments, err := a.segments(channelId)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) > 0 {
+		path := a.segmentPath(channelId, segments[len(segments)-1])
+		var last *common.Block
+		if err := readSegment(path, func(block *common.Block) bool { last = block; return true }); err != nil {
+			return nil, err
+		}
+		if last != nil {
+			if err := ch.advance(last); err != nil {
+				return nil, err
+			}
+		}
+		if ch.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+			return nil, err
+		}
+		info, err := ch.file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		ch.size = info.Size()
+	}
+	if a.channels == nil {
+		a.channels = make(map[string]*fileArchiveChannel)
+	}
+	a.channels[channelId] = ch
+	return ch, nil
+}
+
+func (a *FileBlockArchive) startSegment(channelId string, ch *fileArchiveChannel, first uint64) error {
+	if ch.file != nil {
+		if err := ch.file.Close(); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(a.segmentPath(channelId, first), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	ch.file, ch.size = f, 0
+	return nil
+}
+
+func (a *FileBlockArchive) segmentPath(channelId string, first uint64) string {
+	return filepath.Join(a.Dir, channelId, fmt.Sprintf("%020d%s", first, segmentSuffix))
+}
+
+// segments returns numbers of first blocks of all segments of channel in ascending order
+func (a *FileBlockArchive) segments(channelId string) ([]uint64, error) {
+	files, err := ioutil.ReadDir(filepath.Join(a.Dir, channelId))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var result []uint64
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), segmentSuffix) {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSuffix(f.Name(), segmentSuffix), 10, 64)
+		if err == nil {
+			result = append(result, n)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result, nil
+}
+
+// readSegment calls fn for every block in segment until fn returns false. Incomplete record at the end of segment
+// (left by crash during write) is ignored.
+func readSegment(path string, fn func(*common.Block) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var size [4]byte
+	for {
+		if _, err := io.ReadFull(f, size[:]); err != nil {
+			return nil
+		}
+		raw := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(f, raw); err != nil {
+			return nil
+		}
+		block := new(common.Block)
+		if err := proto.Unmarshal(raw, block); err != nil {
+			return err
+		}
+		if !fn(block) {
+			return nil
+		}
+	}
+}
+
+// S3BlockArchive stores every block as separate object <Prefix><channelId>/<number>.block in S3 compatible object
+// store. S3BlockArchive also implements BlockStore.
+type S3BlockArchive struct {
+	Client *S3Client
+	Prefix string
+
+	mu     sync.Mutex
+	chains map[string]*archiveChain
+}
+
+// PutBlock verifies block and uploads it
+func (a *S3BlockArchive) PutBlock(channelId string, block *common.Block) error {
+	if err := verifyBlockData(channelId, block); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.chains == nil {
+		a.chains = make(map[string]*archiveChain)
+	}
+	chain, ok := a.chains[channelId]
+	if !ok {
+		chain = new(archiveChain)
+		a.chains[channelId] = chain
+	}
+	store, err := chain.verify(channelId, block)
+	if err != nil || !store {
+		return err
+	}
+	raw, err := proto.Marshal(block)
+	if err != nil {
+		return err
+	}
+	if _, err := a.Client.PutObject(context.Background(), a.key(channelId, block.Header.Number), raw, "", ""); err != nil {
+		return err
+	}
+	return chain.advance(block)
+}
+
+// GetBlock downloads block from object store
+func (a *S3BlockArchive) GetBlock(channelId string, number uint64) (*common.Block, error) {
+	raw, _, err := a.Client.GetObject(context.Background(), a.key(channelId, number))
+	if err != nil {
+		return nil, err
+	}
+	block := new(common.Block)
+	if err := proto.Unmarshal(raw, block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (a *S3BlockArchive) key(channelId string, number uint64) string {
+	return fmt.Sprintf("%s%s/%020d.block", a.Prefix, channelId, number)
+}
@@ -0,0 +1,192 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+// BlockIteratorTail as BlockIteratorConfig.To iterates over existing blocks and then waits for new blocks
+const BlockIteratorTail = math.MaxUint64
+
+// BlockIteratorConfig configures BlockIterator
+type BlockIteratorConfig struct {
+	// From is the first block
+	From uint64
+	// To is the last block, inclusive. BlockIteratorTail never ends.
+	To uint64
+	// PageSize is number of blocks requested from peer with one deliver request, default is 100. Blocks are
+	// received only when Next is called, so at most one page is buffered by peer and gRPC.
+	PageSize uint64
+	// UseQSCC fetches every block with QSCC query instead of deliver service. Peer must be in FabricClient.Peers
+	// instead of EventPeers. Blocks that are not available on peer are taken from archive peers and block store.
+	UseQSCC bool
+	// PollInterval is how long QSCC iterator waits before asking peer for new blocks at the end of the chain,
+	// default is 1 second
+	PollInterval time.Duration
+}
+
+// BlockIterator fetches blocks of channel one by one when Next is called. It is used to replay whole chain, for
+// example by analytics jobs. BlockIterator is not safe for concurrent use.
+type BlockIterator struct {
+	client    *FabricClient
+	ctx       context.Context
+	identity  Identity
+	peer      string
+	channelId string
+	config    BlockIteratorConfig
+	next      uint64
+	done      bool
+
+	// deliver
+	listener *EventListener
+	pageEnd  uint64
+	inPage   bool
+	// qscc
+	height uint64
+}
+
+// NewBlockIterator creates iterator over blocks of channel in peer. Iterator must be closed with Close.
+func (c *FabricClient) NewBlockIterator(ctx context.Context, identity Identity, peerName, channelId string,
+	config BlockIteratorConfig) (*BlockIterator, error) {
+	if config.From > config.To {
+		return nil, fmt.Errorf("from: %d cannot be bigger than to: %d", config.From, config.To)
+	}
+	if config.PageSize == 0 {
+		config.PageSize = 100
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	it := &BlockIterator{client: c, ctx: ctx, identity: identity, peer: peerName, channelId: channelId, config: config,
+		next: config.From}
+	if config.UseQSCC {
+		if len(c.getPeers([]string{peerName})) != 1 {
+			return nil, ErrPeerNameNotFound
+		}
+		return it, nil
+	}
+	ep, ok := c.getEventPeer(peerName)
+	if !ok {
+		return nil, ErrPeerNameNotFound
+	}
+	listener, err := NewEventListener(ctx, c.cryptoSuite(identity, channelId), identity, *ep, channelId, EventTypeFullBlock)
+	if err != nil {
+		return nil, err
+	}
+	it.listener = listener
+	return it, nil
+}
+
+// Next returns the next block. ErrNoMoreBlocks is returned after the last block.
+func (it *BlockIterator) Next() (*common.Block, error) {
+	if it.done {
+		return nil, ErrNoMoreBlocks
+	}
+	var block *common.Block
+	var err error
+	if it.config.UseQSCC {
+		block, err = it.nextQSCC()
+	} else {
+		block, err = it.nextDeliver()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if block.Header.Number != it.next {
+		return nil, fmt.Errorf("expected block %d, peer %s returned block %d", it.next, it.peer, block.Header.Number)
+	}
+	if it.next == it.config.To {
+		it.done = true
+	} else {
+		it.next++
+	}
+	return block, nil
+}
+
+// Close closes connection to peer
+func (it *BlockIterator) Close() error {
+	it.done = true
+	if it.listener != nil && it.listener.connection != nil {
+		return it.listener.connection.Close()
+	}
+	return nil
+}
+
+func (it *BlockIterator) nextDeliver() (*common.Block, error) {
+	if !it.inPage {
+		it.pageEnd = it.config.To
+		if it.config.To-it.next >= it.config.PageSize {
+			it.pageEnd = it.next + it.config.PageSize - 1
+		}
+		if err := it.seek(it.next, it.pageEnd); err != nil {
+			return nil, err
+		}
+		it.inPage = true
+	}
+	for {
+		msg, err := it.listener.client.Recv()
+		if err != nil {
+			return nil, fmt.Errorf("error receiving data:%v", err)
+		}
+		switch t := msg.Type.(type) {
+		case *peer.DeliverResponse_Block:
+			if t.Block.Header.Number == it.pageEnd {
+				it.inPage = false
+			}
+			return t.Block, nil
+		case *peer.DeliverResponse_Status:
+			// status is send after the last block of page
+			if t.Status != common.Status_SUCCESS {
+				return nil, &DeliverError{Node: it.listener.Peer.Name, ChannelId: it.listener.ChannelId, Status: t.Status}
+			}
+		}
+	}
+}
+
+func (it *BlockIterator) seek(start, end uint64) error {
+	startPos := &orderer.SeekPosition{Type: &orderer.SeekPosition_Specified{Specified: &orderer.SeekSpecified{Number: start}}}
+	endPos := &orderer.SeekPosition{Type: &orderer.SeekPosition_Specified{Specified: &orderer.SeekSpecified{Number: end}}}
+	seek, err := it.listener.createSeekEnvelope(startPos, endPos)
+	if err != nil {
+		return err
+	}
+	return it.listener.client.Send(seek)
+}
+
+func (it *BlockIterator) nextQSCC() (*common.Block, error) {
+	for it.next >= it.height {
+		info, err := it.client.GetChainInfo(it.identity, it.channelId, it.peer)
+		if err != nil {
+			return nil, err
+		}
+		if info.Height > it.next {
+			it.height = info.Height
+			break
+		}
+		t := time.NewTimer(it.config.PollInterval)
+		select {
+		case <-t.C:
+		case <-it.ctx.Done():
+			t.Stop()
+			return nil, it.ctx.Err()
+		}
+	}
+	r, err := it.client.QueryBlockByNumber(it.identity, it.channelId, it.next, []string{it.peer})
+	if err != nil {
+		return nil, err
+	}
+	if r[0].Error != nil {
+		return nil, r[0].Error
+	}
+	return r[0].RawBlock, nil
+}
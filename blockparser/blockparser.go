@@ -0,0 +1,482 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+
+// Package blockparser decodes raw Hyperledger Fabric blocks into Go structures that are easy to inspect,
+// serialize to JSON and use in explorers and audit tools.
+package blockparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+// Block is decoded Fabric block
+type Block struct {
+	Number       uint64         `json:"number"`
+	PreviousHash []byte         `json:"previousHash"`
+	DataHash     []byte         `json:"dataHash"`
+	Transactions []*Transaction `json:"transactions"`
+	Metadata     *Metadata      `json:"metadata"`
+}
+
+// Metadata is decoded block metadata
+type Metadata struct {
+	// Signatures are orderer signatures over the block
+	Signatures []*Signature `json:"signatures"`
+	// LastConfig is the number of the last config block at the time this block was created
+	LastConfig uint64 `json:"lastConfig"`
+	// TransactionsFilter holds validation code for every transaction in the block
+	TransactionsFilter []string `json:"transactionsFilter"`
+	// Orderer is consensus specific metadata, nil when ordering service did not write any (solo)
+	Orderer *OrdererMetadata `json:"orderer,omitempty"`
+}
+
+// Signature is signature with decoded creator
+type Signature struct {
+	Creator   *Identity `json:"creator"`
+	Signature []byte    `json:"signature"`
+}
+
+// Identity is decoded serialized identity of transaction creator, endorser or orderer
+type Identity struct {
+	MspId       string `json:"mspId"`
+	Certificate string `json:"certificate"`
+}
+
+// Transaction is decoded envelope from block data
+type Transaction struct {
+	TxId           string    `json:"txId"`
+	Type           string    `json:"type"`
+	ChannelId      string    `json:"channelId"`
+	Timestamp      time.Time `json:"timestamp"`
+	Creator        *Identity `json:"creator"`
+	Nonce          []byte    `json:"nonce"`
+	Signature      []byte    `json:"signature"`
+	ValidationCode string    `json:"validationCode"`
+	// Actions are set only for endorser transactions
+	Actions []*Action `json:"actions,omitempty"`
+	// Config is set only for config transactions
+	Config *common.ConfigEnvelope `json:"config,omitempty"`
+	// Raw is set for transaction types that are not decoded
+	Raw *RawTransaction `json:"raw,omitempty"`
+}
+
+// RawTransaction holds payload data of transaction whose type is not decoded by the parser.
+// This allows unknown or future transaction types to be passed to the caller instead of failing the whole block.
+type RawTransaction struct {
+	HeaderType int32  `json:"headerType"`
+	Data       []byte `json:"data"`
+}
+
+// DecodeDepth defines how deep the parser will decode transactions
+type DecodeDepth int
+
+const (
+	// DecodeFull decodes transactions including read/write sets. This is the default.
+	DecodeFull DecodeDepth = iota
+	// DecodeActions decodes chaincode actions without read/write sets
+	DecodeActions
+	// DecodeHeaders decodes only transaction headers. Transaction data is returned as RawTransaction
+	DecodeHeaders
+)
+
+// Parser decodes blocks with configurable depth and set of decoded transaction types.
+// Zero value Parser decodes everything it knows about.
+type Parser struct {
+	// Depth is the maximum depth of decoding
+	Depth DecodeDepth
+	// HeaderTypes are transaction types whose data will be decoded. Data of all other types is returned as
+	// RawTransaction. If empty ENDORSER_TRANSACTION and CONFIG are decoded.
+	HeaderTypes []common.HeaderType
+	// ConsensusType is ConsensusKafka or ConsensusRaft, it selects how orderer metadata is decoded.
+	// If empty it is guessed from metadata encoding.
+	ConsensusType string
+}
+
+var defaultParser = &Parser{}
+
+// Action is single chaincode action in endorser transaction
+type Action struct {
+	ChaincodeName    string            `json:"chaincodeName"`
+	ChaincodeVersion string            `json:"chaincodeVersion"`
+	ChaincodePath    string            `json:"chaincodePath"`
+	Input            [][]byte          `json:"input"`
+	Endorsements     []*Signature      `json:"endorsements"`
+	ProposalHash     []byte            `json:"proposalHash"`
+	Response         *Response         `json:"response"`
+	Event            *ChaincodeEvent   `json:"event,omitempty"`
+	ReadWriteSets    []*NsReadWriteSet `json:"readWriteSets"`
+}
+
+// Response is chaincode response as returned from simulation
+type Response struct {
+	Status  int32  `json:"status"`
+	Message string `json:"message"`
+	Payload []byte `json:"payload"`
+}
+
+// ChaincodeEvent is event set by chaincode
+type ChaincodeEvent struct {
+	ChaincodeId string `json:"chaincodeId"`
+	TxId        string `json:"txId"`
+	EventName   string `json:"eventName"`
+	Payload     []byte `json:"payload"`
+}
+
+// ParseBlock decodes block into Block structure using default parser
+func ParseBlock(block *common.Block) (*Block, error) {
+	return defaultParser.ParseBlock(block)
+}
+
+// ParseBlockBytes decodes marshaled block into Block structure using default parser
+func ParseBlockBytes(data []byte) (*Block, error) {
+	return defaultParser.ParseBlockBytes(data)
+}
+
+// ParseEnvelopeBytes decodes single marshaled envelope from block data using default parser.
+// Validation code is not available in envelope and is not set.
+func ParseEnvelopeBytes(data []byte) (*Transaction, error) {
+	return defaultParser.ParseEnvelopeBytes(data)
+}
+
+// ParseEnvelope decodes single envelope using default parser. Validation code is not available in envelope and is
+// not set.
+func ParseEnvelope(envelope *common.Envelope) (*Transaction, error) {
+	return defaultParser.ParseEnvelope(envelope)
+}
+
+// ParseBlock decodes block into Block structure
+func (p *Parser) ParseBlock(block *common.Block) (*Block, error) {
+	if block == nil || block.Header == nil || block.Data == nil {
+		return nil, fmt.Errorf("block is empty")
+	}
+	result := &Block{
+		Number:       block.Header.Number,
+		PreviousHash: block.Header.PreviousHash,
+		DataHash:     block.Header.DataHash,
+		Transactions: make([]*Transaction, 0, len(block.Data.Data)),
+	}
+	metadata, err := parseMetadata(block.Metadata, p.ConsensusType)
+	if err != nil {
+		return nil, err
+	}
+	result.Metadata = metadata
+
+	for idx, data := range block.Data.Data {
+		tx, err := p.ParseEnvelopeBytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode transaction %d: %v", idx, err)
+		}
+		if idx < len(metadata.TransactionsFilter) {
+			tx.ValidationCode = metadata.TransactionsFilter[idx]
+		}
+		result.Transactions = append(result.Transactions, tx)
+	}
+	return result, nil
+}
+
+// ParseBlockBytes decodes marshaled block into Block structure
+func (p *Parser) ParseBlockBytes(data []byte) (*Block, error) {
+	block := new(common.Block)
+	if err := proto.Unmarshal(data, block); err != nil {
+		return nil, err
+	}
+	return p.ParseBlock(block)
+}
+
+// ToJSON returns JSON representation of the block
+func (b *Block) ToJSON() ([]byte, error) {
+	return json.Marshal(b)
+}
+
+// ParseEnvelopeBytes decodes single marshaled envelope from block data. Validation code is not available in envelope
+// and is not set.
+func (p *Parser) ParseEnvelopeBytes(data []byte) (*Transaction, error) {
+	envelope := new(common.Envelope)
+	if err := proto.Unmarshal(data, envelope); err != nil {
+		return nil, err
+	}
+	return p.ParseEnvelope(envelope)
+}
+
+// ParseEnvelope decodes single envelope. Validation code is not available in envelope and is not set.
+func (p *Parser) ParseEnvelope(envelope *common.Envelope) (*Transaction, error) {
+	payload := new(common.Payload)
+	if err := proto.Unmarshal(envelope.Payload, payload); err != nil {
+		return nil, err
+	}
+	if payload.Header == nil {
+		return nil, fmt.Errorf("payload header is missing")
+	}
+	channelHeader := new(common.ChannelHeader)
+	if err := proto.Unmarshal(payload.Header.ChannelHeader, channelHeader); err != nil {
+		return nil, err
+	}
+	signatureHeader := new(common.SignatureHeader)
+	if err := proto.Unmarshal(payload.Header.SignatureHeader, signatureHeader); err != nil {
+		return nil, err
+	}
+	creator, err := parseIdentity(signatureHeader.Creator)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &Transaction{
+		TxId:      channelHeader.TxId,
+		Type:      common.HeaderType_name[channelHeader.Type],
+		ChannelId: channelHeader.ChannelId,
+		Creator:   creator,
+		Nonce:     signatureHeader.Nonce,
+		Signature: envelope.Signature,
+	}
+	if channelHeader.Timestamp != nil {
+		ts, err := ptypes.Timestamp(channelHeader.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		tx.Timestamp = ts
+	}
+
+	headerType := common.HeaderType(channelHeader.Type)
+	if !p.decodeType(headerType) {
+		tx.Raw = &RawTransaction{HeaderType: channelHeader.Type, Data: payload.Data}
+		return tx, nil
+	}
+	switch headerType {
+	case common.HeaderType_ENDORSER_TRANSACTION:
+		actions, err := p.parseEndorserTransaction(payload.Data)
+		if err != nil {
+			return nil, err
+		}
+		tx.Actions = actions
+	case common.HeaderType_CONFIG:
+		config := new(common.ConfigEnvelope)
+		if err := proto.Unmarshal(payload.Data, config); err != nil {
+			return nil, err
+		}
+		tx.Config = config
+	default:
+		tx.Raw = &RawTransaction{HeaderType: channelHeader.Type, Data: payload.Data}
+	}
+	return tx, nil
+}
+
+// decodeType checks is data of transaction with this type must be decoded
+func (p *Parser) decodeType(t common.HeaderType) bool {
+	if p.Depth >= DecodeHeaders {
+		return false
+	}
+	if len(p.HeaderTypes) == 0 {
+		return t == common.HeaderType_ENDORSER_TRANSACTION || t == common.HeaderType_CONFIG
+	}
+	for _, ht := range p.HeaderTypes {
+		if ht == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Parser) parseEndorserTransaction(data []byte) ([]*Action, error) {
+	tx := new(peer.Transaction)
+	if err := proto.Unmarshal(data, tx); err != nil {
+		return nil, err
+	}
+	actions := make([]*Action, 0, len(tx.Actions))
+	for _, ta := range tx.Actions {
+		action, err := p.parseTransactionAction(ta)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+func (p *Parser) parseTransactionAction(ta *peer.TransactionAction) (*Action, error) {
+	actionPayload := new(peer.ChaincodeActionPayload)
+	if err := proto.Unmarshal(ta.Payload, actionPayload); err != nil {
+		return nil, err
+	}
+	action := new(Action)
+
+	proposalPayload := new(peer.ChaincodeProposalPayload)
+	if err := proto.Unmarshal(actionPayload.ChaincodeProposalPayload, proposalPayload); err != nil {
+		return nil, err
+	}
+	invocation := new(peer.ChaincodeInvocationSpec)
+	if err := proto.Unmarshal(proposalPayload.Input, invocation); err != nil {
+		return nil, err
+	}
+	if invocation.ChaincodeSpec != nil && invocation.ChaincodeSpec.Input != nil {
+		action.Input = invocation.ChaincodeSpec.Input.Args
+	}
+	if actionPayload.Action == nil {
+		return action, nil
+	}
+
+	for _, e := range actionPayload.Action.Endorsements {
+		endorser, err := parseIdentity(e.Endorser)
+		if err != nil {
+			return nil, err
+		}
+		action.Endorsements = append(action.Endorsements, &Signature{Creator: endorser, Signature: e.Signature})
+	}
+
+	responsePayload := new(peer.ProposalResponsePayload)
+	if err := proto.Unmarshal(actionPayload.Action.ProposalResponsePayload, responsePayload); err != nil {
+		return nil, err
+	}
+	action.ProposalHash = responsePayload.ProposalHash
+
+	chaincodeAction := new(peer.ChaincodeAction)
+	if err := proto.Unmarshal(responsePayload.Extension, chaincodeAction); err != nil {
+		return nil, err
+	}
+	if chaincodeAction.ChaincodeId != nil {
+		action.ChaincodeName = chaincodeAction.ChaincodeId.Name
+		action.ChaincodeVersion = chaincodeAction.ChaincodeId.Version
+		action.ChaincodePath = chaincodeAction.ChaincodeId.Path
+	}
+	if chaincodeAction.Response != nil {
+		action.Response = &Response{
+			Status:  chaincodeAction.Response.Status,
+			Message: chaincodeAction.Response.Message,
+			Payload: chaincodeAction.Response.Payload,
+		}
+	}
+	if len(chaincodeAction.Events) > 0 {
+		event := new(peer.ChaincodeEvent)
+		if err := proto.Unmarshal(chaincodeAction.Events, event); err != nil {
+			return nil, err
+		}
+		action.Event = &ChaincodeEvent{
+			ChaincodeId: event.ChaincodeId,
+			TxId:        event.TxId,
+			EventName:   event.EventName,
+			Payload:     event.Payload,
+		}
+	}
+	if p.Depth >= DecodeActions {
+		return action, nil
+	}
+	rwSets, err := ParseReadWriteSet(chaincodeAction.Results)
+	if err != nil {
+		return nil, err
+	}
+	action.ReadWriteSets = rwSets
+	return action, nil
+}
+
+func parseMetadata(metadata *common.BlockMetadata, consensusType string) (*Metadata, error) {
+	result := new(Metadata)
+	if metadata == nil {
+		return result, nil
+	}
+	if len(metadata.Metadata) > int(common.BlockMetadataIndex_SIGNATURES) {
+		raw := metadata.Metadata[common.BlockMetadataIndex_SIGNATURES]
+		if len(raw) > 0 {
+			md := new(common.Metadata)
+			if err := proto.Unmarshal(raw, md); err != nil {
+				return nil, err
+			}
+			signatures, err := parseMetadataSignatures(md.Signatures)
+			if err != nil {
+				return nil, err
+			}
+			result.Signatures = signatures
+			// Fabric 2.x stores last config and consenter metadata in signatures metadata value
+			if len(md.Value) > 0 {
+				obm := new(ordererBlockMetadata)
+				if err := proto.Unmarshal(md.Value, obm); err != nil {
+					return nil, err
+				}
+				if obm.LastConfig != nil {
+					result.LastConfig = obm.LastConfig.Index
+				}
+				if len(obm.ConsenterMetadata) > 0 {
+					result.Orderer = &OrdererMetadata{Value: obm.ConsenterMetadata}
+				}
+			}
+		}
+	}
+	if len(metadata.Metadata) > int(common.BlockMetadataIndex_LAST_CONFIG) {
+		raw := metadata.Metadata[common.BlockMetadataIndex_LAST_CONFIG]
+		if len(raw) > 0 {
+			md := new(common.Metadata)
+			if err := proto.Unmarshal(raw, md); err != nil {
+				return nil, err
+			}
+			lc := new(common.LastConfig)
+			if err := proto.Unmarshal(md.Value, lc); err != nil {
+				return nil, err
+			}
+			result.LastConfig = lc.Index
+		}
+	}
+	if len(metadata.Metadata) > int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		raw := metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+		result.TransactionsFilter = make([]string, len(raw))
+		for i, code := range raw {
+			result.TransactionsFilter[i] = peer.TxValidationCode_name[int32(code)]
+		}
+	}
+	if len(metadata.Metadata) > int(common.BlockMetadataIndex_ORDERER) && result.Orderer == nil {
+		raw := metadata.Metadata[common.BlockMetadataIndex_ORDERER]
+		if len(raw) > 0 {
+			md := new(common.Metadata)
+			if err := proto.Unmarshal(raw, md); err != nil {
+				return nil, err
+			}
+			if len(md.Value) > 0 || len(md.Signatures) > 0 {
+				signatures, err := parseMetadataSignatures(md.Signatures)
+				if err != nil {
+					return nil, err
+				}
+				result.Orderer = &OrdererMetadata{Value: md.Value, Signatures: signatures}
+			}
+		}
+	}
+	if result.Orderer != nil {
+		if err := decodeConsenterMetadata(consensusType, result.Orderer); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func parseMetadataSignatures(signatures []*common.MetadataSignature) ([]*Signature, error) {
+	var result []*Signature
+	for _, s := range signatures {
+		sh := new(common.SignatureHeader)
+		if err := proto.Unmarshal(s.SignatureHeader, sh); err != nil {
+			return nil, err
+		}
+		creator, err := parseIdentity(sh.Creator)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &Signature{Creator: creator, Signature: s.Signature})
+	}
+	return result, nil
+}
+
+func parseIdentity(data []byte) (*Identity, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	sid := new(msp.SerializedIdentity)
+	if err := proto.Unmarshal(data, sid); err != nil {
+		return nil, err
+	}
+	return &Identity{MspId: sid.Mspid, Certificate: string(sid.IdBytes)}, nil
+}
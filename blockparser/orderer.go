@@ -0,0 +1,103 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package blockparser
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/orderer"
+)
+
+// Consensus types that can be set in Parser.ConsensusType
+const (
+	ConsensusKafka = "kafka"
+	ConsensusRaft  = "etcdraft"
+)
+
+// OrdererMetadata is consensus specific metadata written to the block by ordering service.
+// Fabric 1.x keeps it in ORDERER block metadata, Fabric 2.x in SIGNATURES block metadata.
+type OrdererMetadata struct {
+	// Value is raw consenter metadata
+	Value []byte `json:"value"`
+	// Signatures are orderer signatures over ORDERER metadata
+	Signatures []*Signature `json:"signatures,omitempty"`
+	// Kafka is set when Value is Kafka metadata
+	Kafka *KafkaMetadata `json:"kafka,omitempty"`
+	// Raft is set when Value is Raft metadata
+	Raft *RaftMetadata `json:"raft,omitempty"`
+}
+
+// KafkaMetadata holds Kafka offsets of the block
+type KafkaMetadata struct {
+	LastOffsetPersisted         int64 `json:"lastOffsetPersisted"`
+	LastOriginalOffsetProcessed int64 `json:"lastOriginalOffsetProcessed"`
+	LastResubmittedConfigOffset int64 `json:"lastResubmittedConfigOffset"`
+}
+
+// RaftMetadata holds Raft consenter information of the block
+type RaftMetadata struct {
+	// ConsenterIds are Raft node ids of consenters, in the order of consenters in channel config
+	ConsenterIds []uint64 `json:"consenterIds"`
+	// NextConsenterId is id assigned to the next added consenter
+	NextConsenterId uint64 `json:"nextConsenterId"`
+	// RaftIndex is the Raft log index of the block
+	RaftIndex uint64 `json:"raftIndex"`
+}
+
+// raftBlockMetadata is etcdraft.BlockMetadata message, it is not part of vendored protos
+type raftBlockMetadata struct {
+	ConsenterIds    []uint64 `protobuf:"varint,1,rep,packed,name=consenter_ids,json=consenterIds" json:"consenter_ids,omitempty"`
+	NextConsenterId uint64   `protobuf:"varint,2,opt,name=next_consenter_id,json=nextConsenterId" json:"next_consenter_id,omitempty"`
+	RaftIndex       uint64   `protobuf:"varint,3,opt,name=raft_index,json=raftIndex" json:"raft_index,omitempty"`
+}
+
+func (m *raftBlockMetadata) Reset()         { *m = raftBlockMetadata{} }
+func (m *raftBlockMetadata) String() string { return proto.CompactTextString(m) }
+func (*raftBlockMetadata) ProtoMessage()    {}
+
+// ordererBlockMetadata is common.OrdererBlockMetadata message from Fabric 2.x, stored as value of SIGNATURES metadata
+type ordererBlockMetadata struct {
+	LastConfig        *common.LastConfig `protobuf:"bytes,1,opt,name=last_config,json=lastConfig" json:"last_config,omitempty"`
+	ConsenterMetadata []byte             `protobuf:"bytes,2,opt,name=consenter_metadata,json=consenterMetadata,proto3" json:"consenter_metadata,omitempty"`
+}
+
+func (m *ordererBlockMetadata) Reset()         { *m = ordererBlockMetadata{} }
+func (m *ordererBlockMetadata) String() string { return proto.CompactTextString(m) }
+func (*ordererBlockMetadata) ProtoMessage()    {}
+
+// decodeConsenterMetadata decodes Kafka or Raft metadata. When consensus type is not known it is guessed from
+// encoding: Raft metadata starts with packed consenter ids, Kafka metadata with offset varint.
+func decodeConsenterMetadata(consensusType string, om *OrdererMetadata) error {
+	if len(om.Value) == 0 {
+		return nil
+	}
+	if consensusType == "" {
+		switch om.Value[0] {
+		case 0x0a:
+			consensusType = ConsensusRaft
+		case 0x08:
+			consensusType = ConsensusKafka
+		}
+	}
+	switch consensusType {
+	case ConsensusRaft:
+		md := new(raftBlockMetadata)
+		if err := proto.Unmarshal(om.Value, md); err != nil {
+			return err
+		}
+		om.Raft = &RaftMetadata{ConsenterIds: md.ConsenterIds, NextConsenterId: md.NextConsenterId, RaftIndex: md.RaftIndex}
+	case ConsensusKafka:
+		md := new(orderer.KafkaMetadata)
+		if err := proto.Unmarshal(om.Value, md); err != nil {
+			return err
+		}
+		om.Kafka = &KafkaMetadata{
+			LastOffsetPersisted:         md.LastOffsetPersisted,
+			LastOriginalOffsetProcessed: md.LastOriginalOffsetProcessed,
+			LastResubmittedConfigOffset: md.LastResubmittedConfigOffset,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package blockparser
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/ledger/rwset"
+	"github.com/hyperledger/fabric/protos/ledger/rwset/kvrwset"
+)
+
+// NsReadWriteSet holds keys read and written by transaction in single namespace (chaincode)
+type NsReadWriteSet struct {
+	Namespace    string                `json:"namespace"`
+	Reads        []*KVRead             `json:"reads"`
+	Writes       []*KVWrite            `json:"writes"`
+	RangeQueries []*RangeQuery         `json:"rangeQueries,omitempty"`
+	Collections  []*CollectionHashedRW `json:"collections,omitempty"`
+}
+
+// KVRead is single key read together with the version of the key at the time of simulation.
+// Version is nil when key does not exist.
+type KVRead struct {
+	Key     string   `json:"key"`
+	Version *Version `json:"version"`
+}
+
+// KVWrite is single key write or delete
+type KVWrite struct {
+	Key      string `json:"key"`
+	IsDelete bool   `json:"isDelete"`
+	Value    []byte `json:"value"`
+}
+
+// Version is the height (block and transaction number) where key was last committed
+type Version struct {
+	BlockNum uint64 `json:"blockNum"`
+	TxNum    uint64 `json:"txNum"`
+}
+
+// RangeQuery is range query executed during simulation
+type RangeQuery struct {
+	StartKey     string    `json:"startKey"`
+	EndKey       string    `json:"endKey"`
+	ItrExhausted bool      `json:"itrExhausted"`
+	Reads        []*KVRead `json:"reads,omitempty"`
+}
+
+// CollectionHashedRW holds hashed reads and writes for private data collection.
+// Actual keys and values are not part of the block.
+type CollectionHashedRW struct {
+	CollectionName string         `json:"collectionName"`
+	PvtRwSetHash   []byte         `json:"pvtRwSetHash"`
+	HashedReads    []*KVReadHash  `json:"hashedReads"`
+	HashedWrites   []*KVWriteHash `json:"hashedWrites"`
+}
+
+// KVReadHash is hashed key read from private data collection
+type KVReadHash struct {
+	KeyHash []byte   `json:"keyHash"`
+	Version *Version `json:"version"`
+}
+
+// KVWriteHash is hashed key write to private data collection
+type KVWriteHash struct {
+	KeyHash   []byte `json:"keyHash"`
+	IsDelete  bool   `json:"isDelete"`
+	ValueHash []byte `json:"valueHash"`
+}
+
+// ParseReadWriteSet decodes marshaled TxReadWriteSet (ChaincodeAction.Results) to list of namespace read write sets
+func ParseReadWriteSet(data []byte) ([]*NsReadWriteSet, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	txRwSet := new(rwset.TxReadWriteSet)
+	if err := proto.Unmarshal(data, txRwSet); err != nil {
+		return nil, err
+	}
+	result := make([]*NsReadWriteSet, 0, len(txRwSet.NsRwset))
+	for _, ns := range txRwSet.NsRwset {
+		kv := new(kvrwset.KVRWSet)
+		if err := proto.Unmarshal(ns.Rwset, kv); err != nil {
+			return nil, err
+		}
+		nsRwSet := &NsReadWriteSet{
+			Namespace: ns.Namespace,
+			Reads:     convertReads(kv.Reads),
+			Writes:    make([]*KVWrite, 0, len(kv.Writes)),
+		}
+		for _, w := range kv.Writes {
+			nsRwSet.Writes = append(nsRwSet.Writes, &KVWrite{Key: w.Key, IsDelete: w.IsDelete, Value: w.Value})
+		}
+		for _, rq := range kv.RangeQueriesInfo {
+			q := &RangeQuery{StartKey: rq.StartKey, EndKey: rq.EndKey, ItrExhausted: rq.ItrExhausted}
+			if raw := rq.GetRawReads(); raw != nil {
+				q.Reads = convertReads(raw.KvReads)
+			}
+			nsRwSet.RangeQueries = append(nsRwSet.RangeQueries, q)
+		}
+		for _, coll := range ns.CollectionHashedRwset {
+			hashed := new(kvrwset.HashedRWSet)
+			if err := proto.Unmarshal(coll.HashedRwset, hashed); err != nil {
+				return nil, err
+			}
+			c := &CollectionHashedRW{CollectionName: coll.CollectionName, PvtRwSetHash: coll.PvtRwsetHash}
+			for _, r := range hashed.HashedReads {
+				c.HashedReads = append(c.HashedReads, &KVReadHash{KeyHash: r.KeyHash, Version: convertVersion(r.Version)})
+			}
+			for _, w := range hashed.HashedWrites {
+				c.HashedWrites = append(c.HashedWrites, &KVWriteHash{KeyHash: w.KeyHash, IsDelete: w.IsDelete, ValueHash: w.ValueHash})
+			}
+			nsRwSet.Collections = append(nsRwSet.Collections, c)
+		}
+		result = append(result, nsRwSet)
+	}
+	return result, nil
+}
+
+func convertReads(reads []*kvrwset.KVRead) []*KVRead {
+	result := make([]*KVRead, 0, len(reads))
+	for _, r := range reads {
+		result = append(result, &KVRead{Key: r.Key, Version: convertVersion(r.Version)})
+	}
+	return result
+}
+
+func convertVersion(v *kvrwset.Version) *Version {
+	if v == nil {
+		return nil
+	}
+	return &Version{BlockNum: v.BlockNum, TxNum: v.TxNum}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/orderer"
+)
+
+// BroadcastStream sends many envelopes to orderer over single broadcast stream. Orderer acknowledges envelopes in
+// the order they were received, so Broadcast does not wait for acknowledgements of envelopes sent before and many
+// goroutines can broadcast at the same time. It is safe for concurrent use.
+type BroadcastStream struct {
+	orderer *Orderer
+	stream  orderer.AtomicBroadcast_BroadcastClient
+	cancel  context.CancelFunc
+	// sendMu keeps order of sends and pending acknowledgements the same
+	sendMu sync.Mutex
+	closed bool
+	// mu guards pending and err
+	mu      sync.Mutex
+	pending []*pendingBroadcast
+	err     error
+	done    chan struct{}
+}
+
+type pendingBroadcast struct {
+	start    time.Time
+	response chan *orderer.BroadcastResponse
+}
+
+// NewBroadcastStream opens broadcast stream to orderer. Stream is open until Close is called, ctx is canceled or
+// orderer ends it.
+func (c *FabricClient) NewBroadcastStream(ctx context.Context, ordererName string) (*BroadcastStream, error) {
+	ord, ok := c.getOrderer(ordererName)
+	if !ok {
+		return nil, ErrInvalidOrdererName
+	}
+	client, err := ord.broadcastClient(ctx, effectiveTimeouts(ctx, ord.Timeouts).Dial)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := client.Broadcast(ctx)
+	if err != nil {
+		cancel()
+		return nil, rpcError(ord.Name, err)
+	}
+	s := &BroadcastStream{orderer: ord, stream: stream, cancel: cancel, done: make(chan struct{})}
+	go s.receive()
+	return s, nil
+}
+
+// Orderer returns name of orderer
+func (s *BroadcastStream) Orderer() string {
+	return s.orderer.Name
+}
+
+// Broadcast sends envelope and waits for acknowledgement of orderer. Status other than SUCCESS is returned as
+// BroadcastError. Waiting is limited by Broadcast timeout of orderer, envelope is still acknowledged by orderer
+// when ctx is done before.
+func (s *BroadcastStream) Broadcast(ctx context.Context, envelope *common.Envelope) (*orderer.BroadcastResponse, error) {
+	ctx, span := startSpan(ctx, "gohfc.Broadcast", "orderer", s.orderer.Name, "endpoint", s.orderer.Uri)
+	response, err := s.broadcast(ctx, envelope)
+	endSpan(span, err)
+	return response, err
+}
+
+func (s *BroadcastStream) broadcast(ctx context.Context, envelope *common.Envelope) (*orderer.BroadcastResponse, error) {
+	p := &pendingBroadcast{start: time.Now(), response: make(chan *orderer.BroadcastResponse, 1)}
+	s.sendMu.Lock()
+	if s.closed {
+		s.sendMu.Unlock()
+		return nil, ErrBroadcastStreamClosed
+	}
+	s.mu.Lock()
+	if s.err != nil {
+		err := s.err
+		s.mu.Unlock()
+		s.sendMu.Unlock()
+		return nil, err
+	}
+	s.pending = append(s.pending, p)
+	s.mu.Unlock()
+	err := s.stream.Send(envelope)
+	s.sendMu.Unlock()
+	if err != nil {
+		// stream is broken, receive fails pending acknowledgements with its error
+		logger().Warn("broadcast failed", "orderer", s.orderer.Name, "endpoint", s.orderer.Uri, "error", err)
+	}
+	ctx, cancel := withTimeout(ctx, effectiveTimeouts(ctx, s.orderer.Timeouts).Broadcast)
+	defer cancel()
+	select {
+	case response := <-p.response:
+		if response == nil {
+			s.mu.Lock()
+			err := s.err
+			s.mu.Unlock()
+			metrics().Broadcast(s.orderer.Name, time.Since(p.start), err)
+			return nil, err
+		}
+		return s.orderer.broadcastResponse(response, p.start)
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, &TimeoutError{Node: s.orderer.Name, Err: ErrOrdererTimeout}
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// receive matches acknowledgements of orderer with pending envelopes until stream ends
+func (s *BroadcastStream) receive() {
+	defer close(s.done)
+	for {
+		response, err := s.stream.Recv()
+		s.mu.Lock()
+		if err != nil {
+			if err == io.EOF {
+				err = ErrBroadcastStreamClosed
+			}
+			s.err = rpcError(s.orderer.Name, err)
+			for _, p := range s.pending {
+				close(p.response)
+			}
+			s.pending = nil
+			s.mu.Unlock()
+			return
+		}
+		if len(s.pending) == 0 {
+			s.mu.Unlock()
+			logger().Warn("unexpected broadcast response", "orderer", s.orderer.Name, "status", response.Status)
+			continue
+		}
+		p := s.pending[0]
+		s.pending = s.pending[1:]
+		s.mu.Unlock()
+		p.response <- response
+	}
+}
+
+// Close waits for acknowledgements of envelopes already sent and closes stream. Waiting ends when ctx is done.
+func (s *BroadcastStream) Close(ctx context.Context) error {
+	s.sendMu.Lock()
+	if !s.closed {
+		s.closed = true
+		s.stream.CloseSend()
+	}
+	s.sendMu.Unlock()
+	defer s.cancel()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type broadcastStreamKey struct{}
+
+// WithBroadcastStream attach broadcast stream to ctx. InvokeWithContext, InvokeAsync and InvokeWithQuorum send
+// transactions to orderer of stream over it, instead of opening new stream for every transaction. Transactions for
+// other orderers are not affected.
+func WithBroadcastStream(ctx context.Context, stream *BroadcastStream) context.Context {
+	return context.WithValue(ctx, broadcastStreamKey{}, stream)
+}
+
+// broadcastEnvelope sends envelope over broadcast stream attached to ctx when it is stream of ord, otherwise over
+// new stream
+func broadcastEnvelope(ctx context.Context, ord *Orderer, envelope *common.Envelope) (*orderer.BroadcastResponse, error) {
+	if s, ok := ctx.Value(broadcastStreamKey{}).(*BroadcastStream); ok && s.orderer == ord {
+		return s.Broadcast(ctx, envelope)
+	}
+	return ord.broadcast(ctx, envelope)
+}
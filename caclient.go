@@ -7,8 +7,8 @@ package gohfc
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/tls"
-	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
@@ -480,6 +480,16 @@ func (f *FabricCAClient) Enroll(request CaEnrollmentRequest) (*Identity, []byte,
 	if err != nil {
 		return nil, nil, err
 	}
+	return f.enroll(request, key)
+}
+
+// EnrollWithKey is same as Enroll, but certificate is issued for key instead of generated one. Key can be Signer
+// of key kept in Vault or cloud KMS, csr is then signed by signing service.
+func (f *FabricCAClient) EnrollWithKey(request CaEnrollmentRequest, key crypto.Signer) (*Identity, []byte, error) {
+	return f.enroll(request, key)
+}
+
+func (f *FabricCAClient) enroll(request CaEnrollmentRequest, key interface{}) (*Identity, []byte, error) {
 	var hosts []string
 	if len(request.Hosts) == 0 {
 		parsedUrl, err := url.Parse(f.Url)
@@ -531,7 +541,7 @@ func (f *FabricCAClient) Enroll(request CaEnrollmentRequest) (*Identity, []byte,
 			return nil, nil, err
 		}
 		a, _ := pem.Decode(rawCert)
-		cert, err := x509.ParseCertificate(a.Bytes)
+		cert, err := parseCertificate(a.Bytes)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -600,6 +610,18 @@ func (f *FabricCAClient) ReEnroll(request CaReEnrollmentRequest) (*Identity, []b
 	if err != nil {
 		return nil, nil, err
 	}
+	return f.reEnroll(request, key)
+}
+
+// ReEnrollWithKey is same as ReEnroll, but new certificate is issued for key instead of generated one
+func (f *FabricCAClient) ReEnrollWithKey(request CaReEnrollmentRequest, key crypto.Signer) (*Identity, []byte, error) {
+	if request.Identity == nil || request.Identity.EnrollmentId() == "" {
+		return nil, nil, ErrCertificateEmpty
+	}
+	return f.reEnroll(request, key)
+}
+
+func (f *FabricCAClient) reEnroll(request CaReEnrollmentRequest, key interface{}) (*Identity, []byte, error) {
 	var hosts []string
 	if len(request.Hosts) == 0 {
 		parsedUrl, err := url.Parse(f.Url)
@@ -659,7 +681,7 @@ func (f *FabricCAClient) ReEnroll(request CaReEnrollmentRequest) (*Identity, []b
 			return nil, nil, err
 		}
 		a, _ := pem.Decode(rawCert)
-		cert, err := x509.ParseCertificate(a.Bytes)
+		cert, err := parseCertificate(a.Bytes)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -719,7 +741,7 @@ func (f *FabricCAClient) GetCaCertificateChain(caName string) (*CAGetCertsRespon
 				break
 			}
 
-			cert, err := x509.ParseCertificate(block.Bytes)
+			cert, err := parseCertificate(block.Bytes)
 			if err != nil {
 				return nil, fmt.Errorf("error parsing certificate from ca chain")
 
@@ -1234,16 +1256,9 @@ func concatErrors(errs []caResponseErr) (error) {
 // NewCaClientFromConfig creates new FabricCAClient from CAConfig
 func NewCaClientFromConfig(config CAConfig, transport *http.Transport) (*FabricCAClient, error) {
 
-	var crypto CryptoSuite
-	var err error
-	switch config.CryptoConfig.Family {
-	case "ecdsa":
-		crypto, err = NewECCryptSuiteFromConfig(config.CryptoConfig)
-		if err != nil {
-			return nil, err
-		}
-	default:
-		return nil, ErrInvalidAlgorithmFamily
+	crypto, err := NewCryptoSuiteFromConfig(config.CryptoConfig)
+	if err != nil {
+		return nil, err
 	}
 
 	return &FabricCAClient{SkipTLSVerification: config.SkipTLSValidation,
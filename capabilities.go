@@ -0,0 +1,139 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Known capability names. Enabling capability implies all older capabilities of the same kind.
+const (
+	CapabilityV1_1   = "V1_1"
+	CapabilityV1_2   = "V1_2"
+	CapabilityV1_3   = "V1_3"
+	CapabilityV1_4_2 = "V1_4_2"
+	CapabilityV1_4_3 = "V1_4_3"
+	CapabilityV2_0   = "V2_0"
+	CapabilityV2_5   = "V2_5"
+)
+
+// Capabilities is set of enabled capabilities for one level of channel config (channel, application or orderer)
+type Capabilities []string
+
+// Has returns true if capability is explicitly enabled
+func (c Capabilities) Has(capability string) bool {
+	for _, name := range c {
+		if name == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// AtLeast returns true if capability or any newer one is enabled. Unknown capability names are ignored.
+func (c Capabilities) AtLeast(capability string) bool {
+	want, ok := parseCapabilityVersion(capability)
+	if !ok {
+		return false
+	}
+	for _, name := range c {
+		have, ok := parseCapabilityVersion(name)
+		if ok && compareVersions(have, want) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Highest returns the newest enabled capability or empty string if none is enabled
+func (c Capabilities) Highest() string {
+	var highest string
+	var highestVersion []int
+	for _, name := range c {
+		v, ok := parseCapabilityVersion(name)
+		if ok && (highestVersion == nil || compareVersions(v, highestVersion) > 0) {
+			highest, highestVersion = name, v
+		}
+	}
+	return highest
+}
+
+// Channel returns channel level capabilities
+func (cc *ChannelConfig) Channel() Capabilities {
+	return Capabilities(cc.ChannelCapabilities)
+}
+
+// Application returns application (peer) level capabilities
+func (cc *ChannelConfig) Application() Capabilities {
+	return Capabilities(cc.ApplicationCapabilities)
+}
+
+// Orderer returns ordering service capabilities
+func (cc *ChannelConfig) Orderer() Capabilities {
+	return Capabilities(cc.OrdererCapabilities)
+}
+
+// SupportsLifecycleV2 returns true when chaincodes in channel are managed by new _lifecycle instead of LSCC.
+// Note that gohfc install and instantiate functions use LSCC only.
+func (cc *ChannelConfig) SupportsLifecycleV2() bool {
+	return cc.Application().AtLeast(CapabilityV2_0)
+}
+
+// SupportsPrivateData returns true when private data collections can be used in channel
+func (cc *ChannelConfig) SupportsPrivateData() bool {
+	return cc.Application().AtLeast(CapabilityV1_1)
+}
+
+// SupportsKeyLevelEndorsement returns true when state based endorsement policies can be set on keys
+func (cc *ChannelConfig) SupportsKeyLevelEndorsement() bool {
+	return cc.Application().AtLeast(CapabilityV1_3)
+}
+
+// SupportsFabToken returns true when channel has FabToken enabled
+func (cc *ChannelConfig) SupportsFabToken() bool {
+	return cc.Application().Has("V1_4_FABTOKEN_EXPERIMENTAL")
+}
+
+// SupportsOrdererV2 returns true when ordering service supports Fabric 2.0 features like channel participation
+func (cc *ChannelConfig) SupportsOrdererV2() bool {
+	return cc.Orderer().AtLeast(CapabilityV2_0)
+}
+
+// parseCapabilityVersion parses name like V1_4_2 to [1 4 2]
+func parseCapabilityVersion(name string) ([]int, bool) {
+	if !strings.HasPrefix(name, "V") {
+		return nil, false
+	}
+	parts := strings.Split(name[1:], "_")
+	version := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, false
+		}
+		version[i] = n
+	}
+	return version, true
+}
+
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,190 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// LifecycleSCC is the name of Fabric 2.x chaincode lifecycle system chaincode
+const LifecycleSCC = "_lifecycle"
+
+// ExternalChaincodeType is package type handled by builtin chaincode as a service builder of Fabric 2.4+. Older
+// peers with external builder from Fabric samples use "external".
+const ExternalChaincodeType = "ccaas"
+
+// ExternalChaincode describes chaincode running as a service outside of peer. It is packed in connection.json of
+// chaincode package, peer connects to chaincode on Address.
+type ExternalChaincode struct {
+	// Label is package label, package id is label and hash of package
+	Label string
+	// Type is package type, default is ExternalChaincodeType
+	Type    string
+	Address string
+	// DialTimeout is timeout of peer connection to chaincode, default is 10s
+	DialTimeout time.Duration
+	TlsRequired bool
+	// ClientAuthRequired means chaincode requires client certificate of peer, ClientKey and ClientCert are PEM
+	// encoded key and certificate that peer uses.
+	ClientAuthRequired bool
+	ClientKey          string
+	ClientCert         string
+	// RootCert is PEM encoded CA certificate of chaincode server
+	RootCert string
+	// Indexes are CouchDB index definitions by file name, packed in META-INF/statedb/couchdb/indexes
+	Indexes map[string][]byte
+}
+
+type externalConnection struct {
+	Address            string `json:"address"`
+	DialTimeout        string `json:"dial_timeout"`
+	TlsRequired        bool   `json:"tls_required"`
+	ClientAuthRequired bool   `json:"client_auth_required"`
+	ClientKey          string `json:"client_key,omitempty"`
+	ClientCert         string `json:"client_cert,omitempty"`
+	RootCert           string `json:"root_cert,omitempty"`
+}
+
+type packageMetadata struct {
+	Type  string `json:"type"`
+	Label string `json:"label"`
+}
+
+// Messages of Fabric 2.x _lifecycle, they are not part of vendored protos
+
+type installChaincodeArgs struct {
+	ChaincodeInstallPackage []byte `protobuf:"bytes,1,opt,name=chaincode_install_package,json=chaincodeInstallPackage,proto3" json:"chaincode_install_package,omitempty"`
+}
+
+func (m *installChaincodeArgs) Reset()         { *m = installChaincodeArgs{} }
+func (m *installChaincodeArgs) String() string { return proto.CompactTextString(m) }
+func (*installChaincodeArgs) ProtoMessage()    {}
+
+type installChaincodeResult struct {
+	PackageId string `protobuf:"bytes,1,opt,name=package_id,json=packageId" json:"package_id,omitempty"`
+	Label     string `protobuf:"bytes,2,opt,name=label" json:"label,omitempty"`
+}
+
+func (m *installChaincodeResult) Reset()         { *m = installChaincodeResult{} }
+func (m *installChaincodeResult) String() string { return proto.CompactTextString(m) }
+func (*installChaincodeResult) ProtoMessage()    {}
+
+// LifecycleInstallResponse is result of chaincode install in single peer
+type LifecycleInstallResponse struct {
+	PeerName string
+	Error    error
+	// PackageId is used to approve chaincode definition for organization
+	PackageId string
+	Label     string
+}
+
+// PackageExternalChaincode builds Fabric 2.x chaincode package with connection.json of chaincode as a service.
+// Package can be stored and installed later with InstallLifecyclePackage.
+func PackageExternalChaincode(cc ExternalChaincode) ([]byte, error) {
+	if cc.Label == "" || cc.Address == "" {
+		return nil, fmt.Errorf("external chaincode must have label and address")
+	}
+	if cc.Type == "" {
+		cc.Type = ExternalChaincodeType
+	}
+	if cc.DialTimeout == 0 {
+		cc.DialTimeout = 10 * time.Second
+	}
+	connection, err := json.Marshal(externalConnection{Address: cc.Address, DialTimeout: cc.DialTimeout.String(),
+		TlsRequired: cc.TlsRequired, ClientAuthRequired: cc.ClientAuthRequired, ClientKey: cc.ClientKey,
+		ClientCert: cc.ClientCert, RootCert: cc.RootCert})
+	if err != nil {
+		return nil, err
+	}
+	code := map[string][]byte{"connection.json": connection}
+	for name, index := range cc.Indexes {
+		code["META-INF/statedb/couchdb/indexes/"+name] = index
+	}
+	codePackage, err := tarGzFiles(code)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := json.Marshal(packageMetadata{Type: cc.Type, Label: cc.Label})
+	if err != nil {
+		return nil, err
+	}
+	return tarGzFiles(map[string][]byte{"metadata.json": metadata, "code.tar.gz": codePackage})
+}
+
+// LifecyclePackageId returns package id of Fabric 2.x chaincode package with label, same as peer computes it
+func LifecyclePackageId(label string, pkg []byte) string {
+	hash := sha256.Sum256(pkg)
+	return label + ":" + hex.EncodeToString(hash[:])
+}
+
+// InstallLifecyclePackage installs Fabric 2.x chaincode package, like one from PackageExternalChaincode, to peers
+// using _lifecycle. Identity must be admin of peer organization.
+func (c *FabricClient) InstallLifecyclePackage(ctx context.Context, identity Identity, pkg []byte, peers []string) ([]*LifecycleInstallResponse, error) {
+	r, err := c.queryLifecycle(ctx, identity, "", "InstallChaincode", &installChaincodeArgs{ChaincodeInstallPackage: pkg}, peers)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]*LifecycleInstallResponse, len(r))
+	for i, p := range r {
+		ir := &LifecycleInstallResponse{PeerName: p.PeerName, Error: p.Error}
+		if ir.Error == nil {
+			result := new(installChaincodeResult)
+			if err := proto.Unmarshal(p.Payload, result); err != nil {
+				ir.Error = err
+			}
+			ir.PackageId, ir.Label = result.PackageId, result.Label
+		}
+		response[i] = ir
+	}
+	return response, nil
+}
+
+// InstallExternalChaincode packs chaincode as a service with PackageExternalChaincode and installs it to peers
+func (c *FabricClient) InstallExternalChaincode(ctx context.Context, identity Identity, cc ExternalChaincode, peers []string) ([]*LifecycleInstallResponse, error) {
+	pkg, err := PackageExternalChaincode(cc)
+	if err != nil {
+		return nil, err
+	}
+	return c.InstallLifecyclePackage(ctx, identity, pkg, peers)
+}
+
+// tarGzFiles packs files by name in gzip compressed tar, in order of names
+func tarGzFiles(files map[string][]byte) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, name := range names {
+		header := &tar.Header{Name: name, Mode: 0100644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
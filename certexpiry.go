@@ -0,0 +1,194 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"crypto/x509"
+	"sort"
+	"time"
+)
+
+const (
+	// CertKindEnrollment is enrollment certificate of identity
+	CertKindEnrollment = "enrollment"
+	// CertKindTls is TLS certificate presented by peer or orderer
+	CertKindTls = "tls"
+	// CertKindClientTls is client TLS certificate presented by client to peers and orderers
+	CertKindClientTls = "clientTls"
+)
+
+// DefaultCertExpiryWarning is how long before expiry certificates are reported by default
+const DefaultCertExpiryWarning = 30 * 24 * time.Hour
+
+// CertExpiry is certificate that expires soon or already expired
+type CertExpiry struct {
+	// Kind is CertKindEnrollment or CertKindTls
+	Kind string
+	// Name is MSP id for enrollment certificates, node name for TLS certificates and client for client TLS certificate
+	Name     string
+	Subject  string
+	NotAfter time.Time
+	// Remaining is time left until expiry when certificate was checked, negative when expired
+	Remaining time.Duration
+}
+
+// Expired returns true when certificate expired
+func (e CertExpiry) Expired() bool {
+	return e.Remaining <= 0
+}
+
+// CertExpiryHandler is called for every certificate that expires within warning period
+type CertExpiryHandler func(CertExpiry)
+
+// certExpiryHolder is expiry warning setting of client
+type certExpiryHolder struct {
+	handler    CertExpiryHandler
+	warnBefore time.Duration
+}
+
+// SetCertExpiryHandler sets handler called when TLS certificate of peer or orderer of client presented on connect
+// expires within warnBefore. Warning is always logged, handler is optional. Zero warnBefore uses
+// DefaultCertExpiryWarning, negative disables checks.
+func (c *FabricClient) SetCertExpiryHandler(warnBefore time.Duration, handler CertExpiryHandler) {
+	if warnBefore == 0 {
+		warnBefore = DefaultCertExpiryWarning
+	}
+	ct := c.tlsState()
+	ct.mu.Lock()
+	ct.expiry = &certExpiryHolder{handler: handler, warnBefore: warnBefore}
+	ct.mu.Unlock()
+}
+
+// checkNodeTlsExpiry remembers TLS certificate presented by node and reports it when it expires soon
+func (t *clientTls) checkNodeTlsExpiry(node string, cert *x509.Certificate) {
+	if t == nil {
+		return
+	}
+	h := certExpiryHolder{warnBefore: DefaultCertExpiryWarning}
+	t.mu.Lock()
+	if t.observed == nil {
+		t.observed = make(map[string]*x509.Certificate)
+	}
+	t.observed[node] = cert
+	if t.expiry != nil {
+		h = *t.expiry
+	}
+	t.mu.Unlock()
+	if h.warnBefore < 0 {
+		return
+	}
+	if e := certExpiry(CertKindTls, node, cert, h.warnBefore, time.Now()); e != nil {
+		reportCertExpiry(*e, h.handler)
+	}
+}
+
+// expiringTlsCerts returns client TLS certificate and the last TLS certificates of nodes that expire within
+// warnBefore from now
+func (t *clientTls) expiringTlsCerts(warnBefore time.Duration, now time.Time) []CertExpiry {
+	result := make([]CertExpiry, 0)
+	if t == nil {
+		return result
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.cert != nil && len(t.cert.Certificate) > 0 {
+		cert := t.cert.Leaf
+		if cert == nil {
+			cert, _ = parseCertificate(t.cert.Certificate[0])
+		}
+		if e := certExpiry(CertKindClientTls, "client", cert, warnBefore, now); e != nil {
+			result = append(result, *e)
+		}
+	}
+	for name, cert := range t.observed {
+		if e := certExpiry(CertKindTls, name, cert, warnBefore, now); e != nil {
+			result = append(result, *e)
+		}
+	}
+	return result
+}
+
+// certExpiry returns expiry of cert when it expires within warnBefore from now, otherwise nil
+func certExpiry(kind, name string, cert *x509.Certificate, warnBefore time.Duration, now time.Time) *CertExpiry {
+	if cert == nil {
+		return nil
+	}
+	remaining := cert.NotAfter.Sub(now)
+	if remaining > warnBefore {
+		return nil
+	}
+	return &CertExpiry{Kind: kind, Name: name, Subject: cert.Subject.CommonName, NotAfter: cert.NotAfter,
+		Remaining: remaining}
+}
+
+func reportCertExpiry(e CertExpiry, handler CertExpiryHandler) {
+	if e.Expired() {
+		logger().Error("certificate expired", "kind", e.Kind, "name", e.Name, "subject", e.Subject,
+			"notAfter", e.NotAfter)
+	} else {
+		logger().Warn("certificate expires soon", "kind", e.Kind, "name", e.Name, "subject", e.Subject,
+			"notAfter", e.NotAfter, "remaining", e.Remaining)
+	}
+	if handler != nil {
+		handler(e)
+	}
+}
+
+// CheckCertExpiry returns enrollment certificates of identities, client TLS certificate and TLS certificates of
+// peers and orderers the client connected to that expire within warnBefore, sorted by expiry. It does not log or
+// call handlers.
+func (c *FabricClient) CheckCertExpiry(warnBefore time.Duration, identities ...Identity) []CertExpiry {
+	now := time.Now()
+	c.mu.RLock()
+	result := c.clientTls.expiringTlsCerts(warnBefore, now)
+	c.mu.RUnlock()
+	for _, identity := range identities {
+		if e := certExpiry(CertKindEnrollment, identity.MspId, identity.Certificate, warnBefore, now); e != nil {
+			result = append(result, *e)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].NotAfter.Before(result[j].NotAfter) })
+	return result
+}
+
+// CertExpiryMonitor configures MonitorCertExpiry
+type CertExpiryMonitor struct {
+	// WarnBefore is how long before expiry certificates are reported, default is DefaultCertExpiryWarning
+	WarnBefore time.Duration
+	// Interval between checks, default is one hour
+	Interval time.Duration
+	// Identities returns identities to check. It is called on every check, so rotated identities are picked up.
+	Identities func() []Identity
+	// Handler is called for every certificate that expires within WarnBefore, on every check. Optional.
+	Handler CertExpiryHandler
+}
+
+// MonitorCertExpiry checks certificates of client immediately and then every Interval until ctx is canceled, see
+// CheckCertExpiry. Every certificate that expires soon is logged as warning, expired ones as errors.
+func (c *FabricClient) MonitorCertExpiry(ctx context.Context, monitor CertExpiryMonitor) {
+	if monitor.WarnBefore <= 0 {
+		monitor.WarnBefore = DefaultCertExpiryWarning
+	}
+	if monitor.Interval <= 0 {
+		monitor.Interval = time.Hour
+	}
+	ticker := time.NewTicker(monitor.Interval)
+	defer ticker.Stop()
+	for {
+		var identities []Identity
+		if monitor.Identities != nil {
+			identities = monitor.Identities()
+		}
+		for _, e := range c.CheckCertExpiry(monitor.WarnBefore, identities...) {
+			reportCertExpiry(e, monitor.Handler)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
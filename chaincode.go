@@ -19,6 +19,7 @@ import (
 	"github.com/golang/protobuf/ptypes/timestamp"
 	"time"
 	"fmt"
+	"encoding/hex"
 )
 
 type ChainCodeType int32
@@ -40,7 +41,10 @@ type ChainCode struct {
 	Args         []string
 	ArgBytes     []byte
 	TransientMap map[string][]byte
-	rawArgs      [][]byte
+	// IsInit marks invocation as chaincode initialization. Needed for Fabric 2.x chaincodes that are approved
+	// with init required flag, first invocation after commit must have IsInit set.
+	IsInit  bool
+	rawArgs [][]byte
 }
 
 func (c *ChainCode) toChainCodeArgs() ([][]byte) {
@@ -57,7 +61,8 @@ func (c *ChainCode) toChainCodeArgs() ([][]byte) {
 	return args
 }
 
-// InstallRequest holds fields needed to install chaincode
+// InstallRequest holds fields needed to install chaincode. ChainCodeType selects packaging: GOLANG uses Namespace
+// and Libraries, NODE and JAVA pack SrcPath only.
 type InstallRequest struct {
 	ChannelId        string
 	ChainCodeName    string
@@ -68,11 +73,29 @@ type InstallRequest struct {
 	Libraries        []ChaincodeLibrary
 }
 
+// CollectionConfig is configuration of private data collection.
+// Members of collection are specified either with Organizations (any member of any of them) or with Policy
+// expression like "OR('Org1MSP.member','Org2MSP.member')". If both are provided Policy is used.
+// BlockToLive, MemberOnlyRead and MemberOnlyWrite are not supported by Fabric version gohfc is build with, and
+// collections that set them are rejected instead of silently ignoring them.
 type CollectionConfig struct {
-	Name               string
-	RequiredPeersCount int32
-	MaximumPeersCount  int32
-	Organizations      []string
+	Name               string   `yaml:"name" json:"name"`
+	RequiredPeersCount int32    `yaml:"requiredPeerCount" json:"requiredPeerCount"`
+	MaximumPeersCount  int32    `yaml:"maxPeerCount" json:"maxPeerCount"`
+	Organizations      []string `yaml:"organizations" json:"organizations,omitempty"`
+	Policy             string   `yaml:"policy" json:"policy,omitempty"`
+	BlockToLive        uint64   `yaml:"blockToLive" json:"blockToLive,omitempty"`
+	MemberOnlyRead     bool     `yaml:"memberOnlyRead" json:"memberOnlyRead,omitempty"`
+	MemberOnlyWrite    bool     `yaml:"memberOnlyWrite" json:"memberOnlyWrite,omitempty"`
+	// EndorsementPolicy overrides chaincode endorsement policy for writes to collection. Requires Fabric 2.x.
+	EndorsementPolicy *CollectionEndorsementPolicy `yaml:"endorsementPolicy" json:"endorsementPolicy,omitempty"`
+}
+
+// CollectionEndorsementPolicy is endorsement policy of collection, either signature policy like
+// "OR('Org1MSP.member')" or reference to channel config policy like "/Channel/Application/Endorsement"
+type CollectionEndorsementPolicy struct {
+	SignaturePolicy     string `yaml:"signaturePolicy" json:"signaturePolicy,omitempty"`
+	ChannelConfigPolicy string `yaml:"channelConfigPolicy" json:"channelConfigPolicy,omitempty"`
 }
 
 type ChaincodeLibrary struct {
@@ -85,54 +108,123 @@ type ChainCodesResponse struct {
 	PeerName   string
 	Error      error
 	ChainCodes []*peer.ChaincodeInfo
+	// List holds the same chaincodes as ChainCodes in form that is easier to work with
+	List []*ChainCodeInfo
 }
 
-// createInstallProposal read chaincode from provided source and namespace, pack it and generate install proposal
-// transaction. Transaction is not send from this func
-func createInstallProposal(identity Identity, req *InstallRequest) (*transactionProposal, error) {
+// ChainCodeInfo describes installed or instantiated chaincode
+type ChainCodeInfo struct {
+	Name    string
+	Version string
+	Path    string
+	// PackageId is hex encoded hash of chaincode deployment package as returned from LSCC
+	PackageId string
+	// Input, Escc and Vscc are set only for instantiated chaincodes
+	Input string
+	Escc  string
+	Vscc  string
+}
+
+func newChainCodesResponse(p *PeerResponse) *ChainCodesResponse {
+	ic := &ChainCodesResponse{PeerName: p.Name, Error: p.Err}
+	if p.Err != nil {
+		return ic
+	}
+	if p.Response.Response.GetStatus() != 200 {
+		ic.Error = fmt.Errorf("peer returned status %d: %s", p.Response.Response.GetStatus(), p.Response.Response.GetMessage())
+		return ic
+	}
+	dec, err := decodeChainCodeQueryResponse(p.Response.Response.GetPayload())
+	if err != nil {
+		ic.Error = err
+		return ic
+	}
+	ic.ChainCodes = dec
+	ic.List = make([]*ChainCodeInfo, len(dec))
+	for i, cc := range dec {
+		ic.List[i] = &ChainCodeInfo{
+			Name:      cc.Name,
+			Version:   cc.Version,
+			Path:      cc.Path,
+			PackageId: hex.EncodeToString(cc.Id),
+			Input:     cc.Input,
+			Escc:      cc.Escc,
+			Vscc:      cc.Vscc,
+		}
+	}
+	return ic
+}
 
+// PackageChainCode read chaincode from provided source and namespace and pack it in deployment package.
+// Returned bytes are marshaled ChaincodeDeploymentSpec that can be stored and installed later using
+// InstallChainCodeFromPackage, without access to the source code.
+func PackageChainCode(req *InstallRequest) ([]byte, error) {
 	var packageBytes []byte
 	var err error
 
+	// Go chaincode path is its namespace, Node and Java chaincodes are identified by source path like in peer CLI
+	ccPath := req.SrcPath
 	switch req.ChainCodeType {
 	case ChaincodeSpec_GOLANG:
 		packageBytes, err = packGolangCC(req.Namespace, req.SrcPath, req.Libraries)
-		if err != nil {
-			return nil, err
-		}
+		ccPath = req.Namespace
+	case ChaincodeSpec_NODE:
+		packageBytes, err = packNodeCC(req.SrcPath)
+	case ChaincodeSpec_JAVA:
+		packageBytes, err = packJavaCC(req.SrcPath)
 	default:
 		return nil, ErrUnsupportedChaincodeType
 	}
+	if err != nil {
+		return nil, err
+	}
 	now := time.Now()
-	depSpec, err := proto.Marshal(&peer.ChaincodeDeploymentSpec{
+	return proto.Marshal(&peer.ChaincodeDeploymentSpec{
 		ChaincodeSpec: &peer.ChaincodeSpec{
-			ChaincodeId: &peer.ChaincodeID{Name: req.ChainCodeName, Path: req.Namespace, Version: req.ChainCodeVersion},
+			ChaincodeId: &peer.ChaincodeID{Name: req.ChainCodeName, Path: ccPath, Version: req.ChainCodeVersion},
 			Type:        peer.ChaincodeSpec_Type(req.ChainCodeType),
 		},
 		CodePackage:   packageBytes,
 		EffectiveDate: &timestamp.Timestamp{Seconds: int64(now.Second()), Nanos: int32(now.Nanosecond())},
 	})
+}
+
+// createInstallProposal read chaincode from provided source and namespace, pack it and generate install proposal
+// transaction. Transaction is not send from this func
+func createInstallProposal(identity Identity, req *InstallRequest, opts txOptions) (*transactionProposal, error) {
+	depSpec, err := PackageChainCode(req)
 	if err != nil {
 		return nil, err
 	}
+	return createInstallProposalFromPackage(identity, req.ChannelId, depSpec, opts)
+}
 
-	spec, err := chainCodeInvocationSpec(ChainCode{Type: req.ChainCodeType,
+// createInstallProposalFromPackage generate install proposal for already packed chaincode.
+// Package must be marshaled ChaincodeDeploymentSpec.
+func createInstallProposalFromPackage(identity Identity, channelId string, depSpec []byte, opts txOptions) (*transactionProposal, error) {
+	if err := proto.Unmarshal(depSpec, new(peer.ChaincodeDeploymentSpec)); err != nil {
+		return nil, fmt.Errorf("invalid chaincode package: %v", err)
+	}
+	spec, err := chainCodeInvocationSpec(ChainCode{Type: ChaincodeSpec_GOLANG,
 		Name: LSCC,
 		Args: []string{"install"},
 		ArgBytes: depSpec,
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	creator, err := marshalProtoIdentity(identity)
 	if err != nil {
 		return nil, err
 	}
-	txId, err := newTransactionId(creator)
+	txId, err := newTransactionId(creator, opts)
 	if err != nil {
 		return nil, err
 	}
 	ccHdrExt := &peer.ChaincodeHeaderExtension{ChaincodeId: &peer.ChaincodeID{Name: LSCC}}
 
-	channelHeaderBytes, err := channelHeader(common.HeaderType_ENDORSER_TRANSACTION, txId, req.ChannelId, 0, ccHdrExt)
+	channelHeaderBytes, err := channelHeader(common.HeaderType_ENDORSER_TRANSACTION, txId, channelId, 0, ccHdrExt, opts.tlsCertHash)
 	if err != nil {
 		return nil, err
 	}
@@ -165,7 +257,9 @@ func createInstallProposal(identity Identity, req *InstallRequest) (*transaction
 
 // createInstantiateProposal creates instantiate proposal transaction for already installed chaincode.
 // transaction is not send from this func
-func createInstantiateProposal(identity Identity, req *ChainCode, operation string, collectionConfig []byte) (*transactionProposal, error) {
+// If policy is nil, default policy that requires endorsement from any member of identity MSP is used.
+func createInstantiateProposal(identity Identity, req *ChainCode, operation string, policy *common.SignaturePolicyEnvelope,
+	collectionConfig []byte, opts txOptions) (*transactionProposal, error) {
 	if operation != "deploy" && operation != "upgrade" {
 		return nil, fmt.Errorf("install proposall accept only 'deploy' and 'upgrade' operations")
 	}
@@ -181,9 +275,11 @@ func createInstantiateProposal(identity Identity, req *ChainCode, operation stri
 		return nil, err
 	}
 
-	policy, err := defaultPolicy(identity.MspId)
-	if err != nil {
-		return nil, err
+	if policy == nil {
+		policy, err = defaultPolicy(identity.MspId)
+		if err != nil {
+			return nil, err
+		}
 	}
 	marshPolicy, err := proto.Marshal(policy)
 	if err != nil {
@@ -212,13 +308,13 @@ func createInstantiateProposal(identity Identity, req *ChainCode, operation stri
 	if err != nil {
 		return nil, err
 	}
-	txId, err := newTransactionId(creator)
+	txId, err := newTransactionId(creator, opts)
 	if err != nil {
 		return nil, err
 	}
 	headerExtension := &peer.ChaincodeHeaderExtension{ChaincodeId: &peer.ChaincodeID{Name: LSCC}}
 
-	channelHeaderBytes, err := channelHeader(common.HeaderType_ENDORSER_TRANSACTION, txId, req.ChannelId, 0, headerExtension)
+	channelHeaderBytes, err := channelHeader(common.HeaderType_ENDORSER_TRANSACTION, txId, req.ChannelId, 0, headerExtension, opts.tlsCertHash)
 	if err != nil {
 		return nil, err
 	}
@@ -301,11 +397,13 @@ func packGolangCC(namespace, source string, libs []ChaincodeLibrary) ([]byte, er
 			return nil, err
 		}
 	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
 	_, err := zw.Write(twBuf.Bytes())
 	if err != nil {
 		return nil, err
 	}
-	tw.Close()
 	zw.Close()
 	return gzBuf.Bytes(), nil
 }
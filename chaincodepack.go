@@ -0,0 +1,112 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// metaInfDir holds chaincode metadata like CouchDB indexes, it is packed beside source of Node and Java chaincodes
+const metaInfDir = "META-INF"
+
+// nodeExcludes are directories of Node chaincode that are not packed, peer installs dependencies from package.json
+var nodeExcludes = []string{"node_modules"}
+
+// javaExcludes are build output directories of Gradle and Maven projects, peer builds chaincode itself
+var javaExcludes = []string{"build", "target", "out", ".gradle"}
+
+// packNodeCC packs Node chaincode in npm layout: package.json must be in source root. node_modules are not packed.
+func packNodeCC(source string) ([]byte, error) {
+	if _, err := os.Stat(filepath.Join(source, "package.json")); err != nil {
+		return nil, fmt.Errorf("node chaincode must have package.json in %s: %v", source, err)
+	}
+	return packChaincodeFolder(source, nodeExcludes)
+}
+
+// packJavaCC packs Java chaincode in Gradle or Maven layout: build.gradle, build.gradle.kts or pom.xml must be in
+// source root. Build output is not packed.
+func packJavaCC(source string) ([]byte, error) {
+	for _, f := range []string{"build.gradle", "build.gradle.kts", "pom.xml"} {
+		if _, err := os.Stat(filepath.Join(source, f)); err == nil {
+			return packChaincodeFolder(source, javaExcludes)
+		}
+	}
+	return nil, fmt.Errorf("java chaincode must have build.gradle, build.gradle.kts or pom.xml in %s", source)
+}
+
+// packChaincodeFolder packs files of source under src and META-INF of source under META-INF, same as peer CLI.
+// Top level directories excludes are skipped.
+func packChaincodeFolder(source string, excludes []string) ([]byte, error) {
+	source = filepath.Clean(source)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	err := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		top := strings.SplitN(rel, "/", 2)[0]
+		if info.IsDir() {
+			for _, e := range excludes {
+				if rel == e {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		name := "src/" + rel
+		if top == metaInfDir {
+			name = rel
+		}
+		return writeTarFile(tw, name, path, info)
+	})
+	if err != nil {
+		tw.Close()
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeTarFile writes regular file path as name
+func writeTarFile(tw *tar.Writer, name, path string, info os.FileInfo) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Mode = 0100644
+	header.Uid, header.Gid, header.Uname, header.Gname = 500, 500, "", ""
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(tw, file)
+	return err
+}
@@ -42,7 +42,7 @@ func decodeChannelFromFs(path string) (*common.Envelope, error) {
 }
 
 // buildAndSignChannelConfig take channel config payload and prepare the structure need for join transaction
-func buildAndSignChannelConfig(identity Identity, configPayload []byte, crypto CryptoSuite,channelId string) (*common.Envelope, error) {
+func buildAndSignChannelConfig(identity Identity, configPayload []byte, crypto CryptoSuite,channelId string, opts txOptions) (*common.Envelope, error) {
 
 	pl := &common.Payload{}
 	if err := proto.Unmarshal(configPayload, pl); err != nil {
@@ -58,7 +58,7 @@ func buildAndSignChannelConfig(identity Identity, configPayload []byte, crypto C
 	if err != nil {
 		return nil, err
 	}
-	txId, err := newTransactionId(creator)
+	txId, err := newTransactionId(creator, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -78,7 +78,7 @@ func buildAndSignChannelConfig(identity Identity, configPayload []byte, crypto C
 	configSignature.Signature = sig
 	configUpdateEnvelope.Signatures = append(configUpdateEnvelope.GetSignatures(), configSignature)
 
-	channelHeaderBytes, err := channelHeader(common.HeaderType_CONFIG_UPDATE, txId, channelId,0,nil)
+	channelHeaderBytes, err := channelHeader(common.HeaderType_CONFIG_UPDATE, txId, channelId,0,nil, opts.tlsCertHash)
 	header := header(sigHeaderBytes, channelHeaderBytes)
 
 	envelopeBytes, err := proto.Marshal(configUpdateEnvelope)
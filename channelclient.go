@@ -0,0 +1,103 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import "context"
+
+// Channel is FabricClient bound to one channel. Chaincode name, peers and orderer that are not set in request are
+// taken from channel entry in channels section of client config, or from its default entry.
+type Channel struct {
+	client    *FabricClient
+	channelId string
+}
+
+// Channel returns client for channelId. It is cheap to create and safe for concurrent use.
+func (c *FabricClient) Channel(channelId string) *Channel {
+	return &Channel{client: c, channelId: channelId}
+}
+
+// SetChannelOptions sets options used by Channel for channelId. Use DefaultChannelOptions as channelId to set
+// options for all channels without own entry. Options are replaced by Reload with channels section of config.
+// Hash of options is used for transaction ids and signatures in channel, invalid hash family is error.
+func (c *FabricClient) SetChannelOptions(channelId string, options ChannelOptions) error {
+	if err := validateChannelOptions(map[string]ChannelOptions{channelId: options}); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	channels := make(map[string]ChannelOptions, len(c.channels)+1)
+	for id, o := range c.channels {
+		channels[id] = o
+	}
+	channels[channelId] = options
+	c.channels = channels
+	return nil
+}
+
+// channelOptions returns options of channel or default options
+func (c *FabricClient) channelOptions(channelId string) ChannelOptions {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if options, ok := c.channels[channelId]; ok {
+		return options
+	}
+	return c.channels[DefaultChannelOptions]
+}
+
+// Id returns channel id
+func (ch *Channel) Id() string {
+	return ch.channelId
+}
+
+// Options returns options used for requests in channel
+func (ch *Channel) Options() ChannelOptions {
+	return ch.client.channelOptions(ch.channelId)
+}
+
+// Query executes chaincode query in channel. Empty chainCode.Name is set to channel chaincode.
+func (ch *Channel) Query(identity Identity, chainCode ChainCode) ([]*QueryResponse, error) {
+	return ch.QueryWithContext(context.Background(), identity, chainCode)
+}
+
+// QueryWithContext is same as Query, but request is canceled when ctx is done
+func (ch *Channel) QueryWithContext(ctx context.Context, identity Identity, chainCode ChainCode) ([]*QueryResponse, error) {
+	options, err := ch.prepare(&chainCode)
+	if err != nil {
+		return nil, err
+	}
+	return ch.client.QueryWithContext(ctx, identity, chainCode, options.Peers)
+}
+
+// Invoke sends transaction proposal to channel peers and transaction to channel orderer
+func (ch *Channel) Invoke(identity Identity, chainCode ChainCode) (*InvokeResponse, error) {
+	return ch.InvokeWithContext(context.Background(), identity, chainCode)
+}
+
+// InvokeWithContext is same as Invoke, but request is canceled when ctx is done
+func (ch *Channel) InvokeWithContext(ctx context.Context, identity Identity, chainCode ChainCode) (*InvokeResponse, error) {
+	options, err := ch.prepare(&chainCode)
+	if err != nil {
+		return nil, err
+	}
+	return ch.client.InvokeWithContext(ctx, identity, chainCode, options.Peers, options.Orderer)
+}
+
+// ListenForFilteredBlock listens for filtered blocks of channel from eventPeer
+func (ch *Channel) ListenForFilteredBlock(ctx context.Context, identity Identity, eventPeer string, response chan<- EventBlockResponse) error {
+	return ch.client.ListenForFilteredBlock(ctx, identity, eventPeer, ch.channelId, response)
+}
+
+// prepare sets channel id and default chaincode name of chainCode and returns channel options
+func (ch *Channel) prepare(chainCode *ChainCode) (ChannelOptions, error) {
+	options := ch.client.channelOptions(ch.channelId)
+	chainCode.ChannelId = ch.channelId
+	if chainCode.Name == "" {
+		chainCode.Name = options.ChainCode
+	}
+	if chainCode.Name == "" {
+		return options, ErrChainCodeNameMissing
+	}
+	return options, nil
+}
@@ -0,0 +1,352 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	channelGroupKey     = "Channel"
+	applicationGroupKey = "Application"
+	ordererGroupKey     = "Orderer"
+
+	mspKey              = "MSP"
+	anchorPeersKey      = "AnchorPeers"
+	capabilitiesKey     = "Capabilities"
+	ordererAddressesKey = "OrdererAddresses"
+	hashingAlgorithmKey = "HashingAlgorithm"
+	consensusTypeKey    = "ConsensusType"
+	batchSizeKey        = "BatchSize"
+	batchTimeoutKey     = "BatchTimeout"
+)
+
+// ChannelConfig is decoded channel configuration from config block
+type ChannelConfig struct {
+	ChannelId string
+	// Sequence is the number of config updates applied to the channel
+	Sequence uint64
+	// HashingAlgorithm is the algorithm used for block hashing
+	HashingAlgorithm string
+	// OrdererAddresses are addresses of ordering service nodes
+	OrdererAddresses []string
+	// ConsensusType is the type of ordering service consensus like solo, kafka, etcdraft or BFT
+	ConsensusType string
+	// Consenters are ordering service nodes of etcdraft and BFT channels
+	Consenters []Consenter
+	// BatchSize holds settings for block cutting
+	BatchSize *orderer.BatchSize
+	// BatchTimeout is the time to wait before creating block
+	BatchTimeout string
+	// ChannelCapabilities are capabilities enabled on channel level
+	ChannelCapabilities []string
+	// ApplicationCapabilities are capabilities enabled for application (peers)
+	ApplicationCapabilities []string
+	// OrdererCapabilities are capabilities enabled for ordering service
+	OrdererCapabilities []string
+	// ApplicationOrgs are peer organizations members of the channel, by organization name
+	ApplicationOrgs map[string]*OrgConfig
+	// OrdererOrgs are ordering service organizations, by organization name
+	OrdererOrgs map[string]*OrgConfig
+	// Policies are all policies in the config by their full path like /Channel/Application/Writers
+	Policies map[string]*PolicyConfig
+	// Raw is the config as it is in the block
+	Raw *common.Config
+}
+
+// OrgConfig holds organization MSP definition from channel config
+type OrgConfig struct {
+	Name                 string
+	MspId                string
+	RootCerts            [][]byte
+	IntermediateCerts    [][]byte
+	Admins               [][]byte
+	RevocationList       [][]byte
+	TlsRootCerts         [][]byte
+	TlsIntermediateCerts [][]byte
+	// AnchorPeers are in host:port notation. Only application organizations have anchor peers.
+	AnchorPeers []string
+	// Endpoints are orderer addresses of orderer organization, Fabric 1.4.2+
+	Endpoints []string
+	// SignatureHashFamily is hash family of MSP signatures, SHA2 or SHA3
+	SignatureHashFamily string
+}
+
+// PolicyConfig is decoded policy from channel config.
+// Depending on type one of Signature or ImplicitMeta is set.
+type PolicyConfig struct {
+	Type         string
+	ModPolicy    string
+	Signature    *common.SignaturePolicyEnvelope
+	ImplicitMeta *common.ImplicitMetaPolicy
+}
+
+// GetConfigBlock get latest config block for channel from peer using CSCC and decode channel config from it
+func (c *FabricClient) GetConfigBlock(identity Identity, channelId string, peerName string) (*common.Block, *ChannelConfig, error) {
+	execPeers := c.getPeers([]string{peerName})
+	if len(execPeers) != 1 {
+		return nil, nil, ErrPeerNameNotFound
+	}
+	prop, err := createQueryProposal(identity, ChainCode{
+		ChannelId: channelId,
+		Name:      CSCC,
+		Type:      ChaincodeSpec_GOLANG,
+		Args:      []string{"GetConfigBlock", channelId},
+	}, c.txOptions(channelId))
+	if err != nil {
+		return nil, nil, err
+	}
+	proposal, err := signedProposal(prop.proposal, identity, c.cryptoSuite(identity, channelId))
+	if err != nil {
+		return nil, nil, err
+	}
+	r := sendToPeers(execPeers, proposal)[0]
+	if r.Err != nil {
+		return nil, nil, r.Err
+	}
+	if r.Response.Response.GetStatus() != 200 {
+		return nil, nil, fmt.Errorf("peer %s returned status %d: %s", r.Name, r.Response.Response.GetStatus(), r.Response.Response.GetMessage())
+	}
+	block := new(common.Block)
+	if err := proto.Unmarshal(r.Response.Response.GetPayload(), block); err != nil {
+		return nil, nil, err
+	}
+	config, err := ParseChannelConfigBlock(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, config, nil
+}
+
+// ParseChannelConfigBlock decodes channel config from config block
+func ParseChannelConfigBlock(block *common.Block) (*ChannelConfig, error) {
+	if block == nil || block.Data == nil || len(block.Data.Data) != 1 {
+		return nil, fmt.Errorf("config block must have exactly one transaction")
+	}
+	envelope := new(common.Envelope)
+	if err := proto.Unmarshal(block.Data.Data[0], envelope); err != nil {
+		return nil, err
+	}
+	payload := new(common.Payload)
+	if err := proto.Unmarshal(envelope.Payload, payload); err != nil {
+		return nil, err
+	}
+	if payload.Header == nil {
+		return nil, fmt.Errorf("payload header is missing")
+	}
+	chHeader := new(common.ChannelHeader)
+	if err := proto.Unmarshal(payload.Header.ChannelHeader, chHeader); err != nil {
+		return nil, err
+	}
+	if common.HeaderType(chHeader.Type) != common.HeaderType_CONFIG {
+		return nil, fmt.Errorf("block is not config block, transaction type is: %s", common.HeaderType_name[chHeader.Type])
+	}
+	configEnvelope := new(common.ConfigEnvelope)
+	if err := proto.Unmarshal(payload.Data, configEnvelope); err != nil {
+		return nil, err
+	}
+	config, err := ParseChannelConfig(configEnvelope.Config)
+	if err != nil {
+		return nil, err
+	}
+	config.ChannelId = chHeader.ChannelId
+	return config, nil
+}
+
+// ParseChannelConfig decodes channel config
+func ParseChannelConfig(config *common.Config) (*ChannelConfig, error) {
+	if config == nil || config.ChannelGroup == nil {
+		return nil, fmt.Errorf("config has no channel group")
+	}
+	result := &ChannelConfig{
+		Sequence:        config.Sequence,
+		ApplicationOrgs: make(map[string]*OrgConfig),
+		OrdererOrgs:     make(map[string]*OrgConfig),
+		Policies:        make(map[string]*PolicyConfig),
+		Raw:             config,
+	}
+	root := config.ChannelGroup
+	if err := collectPolicies("/"+channelGroupKey, root, result.Policies); err != nil {
+		return nil, err
+	}
+
+	if v, ok := root.Values[hashingAlgorithmKey]; ok {
+		ha := new(common.HashingAlgorithm)
+		if err := proto.Unmarshal(v.Value, ha); err != nil {
+			return nil, err
+		}
+		result.HashingAlgorithm = ha.Name
+	}
+	if v, ok := root.Values[ordererAddressesKey]; ok {
+		oa := new(common.OrdererAddresses)
+		if err := proto.Unmarshal(v.Value, oa); err != nil {
+			return nil, err
+		}
+		result.OrdererAddresses = oa.Addresses
+	}
+	capabilities, err := decodeCapabilities(root)
+	if err != nil {
+		return nil, err
+	}
+	result.ChannelCapabilities = capabilities
+
+	if app, ok := root.Groups[applicationGroupKey]; ok {
+		if result.ApplicationCapabilities, err = decodeCapabilities(app); err != nil {
+			return nil, err
+		}
+		for name, g := range app.Groups {
+			org, err := decodeOrg(name, g)
+			if err != nil {
+				return nil, err
+			}
+			result.ApplicationOrgs[name] = org
+		}
+	}
+
+	if ord, ok := root.Groups[ordererGroupKey]; ok {
+		if result.OrdererCapabilities, err = decodeCapabilities(ord); err != nil {
+			return nil, err
+		}
+		if v, ok := ord.Values[consensusTypeKey]; ok {
+			ct := new(consensusTypeValue)
+			if err := proto.Unmarshal(v.Value, ct); err != nil {
+				return nil, err
+			}
+			result.ConsensusType = ct.Type
+			if result.Consenters, err = decodeConsenters(ord, ct); err != nil {
+				return nil, err
+			}
+		}
+		if v, ok := ord.Values[batchSizeKey]; ok {
+			bs := new(orderer.BatchSize)
+			if err := proto.Unmarshal(v.Value, bs); err != nil {
+				return nil, err
+			}
+			result.BatchSize = bs
+		}
+		if v, ok := ord.Values[batchTimeoutKey]; ok {
+			bt := new(orderer.BatchTimeout)
+			if err := proto.Unmarshal(v.Value, bt); err != nil {
+				return nil, err
+			}
+			result.BatchTimeout = bt.Timeout
+		}
+		for name, g := range ord.Groups {
+			org, err := decodeOrg(name, g)
+			if err != nil {
+				return nil, err
+			}
+			if org.Endpoints, err = decodeEndpoints(g); err != nil {
+				return nil, err
+			}
+			result.OrdererOrgs[name] = org
+		}
+	}
+	return result, nil
+}
+
+// Org returns application or orderer organization with particular MSP id
+func (cc *ChannelConfig) Org(mspId string) (*OrgConfig, bool) {
+	for _, o := range cc.ApplicationOrgs {
+		if o.MspId == mspId {
+			return o, true
+		}
+	}
+	for _, o := range cc.OrdererOrgs {
+		if o.MspId == mspId {
+			return o, true
+		}
+	}
+	return nil, false
+}
+
+func decodeOrg(name string, group *common.ConfigGroup) (*OrgConfig, error) {
+	org := &OrgConfig{Name: name}
+	if v, ok := group.Values[mspKey]; ok {
+		mspConfig := new(msp.MSPConfig)
+		if err := proto.Unmarshal(v.Value, mspConfig); err != nil {
+			return nil, err
+		}
+		fabricConfig := new(msp.FabricMSPConfig)
+		if err := proto.Unmarshal(mspConfig.Config, fabricConfig); err != nil {
+			return nil, err
+		}
+		org.MspId = fabricConfig.Name
+		org.RootCerts = fabricConfig.RootCerts
+		org.IntermediateCerts = fabricConfig.IntermediateCerts
+		org.Admins = fabricConfig.Admins
+		org.RevocationList = fabricConfig.RevocationList
+		org.TlsRootCerts = fabricConfig.TlsRootCerts
+		org.TlsIntermediateCerts = fabricConfig.TlsIntermediateCerts
+		if fabricConfig.CryptoConfig != nil {
+			org.SignatureHashFamily = fabricConfig.CryptoConfig.SignatureHashFamily
+		}
+	}
+	if v, ok := group.Values[anchorPeersKey]; ok {
+		ap := new(peer.AnchorPeers)
+		if err := proto.Unmarshal(v.Value, ap); err != nil {
+			return nil, err
+		}
+		for _, a := range ap.AnchorPeers {
+			org.AnchorPeers = append(org.AnchorPeers, fmt.Sprintf("%s:%d", a.Host, a.Port))
+		}
+	}
+	return org, nil
+}
+
+func decodeCapabilities(group *common.ConfigGroup) ([]string, error) {
+	v, ok := group.Values[capabilitiesKey]
+	if !ok {
+		return nil, nil
+	}
+	capabilities := new(common.Capabilities)
+	if err := proto.Unmarshal(v.Value, capabilities); err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(capabilities.Capabilities))
+	for name := range capabilities.Capabilities {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// collectPolicies walks config group recursively and decodes all policies by their full path
+func collectPolicies(path string, group *common.ConfigGroup, policies map[string]*PolicyConfig) error {
+	for name, p := range group.Policies {
+		pc := &PolicyConfig{ModPolicy: p.ModPolicy}
+		if p.Policy != nil {
+			pc.Type = common.Policy_PolicyType_name[p.Policy.Type]
+			switch common.Policy_PolicyType(p.Policy.Type) {
+			case common.Policy_SIGNATURE:
+				sp := new(common.SignaturePolicyEnvelope)
+				if err := proto.Unmarshal(p.Policy.Value, sp); err != nil {
+					return err
+				}
+				pc.Signature = sp
+			case common.Policy_IMPLICIT_META:
+				ip := new(common.ImplicitMetaPolicy)
+				if err := proto.Unmarshal(p.Policy.Value, ip); err != nil {
+					return err
+				}
+				pc.ImplicitMeta = ip
+			}
+		}
+		policies[path+"/"+name] = pc
+	}
+	for name, g := range group.Groups {
+		if err := collectPolicies(path+"/"+name, g, policies); err != nil {
+			return err
+		}
+	}
+	return nil
+}
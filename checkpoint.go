@@ -0,0 +1,153 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CheckpointStore persists the number of the next block that consumer must receive. It allows event processing to be
+// resumed after restart from the place where it was stopped.
+type CheckpointStore interface {
+	// Load returns next block number for consumer in channel. Returns false if there is no checkpoint yet.
+	Load(channelId, consumer string) (uint64, bool, error)
+	// Save stores next block number for consumer in channel
+	Save(channelId, consumer string, next uint64) error
+}
+
+// MemoryCheckpointStore keeps checkpoints in memory. Useful for tests and for consumers that do not need to survive
+// restart.
+type MemoryCheckpointStore struct {
+	mu          sync.Mutex
+	checkpoints map[string]uint64
+}
+
+// Load returns checkpoint from memory
+func (m *MemoryCheckpointStore) Load(channelId, consumer string) (uint64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	next, ok := m.checkpoints[channelId+"/"+consumer]
+	return next, ok, nil
+}
+
+// Save stores checkpoint in memory
+func (m *MemoryCheckpointStore) Save(channelId, consumer string, next uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.checkpoints == nil {
+		m.checkpoints = make(map[string]uint64)
+	}
+	m.checkpoints[channelId+"/"+consumer] = next
+	return nil
+}
+
+// FileCheckpointStore keeps every checkpoint in separate file in Dir. Files are replaced atomically, so checkpoint
+// is never partially written.
+type FileCheckpointStore struct {
+	Dir string
+}
+
+// Load reads checkpoint from file
+func (f *FileCheckpointStore) Load(channelId, consumer string) (uint64, bool, error) {
+	data, err := ioutil.ReadFile(f.path(channelId, consumer))
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	next, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	return next, true, nil
+}
+
+// Save writes checkpoint to temporary file and renames it over the previous one
+func (f *FileCheckpointStore) Save(channelId, consumer string, next uint64) error {
+	path := f.path(channelId, consumer)
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(strconv.FormatUint(next, 10)), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (f *FileCheckpointStore) path(channelId, consumer string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(channelId + "_" + consumer)
+	return filepath.Join(f.Dir, name+".checkpoint")
+}
+
+// ObjectCheckpointStore keeps every checkpoint as object <Prefix><channelId>/<consumer>.checkpoint in S3 or Google
+// Cloud Storage, so stateless containers can resume event processing without persistent volumes.
+// Writes are conditional on the version that was loaded or saved last by this store. If another instance of the same
+// consumer saved checkpoint in between, Save returns ErrCheckpointConflict and the consumer must Load again.
+type ObjectCheckpointStore struct {
+	Client *S3Client
+	Prefix string
+
+	mu       sync.Mutex
+	versions map[string]string
+}
+
+// Load downloads checkpoint and remembers its version
+func (o *ObjectCheckpointStore) Load(channelId, consumer string) (uint64, bool, error) {
+	key := o.key(channelId, consumer)
+	data, version, err := o.Client.GetObject(context.Background(), key)
+	if errors.Is(err, ErrObjectNotFound) {
+		o.setVersion(key, "")
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	next, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	o.setVersion(key, version)
+	return next, true, nil
+}
+
+// Save uploads checkpoint if it was not changed since the last Load or Save
+func (o *ObjectCheckpointStore) Save(channelId, consumer string, next uint64) error {
+	key := o.key(channelId, consumer)
+	o.mu.Lock()
+	version := o.versions[key]
+	o.mu.Unlock()
+	ifNoneMatch := ""
+	if version == "" {
+		ifNoneMatch = "*"
+	}
+	version, err := o.Client.PutObject(context.Background(), key, []byte(strconv.FormatUint(next, 10)), version, ifNoneMatch)
+	if errors.Is(err, ErrPreconditionFailed) {
+		return ErrCheckpointConflict
+	}
+	if err != nil {
+		return err
+	}
+	o.setVersion(key, version)
+	return nil
+}
+
+func (o *ObjectCheckpointStore) setVersion(key, version string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.versions == nil {
+		o.versions = make(map[string]string)
+	}
+	o.versions[key] = version
+}
+
+func (o *ObjectCheckpointStore) key(channelId, consumer string) string {
+	return o.Prefix + channelId + "/" + strings.NewReplacer("/", "_", "\\", "_").Replace(consumer) + ".checkpoint"
+}
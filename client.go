@@ -12,21 +12,63 @@ import (
 	"github.com/hyperledger/fabric/protos/orderer"
 	"context"
 	"fmt"
+	"sync"
 )
 
-// FabricClient expose API's to work with Hyperledger Fabric
+// FabricClient expose API's to work with Hyperledger Fabric.
+// FabricClient is safe for concurrent use by multiple goroutines. Exported fields must be set before first request,
+// peers and orderers are replaced only by Reload. There is no package level client, every FabricClient is
+// independent and many can be used in the same process. Only logger, metrics, tracer and registries of TLS types and
+// in process listeners are shared by all clients.
 type FabricClient struct {
 	Crypto     CryptoSuite
 	Peers      map[string]*Peer
 	Orderers   map[string]*Orderer
 	EventPeers map[string]*Peer
+	// EventDecoders decode chaincode event payloads received by ListenForFullBlock. Optional.
+	EventDecoders *EventDecoderRegistry
+	// AnomalyDetector collects per peer results when set. Optional.
+	AnomalyDetector *AnomalyDetector
+	// BlockStore is the last fallback for block queries when peers do not have the block. Optional.
+	BlockStore BlockStore
+	// BlockArchive stores every block received by ListenForFullBlock and ListenWithAck. Optional.
+	BlockArchive BlockArchive
+	// EventBuffer controls what ListenForFullBlock, ListenForFilteredBlock and ListenBlocks do when consumer is
+	// slow. Default blocks reading of block stream until consumer receives block.
+	EventBuffer EventBufferConfig
+	// Tenants are used for requests with tenant attached to context by WithTenant. Optional.
+	Tenants *TenantRegistry
+	// archivePeers are peers queried for blocks that other peers do not have
+	archivePeers []string
+	// mu guards Peers, Orderers and EventPeers when they are replaced by config reload
+	mu           sync.RWMutex
+	warmUpReport *WarmUpReport
+	// txMu guards txListeners, shared listeners used from RegisterTxStatusEvent, and closed
+	txMu        sync.Mutex
+	txListeners map[string]*TxStatusListener
+	// closed is set by Close, txListenersCancel stops shared listeners
+	closed            bool
+	txListenersCtx    context.Context
+	txListenersCancel context.CancelFunc
+	// defaults are peers and orderer for requests without them, guarded by mu
+	defaults *requestDefaults
+	// channels are per channel options used by Channel, guarded by mu
+	channels map[string]ChannelOptions
+	// consenters are addresses of consenters by channel from the last SyncConsenters, guarded by mu
+	consenters map[string]map[string]bool
+	// syncedOrderers are names of orderers added by SyncConsenters, only they are removed by it, guarded by mu
+	syncedOrderers map[string]bool
+	// clientTls is client TLS certificate presented to peers and orderers of client, guarded by mu
+	clientTls *clientTls
+	// nonceSource is set by SetNonceSource, guarded by mu
+	nonceSource NonceSource
 }
 
 // CreateUpdateChannel read channel config generated (usually) from configtxgen and send it to orderer
 // This step is needed before any peer is able to join the channel and before any future updates of the channel.
 func (c *FabricClient) CreateUpdateChannel(identity Identity, path string, channelId string, orderer string) (error) {
 
-	ord, ok := c.Orderers[orderer]
+	ord, ok := c.getOrderer(orderer)
 	if !ok {
 		return ErrInvalidOrdererName
 	}
@@ -35,7 +77,8 @@ func (c *FabricClient) CreateUpdateChannel(identity Identity, path string, chann
 	if err != nil {
 		return err
 	}
-	ou, err := buildAndSignChannelConfig(identity, envelope.GetPayload(), c.Crypto, channelId)
+	ou, err := buildAndSignChannelConfig(identity, envelope.GetPayload(), c.cryptoSuite(identity, channelId), channelId,
+		c.txOptions(channelId))
 	if err != nil {
 		return err
 	}
@@ -53,7 +96,7 @@ func (c *FabricClient) CreateUpdateChannel(identity Identity, path string, chann
 // Channel must be created before this operation using `CreateUpdateChannel` or manually using CLI interface.
 // Orderers must be aware of this channel, otherwise operation will fail.
 func (c *FabricClient) JoinChannel(identity Identity, channelId string, peers []string, orderer string) ([]*PeerResponse, error) {
-	ord, ok := c.Orderers[orderer]
+	ord, ok := c.getOrderer(orderer)
 	if !ok {
 		return nil, ErrInvalidOrdererName
 	}
@@ -63,7 +106,7 @@ func (c *FabricClient) JoinChannel(identity Identity, channelId string, peers []
 		return nil, ErrPeerNameNotFound
 	}
 
-	block, err := ord.getGenesisBlock(identity, c.Crypto, channelId)
+	block, err := ord.getGenesisBlock(identity, c.cryptoSuite(identity, channelId), channelId, c.txOptions(channelId).sentTo(ord))
 
 	if err != nil {
 		return nil, err
@@ -87,12 +130,13 @@ func (c *FabricClient) JoinChannel(identity Identity, channelId string, peers []
 	if err != nil {
 		return nil, err
 	}
-	txId, err := newTransactionId(creator)
+	opts := c.txOptions(channelId)
+	txId, err := newTransactionId(creator, opts)
 	if err != nil {
 		return nil, err
 	}
 	ext := &peer.ChaincodeHeaderExtension{ChaincodeId: &peer.ChaincodeID{Name: CSCC}}
-	channelHeaderBytes, err := channelHeader(common.HeaderType_ENDORSER_TRANSACTION, txId, "", 0, ext)
+	channelHeaderBytes, err := channelHeader(common.HeaderType_ENDORSER_TRANSACTION, txId, "", 0, ext, opts.tlsCertHash)
 	if err != nil {
 		return nil, err
 	}
@@ -120,7 +164,7 @@ func (c *FabricClient) JoinChannel(identity Identity, channelId string, peers []
 		return nil, err
 	}
 
-	proposal, err := signedProposal(proposalBytes, identity, c.Crypto)
+	proposal, err := signedProposal(proposalBytes, identity, c.cryptoSuite(identity, channelId))
 	if err != nil {
 		return nil, err
 	}
@@ -133,11 +177,11 @@ func (c *FabricClient) InstallChainCode(identity Identity, req *InstallRequest,
 	if len(peers) != len(execPeers) {
 		return nil, ErrPeerNameNotFound
 	}
-	prop, err := createInstallProposal(identity, req)
+	prop, err := createInstallProposal(identity, req, c.txOptions(req.ChannelId))
 	if err != nil {
 		return nil, err
 	}
-	proposal, err := signedProposal(prop.proposal, identity, c.Crypto)
+	proposal, err := signedProposal(prop.proposal, identity, c.cryptoSuite(identity, ""))
 	if err != nil {
 		return nil, err
 	}
@@ -145,6 +189,24 @@ func (c *FabricClient) InstallChainCode(identity Identity, req *InstallRequest,
 
 }
 
+// InstallChainCodeFromPackage install already packed chaincode to one or many peers.
+// Package is created using PackageChainCode, usually in different stage of CI pipeline.
+func (c *FabricClient) InstallChainCodeFromPackage(identity Identity, channelId string, pkg []byte, peers []string) ([]*PeerResponse, error) {
+	execPeers := c.getPeers(peers)
+	if len(peers) != len(execPeers) {
+		return nil, ErrPeerNameNotFound
+	}
+	prop, err := createInstallProposalFromPackage(identity, channelId, pkg, c.txOptions(channelId))
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := signedProposal(prop.proposal, identity, c.cryptoSuite(identity, channelId))
+	if err != nil {
+		return nil, err
+	}
+	return sendToPeers(execPeers, proposal), nil
+}
+
 // InstantiateChainCode run installed chainCode to particular peer in particular channel.
 // Chaincode must be installed using InstallChainCode or CLI interface before this operation.
 // If this is first time running the chaincode operation must be `deploy`
@@ -153,7 +215,30 @@ func (c *FabricClient) InstallChainCode(identity Identity, req *InstallRequest,
 // will be created. collectionsConfig can be specified when chaincode is upgraded.
 func (c *FabricClient) InstantiateChainCode(identity Identity, req *ChainCode, peers []string, orderer string,
 	operation string, collectionsConfig []CollectionConfig) (*orderer.BroadcastResponse, error) {
-	ord, ok := c.Orderers[orderer]
+	return c.instantiateChainCode(identity, req, peers, orderer, operation, nil, collectionsConfig)
+}
+
+// UpgradeChainCode upgrade instantiated chainCode to new version. New version must be installed using
+// InstallChainCode before this operation. req.Args are passed to chaincode Init.
+// policy is endorsement policy in the format used by peer CLI, for example "AND('Org1MSP.member','Org2MSP.member')".
+// If policy is empty, policy that requires endorsement from any member of identity MSP is used.
+// collectionsConfig is the new configuration for private collections and can be nil if collections are not used.
+func (c *FabricClient) UpgradeChainCode(identity Identity, req *ChainCode, policy string, peers []string, orderer string,
+	collectionsConfig []CollectionConfig) (*orderer.BroadcastResponse, error) {
+	var signaturePolicy *common.SignaturePolicyEnvelope
+	if len(policy) > 0 {
+		var err error
+		signaturePolicy, err = ParsePolicy(policy)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c.instantiateChainCode(identity, req, peers, orderer, "upgrade", signaturePolicy, collectionsConfig)
+}
+
+func (c *FabricClient) instantiateChainCode(identity Identity, req *ChainCode, peers []string, orderer string,
+	operation string, policy *common.SignaturePolicyEnvelope, collectionsConfig []CollectionConfig) (*orderer.BroadcastResponse, error) {
+	ord, ok := c.getOrderer(orderer)
 	if !ok {
 		return nil, ErrInvalidOrdererName
 	}
@@ -164,32 +249,29 @@ func (c *FabricClient) InstantiateChainCode(identity Identity, req *ChainCode, p
 	}
 	var collConfigBytes []byte
 	if len(collectionsConfig) > 0 {
-		collectionPolicy, err := CollectionConfigToPolicy(collectionsConfig)
-		if err != nil {
-			return nil, err
-		}
-		collConfigBytes, err = proto.Marshal(&common.CollectionConfigPackage{Config: collectionPolicy})
+		var err error
+		collConfigBytes, err = MarshalCollectionsConfig(collectionsConfig)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	prop, err := createInstantiateProposal(identity, req, operation, collConfigBytes)
+	prop, err := createInstantiateProposal(identity, req, operation, policy, collConfigBytes, c.txOptions(req.ChannelId))
 	if err != nil {
 		return nil, err
 	}
 
-	proposal, err := signedProposal(prop.proposal, identity, c.Crypto)
+	proposal, err := signedProposal(prop.proposal, identity, c.cryptoSuite(identity, req.ChannelId))
 	if err != nil {
 		return nil, err
 	}
 
-	transaction, err := createTransaction(prop.proposal, sendToPeers(execPeers, proposal))
+	transaction, err := createTransaction(prop, sendToPeers(execPeers, proposal))
 	if err != nil {
 		return nil, err
 	}
 
-	signedTransaction, err := c.Crypto.Sign(transaction, identity.PrivateKey)
+	signedTransaction, err := c.cryptoSuite(identity, req.ChannelId).Sign(transaction, identity.PrivateKey)
 	if err != nil {
 		return nil, err
 	}
@@ -201,7 +283,9 @@ func (c *FabricClient) InstantiateChainCode(identity Identity, req *ChainCode, p
 	return reply, nil
 }
 
-// QueryInstalledChainCodes get all chainCodes that are installed but not instantiated in one or many peers
+// QueryInstalledChainCodes get all chainCodes that are installed but not instantiated in one or many peers.
+// Only chaincodes installed using LSCC are returned, packages installed with Fabric v2 `_lifecycle` are returned by
+// QueryInstalledChaincodes.
 func (c *FabricClient) QueryInstalledChainCodes(identity Identity, peers []string) ([]*ChainCodesResponse, error) {
 	execPeers := c.getPeers(peers)
 	if len(peers) != len(execPeers) {
@@ -216,12 +300,12 @@ func (c *FabricClient) QueryInstalledChainCodes(identity Identity, peers []strin
 		Args: []string{"getinstalledchaincodes"},
 	}
 
-	prop, err := createTransactionProposal(identity, chainCode)
+	prop, err := createQueryProposal(identity, chainCode, c.txOptions(chainCode.ChannelId))
 	if err != nil {
 		return nil, err
 	}
 
-	proposal, err := signedProposal(prop.proposal, identity, c.Crypto)
+	proposal, err := signedProposal(prop.proposal, identity, c.cryptoSuite(identity, ""))
 	if err != nil {
 		return nil, err
 	}
@@ -229,17 +313,7 @@ func (c *FabricClient) QueryInstalledChainCodes(identity Identity, peers []strin
 
 	response := make([]*ChainCodesResponse, len(r))
 	for idx, p := range r {
-		ic := ChainCodesResponse{PeerName: p.Name, Error: p.Err}
-		if p.Err != nil {
-			ic.Error = p.Err
-		} else {
-			dec, err := decodeChainCodeQueryResponse(p.Response.Response.GetPayload())
-			if err != nil {
-				ic.Error = err
-			}
-			ic.ChainCodes = dec
-		}
-		response[idx] = &ic
+		response[idx] = newChainCodesResponse(p)
 	}
 	return response, nil
 }
@@ -251,33 +325,23 @@ func (c *FabricClient) QueryInstantiatedChainCodes(identity Identity, channelId
 		return nil, ErrPeerNameNotFound
 	}
 
-	prop, err := createTransactionProposal(identity, ChainCode{
+	prop, err := createQueryProposal(identity, ChainCode{
 		ChannelId: channelId,
 		Name:      LSCC,
 		Type:      ChaincodeSpec_GOLANG,
 		Args:      []string{"getchaincodes"},
-	})
+	}, c.txOptions(channelId))
 	if err != nil {
 		return nil, err
 	}
-	proposal, err := signedProposal(prop.proposal, identity, c.Crypto)
+	proposal, err := signedProposal(prop.proposal, identity, c.cryptoSuite(identity, channelId))
 	if err != nil {
 		return nil, err
 	}
 	r := sendToPeers(execPeers, proposal)
 	response := make([]*ChainCodesResponse, len(r))
 	for idx, p := range r {
-		ic := ChainCodesResponse{PeerName: p.Name, Error: p.Err}
-		if p.Err != nil {
-			ic.Error = p.Err
-		} else {
-			dec, err := decodeChainCodeQueryResponse(p.Response.Response.GetPayload())
-			if err != nil {
-				ic.Error = err
-			}
-			ic.ChainCodes = dec
-		}
-		response[idx] = &ic
+		response[idx] = newChainCodesResponse(p)
 	}
 	return response, nil
 }
@@ -295,11 +359,11 @@ func (c *FabricClient) QueryChannels(identity Identity, peers []string) ([]*Quer
 		Args: []string{"GetChannels"},
 	}
 
-	prop, err := createTransactionProposal(identity, chainCode)
+	prop, err := createQueryProposal(identity, chainCode, c.txOptions(chainCode.ChannelId))
 	if err != nil {
 		return nil, err
 	}
-	proposal, err := signedProposal(prop.proposal, identity, c.Crypto)
+	proposal, err := signedProposal(prop.proposal, identity, c.cryptoSuite(identity, ""))
 	if err != nil {
 		return nil, err
 	}
@@ -308,7 +372,7 @@ func (c *FabricClient) QueryChannels(identity Identity, peers []string) ([]*Quer
 	for _, pr := range r {
 		peerResponse := QueryChannelsResponse{PeerName: pr.Name}
 		if pr.Err != nil {
-			peerResponse.Error = err
+			peerResponse.Error = pr.Err
 		} else {
 			channels := new(peer.ChannelQueryResponse)
 			if err := proto.Unmarshal(pr.Response.Response.Payload, channels); err != nil {
@@ -326,67 +390,50 @@ func (c *FabricClient) QueryChannels(identity Identity, peers []string) ([]*Quer
 	return response, nil
 }
 
-// QueryChannelInfo get current block height, current hash and prev hash about particular channel in peer/s
+// QueryChannelInfo get current block height, current hash and prev hash about particular channel in peer/s.
+// It is the same as QueryChainInfo, responses with status other than 200 are returned as errors.
 func (c *FabricClient) QueryChannelInfo(identity Identity, channelId string, peers []string) ([]*QueryChannelInfoResponse, error) {
-	execPeers := c.getPeers(peers)
-	if len(peers) != len(execPeers) {
-		return nil, ErrPeerNameNotFound
-	}
-	chainCode := ChainCode{
-		ChannelId: channelId,
-		Name:      QSCC,
-		Type:      ChaincodeSpec_GOLANG,
-		Args:      []string{"GetChainInfo", channelId},
-	}
-
-	prop, err := createTransactionProposal(identity, chainCode)
-	if err != nil {
-		return nil, err
-	}
-	proposal, err := signedProposal(prop.proposal, identity, c.Crypto)
-	if err != nil {
-		return nil, err
-	}
-	r := sendToPeers(execPeers, proposal)
-
-	response := make([]*QueryChannelInfoResponse, 0, len(r))
-	for _, pr := range r {
-		peerResponse := QueryChannelInfoResponse{PeerName: pr.Name}
-		if pr.Err != nil {
-			peerResponse.Error = err
-		} else {
-			bci := new(common.BlockchainInfo)
-			if err := proto.Unmarshal(pr.Response.Response.Payload, bci); err != nil {
-				peerResponse.Error = err
-
-			} else {
-				peerResponse.Info = bci
-			}
-		}
-		response = append(response, &peerResponse)
-	}
-	return response, nil
-
+	return c.QueryChainInfo(identity, channelId, peers)
 }
 
 // Query execute chainCode to one or many peers and return there responses without sending
 // them to orderer for transaction - ReadOnly operation.
+// Proposals created for queries are marked as read-only and cannot be send to orderer, and `QueryResponse` does not
+// carry anything that can be broadcast.
 // Because is expected all peers to be in same state this function allows very easy horizontal scaling by
 // distributing query operations between peers.
 func (c *FabricClient) Query(identity Identity, chainCode ChainCode, peers []string) ([]*QueryResponse, error) {
+	return c.QueryWithContext(context.Background(), identity, chainCode, peers)
+}
+
+// QueryWithContext is same as Query, but request is canceled when ctx is done. Peers can be overridden with
+// routing attached to ctx using WithRouting.
+func (c *FabricClient) QueryWithContext(ctx context.Context, identity Identity, chainCode ChainCode, peers []string) (resp []*QueryResponse, err error) {
+	ctx, span := startSpan(ctx, "gohfc.Query", "channel", chainCode.ChannelId, "chaincode", chainCode.Name)
+	defer func() { endSpan(span, err) }()
+	ctx, peers, _, cancel := applyRouting(ctx, peers, "")
+	defer cancel()
+	peers, _ = c.applyDefaults(peers, "", false)
+	identity, release, err := c.enforceTenant(ctx, identity, chainCode.ChannelId, chainCode.Name, peers, "")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	execPeers := c.getPeers(peers)
 	if len(peers) != len(execPeers) {
 		return nil, ErrPeerNameNotFound
 	}
-	prop, err := createTransactionProposal(identity, chainCode)
+	prop, err := createQueryProposal(identity, chainCode, c.txOptions(chainCode.ChannelId))
 	if err != nil {
 		return nil, err
 	}
-	proposal, err := signedProposal(prop.proposal, identity, c.Crypto)
+	proposal, err := signedProposal(prop.proposal, identity, c.cryptoSuite(identity, chainCode.ChannelId))
 	if err != nil {
 		return nil, err
 	}
-	r := sendToPeers(execPeers, proposal)
+	metrics().ProposalSent(chainCode.ChannelId, chainCode.Name)
+	r := sendToPeersWithContext(ctx, execPeers, proposal)
+	c.observeEndorsements(r)
 	response := make([]*QueryResponse, len(r))
 	for idx, p := range r {
 		ic := QueryResponse{PeerName: p.Name, Error: p.Err}
@@ -394,6 +441,8 @@ func (c *FabricClient) Query(identity Identity, chainCode ChainCode, peers []str
 			ic.Error = p.Err
 		} else {
 			ic.Response = p.Response
+			cr := newChaincodeResponse(p.Name, p.Response)
+			ic.Status, ic.Message, ic.Payload = cr.Status, cr.Message, cr.Payload
 		}
 		response[idx] = &ic
 	}
@@ -408,7 +457,23 @@ func (c *FabricClient) Query(identity Identity, chainCode ChainCode, peers []str
 // In such case Invoke will return the error and transaction will NOT be send to orderer. This transaction will NOT be
 // committed to blockchain.
 func (c *FabricClient) Invoke(identity Identity, chainCode ChainCode, peers []string, orderer string) (*InvokeResponse, error) {
-	ord, ok := c.Orderers[orderer]
+	return c.InvokeWithContext(context.Background(), identity, chainCode, peers, orderer)
+}
+
+// InvokeWithContext is same as Invoke, but request is canceled when ctx is done. Peers and orderer can be overridden
+// with routing attached to ctx using WithRouting.
+func (c *FabricClient) InvokeWithContext(ctx context.Context, identity Identity, chainCode ChainCode, peers []string, orderer string) (resp *InvokeResponse, err error) {
+	ctx, span := startSpan(ctx, "gohfc.Invoke", "channel", chainCode.ChannelId, "chaincode", chainCode.Name)
+	defer func() { endSpan(span, err) }()
+	ctx, peers, orderer, cancel := applyRouting(ctx, peers, orderer)
+	defer cancel()
+	peers, orderer = c.applyDefaults(peers, orderer, true)
+	identity, release, err := c.enforceTenant(ctx, identity, chainCode.ChannelId, chainCode.Name, peers, orderer)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	ord, ok := c.getOrderer(orderer)
 	if !ok {
 		return nil, ErrInvalidOrdererName
 	}
@@ -416,67 +481,51 @@ func (c *FabricClient) Invoke(identity Identity, chainCode ChainCode, peers []st
 	if len(peers) != len(execPeers) {
 		return nil, ErrPeerNameNotFound
 	}
-	prop, err := createTransactionProposal(identity, chainCode)
-	if err != nil {
+	if err := checkWritersFromContext(ctx, chainCode.ChannelId, identity); err != nil {
 		return nil, err
 	}
-	proposal, err := signedProposal(prop.proposal, identity, c.Crypto)
+	if committed, err := c.checkCommitted(ctx, identity, chainCode.ChannelId, peers); committed != nil || err != nil {
+		return committed, err
+	}
+	_, buildSpan := startSpan(ctx, "gohfc.BuildProposal")
+	prop, err := createTransactionProposalWithId(identity, chainCode, txIdFromContext(ctx), c.txOptions(chainCode.ChannelId))
 	if err != nil {
+		endSpan(buildSpan, err)
 		return nil, err
 	}
-	transaction, err := createTransaction(prop.proposal, sendToPeers(execPeers, proposal))
+	proposal, err := signedProposal(prop.proposal, identity, c.cryptoSuite(identity, chainCode.ChannelId))
+	endSpan(buildSpan, err)
 	if err != nil {
 		return nil, err
 	}
-	signedTransaction, err := c.Crypto.Sign(transaction, identity.PrivateKey)
-	if err != nil {
+	span.SetAttributes("txId", prop.transactionId)
+	metrics().ProposalSent(chainCode.ChannelId, chainCode.Name)
+	endorsements := sendToPeersWithContext(ctx, execPeers, proposal)
+	c.observeEndorsements(endorsements)
+	if err := verifyEndorsementsFromContext(ctx, endorsements); err != nil {
 		return nil, err
 	}
-	reply, err := ord.Broadcast(&common.Envelope{Payload: transaction, Signature: signedTransaction})
+	transaction, err := createTransaction(prop, endorsements)
 	if err != nil {
 		return nil, err
 	}
-	return &InvokeResponse{Status: reply.Status, TxID: prop.transactionId}, nil
-}
-
-// QueryTransaction get data for particular transaction.
-// TODO for now it only returns status of the transaction, and not the whole data (payload, endorsement etc)
-func (c *FabricClient) QueryTransaction(identity Identity, channelId string, txId string, peers []string) ([]*QueryTransactionResponse, error) {
-	execPeers := c.getPeers(peers)
-	if len(peers) != len(execPeers) {
-		return nil, ErrPeerNameNotFound
-	}
-	chainCode := ChainCode{
-		ChannelId: channelId,
-		Name:      QSCC,
-		Type:      ChaincodeSpec_GOLANG,
-		Args:      []string{"GetTransactionByID", channelId, txId}}
-
-	prop, err := createTransactionProposal(identity, chainCode)
+	signedTransaction, err := c.cryptoSuite(identity, chainCode.ChannelId).Sign(transaction, identity.PrivateKey)
 	if err != nil {
 		return nil, err
 	}
-	proposal, err := signedProposal(prop.proposal, identity, c.Crypto)
+	reply, err := broadcastEnvelope(ctx, ord, &common.Envelope{Payload: transaction, Signature: signedTransaction})
 	if err != nil {
+		logger().Warn("transaction not accepted", "txId", prop.transactionId, "orderer", orderer, "error", err)
 		return nil, err
 	}
-	r := sendToPeers(execPeers, proposal)
-	fmt.Println(r)
-	response := make([]*QueryTransactionResponse, len(r))
-	for idx, p := range r {
-		qtr := QueryTransactionResponse{PeerName: p.Name, Error: p.Err}
-		if p.Err != nil {
-			qtr.Error = p.Err
-		} else {
-			dec, err := decodeTransaction(p.Response.Response.GetPayload())
-			if err != nil {
-				qtr.Error = err
-			}
-			qtr.StatusCode = dec
-		}
-		response[idx] = &qtr
+	logger().Debug("transaction submitted", "txId", prop.transactionId, "channel", chainCode.ChannelId,
+		"chaincode", chainCode.Name, "orderer", orderer)
+	responses := make([]ChaincodeResponse, len(endorsements))
+	for i, e := range endorsements {
+		responses[i] = newChaincodeResponse(e.Name, e.Response)
 	}
-	return response, nil
+	return &InvokeResponse{Status: reply.Status, TxID: prop.transactionId, Payload: responses[0].Payload,
+		Message: responses[0].Message, Responses: responses, Info: reply.Info}, nil
 }
 
 // ListenForFullBlock will listen for events when new block is committed to blockchain and will return block height,
@@ -490,15 +539,30 @@ func (c *FabricClient) QueryTransaction(identity Identity, channelId string, txI
 // User can listen for same events in same channel in multiple peers for redundancy using same `chan<- EventBlockResponse`
 // In this case every peer will send its events, so identical events may appear more than once in channel.
 func (c *FabricClient) ListenForFullBlock(ctx context.Context, identity Identity, eventPeer, channelId string, response chan<- EventBlockResponse) (error) {
-	ep, ok := c.EventPeers[eventPeer]
+	return c.listenForBlocks(ctx, identity, eventPeer, channelId, EventTypeFullBlock, nil, response)
+}
+
+// listenForBlocks starts listener of listenerType from block from, or from the newest block when from is nil
+func (c *FabricClient) listenForBlocks(ctx context.Context, identity Identity, eventPeer, channelId string, listenerType int,
+	from *uint64, response chan<- EventBlockResponse) error {
+	ep, ok := c.getEventPeer(eventPeer)
 	if !ok {
 		return ErrPeerNameNotFound
 	}
-	listener, err := NewEventListener(ctx, c.Crypto, identity, *ep, channelId, EventTypeFullBlock)
+	listener, err := NewEventListener(ctx, c.cryptoSuite(identity, channelId), identity, *ep, channelId, listenerType)
 	if err != nil {
 		return err
 	}
-	err = listener.SeekNewest()
+	if listenerType == EventTypeFullBlock {
+		listener.Decoders = c.EventDecoders
+		listener.Archive = c.BlockArchive
+	}
+	listener.Buffer = c.EventBuffer
+	if from != nil {
+		err = listener.SeekFrom(*from)
+	} else {
+		err = listener.SeekNewest()
+	}
 	if err != nil {
 		return err
 	}
@@ -506,73 +570,122 @@ func (c *FabricClient) ListenForFullBlock(ctx context.Context, identity Identity
 	return nil
 }
 
-// ListenForFilteredBlock listen for events in blockchain. Difference with `ListenForFullBlock` is that event names
-// will be returned but NOT events data. Also full block data will not be available.
-// Other options are same as `ListenForFullBlock`.
-func (c *FabricClient) ListenForFilteredBlock(ctx context.Context, identity Identity, eventPeer, channelId string, response chan<- EventBlockResponse) (error) {
-	ep, ok := c.EventPeers[eventPeer]
+// ListenBlocks sends raw blocks of channel to response, starting with block fromBlock and continuing with new blocks
+// as they are committed. Blocks are not decoded, so services that sync or archive ledger get them as stored by peer.
+// Blocks are also stored in FabricClient.BlockArchive when it is set.
+// Block with error is send when delivery fails, after that no more blocks are send. To stop listening cancel ctx.
+func (c *FabricClient) ListenBlocks(ctx context.Context, identity Identity, eventPeer, channelId string, fromBlock uint64,
+	response chan<- BlockResponse) error {
+	ep, ok := c.getEventPeer(eventPeer)
 	if !ok {
 		return ErrPeerNameNotFound
 	}
-	listener, err := NewEventListener(ctx, c.Crypto, identity, *ep, channelId, EventTypeFiltered)
+	listener, err := NewEventListener(ctx, c.cryptoSuite(identity, channelId), identity, *ep, channelId, EventTypeFullBlock)
 	if err != nil {
 		return err
 	}
-	err = listener.SeekNewest()
-	if err != nil {
+	listener.Archive = c.BlockArchive
+	listener.Buffer = c.EventBuffer
+	if err := listener.SeekFrom(fromBlock); err != nil {
 		return err
 	}
-	listener.Listen(response)
+	listener.ListenRaw(response)
 	return nil
 }
 
+// ListenForFilteredBlock listen for events in blockchain. Difference with `ListenForFullBlock` is that event names
+// will be returned but NOT events data. Also full block data will not be available.
+// Other options are same as `ListenForFullBlock`.
+func (c *FabricClient) ListenForFilteredBlock(ctx context.Context, identity Identity, eventPeer, channelId string, response chan<- EventBlockResponse) (error) {
+	return c.listenForBlocks(ctx, identity, eventPeer, channelId, EventTypeFiltered, nil, response)
+}
+
 
 // NewFabricClientFromConfig create a new FabricClient from ClientConfig
 func NewFabricClientFromConfig(config ClientConfig) (*FabricClient, error) {
-	var crypto CryptoSuite
-	var err error
-	switch config.CryptoConfig.Family {
-	case "ecdsa":
-		crypto, err = NewECCryptSuiteFromConfig(config.CryptoConfig)
-		if err != nil {
-			return nil, err
+	crypto, err := NewCryptoSuiteFromConfig(config.CryptoConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateChannelOptions(config.Channels); err != nil {
+		return nil, err
+	}
+	clientCert, err := config.ClientTls.certificate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid client TLS certificate: %v", err)
+	}
+	ct := newClientTls(clientCert)
+
+	peers, eventPeers, orderers, err := nodesFromConfig(config, ct)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.EventBuffer.validate(); err != nil {
+		return nil, err
+	}
+	client := &FabricClient{Peers: peers, EventPeers: eventPeers, Orderers: orderers, Crypto: crypto,
+		archivePeers: config.Archive.Peers, channels: config.Channels, EventBuffer: config.EventBuffer, clientTls: ct}
+	if err := client.SetDefaults(config.Defaults); err != nil {
+		return nil, err
+	}
+	if config.Archive.BlockStoreUrl != "" {
+		client.BlockStore = &HTTPBlockStore{Url: config.Archive.BlockStoreUrl}
+	}
+	if config.WarmUp.Enabled {
+		ctx := context.Background()
+		if config.WarmUp.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, config.WarmUp.Timeout)
+			defer cancel()
+		}
+		var report *WarmUpReport
+		if config.WarmUp.HealthCheck {
+			report = client.HealthCheck(ctx, config.WarmUp.Parallelism)
+		} else {
+			report = client.WarmUp(ctx, config.WarmUp.Parallelism)
+		}
+		if config.WarmUp.FailFast && !report.Ready() {
+			client.closeConnections()
+			return nil, &WarmUpError{Report: report}
 		}
-	default:
-		return nil, ErrInvalidAlgorithmFamily
 	}
+	return client, nil
+}
 
+// nodesFromConfig creates peers, event peers and orderers from ClientConfig that present client certificate ct
+func nodesFromConfig(config ClientConfig, ct *clientTls) (map[string]*Peer, map[string]*Peer, map[string]*Orderer, error) {
 	peers := make(map[string]*Peer)
 	for name, p := range config.Peers {
-		newPeer, err := NewPeerFromConfig(p)
+		newPeer, err := newPeerFromConfig(name, p, ct)
 		if err != nil {
-			return nil, err
+			return nil, nil, nil, err
 		}
-		newPeer.Name = name
+		newPeer.Timeouts = config.Timeouts
 		peers[name] = newPeer
 
 	}
 
 	eventPeers := make(map[string]*Peer)
 	for name, p := range config.EventPeers {
-		newEventPeer, err := NewPeerFromConfig(p)
+		newEventPeer, err := newPeerFromConfig(name, p, ct)
 		if err != nil {
-			return nil, err
+			return nil, nil, nil, err
 		}
-		newEventPeer.Name = name
+		newEventPeer.Timeouts = config.Timeouts
 		eventPeers[name] = newEventPeer
 	}
 
 	orderers := make(map[string]*Orderer)
 	for name, o := range config.Orderers {
-		newOrderer, err := NewOrdererFromConfig(o)
+		newOrderer, err := newOrdererFromConfig(name, o, ct)
 		if err != nil {
-			return nil, err
+			return nil, nil, nil, err
 		}
-		newOrderer.Name = name
+		newOrderer.Timeouts = config.Timeouts
 		orderers[name] = newOrderer
 	}
-	client := FabricClient{Peers: peers, EventPeers: eventPeers, Orderers: orderers, Crypto: crypto}
-	return &client, nil
+	return peers, eventPeers, orderers, nil
 }
 
 // NewFabricClient creates new client from provided config file.
@@ -584,7 +697,19 @@ func NewFabricClient(path string) (*FabricClient, error) {
 	return NewFabricClientFromConfig(*config)
 }
 
-func (c FabricClient) getPeers(names []string) []*Peer {
+// cryptoSuite returns crypto suite that must be used for identity. If identity has own crypto suite it is used,
+// otherwise client crypto suite is used. Hash configured for channel is applied to the suite.
+func (c *FabricClient) cryptoSuite(identity Identity, channelId string) CryptoSuite {
+	h, _ := c.channelHash(channelId)
+	if identity.Crypto != nil {
+		return channelCryptoSuite(identity.Crypto, h)
+	}
+	return channelCryptoSuite(c.Crypto, h)
+}
+
+func (c *FabricClient) getPeers(names []string) []*Peer {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	res := make([]*Peer, 0, len(names))
 	for _, p := range names {
 		if fp, ok := c.Peers[p]; ok {
@@ -594,7 +719,9 @@ func (c FabricClient) getPeers(names []string) []*Peer {
 	return res
 }
 
-func (c FabricClient) getEventPeers(names []string) []*Peer {
+func (c *FabricClient) getEventPeers(names []string) []*Peer {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	res := make([]*Peer, 0, len(names))
 	for _, p := range names {
 		if fp, ok := c.EventPeers[p]; ok {
@@ -603,3 +730,17 @@ func (c FabricClient) getEventPeers(names []string) []*Peer {
 	}
 	return res
 }
+
+func (c *FabricClient) getEventPeer(name string) (*Peer, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	p, ok := c.EventPeers[name]
+	return p, ok
+}
+
+func (c *FabricClient) getOrderer(name string) (*Orderer, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	o, ok := c.Orderers[name]
+	return o, ok
+}
@@ -0,0 +1,164 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"io/ioutil"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"gopkg.in/yaml.v2"
+)
+
+// Collection config messages of Fabric 2.x, vendored protos are missing fields 5 to 8 of StaticCollectionConfig.
+// Oneof fields with single used member are declared as plain fields, their encoding is the same.
+
+type collectionConfigPackage struct {
+	Config []*collectionConfig `protobuf:"bytes,1,rep,name=config" json:"config,omitempty"`
+}
+
+func (m *collectionConfigPackage) Reset()         { *m = collectionConfigPackage{} }
+func (m *collectionConfigPackage) String() string { return proto.CompactTextString(m) }
+func (*collectionConfigPackage) ProtoMessage()    {}
+
+type collectionConfig struct {
+	StaticCollectionConfig *staticCollectionConfig `protobuf:"bytes,1,opt,name=static_collection_config,json=staticCollectionConfig" json:"static_collection_config,omitempty"`
+}
+
+func (m *collectionConfig) Reset()         { *m = collectionConfig{} }
+func (m *collectionConfig) String() string { return proto.CompactTextString(m) }
+func (*collectionConfig) ProtoMessage()    {}
+
+type staticCollectionConfig struct {
+	Name              string                         `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	MemberOrgsPolicy  *common.CollectionPolicyConfig `protobuf:"bytes,2,opt,name=member_orgs_policy,json=memberOrgsPolicy" json:"member_orgs_policy,omitempty"`
+	RequiredPeerCount int32                          `protobuf:"varint,3,opt,name=required_peer_count,json=requiredPeerCount" json:"required_peer_count,omitempty"`
+	MaximumPeerCount  int32                          `protobuf:"varint,4,opt,name=maximum_peer_count,json=maximumPeerCount" json:"maximum_peer_count,omitempty"`
+	BlockToLive       uint64                         `protobuf:"varint,5,opt,name=block_to_live,json=blockToLive" json:"block_to_live,omitempty"`
+	MemberOnlyRead    bool                           `protobuf:"varint,6,opt,name=member_only_read,json=memberOnlyRead" json:"member_only_read,omitempty"`
+	MemberOnlyWrite   bool                           `protobuf:"varint,7,opt,name=member_only_write,json=memberOnlyWrite" json:"member_only_write,omitempty"`
+	EndorsementPolicy *applicationPolicy             `protobuf:"bytes,8,opt,name=endorsement_policy,json=endorsementPolicy" json:"endorsement_policy,omitempty"`
+}
+
+func (m *staticCollectionConfig) Reset()         { *m = staticCollectionConfig{} }
+func (m *staticCollectionConfig) String() string { return proto.CompactTextString(m) }
+func (*staticCollectionConfig) ProtoMessage()    {}
+
+// applicationPolicy is oneof signature policy and reference to channel config policy
+type applicationPolicy struct {
+	SignaturePolicy              *common.SignaturePolicyEnvelope `protobuf:"bytes,1,opt,name=signature_policy,json=signaturePolicy" json:"signature_policy,omitempty"`
+	ChannelConfigPolicyReference string                          `protobuf:"bytes,2,opt,name=channel_config_policy_reference,json=channelConfigPolicyReference" json:"channel_config_policy_reference,omitempty"`
+}
+
+func (m *applicationPolicy) Reset()         { *m = applicationPolicy{} }
+func (m *applicationPolicy) String() string { return proto.CompactTextString(m) }
+func (*applicationPolicy) ProtoMessage()    {}
+
+// CollectionBuilder builds CollectionConfig validating it on Build
+type CollectionBuilder struct {
+	config CollectionConfig
+}
+
+// NewCollection starts building collection with name
+func NewCollection(name string) *CollectionBuilder {
+	return &CollectionBuilder{config: CollectionConfig{Name: name}}
+}
+
+// Organizations sets MSP ids of organizations which members can access collection
+func (b *CollectionBuilder) Organizations(mspIds ...string) *CollectionBuilder {
+	b.config.Organizations = append(b.config.Organizations, mspIds...)
+	return b
+}
+
+// Policy sets collection member policy like "OR('Org1MSP.member','Org2MSP.member')"
+func (b *CollectionBuilder) Policy(policy string) *CollectionBuilder {
+	b.config.Policy = policy
+	return b
+}
+
+// RequiredPeers sets minimum number of peers private data must be send to during endorsement
+func (b *CollectionBuilder) RequiredPeers(n int32) *CollectionBuilder {
+	b.config.RequiredPeersCount = n
+	return b
+}
+
+// MaxPeers sets maximum number of peers private data will be send to during endorsement
+func (b *CollectionBuilder) MaxPeers(n int32) *CollectionBuilder {
+	b.config.MaximumPeersCount = n
+	return b
+}
+
+// BlockToLive sets number of blocks after which private data is purged, zero keeps it forever
+func (b *CollectionBuilder) BlockToLive(blocks uint64) *CollectionBuilder {
+	b.config.BlockToLive = blocks
+	return b
+}
+
+// MemberOnlyRead allows only clients of member organizations to read private data
+func (b *CollectionBuilder) MemberOnlyRead(only bool) *CollectionBuilder {
+	b.config.MemberOnlyRead = only
+	return b
+}
+
+// MemberOnlyWrite allows only clients of member organizations to write private data
+func (b *CollectionBuilder) MemberOnlyWrite(only bool) *CollectionBuilder {
+	b.config.MemberOnlyWrite = only
+	return b
+}
+
+// EndorsementPolicy sets signature policy like "OR('Org1MSP.peer')" for writes to collection
+func (b *CollectionBuilder) EndorsementPolicy(policy string) *CollectionBuilder {
+	b.config.EndorsementPolicy = &CollectionEndorsementPolicy{SignaturePolicy: policy}
+	return b
+}
+
+// EndorsementChannelPolicy sets reference to channel config policy used for writes to collection
+func (b *CollectionBuilder) EndorsementChannelPolicy(policyRef string) *CollectionBuilder {
+	b.config.EndorsementPolicy = &CollectionEndorsementPolicy{ChannelConfigPolicy: policyRef}
+	return b
+}
+
+// Build validates and returns collection config
+func (b *CollectionBuilder) Build() (CollectionConfig, error) {
+	if err := b.config.Validate(); err != nil {
+		return CollectionConfig{}, err
+	}
+	return b.config, nil
+}
+
+// ParseCollectionsConfig parses list of collections from YAML or JSON and validates them.
+// Format is the same as the one used from peer CLI:
+//
+//	[
+//	  {
+//	    "name": "collectionMarbles",
+//	    "policy": "OR('Org1MSP.member', 'Org2MSP.member')",
+//	    "requiredPeerCount": 0,
+//	    "maxPeerCount": 3,
+//	    "blockToLive": 1000000,
+//	    "memberOnlyRead": true,
+//	    "memberOnlyWrite": true,
+//	    "endorsementPolicy": {"signaturePolicy": "OR('Org1MSP.member')"}
+//	  }
+//	]
+func ParseCollectionsConfig(data []byte) ([]CollectionConfig, error) {
+	var collections []CollectionConfig
+	if err := yaml.Unmarshal(data, &collections); err != nil {
+		return nil, err
+	}
+	if _, err := CollectionConfigToPolicy(collections); err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+// LoadCollectionsConfig reads collections config from YAML or JSON file in path
+func LoadCollectionsConfig(path string) ([]CollectionConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCollectionsConfig(data)
+}
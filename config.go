@@ -7,6 +7,10 @@ package gohfc
 import (
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"crypto/x509"
+	"errors"
+	"google.golang.org/grpc/credentials"
+	"time"
 )
 
 // ClientConfig holds config data for crypto, peers and orderers
@@ -15,6 +19,32 @@ type ClientConfig struct {
 	Orderers   map[string]OrdererConfig `yaml:"orderers"`
 	Peers      map[string]PeerConfig    `yaml:"peers"`
 	EventPeers map[string]PeerConfig    `yaml:"eventPeers"`
+	WarmUp     WarmUpConfig             `yaml:"warmUp"`
+	Archive    ArchiveConfig            `yaml:"archive"`
+	// Channels holds per channel options, entry named default applies to all channels without own entry
+	Channels map[string]ChannelOptions `yaml:"channels"`
+	// Defaults are peers and orderer used when application does not pass them
+	Defaults DefaultsConfig `yaml:"defaults"`
+	// Timeouts apply to all peers, event peers and orderers
+	Timeouts Timeouts `yaml:"timeouts"`
+	// EventBuffer controls buffering of ListenForFullBlock, ListenForFilteredBlock and ListenBlocks
+	EventBuffer EventBufferConfig `yaml:"eventBuffer"`
+	// ClientTls is client TLS certificate for nodes that require client authentication, see
+	// FabricClient.SetClientTlsCertificate
+	ClientTls ClientTlsConfig `yaml:"clientTls"`
+}
+
+// WarmUpConfig controls connecting to peers and orderers when client is created.
+// Result is available from FabricClient.WarmUpReport. Nodes that cannot be connected do not fail client creation,
+// they will be dialed again on first request, unless FailFast is set.
+type WarmUpConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	Parallelism int           `yaml:"parallelism"`
+	Timeout     time.Duration `yaml:"timeout"`
+	// HealthCheck pings every node after it is connected, see FabricClient.HealthCheck
+	HealthCheck bool `yaml:"healthCheck"`
+	// FailFast makes client creation fail with *WarmUpError when any node is not ready
+	FailFast bool `yaml:"failFast"`
 }
 
 // CAConfig holds config for Fabric CA
@@ -30,20 +60,80 @@ type CryptoConfig struct {
 	Family    string `yaml:"family"`
 	Algorithm string `yaml:"algorithm"`
 	Hash      string `yaml:"hash"`
+	// KeyStore is directory where generated keys are stored. Used only by gm family.
+	KeyStore string `yaml:"keyStore"`
 }
 
-// PeerConfig hold config values for Peer. ULR is in address:port notation
+// PeerConfig hold config values for Peer. ULR is in address:port notation, unix:///path/to/socket for unix domain
+// socket, or inproc://name for listener registered with RegisterInProcessListener.
+// TLS root certificate can be provided as path to file (TlsPath) or as PEM encoded content (TlsCert).
+// If both are provided TlsCert takes precedence.
+// TlsType selects TLS implementation, empty or "tls" for standard TLS, "gmtls" for GM TLS (see RegisterTransportCredentials).
 type PeerConfig struct {
-	Host    string `yaml:"host"`
-	UseTLS  bool   `yaml:"useTLS"`
-	TlsPath string `yaml:"tlsPath"`
+	Host    string      `yaml:"host"`
+	UseTLS  bool        `yaml:"useTLS"`
+	TlsPath string      `yaml:"tlsPath"`
+	TlsCert string      `yaml:"tlsCert"`
+	TlsType string      `yaml:"tlsType"`
+	GmTls   GmTlsConfig `yaml:"gmTls"`
+	// ServerName is authority of connection and name TLS certificate of node is verified against. Default is host
+	// name of Host, localhost for unix:// and inproc:// hosts. Optional.
+	ServerName string `yaml:"serverName"`
+	// MspId is MSP id of node organization. When set, only TLS roots of this organization from channel configs are
+	// trusted for node, see TrustChannelTlsRoots. Optional.
+	MspId string `yaml:"mspId"`
+
+	// MspPath is MSP directory of node organization. When TlsPath and TlsCert are empty, TLS certificates are read
+	// from its tlscacerts and tlsintermediatecerts folders.
+	MspPath string `yaml:"mspPath"`
+
+	// OperationsUrl is base url of operations endpoint, used to read node version during warm up. Optional.
+	OperationsUrl string `yaml:"operationsUrl"`
+	// Grpc tunes keepalive and message size limits of connection. Optional.
+	Grpc GrpcConfig `yaml:"grpc"`
 }
 
-// OrdererConfig hold config values for Orderer. ULR is in address:port notation
+// OrdererConfig hold config values for Orderer. ULR is in address:port notation, unix:///path/to/socket for unix
+// domain socket, or inproc://name for listener registered with RegisterInProcessListener.
+// TLS root certificate can be provided as path to file (TlsPath) or as PEM encoded content (TlsCert).
+// If both are provided TlsCert takes precedence.
+// TlsType selects TLS implementation, empty or "tls" for standard TLS, "gmtls" for GM TLS (see RegisterTransportCredentials).
 type OrdererConfig struct {
-	Host    string `yaml:"host"`
-	UseTLS  bool   `yaml:"useTLS"`
-	TlsPath string `yaml:"tlsPath"`
+	Host    string      `yaml:"host"`
+	UseTLS  bool        `yaml:"useTLS"`
+	TlsPath string      `yaml:"tlsPath"`
+	TlsCert string      `yaml:"tlsCert"`
+	TlsType string      `yaml:"tlsType"`
+	GmTls   GmTlsConfig `yaml:"gmTls"`
+	// ServerName is authority of connection and name TLS certificate of node is verified against. Default is host
+	// name of Host, localhost for unix:// and inproc:// hosts. Optional.
+	ServerName string `yaml:"serverName"`
+	// MspId is MSP id of node organization. When set, only TLS roots of this organization from channel configs are
+	// trusted for node, see TrustChannelTlsRoots. Optional.
+	MspId string `yaml:"mspId"`
+
+	// MspPath is MSP directory of node organization. When TlsPath and TlsCert are empty, TLS certificates are read
+	// from its tlscacerts and tlsintermediatecerts folders.
+	MspPath string `yaml:"mspPath"`
+
+	// OperationsUrl is base url of operations endpoint, used to read node version during warm up. Optional.
+	OperationsUrl string `yaml:"operationsUrl"`
+	// Grpc tunes keepalive and message size limits of connection. Optional.
+	Grpc GrpcConfig `yaml:"grpc"`
+}
+
+// GrpcConfig holds gRPC connection settings of peer or orderer. Zero values use defaults: keepalive ping every
+// minute with 20 seconds timeout, also when there are no active streams, and 100MB message size limits.
+// Keepalive time must not be lower than peer or orderer keepalive enforcement policy minimum (1 minute by default),
+// otherwise node closes connection.
+type GrpcConfig struct {
+	KeepaliveTime    time.Duration `yaml:"keepaliveTime"`
+	KeepaliveTimeout time.Duration `yaml:"keepaliveTimeout"`
+	// KeepalivePermitWithoutStream sends pings on connection without active streams, default true
+	KeepalivePermitWithoutStream *bool `yaml:"keepalivePermitWithoutStream"`
+	// MaxRecvMsgSize is maximum size of received message in bytes, large blocks need higher limit
+	MaxRecvMsgSize int `yaml:"maxRecvMsgSize"`
+	MaxSendMsgSize int `yaml:"maxSendMsgSize"`
 }
 
 // NewFabricClientConfig create config from provided yaml file in path
@@ -52,11 +142,14 @@ func NewClientConfig(path string) (*ClientConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+	return NewClientConfigFromBytes(data)
+}
+
+// NewClientConfigFromBytes create config from yaml content. Useful when config is not stored in file system,
+// for example when it comes from environment variable or secret store.
+func NewClientConfigFromBytes(data []byte) (*ClientConfig, error) {
 	config := new(ClientConfig)
-	err = yaml.Unmarshal([]byte(data), config)
-	if err != nil {
-		return nil, err
-	}
+	err := yaml.Unmarshal(data, config)
 	if err != nil {
 		return nil, err
 	}
@@ -69,10 +162,32 @@ func NewCAConfig(path string) (*CAConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+	return NewCAConfigFromBytes(data)
+}
+
+// NewCAConfigFromBytes create new Fabric CA config from yaml content
+func NewCAConfigFromBytes(data []byte) (*CAConfig, error) {
 	config := new(CAConfig)
-	err = yaml.Unmarshal([]byte(data), config)
+	err := yaml.Unmarshal(data, config)
 	if err != nil {
 		return nil, err
 	}
 	return config, nil
 }
+
+// transportCredentials creates TLS credentials from PEM content or from file in path. PEM content takes precedence.
+// Credentials tolerate rotation of node certificates, see TrustChannelTlsRoots, and present client certificate of node.
+func transportCredentials(pemCert, path string, node nodeTls) (credentials.TransportCredentials, error) {
+	if pemCert == "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		pemCert = string(data)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(pemCert)) {
+		return nil, errors.New("cannot parse PEM encoded TLS certificate")
+	}
+	return newNodeTlsCredentials(pool, node), nil
+}
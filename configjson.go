@@ -0,0 +1,392 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+// aclsValue is peer.ACLs message from Fabric 1.3+, it is not part of vendored protos
+type aclsValue struct {
+	Acls map[string]*apiResource `protobuf:"bytes,1,rep,name=acls" json:"acls,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value"`
+}
+
+func (m *aclsValue) Reset()         { *m = aclsValue{} }
+func (m *aclsValue) String() string { return proto.CompactTextString(m) }
+func (*aclsValue) ProtoMessage()    {}
+
+type apiResource struct {
+	PolicyRef string `protobuf:"bytes,1,opt,name=policy_ref,json=policyRef" json:"policy_ref,omitempty"`
+}
+
+func (m *apiResource) Reset()         { *m = apiResource{} }
+func (m *apiResource) String() string { return proto.CompactTextString(m) }
+func (*apiResource) ProtoMessage()    {}
+
+// configValueTypes are messages of config values by key
+var configValueTypes = map[string]func() proto.Message{
+	"MSP":                       func() proto.Message { return new(msp.MSPConfig) },
+	"AnchorPeers":               func() proto.Message { return new(peer.AnchorPeers) },
+	"Capabilities":              func() proto.Message { return new(common.Capabilities) },
+	"HashingAlgorithm":          func() proto.Message { return new(common.HashingAlgorithm) },
+	"BlockDataHashingStructure": func() proto.Message { return new(common.BlockDataHashingStructure) },
+	"OrdererAddresses":          func() proto.Message { return new(common.OrdererAddresses) },
+	"Endpoints":                 func() proto.Message { return new(common.OrdererAddresses) },
+	"Consortium":                func() proto.Message { return new(common.Consortium) },
+	"ConsensusType":             func() proto.Message { return new(consensusTypeValue) },
+	"BatchSize":                 func() proto.Message { return new(orderer.BatchSize) },
+	"BatchTimeout":              func() proto.Message { return new(orderer.BatchTimeout) },
+	"ChannelRestrictions":       func() proto.Message { return new(orderer.ChannelRestrictions) },
+	"KafkaBrokers":              func() proto.Message { return new(orderer.KafkaBrokers) },
+	"ACLs":                      func() proto.Message { return new(aclsValue) },
+	"Orderers":                  func() proto.Message { return new(bftOrderers) },
+}
+
+// consensusStates are names of orderer.ConsensusType.State values
+var consensusStates = map[int32]string{0: "STATE_NORMAL", 1: "STATE_MAINTENANCE"}
+
+// DecodeConfigBlock decodes config block into JSON of the same shape as
+// `configtxlator proto_decode --type common.Block`: nested envelopes, config values, policies and MSP definitions
+// are decoded, certificates are base64 encoded PEM. Channel configurations can be diffed as JSON without
+// configtxlator binary. Values of unknown types are left base64 encoded.
+func DecodeConfigBlock(block *common.Block) ([]byte, error) {
+	m, err := protoToMap(block)
+	if err != nil {
+		return nil, err
+	}
+	if block.Data != nil {
+		txs := make([]interface{}, len(block.Data.Data))
+		for i, data := range block.Data.Data {
+			envelope := new(common.Envelope)
+			if err := proto.Unmarshal(data, envelope); err != nil {
+				return nil, fmt.Errorf("transaction %d: %v", i, err)
+			}
+			if txs[i], err = envelopeToMap(envelope); err != nil {
+				return nil, fmt.Errorf("transaction %d: %v", i, err)
+			}
+		}
+		m["data"] = map[string]interface{}{"data": txs}
+	}
+	return json.MarshalIndent(m, "", "  ")
+}
+
+// DecodeConfig decodes channel config into JSON of the same shape as `configtxlator proto_decode --type common.Config`
+func DecodeConfig(config *common.Config) ([]byte, error) {
+	m, err := configToMap(config)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(m, "", "  ")
+}
+
+// protoToMap marshals message with the same options as configtxlator and returns it as generic JSON value
+func protoToMap(msg proto.Message) (map[string]interface{}, error) {
+	var buf bytes.Buffer
+	if err := (&jsonpb.Marshaler{OrigName: true, EmitDefaults: true}).Marshal(&buf, msg); err != nil {
+		return nil, err
+	}
+	d := json.NewDecoder(&buf)
+	d.UseNumber()
+	m := make(map[string]interface{})
+	if err := d.Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func envelopeToMap(envelope *common.Envelope) (map[string]interface{}, error) {
+	m, err := protoToMap(envelope)
+	if err != nil {
+		return nil, err
+	}
+	payload := new(common.Payload)
+	if err := proto.Unmarshal(envelope.Payload, payload); err != nil {
+		return nil, err
+	}
+	pm, err := protoToMap(payload)
+	if err != nil {
+		return nil, err
+	}
+	if payload.Header == nil {
+		m["payload"] = pm
+		return m, nil
+	}
+	channelHeader := new(common.ChannelHeader)
+	if err := proto.Unmarshal(payload.Header.ChannelHeader, channelHeader); err != nil {
+		return nil, err
+	}
+	chm, err := protoToMap(channelHeader)
+	if err != nil {
+		return nil, err
+	}
+	shm, err := signatureHeaderToMap(payload.Header.SignatureHeader)
+	if err != nil {
+		return nil, err
+	}
+	pm["header"] = map[string]interface{}{"channel_header": chm, "signature_header": shm}
+	switch common.HeaderType(channelHeader.Type) {
+	case common.HeaderType_CONFIG:
+		configEnvelope := new(common.ConfigEnvelope)
+		if err := proto.Unmarshal(payload.Data, configEnvelope); err != nil {
+			return nil, err
+		}
+		dm := map[string]interface{}{"config": nil, "last_update": nil}
+		if configEnvelope.Config != nil {
+			if dm["config"], err = configToMap(configEnvelope.Config); err != nil {
+				return nil, err
+			}
+		}
+		if configEnvelope.LastUpdate != nil {
+			if dm["last_update"], err = envelopeToMap(configEnvelope.LastUpdate); err != nil {
+				return nil, err
+			}
+		}
+		pm["data"] = dm
+	case common.HeaderType_CONFIG_UPDATE:
+		if pm["data"], err = configUpdateEnvelopeToMap(payload.Data); err != nil {
+			return nil, err
+		}
+	}
+	m["payload"] = pm
+	return m, nil
+}
+
+func signatureHeaderToMap(data []byte) (map[string]interface{}, error) {
+	header := new(common.SignatureHeader)
+	if err := proto.Unmarshal(data, header); err != nil {
+		return nil, err
+	}
+	m, err := protoToMap(header)
+	if err != nil {
+		return nil, err
+	}
+	creator := new(msp.SerializedIdentity)
+	if err := proto.Unmarshal(header.Creator, creator); err != nil {
+		return nil, err
+	}
+	if m["creator"], err = protoToMap(creator); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func configUpdateEnvelopeToMap(data []byte) (map[string]interface{}, error) {
+	envelope := new(common.ConfigUpdateEnvelope)
+	if err := proto.Unmarshal(data, envelope); err != nil {
+		return nil, err
+	}
+	m, err := protoToMap(envelope)
+	if err != nil {
+		return nil, err
+	}
+	update := new(common.ConfigUpdate)
+	if err := proto.Unmarshal(envelope.ConfigUpdate, update); err != nil {
+		return nil, err
+	}
+	um, err := protoToMap(update)
+	if err != nil {
+		return nil, err
+	}
+	if update.ReadSet != nil {
+		if um["read_set"], err = configGroupToMap(update.ReadSet); err != nil {
+			return nil, err
+		}
+	}
+	if update.WriteSet != nil {
+		if um["write_set"], err = configGroupToMap(update.WriteSet); err != nil {
+			return nil, err
+		}
+	}
+	m["config_update"] = um
+	signatures := make([]interface{}, len(envelope.Signatures))
+	for i, s := range envelope.Signatures {
+		sm, err := protoToMap(s)
+		if err != nil {
+			return nil, err
+		}
+		if sm["signature_header"], err = signatureHeaderToMap(s.SignatureHeader); err != nil {
+			return nil, err
+		}
+		signatures[i] = sm
+	}
+	m["signatures"] = signatures
+	return m, nil
+}
+
+func configToMap(config *common.Config) (map[string]interface{}, error) {
+	m, err := protoToMap(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.ChannelGroup != nil {
+		if m["channel_group"], err = configGroupToMap(config.ChannelGroup); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func configGroupToMap(group *common.ConfigGroup) (map[string]interface{}, error) {
+	m, err := protoToMap(group)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string]interface{}, len(group.Groups))
+	for name, g := range group.Groups {
+		if groups[name], err = configGroupToMap(g); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	m["groups"] = groups
+	values := make(map[string]interface{}, len(group.Values))
+	for name, v := range group.Values {
+		vm, err := protoToMap(v)
+		if err != nil {
+			return nil, err
+		}
+		if newValue, ok := configValueTypes[name]; ok {
+			if vm["value"], err = configValueToMap(name, v.Value, newValue()); err != nil {
+				return nil, fmt.Errorf("value %s: %v", name, err)
+			}
+		}
+		values[name] = vm
+	}
+	m["values"] = values
+	policies := make(map[string]interface{}, len(group.Policies))
+	for name, p := range group.Policies {
+		pm, err := protoToMap(p)
+		if err != nil {
+			return nil, err
+		}
+		if p.Policy != nil {
+			if pm["policy"], err = policyToMap(p.Policy); err != nil {
+				return nil, fmt.Errorf("policy %s: %v", name, err)
+			}
+		}
+		policies[name] = pm
+	}
+	m["policies"] = policies
+	return m, nil
+}
+
+func configValueToMap(name string, data []byte, msg proto.Message) (map[string]interface{}, error) {
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	m, err := protoToMap(msg)
+	if err != nil {
+		return nil, err
+	}
+	if ct, ok := msg.(*consensusTypeValue); ok {
+		return consensusTypeToMap(ct, m)
+	}
+	mspConfig, ok := msg.(*msp.MSPConfig)
+	if !ok {
+		return m, nil
+	}
+	var config proto.Message
+	switch mspConfig.Type {
+	case 0:
+		config = new(msp.FabricMSPConfig)
+	case 1:
+		config = new(msp.IdemixMSPConfig)
+	default:
+		return m, nil
+	}
+	if m["config"], err = configValueToMap(name, mspConfig.Config, config); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// consensusTypeToMap decodes state and etcdraft metadata of consensus type, metadata of other consensus types is
+// left base64 encoded
+func consensusTypeToMap(ct *consensusTypeValue, m map[string]interface{}) (map[string]interface{}, error) {
+	if name, ok := consensusStates[ct.State]; ok {
+		m["state"] = name
+	}
+	if ct.Type != consensusTypeRaft {
+		return m, nil
+	}
+	metadata := new(raftConfigMetadata)
+	if err := proto.Unmarshal(ct.Metadata, metadata); err != nil {
+		return nil, err
+	}
+	var err error
+	if m["metadata"], err = protoToMap(metadata); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func policyToMap(policy *common.Policy) (map[string]interface{}, error) {
+	m, err := protoToMap(policy)
+	if err != nil {
+		return nil, err
+	}
+	var value proto.Message
+	switch common.Policy_PolicyType(policy.Type) {
+	case common.Policy_SIGNATURE:
+		value = new(common.SignaturePolicyEnvelope)
+	case common.Policy_IMPLICIT_META:
+		value = new(common.ImplicitMetaPolicy)
+	default:
+		return m, nil
+	}
+	if err := proto.Unmarshal(policy.Value, value); err != nil {
+		return nil, err
+	}
+	vm, err := protoToMap(value)
+	if err != nil {
+		return nil, err
+	}
+	if envelope, ok := value.(*common.SignaturePolicyEnvelope); ok {
+		identities := make([]interface{}, len(envelope.Identities))
+		for i, principal := range envelope.Identities {
+			if identities[i], err = principalToMap(principal); err != nil {
+				return nil, fmt.Errorf("identity %d: %v", i, err)
+			}
+		}
+		vm["identities"] = identities
+	}
+	m["value"] = vm
+	return m, nil
+}
+
+// principalToMap decodes principal of MSP principal by its classification, principals of classifications newer than
+// vendored protos are left base64 encoded
+func principalToMap(principal *msp.MSPPrincipal) (map[string]interface{}, error) {
+	m, err := protoToMap(principal)
+	if err != nil {
+		return nil, err
+	}
+	var value proto.Message
+	switch principal.PrincipalClassification {
+	case msp.MSPPrincipal_ROLE:
+		value = new(msp.MSPRole)
+	case msp.MSPPrincipal_ORGANIZATION_UNIT:
+		value = new(msp.OrganizationUnit)
+	case msp.MSPPrincipal_IDENTITY:
+		value = new(msp.SerializedIdentity)
+	default:
+		return m, nil
+	}
+	if err := proto.Unmarshal(principal.Principal, value); err != nil {
+		return nil, err
+	}
+	if m["principal"], err = protoToMap(value); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,244 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/CognitionFoundry/gohfc/blockparser"
+)
+
+// ConflictAnalyzerConfig configures ConflictAnalyzer. Zero values are replaced with defaults.
+type ConflictAnalyzerConfig struct {
+	// TopKeys is number of hottest keys in report, default 20
+	TopKeys int
+	// Window is length of time windows in report, default 1 minute
+	Window time.Duration
+}
+
+// KeyContention are statistics of single key
+type KeyContention struct {
+	Namespace string
+	Key       string
+	Reads     int
+	Writes    int
+	// Conflicts is number of transactions invalidated because key was changed after they read it
+	Conflicts int
+	// AvgConflictGap is average number of blocks between version read by conflicting transaction and block where
+	// transaction was committed. Small gap means key is written by many transactions at the same time.
+	AvgConflictGap float64
+
+	gapSum uint64
+}
+
+// FunctionConflicts are statistics of chaincode function, Function is the first argument of chaincode input
+type FunctionConflicts struct {
+	Chaincode    string
+	Function     string
+	Transactions int
+	Conflicts    int
+}
+
+// ConflictRate returns ratio of transactions invalidated with MVCC or phantom read conflict
+func (f FunctionConflicts) ConflictRate() float64 {
+	if f.Transactions == 0 {
+		return 0
+	}
+	return float64(f.Conflicts) / float64(f.Transactions)
+}
+
+// ConflictWindow are transactions and conflicts committed in time window starting at Start
+type ConflictWindow struct {
+	Start        time.Time
+	Transactions int
+	Conflicts    int
+}
+
+// ConflictReport is result of ConflictAnalyzer
+type ConflictReport struct {
+	Blocks       int
+	Transactions int
+	Conflicts    int
+	// HotKeys are keys with most conflicts and writes, sorted from the hottest
+	HotKeys []KeyContention
+	// Functions are sorted by conflict rate
+	Functions []FunctionConflicts
+	// Windows are sorted by time
+	Windows []ConflictWindow
+}
+
+// ConflictAnalyzer collects write-set contention statistics from committed blocks: hottest keys, MVCC conflict
+// rates per chaincode function and time windows with most conflicts. Conflicts are attributed to keys only when
+// the key was written in analyzed blocks, so analysis should start well before the period of interest.
+// It is safe for concurrent use.
+type ConflictAnalyzer struct {
+	config    ConflictAnalyzerConfig
+	mu        sync.Mutex
+	report    ConflictReport
+	keys      map[string]map[string]*KeyContention
+	versions  map[string]map[string]blockparser.Version
+	functions map[[2]string]*FunctionConflicts
+	windows   map[int64]*ConflictWindow
+}
+
+// NewConflictAnalyzer creates analyzer with config
+func NewConflictAnalyzer(config ConflictAnalyzerConfig) *ConflictAnalyzer {
+	if config.TopKeys <= 0 {
+		config.TopKeys = 20
+	}
+	if config.Window <= 0 {
+		config.Window = time.Minute
+	}
+	return &ConflictAnalyzer{
+		config:    config,
+		keys:      make(map[string]map[string]*KeyContention),
+		versions:  make(map[string]map[string]blockparser.Version),
+		functions: make(map[[2]string]*FunctionConflicts),
+		windows:   make(map[int64]*ConflictWindow),
+	}
+}
+
+// AddBlock adds committed block to statistics. Blocks must be added in order.
+func (a *ConflictAnalyzer) AddBlock(block *blockparser.Block) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.report.Blocks++
+	for txNum, tx := range block.Transactions {
+		if len(tx.Actions) == 0 {
+			continue
+		}
+		conflict := tx.ValidationCode == "MVCC_READ_CONFLICT" || tx.ValidationCode == "PHANTOM_READ_CONFLICT"
+		a.report.Transactions++
+		if conflict {
+			a.report.Conflicts++
+		}
+		w := a.window(tx.Timestamp)
+		w.Transactions++
+		if conflict {
+			w.Conflicts++
+		}
+		for _, action := range tx.Actions {
+			f := a.function(action)
+			f.Transactions++
+			if conflict {
+				f.Conflicts++
+			}
+			for _, rw := range action.ReadWriteSets {
+				for _, r := range rw.Reads {
+					k := a.key(rw.Namespace, r.Key)
+					k.Reads++
+					if conflict && a.changed(rw.Namespace, r) {
+						k.Conflicts++
+						if r.Version != nil {
+							k.gapSum += block.Number - r.Version.BlockNum
+						}
+					}
+				}
+				if tx.ValidationCode != "VALID" {
+					continue
+				}
+				for _, wr := range rw.Writes {
+					a.key(rw.Namespace, wr.Key).Writes++
+					if a.versions[rw.Namespace] == nil {
+						a.versions[rw.Namespace] = make(map[string]blockparser.Version)
+					}
+					a.versions[rw.Namespace][wr.Key] = blockparser.Version{BlockNum: block.Number, TxNum: uint64(txNum)}
+				}
+			}
+		}
+	}
+}
+
+// Report returns statistics of blocks added so far
+func (a *ConflictAnalyzer) Report() *ConflictReport {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	report := a.report
+	var keys []KeyContention
+	for _, byKey := range a.keys {
+		for _, k := range byKey {
+			c := *k
+			if c.Conflicts > 0 {
+				c.AvgConflictGap = float64(c.gapSum) / float64(c.Conflicts)
+			}
+			keys = append(keys, c)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Conflicts != keys[j].Conflicts {
+			return keys[i].Conflicts > keys[j].Conflicts
+		}
+		if keys[i].Writes != keys[j].Writes {
+			return keys[i].Writes > keys[j].Writes
+		}
+		return keys[i].Namespace+"\x00"+keys[i].Key < keys[j].Namespace+"\x00"+keys[j].Key
+	})
+	if len(keys) > a.config.TopKeys {
+		keys = keys[:a.config.TopKeys]
+	}
+	report.HotKeys = keys
+	for _, f := range a.functions {
+		report.Functions = append(report.Functions, *f)
+	}
+	sort.Slice(report.Functions, func(i, j int) bool {
+		fi, fj := report.Functions[i], report.Functions[j]
+		if fi.ConflictRate() != fj.ConflictRate() {
+			return fi.ConflictRate() > fj.ConflictRate()
+		}
+		return fi.Chaincode+"\x00"+fi.Function < fj.Chaincode+"\x00"+fj.Function
+	})
+	for _, w := range a.windows {
+		report.Windows = append(report.Windows, *w)
+	}
+	sort.Slice(report.Windows, func(i, j int) bool { return report.Windows[i].Start.Before(report.Windows[j].Start) })
+	return &report
+}
+
+// changed returns true when key was committed with version different from the one read by transaction
+func (a *ConflictAnalyzer) changed(namespace string, r *blockparser.KVRead) bool {
+	committed, ok := a.versions[namespace][r.Key]
+	if !ok {
+		return false
+	}
+	return r.Version == nil || *r.Version != committed
+}
+
+func (a *ConflictAnalyzer) key(namespace, key string) *KeyContention {
+	if a.keys[namespace] == nil {
+		a.keys[namespace] = make(map[string]*KeyContention)
+	}
+	k, ok := a.keys[namespace][key]
+	if !ok {
+		k = &KeyContention{Namespace: namespace, Key: key}
+		a.keys[namespace][key] = k
+	}
+	return k
+}
+
+func (a *ConflictAnalyzer) function(action *blockparser.Action) *FunctionConflicts {
+	var name string
+	if len(action.Input) > 0 {
+		name = string(action.Input[0])
+	}
+	id := [2]string{action.ChaincodeName, name}
+	f, ok := a.functions[id]
+	if !ok {
+		f = &FunctionConflicts{Chaincode: action.ChaincodeName, Function: name}
+		a.functions[id] = f
+	}
+	return f
+}
+
+func (a *ConflictAnalyzer) window(t time.Time) *ConflictWindow {
+	start := t.Truncate(a.config.Window)
+	w, ok := a.windows[start.UnixNano()]
+	if !ok {
+		w = &ConflictWindow{Start: start}
+		a.windows[start.UnixNano()] = w
+	}
+	return w
+}
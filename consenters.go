@@ -0,0 +1,412 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/CognitionFoundry/gohfc/blockparser"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+const (
+	ordererEndpointsKey  = "Endpoints"
+	ordererConsentersKey = "Orderers"
+
+	consensusTypeRaft = "etcdraft"
+	consensusTypeBFT  = "BFT"
+)
+
+// Messages of Fabric 1.4+ Raft and Fabric 3.x BFT channel config, they are not part of vendored protos
+
+// consensusTypeValue is orderer.ConsensusType with metadata of consensus plugin and state
+type consensusTypeValue struct {
+	Type     string `protobuf:"bytes,1,opt,name=type" json:"type,omitempty"`
+	Metadata []byte `protobuf:"bytes,2,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	State    int32  `protobuf:"varint,3,opt,name=state" json:"state,omitempty"`
+}
+
+func (m *consensusTypeValue) Reset()         { *m = consensusTypeValue{} }
+func (m *consensusTypeValue) String() string { return proto.CompactTextString(m) }
+func (*consensusTypeValue) ProtoMessage()    {}
+
+// raftConfigMetadata is etcdraft.ConfigMetadata
+type raftConfigMetadata struct {
+	Consenters []*raftConsenter `protobuf:"bytes,1,rep,name=consenters" json:"consenters,omitempty"`
+	Options    *raftOptions     `protobuf:"bytes,2,opt,name=options" json:"options,omitempty"`
+}
+
+func (m *raftConfigMetadata) Reset()         { *m = raftConfigMetadata{} }
+func (m *raftConfigMetadata) String() string { return proto.CompactTextString(m) }
+func (*raftConfigMetadata) ProtoMessage()    {}
+
+type raftConsenter struct {
+	Host          string `protobuf:"bytes,1,opt,name=host" json:"host,omitempty"`
+	Port          uint32 `protobuf:"varint,2,opt,name=port" json:"port,omitempty"`
+	ClientTlsCert []byte `protobuf:"bytes,3,opt,name=client_tls_cert,json=clientTlsCert,proto3" json:"client_tls_cert,omitempty"`
+	ServerTlsCert []byte `protobuf:"bytes,4,opt,name=server_tls_cert,json=serverTlsCert,proto3" json:"server_tls_cert,omitempty"`
+}
+
+func (m *raftConsenter) Reset()         { *m = raftConsenter{} }
+func (m *raftConsenter) String() string { return proto.CompactTextString(m) }
+func (*raftConsenter) ProtoMessage()    {}
+
+type raftOptions struct {
+	TickInterval         string `protobuf:"bytes,1,opt,name=tick_interval,json=tickInterval" json:"tick_interval,omitempty"`
+	ElectionTick         uint32 `protobuf:"varint,2,opt,name=election_tick,json=electionTick" json:"election_tick,omitempty"`
+	HeartbeatTick        uint32 `protobuf:"varint,3,opt,name=heartbeat_tick,json=heartbeatTick" json:"heartbeat_tick,omitempty"`
+	MaxInflightBlocks    uint32 `protobuf:"varint,4,opt,name=max_inflight_blocks,json=maxInflightBlocks" json:"max_inflight_blocks,omitempty"`
+	SnapshotIntervalSize uint32 `protobuf:"varint,5,opt,name=snapshot_interval_size,json=snapshotIntervalSize" json:"snapshot_interval_size,omitempty"`
+}
+
+func (m *raftOptions) Reset()         { *m = raftOptions{} }
+func (m *raftOptions) String() string { return proto.CompactTextString(m) }
+func (*raftOptions) ProtoMessage()    {}
+
+// bftOrderers is common.Orderers
+type bftOrderers struct {
+	ConsenterMapping []*bftConsenter `protobuf:"bytes,1,rep,name=consenter_mapping,json=consenterMapping" json:"consenter_mapping,omitempty"`
+}
+
+func (m *bftOrderers) Reset()         { *m = bftOrderers{} }
+func (m *bftOrderers) String() string { return proto.CompactTextString(m) }
+func (*bftOrderers) ProtoMessage()    {}
+
+type bftConsenter struct {
+	Id            uint32 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	Host          string `protobuf:"bytes,2,opt,name=host" json:"host,omitempty"`
+	Port          uint32 `protobuf:"varint,3,opt,name=port" json:"port,omitempty"`
+	MspId         string `protobuf:"bytes,4,opt,name=msp_id,json=mspId" json:"msp_id,omitempty"`
+	Identity      []byte `protobuf:"bytes,5,opt,name=identity,proto3" json:"identity,omitempty"`
+	ClientTlsCert []byte `protobuf:"bytes,6,opt,name=client_tls_cert,json=clientTlsCert,proto3" json:"client_tls_cert,omitempty"`
+	ServerTlsCert []byte `protobuf:"bytes,7,opt,name=server_tls_cert,json=serverTlsCert,proto3" json:"server_tls_cert,omitempty"`
+}
+
+func (m *bftConsenter) Reset()         { *m = bftConsenter{} }
+func (m *bftConsenter) String() string { return proto.CompactTextString(m) }
+func (*bftConsenter) ProtoMessage()    {}
+
+// Consenter is ordering service node that takes part in consensus of channel
+type Consenter struct {
+	// Id, MspId and Identity are set only for BFT consenters
+	Id            uint32
+	Host          string
+	Port          uint32
+	MspId         string
+	Identity      []byte
+	ClientTlsCert []byte
+	ServerTlsCert []byte
+}
+
+// Address returns consenter address in host:port notation
+func (c Consenter) Address() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(int(c.Port)))
+}
+
+// address returns normalized address of consenter used to match it with orderers
+func (c Consenter) address() string {
+	return normalizeAddress(c.Address())
+}
+
+// ordererAddress returns normalized host:port orderer dials, empty for unix socket and in process orderers
+func ordererAddress(o *Orderer) string {
+	target := o.dialTarget()
+	if target == localTarget {
+		return ""
+	}
+	return normalizeAddress(strings.TrimPrefix(target, "dns:///"))
+}
+
+// normalizeAddress lowercases host of host:port address, empty when address has no port
+func normalizeAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return ""
+	}
+	return net.JoinHostPort(strings.ToLower(host), port)
+}
+
+// decodeConsenters returns consenters of etcdraft and BFT channels, other consensus types have none
+func decodeConsenters(ordererGroup *common.ConfigGroup, ct *consensusTypeValue) ([]Consenter, error) {
+	var consenters []Consenter
+	switch ct.Type {
+	case consensusTypeRaft:
+		metadata := new(raftConfigMetadata)
+		if err := proto.Unmarshal(ct.Metadata, metadata); err != nil {
+			return nil, err
+		}
+		for _, c := range metadata.Consenters {
+			consenters = append(consenters, Consenter{Host: c.Host, Port: c.Port, ClientTlsCert: c.ClientTlsCert,
+				ServerTlsCert: c.ServerTlsCert})
+		}
+	case consensusTypeBFT:
+		v, ok := ordererGroup.Values[ordererConsentersKey]
+		if !ok {
+			return nil, nil
+		}
+		orderers := new(bftOrderers)
+		if err := proto.Unmarshal(v.Value, orderers); err != nil {
+			return nil, err
+		}
+		for _, c := range orderers.ConsenterMapping {
+			consenters = append(consenters, Consenter{Id: c.Id, Host: c.Host, Port: c.Port, MspId: c.MspId,
+				Identity: c.Identity, ClientTlsCert: c.ClientTlsCert, ServerTlsCert: c.ServerTlsCert})
+		}
+	}
+	return consenters, nil
+}
+
+// decodeEndpoints returns orderer endpoints of orderer organization (Fabric 1.4.2+)
+func decodeEndpoints(group *common.ConfigGroup) ([]string, error) {
+	v, ok := group.Values[ordererEndpointsKey]
+	if !ok {
+		return nil, nil
+	}
+	endpoints := new(common.OrdererAddresses)
+	if err := proto.Unmarshal(v.Value, endpoints); err != nil {
+		return nil, err
+	}
+	return endpoints.Addresses, nil
+}
+
+// GetConsenters reads current consenter set of channel from config block fetched from peer
+func (c *FabricClient) GetConsenters(identity Identity, channelId, peerName string) ([]Consenter, error) {
+	_, config, err := c.GetConfigBlock(identity, channelId, peerName)
+	if err != nil {
+		return nil, err
+	}
+	return config.Consenters, nil
+}
+
+// ConsenterSyncConfig configures FollowConsenters
+type ConsenterSyncConfig struct {
+	// Orderer is used to fetch current channel config when following starts
+	Orderer string
+	// EventPeer delivers new config blocks of channel
+	EventPeer string
+	// Template is config of orderers added for new endpoints, Host is replaced with endpoint address. When TLS
+	// is used and template has no TLS root, TLS root certificates of orderer organizations from channel config
+	// are trusted.
+	Template OrdererConfig
+	// Timeouts of added orderers
+	Timeouts Timeouts
+	// OnChange is called with names of added and removed orderers. Optional.
+	OnChange func(added, removed []string)
+}
+
+// SyncConsenters makes orderers of client match ordering service in channel config. Orderer named by address is
+// added for every endpoint of orderer organizations that has no orderer dialing the same host and port. Consenter
+// addresses are cluster endpoints that may not serve clients, so they are used only when orderer organizations have
+// no endpoints. Orderers added for endpoints removed since previous sync of channel are closed and removed, unless
+// other channel still has them. Orderers from client config or added with other means are never removed.
+func (c *FabricClient) SyncConsenters(config *ChannelConfig, template OrdererConfig, timeouts Timeouts) (added, removed []string, err error) {
+	endpoints := ordererEndpoints(config)
+	current := make(map[string]bool, len(endpoints))
+	for _, e := range endpoints {
+		current[e.address] = true
+	}
+	if template.UseTLS && template.TlsCert == "" && template.TlsPath == "" {
+		var roots bytes.Buffer
+		for _, org := range config.OrdererOrgs {
+			for _, cert := range org.TlsRootCerts {
+				roots.Write(cert)
+				roots.WriteByte('\n')
+			}
+		}
+		template.TlsCert = roots.String()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	byAddress := make(map[string]string, len(c.Orderers))
+	for name, o := range c.Orderers {
+		if address := ordererAddress(o); address != "" {
+			byAddress[address] = name
+		}
+	}
+	orderers := make(map[string]*Orderer, len(c.Orderers)+len(current))
+	for name, o := range c.Orderers {
+		orderers[name] = o
+	}
+	if c.syncedOrderers == nil {
+		c.syncedOrderers = make(map[string]bool)
+	}
+	for _, e := range endpoints {
+		address := e.address
+		if _, ok := byAddress[address]; ok {
+			continue
+		}
+		conf := template
+		conf.Host = address
+		conf.MspId = e.mspId
+		o, err := newOrdererFromConfig(address, conf, c.clientTls)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot create orderer for %s: %v", address, err)
+		}
+		o.Timeouts = timeouts
+		orderers[address] = o
+		byAddress[address] = address
+		c.syncedOrderers[address] = true
+		added = append(added, address)
+	}
+
+	previous := c.consenters[config.ChannelId]
+	if c.consenters == nil {
+		c.consenters = make(map[string]map[string]bool)
+	}
+	c.consenters[config.ChannelId] = current
+	for address := range previous {
+		if current[address] || c.consenterInOtherChannel(config.ChannelId, address) {
+			continue
+		}
+		name, ok := byAddress[address]
+		if !ok || !c.syncedOrderers[name] {
+			continue
+		}
+		orderers[name].closeConnection()
+		delete(orderers, name)
+		delete(c.syncedOrderers, name)
+		removed = append(removed, name)
+	}
+	c.Orderers = orderers
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed, nil
+}
+
+// ordererEndpoint is client endpoint of ordering service node
+type ordererEndpoint struct {
+	address string
+	mspId   string
+}
+
+// ordererEndpoints returns normalized endpoints of orderer organizations, or addresses of consenters when
+// organizations have no endpoints, for example in channels created before Fabric 1.4.2
+func ordererEndpoints(config *ChannelConfig) []ordererEndpoint {
+	var endpoints []ordererEndpoint
+	for _, org := range config.OrdererOrgs {
+		for _, endpoint := range org.Endpoints {
+			if address := normalizeAddress(endpoint); address != "" {
+				endpoints = append(endpoints, ordererEndpoint{address: address, mspId: org.MspId})
+			}
+		}
+	}
+	if len(endpoints) > 0 {
+		return endpoints
+	}
+	for _, cs := range config.Consenters {
+		endpoints = append(endpoints, ordererEndpoint{address: cs.address(), mspId: cs.MspId})
+	}
+	return endpoints
+}
+
+// consenterInOtherChannel returns true when address is consenter of channel other than channelId. Must be called
+// with mu held.
+func (c *FabricClient) consenterInOtherChannel(channelId, address string) bool {
+	for id, consenters := range c.consenters {
+		if id != channelId && consenters[address] {
+			return true
+		}
+	}
+	return false
+}
+
+// FollowConsenters keeps orderers of client in sync with ordering service of channel. Current config is fetched from
+// config.Orderer and applied with SyncConsenters, then every new config block delivered by config.EventPeer is
+// applied. Errors are send to errs without blocking, errs can be nil. Following stops when ctx is canceled or
+// block stream of event peer fails.
+// Reload replaces orderers with ones from config, so orderers added by sync are removed until next config block.
+func (c *FabricClient) FollowConsenters(ctx context.Context, identity Identity, channelId string, config ConsenterSyncConfig,
+	errs chan<- error) error {
+	newest, err := c.GetNewestBlockFromOrderer(ctx, identity, channelId, config.Orderer)
+	if err != nil {
+		return err
+	}
+	parsed, err := blockparser.ParseBlock(newest)
+	if err != nil {
+		return err
+	}
+	var lastConfig uint64
+	if parsed.Metadata != nil {
+		lastConfig = parsed.Metadata.LastConfig
+	}
+	configBlock := newest
+	if lastConfig != newest.Header.Number {
+		if configBlock, err = c.GetBlockFromOrderer(ctx, identity, channelId, config.Orderer, lastConfig); err != nil {
+			return err
+		}
+	}
+	if err := c.applyConsenters(configBlock, config); err != nil {
+		return err
+	}
+	blocks := make(chan BlockResponse, 1)
+	if err := c.ListenBlocks(ctx, identity, config.EventPeer, channelId, newest.Header.Number+1, blocks); err != nil {
+		return err
+	}
+	go func() {
+		for resp := range blocks {
+			if resp.Error != nil {
+				if ctx.Err() == nil {
+					sendReloadError(errs, resp.Error)
+				}
+				return
+			}
+			if !isConfigBlock(resp.Block) {
+				continue
+			}
+			if err := c.applyConsenters(resp.Block, config); err != nil {
+				sendReloadError(errs, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// applyConsenters syncs orderers with consenters from config block
+func (c *FabricClient) applyConsenters(block *common.Block, config ConsenterSyncConfig) error {
+	channelConfig, err := ParseChannelConfigBlock(block)
+	if err != nil {
+		return err
+	}
+	added, removed, err := c.SyncConsenters(channelConfig, config.Template, config.Timeouts)
+	if err != nil {
+		return err
+	}
+	if len(added) > 0 || len(removed) > 0 {
+		logger().Info("orderers synced with consenters", "channel", channelConfig.ChannelId, "added", added,
+			"removed", removed)
+		if config.OnChange != nil {
+			config.OnChange(added, removed)
+		}
+	}
+	return nil
+}
+
+// isConfigBlock returns true when the only transaction of block is config transaction
+func isConfigBlock(block *common.Block) bool {
+	if block == nil || block.Data == nil || len(block.Data.Data) != 1 {
+		return false
+	}
+	envelope := new(common.Envelope)
+	if err := proto.Unmarshal(block.Data.Data[0], envelope); err != nil {
+		return false
+	}
+	payload := new(common.Payload)
+	if err := proto.Unmarshal(envelope.Payload, payload); err != nil || payload.Header == nil {
+		return false
+	}
+	channelHeader := new(common.ChannelHeader)
+	if err := proto.Unmarshal(payload.Header.ChannelHeader, channelHeader); err != nil {
+		return false
+	}
+	return common.HeaderType(channelHeader.Type) == common.HeaderType_CONFIG
+}
@@ -22,7 +22,7 @@ import (
 )
 
 // CryptSuite defines common interface for different crypto implementations.
-// Currently Hyperledger Fabric supports only Elliptic curves.
+// Currently Hyperledger Fabric supports only Elliptic curves and Ed25519.
 type CryptoSuite interface {
 	// GenerateKey returns PrivateKey.
 	GenerateKey() (interface{}, error)
@@ -32,6 +32,8 @@ type CryptoSuite interface {
 	Sign(msg []byte, key interface{}) ([]byte, error)
 	// Hash computes Hash value of provided data. Hash function will be different in different crypto implementations.
 	Hash(data []byte) []byte
+	// Verify verifies signature over msg created by Sign. Key is public key or *x509.Certificate.
+	Verify(msg, signature []byte, key interface{}) (bool, error)
 }
 
 var (
@@ -78,19 +80,7 @@ func (c *ECCryptSuite) CreateCertificateRequest(enrollmentId string, key interfa
 		return nil, err
 	}
 
-	ipAddr := make([]net.IP, 0)
-	emailAddr := make([]string, 0)
-	dnsAddr := make([]string, 0)
-
-	for i := range hosts {
-		if ip := net.ParseIP(hosts[i]); ip != nil {
-			ipAddr = append(ipAddr, ip)
-		} else if email, err := mail.ParseAddress(hosts[i]); err == nil && email != nil {
-			emailAddr = append(emailAddr, email.Address)
-		} else {
-			dnsAddr = append(dnsAddr, hosts[i])
-		}
-	}
+	ipAddr, emailAddr, dnsAddr := splitHosts(hosts)
 
 	template := x509.CertificateRequest{
 		RawSubject:         asn1Subj,
@@ -108,9 +98,35 @@ func (c *ECCryptSuite) CreateCertificateRequest(enrollmentId string, key interfa
 	return csr, nil
 }
 
+// splitHosts splits hosts to IP addresses, email addresses and DNS names for certificate request
+func splitHosts(hosts []string) ([]net.IP, []string, []string) {
+	ipAddr := make([]net.IP, 0)
+	emailAddr := make([]string, 0)
+	dnsAddr := make([]string, 0)
+
+	for i := range hosts {
+		if ip := net.ParseIP(hosts[i]); ip != nil {
+			ipAddr = append(ipAddr, ip)
+		} else if email, err := mail.ParseAddress(hosts[i]); err == nil && email != nil {
+			emailAddr = append(emailAddr, email.Address)
+		} else {
+			dnsAddr = append(dnsAddr, hosts[i])
+		}
+	}
+	return ipAddr, emailAddr, dnsAddr
+}
+
 func (c *ECCryptSuite) Sign(msg []byte, k interface{}) ([]byte, error) {
 	key, ok := k.(*ecdsa.PrivateKey)
 	if !ok {
+		// Ed25519 keys must be used with ed25519 crypto suite
+		if _, ok := ed25519Key(k); ok {
+			return nil, ErrInvalidKeyType
+		}
+		// keys in Vault or KMS sign digest computed here
+		if signer, ok := k.(Signer); ok {
+			return signDigest(signer, c.Hash(msg))
+		}
 		return nil, ErrInvalidKeyType
 	}
 	var h []byte
@@ -138,12 +154,32 @@ func (c *ECCryptSuite) preventMalleability(k *ecdsa.PrivateKey, S *big.Int) {
 	}
 }
 
+// Verify verifies ECDSA signature over msg digest computed with suite hash. High-S signatures are rejected with
+// ErrSignatureNotLowS. Ed25519 keys are verified with pure Ed25519.
+func (c *ECCryptSuite) Verify(msg, signature []byte, key interface{}) (bool, error) {
+	return verifySignature(key, msg, signature, c.hashFunction)
+}
+
 func (c *ECCryptSuite) Hash(data []byte) []byte {
 	h := c.hashFunction()
 	h.Write(data)
 	return h.Sum(nil)
 }
 
+// NewCryptoSuiteFromConfig creates new crypto suite for family specified in config
+func NewCryptoSuiteFromConfig(config CryptoConfig) (CryptoSuite, error) {
+	switch config.Family {
+	case "ecdsa":
+		return NewECCryptSuiteFromConfig(config)
+	case "gm":
+		return NewGMCryptSuiteFromConfig(config)
+	case "ed25519":
+		return NewEd25519CryptSuiteFromConfig(config)
+	default:
+		return nil, ErrInvalidAlgorithmFamily
+	}
+}
+
 // NewECCryptSuite creates new Elliptic curve crypto suite from config
 func NewECCryptSuiteFromConfig(config CryptoConfig) (CryptoSuite, error) {
 	var suite *ECCryptSuite
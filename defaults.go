@@ -0,0 +1,142 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync/atomic"
+)
+
+const (
+	// EndorsersAll sends invoke proposals to all candidate peers
+	EndorsersAll = "all"
+	// EndorsersMajority sends invoke proposals to more than half of candidate peers
+	EndorsersMajority = "majority"
+)
+
+// DefaultsConfig holds peers and orderer used when application passes empty peer list or orderer name. It lets
+// operators control redundancy of requests centrally in client config.
+type DefaultsConfig struct {
+	Query  QueryDefaults  `yaml:"query"`
+	Invoke InvokeDefaults `yaml:"invoke"`
+}
+
+// QueryDefaults selects peers for queries without peers
+type QueryDefaults struct {
+	// Targets is number of peers query is send to, 0 means no default and empty peer list is an error
+	Targets int `yaml:"targets"`
+	// Peers are candidate peers, all peers of client when empty
+	Peers []string `yaml:"peers"`
+}
+
+// InvokeDefaults selects endorsers and orderer for invokes without peers or orderer
+type InvokeDefaults struct {
+	// Endorsers is number of peers proposal is send to, EndorsersAll or EndorsersMajority. Empty means no default.
+	Endorsers string `yaml:"endorsers"`
+	// Peers are candidate endorsers, all peers of client when empty
+	Peers []string `yaml:"peers"`
+	// Orderer is used when orderer name is empty
+	Orderer string `yaml:"orderer"`
+}
+
+// requestDefaults is DefaultsConfig with round robin position for every kind of request
+type requestDefaults struct {
+	config     DefaultsConfig
+	queryNext  uint64
+	invokeNext uint64
+}
+
+// validate checks that Endorsers is valid
+func (d DefaultsConfig) validate() error {
+	switch d.Invoke.Endorsers {
+	case "", EndorsersAll, EndorsersMajority:
+		return nil
+	}
+	if n, err := strconv.Atoi(d.Invoke.Endorsers); err != nil || n < 1 {
+		return fmt.Errorf("invalid invoke endorsers %q, must be positive number, %s or %s", d.Invoke.Endorsers,
+			EndorsersAll, EndorsersMajority)
+	}
+	return nil
+}
+
+// SetDefaults sets peers and orderer used for requests without them. Defaults are also set from defaults section
+// of client config.
+func (c *FabricClient) SetDefaults(defaults DefaultsConfig) error {
+	if err := defaults.validate(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.defaults = &requestDefaults{config: defaults}
+	c.mu.Unlock()
+	return nil
+}
+
+// applyDefaults returns default peers when peers is empty and default orderer when orderer is empty. Peers are
+// selected round robin from candidates, so load is spread over them.
+func (c *FabricClient) applyDefaults(peers []string, orderer string, invoke bool) ([]string, string) {
+	c.mu.RLock()
+	d := c.defaults
+	c.mu.RUnlock()
+	if d == nil {
+		return peers, orderer
+	}
+	if invoke && orderer == "" {
+		orderer = d.config.Invoke.Orderer
+	}
+	if len(peers) > 0 {
+		return peers, orderer
+	}
+	if invoke {
+		candidates := c.candidatePeers(d.config.Invoke.Peers)
+		var n int
+		switch d.config.Invoke.Endorsers {
+		case "":
+			return peers, orderer
+		case EndorsersAll:
+			n = len(candidates)
+		case EndorsersMajority:
+			n = len(candidates)/2 + 1
+		default:
+			n, _ = strconv.Atoi(d.config.Invoke.Endorsers)
+		}
+		return pickRoundRobin(candidates, n, &d.invokeNext), orderer
+	}
+	if d.config.Query.Targets == 0 {
+		return peers, orderer
+	}
+	return pickRoundRobin(c.candidatePeers(d.config.Query.Peers), d.config.Query.Targets, &d.queryNext), orderer
+}
+
+// candidatePeers returns configured candidates or names of all peers, sorted
+func (c *FabricClient) candidatePeers(configured []string) []string {
+	if len(configured) > 0 {
+		return configured
+	}
+	c.mu.RLock()
+	names := make([]string, 0, len(c.Peers))
+	for name := range c.Peers {
+		names = append(names, name)
+	}
+	c.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+func pickRoundRobin(candidates []string, n int, next *uint64) []string {
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+	if n <= 0 {
+		return nil
+	}
+	start := int(atomic.AddUint64(next, 1) % uint64(len(candidates)))
+	result := make([]string, n)
+	for i := range result {
+		result[i] = candidates[(start+i)%len(candidates)]
+	}
+	return result
+}
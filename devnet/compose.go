@@ -0,0 +1,89 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package devnet
+
+import (
+	"bytes"
+	"text/template"
+)
+
+var composeTemplate = template.Must(template.New("compose").Parse(`version: '2'
+
+networks:
+  devnet:
+    name: devnet
+
+services:
+  orderer.{{.Domain}}:
+    container_name: orderer.{{.Domain}}
+    image: hyperledger/fabric-orderer:{{.ImageTag}}
+    environment:
+      - ORDERER_GENERAL_LOGLEVEL=INFO
+      - ORDERER_GENERAL_LISTENADDRESS=0.0.0.0
+      - ORDERER_GENERAL_GENESISMETHOD=file
+      - ORDERER_GENERAL_GENESISFILE=/var/hyperledger/orderer/genesis.block
+      - ORDERER_GENERAL_LOCALMSPID=OrdererMSP
+      - ORDERER_GENERAL_LOCALMSPDIR=/var/hyperledger/orderer/msp
+      - ORDERER_GENERAL_TLS_ENABLED=true
+      - ORDERER_GENERAL_TLS_PRIVATEKEY=/var/hyperledger/orderer/tls/server.key
+      - ORDERER_GENERAL_TLS_CERTIFICATE=/var/hyperledger/orderer/tls/server.crt
+      - ORDERER_GENERAL_TLS_ROOTCAS=[/var/hyperledger/orderer/tls/ca.crt]
+    working_dir: /opt/gopath/src/github.com/hyperledger/fabric
+    command: orderer
+    volumes:
+      - ./genesis.block:/var/hyperledger/orderer/genesis.block
+      - ./crypto-config/ordererOrganizations/{{.Domain}}/orderers/orderer.{{.Domain}}/msp:/var/hyperledger/orderer/msp
+      - ./crypto-config/ordererOrganizations/{{.Domain}}/orderers/orderer.{{.Domain}}/tls:/var/hyperledger/orderer/tls
+    ports:
+      - {{.OrdererPort}}:7050
+    networks:
+      - devnet
+
+  {{.PeerHost}}:
+    container_name: {{.PeerHost}}
+    image: hyperledger/fabric-peer:{{.ImageTag}}
+    environment:
+      - CORE_VM_ENDPOINT=unix:///host/var/run/docker.sock
+      - CORE_VM_DOCKER_HOSTCONFIG_NETWORKMODE=devnet
+      - CORE_LOGGING_PEER=info
+      - CORE_PEER_ID={{.PeerHost}}
+      - CORE_PEER_ADDRESS={{.PeerHost}}:7051
+      - CORE_PEER_CHAINCODELISTENADDRESS=0.0.0.0:7052
+      - CORE_PEER_GOSSIP_EXTERNALENDPOINT={{.PeerHost}}:7051
+      - CORE_PEER_GOSSIP_USELEADERELECTION=true
+      - CORE_PEER_GOSSIP_ORGLEADER=false
+      - CORE_PEER_LOCALMSPID={{.MspId}}
+      - CORE_PEER_MSPCONFIGPATH=/etc/hyperledger/fabric/msp
+      - CORE_PEER_TLS_ENABLED=true
+      - CORE_PEER_TLS_CERT_FILE=/etc/hyperledger/fabric/tls/server.crt
+      - CORE_PEER_TLS_KEY_FILE=/etc/hyperledger/fabric/tls/server.key
+      - CORE_PEER_TLS_ROOTCERT_FILE=/etc/hyperledger/fabric/tls/ca.crt
+    working_dir: /opt/gopath/src/github.com/hyperledger/fabric/peer
+    command: peer node start
+    volumes:
+      - /var/run/:/host/var/run/
+      - ./crypto-config/peerOrganizations/{{.OrgDomain}}/peers/{{.PeerHost}}/msp:/etc/hyperledger/fabric/msp
+      - ./crypto-config/peerOrganizations/{{.OrgDomain}}/peers/{{.PeerHost}}/tls:/etc/hyperledger/fabric/tls
+    ports:
+      - {{.PeerPort}}:7051
+    depends_on:
+      - orderer.{{.Domain}}
+    networks:
+      - devnet
+`))
+
+// dockerCompose renders docker compose file that starts orderer and peer with generated crypto material
+func (n *Network) dockerCompose() ([]byte, error) {
+	var buf bytes.Buffer
+	err := composeTemplate.Execute(&buf, struct {
+		Config
+		OrgDomain string
+		PeerHost  string
+	}{n.Config, n.peerOrgDomain(), "peer0." + n.peerOrgDomain()})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,276 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package devnet
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+
+	"github.com/CognitionFoundry/gohfc"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/hyperledger/fabric/protos/orderer"
+)
+
+const (
+	adminsPolicy        = "Admins"
+	readersPolicy       = "Readers"
+	writersPolicy       = "Writers"
+	ordererAdminsPolicy = "/Channel/Orderer/Admins"
+)
+
+// genesisBlock creates orderer system channel genesis block with solo consensus and consortium of peer organization
+func (n *Network) genesisBlock() ([]byte, error) {
+	ordererOrg, err := orgGroup(n.orderer)
+	if err != nil {
+		return nil, err
+	}
+	peerOrg, err := orgGroup(n.peerOrg)
+	if err != nil {
+		return nil, err
+	}
+	ordererGroup := newGroup(adminsPolicy)
+	ordererGroup.Groups["OrdererOrg"] = ordererOrg
+	if err := addValues(ordererGroup, adminsPolicy, map[string]proto.Message{
+		"ConsensusType":       &orderer.ConsensusType{Type: "solo"},
+		"BatchSize":           &orderer.BatchSize{MaxMessageCount: 10, AbsoluteMaxBytes: 99 * 1024 * 1024, PreferredMaxBytes: 512 * 1024},
+		"BatchTimeout":        &orderer.BatchTimeout{Timeout: "2s"},
+		"ChannelRestrictions": &orderer.ChannelRestrictions{},
+		"Capabilities":        capabilities(gohfc.CapabilityV1_1),
+	}); err != nil {
+		return nil, err
+	}
+	if err := addImplicitMetaPolicies(ordererGroup); err != nil {
+		return nil, err
+	}
+	if err := addPolicy(ordererGroup, "BlockValidation", adminsPolicy, implicitMeta(writersPolicy, common.ImplicitMetaPolicy_ANY)); err != nil {
+		return nil, err
+	}
+
+	consortium := newGroup(ordererAdminsPolicy)
+	consortium.Groups[n.OrgName] = peerOrg
+	if err := addValues(consortium, ordererAdminsPolicy, map[string]proto.Message{
+		"ChannelCreationPolicy": implicitMeta(adminsPolicy, common.ImplicitMetaPolicy_ANY),
+	}); err != nil {
+		return nil, err
+	}
+	consortiums := newGroup(ordererAdminsPolicy)
+	consortiums.Groups[consortiumName] = consortium
+	acceptAll, err := proto.Marshal(&common.SignaturePolicyEnvelope{
+		Rule: &common.SignaturePolicy{Type: &common.SignaturePolicy_NOutOf_{NOutOf: &common.SignaturePolicy_NOutOf{N: 0}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	consortiums.Policies[adminsPolicy] = &common.ConfigPolicy{
+		ModPolicy: ordererAdminsPolicy,
+		Policy:    &common.Policy{Type: int32(common.Policy_SIGNATURE), Value: acceptAll},
+	}
+
+	channel := newGroup(adminsPolicy)
+	channel.Groups["Orderer"] = ordererGroup
+	channel.Groups["Consortiums"] = consortiums
+	if err := addValues(channel, adminsPolicy, map[string]proto.Message{
+		"HashingAlgorithm":          &common.HashingAlgorithm{Name: "SHA256"},
+		"BlockDataHashingStructure": &common.BlockDataHashingStructure{Width: math.MaxUint32},
+		"Capabilities":              capabilities(gohfc.CapabilityV1_1),
+	}); err != nil {
+		return nil, err
+	}
+	if err := addValues(channel, ordererAdminsPolicy, map[string]proto.Message{
+		"OrdererAddresses": &common.OrdererAddresses{Addresses: []string{fmt.Sprintf("orderer.%s:7050", n.Domain)}},
+	}); err != nil {
+		return nil, err
+	}
+	if err := addImplicitMetaPolicies(channel); err != nil {
+		return nil, err
+	}
+
+	configEnvelope, err := proto.Marshal(&common.ConfigEnvelope{Config: &common.Config{ChannelGroup: channel}})
+	if err != nil {
+		return nil, err
+	}
+	env, err := unsignedEnvelope(common.HeaderType_CONFIG, systemChannelId, configEnvelope)
+	if err != nil {
+		return nil, err
+	}
+	lastConfig, err := proto.Marshal(&common.LastConfig{Index: 0})
+	if err != nil {
+		return nil, err
+	}
+	lastConfigMetadata, err := proto.Marshal(&common.Metadata{Value: lastConfig})
+	if err != nil {
+		return nil, err
+	}
+	dataHash := sha256.Sum256(env)
+	block := &common.Block{
+		Header:   &common.BlockHeader{Number: 0, DataHash: dataHash[:]},
+		Data:     &common.BlockData{Data: [][]byte{env}},
+		Metadata: &common.BlockMetadata{Metadata: make([][]byte, len(common.BlockMetadataIndex_name))},
+	}
+	block.Metadata.Metadata[common.BlockMetadataIndex_LAST_CONFIG] = lastConfigMetadata
+	return proto.Marshal(block)
+}
+
+// channelTx creates config update transaction that creates application channel with peer organization
+func (n *Network) channelTx() ([]byte, error) {
+	consortium, err := proto.Marshal(&common.Consortium{Name: consortiumName})
+	if err != nil {
+		return nil, err
+	}
+	readApp := newGroup("")
+	readApp.Groups[n.OrgName] = newGroup("")
+	readSet := newGroup("")
+	readSet.Groups["Application"] = readApp
+	readSet.Values["Consortium"] = &common.ConfigValue{}
+
+	writeApp := newGroup(adminsPolicy)
+	writeApp.Version = 1
+	writeApp.Groups[n.OrgName] = newGroup("")
+	if err := addValues(writeApp, adminsPolicy, map[string]proto.Message{
+		"Capabilities": capabilities(gohfc.CapabilityV1_2),
+	}); err != nil {
+		return nil, err
+	}
+	if err := addImplicitMetaPolicies(writeApp); err != nil {
+		return nil, err
+	}
+	writeSet := newGroup("")
+	writeSet.Groups["Application"] = writeApp
+	writeSet.Values["Consortium"] = &common.ConfigValue{Value: consortium}
+
+	update, err := proto.Marshal(&common.ConfigUpdate{ChannelId: n.ChannelId, ReadSet: readSet, WriteSet: writeSet})
+	if err != nil {
+		return nil, err
+	}
+	updateEnvelope, err := proto.Marshal(&common.ConfigUpdateEnvelope{ConfigUpdate: update})
+	if err != nil {
+		return nil, err
+	}
+	return unsignedEnvelope(common.HeaderType_CONFIG_UPDATE, n.ChannelId, updateEnvelope)
+}
+
+// orgGroup creates config group of organization with MSP and member/admin signature policies
+func orgGroup(o *organization) (*common.ConfigGroup, error) {
+	mspConfig, err := proto.Marshal(&msp.FabricMSPConfig{
+		Name:         o.mspId,
+		RootCerts:    [][]byte{o.ca.pem},
+		Admins:       [][]byte{o.admin},
+		TlsRootCerts: [][]byte{o.tlsCa.pem},
+		CryptoConfig: &msp.FabricCryptoConfig{SignatureHashFamily: "SHA2", IdentityIdentifierHashFunction: "SHA256"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	group := newGroup(adminsPolicy)
+	if err := addValues(group, adminsPolicy, map[string]proto.Message{
+		"MSP": &msp.MSPConfig{Type: 0, Config: mspConfig},
+	}); err != nil {
+		return nil, err
+	}
+	for name, role := range map[string]string{readersPolicy: "member", writersPolicy: "member", adminsPolicy: "admin"} {
+		policy, err := gohfc.ParsePolicy(gohfc.PolicyOr(gohfc.PolicyPrincipal(o.mspId, role)))
+		if err != nil {
+			return nil, err
+		}
+		if err := addPolicy(group, name, adminsPolicy, policy); err != nil {
+			return nil, err
+		}
+	}
+	return group, nil
+}
+
+func newGroup(modPolicy string) *common.ConfigGroup {
+	return &common.ConfigGroup{
+		Groups:    make(map[string]*common.ConfigGroup),
+		Values:    make(map[string]*common.ConfigValue),
+		Policies:  make(map[string]*common.ConfigPolicy),
+		ModPolicy: modPolicy,
+	}
+}
+
+func addValues(group *common.ConfigGroup, modPolicy string, values map[string]proto.Message) error {
+	for name, v := range values {
+		raw, err := proto.Marshal(v)
+		if err != nil {
+			return err
+		}
+		group.Values[name] = &common.ConfigValue{Value: raw, ModPolicy: modPolicy}
+	}
+	return nil
+}
+
+// addPolicy adds policy to group. Policy must be *common.SignaturePolicyEnvelope or *common.ImplicitMetaPolicy.
+func addPolicy(group *common.ConfigGroup, name, modPolicy string, policy proto.Message) error {
+	raw, err := proto.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	policyType := common.Policy_SIGNATURE
+	if _, ok := policy.(*common.ImplicitMetaPolicy); ok {
+		policyType = common.Policy_IMPLICIT_META
+	}
+	group.Policies[name] = &common.ConfigPolicy{ModPolicy: modPolicy, Policy: &common.Policy{Type: int32(policyType), Value: raw}}
+	return nil
+}
+
+// addImplicitMetaPolicies adds standard Readers, Writers and Admins policies
+func addImplicitMetaPolicies(group *common.ConfigGroup) error {
+	if err := addPolicy(group, readersPolicy, adminsPolicy, implicitMeta(readersPolicy, common.ImplicitMetaPolicy_ANY)); err != nil {
+		return err
+	}
+	if err := addPolicy(group, writersPolicy, adminsPolicy, implicitMeta(writersPolicy, common.ImplicitMetaPolicy_ANY)); err != nil {
+		return err
+	}
+	return addPolicy(group, adminsPolicy, adminsPolicy, implicitMeta(adminsPolicy, common.ImplicitMetaPolicy_MAJORITY))
+}
+
+func implicitMeta(subPolicy string, rule common.ImplicitMetaPolicy_Rule) *common.ImplicitMetaPolicy {
+	return &common.ImplicitMetaPolicy{SubPolicy: subPolicy, Rule: rule}
+}
+
+func capabilities(names ...string) *common.Capabilities {
+	c := &common.Capabilities{Capabilities: make(map[string]*common.Capability)}
+	for _, name := range names {
+		c.Capabilities[name] = &common.Capability{}
+	}
+	return c
+}
+
+// unsignedEnvelope creates envelope without creator and signature, like configtxgen does
+func unsignedEnvelope(headerType common.HeaderType, channelId string, data []byte) ([]byte, error) {
+	nonce := make([]byte, 24)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	txId := sha256.Sum256(nonce)
+	channelHeader, err := proto.Marshal(&common.ChannelHeader{
+		Type:      int32(headerType),
+		Version:   1,
+		Timestamp: ptypes.TimestampNow(),
+		ChannelId: channelId,
+		TxId:      hex.EncodeToString(txId[:]),
+	})
+	if err != nil {
+		return nil, err
+	}
+	signatureHeader, err := proto.Marshal(&common.SignatureHeader{Nonce: nonce})
+	if err != nil {
+		return nil, err
+	}
+	payload, err := proto.Marshal(&common.Payload{
+		Header: &common.Header{ChannelHeader: channelHeader, SignatureHeader: signatureHeader},
+		Data:   data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(&common.Envelope{Payload: payload})
+}
@@ -0,0 +1,207 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package devnet
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/CognitionFoundry/gohfc"
+)
+
+// certValidity is validity of all generated certificates
+const certValidity = 10 * 365 * 24 * time.Hour
+
+// organization is crypto material of one organization in cryptogen layout
+type organization struct {
+	dir      string
+	domain   string
+	mspId    string
+	nodeName string
+	ca       *signer
+	tlsCa    *signer
+	// admin is the certificate of organization admin, listed in MSP admincerts
+	admin []byte
+}
+
+type signer struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newOrganization generates CA, TLS CA, single node and Admin and User1 users of organization in dir
+func newOrganization(dir, domain, mspId, nodeName string) (*organization, error) {
+	o := &organization{dir: dir, domain: domain, mspId: mspId, nodeName: nodeName}
+	var err error
+	if o.ca, err = newCA("ca." + domain); err != nil {
+		return nil, err
+	}
+	if o.tlsCa, err = newCA("tlsca." + domain); err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(dir, "ca", "ca."+domain+"-cert.pem"), o.ca.pem); err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(dir, "tlsca", "tlsca."+domain+"-cert.pem"), o.tlsCa.pem); err != nil {
+		return nil, err
+	}
+	admin, adminKey, err := o.ca.issue("Admin@"+domain, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	o.admin = admin
+	if err := o.writeMsp(filepath.Join(dir, "msp"), nil, nil); err != nil {
+		return nil, err
+	}
+	if err := o.writeMsp(filepath.Join(dir, "users", "Admin@"+domain, "msp"), admin, adminKey); err != nil {
+		return nil, err
+	}
+	user, userKey, err := o.ca.issue("User1@"+domain, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.writeMsp(filepath.Join(dir, "users", "User1@"+domain, "msp"), user, userKey); err != nil {
+		return nil, err
+	}
+	host := nodeName + "." + domain
+	node, nodeKey, err := o.ca.issue(host, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.writeMsp(filepath.Join(o.nodeDir(), "msp"), node, nodeKey); err != nil {
+		return nil, err
+	}
+	tlsCert, tlsKey, err := o.tlsCa.issue(host, []string{host, nodeName, "localhost"}, true)
+	if err != nil {
+		return nil, err
+	}
+	tlsDir := filepath.Join(o.nodeDir(), "tls")
+	for name, data := range map[string][]byte{"ca.crt": o.tlsCa.pem, "server.crt": tlsCert, "server.key": tlsKey} {
+		if err := writeFile(filepath.Join(tlsDir, name), data); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// nodeDir is the directory of organization peer or orderer
+func (o *organization) nodeDir() string {
+	kind := "peers"
+	if o.nodeName == "orderer" {
+		kind = "orderers"
+	}
+	return filepath.Join(o.dir, kind, o.nodeName+"."+o.domain)
+}
+
+// writeMsp writes MSP folder. Signing certificate and key are optional, organization MSP has none.
+func (o *organization) writeMsp(dir string, cert, key []byte) error {
+	files := map[string][]byte{
+		filepath.Join("cacerts", "ca."+o.domain+"-cert.pem"):       o.ca.pem,
+		filepath.Join("tlscacerts", "tlsca."+o.domain+"-cert.pem"): o.tlsCa.pem,
+		filepath.Join("admincerts", "Admin@"+o.domain+"-cert.pem"): o.admin,
+	}
+	if cert != nil {
+		files[filepath.Join("signcerts", "cert.pem")] = cert
+		files[filepath.Join("keystore", "priv_sk")] = key
+	}
+	for name, data := range files {
+		if err := writeFile(filepath.Join(dir, name), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// userIdentity loads user certificate and key as gohfc identity
+func (o *organization) userIdentity(user string) (*gohfc.Identity, error) {
+	dir := filepath.Join(o.dir, "users", user+"@"+o.domain, "msp")
+	identity, err := gohfc.LoadCertFromFile(filepath.Join(dir, "signcerts", "cert.pem"), filepath.Join(dir, "keystore", "priv_sk"))
+	if err != nil {
+		return nil, err
+	}
+	identity.MspId = o.mspId
+	return identity, nil
+}
+
+// newCA creates self signed CA
+func newCA(name string) (*signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template, err := certTemplate(name, &key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &signer{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw})}, nil
+}
+
+// issue creates new key and certificate signed by CA. Returns PEM encoded certificate and PKCS8 key.
+func (s *signer) issue(name string, hosts []string, tls bool) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template, err := certTemplate(name, &key.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.AuthorityKeyId = s.cert.SubjectKeyId
+	if tls {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+		template.DNSNames = hosts
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, s.cert, &key.PublicKey, s.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyRaw, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyRaw}), nil
+}
+
+func certTemplate(name string, pub *ecdsa.PublicKey) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	ski := sha256.Sum256(elliptic.Marshal(pub.Curve, pub.X, pub.Y))
+	now := time.Now().Add(-5 * time.Minute)
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    now,
+		NotAfter:     now.Add(certValidity),
+		SubjectKeyId: ski[:],
+	}, nil
+}
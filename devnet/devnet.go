@@ -0,0 +1,222 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+
+// Package devnet generates everything needed to run local development network with one organization, one peer and
+// solo orderer: crypto material, orderer genesis block, channel creation transaction, docker compose file and gohfc
+// client config. It is the Go equivalent of cryptogen + configtxgen + docker compose files from fabric-samples.
+//
+//	net, err := devnet.Generate(devnet.Config{Dir: "/tmp/devnet"})
+//	// docker-compose -f /tmp/devnet/docker-compose.yaml up -d
+//	client, err := net.Client()
+//	err = net.CreateAndJoinChannel(client)
+package devnet
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/CognitionFoundry/gohfc"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// OrdererName is the name of the orderer in generated client config
+	OrdererName = "orderer0"
+	// PeerName is the name of the peer in generated client config
+	PeerName = "peer0"
+
+	systemChannelId = "testchainid"
+	consortiumName  = "SampleConsortium"
+)
+
+// Config describes network to generate. Zero values are replaced with defaults.
+type Config struct {
+	// Dir is the directory where all files are generated. Required.
+	Dir string
+	// Domain is the base domain of the network, default example.com
+	Domain string
+	// OrgName is the name of the peer organization, default Org1
+	OrgName string
+	// MspId is MSP id of the peer organization, default Org1MSP
+	MspId string
+	// ChannelId is the application channel, default mychannel
+	ChannelId string
+	// PeerPort is the host port on which peer is exposed, default 7051
+	PeerPort int
+	// OrdererPort is the host port on which orderer is exposed, default 7050
+	OrdererPort int
+	// ImageTag is the tag of hyperledger/fabric-peer and hyperledger/fabric-orderer images, default 1.2.1
+	ImageTag string
+}
+
+func (c *Config) setDefaults() {
+	if c.Domain == "" {
+		c.Domain = "example.com"
+	}
+	if c.OrgName == "" {
+		c.OrgName = "Org1"
+	}
+	if c.MspId == "" {
+		c.MspId = c.OrgName + "MSP"
+	}
+	if c.ChannelId == "" {
+		c.ChannelId = "mychannel"
+	}
+	if c.PeerPort == 0 {
+		c.PeerPort = 7051
+	}
+	if c.OrdererPort == 0 {
+		c.OrdererPort = 7050
+	}
+	if c.ImageTag == "" {
+		c.ImageTag = "1.2.1"
+	}
+}
+
+// Network is generated development network
+type Network struct {
+	Config
+	orderer *organization
+	peerOrg *organization
+}
+
+// Generate creates crypto material, genesis block, channel transaction, docker compose file and client config in
+// config.Dir. Existing files are overwritten.
+func Generate(config Config) (*Network, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("devnet: Dir is required")
+	}
+	config.setDefaults()
+	n := &Network{Config: config}
+	var err error
+	n.orderer, err = newOrganization(filepath.Join(config.Dir, "crypto-config", "ordererOrganizations", config.Domain),
+		config.Domain, "OrdererMSP", "orderer")
+	if err != nil {
+		return nil, err
+	}
+	n.peerOrg, err = newOrganization(filepath.Join(config.Dir, "crypto-config", "peerOrganizations", n.peerOrgDomain()),
+		n.peerOrgDomain(), config.MspId, "peer0")
+	if err != nil {
+		return nil, err
+	}
+	genesis, err := n.genesisBlock()
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(n.GenesisBlockPath(), genesis); err != nil {
+		return nil, err
+	}
+	channelTx, err := n.channelTx()
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(n.ChannelTxPath(), channelTx); err != nil {
+		return nil, err
+	}
+	compose, err := n.dockerCompose()
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(n.DockerComposePath(), compose); err != nil {
+		return nil, err
+	}
+	client, err := yaml.Marshal(n.ClientConfig())
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(n.ClientConfigPath(), client); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// GenesisBlockPath is the path of orderer genesis block
+func (n *Network) GenesisBlockPath() string {
+	return filepath.Join(n.Dir, "genesis.block")
+}
+
+// ChannelTxPath is the path of transaction that creates application channel
+func (n *Network) ChannelTxPath() string {
+	return filepath.Join(n.Dir, n.ChannelId+".tx")
+}
+
+// DockerComposePath is the path of docker compose file that starts the network
+func (n *Network) DockerComposePath() string {
+	return filepath.Join(n.Dir, "docker-compose.yaml")
+}
+
+// ClientConfigPath is the path of gohfc client config for the network
+func (n *Network) ClientConfigPath() string {
+	return filepath.Join(n.Dir, "client.yaml")
+}
+
+// ClientConfig returns gohfc config for connecting to the network from host
+func (n *Network) ClientConfig() gohfc.ClientConfig {
+	peer := gohfc.PeerConfig{
+		Host:    fmt.Sprintf("localhost:%d", n.PeerPort),
+		UseTLS:  true,
+		TlsPath: filepath.Join(n.peerOrg.nodeDir(), "tls", "ca.crt"),
+	}
+	return gohfc.ClientConfig{
+		CryptoConfig: gohfc.CryptoConfig{Family: "ecdsa", Algorithm: "P256-SHA256", Hash: "SHA2-256"},
+		Orderers: map[string]gohfc.OrdererConfig{OrdererName: {
+			Host:    fmt.Sprintf("localhost:%d", n.OrdererPort),
+			UseTLS:  true,
+			TlsPath: filepath.Join(n.orderer.nodeDir(), "tls", "ca.crt"),
+		}},
+		Peers:      map[string]gohfc.PeerConfig{PeerName: peer},
+		EventPeers: map[string]gohfc.PeerConfig{PeerName: peer},
+	}
+}
+
+// Client creates gohfc client connected to the network
+func (n *Network) Client() (*gohfc.FabricClient, error) {
+	return gohfc.NewFabricClientFromConfig(n.ClientConfig())
+}
+
+// AdminIdentity returns admin of the peer organization
+func (n *Network) AdminIdentity() (*gohfc.Identity, error) {
+	return n.peerOrg.userIdentity("Admin")
+}
+
+// UserIdentity returns regular user of the peer organization
+func (n *Network) UserIdentity() (*gohfc.Identity, error) {
+	return n.peerOrg.userIdentity("User1")
+}
+
+// CreateAndJoinChannel creates application channel and joins the peer to it. Network must be started first.
+func (n *Network) CreateAndJoinChannel(client *gohfc.FabricClient) error {
+	admin, err := n.AdminIdentity()
+	if err != nil {
+		return err
+	}
+	if err := client.CreateUpdateChannel(*admin, n.ChannelTxPath(), n.ChannelId, OrdererName); err != nil {
+		return err
+	}
+	res, err := client.JoinChannel(*admin, n.ChannelId, []string{PeerName}, OrdererName)
+	if err != nil {
+		return err
+	}
+	for _, r := range res {
+		if r.Err != nil {
+			return r.Err
+		}
+	}
+	return nil
+}
+
+func (n *Network) peerOrgDomain() string {
+	return strings.ToLower(n.OrgName) + "." + n.Domain
+}
+
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"hash"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Ed25519CryptSuite implements Ed25519 crypto suite. Signatures are pure Ed25519 over the whole message, as
+// verified by Fabric 2.4+. Hash is used only for transaction ids and defaults to SHA2-256.
+// It is selected with family: ed25519 in config.
+type Ed25519CryptSuite struct {
+	hashFunction func() hash.Hash
+}
+
+// NewEd25519CryptSuiteFromConfig creates new Ed25519 crypto suite from config. Algorithm must be empty or Ed25519.
+func NewEd25519CryptSuiteFromConfig(config CryptoConfig) (CryptoSuite, error) {
+	if config.Algorithm != "" && config.Algorithm != "Ed25519" {
+		return nil, ErrInvalidAlgorithm
+	}
+	suite := &Ed25519CryptSuite{}
+	switch config.Hash {
+	case "", "SHA2-256":
+		suite.hashFunction = sha256.New
+	case "SHA2-384":
+		suite.hashFunction = sha512.New384
+	case "SHA3-256":
+		suite.hashFunction = sha3.New256
+	case "SHA3-384":
+		suite.hashFunction = sha3.New384
+	default:
+		return nil, ErrInvalidHash
+	}
+	return suite, nil
+}
+
+func (c *Ed25519CryptSuite) GenerateKey() (interface{}, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (c *Ed25519CryptSuite) CreateCertificateRequest(enrollmentId string, key interface{}, hosts []string) ([]byte, error) {
+	if enrollmentId == "" {
+		return nil, ErrEnrollmentIdMissing
+	}
+	k, ok := ed25519Key(key)
+	if !ok {
+		return nil, ErrInvalidKeyType
+	}
+	ipAddr, emailAddr, dnsAddr := splitHosts(hosts)
+	template := x509.CertificateRequest{
+		Subject:            pkix.Name{CommonName: enrollmentId},
+		SignatureAlgorithm: x509.PureEd25519,
+		IPAddresses:        ipAddr,
+		EmailAddresses:     emailAddr,
+		DNSNames:           dnsAddr,
+	}
+	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &template, k)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrBytes}), nil
+}
+
+// Sign signs msg with Ed25519 private key. Message is not hashed before signing.
+func (c *Ed25519CryptSuite) Sign(msg []byte, k interface{}) ([]byte, error) {
+	key, ok := ed25519Key(k)
+	if !ok {
+		return nil, ErrInvalidKeyType
+	}
+	return ed25519.Sign(key, msg), nil
+}
+
+// Verify verifies Ed25519 signature over msg
+func (c *Ed25519CryptSuite) Verify(msg, signature []byte, key interface{}) (bool, error) {
+	return verifySignature(key, msg, signature, c.hashFunction)
+}
+
+func (c *Ed25519CryptSuite) Hash(data []byte) []byte {
+	h := c.hashFunction()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// ed25519Key accepts Ed25519 private key as value or pointer, as returned by different parsers
+func ed25519Key(k interface{}) (ed25519.PrivateKey, bool) {
+	switch key := k.(type) {
+	case ed25519.PrivateKey:
+		return key, len(key) == ed25519.PrivateKeySize
+	case *ed25519.PrivateKey:
+		return *key, key != nil && len(*key) == ed25519.PrivateKeySize
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,256 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/CognitionFoundry/gohfc/blockparser"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+// EndorsementGroup is set of peers that returned identical proposal response payload
+type EndorsementGroup struct {
+	Peers []string
+	// PayloadHash is hex encoded SHA2-256 of proposal response payload
+	PayloadHash string
+	payload     []byte
+}
+
+// EndorsementMismatchError is returned when endorsing peers return different proposal response payloads.
+// Transaction built from such endorsements will be invalidated, so it is not send to orderer.
+type EndorsementMismatchError struct {
+	// Groups are peers grouped by identical payload, largest group first
+	Groups []*EndorsementGroup
+	// Diff describes differences of every other group compared to the first group
+	Diff []string
+}
+
+func (e *EndorsementMismatchError) Error() string {
+	groups := make([]string, len(e.Groups))
+	for i, g := range e.Groups {
+		groups[i] = fmt.Sprintf("[%s]", strings.Join(g.Peers, ","))
+	}
+	return fmt.Sprintf("%v: peers %s returned different responses: %s", ErrEndorsementsDoNotMatch,
+		strings.Join(groups, " vs "), strings.Join(e.Diff, "; "))
+}
+
+// ErrEndorsementMismatch is the name under which mismatch error can be matched with errors.As:
+//
+//	var mismatch *gohfc.ErrEndorsementMismatch
+//	if errors.As(err, &mismatch) { ... }
+type ErrEndorsementMismatch = EndorsementMismatchError
+
+// DifferingPeers returns peers whose response differs from the response returned by most peers
+func (e *EndorsementMismatchError) DifferingPeers() []string {
+	var peers []string
+	for _, g := range e.Groups[1:] {
+		peers = append(peers, g.Peers...)
+	}
+	return peers
+}
+
+// Is allows errors.Is(err, ErrEndorsementsDoNotMatch) to match EndorsementMismatchError
+func (e *EndorsementMismatchError) Is(target error) bool {
+	return errors.Is(ErrEndorsementsDoNotMatch, target)
+}
+
+// checkEndorsementsMatch compares proposal response payloads from all successful endorsements.
+// Returns *EndorsementMismatchError when they are not identical.
+func checkEndorsementsMatch(endorsements []*PeerResponse) error {
+	var groups []*EndorsementGroup
+	for _, e := range endorsements {
+		var group *EndorsementGroup
+		for _, g := range groups {
+			if bytes.Equal(g.payload, e.Response.Payload) {
+				group = g
+				break
+			}
+		}
+		if group == nil {
+			hash := sha256.Sum256(e.Response.Payload)
+			group = &EndorsementGroup{PayloadHash: hex.EncodeToString(hash[:]), payload: e.Response.Payload}
+			groups = append(groups, group)
+		}
+		group.Peers = append(group.Peers, e.Name)
+	}
+	if len(groups) < 2 {
+		return nil
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i].Peers) > len(groups[j].Peers) })
+	mismatch := &EndorsementMismatchError{Groups: groups}
+	for _, g := range groups[1:] {
+		for _, d := range diffProposalResponsePayloads(groups[0].payload, g.payload) {
+			mismatch.Diff = append(mismatch.Diff, fmt.Sprintf("[%s] %s", strings.Join(g.Peers, ","), d))
+		}
+	}
+	return mismatch
+}
+
+// diffProposalResponsePayloads returns human readable list of differences between two proposal response payloads
+func diffProposalResponsePayloads(a, b []byte) []string {
+	actionA, errA := chaincodeActionFromPayload(a)
+	actionB, errB := chaincodeActionFromPayload(b)
+	if errA != nil || errB != nil {
+		return []string{"payload cannot be decoded"}
+	}
+	var diff []string
+	if actionA.GetChaincodeId().GetVersion() != actionB.GetChaincodeId().GetVersion() {
+		diff = append(diff, fmt.Sprintf("chaincode version %q != %q",
+			actionA.GetChaincodeId().GetVersion(), actionB.GetChaincodeId().GetVersion()))
+	}
+	ra, rb := actionA.GetResponse(), actionB.GetResponse()
+	if ra.GetStatus() != rb.GetStatus() {
+		diff = append(diff, fmt.Sprintf("response status %d != %d", ra.GetStatus(), rb.GetStatus()))
+	}
+	if ra.GetMessage() != rb.GetMessage() {
+		diff = append(diff, fmt.Sprintf("response message %q != %q", ra.GetMessage(), rb.GetMessage()))
+	}
+	if !bytes.Equal(ra.GetPayload(), rb.GetPayload()) {
+		diff = append(diff, fmt.Sprintf("response payload %q != %q", ra.GetPayload(), rb.GetPayload()))
+	}
+	if !bytes.Equal(actionA.Events, actionB.Events) {
+		diff = append(diff, "chaincode events are different")
+	}
+	if !bytes.Equal(actionA.Results, actionB.Results) {
+		rwA, errA := blockparser.ParseReadWriteSet(actionA.Results)
+		rwB, errB := blockparser.ParseReadWriteSet(actionB.Results)
+		if errA != nil || errB != nil {
+			diff = append(diff, "read write sets cannot be decoded")
+		} else {
+			diff = append(diff, diffReadWriteSets(rwA, rwB)...)
+		}
+	}
+	if len(diff) == 0 {
+		diff = append(diff, "proposal hash or encoding is different")
+	}
+	return diff
+}
+
+func chaincodeActionFromPayload(payload []byte) (*peer.ChaincodeAction, error) {
+	prp := new(peer.ProposalResponsePayload)
+	if err := proto.Unmarshal(payload, prp); err != nil {
+		return nil, err
+	}
+	action := new(peer.ChaincodeAction)
+	if err := proto.Unmarshal(prp.Extension, action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+// diffReadWriteSets compares reads and writes by namespace and key
+func diffReadWriteSets(a, b []*blockparser.NsReadWriteSet) []string {
+	var diff []string
+	nsA, nsB := rwSetsByNamespace(a), rwSetsByNamespace(b)
+	for _, ns := range unionKeys(nsA, nsB) {
+		ra, okA := nsA[ns]
+		rb, okB := nsB[ns]
+		if !okA || !okB {
+			diff = append(diff, fmt.Sprintf("namespace %s exists only in one read write set", ns))
+			continue
+		}
+		readsA, readsB := make(map[string]string), make(map[string]string)
+		for _, r := range ra.Reads {
+			readsA[r.Key] = versionString(r.Version)
+		}
+		for _, r := range rb.Reads {
+			readsB[r.Key] = versionString(r.Version)
+		}
+		for _, k := range unionStringKeys(readsA, readsB) {
+			va, okA := readsA[k]
+			vb, okB := readsB[k]
+			if okA != okB || va != vb {
+				diff = append(diff, fmt.Sprintf("%s read %q version %s != %s", ns, k, orMissing(va, okA), orMissing(vb, okB)))
+			}
+		}
+		writesA, writesB := make(map[string]string), make(map[string]string)
+		for _, w := range ra.Writes {
+			writesA[w.Key] = writeString(w)
+		}
+		for _, w := range rb.Writes {
+			writesB[w.Key] = writeString(w)
+		}
+		for _, k := range unionStringKeys(writesA, writesB) {
+			va, okA := writesA[k]
+			vb, okB := writesB[k]
+			if okA != okB || va != vb {
+				diff = append(diff, fmt.Sprintf("%s write %q %s != %s", ns, k, orMissing(va, okA), orMissing(vb, okB)))
+			}
+		}
+	}
+	if len(diff) == 0 {
+		diff = append(diff, "range queries or private data hashes are different")
+	}
+	return diff
+}
+
+func rwSetsByNamespace(sets []*blockparser.NsReadWriteSet) map[string]*blockparser.NsReadWriteSet {
+	result := make(map[string]*blockparser.NsReadWriteSet, len(sets))
+	for _, s := range sets {
+		result[s.Namespace] = s
+	}
+	return result
+}
+
+func unionKeys(a, b map[string]*blockparser.NsReadWriteSet) []string {
+	seen := make(map[string]bool)
+	for k := range a {
+		seen[k] = true
+	}
+	for k := range b {
+		seen[k] = true
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func unionStringKeys(a, b map[string]string) []string {
+	seen := make(map[string]bool)
+	for k := range a {
+		seen[k] = true
+	}
+	for k := range b {
+		seen[k] = true
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func versionString(v *blockparser.Version) string {
+	if v == nil {
+		return "nil"
+	}
+	return fmt.Sprintf("%d:%d", v.BlockNum, v.TxNum)
+}
+
+func writeString(w *blockparser.KVWrite) string {
+	if w.IsDelete {
+		return "delete"
+	}
+	return fmt.Sprintf("%q", w.Value)
+}
+
+func orMissing(v string, ok bool) string {
+	if !ok {
+		return "missing"
+	}
+	return v
+}
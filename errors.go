@@ -4,7 +4,19 @@ License: Apache License Version 2.0
 */
 package gohfc
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	gohfcerrors "github.com/CognitionFoundry/gohfc/errors"
+	"github.com/hyperledger/fabric/protos/common"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
 
 var (
 	ErrInvalidAlgorithmFamily       = errors.New("invalid algorithm family")
@@ -18,9 +30,9 @@ var (
 	ErrCertificateEmpty             = errors.New("certificate cannot be nil")
 	ErrInvalidDataForParcelIdentity = errors.New("invalid data for parsing identity")
 	ErrInvalidOrdererName           = errors.New("orderer with this name is not found")
-	ErrOrdererTimeout               = errors.New("orderer response timeout")
+	ErrOrdererTimeout               = newKindError("orderer response timeout", gohfcerrors.ErrTimeout)
 	ErrBadTransactionStatus         = errors.New("transaction status is not 200")
-	ErrEndorsementsDoNotMatch       = errors.New("endorsed responses are different")
+	ErrEndorsementsDoNotMatch       = newKindError("endorsed responses are different", gohfcerrors.ErrEndorsementMismatch)
 	ErrNoValidEndorsementFound      = errors.New("invocation was not endorsed")
 	ErrPeerNameNotFound             = errors.New("peer name is not found")
 	ErrUnsupportedChaincodeType     = errors.New("this chainCode type is not currently supported")
@@ -35,4 +47,308 @@ var (
 	ErrAffiliationNameMissing        = errors.New("affiliation must have name")
 	ErrAffiliationNewNameMissing        = errors.New("affiliation must have new name")
 	ErrIdentityNameMissing        = errors.New("identity must have  name")
+	ErrReadOnlyProposal             = errors.New("read only proposal cannot be send to orderer")
+	ErrUnsupportedTopologyFormat    = errors.New("unsupported topology format")
+	ErrReadOnlyIdentity             = errors.New("identity has no private key and cannot sign")
+	ErrCollectionEndorsementPolicy  = errors.New("collection endorsement policy must have either signature policy or channel config policy")
+	ErrClientClosed                 = errors.New("client is closed")
+	ErrEndorsementQuorumNotReached  = errors.New("required number of matching endorsements was not collected")
+	ErrEndorserVerificationFailed   = errors.New("endorser verification failed")
+	ErrTlsTypeNotRegistered         = errors.New("transport credentials for this TLS type are not registered")
+	ErrPolicyNotSatisfied           = newKindError("policy is not satisfied", gohfcerrors.ErrNotAuthorized)
+	ErrCheckpointConflict           = errors.New("checkpoint was changed by another consumer")
+	ErrTenantNotFound               = errors.New("tenant is not found")
+	ErrTenantNotAllowed             = newKindError("tenant is not allowed to access target", gohfcerrors.ErrNotAuthorized)
+	ErrCommitTimeout                = newKindError("commit event was not received in time", gohfcerrors.ErrTimeout)
+	ErrChainCodeNameMissing         = errors.New("chaincode name is empty and channel has no default chaincode")
+	ErrTenantQuotaExceeded          = errors.New("tenant quota exceeded")
+	ErrSignatureNotLowS             = errors.New("signature S value is greater than half of curve order")
+	ErrOrdererSignatureInvalid      = errors.New("orderer signature is not valid")
+	ErrSchemaNotFound               = errors.New("schema is not found in schema registry")
+	ErrSchemaValidation             = errors.New("payload does not match schema")
+	ErrSchemaTypeNotSupported       = errors.New("schema type is not supported")
+	ErrSchemaNotCached              = errors.New("schema is not fetched from schema registry yet")
+	ErrNoMoreBlocks                 = errors.New("no more blocks")
+	ErrEventPeerMissing             = errors.New("event peer is needed to wait for commit")
+	ErrEventBufferOverflow          = errors.New("event consumer is too slow, event buffer is full")
+	ErrTxIDMismatch                 = errors.New("transaction id does not match identity, nonce or channel hash")
+	ErrNonceEmpty                   = errors.New("nonce is empty")
+	ErrTxNotFound                   = errors.New("transaction is not found on ledger")
+	ErrInvalidPageSize              = errors.New("page size must be greater than zero")
+	ErrKeyEncrypted                 = errors.New("private key is encrypted, password is needed")
+	ErrKeyDecryption                = errors.New("private key cannot be decrypted, password may be wrong")
+	ErrUnsupportedKeyEncryption     = errors.New("private key encryption algorithm is not supported")
+	ErrKdfIterations                = errors.New("iteration count of key derivation is out of range")
+	ErrCertificateMissing           = errors.New("certificate is not found")
+	ErrBroadcastStreamClosed        = errors.New("broadcast stream is closed")
+	ErrInvalidWatchInterval         = errors.New("watch interval must be greater than zero")
+	ErrCheckpointStoreRequired      = errors.New("checkpoint store is required")
+	ErrConsumerRequired             = errors.New("consumer name is required")
 )
+
+// kindError is sentinel error that also matches general error from gohfc/errors package
+type kindError struct {
+	msg  string
+	kind error
+}
+
+func newKindError(msg string, kind error) error {
+	return &kindError{msg: msg, kind: kind}
+}
+
+func (e *kindError) Error() string {
+	return e.msg
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+// channelNotFoundMessage matches messages of peers about missing channel, like "channel 'mychannel' not found"
+var channelNotFoundMessage = regexp.MustCompile(`channel \S+ (is )?not found|channel \S+ does not exist`)
+
+// messageKind returns general error from gohfc/errors package for error message of peer, or nil
+func messageKind(message string) error {
+	message = strings.ToLower(message)
+	switch {
+	case strings.Contains(message, "access denied") || strings.Contains(message, "permission denied"):
+		return gohfcerrors.ErrNotAuthorized
+	case channelNotFoundMessage.MatchString(message):
+		return gohfcerrors.ErrChannelNotFound
+	}
+	return nil
+}
+
+// statusKind returns general error from gohfc/errors package for status of orderer or deliver service, or nil
+func statusKind(s common.Status) error {
+	switch s {
+	case common.Status_FORBIDDEN:
+		return gohfcerrors.ErrNotAuthorized
+	case common.Status_NOT_FOUND:
+		return gohfcerrors.ErrChannelNotFound
+	case common.Status_SERVICE_UNAVAILABLE:
+		return gohfcerrors.ErrServiceUnavailable
+	case common.Status_BAD_REQUEST:
+		return gohfcerrors.ErrBadRequest
+	}
+	return nil
+}
+
+// EndorsementError is returned when peer does not endorse proposal. Err is set when peer cannot be reached,
+// otherwise Status and Message are from peer response.
+type EndorsementError struct {
+	Peer    string
+	Status  int32
+	Message string
+	Err     error
+}
+
+func (e *EndorsementError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("peer %s: %v", e.Peer, e.Err)
+	}
+	return fmt.Sprintf("peer %s returned status %d: %s", e.Peer, e.Status, e.Message)
+}
+
+func (e *EndorsementError) Unwrap() error {
+	return e.Err
+}
+
+// Is allows errors.Is(err, ErrBadTransactionStatus) to match peer responses with non 200 status. Access denied
+// and missing channel responses also match ErrNotAuthorized and ErrChannelNotFound from gohfc/errors.
+func (e *EndorsementError) Is(target error) bool {
+	if e.Err == nil {
+		return target == ErrBadTransactionStatus || (target != nil && target == messageKind(e.Message))
+	}
+	if s, ok := status.FromError(e.Err); ok {
+		switch s.Code() {
+		case codes.PermissionDenied, codes.Unauthenticated:
+			return target == gohfcerrors.ErrNotAuthorized
+		}
+		return target != nil && target == messageKind(s.Message())
+	}
+	return false
+}
+
+// BroadcastError is returned when orderer does not accept transaction
+type BroadcastError struct {
+	Orderer string
+	Status  common.Status
+	Info    string
+}
+
+func (e *BroadcastError) Error() string {
+	return fmt.Sprintf("orderer %s returned status %v: %s", e.Orderer, e.Status, e.Info)
+}
+
+// Is allows errors.Is to match FORBIDDEN status with ErrNotAuthorized, NOT_FOUND with ErrChannelNotFound,
+// SERVICE_UNAVAILABLE with ErrServiceUnavailable and BAD_REQUEST with ErrBadRequest from gohfc/errors
+func (e *BroadcastError) Is(target error) bool {
+	return target != nil && target == statusKind(e.Status)
+}
+
+// Temporary returns true when orderer could not accept transaction at the moment, and the same envelope can be
+// broadcast again later
+func (e *BroadcastError) Temporary() bool {
+	return e.Status == common.Status_SERVICE_UNAVAILABLE
+}
+
+// DeliverError is returned when peer or orderer ends block delivery with status other than SUCCESS
+type DeliverError struct {
+	Node      string
+	ChannelId string
+	Status    common.Status
+}
+
+func (e *DeliverError) Error() string {
+	if e.ChannelId == "" {
+		return fmt.Sprintf("deliver from %s returned status: %s", e.Node, e.Status)
+	}
+	return fmt.Sprintf("deliver of channel %s from %s returned status: %s", e.ChannelId, e.Node, e.Status)
+}
+
+// Is allows errors.Is to match FORBIDDEN status with ErrNotAuthorized and NOT_FOUND with ErrChannelNotFound
+// from gohfc/errors
+func (e *DeliverError) Is(target error) bool {
+	return target != nil && target == statusKind(e.Status)
+}
+
+// TimeoutError is returned when peer or orderer does not respond in time
+type TimeoutError struct {
+	Node string
+	Err  error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timeout waiting for %s: %v", e.Node, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Is allows errors.Is(err, ErrTimeout) from gohfc/errors to match TimeoutError
+func (e *TimeoutError) Is(target error) bool {
+	return target == gohfcerrors.ErrTimeout
+}
+
+// Timeout is always true, it allows TimeoutError to be detected as net.Error style timeout
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// ConnectionError is returned when peer or orderer cannot be reached
+type ConnectionError struct {
+	Node string
+	Err  error
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("cannot connect to %s: %v", e.Node, e.Err)
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
+
+// UnhealthyError is returned by health check when operations endpoint of node reports failed checks
+type UnhealthyError struct {
+	Node   string
+	Health *NodeHealth
+}
+
+func (e *UnhealthyError) Error() string {
+	msg := fmt.Sprintf("node %s is not healthy: %s", e.Node, e.Health.Status)
+	for _, check := range e.Health.FailedChecks {
+		msg += fmt.Sprintf("; %s: %s", check.Component, check.Reason)
+	}
+	return msg
+}
+
+// WarmUpError is returned when client is created with warm up FailFast and some nodes are not ready
+type WarmUpError struct {
+	Report *WarmUpReport
+}
+
+func (e *WarmUpError) Error() string {
+	msg := "nodes are not ready"
+	for _, res := range e.Report.Failed() {
+		msg += fmt.Sprintf("; %s: %v", res.Name, res.Error)
+	}
+	return msg
+}
+
+// CommitError describes transaction that was committed as invalid
+type CommitError struct {
+	TxId string
+	// ValidationCode is validation code name like MVCC_READ_CONFLICT
+	ValidationCode string
+}
+
+func (e *CommitError) Error() string {
+	return fmt.Sprintf("transaction %s is invalid: %s", e.TxId, e.ValidationCode)
+}
+
+// TxExpiredError is returned when commit event of transaction is not received within TTL set with WithTxTTL.
+// Transaction status is unknown, it can still be committed.
+type TxExpiredError struct {
+	TxId string
+	TTL  time.Duration
+}
+
+func (e *TxExpiredError) Error() string {
+	return fmt.Sprintf("transaction %s is not committed within %v", e.TxId, e.TTL)
+}
+
+// IsMVCCConflict returns true if err is CommitError for MVCC_READ_CONFLICT or PHANTOM_READ_CONFLICT.
+// Such transactions can be executed again.
+func IsMVCCConflict(err error) bool {
+	var commit *CommitError
+	if !errors.As(err, &commit) {
+		return false
+	}
+	return commit.ValidationCode == "MVCC_READ_CONFLICT" || commit.ValidationCode == "PHANTOM_READ_CONFLICT"
+}
+
+// IsTxExpired returns true if err is TxExpiredError
+func IsTxExpired(err error) bool {
+	var expired *TxExpiredError
+	return errors.As(err, &expired)
+}
+
+// IsChaincodeError returns true if err is endorsement rejected by chaincode (peer responded with error status)
+func IsChaincodeError(err error) bool {
+	var endorsement *EndorsementError
+	return errors.As(err, &endorsement) && endorsement.Err == nil
+}
+
+// IsTimeout returns true if err is TimeoutError or deadline exceeded
+func IsTimeout(err error) bool {
+	var timeout *TimeoutError
+	return errors.As(err, &timeout) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// IsConnectionError returns true if err is ConnectionError
+func IsConnectionError(err error) bool {
+	var connection *ConnectionError
+	return errors.As(err, &connection)
+}
+
+// rpcError converts gRPC error from node to TimeoutError or ConnectionError when possible
+func rpcError(node string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &TimeoutError{Node: node, Err: err}
+	}
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.DeadlineExceeded:
+			return &TimeoutError{Node: node, Err: err}
+		case codes.Unavailable:
+			return &ConnectionError{Node: node, Err: err}
+		}
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+
+// Package errors defines general errors of gohfc. Errors returned from gohfc match them with errors.Is, whatever
+// the detailed error is:
+//
+//	if errors.Is(err, gohfcerrors.ErrNotAuthorized) { ... }
+//
+// Detailed errors like gohfc.BroadcastError or gohfc.TimeoutError can still be extracted with errors.As.
+// Is, As and Unwrap are the same as in standard errors package, so this package can be imported instead of it.
+package errors
+
+import "errors"
+
+var (
+	// ErrTimeout matches errors caused by peer or orderer not responding in time
+	ErrTimeout = errors.New("timeout")
+	// ErrEndorsementMismatch matches errors caused by endorsing peers returning different responses
+	ErrEndorsementMismatch = errors.New("endorsements do not match")
+	// ErrNotAuthorized matches errors caused by identity that is not allowed to make request
+	ErrNotAuthorized = errors.New("not authorized")
+	// ErrChannelNotFound matches errors caused by channel that does not exist on peer or orderer
+	ErrChannelNotFound = errors.New("channel not found")
+	// ErrServiceUnavailable matches errors caused by orderer that cannot accept requests at the moment, for example
+	// when consensus has no leader. Request can be sent again later.
+	ErrServiceUnavailable = errors.New("service unavailable")
+	// ErrBadRequest matches errors caused by request that orderer rejects as malformed or invalid. Sending it again
+	// does not help.
+	ErrBadRequest = errors.New("bad request")
+)
+
+// New is errors.New from standard library
+func New(text string) error {
+	return errors.New(text)
+}
+
+// Is is errors.Is from standard library
+func Is(err, target error) bool {
+	return errors.Is(err, target)
+}
+
+// As is errors.As from standard library
+func As(err error, target interface{}) bool {
+	return errors.As(err, target)
+}
+
+// Unwrap is errors.Unwrap from standard library
+func Unwrap(err error) error {
+	return errors.Unwrap(err)
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/protos/peer"
+	"github.com/CognitionFoundry/gohfc/blockparser"
 )
 
 const (
@@ -22,11 +23,6 @@ const (
 	EventTypeFiltered
 )
 
-const (
-	maxRecvMsgSize = 100 * 1024 * 1024
-	maxSendMsgSize = 100 * 1024 * 1024
-)
-
 var (
 	oldest  = &orderer.SeekPosition{Type: &orderer.SeekPosition_Oldest{Oldest: &orderer.SeekOldest{}}}
 	newest  = &orderer.SeekPosition{Type: &orderer.SeekPosition_Newest{Newest: &orderer.SeekNewest{}}}
@@ -46,6 +42,12 @@ type EventListener struct {
 	ChannelId    string
 	ListenerType int
 	FullBlock    bool
+	// Decoders decode chaincode event payloads. Optional.
+	Decoders     *EventDecoderRegistry
+	// Archive stores every received full block before it is delivered. Optional.
+	Archive      BlockArchive
+	// Buffer controls what happens when consumer does not receive blocks fast enough. Optional.
+	Buffer       EventBufferConfig
 	connection   *grpc.ClientConn
 	client       deliveryClient
 }
@@ -56,6 +58,19 @@ type EventBlockResponse struct {
 	BlockHeight  uint64
 	Transactions []EventBlockResponseTransaction
 	RawBlock     []byte
+	// Timestamp is the creation time of the last transaction in block. Available only for full block events.
+	Timestamp time.Time
+	// ArchiveError is set when block was not stored in EventListener.Archive
+	ArchiveError error
+}
+
+// BlockResponse is raw block received by ListenBlocks
+type BlockResponse struct {
+	Error     error
+	ChannelId string
+	Block     *common.Block
+	// ArchiveError is set when block was not stored in EventListener.Archive
+	ArchiveError error
 }
 
 type EventBlockResponseTransaction struct {
@@ -64,35 +79,42 @@ type EventBlockResponseTransaction struct {
 	Status      string
 	ChainCodeId string
 	Events      []EventBlockResponseTransactionEvent
+	// ReadWriteSets are keys read and written by this transaction. Available only for full block events.
+	ReadWriteSets []*blockparser.NsReadWriteSet
 }
 
 type EventBlockResponseTransactionEvent struct {
 	Name  string
 	Value []byte
+	// Decoded is Value decoded by decoder from EventDecoderRegistry, nil if there is no decoder for the event
+	Decoded interface{}
+	// DecodeError is error returned by decoder
+	DecodeError error
 }
 
 func (e *EventListener) newConnection() error {
-
-
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	dial := effectiveTimeouts(e.Context, e.Peer.Timeouts).Dial
+	if dial <= 0 {
+		dial = 5 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dial)
 	defer cancel()
-	conn, err := grpc.DialContext(ctx, e.Peer.Uri, e.Peer.Opts...)
+	conn, err := grpc.DialContext(ctx, e.Peer.dialTarget(), e.Peer.Opts...)
 	if err != nil {
-		return fmt.Errorf("cannot make new connection to: %s err: %v", e.Peer.Uri, err)
+		return &ConnectionError{Node: e.Peer.Name, Err: err}
 	}
 	e.connection = conn
 	switch e.ListenerType {
 	case EventTypeFiltered:
 		client, err := peer.NewDeliverClient(e.connection).DeliverFiltered(e.Context)
 		if err != nil {
-			return err
+			return rpcError(e.Peer.Name, err)
 		}
 		e.client = client
 	case EventTypeFullBlock:
 		client, err := peer.NewDeliverClient(e.connection).Deliver(e.Context)
 		if err != nil {
-			return err
+			return rpcError(e.Peer.Name, err)
 		}
 		e.client = client
 	default:
@@ -151,19 +173,114 @@ func (e *EventListener) SeekRange(start, end uint64) error {
 	return e.client.Send(seek)
 }
 
+// SeekFrom starts delivery from block num and continues with new blocks as they are committed
+func (e *EventListener) SeekFrom(num uint64) error {
+	if e.connection == nil || e.client == nil {
+		return fmt.Errorf("cannot seek no connection or client")
+	}
+	pos := &orderer.SeekPosition{Type: &orderer.SeekPosition_Specified{Specified: &orderer.SeekSpecified{Number: num}}}
+	seek, err := e.createSeekEnvelope(pos, maxStop)
+	if err != nil {
+		return err
+	}
+	return e.client.Send(seek)
+}
+
+// ListenRaw sends received blocks to response without decoding them. Listener must be of EventTypeFullBlock.
+// Delivery stops after error is send, when peer ends delivery of requested range or when Context is done.
+func (e *EventListener) ListenRaw(response chan<- BlockResponse) {
+	sender := e.newEventSender(func(ctx context.Context, item interface{}) bool {
+		select {
+		case response <- item.(BlockResponse):
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}, func() interface{} {
+		return BlockResponse{ChannelId: e.ChannelId, Error: ErrEventBufferOverflow}
+	})
+	go func() {
+		for {
+			msg, err := e.client.Recv()
+			if err != nil {
+				logger().Warn("block stream failed", "peer", e.Peer.Name, "channel", e.ChannelId, "error", err)
+				sender.final(BlockResponse{ChannelId: e.ChannelId, Error: fmt.Errorf("error receiving data:%w", rpcError(e.Peer.Name, err))})
+				return
+			}
+			switch t := msg.Type.(type) {
+			case *peer.DeliverResponse_Block:
+				resp := BlockResponse{ChannelId: e.ChannelId, Block: t.Block}
+				if e.Archive != nil {
+					if err := e.Archive.PutBlock(e.ChannelId, t.Block); err != nil {
+						logger().Error("block archiving failed", "channel", e.ChannelId, "block", t.Block.Header.Number, "error", err)
+						resp.ArchiveError = err
+					}
+				}
+				logger().Debug("raw block received", "peer", e.Peer.Name, "channel", e.ChannelId, "block", t.Block.Header.Number)
+				if !sender.deliver(resp) {
+					return
+				}
+			case *peer.DeliverResponse_Status:
+				if t.Status != common.Status_SUCCESS {
+					sender.final(BlockResponse{ChannelId: e.ChannelId, Error: &DeliverError{Node: e.Peer.Name, ChannelId: e.ChannelId, Status: t.Status}})
+				}
+				return
+			}
+		}
+	}()
+}
+
+// listenContext returns Context of listener, background context when it is not set
+func (e *EventListener) listenContext() context.Context {
+	if e.Context == nil {
+		return context.Background()
+	}
+	return e.Context
+}
+
 func (e *EventListener) Listen(response chan<- EventBlockResponse) {
+	sender := e.newEventSender(func(ctx context.Context, item interface{}) bool {
+		select {
+		case response <- item.(EventBlockResponse):
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}, func() interface{} {
+		return EventBlockResponse{ChannelId: e.ChannelId, Error: ErrEventBufferOverflow}
+	})
 	go func() {
 		for {
 			msg, err := e.client.Recv()
 			if err != nil {
-				response <- EventBlockResponse{Error: fmt.Errorf("error receiving data:%v", err)}
+				logger().Warn("event stream failed", "peer", e.Peer.Name, "channel", e.ChannelId, "error", err)
+				sender.final(EventBlockResponse{Error: fmt.Errorf("error receiving data:%w", rpcError(e.Peer.Name, err))})
 				return
 			}
 			switch t := msg.Type.(type) {
 			case *peer.DeliverResponse_Block:
-				response <- *e.parseFullBlock(t, e.FullBlock)
+				block := e.Decoders.decodeBlock(e.parseFullBlock(t, e.FullBlock))
+				if e.Archive != nil {
+					if err := e.Archive.PutBlock(e.ChannelId, t.Block); err != nil {
+						logger().Error("block archiving failed", "channel", e.ChannelId, "block", block.BlockHeight, "error", err)
+						block.ArchiveError = err
+					}
+				}
+				logger().Debug("block received", "peer", e.Peer.Name, "channel", e.ChannelId,
+					"block", block.BlockHeight, "transactions", len(block.Transactions))
+				if !block.Timestamp.IsZero() {
+					metrics().EventLag(e.Peer.Name, e.ChannelId, time.Since(block.Timestamp))
+				}
+				if !sender.deliver(*block) {
+					return
+				}
 			case *peer.DeliverResponse_FilteredBlock:
-				response <- *e.parseFilteredBlock(t, e.FullBlock)
+				block := e.parseFilteredBlock(t, e.FullBlock)
+				logger().Debug("filtered block received", "peer", e.Peer.Name, "channel", e.ChannelId,
+					"block", block.BlockHeight, "transactions", len(block.Transactions))
+				if !sender.deliver(*block) {
+					return
+				}
 			}
 		}
 	}()
@@ -251,6 +368,9 @@ func (e *EventListener) parseFullBlock(block *peer.DeliverResponse_Block, fullBl
 
 		response.ChannelId = header.ChannelId
 		transaction.Id = header.TxId
+		if header.Timestamp != nil {
+			response.Timestamp = time.Unix(header.Timestamp.Seconds, int64(header.Timestamp.Nanos))
+		}
 
 		transaction.Status = peer.TxValidationCode_name[int32(block.Block.Metadata.Metadata[2][idx])]
 		transaction.Type = common.HeaderType_name[header.Type]
@@ -283,6 +403,12 @@ func (e *EventListener) parseFullBlock(block *peer.DeliverResponse_Block, fullBl
 				response.Error = err
 				return response
 			}
+			rwSets, err := blockparser.ParseReadWriteSet(caPayload.Results)
+			if err != nil {
+				response.Error = err
+				return response
+			}
+			transaction.ReadWriteSets = rwSets
 			ccEvent := &peer.ChaincodeEvent{}
 			err = proto.Unmarshal(caPayload.Events, ccEvent)
 			if err != nil {
@@ -301,12 +427,16 @@ func (e *EventListener) parseFullBlock(block *peer.DeliverResponse_Block, fullBl
 }
 
 func (e *EventListener) createSeekEnvelope(start *orderer.SeekPosition, stop *orderer.SeekPosition) (*common.Envelope, error) {
-
-	marshaledIdentity, err := marshalProtoIdentity(e.Identity)
-	if err != nil {
-		return nil, err
+	// identity without certificate is used by followers on channels that allow anonymous deliver
+	var marshaledIdentity []byte
+	if e.Identity.Certificate != nil {
+		var err error
+		marshaledIdentity, err = marshalProtoIdentity(e.Identity)
+		if err != nil {
+			return nil, err
+		}
 	}
-	nonce, err := generateRandomBytes(24)
+	nonce, err := generateRandomBytes(nonceSize)
 	if err != nil {
 		return nil, err
 	}
@@ -318,9 +448,9 @@ func (e *EventListener) createSeekEnvelope(start *orderer.SeekPosition, stop *or
 			Seconds: time.Now().Unix(),
 			Nanos:   0,
 		},
-		ChannelId: e.ChannelId,
-		Epoch:     0,
-		// TlsCertHash:[]
+		ChannelId:   e.ChannelId,
+		Epoch:       0,
+		TlsCertHash: e.Peer.tlsCertHash(),
 	})
 	if err != nil {
 		return nil, err
@@ -354,6 +484,9 @@ func (e *EventListener) createSeekEnvelope(start *orderer.SeekPosition, stop *or
 		return nil, err
 	}
 
+	if e.Identity.ReadOnly() {
+		return &common.Envelope{Payload: payload}, nil
+	}
 	sig, err := e.Crypto.Sign(payload, e.Identity.PrivateKey)
 	if err != nil {
 		return nil, err
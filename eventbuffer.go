@@ -0,0 +1,166 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// defaultEventBuffer is number of blocks buffered by listener when overflow policy is set and buffer is not
+const defaultEventBuffer = 64
+
+// OverflowPolicy decides what listener does when consumer does not receive blocks fast enough
+type OverflowPolicy string
+
+const (
+	// OverflowBlock waits until consumer receives block. Block stream from peer is not read meanwhile. Default.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest drops the oldest buffered block to make room for new one, so stream is always read
+	OverflowDropOldest OverflowPolicy = "dropOldest"
+	// OverflowError stops listening and sends ErrEventBufferOverflow when buffer is full
+	OverflowError OverflowPolicy = "error"
+)
+
+// EventBufferConfig controls buffering between block stream and consumer channel of event listeners
+type EventBufferConfig struct {
+	// Buffer is number of blocks buffered when consumer is slow, default is 64. Used only with Overflow other than
+	// OverflowBlock, consumer channel has its own buffer.
+	Buffer int `yaml:"buffer"`
+	// Overflow is policy when buffer is full, default is OverflowBlock
+	Overflow OverflowPolicy `yaml:"overflow"`
+}
+
+// validate checks that overflow policy is known
+func (c EventBufferConfig) validate() error {
+	switch c.Overflow {
+	case "", OverflowBlock, OverflowDropOldest, OverflowError:
+		return nil
+	}
+	return fmt.Errorf("invalid event overflow policy %q, must be %s, %s or %s", c.Overflow, OverflowBlock,
+		OverflowDropOldest, OverflowError)
+}
+
+// eventQueue buffers blocks between listener and consumer channel
+type eventQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	items   []interface{}
+	size    int
+	closed  bool
+	dropped uint64
+}
+
+func newEventQueue(size int) *eventQueue {
+	if size < 1 {
+		size = defaultEventBuffer
+	}
+	q := &eventQueue{size: size}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push adds item to queue. When queue is full and dropOldest is set, the oldest item is dropped, otherwise false is
+// returned and item is not added.
+func (q *eventQueue) push(item interface{}, dropOldest bool) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) >= q.size {
+		if !dropOldest {
+			return false
+		}
+		q.items[0] = nil
+		q.items = q.items[1:]
+		q.dropped++
+	}
+	q.items = append(q.items, item)
+	q.cond.Signal()
+	return true
+}
+
+// closeWith adds final item regardless of size and closes queue
+func (q *eventQueue) closeWith(item interface{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.items = append(q.items, item)
+	q.closed = true
+	q.cond.Signal()
+}
+
+// pop waits for item, returns false when queue is closed and empty
+func (q *eventQueue) pop() (interface{}, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	item := q.items[0]
+	q.items[0] = nil
+	q.items = q.items[1:]
+	return item, true
+}
+
+// forward sends items to consumer until queue is closed and empty or ctx is done
+func (q *eventQueue) forward(ctx context.Context, send func(context.Context, interface{}) bool) {
+	for {
+		item, ok := q.pop()
+		if !ok || ctx.Err() != nil {
+			return
+		}
+		if !send(ctx, item) {
+			return
+		}
+	}
+}
+
+// eventSender delivers blocks of listener to consumer according to overflow policy. deliver returns false when
+// listener must stop because buffer overflowed or context is done, final is used for the last item.
+type eventSender struct {
+	deliver func(item interface{}) bool
+	final   func(item interface{})
+}
+
+// newEventSender returns sender that calls send directly for OverflowBlock, or through queue for other policies.
+// send must return false without waiting for consumer when ctx is done. overflow creates item send to consumer when
+// OverflowError stops listener.
+func (e *EventListener) newEventSender(send func(context.Context, interface{}) bool,
+	overflow func() interface{}) eventSender {
+	ctx := e.listenContext()
+	if e.Buffer.Overflow == "" || e.Buffer.Overflow == OverflowBlock {
+		return eventSender{deliver: func(item interface{}) bool { return send(ctx, item) },
+			final: func(item interface{}) { send(ctx, item) }}
+	}
+	q := newEventQueue(e.Buffer.Buffer)
+	go q.forward(ctx, send)
+	dropOldest := e.Buffer.Overflow == OverflowDropOldest
+	var dropped uint64
+	return eventSender{
+		deliver: func(item interface{}) bool {
+			if q.push(item, dropOldest) {
+				q.mu.Lock()
+				total := q.dropped
+				q.mu.Unlock()
+				if total > dropped {
+					dropped = total
+					logger().Warn("slow event consumer, blocks dropped", "peer", e.Peer.Name, "channel", e.ChannelId,
+						"dropped", dropped)
+				}
+				return true
+			}
+			logger().Error("slow event consumer, event buffer is full", "peer", e.Peer.Name, "channel", e.ChannelId,
+				"buffer", q.size)
+			q.closeWith(overflow())
+			if e.connection != nil {
+				e.connection.Close()
+			}
+			return false
+		},
+		final: q.closeWith,
+	}
+}
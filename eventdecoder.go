@@ -0,0 +1,114 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// EventDecoder decodes chaincode event payload
+type EventDecoder interface {
+	Decode(payload []byte) (interface{}, error)
+}
+
+// EventDecoderFunc is function that implements EventDecoder. Use it for formats that gohfc does not know about,
+// for example Avro payloads decoded with schema codec:
+//
+//	registry.Register("mycc", "transfer", gohfc.EventDecoderFunc(func(p []byte) (interface{}, error) {
+//		v, _, err := codec.NativeFromBinary(p)
+//		return v, err
+//	}))
+type EventDecoderFunc func(payload []byte) (interface{}, error)
+
+// Decode calls f
+func (f EventDecoderFunc) Decode(payload []byte) (interface{}, error) {
+	return f(payload)
+}
+
+// ProtoEventDecoder decodes payload as protobuf message of the same type as msg. Decoded value is new message
+// of that type, msg itself is not modified.
+func ProtoEventDecoder(msg proto.Message) EventDecoder {
+	t := reflect.TypeOf(msg).Elem()
+	return EventDecoderFunc(func(payload []byte) (interface{}, error) {
+		m := reflect.New(t).Interface().(proto.Message)
+		if err := proto.Unmarshal(payload, m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	})
+}
+
+// JSONEventDecoder decodes JSON payload into new value of the same type as v. v must be a pointer.
+func JSONEventDecoder(v interface{}) EventDecoder {
+	t := reflect.TypeOf(v).Elem()
+	return EventDecoderFunc(func(payload []byte) (interface{}, error) {
+		d := reflect.New(t).Interface()
+		if err := json.Unmarshal(payload, d); err != nil {
+			return nil, err
+		}
+		return d, nil
+	})
+}
+
+// EventDecoderRegistry holds decoders for chaincode events. When registry is set in FabricClient.EventDecoders
+// (or EventListener.Decoders) payloads of matching events arrive decoded in
+// EventBlockResponseTransactionEvent.Decoded. Events without decoder are not changed.
+type EventDecoderRegistry struct {
+	mu       sync.RWMutex
+	decoders map[string]map[string]EventDecoder
+}
+
+// NewEventDecoderRegistry creates empty registry
+func NewEventDecoderRegistry() *EventDecoderRegistry {
+	return &EventDecoderRegistry{decoders: make(map[string]map[string]EventDecoder)}
+}
+
+// Register sets decoder for events with eventName emitted by chaincode. Empty eventName registers default decoder
+// for all events of chaincode that have no decoder of their own.
+func (r *EventDecoderRegistry) Register(chainCodeId, eventName string, decoder EventDecoder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.decoders[chainCodeId] == nil {
+		r.decoders[chainCodeId] = make(map[string]EventDecoder)
+	}
+	r.decoders[chainCodeId][eventName] = decoder
+}
+
+// Decoder returns decoder for event, falling back to default decoder of chaincode
+func (r *EventDecoderRegistry) Decoder(chainCodeId, eventName string) (EventDecoder, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	events := r.decoders[chainCodeId]
+	if d, ok := events[eventName]; ok {
+		return d, true
+	}
+	d, ok := events[""]
+	return d, ok
+}
+
+// decodeBlock decodes payloads of all events in block that have decoder. Filtered blocks carry no payloads, so
+// only full block events are decoded. Registry can be nil.
+func (r *EventDecoderRegistry) decodeBlock(block *EventBlockResponse) *EventBlockResponse {
+	if r == nil {
+		return block
+	}
+	for i := range block.Transactions {
+		tx := &block.Transactions[i]
+		for j := range tx.Events {
+			ev := &tx.Events[j]
+			if ev.Value == nil {
+				continue
+			}
+			if d, ok := r.Decoder(tx.ChainCodeId, ev.Name); ok {
+				ev.Decoded, ev.DecodeError = d.Decode(ev.Value)
+			}
+		}
+	}
+	return block
+}
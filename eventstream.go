@@ -0,0 +1,491 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// EventStreamConfig holds options for NewEventStream
+type EventStreamConfig struct {
+	// FullBlock selects full blocks. Chaincode event payloads and read/write sets are available only in full blocks.
+	FullBlock bool
+	// StartBlock is the first delivered block, newest block when nil
+	StartBlock *uint64
+	// ReconnectDelay is time to wait before connecting again when block stream fails. Zero means stream stops on
+	// the first failure.
+	ReconnectDelay time.Duration
+	// Overflow is policy when registered channel is full. With OverflowDropOldest the oldest event in channel is
+	// dropped, with OverflowError channel is closed and removed while other consumers continue. Default is
+	// OverflowBlock, slow consumer holds all others.
+	Overflow OverflowPolicy
+}
+
+// ChaincodeEvent is chaincode event of transaction delivered by EventStream
+type ChaincodeEvent struct {
+	ChannelId   string
+	BlockHeight uint64
+	TxId        string
+	ChainCodeId string
+	// ValidationCode is validation code name of transaction like VALID or MVCC_READ_CONFLICT
+	ValidationCode string
+	Event          EventBlockResponseTransactionEvent
+}
+
+// ConnectionEvent notifies about state of EventStream connection to event peer
+type ConnectionEvent struct {
+	Peer      string
+	ChannelId string
+	// Connected is true when stream is connected and delivers blocks from NextBlock
+	Connected bool
+	// NextBlock is the next block stream expects, zero before the first block when StartBlock is nil
+	NextBlock uint64
+	// Error is cause of disconnection
+	Error error
+}
+
+// EventStream listens for blocks of single channel and delivers them split into typed streams. Every consumer
+// registers only for events it needs, before Start. By default sends to registered channels block until consumer receives them,
+// so slow consumer holds all others, see EventStreamConfig.Overflow. All channels are closed when stream stops.
+type EventStream struct {
+	client    *FabricClient
+	ctx       context.Context
+	start     sync.Once
+	identity  Identity
+	eventPeer string
+	channelId string
+	config    EventStreamConfig
+	mu        sync.Mutex
+	subs      []*eventSubscription
+	stopped   bool
+	err       error
+	done      chan struct{}
+}
+
+// eventSubscription is single registration, only one of channels is set
+type eventSubscription struct {
+	done       chan struct{}
+	overflow   OverflowPolicy
+	overflowed bool
+	dropped    uint64
+	blocks     chan EventBlockResponse
+	txStatus   chan TxStatusEvent
+	chainCode  string
+	eventName  *regexp.Regexp
+	ccEvents   chan ChaincodeEvent
+	conn       chan ConnectionEvent
+}
+
+// NewEventStream creates stream of blocks of channelId from eventPeer. Register consumers and then call Start.
+// Listening stops when ctx is canceled or block stream fails and ReconnectDelay is not set.
+func (c *FabricClient) NewEventStream(ctx context.Context, identity Identity, eventPeer, channelId string,
+	config EventStreamConfig) (*EventStream, error) {
+	if _, ok := c.getEventPeer(eventPeer); !ok {
+		return nil, ErrPeerNameNotFound
+	}
+	s := &EventStream{client: c, ctx: ctx, identity: identity, eventPeer: eventPeer, channelId: channelId,
+		config: config, done: make(chan struct{})}
+	return s, nil
+}
+
+// Start starts listening. Consumers registered before Start receive all events from StartBlock, consumers
+// registered later only events delivered after registration. Calling Start again has no effect.
+func (s *EventStream) Start() {
+	s.start.Do(func() { go s.run(s.ctx) })
+}
+
+// RotateIdentity replaces identity of stream. Open block stream is not interrupted, identity is used from the next
+// reconnect.
+func (s *EventStream) RotateIdentity(identity Identity) {
+	s.mu.Lock()
+	s.identity = identity
+	s.mu.Unlock()
+}
+
+// RegisterBlockEvents returns channel of all blocks with buffer size. Returned func unregisters it, channel is not
+// closed by unregister.
+func (s *EventStream) RegisterBlockEvents(buffer int) (<-chan EventBlockResponse, func()) {
+	sub := &eventSubscription{blocks: make(chan EventBlockResponse, buffer)}
+	return sub.blocks, s.register(sub)
+}
+
+// RegisterTxStatusEvents returns channel of validation results of all transactions
+func (s *EventStream) RegisterTxStatusEvents(buffer int) (<-chan TxStatusEvent, func()) {
+	sub := &eventSubscription{txStatus: make(chan TxStatusEvent, buffer)}
+	return sub.txStatus, s.register(sub)
+}
+
+// RegisterChaincodeEvents returns channel of events of chainCode whose names match eventName. Empty chainCode
+// selects all chaincodes and nil eventName all events. Events of invalid transactions are delivered too, check
+// ValidationCode.
+func (s *EventStream) RegisterChaincodeEvents(chainCode string, eventName *regexp.Regexp, buffer int) (<-chan ChaincodeEvent, func()) {
+	sub := &eventSubscription{chainCode: chainCode, eventName: eventName, ccEvents: make(chan ChaincodeEvent, buffer)}
+	return sub.ccEvents, s.register(sub)
+}
+
+// RegisterConnectionEvents returns channel of connect and disconnect notifications
+func (s *EventStream) RegisterConnectionEvents(buffer int) (<-chan ConnectionEvent, func()) {
+	sub := &eventSubscription{conn: make(chan ConnectionEvent, buffer)}
+	return sub.conn, s.register(sub)
+}
+
+// Done is closed when stream stops
+func (s *EventStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the error that stopped stream
+func (s *EventStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// register adds subscription and returns func that removes it. Channels of subscription registered after stream
+// stopped are closed immediately.
+func (s *EventStream) register(sub *eventSubscription) func() {
+	sub.done = make(chan struct{})
+	sub.overflow = s.config.Overflow
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		sub.close()
+		return func() {}
+	}
+	s.subs = append(s.subs, sub)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			for i, existing := range s.subs {
+				if existing == sub {
+					s.subs = append(s.subs[:i:i], s.subs[i+1:]...)
+					break
+				}
+			}
+			close(sub.done)
+		})
+	}
+}
+
+func (s *EventStream) run(ctx context.Context) {
+	var next uint64
+	started := s.config.StartBlock != nil
+	if started {
+		next = *s.config.StartBlock
+	}
+	for {
+		err := s.listen(ctx, &next, &started)
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		s.publish(ctx, func(sub *eventSubscription) {
+			if sub.conn != nil {
+				sub.send(ctx, ConnectionEvent{Peer: s.eventPeer, ChannelId: s.channelId, NextBlock: next, Error: err})
+			}
+		})
+		if ctx.Err() != nil || s.config.ReconnectDelay <= 0 {
+			s.stop(err)
+			return
+		}
+		logger().Warn("event stream disconnected", "peer", s.eventPeer, "channel", s.channelId, "nextBlock", next,
+			"error", err)
+		t := time.NewTimer(s.config.ReconnectDelay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			s.stop(ctx.Err())
+			return
+		}
+	}
+}
+
+// listen delivers blocks until block stream fails
+func (s *EventStream) listen(ctx context.Context, next *uint64, started *bool) error {
+	ep, ok := s.client.getEventPeer(s.eventPeer)
+	if !ok {
+		return ErrPeerNameNotFound
+	}
+	listenerType := EventTypeFiltered
+	if s.config.FullBlock {
+		listenerType = EventTypeFullBlock
+	}
+	listenCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.mu.Lock()
+	identity := s.identity
+	s.mu.Unlock()
+	listener, err := NewEventListener(listenCtx, s.client.cryptoSuite(identity, s.channelId), identity, *ep,
+		s.channelId, listenerType)
+	if err != nil {
+		return err
+	}
+	defer listener.connection.Close()
+	listener.FullBlock = s.config.FullBlock
+	listener.Decoders = s.client.EventDecoders
+	if *started {
+		err = listener.SeekFrom(*next)
+	} else {
+		err = listener.SeekNewest()
+	}
+	if err != nil {
+		return err
+	}
+	s.publish(ctx, func(sub *eventSubscription) {
+		if sub.conn != nil {
+			sub.send(ctx, ConnectionEvent{Peer: s.eventPeer, ChannelId: s.channelId, Connected: true, NextBlock: *next})
+		}
+	})
+	// listener stops sending when listenCtx is canceled on return, so nothing is left blocked on blocks
+	blocks := make(chan EventBlockResponse)
+	listener.Listen(blocks)
+	for {
+		var block EventBlockResponse
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case block = <-blocks:
+		}
+		if block.Error != nil {
+			return block.Error
+		}
+		*next, *started = block.BlockHeight+1, true
+		s.publish(ctx, func(sub *eventSubscription) { sub.deliver(ctx, block) })
+	}
+}
+
+// publish calls fn for every subscription registered at the time of call
+func (s *EventStream) publish(ctx context.Context, fn func(*eventSubscription)) {
+	s.mu.Lock()
+	subs := make([]*eventSubscription, len(s.subs))
+	copy(subs, s.subs)
+	s.mu.Unlock()
+	for _, sub := range subs {
+		dropped := sub.dropped
+		fn(sub)
+		if sub.dropped > dropped {
+			logger().Warn("slow event consumer, events dropped", "peer", s.eventPeer, "channel", s.channelId,
+				"dropped", sub.dropped)
+		}
+		if sub.overflowed {
+			logger().Error("slow event consumer, channel is full and removed", "peer", s.eventPeer,
+				"channel", s.channelId)
+			s.remove(sub)
+		}
+	}
+}
+
+// remove removes overflowed subscription and closes its channel
+func (s *EventStream) remove(sub *eventSubscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, existing := range s.subs {
+		if existing == sub {
+			s.subs = append(s.subs[:i:i], s.subs[i+1:]...)
+			sub.close()
+			return
+		}
+	}
+}
+
+// stop closes channels of all subscriptions
+func (s *EventStream) stop(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped, s.err = true, err
+	for _, sub := range s.subs {
+		sub.close()
+	}
+	s.subs = nil
+	close(s.done)
+}
+
+// deliver sends parts of block subscription is registered for
+func (sub *eventSubscription) deliver(ctx context.Context, block EventBlockResponse) {
+	switch {
+	case sub.blocks != nil:
+		sub.send(ctx, block)
+	case sub.txStatus != nil:
+		for _, tx := range block.Transactions {
+			if !sub.send(ctx, TxStatusEvent{TxId: tx.Id, ChannelId: block.ChannelId, BlockHeight: block.BlockHeight,
+				ValidationCode: tx.Status}) {
+				return
+			}
+		}
+	case sub.ccEvents != nil:
+		for _, tx := range block.Transactions {
+			if sub.chainCode != "" && tx.ChainCodeId != sub.chainCode {
+				continue
+			}
+			for _, e := range tx.Events {
+				if e.Name == "" || (sub.eventName != nil && !sub.eventName.MatchString(e.Name)) {
+					continue
+				}
+				if !sub.send(ctx, ChaincodeEvent{ChannelId: block.ChannelId, BlockHeight: block.BlockHeight, TxId: tx.Id,
+					ChainCodeId: tx.ChainCodeId, ValidationCode: tx.Status, Event: e}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// send sends event to channel of subscription according to overflow policy, returns false when subscription is
+// removed, overflowed or ctx is done
+func (sub *eventSubscription) send(ctx context.Context, event interface{}) bool {
+	select {
+	case <-sub.done:
+		return false
+	default:
+	}
+	switch sub.overflow {
+	case OverflowDropOldest:
+		for !sub.trySend(event) {
+			if !sub.dropOldest() && sub.capacity() == 0 {
+				// unbuffered channel has nothing to drop, the new event is dropped instead
+				sub.dropped++
+				break
+			}
+		}
+		return true
+	case OverflowError:
+		if !sub.trySend(event) {
+			sub.overflowed = true
+			return false
+		}
+		return true
+	}
+	switch e := event.(type) {
+	case EventBlockResponse:
+		select {
+		case sub.blocks <- e:
+		case <-sub.done:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	case TxStatusEvent:
+		select {
+		case sub.txStatus <- e:
+		case <-sub.done:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	case ChaincodeEvent:
+		select {
+		case sub.ccEvents <- e:
+		case <-sub.done:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	case ConnectionEvent:
+		select {
+		case sub.conn <- e:
+		case <-sub.done:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// trySend sends event without waiting, returns false when channel is full
+func (sub *eventSubscription) trySend(event interface{}) bool {
+	switch e := event.(type) {
+	case EventBlockResponse:
+		select {
+		case sub.blocks <- e:
+			return true
+		default:
+		}
+	case TxStatusEvent:
+		select {
+		case sub.txStatus <- e:
+			return true
+		default:
+		}
+	case ChaincodeEvent:
+		select {
+		case sub.ccEvents <- e:
+			return true
+		default:
+		}
+	case ConnectionEvent:
+		select {
+		case sub.conn <- e:
+			return true
+		default:
+		}
+	}
+	return false
+}
+
+// capacity returns buffer size of channel of subscription
+func (sub *eventSubscription) capacity() int {
+	switch {
+	case sub.blocks != nil:
+		return cap(sub.blocks)
+	case sub.txStatus != nil:
+		return cap(sub.txStatus)
+	case sub.ccEvents != nil:
+		return cap(sub.ccEvents)
+	case sub.conn != nil:
+		return cap(sub.conn)
+	}
+	return 0
+}
+
+// dropOldest removes the oldest event from channel, returns false when channel is empty
+func (sub *eventSubscription) dropOldest() bool {
+	var ok bool
+	switch {
+	case sub.blocks != nil:
+		select {
+		case _, ok = <-sub.blocks:
+		default:
+		}
+	case sub.txStatus != nil:
+		select {
+		case _, ok = <-sub.txStatus:
+		default:
+		}
+	case sub.ccEvents != nil:
+		select {
+		case _, ok = <-sub.ccEvents:
+		default:
+		}
+	case sub.conn != nil:
+		select {
+		case _, ok = <-sub.conn:
+		default:
+		}
+	}
+	if ok {
+		sub.dropped++
+	}
+	return ok
+}
+
+// close closes channel of subscription
+func (sub *eventSubscription) close() {
+	switch {
+	case sub.blocks != nil:
+		close(sub.blocks)
+	case sub.txStatus != nil:
+		close(sub.txStatus)
+	case sub.ccEvents != nil:
+		close(sub.ccEvents)
+	case sub.conn != nil:
+		close(sub.conn)
+	}
+}
@@ -0,0 +1,505 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/protos/orderer"
+)
+
+const (
+	// FailoverPrimary is the name of primary network profile of FailoverClient
+	FailoverPrimary = "primary"
+	// FailoverDR is the name of disaster recovery network profile of FailoverClient
+	FailoverDR = "dr"
+)
+
+// FailoverConfig configures FailoverClient. Zero values are replaced with defaults.
+type FailoverConfig struct {
+	// FailureThreshold is number of consecutive requests that could not reach primary nodes after which requests
+	// are switched to DR, default 3
+	FailureThreshold int
+	// RetryPrimaryAfter is time after switch to DR when request is send to primary again, default 1 minute.
+	// When primary responds requests switch back to it.
+	RetryPrimaryAfter time.Duration
+	// ListenRetryDelay is wait before listener is started again after event stream failed, default 1 second
+	ListenRetryDelay time.Duration
+	// OnSwitch is called when requests switch from one profile to another. cause is the error that caused switch
+	// to DR and nil when switching back to primary.
+	OnSwitch func(from, to string, cause error)
+}
+
+// FailoverClient sends requests to primary network profile and switches invokes, queries and listeners to warm
+// standby DR profile when primary peers and orderers are unreachable. Only connection errors and timeouts count as
+// failures, chaincode and endorsement errors are returned without switching. Both profiles must use the same peer
+// and orderer names. It is safe for concurrent use.
+type FailoverClient struct {
+	Primary *FabricClient
+	DR      *FabricClient
+
+	config     FailoverConfig
+	mu         sync.Mutex
+	active     string
+	failures   int
+	switchedAt time.Time
+}
+
+var _ Handler = (*FailoverClient)(nil)
+
+// NewFailoverClient creates failover client from clients of primary and DR profile
+func NewFailoverClient(primary, dr *FabricClient, config FailoverConfig) *FailoverClient {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 3
+	}
+	if config.RetryPrimaryAfter <= 0 {
+		config.RetryPrimaryAfter = time.Minute
+	}
+	if config.ListenRetryDelay <= 0 {
+		config.ListenRetryDelay = time.Second
+	}
+	return &FailoverClient{Primary: primary, DR: dr, config: config, active: FailoverPrimary}
+}
+
+// NewFailoverClientFromConfig creates failover client from client configs of primary and DR profile
+func NewFailoverClientFromConfig(primary, dr ClientConfig, config FailoverConfig) (*FailoverClient, error) {
+	p, err := NewFabricClientFromConfig(primary)
+	if err != nil {
+		return nil, fmt.Errorf("primary profile: %v", err)
+	}
+	d, err := NewFabricClientFromConfig(dr)
+	if err != nil {
+		return nil, fmt.Errorf("dr profile: %v", err)
+	}
+	return NewFailoverClient(p, d, config), nil
+}
+
+// Active returns name of profile requests are send to, FailoverPrimary or FailoverDR
+func (c *FailoverClient) Active() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.active
+}
+
+// current returns client for next request. Primary is returned when DR is active and it is time to retry primary.
+func (c *FailoverClient) current() (*FabricClient, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.active == FailoverPrimary || time.Since(c.switchedAt) >= c.config.RetryPrimaryAfter {
+		return c.Primary, FailoverPrimary
+	}
+	return c.DR, FailoverDR
+}
+
+func (c *FailoverClient) profileOf(client *FabricClient) string {
+	if client == c.Primary {
+		return FailoverPrimary
+	}
+	return FailoverDR
+}
+
+// observe records result of request to profile. It returns true when request should be repeated on DR.
+func (c *FailoverClient) observe(profile string, err error) bool {
+	if profile != FailoverPrimary {
+		return false
+	}
+	unreachable := err != nil && (IsConnectionError(err) || IsTimeout(err))
+	var retry bool
+	var from, to string
+	c.mu.Lock()
+	switch {
+	case !unreachable:
+		c.failures = 0
+		if c.active == FailoverDR {
+			c.active, from, to = FailoverPrimary, FailoverDR, FailoverPrimary
+		}
+	case c.active == FailoverDR:
+		// primary is still down, wait RetryPrimaryAfter again
+		c.switchedAt, retry = time.Now(), true
+	default:
+		c.failures++
+		if c.failures >= c.config.FailureThreshold {
+			c.active, c.switchedAt, retry = FailoverDR, time.Now(), true
+			from, to = FailoverPrimary, FailoverDR
+		}
+	}
+	c.mu.Unlock()
+	if to != "" {
+		logger().Warn("network profile switched", "from", from, "to", to, "error", err)
+		if c.config.OnSwitch != nil {
+			if unreachable {
+				c.config.OnSwitch(from, to, err)
+			} else {
+				c.config.OnSwitch(from, to, nil)
+			}
+		}
+	}
+	return retry
+}
+
+// do calls fn with current client and repeats it on DR when primary is unreachable. fn returns error that
+// describes reachability of nodes.
+func (c *FailoverClient) do(fn func(client *FabricClient) error) {
+	client, profile := c.current()
+	if c.observe(profile, fn(client)) {
+		client, profile = c.current()
+		c.observe(profile, fn(client))
+	}
+}
+
+// unreachableError returns the first error when all errors are connection errors or timeouts, otherwise nil
+func unreachableError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	for _, err := range errs {
+		if err == nil || !(IsConnectionError(err) || IsTimeout(err)) {
+			return nil
+		}
+	}
+	return errs[0]
+}
+
+func queryErrors(responses []*QueryResponse) []error {
+	errs := make([]error, len(responses))
+	for i, r := range responses {
+		errs[i] = r.Error
+	}
+	return errs
+}
+
+func peerErrors(responses []*PeerResponse) []error {
+	errs := make([]error, len(responses))
+	for i, r := range responses {
+		errs[i] = r.Err
+	}
+	return errs
+}
+
+func (c *FailoverClient) Query(identity Identity, chainCode ChainCode, peers []string) ([]*QueryResponse, error) {
+	return c.QueryWithContext(context.Background(), identity, chainCode, peers)
+}
+
+func (c *FailoverClient) QueryWithContext(ctx context.Context, identity Identity, chainCode ChainCode, peers []string) (resp []*QueryResponse, err error) {
+	c.do(func(client *FabricClient) error {
+		resp, err = client.QueryWithContext(ctx, identity, chainCode, peers)
+		if err != nil {
+			return err
+		}
+		return unreachableError(queryErrors(resp))
+	})
+	return resp, err
+}
+
+func (c *FailoverClient) Invoke(identity Identity, chainCode ChainCode, peers []string, orderer string) (*InvokeResponse, error) {
+	return c.InvokeWithContext(context.Background(), identity, chainCode, peers, orderer)
+}
+
+// InvokeWithContext is repeated on DR only when endorsing peers could not be reached. When orderer could not be
+// reached transaction may still be committed, so error is returned and next requests go to DR.
+func (c *FailoverClient) InvokeWithContext(ctx context.Context, identity Identity, chainCode ChainCode, peers []string, orderer string) (resp *InvokeResponse, err error) {
+	var broadcast bool
+	c.do(func(client *FabricClient) error {
+		if broadcast {
+			return nil
+		}
+		resp, err = client.InvokeWithContext(ctx, identity, chainCode, peers, orderer)
+		// errors of endorsing peers are EndorsementError, other unreachable errors come from orderer
+		var endorsement *EndorsementError
+		broadcast = err != nil && !errors.As(err, &endorsement)
+		return err
+	})
+	return resp, err
+}
+
+func (c *FailoverClient) CreateUpdateChannel(identity Identity, path string, channelId string, orderer string) (err error) {
+	c.do(func(client *FabricClient) error {
+		err = client.CreateUpdateChannel(identity, path, channelId, orderer)
+		return err
+	})
+	return err
+}
+
+func (c *FailoverClient) JoinChannel(identity Identity, channelId string, peers []string, orderer string) (resp []*PeerResponse, err error) {
+	c.do(func(client *FabricClient) error {
+		resp, err = client.JoinChannel(identity, channelId, peers, orderer)
+		if err != nil {
+			return err
+		}
+		return unreachableError(peerErrors(resp))
+	})
+	return resp, err
+}
+
+func (c *FailoverClient) InstallChainCode(identity Identity, req *InstallRequest, peers []string) (resp []*PeerResponse, err error) {
+	c.do(func(client *FabricClient) error {
+		resp, err = client.InstallChainCode(identity, req, peers)
+		if err != nil {
+			return err
+		}
+		return unreachableError(peerErrors(resp))
+	})
+	return resp, err
+}
+
+func (c *FailoverClient) InstantiateChainCode(identity Identity, req *ChainCode, peers []string, orderer string, operation string,
+	collectionsConfig []CollectionConfig) (resp *orderer.BroadcastResponse, err error) {
+	c.do(func(client *FabricClient) error {
+		resp, err = client.InstantiateChainCode(identity, req, peers, orderer, operation, collectionsConfig)
+		return err
+	})
+	return resp, err
+}
+
+func (c *FailoverClient) UpgradeChainCode(identity Identity, req *ChainCode, policy string, peers []string, orderer string,
+	collectionsConfig []CollectionConfig) (resp *orderer.BroadcastResponse, err error) {
+	c.do(func(client *FabricClient) error {
+		resp, err = client.UpgradeChainCode(identity, req, policy, peers, orderer, collectionsConfig)
+		return err
+	})
+	return resp, err
+}
+
+func (c *FailoverClient) QueryInstalledChainCodes(identity Identity, peers []string) (resp []*ChainCodesResponse, err error) {
+	c.do(func(client *FabricClient) error {
+		resp, err = client.QueryInstalledChainCodes(identity, peers)
+		if err != nil {
+			return err
+		}
+		errs := make([]error, len(resp))
+		for i, r := range resp {
+			errs[i] = r.Error
+		}
+		return unreachableError(errs)
+	})
+	return resp, err
+}
+
+func (c *FailoverClient) QueryInstantiatedChainCodes(identity Identity, channelId string, peers []string) (resp []*ChainCodesResponse, err error) {
+	c.do(func(client *FabricClient) error {
+		resp, err = client.QueryInstantiatedChainCodes(identity, channelId, peers)
+		if err != nil {
+			return err
+		}
+		errs := make([]error, len(resp))
+		for i, r := range resp {
+			errs[i] = r.Error
+		}
+		return unreachableError(errs)
+	})
+	return resp, err
+}
+
+func (c *FailoverClient) QueryChannels(identity Identity, peers []string) (resp []*QueryChannelsResponse, err error) {
+	c.do(func(client *FabricClient) error {
+		resp, err = client.QueryChannels(identity, peers)
+		if err != nil {
+			return err
+		}
+		errs := make([]error, len(resp))
+		for i, r := range resp {
+			errs[i] = r.Error
+		}
+		return unreachableError(errs)
+	})
+	return resp, err
+}
+
+func (c *FailoverClient) QueryChannelInfo(identity Identity, channelId string, peers []string) (resp []*QueryChannelInfoResponse, err error) {
+	c.do(func(client *FabricClient) error {
+		resp, err = client.QueryChannelInfo(identity, channelId, peers)
+		if err != nil {
+			return err
+		}
+		errs := make([]error, len(resp))
+		for i, r := range resp {
+			errs[i] = r.Error
+		}
+		return unreachableError(errs)
+	})
+	return resp, err
+}
+
+// ListenForFullBlock listens on active profile. When event stream fails because event peer cannot be reached,
+// failure is counted against the profile and listener is started again on the profile that is active then, from the
+// block after the last delivered one. Other errors, like denied access or buffer overflow, are send to response and
+// stop listening.
+func (c *FailoverClient) ListenForFullBlock(ctx context.Context, identity Identity, eventPeer, channelId string, response chan<- EventBlockResponse) error {
+	return c.listenEvents(ctx, response, func(client *FabricClient, from *uint64, events chan<- EventBlockResponse) error {
+		return client.listenForBlocks(ctx, identity, eventPeer, channelId, EventTypeFullBlock, from, events)
+	})
+}
+
+// ListenForFilteredBlock is same as ListenForFullBlock for filtered blocks
+func (c *FailoverClient) ListenForFilteredBlock(ctx context.Context, identity Identity, eventPeer, channelId string, response chan<- EventBlockResponse) error {
+	return c.listenEvents(ctx, response, func(client *FabricClient, from *uint64, events chan<- EventBlockResponse) error {
+		return client.listenForBlocks(ctx, identity, eventPeer, channelId, EventTypeFiltered, from, events)
+	})
+}
+
+func (c *FailoverClient) listenEvents(ctx context.Context, response chan<- EventBlockResponse,
+	listen func(*FabricClient, *uint64, chan<- EventBlockResponse) error) error {
+	events := make(chan EventBlockResponse)
+	var profile string
+	var err error
+	c.do(func(client *FabricClient) error {
+		profile = c.profileOf(client)
+		err = listen(client, nil, events)
+		return listenFailure(profile, err)
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		// next is nil until the first block, listener is then started again from the newest block
+		var next *uint64
+		for {
+			select {
+			case e := <-events:
+				if e.Error == nil {
+					n := e.BlockHeight + 1
+					next = &n
+					select {
+					case response <- e:
+					case <-ctx.Done():
+						return
+					}
+					continue
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				if isTransportError(e.Error) {
+					from := next
+					restarted, err := c.restartListener(ctx, profile, e.Error, func(client *FabricClient) error {
+						return listen(client, from, events)
+					})
+					if err == nil {
+						if restarted == "" {
+							return
+						}
+						profile = restarted
+						continue
+					}
+					e.Error = err
+				}
+				select {
+				case response <- e:
+				case <-ctx.Done():
+				}
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// ListenBlocks listens on active profile. When block stream fails because event peer cannot be reached, failure is
+// counted against the profile and listener is started again on the profile that is active then, from the block
+// after the last delivered one. Other errors are send to response and stop listening.
+func (c *FailoverClient) ListenBlocks(ctx context.Context, identity Identity, eventPeer, channelId string, fromBlock uint64,
+	response chan<- BlockResponse) error {
+	blocks := make(chan BlockResponse)
+	var profile string
+	var err error
+	c.do(func(client *FabricClient) error {
+		profile = c.profileOf(client)
+		err = client.ListenBlocks(ctx, identity, eventPeer, channelId, fromBlock, blocks)
+		return listenFailure(profile, err)
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		next := fromBlock
+		for {
+			select {
+			case b := <-blocks:
+				if b.Error == nil {
+					next = b.Block.Header.Number + 1
+					select {
+					case response <- b:
+					case <-ctx.Done():
+						return
+					}
+					continue
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				if isTransportError(b.Error) {
+					restarted, err := c.restartListener(ctx, profile, b.Error, func(client *FabricClient) error {
+						return client.ListenBlocks(ctx, identity, eventPeer, channelId, next, blocks)
+					})
+					if err == nil {
+						if restarted == "" {
+							return
+						}
+						profile = restarted
+						continue
+					}
+					b.Error = err
+				}
+				select {
+				case response <- b:
+				case <-ctx.Done():
+				}
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// restartListener counts failed stream against profile and starts listener again after ListenRetryDelay until
+// it starts or ctx is done. It returns profile of started listener, or empty string when ctx is done. Error is
+// returned when listener fails to start for other reason than unreachable event peer.
+// Started listener does not reset failures, only successful requests do, since stream can fail right after start.
+func (c *FailoverClient) restartListener(ctx context.Context, profile string, cause error, listen func(*FabricClient) error) (string, error) {
+	logger().Warn("event stream failed, restarting listener", "profile", profile, "error", cause)
+	c.observe(profile, &ConnectionError{Node: profile, Err: cause})
+	for {
+		t := time.NewTimer(c.config.ListenRetryDelay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return "", nil
+		}
+		client, current := c.current()
+		err := listen(client)
+		if err == nil {
+			return current, nil
+		}
+		if !isTransportError(err) {
+			return "", err
+		}
+		c.observe(current, &ConnectionError{Node: current, Err: err})
+	}
+}
+
+// listenFailure returns error of listener start as ConnectionError of profile when event peer cannot be reached,
+// so it counts as failure of profile
+func listenFailure(profile string, err error) error {
+	if err != nil && isTransportError(err) {
+		return &ConnectionError{Node: profile, Err: err}
+	}
+	return err
+}
+
+// isTransportError returns true when err means that node cannot be reached or ended stream, not that request was
+// rejected
+func isTransportError(err error) bool {
+	return IsConnectionError(err) || IsTimeout(err) || errors.Is(err, io.EOF)
+}
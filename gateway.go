@@ -0,0 +1,246 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// GatewayOptions configures Gateway
+type GatewayOptions struct {
+	// EventPeer delivers commit status of submitted transactions. When empty first event peer of client by name
+	// is used.
+	EventPeer string
+	// Peer runs embedded gateway service of Fabric 2.4+. When set, transactions are endorsed, submitted and
+	// evaluated by this peer and it selects endorsers. When peer has no gateway service, requests are made by
+	// client same as without Peer.
+	Peer string
+}
+
+// Gateway is simplified programming model modeled on Fabric Gateway. It binds client to single identity, so
+// applications only select channel and chaincode and call transactions by name. Peers, orderer and default
+// chaincode of channel are taken from channel options, see FabricClient.Channel.
+type Gateway struct {
+	client      *FabricClient
+	mu          sync.RWMutex
+	identity    Identity
+	eventPeer   string
+	gatewayPeer string
+}
+
+// Network is channel of Gateway
+type Network struct {
+	gateway *Gateway
+	channel *Channel
+}
+
+// Contract is chaincode in Network
+type Contract struct {
+	network   *Network
+	chainCode string
+}
+
+// Transaction is single named transaction of Contract with optional transient data
+type Transaction struct {
+	contract      *Contract
+	name          string
+	transient     map[string][]byte
+	endorsingOrgs []string
+}
+
+// NewGateway returns Gateway that signs all requests with identity
+func (c *FabricClient) NewGateway(identity Identity, options GatewayOptions) *Gateway {
+	return &Gateway{client: c, identity: identity, eventPeer: options.EventPeer, gatewayPeer: options.Peer}
+}
+
+// Identity returns identity used by gateway
+func (g *Gateway) Identity() Identity {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.identity
+}
+
+// RotateIdentity replaces identity used by gateway, usually after re-enrollment when certificate expires.
+// Requests already started finish with previous identity, new requests are signed with identity.
+func (g *Gateway) RotateIdentity(identity Identity) error {
+	if identity.ReadOnly() {
+		return ErrReadOnlyIdentity
+	}
+	g.mu.Lock()
+	g.identity = identity
+	g.mu.Unlock()
+	logger().Info("gateway identity rotated", "mspId", identity.MspId)
+	return nil
+}
+
+// Network returns channel channelId
+func (g *Gateway) Network(channelId string) *Network {
+	return &Network{gateway: g, channel: g.client.Channel(channelId)}
+}
+
+// Name returns channel id of network
+func (n *Network) Name() string {
+	return n.channel.Id()
+}
+
+// Contract returns chaincode of network. Empty name selects default chaincode of channel.
+func (n *Network) Contract(chainCode string) *Contract {
+	return &Contract{network: n, chainCode: chainCode}
+}
+
+// Name returns chaincode name, empty for default chaincode of channel
+func (c *Contract) Name() string {
+	return c.chainCode
+}
+
+// SubmitTransaction endorses transaction, sends it to orderer and waits until it is committed. Payload of chaincode
+// response is returned only when transaction is committed as valid, otherwise error is returned, *CommitError when
+// transaction is committed as invalid.
+func (c *Contract) SubmitTransaction(name string, args ...string) ([]byte, error) {
+	return c.CreateTransaction(name).SubmitWithContext(context.Background(), args...)
+}
+
+// EvaluateTransaction executes transaction on peer without sending it to orderer and returns payload of chaincode
+// response. Ledger is not updated.
+func (c *Contract) EvaluateTransaction(name string, args ...string) ([]byte, error) {
+	return c.CreateTransaction(name).EvaluateWithContext(context.Background(), args...)
+}
+
+// CreateTransaction returns transaction name that can be configured before it is submitted or evaluated
+func (c *Contract) CreateTransaction(name string) *Transaction {
+	return &Transaction{contract: c, name: name}
+}
+
+// SetTransient sets transient data of transaction, like private data that must not be part of transaction
+func (t *Transaction) SetTransient(transient map[string][]byte) *Transaction {
+	t.transient = transient
+	return t
+}
+
+// SetEndorsingOrganizations limits peers selected by gateway service to organizations mspIds. It is used only
+// with GatewayOptions.Peer.
+func (t *Transaction) SetEndorsingOrganizations(mspIds ...string) *Transaction {
+	t.endorsingOrgs = mspIds
+	return t
+}
+
+// Submit is same as Contract.SubmitTransaction
+func (t *Transaction) Submit(args ...string) ([]byte, error) {
+	return t.SubmitWithContext(context.Background(), args...)
+}
+
+// SubmitWithContext is same as Submit, but waiting stops when ctx is done. Contexts created with WithMVCCRetry,
+// WithTxTTL and WithTimeouts apply.
+func (t *Transaction) SubmitWithContext(ctx context.Context, args ...string) ([]byte, error) {
+	network := t.contract.network
+	chainCode := t.chainCode(args)
+	options, err := network.channel.prepare(&chainCode)
+	if err != nil {
+		return nil, err
+	}
+	if gatewayPeer := network.gateway.gatewayPeer; gatewayPeer != "" {
+		result, err := network.gateway.client.GatewaySubmit(ctx, network.gateway.Identity(), gatewayPeer, chainCode,
+			t.endorsingOrgs)
+		if err == nil {
+			if err := result.Err(); err != nil {
+				return nil, err
+			}
+			return result.Payload, nil
+		}
+		if !IsGatewayUnavailable(err) {
+			return nil, err
+		}
+	}
+	eventPeer, err := network.gateway.commitPeer()
+	if err != nil {
+		return nil, err
+	}
+	result := network.gateway.client.InvokeAsync(ctx, network.gateway.Identity(), chainCode, options.Peers,
+		options.Orderer, eventPeer).Result()
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+	return result.Payload, nil
+}
+
+// Evaluate is same as Contract.EvaluateTransaction
+func (t *Transaction) Evaluate(args ...string) ([]byte, error) {
+	return t.EvaluateWithContext(context.Background(), args...)
+}
+
+// EvaluateWithContext is same as Evaluate, but request is canceled when ctx is done. Payload of the first peer
+// that succeeds is returned. When all peers fail error of the first one is returned, chaincode errors are
+// *EndorsementError.
+func (t *Transaction) EvaluateWithContext(ctx context.Context, args ...string) ([]byte, error) {
+	network := t.contract.network
+	if gatewayPeer := network.gateway.gatewayPeer; gatewayPeer != "" {
+		chainCode := t.chainCode(args)
+		if _, err := network.channel.prepare(&chainCode); err != nil {
+			return nil, err
+		}
+		resp, err := network.gateway.client.GatewayEvaluate(ctx, network.gateway.Identity(), gatewayPeer, chainCode,
+			t.endorsingOrgs)
+		if err == nil {
+			if resp.GetStatus() >= 400 {
+				return nil, &EndorsementError{Peer: gatewayPeer, Status: resp.Status, Message: resp.Message}
+			}
+			return resp.GetPayload(), nil
+		}
+		if !IsGatewayUnavailable(err) {
+			return nil, err
+		}
+	}
+	responses, err := network.channel.QueryWithContext(ctx, network.gateway.Identity(), t.chainCode(args))
+	if err != nil {
+		return nil, err
+	}
+	var first error
+	for _, resp := range responses {
+		err := resp.Error
+		if err == nil && resp.Status >= 400 {
+			err = &EndorsementError{Peer: resp.PeerName, Status: resp.Status, Message: resp.Message}
+		}
+		if err == nil {
+			return resp.Payload, nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	if first == nil {
+		return nil, ErrNoValidEndorsementFound
+	}
+	return nil, first
+}
+
+// chainCode returns request of transaction, channel and default name are set by channel
+func (t *Transaction) chainCode(args []string) ChainCode {
+	return ChainCode{
+		Name:         t.contract.chainCode,
+		Type:         ChaincodeSpec_GOLANG,
+		Args:         append([]string{t.name}, args...),
+		TransientMap: t.transient,
+	}
+}
+
+// commitPeer returns event peer of gateway or first event peer of client
+func (g *Gateway) commitPeer() (string, error) {
+	if g.eventPeer != "" {
+		return g.eventPeer, nil
+	}
+	g.client.mu.RLock()
+	names := make([]string, 0, len(g.client.EventPeers))
+	for name := range g.client.EventPeers {
+		names = append(names, name)
+	}
+	g.client.mu.RUnlock()
+	if len(names) == 0 {
+		return "", ErrEventPeerMissing
+	}
+	sort.Strings(names)
+	return names[0], nil
+}
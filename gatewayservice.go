@@ -0,0 +1,382 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/peer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Messages of Fabric 2.4+ gateway service, they are not part of vendored protos
+
+type gatewayEndorseRequest struct {
+	TransactionId          string               `protobuf:"bytes,1,opt,name=transaction_id,json=transactionId" json:"transaction_id,omitempty"`
+	ChannelId              string               `protobuf:"bytes,2,opt,name=channel_id,json=channelId" json:"channel_id,omitempty"`
+	ProposedTransaction    *peer.SignedProposal `protobuf:"bytes,3,opt,name=proposed_transaction,json=proposedTransaction" json:"proposed_transaction,omitempty"`
+	EndorsingOrganizations []string             `protobuf:"bytes,4,rep,name=endorsing_organizations,json=endorsingOrganizations" json:"endorsing_organizations,omitempty"`
+}
+
+func (m *gatewayEndorseRequest) Reset()         { *m = gatewayEndorseRequest{} }
+func (m *gatewayEndorseRequest) String() string { return proto.CompactTextString(m) }
+func (*gatewayEndorseRequest) ProtoMessage()    {}
+
+type gatewayEndorseResponse struct {
+	PreparedTransaction *common.Envelope `protobuf:"bytes,1,opt,name=prepared_transaction,json=preparedTransaction" json:"prepared_transaction,omitempty"`
+}
+
+func (m *gatewayEndorseResponse) Reset()         { *m = gatewayEndorseResponse{} }
+func (m *gatewayEndorseResponse) String() string { return proto.CompactTextString(m) }
+func (*gatewayEndorseResponse) ProtoMessage()    {}
+
+type gatewaySubmitRequest struct {
+	TransactionId       string           `protobuf:"bytes,1,opt,name=transaction_id,json=transactionId" json:"transaction_id,omitempty"`
+	ChannelId           string           `protobuf:"bytes,2,opt,name=channel_id,json=channelId" json:"channel_id,omitempty"`
+	PreparedTransaction *common.Envelope `protobuf:"bytes,3,opt,name=prepared_transaction,json=preparedTransaction" json:"prepared_transaction,omitempty"`
+}
+
+func (m *gatewaySubmitRequest) Reset()         { *m = gatewaySubmitRequest{} }
+func (m *gatewaySubmitRequest) String() string { return proto.CompactTextString(m) }
+func (*gatewaySubmitRequest) ProtoMessage()    {}
+
+type gatewaySubmitResponse struct{}
+
+func (m *gatewaySubmitResponse) Reset()         { *m = gatewaySubmitResponse{} }
+func (m *gatewaySubmitResponse) String() string { return proto.CompactTextString(m) }
+func (*gatewaySubmitResponse) ProtoMessage()    {}
+
+type gatewayCommitStatusRequest struct {
+	TransactionId string `protobuf:"bytes,1,opt,name=transaction_id,json=transactionId" json:"transaction_id,omitempty"`
+	ChannelId     string `protobuf:"bytes,2,opt,name=channel_id,json=channelId" json:"channel_id,omitempty"`
+	Identity      []byte `protobuf:"bytes,3,opt,name=identity,proto3" json:"identity,omitempty"`
+}
+
+func (m *gatewayCommitStatusRequest) Reset()         { *m = gatewayCommitStatusRequest{} }
+func (m *gatewayCommitStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*gatewayCommitStatusRequest) ProtoMessage()    {}
+
+type gatewaySignedCommitStatusRequest struct {
+	Request   []byte `protobuf:"bytes,1,opt,name=request,proto3" json:"request,omitempty"`
+	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *gatewaySignedCommitStatusRequest) Reset()         { *m = gatewaySignedCommitStatusRequest{} }
+func (m *gatewaySignedCommitStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*gatewaySignedCommitStatusRequest) ProtoMessage()    {}
+
+type gatewayCommitStatusResponse struct {
+	Result      peer.TxValidationCode `protobuf:"varint,1,opt,name=result,enum=protos.TxValidationCode" json:"result,omitempty"`
+	BlockNumber uint64                `protobuf:"varint,2,opt,name=block_number,json=blockNumber" json:"block_number,omitempty"`
+}
+
+func (m *gatewayCommitStatusResponse) Reset()         { *m = gatewayCommitStatusResponse{} }
+func (m *gatewayCommitStatusResponse) String() string { return proto.CompactTextString(m) }
+func (*gatewayCommitStatusResponse) ProtoMessage()    {}
+
+type gatewayEvaluateRequest struct {
+	TransactionId       string               `protobuf:"bytes,1,opt,name=transaction_id,json=transactionId" json:"transaction_id,omitempty"`
+	ChannelId           string               `protobuf:"bytes,2,opt,name=channel_id,json=channelId" json:"channel_id,omitempty"`
+	ProposedTransaction *peer.SignedProposal `protobuf:"bytes,3,opt,name=proposed_transaction,json=proposedTransaction" json:"proposed_transaction,omitempty"`
+	TargetOrganizations []string             `protobuf:"bytes,4,rep,name=target_organizations,json=targetOrganizations" json:"target_organizations,omitempty"`
+}
+
+func (m *gatewayEvaluateRequest) Reset()         { *m = gatewayEvaluateRequest{} }
+func (m *gatewayEvaluateRequest) String() string { return proto.CompactTextString(m) }
+func (*gatewayEvaluateRequest) ProtoMessage()    {}
+
+type gatewayEvaluateResponse struct {
+	Result *peer.Response `protobuf:"bytes,1,opt,name=result" json:"result,omitempty"`
+}
+
+func (m *gatewayEvaluateResponse) Reset()         { *m = gatewayEvaluateResponse{} }
+func (m *gatewayEvaluateResponse) String() string { return proto.CompactTextString(m) }
+func (*gatewayEvaluateResponse) ProtoMessage()    {}
+
+// gatewayErrorDetail is gateway.ErrorDetail attached to errors of gateway service
+type gatewayErrorDetail struct {
+	Address string `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	MspId   string `protobuf:"bytes,2,opt,name=msp_id,json=mspId" json:"msp_id,omitempty"`
+	Message string `protobuf:"bytes,3,opt,name=message" json:"message,omitempty"`
+}
+
+func (m *gatewayErrorDetail) Reset()         { *m = gatewayErrorDetail{} }
+func (m *gatewayErrorDetail) String() string { return proto.CompactTextString(m) }
+func (*gatewayErrorDetail) ProtoMessage()    {}
+
+const gatewayErrorDetailType = "type.googleapis.com/gateway.ErrorDetail"
+
+// GatewayTransaction is transaction endorsed by gateway service and ready to be submitted
+type GatewayTransaction struct {
+	TxID      string
+	ChannelId string
+	// Envelope is signed transaction
+	Envelope *common.Envelope
+	// Result is chaincode response of endorsement
+	Result *peer.Response
+	// Committed is set when WithCommitCheck found transaction already on ledger, then Envelope is nil and
+	// GatewaySubmitTransaction does not send it again
+	Committed *InvokeResult
+}
+
+// GatewayError is error returned by gateway service. Details are errors of peers and orderers the gateway
+// contacted on behalf of client.
+type GatewayError struct {
+	Peer    string
+	Code    codes.Code
+	Message string
+	Details []GatewayErrorDetail
+}
+
+// GatewayErrorDetail is error of single node contacted by gateway
+type GatewayErrorDetail struct {
+	Address string
+	MspId   string
+	Message string
+}
+
+func (e *GatewayError) Error() string {
+	msg := fmt.Sprintf("gateway %s returned %v: %s", e.Peer, e.Code, e.Message)
+	for _, d := range e.Details {
+		msg += fmt.Sprintf("; %s (%s): %s", d.Address, d.MspId, d.Message)
+	}
+	return msg
+}
+
+// IsGatewayUnavailable returns true if err means that peer does not run gateway service, like peers before
+// Fabric 2.4 or peers with gateway disabled
+func IsGatewayUnavailable(err error) bool {
+	var gatewayErr *GatewayError
+	return errors.As(err, &gatewayErr) && gatewayErr.Code == codes.Unimplemented
+}
+
+// GatewayEvaluate executes chainCode on peers selected by gateway service of peerName and returns chaincode
+// response. When targetOrgs is not empty only peers of these organizations are used.
+func (c *FabricClient) GatewayEvaluate(ctx context.Context, identity Identity, peerName string, chainCode ChainCode,
+	targetOrgs []string) (*peer.Response, error) {
+	p, conn, err := c.gatewayPeer(ctx, peerName)
+	if err != nil {
+		return nil, err
+	}
+	prop, err := createQueryProposal(identity, chainCode, c.txOptions(chainCode.ChannelId))
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := signedProposal(prop.proposal, identity, c.cryptoSuite(identity, chainCode.ChannelId))
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := withTimeout(ctx, effectiveTimeouts(ctx, p.Timeouts).Endorsement)
+	defer cancel()
+	resp := new(gatewayEvaluateResponse)
+	err = grpc.Invoke(ctx, "/gateway.Gateway/Evaluate", &gatewayEvaluateRequest{TransactionId: prop.transactionId,
+		ChannelId: chainCode.ChannelId, ProposedTransaction: proposal, TargetOrganizations: targetOrgs}, resp, conn)
+	if err != nil {
+		return nil, gatewayError(peerName, err)
+	}
+	return resp.Result, nil
+}
+
+// GatewayEndorse sends proposal for chainCode to gateway service of peerName. Gateway selects endorsing peers
+// that satisfy endorsement policy, unless endorsingOrgs is not empty. Returned transaction is signed by identity.
+// With WithCommitCheck peerName is asked first whether transaction is already on ledger.
+func (c *FabricClient) GatewayEndorse(ctx context.Context, identity Identity, peerName string, chainCode ChainCode,
+	endorsingOrgs []string) (*GatewayTransaction, error) {
+	p, conn, err := c.gatewayPeer(ctx, peerName)
+	if err != nil {
+		return nil, err
+	}
+	committed, err := c.checkCommitted(ctx, identity, chainCode.ChannelId, []string{peerName})
+	if err != nil {
+		return nil, err
+	}
+	if committed != nil {
+		return &GatewayTransaction{TxID: committed.TxID, ChannelId: chainCode.ChannelId,
+			Result: &peer.Response{Status: 200, Payload: committed.Payload}, Committed: committed.Committed}, nil
+	}
+	crypto := c.cryptoSuite(identity, chainCode.ChannelId)
+	prop, err := createTransactionProposalWithId(identity, chainCode, txIdFromContext(ctx),
+		c.txOptions(chainCode.ChannelId))
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := signedProposal(prop.proposal, identity, crypto)
+	if err != nil {
+		return nil, err
+	}
+	endorseCtx, cancel := withTimeout(ctx, effectiveTimeouts(ctx, p.Timeouts).Endorsement)
+	defer cancel()
+	resp := new(gatewayEndorseResponse)
+	err = grpc.Invoke(endorseCtx, "/gateway.Gateway/Endorse", &gatewayEndorseRequest{TransactionId: prop.transactionId,
+		ChannelId: chainCode.ChannelId, ProposedTransaction: proposal, EndorsingOrganizations: endorsingOrgs}, resp, conn)
+	if err != nil {
+		return nil, gatewayError(peerName, err)
+	}
+	if resp.PreparedTransaction == nil {
+		return nil, ErrNoValidEndorsementFound
+	}
+	result, err := envelopeChaincodeResponse(resp.PreparedTransaction)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := crypto.Sign(resp.PreparedTransaction.Payload, identity.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &GatewayTransaction{TxID: prop.transactionId, ChannelId: chainCode.ChannelId,
+		Envelope: &common.Envelope{Payload: resp.PreparedTransaction.Payload, Signature: signature}, Result: result}, nil
+}
+
+// GatewaySubmitTransaction sends endorsed transaction to orderer through gateway service of peerName. Transaction
+// that is already committed is not send.
+func (c *FabricClient) GatewaySubmitTransaction(ctx context.Context, peerName string, tx *GatewayTransaction) error {
+	if tx.Committed != nil {
+		return nil
+	}
+	p, conn, err := c.gatewayPeer(ctx, peerName)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx, effectiveTimeouts(ctx, p.Timeouts).Broadcast)
+	defer cancel()
+	err = grpc.Invoke(ctx, "/gateway.Gateway/Submit", &gatewaySubmitRequest{TransactionId: tx.TxID,
+		ChannelId: tx.ChannelId, PreparedTransaction: tx.Envelope}, new(gatewaySubmitResponse), conn)
+	return gatewayError(peerName, err)
+}
+
+// GatewayCommitStatus waits until transaction txId is committed in peerName and returns its validation code
+func (c *FabricClient) GatewayCommitStatus(ctx context.Context, identity Identity, peerName, channelId, txId string) (*InvokeResult, error) {
+	p, conn, err := c.gatewayPeer(ctx, peerName)
+	if err != nil {
+		return nil, err
+	}
+	creator, err := marshalProtoIdentity(identity)
+	if err != nil {
+		return nil, err
+	}
+	request, err := proto.Marshal(&gatewayCommitStatusRequest{TransactionId: txId, ChannelId: channelId, Identity: creator})
+	if err != nil {
+		return nil, err
+	}
+	signature, err := c.cryptoSuite(identity, channelId).Sign(request, identity.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := withTimeout(ctx, effectiveTimeouts(ctx, p.Timeouts).CommitWait)
+	defer cancel()
+	resp := new(gatewayCommitStatusResponse)
+	err = grpc.Invoke(ctx, "/gateway.Gateway/CommitStatus", &gatewaySignedCommitStatusRequest{Request: request,
+		Signature: signature}, resp, conn)
+	if err != nil {
+		return nil, gatewayError(peerName, err)
+	}
+	return &InvokeResult{TxID: txId, Status: common.Status_SUCCESS, ValidationCode: resp.Result.String(),
+		BlockHeight: resp.BlockNumber}, nil
+}
+
+// GatewaySubmit endorses, submits and waits for commit of chainCode using gateway service of peerName. Result
+// has chaincode payload and validation code, use Err to check that transaction is valid.
+func (c *FabricClient) GatewaySubmit(ctx context.Context, identity Identity, peerName string, chainCode ChainCode,
+	endorsingOrgs []string) (*InvokeResult, error) {
+	start := time.Now()
+	tx, err := c.GatewayEndorse(ctx, identity, peerName, chainCode, endorsingOrgs)
+	if err != nil {
+		return nil, err
+	}
+	if tx.Committed != nil {
+		return tx.Committed, nil
+	}
+	if err := c.GatewaySubmitTransaction(ctx, peerName, tx); err != nil {
+		logger().Warn("transaction not accepted", "txId", tx.TxID, "gateway", peerName, "error", err)
+		return nil, err
+	}
+	logger().Debug("transaction submitted", "txId", tx.TxID, "channel", chainCode.ChannelId,
+		"chaincode", chainCode.Name, "gateway", peerName)
+	result, err := c.GatewayCommitStatus(ctx, identity, peerName, chainCode.ChannelId, tx.TxID)
+	if err != nil {
+		return nil, err
+	}
+	metrics().Commit(chainCode.ChannelId, time.Since(start), result.ValidationCode)
+	result.Attempts = 1
+	if tx.Result != nil {
+		result.Payload = tx.Result.Payload
+	}
+	return result, nil
+}
+
+// gatewayPeer returns connected peer
+func (c *FabricClient) gatewayPeer(ctx context.Context, peerName string) (*Peer, *grpc.ClientConn, error) {
+	p := c.getPeers([]string{peerName})
+	if len(p) != 1 {
+		return nil, nil, ErrPeerNameNotFound
+	}
+	conn, _, err := p[0].endorserConnection(ctx, effectiveTimeouts(ctx, p[0].Timeouts).Dial)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p[0], conn, nil
+}
+
+// gatewayError converts error of gateway service to GatewayError, timeouts and connection errors are converted
+// by rpcError
+func gatewayError(peerName string, err error) error {
+	if err == nil {
+		return nil
+	}
+	s, ok := status.FromError(err)
+	if !ok || s.Code() == codes.DeadlineExceeded || s.Code() == codes.Unavailable {
+		return rpcError(peerName, err)
+	}
+	gatewayErr := &GatewayError{Peer: peerName, Code: s.Code(), Message: s.Message()}
+	for _, d := range s.Proto().GetDetails() {
+		if d.GetTypeUrl() != gatewayErrorDetailType {
+			continue
+		}
+		detail := new(gatewayErrorDetail)
+		if proto.Unmarshal(d.GetValue(), detail) == nil {
+			gatewayErr.Details = append(gatewayErr.Details, GatewayErrorDetail{Address: detail.Address,
+				MspId: detail.MspId, Message: detail.Message})
+		}
+	}
+	return gatewayErr
+}
+
+// envelopeChaincodeResponse returns chaincode response from endorsed transaction
+func envelopeChaincodeResponse(envelope *common.Envelope) (*peer.Response, error) {
+	payload := new(common.Payload)
+	if err := proto.Unmarshal(envelope.Payload, payload); err != nil {
+		return nil, err
+	}
+	tx := new(peer.Transaction)
+	if err := proto.Unmarshal(payload.Data, tx); err != nil {
+		return nil, err
+	}
+	if len(tx.Actions) == 0 {
+		return nil, ErrNoValidEndorsementFound
+	}
+	actionPayload := new(peer.ChaincodeActionPayload)
+	if err := proto.Unmarshal(tx.Actions[0].Payload, actionPayload); err != nil {
+		return nil, err
+	}
+	if actionPayload.Action == nil {
+		return nil, ErrNoValidEndorsementFound
+	}
+	prp := new(peer.ProposalResponsePayload)
+	if err := proto.Unmarshal(actionPayload.Action.ProposalResponsePayload, prp); err != nil {
+		return nil, err
+	}
+	action := new(peer.ChaincodeAction)
+	if err := proto.Unmarshal(prp.Extension, action); err != nil {
+		return nil, err
+	}
+	return action.Response, nil
+}
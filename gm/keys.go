@@ -0,0 +1,277 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gm
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Key is SM2 or SM4 key managed by KeyManager. It mirrors Fabric BCCSP Key.
+type Key interface {
+	// Bytes returns raw key material, only public keys can be exported
+	Bytes() ([]byte, error)
+	// SKI returns subject key identifier of the key
+	SKI() []byte
+	Symmetric() bool
+	Private() bool
+	// PublicKey returns public part of asymmetric key
+	PublicKey() (Key, error)
+}
+
+// KeyGenOpts selects key type for KeyManager.KeyGen
+type KeyGenOpts interface {
+	Algorithm() string
+	// Ephemeral keys are not stored in KeyStore
+	Ephemeral() bool
+}
+
+// KeyImportOpts selects format of raw key for KeyManager.KeyImport
+type KeyImportOpts interface {
+	Algorithm() string
+	Ephemeral() bool
+}
+
+// Algorithm names returned by opts
+const (
+	SM2 = "SM2"
+	SM4 = "SM4"
+	// X509Certificate imports SM2 public key from x509 certificate
+	X509Certificate = "X509Certificate"
+)
+
+var (
+	// ErrKeyNotFound is returned when key with requested SKI is not in KeyStore
+	ErrKeyNotFound = errors.New("gm: key not found")
+	// ErrReadOnlyKeyStore is returned when key is stored in read only KeyStore
+	ErrReadOnlyKeyStore = errors.New("gm: key store is read only")
+	// ErrPrivateKeyExport is returned from Bytes of private key
+	ErrPrivateKeyExport = errors.New("gm: private key cannot be exported")
+)
+
+// SM2KeyGenOpts generates SM2 key pair
+type SM2KeyGenOpts struct {
+	Temporary bool
+}
+
+func (o *SM2KeyGenOpts) Algorithm() string { return SM2 }
+func (o *SM2KeyGenOpts) Ephemeral() bool   { return o.Temporary }
+
+// SM4KeyGenOpts generates 128 bit SM4 key
+type SM4KeyGenOpts struct {
+	Temporary bool
+}
+
+func (o *SM4KeyGenOpts) Algorithm() string { return SM4 }
+func (o *SM4KeyGenOpts) Ephemeral() bool   { return o.Temporary }
+
+// SM2PrivateKeyImportOpts imports SM2 private key from *PrivateKey or PKCS #8, SEC 1 DER bytes
+type SM2PrivateKeyImportOpts struct {
+	Temporary bool
+}
+
+func (o *SM2PrivateKeyImportOpts) Algorithm() string { return SM2 }
+func (o *SM2PrivateKeyImportOpts) Ephemeral() bool   { return o.Temporary }
+
+// SM2PublicKeyImportOpts imports SM2 public key from *PublicKey or PKIX DER bytes
+type SM2PublicKeyImportOpts struct {
+	Temporary bool
+}
+
+func (o *SM2PublicKeyImportOpts) Algorithm() string { return SM2 }
+func (o *SM2PublicKeyImportOpts) Ephemeral() bool   { return o.Temporary }
+
+// SM4ImportKeyOpts imports raw 16 bytes SM4 key
+type SM4ImportKeyOpts struct {
+	Temporary bool
+}
+
+func (o *SM4ImportKeyOpts) Algorithm() string { return SM4 }
+func (o *SM4ImportKeyOpts) Ephemeral() bool   { return o.Temporary }
+
+// X509PublicKeyImportOpts imports SM2 public key from *x509.Certificate or DER certificate
+type X509PublicKeyImportOpts struct {
+	Temporary bool
+}
+
+func (o *X509PublicKeyImportOpts) Algorithm() string { return X509Certificate }
+func (o *X509PublicKeyImportOpts) Ephemeral() bool   { return o.Temporary }
+
+// SM2PrivateKey is Key holding SM2 private key
+type SM2PrivateKey struct {
+	Key *PrivateKey
+}
+
+func (k *SM2PrivateKey) Bytes() ([]byte, error) { return nil, ErrPrivateKeyExport }
+func (k *SM2PrivateKey) SKI() []byte            { return publicKeySKI(&k.Key.PublicKey) }
+func (k *SM2PrivateKey) Symmetric() bool        { return false }
+func (k *SM2PrivateKey) Private() bool          { return true }
+func (k *SM2PrivateKey) PublicKey() (Key, error) {
+	return &SM2PublicKey{Key: &k.Key.PublicKey}, nil
+}
+
+// SM2PublicKey is Key holding SM2 public key
+type SM2PublicKey struct {
+	Key *PublicKey
+}
+
+// Bytes returns PKIX DER encoded public key
+func (k *SM2PublicKey) Bytes() ([]byte, error) { return MarshalPKIXPublicKey(k.Key) }
+func (k *SM2PublicKey) SKI() []byte            { return publicKeySKI(k.Key) }
+func (k *SM2PublicKey) Symmetric() bool        { return false }
+func (k *SM2PublicKey) Private() bool          { return false }
+func (k *SM2PublicKey) PublicKey() (Key, error) {
+	return k, nil
+}
+
+// SM4Key is Key holding SM4 secret key
+type SM4Key struct {
+	Key []byte
+}
+
+func (k *SM4Key) Bytes() ([]byte, error) { return nil, ErrPrivateKeyExport }
+
+// SKI of SM4 key is SM3 hash of 0x01 followed by key, same construction Fabric uses for AES keys
+func (k *SM4Key) SKI() []byte {
+	h := NewSM3()
+	h.Write([]byte{0x01})
+	h.Write(k.Key)
+	return h.Sum(nil)
+}
+func (k *SM4Key) Symmetric() bool         { return true }
+func (k *SM4Key) Private() bool           { return true }
+func (k *SM4Key) PublicKey() (Key, error) { return nil, errors.New("gm: SM4 key has no public key") }
+
+// publicKeySKI is SM3 hash of uncompressed public key point
+func publicKeySKI(pub *PublicKey) []byte {
+	sum := SumSM3(elliptic.Marshal(pub.Curve, pub.X, pub.Y))
+	return sum[:]
+}
+
+// KeyManager generates, imports and loads GM keys, persisting non ephemeral keys in KeyStore.
+// It follows KeyGen/KeyImport/GetKey flows of Fabric software BCCSP.
+type KeyManager struct {
+	KeyStore KeyStore
+	// Rand is source of randomness for key generation, crypto/rand if nil
+	Rand io.Reader
+}
+
+// NewKeyManager creates KeyManager with given KeyStore. Nil KeyStore keeps keys only in memory of the caller.
+func NewKeyManager(ks KeyStore) *KeyManager {
+	return &KeyManager{KeyStore: ks}
+}
+
+// KeyGen generates new key and stores it unless opts are ephemeral
+func (m *KeyManager) KeyGen(opts KeyGenOpts) (Key, error) {
+	if opts == nil {
+		return nil, errors.New("gm: key generation options are required")
+	}
+	random := m.Rand
+	if random == nil {
+		random = rand.Reader
+	}
+	var k Key
+	switch opts.Algorithm() {
+	case SM2:
+		priv, err := GenerateKey(random)
+		if err != nil {
+			return nil, err
+		}
+		k = &SM2PrivateKey{Key: priv}
+	case SM4:
+		key := make([]byte, SM4KeySize)
+		if _, err := io.ReadFull(random, key); err != nil {
+			return nil, err
+		}
+		k = &SM4Key{Key: key}
+	default:
+		return nil, fmt.Errorf("gm: unsupported key generation algorithm %s", opts.Algorithm())
+	}
+	return k, m.store(k, opts.Ephemeral())
+}
+
+// KeyImport converts raw key to Key and stores it unless opts are ephemeral. Accepted raw types depend on opts.
+func (m *KeyManager) KeyImport(raw interface{}, opts KeyImportOpts) (Key, error) {
+	if opts == nil {
+		return nil, errors.New("gm: key import options are required")
+	}
+	var k Key
+	switch o := opts.(type) {
+	case *SM2PrivateKeyImportOpts:
+		switch r := raw.(type) {
+		case *PrivateKey:
+			k = &SM2PrivateKey{Key: r}
+		case []byte:
+			priv, err := ParsePKCS8PrivateKey(r)
+			if err != nil {
+				if priv, err = ParseECPrivateKey(r); err != nil {
+					return nil, err
+				}
+			}
+			k = &SM2PrivateKey{Key: priv}
+		default:
+			return nil, fmt.Errorf("gm: invalid raw SM2 private key type %T", raw)
+		}
+	case *SM2PublicKeyImportOpts:
+		switch r := raw.(type) {
+		case *PublicKey:
+			k = &SM2PublicKey{Key: r}
+		case []byte:
+			pub, err := ParsePKIXPublicKey(r)
+			if err != nil {
+				return nil, err
+			}
+			k = &SM2PublicKey{Key: pub}
+		default:
+			return nil, fmt.Errorf("gm: invalid raw SM2 public key type %T", raw)
+		}
+	case *SM4ImportKeyOpts:
+		r, ok := raw.([]byte)
+		if !ok || len(r) != SM4KeySize {
+			return nil, ErrInvalidSM4KeySize
+		}
+		k = &SM4Key{Key: append([]byte{}, r...)}
+	case *X509PublicKeyImportOpts:
+		cert, ok := raw.(*x509.Certificate)
+		if !ok {
+			der, ok := raw.([]byte)
+			if !ok {
+				return nil, fmt.Errorf("gm: invalid raw certificate type %T", raw)
+			}
+			var err error
+			if cert, err = ParseCertificate(der); err != nil {
+				return nil, err
+			}
+		}
+		pub, ok := cert.PublicKey.(*PublicKey)
+		if !ok {
+			return nil, ErrNotSM2Key
+		}
+		k = &SM2PublicKey{Key: pub}
+	default:
+		return nil, fmt.Errorf("gm: unsupported key import options %T", o)
+	}
+	return k, m.store(k, opts.Ephemeral())
+}
+
+// GetKey loads key with given SKI from KeyStore. SM2 private and public keys have the same SKI, private key is
+// returned when it is stored.
+func (m *KeyManager) GetKey(ski []byte) (Key, error) {
+	if m.KeyStore == nil {
+		return nil, ErrKeyNotFound
+	}
+	return m.KeyStore.GetKey(ski)
+}
+
+func (m *KeyManager) store(k Key, ephemeral bool) error {
+	if ephemeral || m.KeyStore == nil {
+		return nil
+	}
+	return m.KeyStore.StoreKey(k)
+}
@@ -0,0 +1,132 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gm
+
+import (
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// KeyStore persists GM keys by their SKI
+type KeyStore interface {
+	ReadOnly() bool
+	GetKey(ski []byte) (Key, error)
+	StoreKey(k Key) error
+}
+
+// FileKeyStore stores keys as PEM files in a directory, using the same layout as Fabric file based key store:
+// <hex ski>_sk for private keys (PKCS #8), <hex ski>_pk for public keys (PKIX) and <hex ski>_key for SM4 keys.
+// Directory can therefore be used as keystore folder of MSP.
+type FileKeyStore struct {
+	Path     string
+	readOnly bool
+	mu       sync.Mutex
+}
+
+const (
+	sm4KeyPemType = "SM4 PRIVATE KEY"
+	privateSuffix = "_sk"
+	publicSuffix  = "_pk"
+	secretSuffix  = "_key"
+)
+
+// NewFileKeyStore creates key store in path, directory is created if it does not exist
+func NewFileKeyStore(path string, readOnly bool) (*FileKeyStore, error) {
+	if path == "" {
+		return nil, errors.New("gm: key store path is empty")
+	}
+	if !readOnly {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &FileKeyStore{Path: path, readOnly: readOnly}, nil
+}
+
+func (ks *FileKeyStore) ReadOnly() bool {
+	return ks.readOnly
+}
+
+// GetKey loads key with given SKI. SKI of SM2 public key returns private key when it is stored.
+func (ks *FileKeyStore) GetKey(ski []byte) (Key, error) {
+	if len(ski) == 0 {
+		return nil, errors.New("gm: SKI is empty")
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	name := hex.EncodeToString(ski)
+	if data, err := ks.read(name + secretSuffix); err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil || block.Type != sm4KeyPemType || len(block.Bytes) != SM4KeySize {
+			return nil, fmt.Errorf("gm: invalid SM4 key file for %s", name)
+		}
+		return &SM4Key{Key: block.Bytes}, nil
+	}
+	if data, err := ks.read(name + privateSuffix); err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("gm: invalid private key file for %s", name)
+		}
+		key, err := ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			if key, err = ParseECPrivateKey(block.Bytes); err != nil {
+				return nil, err
+			}
+		}
+		return &SM2PrivateKey{Key: key}, nil
+	}
+	if data, err := ks.read(name + publicSuffix); err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("gm: invalid public key file for %s", name)
+		}
+		key, err := ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return &SM2PublicKey{Key: key}, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+// StoreKey writes key to file named by its SKI
+func (ks *FileKeyStore) StoreKey(k Key) error {
+	if ks.readOnly {
+		return ErrReadOnlyKeyStore
+	}
+	var name string
+	var block *pem.Block
+	switch key := k.(type) {
+	case *SM2PrivateKey:
+		der, err := MarshalPKCS8PrivateKey(key.Key)
+		if err != nil {
+			return err
+		}
+		name, block = privateSuffix, &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	case *SM2PublicKey:
+		der, err := MarshalPKIXPublicKey(key.Key)
+		if err != nil {
+			return err
+		}
+		name, block = publicSuffix, &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	case *SM4Key:
+		name, block = secretSuffix, &pem.Block{Type: sm4KeyPemType, Bytes: key.Key}
+	default:
+		return fmt.Errorf("gm: unsupported key type %T", k)
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ioutil.WriteFile(filepath.Join(ks.Path, hex.EncodeToString(k.SKI())+name), pem.EncodeToMemory(block), 0600)
+}
+
+func (ks *FileKeyStore) read(name string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(ks.Path, name))
+}
@@ -0,0 +1,181 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gm
+
+import (
+	"crypto"
+	"crypto/elliptic"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"sync"
+)
+
+// DefaultUID is the user id used in SM2 signatures when none is provided. Fabric GM distributions use this value.
+var DefaultUID = []byte("1234567812345678")
+
+var (
+	sm2Once   sync.Once
+	sm2Params *elliptic.CurveParams
+	sm2Curve  *sm2P256
+)
+
+// P256SM2 returns SM2 recommended curve (GB/T 32918.5). Scalar multiplication and point addition run in constant
+// time.
+func P256SM2() elliptic.Curve {
+	sm2Once.Do(func() {
+		sm2Params = &elliptic.CurveParams{Name: "SM2-P-256", BitSize: 256}
+		sm2Params.P, _ = new(big.Int).SetString("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF00000000FFFFFFFFFFFFFFFF", 16)
+		sm2Params.N, _ = new(big.Int).SetString("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFF7203DF6B21C6052B53BBF40939D54123", 16)
+		sm2Params.B, _ = new(big.Int).SetString("28E9FA9E9D9F5E344D5A9E4BCF6509A7F39789F515AB8F92DDBCBD414D940E93", 16)
+		sm2Params.Gx, _ = new(big.Int).SetString("32C4AE2C1F1981195F9904466A39C9948FE30BBFF2660BE1715A4589334C74C7", 16)
+		sm2Params.Gy, _ = new(big.Int).SetString("BC3736A2F4F6779C59BDCEE36B692153D0A9877CC62A474002DF32E52139F0A0", 16)
+		initSM2P256(sm2Params)
+		sm2Curve = &sm2P256{sm2Params}
+	})
+	return sm2Curve
+}
+
+// PublicKey is SM2 public key
+type PublicKey struct {
+	elliptic.Curve
+	X, Y *big.Int
+}
+
+// PrivateKey is SM2 private key. It implements crypto.Signer, signing message with DefaultUID.
+type PrivateKey struct {
+	PublicKey
+	D *big.Int
+}
+
+type sm2Signature struct {
+	R, S *big.Int
+}
+
+// Public returns public part of private key
+func (k *PrivateKey) Public() crypto.PublicKey {
+	return &k.PublicKey
+}
+
+// Sign signs msg (not digest, SM2 hashes the message together with signer identity) and returns DER encoded
+// signature. opts are ignored.
+func (k *PrivateKey) Sign(rand io.Reader, msg []byte, opts crypto.SignerOpts) ([]byte, error) {
+	r, s, err := Sign(rand, k, DefaultUID, msg)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(sm2Signature{r, s})
+}
+
+// Verify verifies DER encoded signature of msg signed with DefaultUID
+func (k *PublicKey) Verify(msg, sig []byte) bool {
+	s := new(sm2Signature)
+	if rest, err := asn1.Unmarshal(sig, s); err != nil || len(rest) > 0 {
+		return false
+	}
+	return Verify(k, DefaultUID, msg, s.R, s.S)
+}
+
+// GenerateKey generates SM2 key pair
+func GenerateKey(rand io.Reader) (*PrivateKey, error) {
+	c := P256SM2()
+	d, err := randScalar(c, rand)
+	if err != nil {
+		return nil, err
+	}
+	k := &PrivateKey{D: d}
+	k.Curve = c
+	k.X, k.Y = c.ScalarBaseMult(d.Bytes())
+	return k, nil
+}
+
+// randScalar returns random value in range [1, N-2], so 1+d is invertible
+func randScalar(c elliptic.Curve, rand io.Reader) (*big.Int, error) {
+	n := c.Params().N
+	b := make([]byte, c.Params().BitSize/8+8)
+	if _, err := io.ReadFull(rand, b); err != nil {
+		return nil, err
+	}
+	k := new(big.Int).SetBytes(b)
+	max := new(big.Int).Sub(n, big.NewInt(2))
+	k.Mod(k, max)
+	k.Add(k, big.NewInt(1))
+	return k, nil
+}
+
+// Digest returns SM3(Z || msg) where Z identifies signer by uid and public key, as required by SM2 signature scheme
+func Digest(pub *PublicKey, uid, msg []byte) []byte {
+	params := pub.Params()
+	a := new(big.Int).Sub(params.P, big.NewInt(3))
+	h := NewSM3()
+	bitLen := len(uid) * 8
+	h.Write([]byte{byte(bitLen >> 8), byte(bitLen)})
+	h.Write(uid)
+	for _, v := range []*big.Int{a, params.B, params.Gx, params.Gy, pub.X, pub.Y} {
+		h.Write(fixedBytes(v, 32))
+	}
+	z := h.Sum(nil)
+	h.Reset()
+	h.Write(z)
+	h.Write(msg)
+	return h.Sum(nil)
+}
+
+// Sign signs msg with SM2 private key using uid as signer id
+func Sign(rand io.Reader, priv *PrivateKey, uid, msg []byte) (r, s *big.Int, err error) {
+	n := priv.Params().N
+	e := new(big.Int).SetBytes(Digest(&priv.PublicKey, uid, msg))
+	dInv := new(big.Int).Add(priv.D, big.NewInt(1))
+	dInv.ModInverse(dInv, n)
+	for {
+		k, err := randScalar(priv.Curve, rand)
+		if err != nil {
+			return nil, nil, err
+		}
+		x1, _ := priv.ScalarBaseMult(k.Bytes())
+		r = new(big.Int).Add(e, x1)
+		r.Mod(r, n)
+		if r.Sign() == 0 || new(big.Int).Add(r, k).Cmp(n) == 0 {
+			continue
+		}
+		s = new(big.Int).Mul(r, priv.D)
+		s.Sub(k, s)
+		s.Mul(s, dInv)
+		s.Mod(s, n)
+		if s.Sign() != 0 {
+			return r, s, nil
+		}
+	}
+}
+
+// Verify verifies SM2 signature r, s of msg signed by signer with uid
+func Verify(pub *PublicKey, uid, msg []byte, r, s *big.Int) bool {
+	n := pub.Params().N
+	if r == nil || s == nil || r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(n) >= 0 || s.Cmp(n) >= 0 {
+		return false
+	}
+	t := new(big.Int).Add(r, s)
+	t.Mod(t, n)
+	if t.Sign() == 0 {
+		return false
+	}
+	e := new(big.Int).SetBytes(Digest(pub, uid, msg))
+	x1, y1 := pub.ScalarBaseMult(s.Bytes())
+	x2, y2 := pub.ScalarMult(pub.X, pub.Y, t.Bytes())
+	x, _ := pub.Add(x1, y1, x2, y2)
+	x.Add(x, e)
+	x.Mod(x, n)
+	return x.Cmp(r) == 0
+}
+
+func fixedBytes(v *big.Int, size int) []byte {
+	b := v.Bytes()
+	if len(b) >= size {
+		return b
+	}
+	out := make([]byte, size)
+	copy(out[size-len(b):], b)
+	return out
+}
@@ -0,0 +1,113 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gm
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func hexInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		t.Fatalf("invalid hex %s", s)
+	}
+	return v
+}
+
+// Example of GM/T 0003.5 for recommended curve, message "message digest" signed with DefaultUID
+func TestVerifyKnownAnswer(t *testing.T) {
+	d := hexInt(t, "3945208F7B2144B13F36E38AC6D39F95889393692860B51A42FB81EF4DF7C5B8")
+	priv := &PrivateKey{D: d}
+	priv.Curve = P256SM2()
+	priv.X, priv.Y = priv.ScalarBaseMult(d.Bytes())
+	if priv.X.Cmp(hexInt(t, "09F9DF311E5421A150DD7D161E4BC5C672179FAD1833FC076BB08FF356F35020")) != 0 ||
+		priv.Y.Cmp(hexInt(t, "CCEA490CE26775A52DC6EA718CC1AA600AED05FBF35E084A6632F6072DA9AD13")) != 0 {
+		t.Fatalf("unexpected public key %X %X", priv.X, priv.Y)
+	}
+	r := hexInt(t, "F5A03B0648D2C4630EEAC513E1BB81A15944DA3827D5B74143AC7EACEEE720B3")
+	s := hexInt(t, "B1B6AA29DF212FD8763182BC0D421CA1BB9038FD1F7F42D4840B69C485BBC1AA")
+	msg := []byte("message digest")
+	if !Verify(&priv.PublicKey, DefaultUID, msg, r, s) {
+		t.Fatal("known answer signature does not verify")
+	}
+	if Verify(&priv.PublicKey, DefaultUID, []byte("message digesT"), r, s) {
+		t.Fatal("signature of other message verified")
+	}
+	if Verify(&priv.PublicKey, []byte("ALICE123@YAHOO.COM"), msg, r, s) {
+		t.Fatal("signature with other user id verified")
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("gohfc")
+	sig, err := priv.Sign(rand.Reader, msg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !priv.PublicKey.Verify(msg, sig) {
+		t.Fatal("signature does not verify")
+	}
+	if priv.PublicKey.Verify([]byte("other"), sig) {
+		t.Fatal("signature of other message verified")
+	}
+	other, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.PublicKey.Verify(msg, sig) {
+		t.Fatal("signature verified with other key")
+	}
+	sig[len(sig)-1] ^= 1
+	if priv.PublicKey.Verify(msg, sig) {
+		t.Fatal("tampered signature verified")
+	}
+}
+
+// TestCurveMatchesGeneric compares constant time implementation with generic one of crypto/elliptic
+func TestCurveMatchesGeneric(t *testing.T) {
+	c := P256SM2()
+	generic := c.Params()
+	n := generic.N
+	scalars := [][]byte{{0}, {1}, {2}, new(big.Int).Sub(n, big.NewInt(1)).Bytes(), n.Bytes(),
+		new(big.Int).Add(n, big.NewInt(1)).Bytes()}
+	for i := 0; i < 20; i++ {
+		k := make([]byte, 32)
+		rand.Read(k)
+		scalars = append(scalars, k)
+	}
+	for _, k := range scalars {
+		x1, y1 := c.ScalarBaseMult(k)
+		x2, y2 := generic.ScalarBaseMult(k)
+		if x1.Cmp(x2) != 0 || y1.Cmp(y2) != 0 {
+			t.Fatalf("ScalarBaseMult(%x) differs", k)
+		}
+		px, py := generic.ScalarBaseMult([]byte{7})
+		x1, y1 = c.ScalarMult(px, py, k)
+		x2, y2 = generic.ScalarMult(px, py, k)
+		if x1.Cmp(x2) != 0 || y1.Cmp(y2) != 0 {
+			t.Fatalf("ScalarMult(%x) differs", k)
+		}
+		x1, y1 = c.Add(px, py, x2, y2)
+		x3, y3 := generic.Add(px, py, x2, y2)
+		if x1.Cmp(x3) != 0 || y1.Cmp(y3) != 0 {
+			t.Fatalf("Add differs for %x", k)
+		}
+		x1, y1 = c.Double(x2, y2)
+		x3, y3 = generic.Double(x2, y2)
+		if x1.Cmp(x3) != 0 || y1.Cmp(y3) != 0 {
+			t.Fatalf("Double differs for %x", k)
+		}
+	}
+	if !c.IsOnCurve(generic.Gx, generic.Gy) {
+		t.Fatal("base point is not on curve")
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gm
+
+import (
+	"crypto/subtle"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// ErrDecryption is returned when SM2 ciphertext is malformed or was not encrypted for the key
+var ErrDecryption = errors.New("sm2: decryption error")
+
+// sm2Ciphertext is ASN.1 form of SM2 ciphertext defined by GM/T 0009, it keeps C1 C3 C2 order
+type sm2Ciphertext struct {
+	X, Y       *big.Int
+	Hash       []byte
+	Ciphertext []byte
+}
+
+// Encrypt encrypts msg with SM2 public key encryption (GB/T 32918.4). Ciphertext is ASN.1 encoded as in GM/T 0009,
+// the format used by OpenSSL and Fabric GM distributions.
+func Encrypt(rand io.Reader, pub *PublicKey, msg []byte) ([]byte, error) {
+	if len(msg) == 0 {
+		return nil, errors.New("sm2: empty message")
+	}
+	for {
+		k, err := randScalar(pub.Curve, rand)
+		if err != nil {
+			return nil, err
+		}
+		x1, y1 := pub.ScalarBaseMult(k.Bytes())
+		x2, y2 := pub.ScalarMult(pub.X, pub.Y, k.Bytes())
+		x2b, y2b := fixedBytes(x2, 32), fixedBytes(y2, 32)
+		t, ok := sm2KDF(len(msg), x2b, y2b)
+		if !ok {
+			continue
+		}
+		c2 := make([]byte, len(msg))
+		for i := range msg {
+			c2[i] = msg[i] ^ t[i]
+		}
+		return asn1.Marshal(sm2Ciphertext{X: x1, Y: y1, Hash: sm2C3(x2b, msg, y2b), Ciphertext: c2})
+	}
+}
+
+// Decrypt decrypts ciphertext produced by Encrypt for public key of priv
+func Decrypt(priv *PrivateKey, ciphertext []byte) ([]byte, error) {
+	var c sm2Ciphertext
+	if rest, err := asn1.Unmarshal(ciphertext, &c); err != nil || len(rest) > 0 {
+		return nil, ErrDecryption
+	}
+	if c.X == nil || c.Y == nil || len(c.Ciphertext) == 0 || !priv.IsOnCurve(c.X, c.Y) {
+		return nil, ErrDecryption
+	}
+	x2, y2 := priv.ScalarMult(c.X, c.Y, priv.D.Bytes())
+	x2b, y2b := fixedBytes(x2, 32), fixedBytes(y2, 32)
+	t, ok := sm2KDF(len(c.Ciphertext), x2b, y2b)
+	if !ok {
+		return nil, ErrDecryption
+	}
+	msg := make([]byte, len(c.Ciphertext))
+	for i := range msg {
+		msg[i] = c.Ciphertext[i] ^ t[i]
+	}
+	if subtle.ConstantTimeCompare(sm2C3(x2b, msg, y2b), c.Hash) != 1 {
+		return nil, ErrDecryption
+	}
+	return msg, nil
+}
+
+// sm2KDF derives length bytes from shared point with SM3 based key derivation function. It returns false when all
+// derived bytes are zero, encryption must then use other random value.
+func sm2KDF(length int, x2, y2 []byte) ([]byte, bool) {
+	out := make([]byte, 0, length+Size)
+	var ct [4]byte
+	for i := uint32(1); len(out) < length; i++ {
+		binary.BigEndian.PutUint32(ct[:], i)
+		h := NewSM3()
+		h.Write(x2)
+		h.Write(y2)
+		h.Write(ct[:])
+		out = h.Sum(out)
+	}
+	out = out[:length]
+	var any byte
+	for _, b := range out {
+		any |= b
+	}
+	return out, any != 0
+}
+
+// sm2C3 returns SM3(x2 || msg || y2), hash that authenticates decrypted message
+func sm2C3(x2, msg, y2 []byte) []byte {
+	h := NewSM3()
+	h.Write(x2)
+	h.Write(msg)
+	h.Write(y2)
+	return h.Sum(nil)
+}
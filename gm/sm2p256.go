@@ -0,0 +1,342 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gm
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"math/bits"
+)
+
+// sm2P256 implements SM2 curve with constant time field arithmetic and scalar multiplication. Field elements are
+// kept in Montgomery form in four 64 bit limbs, points in projective coordinates and are added with complete
+// formulas for a = -3 from "Complete addition formulas for prime order elliptic curves"
+// (https://eprint.iacr.org/2015/1060), so no operation branches on secret values.
+type sm2P256 struct {
+	*elliptic.CurveParams
+}
+
+// fieldElement is element of SM2 prime field in Montgomery form, least significant limb first
+type fieldElement [4]uint64
+
+var (
+	sm2P = fieldElement{0xffffffffffffffff, 0xffffffff00000000, 0xffffffffffffffff, 0xfffffffeffffffff}
+	// sm2RR is 2^512 mod p, used to convert to Montgomery form
+	sm2RR fieldElement
+	// sm2One is 1 in Montgomery form
+	sm2One fieldElement
+	// sm2B is curve parameter b in Montgomery form
+	sm2B fieldElement
+)
+
+func initSM2P256(params *elliptic.CurveParams) {
+	rr := new(big.Int).Lsh(big.NewInt(1), 512)
+	rr.Mod(rr, params.P)
+	sm2RR = fieldFromBig(rr)
+	one := new(big.Int).Lsh(big.NewInt(1), 256)
+	one.Mod(one, params.P)
+	sm2One = fieldFromBig(one)
+	sm2B.toMontgomery(params.B)
+}
+
+// fieldFromBig returns limbs of v without conversion to Montgomery form, v must be lower than p
+func fieldFromBig(v *big.Int) fieldElement {
+	var b [32]byte
+	v.FillBytes(b[:])
+	var e fieldElement
+	for i := range e {
+		e[i] = uint64(b[31-8*i]) | uint64(b[30-8*i])<<8 | uint64(b[29-8*i])<<16 | uint64(b[28-8*i])<<24 |
+			uint64(b[27-8*i])<<32 | uint64(b[26-8*i])<<40 | uint64(b[25-8*i])<<48 | uint64(b[24-8*i])<<56
+	}
+	return e
+}
+
+func (e *fieldElement) toMontgomery(v *big.Int) {
+	if v.Sign() < 0 || v.Cmp(sm2Params.P) >= 0 {
+		v = new(big.Int).Mod(v, sm2Params.P)
+	}
+	x := fieldFromBig(v)
+	e.mul(&x, &sm2RR)
+}
+
+func (e *fieldElement) toBig() *big.Int {
+	var x fieldElement
+	one := fieldElement{1}
+	x.mul(e, &one)
+	var b [32]byte
+	for i := range x {
+		for j := 0; j < 8; j++ {
+			b[31-8*i-j] = byte(x[i] >> (8 * uint(j)))
+		}
+	}
+	return new(big.Int).SetBytes(b[:])
+}
+
+// mul sets e = x * y / 2^256 mod p
+func (e *fieldElement) mul(x, y *fieldElement) {
+	var t [6]uint64
+	for i := 0; i < 4; i++ {
+		var c uint64
+		for j := 0; j < 4; j++ {
+			hi, lo := bits.Mul64(x[j], y[i])
+			var carry uint64
+			lo, carry = bits.Add64(lo, t[j], 0)
+			hi += carry
+			lo, carry = bits.Add64(lo, c, 0)
+			hi += carry
+			t[j], c = lo, hi
+		}
+		var carry uint64
+		t[4], carry = bits.Add64(t[4], c, 0)
+		t[5] = carry
+		// -p^-1 mod 2^64 is 1 because the lowest limb of p is 2^64-1
+		m := t[0]
+		hi, lo := bits.Mul64(m, sm2P[0])
+		_, carry = bits.Add64(lo, t[0], 0)
+		c = hi + carry
+		for j := 1; j < 4; j++ {
+			hi, lo = bits.Mul64(m, sm2P[j])
+			lo, carry = bits.Add64(lo, t[j], 0)
+			hi += carry
+			lo, carry = bits.Add64(lo, c, 0)
+			hi += carry
+			t[j-1], c = lo, hi
+		}
+		t[3], carry = bits.Add64(t[4], c, 0)
+		t[4] = t[5] + carry
+	}
+	e.reduce(t[0], t[1], t[2], t[3], t[4])
+}
+
+// reduce sets e to value of limbs and carry minus p when it is not lower than p
+func (e *fieldElement) reduce(t0, t1, t2, t3, carry uint64) {
+	var b uint64
+	r0, b := bits.Sub64(t0, sm2P[0], 0)
+	r1, b := bits.Sub64(t1, sm2P[1], b)
+	r2, b := bits.Sub64(t2, sm2P[2], b)
+	r3, b := bits.Sub64(t3, sm2P[3], b)
+	_, b = bits.Sub64(carry, 0, b)
+	// b is 1 when value is lower than p and must be kept
+	mask := -b
+	e[0] = t0&mask | r0&^mask
+	e[1] = t1&mask | r1&^mask
+	e[2] = t2&mask | r2&^mask
+	e[3] = t3&mask | r3&^mask
+}
+
+func (e *fieldElement) square(x *fieldElement) {
+	e.mul(x, x)
+}
+
+// add sets e = x + y mod p
+func (e *fieldElement) add(x, y *fieldElement) {
+	var c uint64
+	t0, c := bits.Add64(x[0], y[0], 0)
+	t1, c := bits.Add64(x[1], y[1], c)
+	t2, c := bits.Add64(x[2], y[2], c)
+	t3, c := bits.Add64(x[3], y[3], c)
+	e.reduce(t0, t1, t2, t3, c)
+}
+
+// sub sets e = x - y mod p
+func (e *fieldElement) sub(x, y *fieldElement) {
+	var b uint64
+	t0, b := bits.Sub64(x[0], y[0], 0)
+	t1, b := bits.Sub64(x[1], y[1], b)
+	t2, b := bits.Sub64(x[2], y[2], b)
+	t3, b := bits.Sub64(x[3], y[3], b)
+	// add p back when subtraction borrowed
+	mask := -b
+	var c uint64
+	e[0], c = bits.Add64(t0, sm2P[0]&mask, 0)
+	e[1], c = bits.Add64(t1, sm2P[1]&mask, c)
+	e[2], c = bits.Add64(t2, sm2P[2]&mask, c)
+	e[3], _ = bits.Add64(t3, sm2P[3]&mask, c)
+}
+
+// invert sets e = 1/x mod p as x^(p-2), inverse of zero is zero
+func (e *fieldElement) invert(x *fieldElement) {
+	// p-2 limbs, most significant first
+	exp := [4]uint64{sm2P[3], sm2P[2], sm2P[1], sm2P[0] - 2}
+	r := sm2One
+	for _, limb := range exp {
+		for i := 63; i >= 0; i-- {
+			r.square(&r)
+			if (limb>>uint(i))&1 == 1 {
+				// exponent is public, branching on it does not leak x
+				r.mul(&r, x)
+			}
+		}
+	}
+	*e = r
+}
+
+// selectFrom sets e to x when cond is 1, keeps e when cond is 0
+func (e *fieldElement) selectFrom(x *fieldElement, cond uint64) {
+	mask := -cond
+	for i := range e {
+		e[i] = e[i]&^mask | x[i]&mask
+	}
+}
+
+// sm2Point is point in projective coordinates, identity has z = 0
+type sm2Point struct {
+	x, y, z fieldElement
+}
+
+func newIdentity() *sm2Point {
+	return &sm2Point{y: sm2One}
+}
+
+func newPointFromAffine(x, y *big.Int) *sm2Point {
+	p := &sm2Point{z: sm2One}
+	p.x.toMontgomery(x)
+	p.y.toMontgomery(y)
+	return p
+}
+
+// affine returns affine coordinates of p, (0, 0) for identity
+func (p *sm2Point) affine() (*big.Int, *big.Int) {
+	var zInv, x, y fieldElement
+	zInv.invert(&p.z)
+	x.mul(&p.x, &zInv)
+	y.mul(&p.y, &zInv)
+	return x.toBig(), y.toBig()
+}
+
+// add sets q = p1 + p2, points may overlap
+func (q *sm2Point) add(p1, p2 *sm2Point) *sm2Point {
+	var t0, t1, t2, t3, t4, x3, y3, z3 fieldElement
+	t0.mul(&p1.x, &p2.x)
+	t1.mul(&p1.y, &p2.y)
+	t2.mul(&p1.z, &p2.z)
+	t3.add(&p1.x, &p1.y)
+	t4.add(&p2.x, &p2.y)
+	t3.mul(&t3, &t4)
+	t4.add(&t0, &t1)
+	t3.sub(&t3, &t4)
+	t4.add(&p1.y, &p1.z)
+	x3.add(&p2.y, &p2.z)
+	t4.mul(&t4, &x3)
+	x3.add(&t1, &t2)
+	t4.sub(&t4, &x3)
+	x3.add(&p1.x, &p1.z)
+	y3.add(&p2.x, &p2.z)
+	x3.mul(&x3, &y3)
+	y3.add(&t0, &t2)
+	y3.sub(&x3, &y3)
+	z3.mul(&sm2B, &t2)
+	x3.sub(&y3, &z3)
+	z3.add(&x3, &x3)
+	x3.add(&x3, &z3)
+	z3.sub(&t1, &x3)
+	x3.add(&t1, &x3)
+	y3.mul(&sm2B, &y3)
+	t1.add(&t2, &t2)
+	t2.add(&t1, &t2)
+	y3.sub(&y3, &t2)
+	y3.sub(&y3, &t0)
+	t1.add(&y3, &y3)
+	y3.add(&t1, &y3)
+	t1.add(&t0, &t0)
+	t0.add(&t1, &t0)
+	t0.sub(&t0, &t2)
+	t1.mul(&t4, &y3)
+	t2.mul(&t0, &y3)
+	y3.mul(&x3, &z3)
+	y3.add(&y3, &t2)
+	x3.mul(&t3, &x3)
+	x3.sub(&x3, &t1)
+	z3.mul(&t4, &z3)
+	t1.mul(&t3, &t0)
+	z3.add(&z3, &t1)
+	q.x, q.y, q.z = x3, y3, z3
+	return q
+}
+
+// selectFrom sets q to p when cond is 1, keeps q when cond is 0
+func (q *sm2Point) selectFrom(p *sm2Point, cond uint64) {
+	q.x.selectFrom(&p.x, cond)
+	q.y.selectFrom(&p.y, cond)
+	q.z.selectFrom(&p.z, cond)
+}
+
+// scalarMult sets q = k * p with fixed 4 bit window. Table lookups read all entries, so memory access does not
+// depend on k.
+func (q *sm2Point) scalarMult(p *sm2Point, k []byte) *sm2Point {
+	var table [16]sm2Point
+	table[0] = *newIdentity()
+	table[1] = *p
+	for i := 2; i < 16; i++ {
+		table[i].add(&table[i-1], p)
+	}
+	r := newIdentity()
+	var selected sm2Point
+	for _, b := range k {
+		for _, window := range [2]byte{b >> 4, b & 0x0f} {
+			r.add(r, r)
+			r.add(r, r)
+			r.add(r, r)
+			r.add(r, r)
+			selected = table[0]
+			for i := 1; i < 16; i++ {
+				selected.selectFrom(&table[i], equal(uint64(window), uint64(i)))
+			}
+			r.add(r, &selected)
+		}
+	}
+	*q = *r
+	return q
+}
+
+// equal returns 1 when a and b are equal, otherwise 0
+func equal(a, b uint64) uint64 {
+	v := a ^ b
+	return 1 ^ ((v | -v) >> 63)
+}
+
+// scalarBytes returns k as 32 bytes, scalars longer than order are reduced
+func (c *sm2P256) scalarBytes(k []byte) []byte {
+	if len(k) > 32 {
+		r := new(big.Int).SetBytes(k)
+		r.Mod(r, c.N)
+		k = r.Bytes()
+	}
+	out := make([]byte, 32)
+	copy(out[32-len(k):], k)
+	return out
+}
+
+func (c *sm2P256) Params() *elliptic.CurveParams {
+	return c.CurveParams
+}
+
+func (c *sm2P256) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	p1, p2 := c.point(x1, y1), c.point(x2, y2)
+	return p1.add(p1, p2).affine()
+}
+
+func (c *sm2P256) Double(x, y *big.Int) (*big.Int, *big.Int) {
+	p := c.point(x, y)
+	return p.add(p, p).affine()
+}
+
+func (c *sm2P256) ScalarMult(x, y *big.Int, k []byte) (*big.Int, *big.Int) {
+	p := c.point(x, y)
+	return p.scalarMult(p, c.scalarBytes(k)).affine()
+}
+
+func (c *sm2P256) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	p := newPointFromAffine(c.Gx, c.Gy)
+	return p.scalarMult(p, c.scalarBytes(k)).affine()
+}
+
+// point converts affine point to projective, (0, 0) is identity
+func (c *sm2P256) point(x, y *big.Int) *sm2Point {
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return newIdentity()
+	}
+	return newPointFromAffine(x, y)
+}
@@ -0,0 +1,63 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Examples of GB/T 32905 appendix A
+func TestSM3KnownAnswer(t *testing.T) {
+	tests := []struct {
+		msg, sum string
+	}{
+		{"abc", "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+		{strings.Repeat("abcd", 16), "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+	}
+	for _, test := range tests {
+		sum := SumSM3([]byte(test.msg))
+		if hex.EncodeToString(sum[:]) != test.sum {
+			t.Errorf("SM3(%q) = %x, expected %s", test.msg, sum, test.sum)
+		}
+		h := NewSM3()
+		h.Write([]byte(test.msg))
+		if hex.EncodeToString(h.Sum(nil)) != test.sum {
+			t.Errorf("NewSM3 of %q differs from SumSM3", test.msg)
+		}
+	}
+}
+
+func TestSM3Streaming(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	want := SumSM3(data)
+	for _, chunk := range []int{1, 7, 63, 64, 65, 500} {
+		h := NewSM3()
+		for i := 0; i < len(data); i += chunk {
+			end := i + chunk
+			if end > len(data) {
+				end = len(data)
+			}
+			h.Write(data[i:end])
+		}
+		if !bytes.Equal(h.Sum(nil), want[:]) {
+			t.Fatalf("chunk %d: streaming sum differs", chunk)
+		}
+		// Sum does not change state
+		if !bytes.Equal(h.Sum(nil), want[:]) {
+			t.Fatalf("chunk %d: second Sum differs", chunk)
+		}
+		h.Reset()
+		h.Write(data)
+		if !bytes.Equal(h.Sum(nil), want[:]) {
+			t.Fatalf("chunk %d: sum after Reset differs", chunk)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gm
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// SM4Mode is block cipher mode used by SM4Encrypt and SM4Decrypt
+type SM4Mode int
+
+const (
+	// SM4ModeCBC is CBC mode with PKCS #7 padding. Ciphertext is IV followed by encrypted blocks.
+	SM4ModeCBC SM4Mode = iota
+	// SM4ModeGCM is authenticated GCM mode. Ciphertext is nonce followed by encrypted data and tag.
+	SM4ModeGCM
+)
+
+var (
+	// ErrInvalidPadding is returned when decrypted data has invalid PKCS #7 padding
+	ErrInvalidPadding = errors.New("sm4: invalid padding")
+	// ErrCiphertextTooShort is returned when ciphertext is shorter than IV or nonce
+	ErrCiphertextTooShort = errors.New("sm4: ciphertext too short")
+	// ErrUnknownMode is returned for unknown SM4Mode
+	ErrUnknownMode = errors.New("sm4: unknown mode")
+)
+
+// EncrypterOpts selects mode and parameters for SM4Encrypt and SM4Decrypt. Nil opts select CBC with random IV.
+type EncrypterOpts struct {
+	Mode SM4Mode
+	// IV is CBC IV (16 bytes) or GCM nonce (12 bytes). If empty random value is generated on encryption.
+	// Reusing IV with the same key is insecure, it should be set only to reproduce known ciphertext.
+	IV []byte
+	// AdditionalData is authenticated but not encrypted data for GCM mode
+	AdditionalData []byte
+	// Rand is source of random IV, crypto/rand is used if nil
+	Rand io.Reader
+}
+
+// SM4Encrypt encrypts plaintext of any length with SM4 key. IV or nonce is prepended to ciphertext.
+func SM4Encrypt(key, plaintext []byte, opts *EncrypterOpts) ([]byte, error) {
+	if opts == nil {
+		opts = &EncrypterOpts{}
+	}
+	block, err := NewSM4Cipher(key)
+	if err != nil {
+		return nil, err
+	}
+	random := opts.Rand
+	if random == nil {
+		random = rand.Reader
+	}
+	switch opts.Mode {
+	case SM4ModeCBC:
+		iv, err := ivOrRandom(opts.IV, SM4BlockSize, random)
+		if err != nil {
+			return nil, err
+		}
+		padded := PKCS7Pad(plaintext, SM4BlockSize)
+		out := make([]byte, len(iv)+len(padded))
+		copy(out, iv)
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(out[len(iv):], padded)
+		return out, nil
+	case SM4ModeGCM:
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		nonce, err := ivOrRandom(opts.IV, gcm.NonceSize(), random)
+		if err != nil {
+			return nil, err
+		}
+		return gcm.Seal(append([]byte{}, nonce...), nonce, plaintext, opts.AdditionalData), nil
+	default:
+		return nil, ErrUnknownMode
+	}
+}
+
+// SM4Decrypt decrypts ciphertext produced by SM4Encrypt with the same mode. opts.IV is ignored, IV or nonce is
+// taken from ciphertext.
+func SM4Decrypt(key, ciphertext []byte, opts *EncrypterOpts) ([]byte, error) {
+	if opts == nil {
+		opts = &EncrypterOpts{}
+	}
+	block, err := NewSM4Cipher(key)
+	if err != nil {
+		return nil, err
+	}
+	switch opts.Mode {
+	case SM4ModeCBC:
+		if len(ciphertext) < 2*SM4BlockSize {
+			return nil, ErrCiphertextTooShort
+		}
+		if len(ciphertext)%SM4BlockSize != 0 {
+			return nil, errors.New("sm4: ciphertext is not a multiple of the block size")
+		}
+		iv, data := ciphertext[:SM4BlockSize], ciphertext[SM4BlockSize:]
+		out := make([]byte, len(data))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+		return PKCS7Unpad(out, SM4BlockSize)
+	case SM4ModeGCM:
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		if len(ciphertext) < gcm.NonceSize()+gcm.Overhead() {
+			return nil, ErrCiphertextTooShort
+		}
+		nonce, data := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+		return gcm.Open(nil, nonce, data, opts.AdditionalData)
+	default:
+		return nil, ErrUnknownMode
+	}
+}
+
+// PKCS7Pad pads data to multiple of blockSize. Full block of padding is added when data is already aligned.
+func PKCS7Pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+	return append(append([]byte{}, data...), bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+// PKCS7Unpad removes PKCS #7 padding
+func PKCS7Unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, ErrInvalidPadding
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize {
+		return nil, ErrInvalidPadding
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, ErrInvalidPadding
+		}
+	}
+	return data[:len(data)-n], nil
+}
+
+func ivOrRandom(iv []byte, size int, random io.Reader) ([]byte, error) {
+	if len(iv) > 0 {
+		if len(iv) != size {
+			return nil, errors.New("sm4: invalid IV size")
+		}
+		return iv, nil
+	}
+	iv = make([]byte, size)
+	if _, err := io.ReadFull(random, iv); err != nil {
+		return nil, err
+	}
+	return iv, nil
+}
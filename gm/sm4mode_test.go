@@ -0,0 +1,164 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+var sm4TestKey, _ = hex.DecodeString("0123456789abcdeffedcba9876543210")
+
+// Examples of GB/T 32907 appendix A
+func TestSM4KnownAnswer(t *testing.T) {
+	block, err := NewSM4Cipher(sm4TestKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := make([]byte, SM4BlockSize)
+	block.Encrypt(dst, sm4TestKey)
+	if hex.EncodeToString(dst) != "681edf34d206965e86b3e94f536e4246" {
+		t.Fatalf("unexpected ciphertext %x", dst)
+	}
+	block.Decrypt(dst, dst)
+	if !bytes.Equal(dst, sm4TestKey) {
+		t.Fatalf("unexpected plaintext %x", dst)
+	}
+	if testing.Short() {
+		return
+	}
+	copy(dst, sm4TestKey)
+	for i := 0; i < 1000000; i++ {
+		block.Encrypt(dst, dst)
+	}
+	if hex.EncodeToString(dst) != "595298c7c6fd271f0402f804c33d3f66" {
+		t.Fatalf("unexpected ciphertext after 1000000 rounds %x", dst)
+	}
+}
+
+// Ciphertext generated with OpenSSL 3.0: openssl enc -sm4-cbc -K <key> -iv <iv>
+func TestSM4CBCKnownAnswer(t *testing.T) {
+	iv, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	plaintext := []byte("SM4 CBC known answer text, 45 bytes long!!!!")
+	want := "000102030405060708090a0b0c0d0e0f" + "f54c5332ff1e7f2310ccd1aedaf6d99d846f48cbbacdda8e3067317f9173230f" +
+		"afacda4c2f63b13b524edbab119dddc1"
+	ct, err := SM4Encrypt(sm4TestKey, plaintext, &EncrypterOpts{IV: iv})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(ct) != want {
+		t.Fatalf("unexpected ciphertext %x", ct)
+	}
+	pt, err := SM4Decrypt(sm4TestKey, ct, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pt, plaintext) {
+		t.Fatalf("unexpected plaintext %q", pt)
+	}
+}
+
+func TestSM4RoundTrip(t *testing.T) {
+	for _, mode := range []SM4Mode{SM4ModeCBC, SM4ModeGCM} {
+		for size := 0; size <= 3*SM4BlockSize+1; size++ {
+			plaintext := make([]byte, size)
+			rand.Read(plaintext)
+			opts := &EncrypterOpts{Mode: mode, AdditionalData: []byte("aad")}
+			ct, err := SM4Encrypt(sm4TestKey, plaintext, opts)
+			if err != nil {
+				t.Fatalf("mode %d size %d: %v", mode, size, err)
+			}
+			if mode == SM4ModeCBC && (len(ct)%SM4BlockSize != 0 || len(ct) <= size+SM4BlockSize) {
+				t.Fatalf("size %d: unexpected CBC ciphertext length %d", size, len(ct))
+			}
+			pt, err := SM4Decrypt(sm4TestKey, ct, opts)
+			if err != nil {
+				t.Fatalf("mode %d size %d: %v", mode, size, err)
+			}
+			if !bytes.Equal(pt, plaintext) {
+				t.Fatalf("mode %d size %d: decrypted data differs", mode, size)
+			}
+			ct2, err := SM4Encrypt(sm4TestKey, plaintext, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(ct, ct2) {
+				t.Fatalf("mode %d size %d: random IV was not used", mode, size)
+			}
+		}
+	}
+}
+
+func TestSM4GCMAuthentication(t *testing.T) {
+	opts := &EncrypterOpts{Mode: SM4ModeGCM, AdditionalData: []byte("header")}
+	ct, err := SM4Encrypt(sm4TestKey, []byte("private data"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := append([]byte(nil), ct...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := SM4Decrypt(sm4TestKey, tampered, opts); err == nil {
+		t.Fatal("tampered ciphertext decrypted")
+	}
+	if _, err := SM4Decrypt(sm4TestKey, ct, &EncrypterOpts{Mode: SM4ModeGCM}); err == nil {
+		t.Fatal("ciphertext decrypted without additional data")
+	}
+	otherKey := make([]byte, len(sm4TestKey))
+	if _, err := SM4Decrypt(otherKey, ct, opts); err == nil {
+		t.Fatal("ciphertext decrypted with other key")
+	}
+	if _, err := SM4Decrypt(sm4TestKey, ct[:10], opts); err != ErrCiphertextTooShort {
+		t.Fatalf("expected ErrCiphertextTooShort, got %v", err)
+	}
+}
+
+func TestSM4Errors(t *testing.T) {
+	if _, err := SM4Encrypt(sm4TestKey[:8], []byte("data"), nil); err == nil {
+		t.Fatal("short key accepted")
+	}
+	if _, err := SM4Encrypt(sm4TestKey, []byte("data"), &EncrypterOpts{IV: []byte{1, 2, 3}}); err == nil {
+		t.Fatal("invalid IV size accepted")
+	}
+	if _, err := SM4Encrypt(sm4TestKey, []byte("data"), &EncrypterOpts{Mode: SM4Mode(7)}); err != ErrUnknownMode {
+		t.Fatalf("expected ErrUnknownMode, got %v", err)
+	}
+	if _, err := SM4Decrypt(sm4TestKey, make([]byte, SM4BlockSize), nil); err != ErrCiphertextTooShort {
+		t.Fatalf("expected ErrCiphertextTooShort, got %v", err)
+	}
+	if _, err := SM4Decrypt(sm4TestKey, make([]byte, 2*SM4BlockSize+1), nil); err == nil {
+		t.Fatal("unaligned CBC ciphertext accepted")
+	}
+}
+
+func TestPKCS7(t *testing.T) {
+	for size := 0; size <= 2*SM4BlockSize; size++ {
+		data := bytes.Repeat([]byte{0xaa}, size)
+		padded := PKCS7Pad(data, SM4BlockSize)
+		if len(padded)%SM4BlockSize != 0 || len(padded) <= size {
+			t.Fatalf("size %d: unexpected padded length %d", size, len(padded))
+		}
+		unpadded, err := PKCS7Unpad(padded, SM4BlockSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(unpadded, data) {
+			t.Fatalf("size %d: unpadded data differs", size)
+		}
+	}
+	invalid := [][]byte{
+		nil,
+		make([]byte, SM4BlockSize-1),
+		make([]byte, SM4BlockSize),
+		append(bytes.Repeat([]byte{1}, SM4BlockSize-1), SM4BlockSize+1),
+		append(bytes.Repeat([]byte{1}, SM4BlockSize-2), 3, 2),
+	}
+	for _, data := range invalid {
+		if _, err := PKCS7Unpad(data, SM4BlockSize); err != ErrInvalidPadding {
+			t.Fatalf("%x: expected ErrInvalidPadding, got %v", data, err)
+		}
+	}
+}
@@ -0,0 +1,483 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gm
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"time"
+)
+
+var (
+	// OIDNamedCurveSM2 identifies SM2 curve in keys and certificates
+	OIDNamedCurveSM2 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301}
+	// OIDSignatureSM2WithSM3 identifies SM2 signature with SM3 digest
+	OIDSignatureSM2WithSM3 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 501}
+
+	oidPublicKeyEC               = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidExtensionRequest          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 14}
+	oidExtensionSubjectAlt       = asn1.ObjectIdentifier{2, 5, 29, 17}
+	oidExtensionSubjectKeyId     = asn1.ObjectIdentifier{2, 5, 29, 14}
+	oidExtensionKeyUsage         = asn1.ObjectIdentifier{2, 5, 29, 15}
+	oidExtensionBasicConstraints = asn1.ObjectIdentifier{2, 5, 29, 19}
+	oidExtensionAuthorityKeyId   = asn1.ObjectIdentifier{2, 5, 29, 35}
+)
+
+// maxChainLength limits number of intermediate certificates in chain
+const maxChainLength = 10
+
+var (
+	// ErrNotSM2Key is returned when key or certificate does not use SM2 curve
+	ErrNotSM2Key = errors.New("sm2: key is not SM2 key")
+	// ErrNotSM2Signature is returned when certificate is not signed with SM2-with-SM3
+	ErrNotSM2Signature = errors.New("sm2: certificate is not signed with SM2-with-SM3")
+	// ErrUnknownAuthority is returned when certificate chain does not end in one of root certificates
+	ErrUnknownAuthority = errors.New("sm2: certificate signed by unknown authority")
+)
+
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+type publicKeyInfo struct {
+	Raw       asn1.RawContent
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// MarshalECPrivateKey encodes SM2 private key in SEC 1 form ("EC PRIVATE KEY" PEM block)
+func MarshalECPrivateKey(key *PrivateKey) ([]byte, error) {
+	return asn1.Marshal(ecPrivateKey{
+		Version:       1,
+		PrivateKey:    fixedBytes(key.D, 32),
+		NamedCurveOID: OIDNamedCurveSM2,
+		PublicKey:     asn1.BitString{Bytes: elliptic.Marshal(key.Curve, key.X, key.Y)},
+	})
+}
+
+// ParseECPrivateKey parses SM2 private key in SEC 1 form
+func ParseECPrivateKey(der []byte) (*PrivateKey, error) {
+	return parseECPrivateKey(nil, der)
+}
+
+func parseECPrivateKey(curveOID asn1.ObjectIdentifier, der []byte) (*PrivateKey, error) {
+	var k ecPrivateKey
+	if _, err := asn1.Unmarshal(der, &k); err != nil {
+		return nil, err
+	}
+	if len(k.NamedCurveOID) > 0 {
+		curveOID = k.NamedCurveOID
+	}
+	if !curveOID.Equal(OIDNamedCurveSM2) {
+		return nil, ErrNotSM2Key
+	}
+	c := P256SM2()
+	d := new(big.Int).SetBytes(k.PrivateKey)
+	if d.Sign() <= 0 || d.Cmp(c.Params().N) >= 0 {
+		return nil, errors.New("sm2: invalid private key value")
+	}
+	priv := &PrivateKey{D: d}
+	priv.Curve = c
+	priv.X, priv.Y = c.ScalarBaseMult(fixedBytes(d, 32))
+	return priv, nil
+}
+
+// MarshalPKCS8PrivateKey encodes SM2 private key in PKCS #8 form ("PRIVATE KEY" PEM block)
+func MarshalPKCS8PrivateKey(key *PrivateKey) ([]byte, error) {
+	params, err := asn1.Marshal(OIDNamedCurveSM2)
+	if err != nil {
+		return nil, err
+	}
+	inner, err := asn1.Marshal(ecPrivateKey{
+		Version:    1,
+		PrivateKey: fixedBytes(key.D, 32),
+		PublicKey:  asn1.BitString{Bytes: elliptic.Marshal(key.Curve, key.X, key.Y)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs8{
+		Algo:       pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyEC, Parameters: asn1.RawValue{FullBytes: params}},
+		PrivateKey: inner,
+	})
+}
+
+// ParsePKCS8PrivateKey parses SM2 private key in PKCS #8 form
+func ParsePKCS8PrivateKey(der []byte) (*PrivateKey, error) {
+	var p pkcs8
+	if _, err := asn1.Unmarshal(der, &p); err != nil {
+		return nil, err
+	}
+	var curve asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(p.Algo.Parameters.FullBytes, &curve); err != nil {
+		return nil, ErrNotSM2Key
+	}
+	return parseECPrivateKey(curve, p.PrivateKey)
+}
+
+// MarshalPKIXPublicKey encodes SM2 public key as SubjectPublicKeyInfo
+func MarshalPKIXPublicKey(key *PublicKey) ([]byte, error) {
+	params, err := asn1.Marshal(OIDNamedCurveSM2)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(publicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyEC, Parameters: asn1.RawValue{FullBytes: params}},
+		PublicKey: asn1.BitString{Bytes: elliptic.Marshal(key.Curve, key.X, key.Y), BitLength: 65 * 8},
+	})
+}
+
+// ParsePKIXPublicKey parses SM2 public key from SubjectPublicKeyInfo
+func ParsePKIXPublicKey(der []byte) (*PublicKey, error) {
+	var info publicKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, err
+	}
+	return parsePublicKeyInfo(&info)
+}
+
+func parsePublicKeyInfo(info *publicKeyInfo) (*PublicKey, error) {
+	var curve asn1.ObjectIdentifier
+	if !info.Algorithm.Algorithm.Equal(oidPublicKeyEC) {
+		return nil, ErrNotSM2Key
+	}
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &curve); err != nil || !curve.Equal(OIDNamedCurveSM2) {
+		return nil, ErrNotSM2Key
+	}
+	c := P256SM2()
+	x, y := elliptic.Unmarshal(c, info.PublicKey.RightAlign())
+	if x == nil {
+		return nil, errors.New("sm2: invalid public key")
+	}
+	return &PublicKey{Curve: c, X: x, Y: y}, nil
+}
+
+type certificate struct {
+	TBSCertificate     tbsCertificate
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+type tbsCertificate struct {
+	Raw                asn1.RawContent
+	Version            int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber       *big.Int
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Issuer             asn1.RawValue
+	Validity           validity
+	Subject            asn1.RawValue
+	PublicKey          publicKeyInfo
+	UniqueId           asn1.BitString   `asn1:"optional,tag:1"`
+	SubjectUniqueId    asn1.BitString   `asn1:"optional,tag:2"`
+	Extensions         []pkix.Extension `asn1:"optional,explicit,tag:3"`
+}
+
+type validity struct {
+	NotBefore, NotAfter time.Time
+}
+
+// ParseCertificate parses certificate with SM2 public key. Go standard library rejects such certificates, so
+// only fields needed by gohfc are filled: raw encodings, serial number, subject, issuer, validity, extensions,
+// basic constraints, key usage, key identifiers, subject alternative names, signature and PublicKey which is
+// *PublicKey. Certificate signature is not verified, use CheckSignatureFrom or VerifyChain.
+func ParseCertificate(der []byte) (*x509.Certificate, error) {
+	var c certificate
+	rest, err := asn1.Unmarshal(der, &c)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("sm2: trailing data after certificate")
+	}
+	tbs := &c.TBSCertificate
+	pub, err := parsePublicKeyInfo(&tbs.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	cert := &x509.Certificate{
+		Raw:                     der,
+		RawTBSCertificate:       tbs.Raw,
+		RawSubjectPublicKeyInfo: tbs.PublicKey.Raw,
+		RawSubject:              tbs.Subject.FullBytes,
+		RawIssuer:               tbs.Issuer.FullBytes,
+		Signature:               c.SignatureValue.RightAlign(),
+		PublicKeyAlgorithm:      x509.ECDSA,
+		PublicKey:               pub,
+		Version:                 tbs.Version + 1,
+		SerialNumber:            tbs.SerialNumber,
+		NotBefore:               tbs.Validity.NotBefore,
+		NotAfter:                tbs.Validity.NotAfter,
+		Extensions:              tbs.Extensions,
+	}
+	var subject, issuer pkix.RDNSequence
+	if _, err := asn1.Unmarshal(tbs.Subject.FullBytes, &subject); err != nil {
+		return nil, err
+	}
+	if _, err := asn1.Unmarshal(tbs.Issuer.FullBytes, &issuer); err != nil {
+		return nil, err
+	}
+	cert.Subject.FillFromRDNSequence(&subject)
+	cert.Issuer.FillFromRDNSequence(&issuer)
+	if err := parseExtensions(cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+type basicConstraints struct {
+	IsCA       bool `asn1:"optional"`
+	MaxPathLen int  `asn1:"optional,default:-1"`
+}
+
+type authorityKeyId struct {
+	Id []byte `asn1:"optional,tag:0"`
+}
+
+// parseExtensions fills fields of cert from extensions used in certificate chain verification and TLS
+func parseExtensions(cert *x509.Certificate) error {
+	for _, e := range cert.Extensions {
+		switch {
+		case e.Id.Equal(oidExtensionBasicConstraints):
+			var c basicConstraints
+			if _, err := asn1.Unmarshal(e.Value, &c); err != nil {
+				return err
+			}
+			cert.BasicConstraintsValid = true
+			cert.IsCA = c.IsCA
+			cert.MaxPathLen = c.MaxPathLen
+			cert.MaxPathLenZero = c.MaxPathLen == 0
+		case e.Id.Equal(oidExtensionKeyUsage):
+			var usage asn1.BitString
+			if _, err := asn1.Unmarshal(e.Value, &usage); err != nil {
+				return err
+			}
+			var u int
+			for i := 0; i < 9; i++ {
+				if usage.At(i) != 0 {
+					u |= 1 << uint(i)
+				}
+			}
+			cert.KeyUsage = x509.KeyUsage(u)
+		case e.Id.Equal(oidExtensionSubjectKeyId):
+			if _, err := asn1.Unmarshal(e.Value, &cert.SubjectKeyId); err != nil {
+				return err
+			}
+		case e.Id.Equal(oidExtensionAuthorityKeyId):
+			var a authorityKeyId
+			if _, err := asn1.Unmarshal(e.Value, &a); err != nil {
+				return err
+			}
+			cert.AuthorityKeyId = a.Id
+		case e.Id.Equal(oidExtensionSubjectAlt):
+			var names []asn1.RawValue
+			if _, err := asn1.Unmarshal(e.Value, &names); err != nil {
+				return err
+			}
+			for _, n := range names {
+				switch n.Tag {
+				case 1:
+					cert.EmailAddresses = append(cert.EmailAddresses, string(n.Bytes))
+				case 2:
+					cert.DNSNames = append(cert.DNSNames, string(n.Bytes))
+				case 7:
+					if len(n.Bytes) == net.IPv4len || len(n.Bytes) == net.IPv6len {
+						cert.IPAddresses = append(cert.IPAddresses, net.IP(n.Bytes))
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// IsSM2Signed returns true when certificate is signed with SM2-with-SM3
+func IsSM2Signed(cert *x509.Certificate) bool {
+	var c certificate
+	if _, err := asn1.Unmarshal(cert.Raw, &c); err != nil {
+		return false
+	}
+	return c.SignatureAlgorithm.Algorithm.Equal(OIDSignatureSM2WithSM3)
+}
+
+// CheckSignatureFrom verifies that cert is signed with SM2-with-SM3 by parent, which must be CA certificate with
+// SM2 public key allowed to sign certificates
+func CheckSignatureFrom(cert, parent *x509.Certificate) error {
+	if !IsSM2Signed(cert) {
+		return ErrNotSM2Signature
+	}
+	if parent.Version == 3 && (!parent.BasicConstraintsValid || !parent.IsCA) {
+		return errors.New("sm2: parent certificate is not CA certificate")
+	}
+	if parent.KeyUsage != 0 && parent.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return errors.New("sm2: parent certificate is not allowed to sign certificates")
+	}
+	pub, ok := parent.PublicKey.(*PublicKey)
+	if !ok {
+		return ErrNotSM2Key
+	}
+	if !pub.Verify(cert.RawTBSCertificate, cert.Signature) {
+		return errors.New("sm2: certificate signature is invalid")
+	}
+	return nil
+}
+
+// VerifyChain verifies that cert is valid at now and is signed by one of roots, directly or through
+// intermediates. All certificates in chain must be signed with SM2-with-SM3 and be valid at now.
+func VerifyChain(cert *x509.Certificate, roots, intermediates []*x509.Certificate, now time.Time) error {
+	return verifyChain(cert, roots, intermediates, now, 0)
+}
+
+// checkParent checks signature of cert and path length constraint of parent, depth is number of CA certificates
+// between cert and leaf
+func checkParent(cert, parent *x509.Certificate, depth int) error {
+	if err := CheckSignatureFrom(cert, parent); err != nil {
+		return err
+	}
+	if parent.MaxPathLenZero && depth > 0 || parent.MaxPathLen > 0 && depth > parent.MaxPathLen {
+		return errors.New("sm2: path length constraint exceeded")
+	}
+	return nil
+}
+
+func verifyChain(cert *x509.Certificate, roots, intermediates []*x509.Certificate, now time.Time, depth int) error {
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return fmt.Errorf("sm2: certificate %s is not valid at %s", cert.Subject.CommonName, now.Format(time.RFC3339))
+	}
+	for _, root := range roots {
+		if bytes.Equal(root.Raw, cert.Raw) {
+			return nil
+		}
+	}
+	if depth >= maxChainLength {
+		return ErrUnknownAuthority
+	}
+	var lastErr error = ErrUnknownAuthority
+	for _, root := range roots {
+		if !bytes.Equal(root.RawSubject, cert.RawIssuer) {
+			continue
+		}
+		if err := checkParent(cert, root, depth); err != nil {
+			lastErr = err
+			continue
+		}
+		if now.Before(root.NotBefore) || now.After(root.NotAfter) {
+			lastErr = fmt.Errorf("sm2: root certificate %s is not valid at %s", root.Subject.CommonName,
+				now.Format(time.RFC3339))
+			continue
+		}
+		return nil
+	}
+	for _, parent := range intermediates {
+		if !bytes.Equal(parent.RawSubject, cert.RawIssuer) || bytes.Equal(parent.Raw, cert.Raw) {
+			continue
+		}
+		if err := checkParent(cert, parent, depth); err != nil {
+			lastErr = err
+			continue
+		}
+		err := verifyChain(parent, roots, intermediates, now, depth+1)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+	}
+	return lastErr
+}
+
+type certificateRequestInfo struct {
+	Version    int
+	Subject    asn1.RawValue
+	PublicKey  asn1.RawValue
+	Attributes []attribute `asn1:"tag:0"`
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values [][]pkix.Extension `asn1:"set"`
+}
+
+type certificateRequest struct {
+	Info               asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+}
+
+// CreateCertificateRequest creates DER encoded PKCS #10 request signed with SM2-with-SM3. Subject (or RawSubject),
+// DNSNames, EmailAddresses and IPAddresses are taken from template.
+func CreateCertificateRequest(rand io.Reader, template *x509.CertificateRequest, key *PrivateKey) ([]byte, error) {
+	subject := template.RawSubject
+	if len(subject) == 0 {
+		var err error
+		if subject, err = asn1.Marshal(template.Subject.ToRDNSequence()); err != nil {
+			return nil, err
+		}
+	}
+	pub, err := MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	attributes := []attribute{}
+	var names []asn1.RawValue
+	for _, n := range template.EmailAddresses {
+		names = append(names, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, Bytes: []byte(n)})
+	}
+	for _, n := range template.DNSNames {
+		names = append(names, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, Bytes: []byte(n)})
+	}
+	for _, ip := range template.IPAddresses {
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		names = append(names, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 7, Bytes: ip})
+	}
+	if len(names) > 0 {
+		san, err := asn1.Marshal(names)
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, attribute{
+			Type:   oidExtensionRequest,
+			Values: [][]pkix.Extension{{{Id: oidExtensionSubjectAlt, Value: san}}},
+		})
+	}
+	info, err := asn1.Marshal(certificateRequestInfo{
+		Subject:    asn1.RawValue{FullBytes: subject},
+		PublicKey:  asn1.RawValue{FullBytes: pub},
+		Attributes: attributes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	r, s, err := Sign(rand, key, DefaultUID, info)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := asn1.Marshal(sm2Signature{r, s})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(certificateRequest{
+		Info:               asn1.RawValue{FullBytes: info},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: OIDSignatureSM2WithSM3},
+		Signature:          asn1.BitString{Bytes: sig, BitLength: len(sig) * 8},
+	})
+}
@@ -0,0 +1,198 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gm
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"testing"
+	"time"
+)
+
+// Certificates generated with OpenSSL 3.0, signed with SM2-with-SM3 and default user id. rootPEM signs interPEM,
+// which has path length 0 and signs leafPEM and subPEM. subPEM is CA certificate that signs deepPEM.
+const (
+	rootPEM = `-----BEGIN CERTIFICATE-----
+MIIBuDCCAV6gAwIBAgIUUiZtDtj4cCmo/5nUCUgiigTilGYwCgYIKoEcz1UBg3Uw
+OTEZMBcGA1UECgwQb3JnMS5leGFtcGxlLmNvbTEcMBoGA1UEAwwTY2Eub3JnMS5l
+eGFtcGxlLmNvbTAgFw0yNjEwMTUxMzQ5NTZaGA8yMTI2MDkyMTEzNDk1NlowOTEZ
+MBcGA1UECgwQb3JnMS5leGFtcGxlLmNvbTEcMBoGA1UEAwwTY2Eub3JnMS5leGFt
+cGxlLmNvbTBZMBMGByqGSM49AgEGCCqBHM9VAYItA0IABMVbDoxPH/DZoOE2elEV
+z3qKPTYvA4pJao4ZMOSDoVIn24l0no+97l9fFHgd68RSZ9ZFrzIWg6Ldq6+WQ2TB
+G7ijQjBAMA8GA1UdEwEB/wQFMAMBAf8wDgYDVR0PAQH/BAQDAgEGMB0GA1UdDgQW
+BBQCweFJwwKOKT40X5th5/7y/HNTRTAKBggqgRzPVQGDdQNIADBFAiEA83Gzvvba
+xEaYMAUdD4+i8zcXcSHx8BEBC5UVEXEMrEwCICO+BBQThaPWqZJEzBkX4yJSEfXQ
+mSeSreleo537SV1n
+-----END CERTIFICATE-----
+`
+	interPEM = `-----BEGIN CERTIFICATE-----
+MIIB3DCCAYOgAwIBAgIUWHbLg1mReVyZ2YsdXkek1SaB71QwCgYIKoEcz1UBg3Uw
+OTEZMBcGA1UECgwQb3JnMS5leGFtcGxlLmNvbTEcMBoGA1UEAwwTY2Eub3JnMS5l
+eGFtcGxlLmNvbTAgFw0yNjEwMTUxMzU2NTFaGA8yMTI2MDkyMTEzNTY1MVowOjEZ
+MBcGA1UECgwQb3JnMS5leGFtcGxlLmNvbTEdMBsGA1UEAwwUaWNhLm9yZzEuZXhh
+bXBsZS5jb20wWTATBgcqhkjOPQIBBggqgRzPVQGCLQNCAATwfTXjq9mfSq/QfQXf
+YzG/hX7+FBMaEcF+WI6ghjltxSHCodnggFja3Ls5zpckg2s2v+sSGLuHKS+FFmfn
+uyH6o2YwZDASBgNVHRMBAf8ECDAGAQH/AgEAMA4GA1UdDwEB/wQEAwIBBjAdBgNV
+HQ4EFgQURQ5y98S5i13cZRSOQ5qlcizoeeIwHwYDVR0jBBgwFoAUAsHhScMCjik+
+NF+bYef+8vxzU0UwCgYIKoEcz1UBg3UDRwAwRAIgMe0EILpsbbIlvaWoT3wmR/W0
+2g3JdLGGbEVIaQ9nOLECIE0tqtzHM1KRFPSf6SeptSxQh6TrhIZPv09ilNNS3AS2
+-----END CERTIFICATE-----
+`
+	leafPEM = `-----BEGIN CERTIFICATE-----
+MIICBTCCAaugAwIBAgIURa7FBvpjqjwWcm6rvB1O92u1LGowCgYIKoEcz1UBg3Uw
+OjEZMBcGA1UECgwQb3JnMS5leGFtcGxlLmNvbTEdMBsGA1UEAwwUaWNhLm9yZzEu
+ZXhhbXBsZS5jb20wIBcNMjYxMDE1MTM1NjUxWhgPMjEyNjA5MjExMzU2NTFaMDwx
+GTAXBgNVBAoMEG9yZzEuZXhhbXBsZS5jb20xHzAdBgNVBAMMFnBlZXIwLm9yZzEu
+ZXhhbXBsZS5jb20wWTATBgcqhkjOPQIBBggqgRzPVQGCLQNCAAQWYxi08RNSiIoM
+TX1eVqaBJYT9XlzyQC1rnaS/uEc+b/SAnY3ufIIWayzJUfdu+Vd7TjqhQdQX1/Ef
+h48pjzSXo4GKMIGHMAwGA1UdEwEB/wQCMAAwDgYDVR0PAQH/BAQDAgeAMCcGA1Ud
+EQQgMB6CFnBlZXIwLm9yZzEuZXhhbXBsZS5jb22HBH8AAAEwHwYDVR0jBBgwFoAU
+RQ5y98S5i13cZRSOQ5qlcizoeeIwHQYDVR0OBBYEFPLtqq4QfOlcKBKQnYX4A2S9
++BYFMAoGCCqBHM9VAYN1A0gAMEUCIQD1L/UXMrRbuuc++IK7uo3kDl3bOnmzs44z
+Bx37RpDxYgIgP4OyeUfdUIfkoRuitnSaUN7rRgsUKlR+ukhLyL2qwus=
+-----END CERTIFICATE-----
+`
+	subPEM = `-----BEGIN CERTIFICATE-----
+MIIB2zCCAYGgAwIBAgIURa7FBvpjqjwWcm6rvB1O92u1LGswCgYIKoEcz1UBg3Uw
+OjEZMBcGA1UECgwQb3JnMS5leGFtcGxlLmNvbTEdMBsGA1UEAwwUaWNhLm9yZzEu
+ZXhhbXBsZS5jb20wIBcNMjYxMDE1MTM1NjUxWhgPMjEyNjA5MjExMzU2NTFaMDox
+GTAXBgNVBAoMEG9yZzEuZXhhbXBsZS5jb20xHTAbBgNVBAMMFHN1Yi5vcmcxLmV4
+YW1wbGUuY29tMFkwEwYHKoZIzj0CAQYIKoEcz1UBgi0DQgAEHiRDotvU2u4s5nkS
+tjgOvKEyGlSzxs65aINWO1socMm/JuX032/dueulWJqlejdMB07UHoTfhzmFZZnW
+WEMqMqNjMGEwDwYDVR0TAQH/BAUwAwEB/zAOBgNVHQ8BAf8EBAMCAQYwHQYDVR0O
+BBYEFM3FB9o/asf9w+wzlIIkkSQnITCQMB8GA1UdIwQYMBaAFEUOcvfEuYtd3GUU
+jkOapXIs6HniMAoGCCqBHM9VAYN1A0gAMEUCIQCXQESVRMSwoAY5Dx6Ug454Yg6q
+O4kabAr61tc5tGOwlgIgHbIhldyzqF2BrCH/124Ksu5FY0cJbZeoANjdX5ZBfmI=
+-----END CERTIFICATE-----
+`
+	deepPEM = `-----BEGIN CERTIFICATE-----
+MIICBjCCAaugAwIBAgIUIRxqS8b0t+sjk3GhIDRGmc5OgUgwCgYIKoEcz1UBg3Uw
+OjEZMBcGA1UECgwQb3JnMS5leGFtcGxlLmNvbTEdMBsGA1UEAwwUc3ViLm9yZzEu
+ZXhhbXBsZS5jb20wIBcNMjYxMDE1MTM1NjUyWhgPMjEyNjA5MjExMzU2NTJaMDwx
+GTAXBgNVBAoMEG9yZzEuZXhhbXBsZS5jb20xHzAdBgNVBAMMFnBlZXIwLm9yZzEu
+ZXhhbXBsZS5jb20wWTATBgcqhkjOPQIBBggqgRzPVQGCLQNCAAQWYxi08RNSiIoM
+TX1eVqaBJYT9XlzyQC1rnaS/uEc+b/SAnY3ufIIWayzJUfdu+Vd7TjqhQdQX1/Ef
+h48pjzSXo4GKMIGHMAwGA1UdEwEB/wQCMAAwDgYDVR0PAQH/BAQDAgeAMCcGA1Ud
+EQQgMB6CFnBlZXIwLm9yZzEuZXhhbXBsZS5jb22HBH8AAAEwHwYDVR0jBBgwFoAU
+zcUH2j9qx/3D7DOUgiSRJCchMJAwHQYDVR0OBBYEFPLtqq4QfOlcKBKQnYX4A2S9
++BYFMAoGCCqBHM9VAYN1A0kAMEYCIQCEXBdPsX+k3H9qtU3oi4z2To6bvNB9JDmr
+cDqNnEsosAIhAJ+EkO609oKETkbpYHkEBsAU2XePqi2d/R6ElcxBEpkf
+-----END CERTIFICATE-----
+`
+)
+
+func mustParseCertificate(t *testing.T, s string) *x509.Certificate {
+	t.Helper()
+	b, _ := pem.Decode([]byte(s))
+	if b == nil {
+		t.Fatal("invalid PEM")
+	}
+	cert, err := ParseCertificate(b.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestParseCertificateExtensions(t *testing.T) {
+	root := mustParseCertificate(t, rootPEM)
+	if !root.BasicConstraintsValid || !root.IsCA || root.KeyUsage&x509.KeyUsageCertSign == 0 || len(root.SubjectKeyId) == 0 {
+		t.Fatalf("unexpected root extensions: %+v", root)
+	}
+	inter := mustParseCertificate(t, interPEM)
+	if !inter.IsCA || !inter.MaxPathLenZero {
+		t.Fatalf("expected path length 0, got %d", inter.MaxPathLen)
+	}
+	leaf := mustParseCertificate(t, leafPEM)
+	if leaf.IsCA || leaf.KeyUsage != x509.KeyUsageDigitalSignature {
+		t.Fatalf("unexpected leaf extensions: %+v", leaf)
+	}
+	if len(leaf.DNSNames) != 1 || leaf.DNSNames[0] != "peer0.org1.example.com" {
+		t.Fatalf("unexpected DNS names %v", leaf.DNSNames)
+	}
+	if len(leaf.IPAddresses) != 1 || !leaf.IPAddresses[0].Equal(net.ParseIP("127.0.0.1")) {
+		t.Fatalf("unexpected IP addresses %v", leaf.IPAddresses)
+	}
+	if string(leaf.AuthorityKeyId) != string(inter.SubjectKeyId) {
+		t.Fatal("authority key id of leaf is not subject key id of intermediate")
+	}
+	if !IsSM2Signed(leaf) {
+		t.Fatal("leaf is not SM2 signed")
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	root := mustParseCertificate(t, rootPEM)
+	inter := mustParseCertificate(t, interPEM)
+	leaf := mustParseCertificate(t, leafPEM)
+	roots := []*x509.Certificate{root}
+	intermediates := []*x509.Certificate{inter}
+	now := time.Now()
+
+	if err := VerifyChain(leaf, roots, intermediates, now); err != nil {
+		t.Fatalf("valid chain: %v", err)
+	}
+	if err := VerifyChain(root, roots, nil, now); err != nil {
+		t.Fatalf("root: %v", err)
+	}
+	if err := VerifyChain(leaf, roots, nil, now); err != ErrUnknownAuthority {
+		t.Fatalf("missing intermediate: expected ErrUnknownAuthority, got %v", err)
+	}
+	if err := VerifyChain(leaf, []*x509.Certificate{inter}, nil, now); err != nil {
+		t.Fatalf("intermediate as root: %v", err)
+	}
+	if err := VerifyChain(leaf, []*x509.Certificate{mustParseCertificate(t, subPEM)}, intermediates, now); err == nil {
+		t.Fatal("chain to untrusted root verified")
+	}
+	if err := VerifyChain(leaf, roots, intermediates, leaf.NotAfter.Add(time.Hour)); err == nil {
+		t.Fatal("expired certificate verified")
+	}
+	if err := VerifyChain(leaf, roots, intermediates, leaf.NotBefore.Add(-time.Hour)); err == nil {
+		t.Fatal("not yet valid certificate verified")
+	}
+}
+
+func TestVerifyChainTamperedSignature(t *testing.T) {
+	root := mustParseCertificate(t, rootPEM)
+	inter := mustParseCertificate(t, interPEM)
+	b, _ := pem.Decode([]byte(leafPEM))
+	der := append([]byte(nil), b.Bytes...)
+	// last byte is part of s of signature
+	der[len(der)-1] ^= 1
+	leaf, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckSignatureFrom(leaf, inter); err == nil {
+		t.Fatal("tampered signature verified")
+	}
+	if err := VerifyChain(leaf, []*x509.Certificate{root}, []*x509.Certificate{inter}, time.Now()); err == nil {
+		t.Fatal("chain with tampered signature verified")
+	}
+}
+
+func TestVerifyChainPathLength(t *testing.T) {
+	roots := []*x509.Certificate{mustParseCertificate(t, rootPEM)}
+	inter := mustParseCertificate(t, interPEM)
+	sub := mustParseCertificate(t, subPEM)
+	deep := mustParseCertificate(t, deepPEM)
+	if err := CheckSignatureFrom(deep, sub); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChain(sub, roots, []*x509.Certificate{inter}, time.Now()); err != nil {
+		t.Fatalf("CA certificate directly under intermediate: %v", err)
+	}
+	if err := VerifyChain(deep, roots, []*x509.Certificate{inter, sub}, time.Now()); err == nil {
+		t.Fatal("path length constraint of intermediate not enforced")
+	}
+}
+
+func TestCheckSignatureFromNotCA(t *testing.T) {
+	leaf := mustParseCertificate(t, leafPEM)
+	if err := CheckSignatureFrom(mustParseCertificate(t, deepPEM), leaf); err == nil {
+		t.Fatal("certificate signed by end entity certificate verified")
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+
+	"github.com/CognitionFoundry/gohfc/gm"
+)
+
+// GMCryptSuite implements GM crypto suite: SM2 signatures and SM3 hash. It is selected with family: gm in config.
+// When KeyManager is set generated keys are persisted in its key store.
+type GMCryptSuite struct {
+	KeyManager *gm.KeyManager
+}
+
+// NewGMCryptSuiteFromConfig creates new GM crypto suite from config. Algorithm must be SM2 and hash SM3, empty
+// values select these defaults.
+func NewGMCryptSuiteFromConfig(config CryptoConfig) (CryptoSuite, error) {
+	if config.Algorithm != "" && config.Algorithm != "SM2" {
+		return nil, ErrInvalidAlgorithm
+	}
+	if config.Hash != "" && config.Hash != "SM3" {
+		return nil, ErrInvalidHash
+	}
+	suite := &GMCryptSuite{}
+	if config.KeyStore != "" {
+		ks, err := gm.NewFileKeyStore(config.KeyStore, false)
+		if err != nil {
+			return nil, err
+		}
+		suite.KeyManager = gm.NewKeyManager(ks)
+	}
+	return suite, nil
+}
+
+func (c *GMCryptSuite) GenerateKey() (interface{}, error) {
+	if c.KeyManager == nil {
+		return gm.GenerateKey(rand.Reader)
+	}
+	k, err := c.KeyManager.KeyGen(&gm.SM2KeyGenOpts{})
+	if err != nil {
+		return nil, err
+	}
+	return k.(*gm.SM2PrivateKey).Key, nil
+}
+
+func (c *GMCryptSuite) CreateCertificateRequest(enrollmentId string, key interface{}, hosts []string) ([]byte, error) {
+	if enrollmentId == "" {
+		return nil, ErrEnrollmentIdMissing
+	}
+	k, ok := key.(*gm.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidKeyType
+	}
+	ipAddr, emailAddr, dnsAddr := splitHosts(hosts)
+	template := x509.CertificateRequest{
+		Subject:        pkix.Name{CommonName: enrollmentId},
+		IPAddresses:    ipAddr,
+		EmailAddresses: emailAddr,
+		DNSNames:       dnsAddr,
+	}
+	csrBytes, err := gm.CreateCertificateRequest(rand.Reader, &template, k)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrBytes}), nil
+}
+
+// Sign signs msg with SM2 private key. SM3 digest of msg and signer public key is computed by SM2 itself.
+func (c *GMCryptSuite) Sign(msg []byte, k interface{}) ([]byte, error) {
+	key, ok := k.(*gm.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidKeyType
+	}
+	r, s, err := gm.Sign(rand.Reader, key, gm.DefaultUID, msg)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(eCDSASignature{r, s})
+}
+
+// Verify verifies SM2 signature over msg using default user id
+func (c *GMCryptSuite) Verify(msg, signature []byte, key interface{}) (bool, error) {
+	return verifySignature(key, msg, signature, gm.NewSM3)
+}
+
+func (c *GMCryptSuite) Hash(data []byte) []byte {
+	sum := gm.SumSM3(data)
+	return sum[:]
+}
+
+// parseCertificate parses DER certificate. Certificates with SM2 keys are not supported by standard library and
+// are parsed by gm package.
+func parseCertificate(der []byte) (*x509.Certificate, error) {
+	cert, err := x509.ParseCertificate(der)
+	if err == nil {
+		return cert, nil
+	}
+	if gmCert, gmErr := gm.ParseCertificate(der); gmErr == nil {
+		return gmCert, nil
+	}
+	return nil, err
+}
+
+// parsePKCS8PrivateKey parses PKCS #8 private key, including SM2 private keys
+func parsePKCS8PrivateKey(der []byte) (interface{}, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err == nil {
+		return key, nil
+	}
+	if gmKey, gmErr := gm.ParsePKCS8PrivateKey(der); gmErr == nil {
+		return gmKey, nil
+	}
+	return nil, err
+}
+
+// parseECPrivateKey parses SEC 1 private key, including SM2 private keys
+func parseECPrivateKey(der []byte) (interface{}, error) {
+	key, err := x509.ParseECPrivateKey(der)
+	if err == nil {
+		return key, nil
+	}
+	if gmKey, gmErr := gm.ParseECPrivateKey(der); gmErr == nil {
+		return gmKey, nil
+	}
+	return nil, err
+}
@@ -0,0 +1,165 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"sync"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// TLS types that can be set in PeerConfig.TlsType and OrdererConfig.TlsType
+const (
+	// TlsTypeStandard is standard TLS. It is used when TlsType is empty.
+	TlsTypeStandard = "tls"
+	// TlsTypeGM is GM dual certificate TLS (GM/T 0024) with SM2 certificates, used by national crypto Fabric
+	// distributions.
+	TlsTypeGM = "gmtls"
+)
+
+// GmTlsConfig holds client certificates for GM TLS. GM TLS uses separate certificates for signing and key exchange.
+// Client certificates are needed only when server requires client authentication.
+type GmTlsConfig struct {
+	SignCertPath string `yaml:"signCertPath"`
+	SignKeyPath  string `yaml:"signKeyPath"`
+	EncCertPath  string `yaml:"encCertPath"`
+	EncKeyPath   string `yaml:"encKeyPath"`
+}
+
+// TransportCredentialsFactory creates gRPC transport credentials. rootCerts are PEM encoded root certificates
+// from TlsCert or TlsPath.
+type TransportCredentialsFactory func(rootCerts []byte, gm GmTlsConfig) (credentials.TransportCredentials, error)
+
+var (
+	transportMu        sync.RWMutex
+	transportFactories = make(map[string]TransportCredentialsFactory)
+)
+
+// RegisterTransportCredentials registers factory for TLS type. Go standard library does not implement GM TLS,
+// so to connect to nodes that offer only SM2 based TLS register factory backed by GM TLS implementation
+// (for example github.com/tjfoc/gmsm/gmtls) under TlsTypeGM, and set tlsType: gmtls in peer and orderer config:
+//
+//	gohfc.RegisterTransportCredentials(gohfc.TlsTypeGM, func(root []byte, gm gohfc.GmTlsConfig) (credentials.TransportCredentials, error) {
+//		pool := x509.NewCertPool()
+//		pool.AppendCertsFromPEM(root)
+//		return gmcredentials.NewTLS(&gmtls.Config{GMSupport: &gmtls.GMSupport{}, RootCAs: pool}), nil
+//	})
+//
+// Factory must be registered before client is created. Factories are shared by all clients in process.
+func RegisterTransportCredentials(tlsType string, factory TransportCredentialsFactory) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	transportFactories[tlsType] = factory
+}
+
+// nodeTransportCredentials creates credentials for peer or orderer according to TLS type. Standard TLS presents
+// client certificate of node, other types present certificates from gm. Returned clientTls holds hash of certificate
+// presented on connections.
+func nodeTransportCredentials(tlsType, pemCert, path string, gm GmTlsConfig,
+	node nodeTls) (credentials.TransportCredentials, *clientTls, error) {
+	if tlsType == "" || tlsType == TlsTypeStandard {
+		creds, err := transportCredentials(pemCert, path, node)
+		return creds, node.client, err
+	}
+	transportMu.RLock()
+	factory, ok := transportFactories[tlsType]
+	transportMu.RUnlock()
+	if !ok {
+		return nil, nil, ErrTlsTypeNotRegistered
+	}
+	root := []byte(pemCert)
+	if pemCert == "" {
+		var err error
+		if root, err = ioutil.ReadFile(path); err != nil {
+			return nil, nil, err
+		}
+	}
+	binding, err := gm.clientTls()
+	if err != nil {
+		return nil, nil, err
+	}
+	creds, err := factory(root, gm)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &expiryCheckedCredentials{TransportCredentials: creds, node: node}, binding, nil
+}
+
+// expiryCheckedCredentials checks expiry of certificates presented by nodes to credentials of other TLS types
+type expiryCheckedCredentials struct {
+	credentials.TransportCredentials
+	node nodeTls
+}
+
+func (c *expiryCheckedCredentials) ClientHandshake(ctx context.Context, authority string,
+	rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	conn, info, err := c.TransportCredentials.ClientHandshake(ctx, authority, rawConn)
+	if err != nil {
+		return conn, info, err
+	}
+	if cert := peerCertificate(info); cert != nil {
+		c.node.client.checkNodeTlsExpiry(c.node.name, cert)
+	}
+	return conn, info, nil
+}
+
+func (c *expiryCheckedCredentials) Clone() credentials.TransportCredentials {
+	return &expiryCheckedCredentials{TransportCredentials: c.TransportCredentials.Clone(), node: c.node}
+}
+
+// peerCertificate returns certificate of node from auth info of TLS implementations that keep connection state
+// like crypto/tls in State field, for example gmcredentials.TLSInfo. Nil is returned for other auth info.
+func peerCertificate(info credentials.AuthInfo) *x509.Certificate {
+	v := reflect.Indirect(reflect.ValueOf(info))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	state := reflect.Indirect(v.FieldByName("State"))
+	if state.Kind() != reflect.Struct {
+		return nil
+	}
+	certs := state.FieldByName("PeerCertificates")
+	if certs.Kind() != reflect.Slice || certs.Len() == 0 {
+		return nil
+	}
+	leaf := reflect.Indirect(certs.Index(0))
+	if leaf.Kind() != reflect.Struct {
+		return nil
+	}
+	raw := leaf.FieldByName("Raw")
+	if raw.Kind() != reflect.Slice || raw.Type().Elem().Kind() != reflect.Uint8 {
+		return nil
+	}
+	cert, err := parseCertificate(raw.Bytes())
+	if err != nil {
+		return nil
+	}
+	return cert
+}
+
+// clientTls returns binding to sign certificate, which GM TLS client presents as its certificate
+func (gm GmTlsConfig) clientTls() (*clientTls, error) {
+	binding := new(clientTls)
+	if gm.SignCertPath == "" {
+		return binding, nil
+	}
+	data, err := ioutil.ReadFile(gm.SignCertPath)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("cannot parse PEM encoded GM TLS sign certificate")
+	}
+	binding.hash = ComputeTlsCertHash(block.Bytes)
+	return binding, nil
+}
@@ -0,0 +1,49 @@
+//go:build gmtls
+// +build gmtls
+
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"errors"
+
+	"github.com/tjfoc/gmsm/gmtls"
+	"github.com/tjfoc/gmsm/gmtls/gmcredentials"
+	"github.com/tjfoc/gmsm/x509"
+	"google.golang.org/grpc/credentials"
+)
+
+// Building with gmtls tag registers GM TLS implementation from github.com/tjfoc/gmsm under TlsTypeGM, so peers and
+// orderers with tlsType: gmtls can be used without calling RegisterTransportCredentials.
+func init() {
+	RegisterTransportCredentials(TlsTypeGM, NewGmTransportCredentials)
+}
+
+// NewGmTransportCredentials creates GM TLS (GM/T 0024) credentials. Server certificate chain is verified against
+// SM2 rootCerts. When client certificates are set in gm, both sign and enc pairs must be provided, they are offered
+// to servers that require client authentication.
+func NewGmTransportCredentials(rootCerts []byte, gm GmTlsConfig) (credentials.TransportCredentials, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(rootCerts) {
+		return nil, errors.New("cannot parse PEM encoded GM TLS root certificate")
+	}
+	config := &gmtls.Config{GMSupport: &gmtls.GMSupport{}, RootCAs: pool}
+	if gm.SignCertPath != "" || gm.EncCertPath != "" {
+		if gm.SignCertPath == "" || gm.SignKeyPath == "" || gm.EncCertPath == "" || gm.EncKeyPath == "" {
+			return nil, errors.New("GM TLS client authentication requires sign and enc certificates and keys")
+		}
+		signCert, err := gmtls.LoadX509KeyPair(gm.SignCertPath, gm.SignKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		encCert, err := gmtls.LoadX509KeyPair(gm.EncCertPath, gm.EncKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []gmtls.Certificate{signCert, encCert}
+	}
+	return gmcredentials.NewTLS(config), nil
+}
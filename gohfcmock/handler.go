@@ -0,0 +1,422 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+
+// Package gohfcmock implements gohfc.Handler with programmable responses, so applications built on gohfc can be
+// unit tested without running Fabric network.
+package gohfcmock
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/CognitionFoundry/gohfc"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+// ErrNoResponse is returned from Query and Invoke when no response was programmed for chaincode function
+var ErrNoResponse = errors.New("gohfcmock: no response programmed")
+
+// Call is a request made to Handler
+type Call struct {
+	Method    string
+	ChannelId string
+	ChainCode string
+	// Function is the first argument of chaincode, Args are the remaining arguments
+	Function string
+	Args     []string
+	Peers    []string
+	Orderer  string
+}
+
+// Response is programmed response for matching calls, it is configured using its methods
+type Response struct {
+	method    string
+	chainCode string
+	function  string
+	payload   []byte
+	status    int32
+	message   string
+	err       error
+	// times is how many more calls response matches, 0 means unlimited
+	times int
+}
+
+// Return sets payload returned by chaincode
+func (r *Response) Return(payload []byte) *Response {
+	r.payload = payload
+	return r
+}
+
+// ReturnJSON sets payload returned by chaincode to v encoded as JSON
+func (r *Response) ReturnJSON(v interface{}) *Response {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		r.err = fmt.Errorf("gohfcmock: cannot encode response: %v", err)
+		return r
+	}
+	return r.Return(payload)
+}
+
+// ReturnStatus sets status and message returned by chaincode, for example 500 when chaincode returns shim.Error.
+// Queries get response with this status from every peer, invokes fail with gohfc.EndorsementError.
+func (r *Response) ReturnStatus(status int32, message string) *Response {
+	r.status = status
+	r.message = message
+	return r
+}
+
+// ReturnError sets error returned by Handler method
+func (r *Response) ReturnError(err error) *Response {
+	r.err = err
+	return r
+}
+
+// Times limits number of calls that get this response. After that next matching response is used.
+func (r *Response) Times(n int) *Response {
+	r.times = n
+	return r
+}
+
+type listener struct {
+	ctx       context.Context
+	channelId string
+	events    chan<- gohfc.EventBlockResponse
+	blocks    chan<- gohfc.BlockResponse
+}
+
+// Handler implements gohfc.Handler. Query and Invoke responses must be programmed with OnQuery and OnInvoke,
+// channel and chaincode management methods succeed unless response is programmed with On.
+// Blocks are send to listeners using SendEvent and SendBlock. All calls are recorded.
+// Zero value is ready to use and Handler is safe for concurrent use.
+type Handler struct {
+	mu        sync.Mutex
+	responses []*Response
+	calls     []Call
+	listeners []*listener
+	txCount   int
+}
+
+var _ gohfc.Handler = (*Handler)(nil)
+
+// New creates new Handler
+func New() *Handler {
+	return new(Handler)
+}
+
+// OnQuery programs response for queries of chaincode function. Empty function matches all functions.
+func (h *Handler) OnQuery(chainCode, function string) *Response {
+	return h.On("Query", chainCode, function)
+}
+
+// OnInvoke programs response for invokes of chaincode function. Empty function matches all functions.
+func (h *Handler) OnInvoke(chainCode, function string) *Response {
+	return h.On("Invoke", chainCode, function)
+}
+
+// On programs response for method like "Query", "Invoke" or "InstallChainCode". Empty chainCode or function
+// matches all. Responses are matched in order they were programmed.
+func (h *Handler) On(method, chainCode, function string) *Response {
+	r := &Response{method: method, chainCode: chainCode, function: function, status: 200}
+	h.mu.Lock()
+	h.responses = append(h.responses, r)
+	h.mu.Unlock()
+	return r
+}
+
+// Calls returns all calls made so far
+func (h *Handler) Calls() []Call {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Call(nil), h.calls...)
+}
+
+// CallsOf returns calls of method
+func (h *Handler) CallsOf(method string) []Call {
+	var calls []Call
+	for _, c := range h.Calls() {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Reset removes programmed responses and recorded calls
+func (h *Handler) Reset() {
+	h.mu.Lock()
+	h.responses = nil
+	h.calls = nil
+	h.mu.Unlock()
+}
+
+// SendEvent sends event to ListenForFullBlock and ListenForFilteredBlock listeners of event channel.
+// It blocks until all listeners received event or stopped listening.
+func (h *Handler) SendEvent(event gohfc.EventBlockResponse) {
+	for _, l := range h.activeListeners(event.ChannelId) {
+		if l.events == nil {
+			continue
+		}
+		select {
+		case l.events <- event:
+		case <-l.ctx.Done():
+		}
+	}
+}
+
+// SendBlock sends block to ListenBlocks listeners of channel. It blocks until all listeners received block or
+// stopped listening.
+func (h *Handler) SendBlock(channelId string, block *common.Block) {
+	for _, l := range h.activeListeners(channelId) {
+		if l.blocks == nil {
+			continue
+		}
+		select {
+		case l.blocks <- gohfc.BlockResponse{ChannelId: channelId, Block: block}:
+		case <-l.ctx.Done():
+		}
+	}
+}
+
+// Query returns programmed response from every peer, or from single peer named "mock" when peers is empty
+func (h *Handler) Query(identity gohfc.Identity, chainCode gohfc.ChainCode, peers []string) ([]*gohfc.QueryResponse, error) {
+	return h.QueryWithContext(context.Background(), identity, chainCode, peers)
+}
+
+// QueryWithContext is same as Query
+func (h *Handler) QueryWithContext(ctx context.Context, identity gohfc.Identity, chainCode gohfc.ChainCode, peers []string) ([]*gohfc.QueryResponse, error) {
+	r, err := h.respond(chainCodeCall("Query", chainCode, peers, ""), true)
+	if err != nil {
+		return nil, err
+	}
+	if len(peers) == 0 {
+		peers = []string{"mock"}
+	}
+	result := make([]*gohfc.QueryResponse, len(peers))
+	for i, name := range peers {
+		result[i] = &gohfc.QueryResponse{PeerName: name, Response: &peer.ProposalResponse{
+			Response: &peer.Response{Status: r.status, Message: r.message, Payload: r.payload}},
+			Status: r.status, Message: r.message, Payload: r.payload}
+	}
+	return result, nil
+}
+
+// Invoke returns programmed response with new transaction id
+func (h *Handler) Invoke(identity gohfc.Identity, chainCode gohfc.ChainCode, peers []string, orderer string) (*gohfc.InvokeResponse, error) {
+	return h.InvokeWithContext(context.Background(), identity, chainCode, peers, orderer)
+}
+
+// InvokeWithContext is same as Invoke
+func (h *Handler) InvokeWithContext(ctx context.Context, identity gohfc.Identity, chainCode gohfc.ChainCode, peers []string, orderer string) (*gohfc.InvokeResponse, error) {
+	r, err := h.respond(chainCodeCall("Invoke", chainCode, peers, orderer), true)
+	if err != nil {
+		return nil, err
+	}
+	if r.status != 200 {
+		return nil, &gohfc.EndorsementError{Peer: "mock", Status: r.status, Message: r.message}
+	}
+	responses := make([]gohfc.ChaincodeResponse, len(peers))
+	for i, name := range peers {
+		responses[i] = gohfc.ChaincodeResponse{PeerName: name, Status: r.status, Message: r.message, Payload: r.payload}
+	}
+	return &gohfc.InvokeResponse{Status: common.Status_SUCCESS, TxID: h.newTxId(), Payload: r.payload,
+		Message: r.message, Responses: responses}, nil
+}
+
+// ListenForFullBlock registers listener for events send with SendEvent
+func (h *Handler) ListenForFullBlock(ctx context.Context, identity gohfc.Identity, eventPeer, channelId string, response chan<- gohfc.EventBlockResponse) error {
+	return h.listen(ctx, "ListenForFullBlock", eventPeer, channelId, &listener{events: response})
+}
+
+// ListenForFilteredBlock registers listener for events send with SendEvent
+func (h *Handler) ListenForFilteredBlock(ctx context.Context, identity gohfc.Identity, eventPeer, channelId string, response chan<- gohfc.EventBlockResponse) error {
+	return h.listen(ctx, "ListenForFilteredBlock", eventPeer, channelId, &listener{events: response})
+}
+
+// ListenBlocks registers listener for blocks send with SendBlock, fromBlock is ignored
+func (h *Handler) ListenBlocks(ctx context.Context, identity gohfc.Identity, eventPeer, channelId string, fromBlock uint64, response chan<- gohfc.BlockResponse) error {
+	return h.listen(ctx, "ListenBlocks", eventPeer, channelId, &listener{blocks: response})
+}
+
+// CreateUpdateChannel records call
+func (h *Handler) CreateUpdateChannel(identity gohfc.Identity, path string, channelId string, orderer string) error {
+	_, err := h.respond(Call{Method: "CreateUpdateChannel", ChannelId: channelId, Orderer: orderer}, false)
+	return err
+}
+
+// JoinChannel records call and returns successful response from every peer
+func (h *Handler) JoinChannel(identity gohfc.Identity, channelId string, peers []string, orderer string) ([]*gohfc.PeerResponse, error) {
+	return h.peerResponses(Call{Method: "JoinChannel", ChannelId: channelId, Peers: peers, Orderer: orderer})
+}
+
+// InstallChainCode records call and returns successful response from every peer
+func (h *Handler) InstallChainCode(identity gohfc.Identity, req *gohfc.InstallRequest, peers []string) ([]*gohfc.PeerResponse, error) {
+	return h.peerResponses(Call{Method: "InstallChainCode", ChannelId: req.ChannelId, ChainCode: req.ChainCodeName,
+		Peers: peers})
+}
+
+// InstantiateChainCode records call and returns successful orderer response
+func (h *Handler) InstantiateChainCode(identity gohfc.Identity, req *gohfc.ChainCode, peers []string, orderer string, operation string,
+	collectionsConfig []gohfc.CollectionConfig) (*orderer.BroadcastResponse, error) {
+	return h.broadcastResponse(chainCodeCall("InstantiateChainCode", *req, peers, orderer))
+}
+
+// UpgradeChainCode records call and returns successful orderer response
+func (h *Handler) UpgradeChainCode(identity gohfc.Identity, req *gohfc.ChainCode, policy string, peers []string, orderer string,
+	collectionsConfig []gohfc.CollectionConfig) (*orderer.BroadcastResponse, error) {
+	return h.broadcastResponse(chainCodeCall("UpgradeChainCode", *req, peers, orderer))
+}
+
+// QueryInstalledChainCodes records call and returns empty list from every peer
+func (h *Handler) QueryInstalledChainCodes(identity gohfc.Identity, peers []string) ([]*gohfc.ChainCodesResponse, error) {
+	if _, err := h.respond(Call{Method: "QueryInstalledChainCodes", Peers: peers}, false); err != nil {
+		return nil, err
+	}
+	result := make([]*gohfc.ChainCodesResponse, len(peers))
+	for i, name := range peers {
+		result[i] = &gohfc.ChainCodesResponse{PeerName: name}
+	}
+	return result, nil
+}
+
+// QueryInstantiatedChainCodes records call and returns empty list from every peer
+func (h *Handler) QueryInstantiatedChainCodes(identity gohfc.Identity, channelId string, peers []string) ([]*gohfc.ChainCodesResponse, error) {
+	if _, err := h.respond(Call{Method: "QueryInstantiatedChainCodes", ChannelId: channelId, Peers: peers}, false); err != nil {
+		return nil, err
+	}
+	result := make([]*gohfc.ChainCodesResponse, len(peers))
+	for i, name := range peers {
+		result[i] = &gohfc.ChainCodesResponse{PeerName: name}
+	}
+	return result, nil
+}
+
+// QueryChannels records call and returns empty list from every peer
+func (h *Handler) QueryChannels(identity gohfc.Identity, peers []string) ([]*gohfc.QueryChannelsResponse, error) {
+	if _, err := h.respond(Call{Method: "QueryChannels", Peers: peers}, false); err != nil {
+		return nil, err
+	}
+	result := make([]*gohfc.QueryChannelsResponse, len(peers))
+	for i, name := range peers {
+		result[i] = &gohfc.QueryChannelsResponse{PeerName: name}
+	}
+	return result, nil
+}
+
+// QueryChannelInfo records call and returns empty blockchain info from every peer
+func (h *Handler) QueryChannelInfo(identity gohfc.Identity, channelId string, peers []string) ([]*gohfc.QueryChannelInfoResponse, error) {
+	if _, err := h.respond(Call{Method: "QueryChannelInfo", ChannelId: channelId, Peers: peers}, false); err != nil {
+		return nil, err
+	}
+	result := make([]*gohfc.QueryChannelInfoResponse, len(peers))
+	for i, name := range peers {
+		result[i] = &gohfc.QueryChannelInfoResponse{PeerName: name, Info: new(common.BlockchainInfo)}
+	}
+	return result, nil
+}
+
+func chainCodeCall(method string, chainCode gohfc.ChainCode, peers []string, orderer string) Call {
+	call := Call{Method: method, ChannelId: chainCode.ChannelId, ChainCode: chainCode.Name, Peers: peers, Orderer: orderer}
+	if len(chainCode.Args) > 0 {
+		call.Function = chainCode.Args[0]
+		call.Args = chainCode.Args[1:]
+	}
+	return call
+}
+
+// respond records call and returns the first matching response. When nothing matches ErrNoResponse is returned
+// if response is required, otherwise successful empty response.
+func (h *Handler) respond(call Call, required bool) (*Response, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls = append(h.calls, call)
+	for i, r := range h.responses {
+		if r.method != call.Method || (r.chainCode != "" && r.chainCode != call.ChainCode) ||
+			(r.function != "" && r.function != call.Function) {
+			continue
+		}
+		if r.times > 0 {
+			r.times--
+			if r.times == 0 {
+				h.responses = append(h.responses[:i:i], h.responses[i+1:]...)
+			}
+		}
+		return r, r.err
+	}
+	if required {
+		return nil, fmt.Errorf("%v for %s of %s function %q", ErrNoResponse, call.Method, call.ChainCode, call.Function)
+	}
+	return &Response{status: 200}, nil
+}
+
+func (h *Handler) peerResponses(call Call) ([]*gohfc.PeerResponse, error) {
+	r, err := h.respond(call, false)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*gohfc.PeerResponse, len(call.Peers))
+	for i, name := range call.Peers {
+		result[i] = &gohfc.PeerResponse{Name: name, Response: &peer.ProposalResponse{
+			Response: &peer.Response{Status: r.status, Message: r.message, Payload: r.payload}}}
+	}
+	return result, nil
+}
+
+func (h *Handler) broadcastResponse(call Call) (*orderer.BroadcastResponse, error) {
+	r, err := h.respond(call, false)
+	if err != nil {
+		return nil, err
+	}
+	if r.status != 200 {
+		return &orderer.BroadcastResponse{Status: common.Status(r.status), Info: r.message}, nil
+	}
+	return &orderer.BroadcastResponse{Status: common.Status_SUCCESS}, nil
+}
+
+func (h *Handler) listen(ctx context.Context, method, eventPeer, channelId string, l *listener) error {
+	if _, err := h.respond(Call{Method: method, ChannelId: channelId, Peers: []string{eventPeer}}, false); err != nil {
+		return err
+	}
+	l.ctx = ctx
+	l.channelId = channelId
+	h.mu.Lock()
+	h.listeners = append(h.listeners, l)
+	h.mu.Unlock()
+	return nil
+}
+
+// activeListeners returns listeners of channel and removes listeners which context is done
+func (h *Handler) activeListeners(channelId string) []*listener {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var active, result []*listener
+	for _, l := range h.listeners {
+		if l.ctx.Err() != nil {
+			continue
+		}
+		active = append(active, l)
+		if l.channelId == channelId {
+			result = append(result, l)
+		}
+	}
+	h.listeners = active
+	return result
+}
+
+func (h *Handler) newTxId() string {
+	h.mu.Lock()
+	h.txCount++
+	n := h.txCount
+	h.mu.Unlock()
+	id := sha256.Sum256([]byte("gohfcmock" + strconv.Itoa(n)))
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,290 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfctest
+
+import (
+	"context"
+	"crypto/sha256"
+	"math"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+// ledger holds blocks of channel, shared by orderer and all peers
+type ledger struct {
+	channelId string
+	mu        sync.Mutex
+	blocks    []*common.Block
+	// changed is closed and replaced when block is appended
+	changed chan struct{}
+}
+
+// newLedger creates ledger with genesis block that holds empty channel config
+func newLedger(channelId string) *ledger {
+	l := &ledger{channelId: channelId, changed: make(chan struct{})}
+	config, _ := proto.Marshal(&common.ConfigEnvelope{Config: &common.Config{ChannelGroup: &common.ConfigGroup{}}})
+	envelope, _ := newEnvelope(common.HeaderType_CONFIG, channelId, "", config)
+	l.append(envelope, peer.TxValidationCode_VALID)
+	return l
+}
+
+func newEnvelope(headerType common.HeaderType, channelId, txId string, data []byte) (*common.Envelope, error) {
+	channelHeader, err := proto.Marshal(&common.ChannelHeader{
+		Type:      int32(headerType),
+		ChannelId: channelId,
+		TxId:      txId,
+		Timestamp: ptypes.TimestampNow(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	payload, err := proto.Marshal(&common.Payload{Header: &common.Header{ChannelHeader: channelHeader}, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return &common.Envelope{Payload: payload}, nil
+}
+
+// append commits envelope in new block with validation code
+func (l *ledger) append(envelope *common.Envelope, code peer.TxValidationCode) *common.Block {
+	data, _ := proto.Marshal(envelope)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	block := &common.Block{
+		Header: &common.BlockHeader{Number: uint64(len(l.blocks))},
+		Data:   &common.BlockData{Data: [][]byte{data}},
+		Metadata: &common.BlockMetadata{Metadata: [][]byte{
+			common.BlockMetadataIndex_SIGNATURES:          {},
+			common.BlockMetadataIndex_LAST_CONFIG:         {},
+			common.BlockMetadataIndex_TRANSACTIONS_FILTER: {byte(code)},
+			common.BlockMetadataIndex_ORDERER:             {},
+		}},
+	}
+	dataHash := sha256.Sum256(data)
+	block.Header.DataHash = dataHash[:]
+	if len(l.blocks) > 0 {
+		previous, _ := proto.Marshal(l.blocks[len(l.blocks)-1].Header)
+		previousHash := sha256.Sum256(previous)
+		block.Header.PreviousHash = previousHash[:]
+	}
+	l.blocks = append(l.blocks, block)
+	close(l.changed)
+	l.changed = make(chan struct{})
+	return block
+}
+
+// transaction returns committed transaction txId or nil
+func (l *ledger) transaction(txId string) *peer.ProcessedTransaction {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, block := range l.blocks {
+		codes := block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+		for i, data := range block.Data.Data {
+			envelope := new(common.Envelope)
+			payload := new(common.Payload)
+			channelHeader := new(common.ChannelHeader)
+			if proto.Unmarshal(data, envelope) != nil || proto.Unmarshal(envelope.Payload, payload) != nil ||
+				payload.Header == nil || proto.Unmarshal(payload.Header.ChannelHeader, channelHeader) != nil {
+				continue
+			}
+			if channelHeader.TxId == txId && txId != "" {
+				tx := &peer.ProcessedTransaction{TransactionEnvelope: envelope}
+				if i < len(codes) {
+					tx.ValidationCode = int32(codes[i])
+				}
+				return tx
+			}
+		}
+	}
+	return nil
+}
+
+func (l *ledger) height() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return uint64(len(l.blocks))
+}
+
+// block returns block num or channel that is closed when next block is appended
+func (l *ledger) block(num uint64) (*common.Block, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if num < uint64(len(l.blocks)) {
+		return l.blocks[num], nil
+	}
+	return nil, l.changed
+}
+
+// deliverStream is common part of peer and orderer deliver streams
+type deliverStream interface {
+	Recv() (*common.Envelope, error)
+	Context() context.Context
+}
+
+// serveDeliver serves seek requests received from stream until client closes it. send is called with every block
+// and with status after the last block of seek.
+func serveDeliver(n *Network, nd *node, stream deliverStream, send func(*common.Block, common.Status) error) error {
+	for {
+		envelope, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		if err := nd.before(stream.Context()); err != nil {
+			return err
+		}
+		channelId, seek, err := parseSeek(envelope)
+		if err != nil {
+			if err := send(nil, common.Status_BAD_REQUEST); err != nil {
+				return err
+			}
+			continue
+		}
+		l := n.ledger(channelId)
+		if l == nil {
+			if err := send(nil, common.Status_NOT_FOUND); err != nil {
+				return err
+			}
+			continue
+		}
+		start, stop := seekNumber(seek.Start, l), seekNumber(seek.Stop, l)
+		if seek.Behavior == orderer.SeekInfo_FAIL_IF_NOT_READY && start >= l.height() {
+			if err := send(nil, common.Status_NOT_FOUND); err != nil {
+				return err
+			}
+			continue
+		}
+		for num := start; num <= stop; num++ {
+			block, changed := l.block(num)
+			for block == nil {
+				select {
+				case <-changed:
+				case <-stream.Context().Done():
+					return nil
+				}
+				block, changed = l.block(num)
+			}
+			if err := send(block, 0); err != nil {
+				return err
+			}
+			if num == math.MaxUint64 {
+				break
+			}
+		}
+		if err := send(nil, common.Status_SUCCESS); err != nil {
+			return err
+		}
+	}
+}
+
+func parseSeek(envelope *common.Envelope) (string, *orderer.SeekInfo, error) {
+	payload := new(common.Payload)
+	if err := proto.Unmarshal(envelope.Payload, payload); err != nil {
+		return "", nil, err
+	}
+	if payload.Header == nil {
+		return "", nil, proto.ErrNil
+	}
+	channelHeader := new(common.ChannelHeader)
+	if err := proto.Unmarshal(payload.Header.ChannelHeader, channelHeader); err != nil {
+		return "", nil, err
+	}
+	seek := new(orderer.SeekInfo)
+	if err := proto.Unmarshal(payload.Data, seek); err != nil {
+		return "", nil, err
+	}
+	return channelHeader.ChannelId, seek, nil
+}
+
+func seekNumber(pos *orderer.SeekPosition, l *ledger) uint64 {
+	switch t := pos.GetType().(type) {
+	case *orderer.SeekPosition_Specified:
+		return t.Specified.Number
+	case *orderer.SeekPosition_Newest:
+		return l.height() - 1
+	}
+	return 0
+}
+
+// filteredBlock returns block with transaction ids, validation codes and chaincode events without payloads
+func filteredBlock(channelId string, block *common.Block) *peer.FilteredBlock {
+	filtered := &peer.FilteredBlock{ChannelId: channelId, Number: block.Header.Number}
+	for i, data := range block.Data.Data {
+		tx, err := parseTransaction(data)
+		if err != nil {
+			continue
+		}
+		ft := &peer.FilteredTransaction{
+			Txid:             tx.channelHeader.TxId,
+			Type:             common.HeaderType(tx.channelHeader.Type),
+			TxValidationCode: peer.TxValidationCode(block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER][i]),
+		}
+		if ft.Type == common.HeaderType_ENDORSER_TRANSACTION {
+			actions := &peer.FilteredTransactionActions{}
+			if tx.event != nil {
+				event := *tx.event
+				event.Payload = nil
+				actions.ChaincodeActions = []*peer.FilteredChaincodeAction{{ChaincodeEvent: &event}}
+			}
+			ft.Data = &peer.FilteredTransaction_TransactionActions{TransactionActions: actions}
+		}
+		filtered.FilteredTransactions = append(filtered.FilteredTransactions, ft)
+	}
+	return filtered
+}
+
+type transaction struct {
+	channelHeader *common.ChannelHeader
+	event         *peer.ChaincodeEvent
+}
+
+// parseTransaction returns channel header and chaincode event of envelope
+func parseTransaction(data []byte) (*transaction, error) {
+	envelope := new(common.Envelope)
+	if err := proto.Unmarshal(data, envelope); err != nil {
+		return nil, err
+	}
+	payload := new(common.Payload)
+	if err := proto.Unmarshal(envelope.Payload, payload); err != nil {
+		return nil, err
+	}
+	if payload.Header == nil {
+		return nil, proto.ErrNil
+	}
+	tx := &transaction{channelHeader: new(common.ChannelHeader)}
+	if err := proto.Unmarshal(payload.Header.ChannelHeader, tx.channelHeader); err != nil {
+		return nil, err
+	}
+	if common.HeaderType(tx.channelHeader.Type) != common.HeaderType_ENDORSER_TRANSACTION {
+		return tx, nil
+	}
+	ptx := new(peer.Transaction)
+	if err := proto.Unmarshal(payload.Data, ptx); err != nil || len(ptx.Actions) == 0 {
+		return tx, err
+	}
+	actionPayload := new(peer.ChaincodeActionPayload)
+	if err := proto.Unmarshal(ptx.Actions[0].Payload, actionPayload); err != nil || actionPayload.Action == nil {
+		return tx, err
+	}
+	responsePayload := new(peer.ProposalResponsePayload)
+	if err := proto.Unmarshal(actionPayload.Action.ProposalResponsePayload, responsePayload); err != nil {
+		return tx, err
+	}
+	action := new(peer.ChaincodeAction)
+	if err := proto.Unmarshal(responsePayload.Extension, action); err != nil {
+		return tx, err
+	}
+	if len(action.Events) > 0 {
+		tx.event = new(peer.ChaincodeEvent)
+		if err := proto.Unmarshal(action.Events, tx.event); err != nil {
+			return tx, err
+		}
+	}
+	return tx, nil
+}
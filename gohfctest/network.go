@@ -0,0 +1,259 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+
+// Package gohfctest runs in process fake Fabric network for integration tests of applications built on gohfc.
+// Peers implement Endorser and Deliver services and orderer implements Broadcast and Deliver services over gRPC,
+// so retries, failover and event handling can be tested in CI without docker. Chaincodes are Go functions with
+// canned responses, every transaction send to orderer is committed in its own block.
+package gohfctest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/CognitionFoundry/gohfc"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	// OrdererName is the name of the orderer in client config
+	OrdererName = "orderer0"
+)
+
+// Config describes network to start. Zero values are replaced with defaults.
+type Config struct {
+	// Peers is number of peers, default 1. Peers are named peer0, peer1...
+	Peers int
+	// MspId is MSP id of peers and client identity, default Org1MSP
+	MspId string
+	// ChannelId is the channel that exists when network starts, default mychannel. Other channels are created
+	// with CreateUpdateChannel.
+	ChannelId string
+}
+
+func (c *Config) setDefaults() {
+	if c.Peers <= 0 {
+		c.Peers = 1
+	}
+	if c.MspId == "" {
+		c.MspId = "Org1MSP"
+	}
+	if c.ChannelId == "" {
+		c.ChannelId = "mychannel"
+	}
+}
+
+// Network is running fake network
+type Network struct {
+	Config
+	Peers   []*Peer
+	Orderer *Orderer
+
+	crypto   gohfc.CryptoSuite
+	ca       *signer
+	identity gohfc.Identity
+
+	mu             sync.Mutex
+	ledgers        map[string]*ledger
+	validationCode peer.TxValidationCode
+}
+
+// NewNetwork starts network listening on localhost. Network must be closed with Close.
+func NewNetwork(config Config) (*Network, error) {
+	config.setDefaults()
+	crypto, err := gohfc.NewCryptoSuiteFromConfig(clientCrypto)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := newSigner("ca", nil)
+	if err != nil {
+		return nil, err
+	}
+	n := &Network{Config: config, crypto: crypto, ca: ca, ledgers: make(map[string]*ledger)}
+	user, err := newSigner("User1", ca)
+	if err != nil {
+		return nil, err
+	}
+	n.identity = gohfc.Identity{Certificate: user.cert, PrivateKey: user.key, MspId: config.MspId}
+	n.ledgers[config.ChannelId] = newLedger(config.ChannelId)
+	for i := 0; i < config.Peers; i++ {
+		p, err := newPeer(n, fmt.Sprintf("peer%d", i))
+		if err != nil {
+			n.Close()
+			return nil, err
+		}
+		n.Peers = append(n.Peers, p)
+	}
+	if n.Orderer, err = newOrderer(n, OrdererName); err != nil {
+		n.Close()
+		return nil, err
+	}
+	return n, nil
+}
+
+// clientCrypto is crypto config of client and nodes
+var clientCrypto = gohfc.CryptoConfig{Family: "ecdsa", Algorithm: "P256-SHA256", Hash: "SHA2-256"}
+
+// ClientConfig returns gohfc config for connecting to the network. Every peer is also event peer.
+func (n *Network) ClientConfig() gohfc.ClientConfig {
+	config := gohfc.ClientConfig{
+		CryptoConfig: clientCrypto,
+		Orderers:     map[string]gohfc.OrdererConfig{n.Orderer.Name: {Host: n.Orderer.Addr()}},
+		Peers:        make(map[string]gohfc.PeerConfig),
+		EventPeers:   make(map[string]gohfc.PeerConfig),
+	}
+	for _, p := range n.Peers {
+		config.Peers[p.Name] = gohfc.PeerConfig{Host: p.Addr()}
+		config.EventPeers[p.Name] = gohfc.PeerConfig{Host: p.Addr()}
+	}
+	return config
+}
+
+// Client creates gohfc client connected to the network
+func (n *Network) Client() (*gohfc.FabricClient, error) {
+	return gohfc.NewFabricClientFromConfig(n.ClientConfig())
+}
+
+// Identity returns client identity issued by network CA
+func (n *Network) Identity() gohfc.Identity {
+	return n.identity
+}
+
+// PeerNames returns names of all peers
+func (n *Network) PeerNames() []string {
+	names := make([]string, len(n.Peers))
+	for i, p := range n.Peers {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// Peer returns peer by name or nil
+func (n *Network) Peer(name string) *Peer {
+	for _, p := range n.Peers {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// SetChaincode installs chaincode function on all peers
+func (n *Network) SetChaincode(name string, fn ChaincodeFunc) {
+	for _, p := range n.Peers {
+		p.SetChaincode(name, fn)
+	}
+}
+
+// SetValidationCode sets validation code of transactions committed from now on, for example MVCC_READ_CONFLICT.
+// Default is VALID.
+func (n *Network) SetValidationCode(code peer.TxValidationCode) {
+	n.mu.Lock()
+	n.validationCode = code
+	n.mu.Unlock()
+}
+
+// Height returns number of blocks of channel
+func (n *Network) Height(channelId string) uint64 {
+	l := n.ledger(channelId)
+	if l == nil {
+		return 0
+	}
+	return l.height()
+}
+
+// Close stops all nodes
+func (n *Network) Close() {
+	for _, p := range n.Peers {
+		p.Stop()
+	}
+	if n.Orderer != nil {
+		n.Orderer.Stop()
+	}
+}
+
+func (n *Network) ledger(channelId string) *ledger {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.ledgers[channelId]
+}
+
+// createLedger creates ledger of channel if it does not exist
+func (n *Network) createLedger(channelId string) *ledger {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	l, ok := n.ledgers[channelId]
+	if !ok {
+		l = newLedger(channelId)
+		n.ledgers[channelId] = l
+	}
+	return l
+}
+
+func (n *Network) channels() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	channels := make([]string, 0, len(n.ledgers))
+	for id := range n.ledgers {
+		channels = append(channels, id)
+	}
+	return channels
+}
+
+func (n *Network) currentValidationCode() peer.TxValidationCode {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.validationCode
+}
+
+// signer is certificate and key issued by network CA, or CA itself when issuer is nil
+type signer struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newSigner(name string, issuer *signer) (*signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-5 * time.Minute),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	parent, parentKey := template, key
+	if issuer == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		parent, parentKey = issuer.cert, issuer.key
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &signer{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw})}, nil
+}
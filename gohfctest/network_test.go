@@ -0,0 +1,129 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfctest_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CognitionFoundry/gohfc"
+	"github.com/CognitionFoundry/gohfc/gohfctest"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+// kvChaincode is chaincode with put and get functions over shared state
+func kvChaincode() gohfctest.ChaincodeFunc {
+	var mu sync.Mutex
+	state := make(map[string][]byte)
+	return func(channelId string, args [][]byte) gohfctest.Response {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case len(args) == 3 && string(args[0]) == "put":
+			state[string(args[1])] = args[2]
+			return gohfctest.Success(args[2])
+		case len(args) == 2 && string(args[0]) == "get":
+			return gohfctest.Success(state[string(args[1])])
+		}
+		return gohfctest.Error("unknown function")
+	}
+}
+
+func newTestNetwork(t *testing.T, peers int) (*gohfctest.Network, *gohfc.FabricClient) {
+	n, err := gohfctest.NewNetwork(gohfctest.Config{Peers: peers})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(n.Close)
+	n.SetChaincode("kv", kvChaincode())
+	c, err := n.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n, c
+}
+
+func kv(args ...string) gohfc.ChainCode {
+	return gohfc.ChainCode{ChannelId: "mychannel", Name: "kv", Type: gohfc.ChaincodeSpec_GOLANG, Args: args}
+}
+
+func TestInvokeCommitQuery(t *testing.T) {
+	n, c := newTestNetwork(t, 2)
+	id := n.Identity()
+	height := n.Height("mychannel")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result := c.InvokeAsync(ctx, id, kv("put", "a", "1"), n.PeerNames(), gohfctest.OrdererName, "peer0").Result()
+	if err := result.Err(); err != nil {
+		t.Fatalf("invoke: %v", err)
+	}
+	if string(result.Payload) != "1" {
+		t.Errorf("payload = %q, want 1", result.Payload)
+	}
+	if got := n.Height("mychannel"); got != height+1 {
+		t.Errorf("height = %d, want %d", got, height+1)
+	}
+	if result.BlockHeight != height {
+		t.Errorf("block height = %d, want %d", result.BlockHeight, height)
+	}
+
+	committed, err := c.EnsureCommitted(id, "mychannel", result.TxID, n.PeerNames())
+	if err != nil {
+		t.Fatalf("ensure committed: %v", err)
+	}
+	if !committed.Valid() {
+		t.Errorf("validation code = %s, want VALID", committed.ValidationCode)
+	}
+
+	resp, err := c.Query(id, kv("get", "a"), n.PeerNames())
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(resp) != 2 {
+		t.Fatalf("got %d responses, want 2", len(resp))
+	}
+	for _, r := range resp {
+		if r.Error != nil {
+			t.Fatalf("query %s: %v", r.PeerName, r.Error)
+		}
+		if string(r.Payload) != "1" {
+			t.Errorf("query %s = %q, want 1", r.PeerName, r.Payload)
+		}
+	}
+}
+
+func TestInvokeInvalidTransaction(t *testing.T) {
+	n, c := newTestNetwork(t, 1)
+	n.SetValidationCode(peer.TxValidationCode_MVCC_READ_CONFLICT)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result := c.InvokeAsync(ctx, n.Identity(), kv("put", "a", "1"), n.PeerNames(), gohfctest.OrdererName,
+		"peer0").Result()
+	err := result.Err()
+	if _, ok := err.(*gohfc.CommitError); !ok {
+		t.Fatalf("err = %v, want *gohfc.CommitError", err)
+	}
+	if result.ValidationCode != "MVCC_READ_CONFLICT" {
+		t.Errorf("validation code = %s, want MVCC_READ_CONFLICT", result.ValidationCode)
+	}
+}
+
+func TestQueryChaincodeError(t *testing.T) {
+	n, c := newTestNetwork(t, 1)
+	resp, err := c.Query(n.Identity(), kv("delete", "a"), n.PeerNames())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp[0].Error != nil {
+		t.Fatal(resp[0].Error)
+	}
+	if resp[0].Status != 500 || resp[0].Message != "unknown function" {
+		t.Errorf("status = %d %q, want 500 unknown function", resp[0].Status, resp[0].Message)
+	}
+}
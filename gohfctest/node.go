@@ -0,0 +1,114 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfctest
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// node is gRPC server of peer or orderer that can be stopped, restarted and made to fail or respond slowly
+type node struct {
+	Name string
+
+	register func(*grpc.Server)
+	mu       sync.Mutex
+	addr     string
+	server   *grpc.Server
+	err      error
+	delay    time.Duration
+}
+
+// Addr returns host:port on which node listens. It does not change when node is restarted.
+func (n *node) Addr() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.addr
+}
+
+// Fail makes all requests to node fail with err until Fail(nil) is called. Use gRPC status errors like
+// status.Error(codes.Unavailable, "down") to simulate transport failures.
+func (n *node) Fail(err error) {
+	n.mu.Lock()
+	n.err = err
+	n.mu.Unlock()
+}
+
+// SetDelay delays all responses of node, for example to test timeouts
+func (n *node) SetDelay(d time.Duration) {
+	n.mu.Lock()
+	n.delay = d
+	n.mu.Unlock()
+}
+
+// Start starts stopped node on the same address
+func (n *node) Start() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.server != nil {
+		return nil
+	}
+	addr := n.addr
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	n.addr = lis.Addr().String()
+	n.server = grpc.NewServer(grpc.UnaryInterceptor(n.unary), grpc.StreamInterceptor(n.stream))
+	n.register(n.server)
+	go n.server.Serve(lis)
+	return nil
+}
+
+// Stop stops node and closes all connections to it
+func (n *node) Stop() {
+	n.mu.Lock()
+	server := n.server
+	n.server = nil
+	n.mu.Unlock()
+	if server != nil {
+		server.Stop()
+	}
+}
+
+// before applies delay and returns injected error. It is called before every response.
+func (n *node) before(ctx context.Context) error {
+	n.mu.Lock()
+	err, delay := n.err, n.delay
+	n.mu.Unlock()
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+func (n *node) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := n.before(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// stream fails streams with injected error, streaming handlers call before for every response themselves
+func (n *node) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	n.mu.Lock()
+	err := n.err
+	n.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,93 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfctest
+
+import (
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/orderer"
+	"google.golang.org/grpc"
+)
+
+// Orderer is fake orderer. Every accepted transaction is committed in its own block with validation code set by
+// Network.SetValidationCode. Config update for unknown channel creates the channel.
+type Orderer struct {
+	node
+	network *Network
+	mu      sync.Mutex
+	status  common.Status
+}
+
+func newOrderer(n *Network, name string) (*Orderer, error) {
+	o := &Orderer{network: n, status: common.Status_SUCCESS}
+	o.Name = name
+	o.register = func(s *grpc.Server) {
+		orderer.RegisterAtomicBroadcastServer(s, o)
+	}
+	return o, o.Start()
+}
+
+// SetStatus sets status returned for broadcast transactions, transactions with status other than SUCCESS are not
+// committed. Default is SUCCESS.
+func (o *Orderer) SetStatus(status common.Status) {
+	o.mu.Lock()
+	o.status = status
+	o.mu.Unlock()
+}
+
+// Broadcast implements orderer.AtomicBroadcastServer
+func (o *Orderer) Broadcast(stream orderer.AtomicBroadcast_BroadcastServer) error {
+	for {
+		envelope, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		if err := o.before(stream.Context()); err != nil {
+			return err
+		}
+		if err := stream.Send(o.broadcast(envelope)); err != nil {
+			return err
+		}
+	}
+}
+
+func (o *Orderer) broadcast(envelope *common.Envelope) *orderer.BroadcastResponse {
+	o.mu.Lock()
+	status := o.status
+	o.mu.Unlock()
+	if status != common.Status_SUCCESS {
+		return &orderer.BroadcastResponse{Status: status, Info: "rejected by test orderer"}
+	}
+	payload := new(common.Payload)
+	if err := proto.Unmarshal(envelope.Payload, payload); err != nil || payload.Header == nil {
+		return &orderer.BroadcastResponse{Status: common.Status_BAD_REQUEST, Info: "malformed envelope"}
+	}
+	channelHeader := new(common.ChannelHeader)
+	if err := proto.Unmarshal(payload.Header.ChannelHeader, channelHeader); err != nil {
+		return &orderer.BroadcastResponse{Status: common.Status_BAD_REQUEST, Info: err.Error()}
+	}
+	if common.HeaderType(channelHeader.Type) == common.HeaderType_CONFIG_UPDATE && o.network.ledger(channelHeader.ChannelId) == nil {
+		o.network.createLedger(channelHeader.ChannelId)
+		return &orderer.BroadcastResponse{Status: common.Status_SUCCESS}
+	}
+	l := o.network.ledger(channelHeader.ChannelId)
+	if l == nil {
+		return &orderer.BroadcastResponse{Status: common.Status_NOT_FOUND, Info: "channel does not exist"}
+	}
+	l.append(envelope, o.network.currentValidationCode())
+	return &orderer.BroadcastResponse{Status: common.Status_SUCCESS}
+}
+
+// Deliver implements orderer.AtomicBroadcastServer
+func (o *Orderer) Deliver(stream orderer.AtomicBroadcast_DeliverServer) error {
+	return serveDeliver(o.network, &o.node, stream, func(block *common.Block, status common.Status) error {
+		if block == nil {
+			return stream.Send(&orderer.DeliverResponse{Type: &orderer.DeliverResponse_Status{Status: status}})
+		}
+		return stream.Send(&orderer.DeliverResponse{Type: &orderer.DeliverResponse_Block{Block: block}})
+	})
+}
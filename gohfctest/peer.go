@@ -0,0 +1,273 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfctest
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/ledger/rwset"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/hyperledger/fabric/protos/peer"
+	"google.golang.org/grpc"
+)
+
+// Response is canned response of chaincode. Status 400 and above is error, proposal is not endorsed.
+type Response struct {
+	Status  int32
+	Message string
+	Payload []byte
+	// Event is optional chaincode event set by transaction
+	Event *peer.ChaincodeEvent
+}
+
+// Success returns response with status 200 and payload
+func Success(payload []byte) Response {
+	return Response{Status: 200, Payload: payload}
+}
+
+// Error returns response with status 500 and message, like shim.Error
+func Error(message string) Response {
+	return Response{Status: 500, Message: message}
+}
+
+// ChaincodeFunc executes chaincode, args[0] is the function name
+type ChaincodeFunc func(channelId string, args [][]byte) Response
+
+// Peer is fake peer. Proposals to chaincodes set with SetChaincode are endorsed with chaincode response, qscc
+// GetChainInfo, GetBlockByNumber and GetTransactionByID and cscc JoinChain and GetChannels are answered from
+// network ledgers.
+type Peer struct {
+	node
+	network    *Network
+	signer     *signer
+	endorser   []byte
+	mu         sync.RWMutex
+	chaincodes map[string]ChaincodeFunc
+}
+
+func newPeer(n *Network, name string) (*Peer, error) {
+	s, err := newSigner(name, n.ca)
+	if err != nil {
+		return nil, err
+	}
+	endorser, err := proto.Marshal(&msp.SerializedIdentity{Mspid: n.MspId, IdBytes: s.pem})
+	if err != nil {
+		return nil, err
+	}
+	p := &Peer{network: n, signer: s, endorser: endorser, chaincodes: make(map[string]ChaincodeFunc)}
+	p.Name = name
+	p.register = func(s *grpc.Server) {
+		peer.RegisterEndorserServer(s, p)
+		peer.RegisterDeliverServer(s, p)
+	}
+	return p, p.Start()
+}
+
+// SetChaincode installs chaincode function on peer, replacing previous one. Nil fn removes chaincode.
+func (p *Peer) SetChaincode(name string, fn ChaincodeFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if fn == nil {
+		delete(p.chaincodes, name)
+		return
+	}
+	p.chaincodes[name] = fn
+}
+
+// ProcessProposal implements peer.EndorserServer
+func (p *Peer) ProcessProposal(ctx context.Context, signed *peer.SignedProposal) (*peer.ProposalResponse, error) {
+	proposal := new(peer.Proposal)
+	if err := proto.Unmarshal(signed.ProposalBytes, proposal); err != nil {
+		return nil, err
+	}
+	header := new(common.Header)
+	if err := proto.Unmarshal(proposal.Header, header); err != nil {
+		return nil, err
+	}
+	channelHeader := new(common.ChannelHeader)
+	if err := proto.Unmarshal(header.ChannelHeader, channelHeader); err != nil {
+		return nil, err
+	}
+	extension := new(peer.ChaincodeHeaderExtension)
+	if err := proto.Unmarshal(channelHeader.Extension, extension); err != nil {
+		return nil, err
+	}
+	payload := new(peer.ChaincodeProposalPayload)
+	if err := proto.Unmarshal(proposal.Payload, payload); err != nil {
+		return nil, err
+	}
+	spec := new(peer.ChaincodeInvocationSpec)
+	if err := proto.Unmarshal(payload.Input, spec); err != nil {
+		return nil, err
+	}
+	if extension.ChaincodeId == nil || spec.ChaincodeSpec == nil || spec.ChaincodeSpec.Input == nil {
+		return nil, fmt.Errorf("proposal has no chaincode invocation")
+	}
+	name := extension.ChaincodeId.Name
+	resp := p.execute(channelHeader.ChannelId, name, spec.ChaincodeSpec.Input.Args)
+	if resp.Status >= 400 {
+		return &peer.ProposalResponse{Version: 1, Response: &peer.Response{Status: resp.Status, Message: resp.Message}}, nil
+	}
+	results, err := proto.Marshal(&rwset.TxReadWriteSet{DataModel: rwset.TxReadWriteSet_KV})
+	if err != nil {
+		return nil, err
+	}
+	response := &peer.Response{Status: resp.Status, Message: resp.Message, Payload: resp.Payload}
+	action := &peer.ChaincodeAction{Results: results, Response: response, ChaincodeId: &peer.ChaincodeID{Name: name}}
+	if resp.Event != nil {
+		event := *resp.Event
+		event.ChaincodeId = name
+		event.TxId = channelHeader.TxId
+		if action.Events, err = proto.Marshal(&event); err != nil {
+			return nil, err
+		}
+	}
+	extensionBytes, err := proto.Marshal(action)
+	if err != nil {
+		return nil, err
+	}
+	// proposal hash covers headers and input without transient data
+	input, err := proto.Marshal(&peer.ChaincodeProposalPayload{Input: payload.Input})
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	hash.Write(header.ChannelHeader)
+	hash.Write(header.SignatureHeader)
+	hash.Write(input)
+	responsePayload, err := proto.Marshal(&peer.ProposalResponsePayload{ProposalHash: hash.Sum(nil), Extension: extensionBytes})
+	if err != nil {
+		return nil, err
+	}
+	signature, err := p.network.crypto.Sign(append(append([]byte{}, responsePayload...), p.endorser...), p.signer.key)
+	if err != nil {
+		return nil, err
+	}
+	return &peer.ProposalResponse{
+		Version:     1,
+		Response:    response,
+		Payload:     responsePayload,
+		Endorsement: &peer.Endorsement{Endorser: p.endorser, Signature: signature},
+	}, nil
+}
+
+// execute runs system or user chaincode
+func (p *Peer) execute(channelId, name string, args [][]byte) Response {
+	switch name {
+	case "qscc":
+		return p.qscc(args)
+	case "cscc":
+		return p.cscc(args)
+	}
+	if p.network.ledger(channelId) == nil {
+		return Error(fmt.Sprintf("channel '%s' not found", channelId))
+	}
+	p.mu.RLock()
+	fn, ok := p.chaincodes[name]
+	p.mu.RUnlock()
+	if !ok {
+		return Error(fmt.Sprintf("make sure the chaincode %s has been successfully instantiated and try again", name))
+	}
+	return fn(channelId, args)
+}
+
+func (p *Peer) qscc(args [][]byte) Response {
+	if len(args) < 2 {
+		return Error("incorrect number of arguments")
+	}
+	l := p.network.ledger(string(args[1]))
+	if l == nil {
+		return Error(fmt.Sprintf("channel %s not found", args[1]))
+	}
+	switch string(args[0]) {
+	case "GetChainInfo":
+		height := l.height()
+		last, _ := l.block(height - 1)
+		header, _ := proto.Marshal(last.Header)
+		hash := sha256.Sum256(header)
+		return marshalResponse(&common.BlockchainInfo{Height: height, CurrentBlockHash: hash[:],
+			PreviousBlockHash: last.Header.PreviousHash})
+	case "GetBlockByNumber":
+		if len(args) < 3 {
+			return Error("incorrect number of arguments")
+		}
+		num, err := strconv.ParseUint(string(args[2]), 10, 64)
+		if err != nil {
+			return Error(err.Error())
+		}
+		block, _ := l.block(num)
+		if block == nil {
+			return Error(fmt.Sprintf("Failed to get block number %d, error no such block number [%d] in index", num, num))
+		}
+		return marshalResponse(block)
+	case "GetTransactionByID":
+		if len(args) < 3 {
+			return Error("incorrect number of arguments")
+		}
+		tx := l.transaction(string(args[2]))
+		if tx == nil {
+			return Error(fmt.Sprintf("Failed to get transaction with id %s, error no such transaction ID [%s] in index",
+				args[2], args[2]))
+		}
+		return marshalResponse(tx)
+	}
+	return Error(fmt.Sprintf("requested function %s not found", args[0]))
+}
+
+func (p *Peer) cscc(args [][]byte) Response {
+	if len(args) == 0 {
+		return Error("incorrect number of arguments")
+	}
+	switch string(args[0]) {
+	case "JoinChain":
+		return Success(nil)
+	case "GetChannels":
+		resp := new(peer.ChannelQueryResponse)
+		for _, id := range p.network.channels() {
+			resp.Channels = append(resp.Channels, &peer.ChannelInfo{ChannelId: id})
+		}
+		return marshalResponse(resp)
+	}
+	return Error(fmt.Sprintf("requested function %s not found", args[0]))
+}
+
+func marshalResponse(msg proto.Message) Response {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return Error(err.Error())
+	}
+	return Success(payload)
+}
+
+// Deliver implements peer.DeliverServer
+func (p *Peer) Deliver(stream peer.Deliver_DeliverServer) error {
+	return serveDeliver(p.network, &p.node, stream, func(block *common.Block, status common.Status) error {
+		if block == nil {
+			return stream.Send(&peer.DeliverResponse{Type: &peer.DeliverResponse_Status{Status: status}})
+		}
+		return stream.Send(&peer.DeliverResponse{Type: &peer.DeliverResponse_Block{Block: block}})
+	})
+}
+
+// DeliverFiltered implements peer.DeliverServer
+func (p *Peer) DeliverFiltered(stream peer.Deliver_DeliverFilteredServer) error {
+	return serveDeliver(p.network, &p.node, stream, func(block *common.Block, status common.Status) error {
+		if block == nil {
+			return stream.Send(&peer.DeliverResponse{Type: &peer.DeliverResponse_Status{Status: status}})
+		}
+		channelId := ""
+		if tx, err := parseTransaction(block.Data.Data[0]); err == nil {
+			channelId = tx.channelHeader.ChannelId
+		}
+		return stream.Send(&peer.DeliverResponse{Type: &peer.DeliverResponse_FilteredBlock{
+			FilteredBlock: filteredBlock(channelId, block)}})
+	})
+}
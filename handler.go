@@ -0,0 +1,37 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+
+	"github.com/hyperledger/fabric/protos/orderer"
+)
+
+// Handler is the part of FabricClient API used by applications to query and invoke chaincodes, listen for blocks
+// and manage channels and chaincodes. Applications that depend on Handler instead of *FabricClient can be unit
+// tested with gohfcmock.Handler without running Fabric network.
+type Handler interface {
+	Query(identity Identity, chainCode ChainCode, peers []string) ([]*QueryResponse, error)
+	QueryWithContext(ctx context.Context, identity Identity, chainCode ChainCode, peers []string) ([]*QueryResponse, error)
+	Invoke(identity Identity, chainCode ChainCode, peers []string, orderer string) (*InvokeResponse, error)
+	InvokeWithContext(ctx context.Context, identity Identity, chainCode ChainCode, peers []string, orderer string) (*InvokeResponse, error)
+	ListenForFullBlock(ctx context.Context, identity Identity, eventPeer, channelId string, response chan<- EventBlockResponse) error
+	ListenForFilteredBlock(ctx context.Context, identity Identity, eventPeer, channelId string, response chan<- EventBlockResponse) error
+	ListenBlocks(ctx context.Context, identity Identity, eventPeer, channelId string, fromBlock uint64, response chan<- BlockResponse) error
+	CreateUpdateChannel(identity Identity, path string, channelId string, orderer string) error
+	JoinChannel(identity Identity, channelId string, peers []string, orderer string) ([]*PeerResponse, error)
+	InstallChainCode(identity Identity, req *InstallRequest, peers []string) ([]*PeerResponse, error)
+	InstantiateChainCode(identity Identity, req *ChainCode, peers []string, orderer string, operation string,
+		collectionsConfig []CollectionConfig) (*orderer.BroadcastResponse, error)
+	UpgradeChainCode(identity Identity, req *ChainCode, policy string, peers []string, orderer string,
+		collectionsConfig []CollectionConfig) (*orderer.BroadcastResponse, error)
+	QueryInstalledChainCodes(identity Identity, peers []string) ([]*ChainCodesResponse, error)
+	QueryInstantiatedChainCodes(identity Identity, channelId string, peers []string) ([]*ChainCodesResponse, error)
+	QueryChannels(identity Identity, peers []string) ([]*QueryChannelsResponse, error)
+	QueryChannelInfo(identity Identity, channelId string, peers []string) ([]*QueryChannelInfoResponse, error)
+}
+
+var _ Handler = (*FabricClient)(nil)
@@ -0,0 +1,99 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+
+	"github.com/CognitionFoundry/gohfc/gm"
+	"golang.org/x/crypto/sha3"
+)
+
+// DefaultChannelOptions is the name of ChannelOptions entry in config that applies to channels without own entry
+const DefaultChannelOptions = "default"
+
+// ChannelOptions holds per channel settings: hash family and defaults for requests made with FabricClient.Channel
+type ChannelOptions struct {
+	// Hash is used for transaction ids and for signature digest of proposals and transactions in channel:
+	// SHA2-256, SHA2-384, SHA3-256, SHA3-384 or SM3. Default is SHA2-256 for transaction ids and crypto suite hash
+	// for signatures. SM2 and Ed25519 signatures are not affected.
+	Hash string `yaml:"hash"`
+	// ChainCode is chaincode name used when request has no name
+	ChainCode string `yaml:"chaincode"`
+	// Peers are used when request has no peers. When empty client defaults apply.
+	Peers []string `yaml:"peers"`
+	// Orderer is used for invokes without orderer. When empty client defaults apply.
+	Orderer string `yaml:"orderer"`
+}
+
+// newHashFunction returns hash function for hash family name
+func newHashFunction(name string) (func() hash.Hash, error) {
+	switch name {
+	case "SHA2-256":
+		return sha256.New, nil
+	case "SHA2-384":
+		return sha512.New384, nil
+	case "SHA3-256":
+		return sha3.New256, nil
+	case "SHA3-384":
+		return sha3.New384, nil
+	case "SM3":
+		return gm.NewSM3, nil
+	default:
+		return nil, ErrInvalidHash
+	}
+}
+
+// validateChannelOptions checks hash families of channel options
+func validateChannelOptions(channels map[string]ChannelOptions) error {
+	for channelId, options := range channels {
+		if options.Hash == "" {
+			continue
+		}
+		if _, err := newHashFunction(options.Hash); err != nil {
+			return fmt.Errorf("invalid hash for channel %s: %v", channelId, err)
+		}
+	}
+	return nil
+}
+
+// channelHash returns hash function configured for channel in channel options of client, or in default options.
+// Channel entry without hash uses hash of default entry.
+func (c *FabricClient) channelHash(channelId string) (func() hash.Hash, bool) {
+	c.mu.RLock()
+	name := c.channels[channelId].Hash
+	if name == "" {
+		name = c.channels[DefaultChannelOptions].Hash
+	}
+	c.mu.RUnlock()
+	if name == "" {
+		return nil, false
+	}
+	h, err := newHashFunction(name)
+	return h, err == nil
+}
+
+// channelCryptoSuite returns suite that signs with hash h of channel. Suites where hash is part of the signature
+// algorithm, and all suites when h is nil, are returned unchanged.
+func channelCryptoSuite(suite CryptoSuite, h func() hash.Hash) CryptoSuite {
+	if h == nil {
+		return suite
+	}
+	switch s := suite.(type) {
+	case *ECCryptSuite:
+		cp := *s
+		cp.hashFunction = h
+		return &cp
+	case *Ed25519CryptSuite:
+		cp := *s
+		cp.hashFunction = h
+		return &cp
+	default:
+		return suite
+	}
+}
@@ -0,0 +1,215 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/peer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NodeHealth is response of /healthz operations endpoint of peer or orderer (Fabric 1.4+)
+type NodeHealth struct {
+	// Status is OK for healthy node
+	Status       string        `json:"status"`
+	FailedChecks []FailedCheck `json:"failed_checks"`
+}
+
+// FailedCheck is single failed health check of node, like docker or couchdb
+type FailedCheck struct {
+	Component string `json:"component"`
+	Reason    string `json:"reason"`
+}
+
+// GetNodeHealth reads /healthz of peer or orderer operations endpoint. Unhealthy node is not error, it is
+// returned with failed checks. If client is nil http.DefaultClient is used.
+func GetNodeHealth(ctx context.Context, operationsUrl string, client *http.Client) (*NodeHealth, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(operationsUrl, "/")+"/healthz", nil)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	health := new(NodeHealth)
+	if err := json.NewDecoder(resp.Body).Decode(health); err != nil {
+		return nil, err
+	}
+	return health, nil
+}
+
+// PingPeer connects to peer or event peer and checks that it answers requests. Malformed proposal is send, so
+// peer only has to respond, any response of endorser service means peer is alive. When OperationsUrl of peer is
+// set, version and health are read from operations endpoint too.
+func (c *FabricClient) PingPeer(ctx context.Context, name string) *WarmUpResult {
+	p, ok := c.getPeer(name)
+	if !ok {
+		return &WarmUpResult{Name: name, Error: ErrPeerNameNotFound}
+	}
+	return pingPeer(ctx, p)
+}
+
+// PingOrderer connects to orderer and checks that it answers requests. Empty deliver request is send, so orderer
+// only has to reject it. When OperationsUrl of orderer is set, version and health are read from operations
+// endpoint too.
+func (c *FabricClient) PingOrderer(ctx context.Context, name string) *WarmUpResult {
+	o, ok := c.getOrderer(name)
+	if !ok {
+		return &WarmUpResult{Name: name, Orderer: true, Error: ErrInvalidOrdererName}
+	}
+	return pingOrderer(ctx, o)
+}
+
+// HealthCheck pings all peers, event peers and orderers, connected or not, same as WarmUp at most parallelism
+// at the same time. Report replaces report of WarmUp.
+func (c *FabricClient) HealthCheck(ctx context.Context, parallelism int) *WarmUpReport {
+	c.mu.RLock()
+	var jobs []func() *WarmUpResult
+	for _, p := range c.Peers {
+		p := p
+		jobs = append(jobs, func() *WarmUpResult { return pingPeer(ctx, p) })
+	}
+	for _, p := range c.EventPeers {
+		p := p
+		jobs = append(jobs, func() *WarmUpResult {
+			result := pingPeer(ctx, p)
+			result.EventPeer = true
+			return result
+		})
+	}
+	for _, o := range c.Orderers {
+		o := o
+		jobs = append(jobs, func() *WarmUpResult { return pingOrderer(ctx, o) })
+	}
+	c.mu.RUnlock()
+	return c.runWarmUp(jobs, parallelism)
+}
+
+func pingPeer(ctx context.Context, p *Peer) *WarmUpResult {
+	start := time.Now()
+	result := &WarmUpResult{Name: p.Name}
+	timeouts := effectiveTimeouts(ctx, p.Timeouts)
+	_, client, err := p.endorserConnection(ctx, timeouts.Dial)
+	if err != nil {
+		result.Duration, result.Error = time.Since(start), err
+		return result
+	}
+	probeCtx, cancel := withTimeout(ctx, timeouts.Endorsement)
+	_, err = client.ProcessProposal(probeCtx, &peer.SignedProposal{})
+	cancel()
+	result.Duration, result.Error = time.Since(start), pingError(p.Name, err)
+	if result.Error == nil {
+		result.checkVersion(ctx, p.OperationsUrl)
+		result.checkHealth(ctx, p.OperationsUrl)
+	}
+	return result
+}
+
+func pingOrderer(ctx context.Context, o *Orderer) *WarmUpResult {
+	start := time.Now()
+	result := &WarmUpResult{Name: o.Name, Orderer: true}
+	timeouts := effectiveTimeouts(ctx, o.Timeouts)
+	client, err := o.broadcastClient(ctx, timeouts.Dial)
+	if err != nil {
+		result.Duration, result.Error = time.Since(start), err
+		return result
+	}
+	probeCtx, cancel := withTimeout(ctx, timeouts.Broadcast)
+	defer cancel()
+	stream, err := client.Deliver(probeCtx)
+	if err == nil {
+		if err = stream.Send(&common.Envelope{}); err == nil {
+			_, err = stream.Recv()
+		}
+		stream.CloseSend()
+	}
+	result.Duration, result.Error = time.Since(start), pingError(o.Name, err)
+	if result.Error == nil {
+		result.checkVersion(ctx, o.OperationsUrl)
+		result.checkHealth(ctx, o.OperationsUrl)
+	}
+	return result
+}
+
+// pingError returns nil when node responded, even with error about malformed request
+func pingError(node string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled, codes.Unimplemented:
+			return rpcError(node, err)
+		}
+		return nil
+	}
+	return rpcError(node, err)
+}
+
+// checkHealth reads node health from operations endpoint. Unreachable endpoint is only logged, failed checks are
+// error of result.
+func (r *WarmUpResult) checkHealth(ctx context.Context, operationsUrl string) {
+	if operationsUrl == "" {
+		return
+	}
+	health, err := GetNodeHealth(ctx, operationsUrl, nil)
+	if err != nil {
+		logger().Debug("cannot read node health", "node", r.Name, "error", err)
+		return
+	}
+	r.Health = health
+	if health.Status != "OK" {
+		r.Error = &UnhealthyError{Node: r.Name, Health: health}
+		logger().Warn("node is not healthy", "node", r.Name, "status", health.Status,
+			"failedChecks", len(health.FailedChecks))
+	}
+}
+
+// getPeer returns peer or event peer by name
+func (c *FabricClient) getPeer(name string) (*Peer, bool) {
+	if p := c.getPeers([]string{name}); len(p) == 1 {
+		return p[0], true
+	}
+	return c.getEventPeer(name)
+}
+
+// Close stops shared listeners of RegisterTxStatusEvent and closes connections of all peers and orderers. Listeners
+// and streams created with context are not affected, they end when their context is canceled.
+func (c *FabricClient) Close() {
+	c.txMu.Lock()
+	c.closed = true
+	if c.txListenersCancel != nil {
+		c.txListenersCancel()
+	}
+	c.txListeners = nil
+	c.txMu.Unlock()
+	c.closeConnections()
+}
+
+// closeConnections closes connections of all peers and orderers
+func (c *FabricClient) closeConnections() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, peers := range []map[string]*Peer{c.Peers, c.EventPeers} {
+		for _, p := range peers {
+			p.closeConnection()
+		}
+	}
+	for _, o := range c.Orderers {
+		o.closeConnection()
+	}
+}
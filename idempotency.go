@@ -0,0 +1,82 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+// txNotFoundMessage matches messages of peers about transaction id missing in ledger index
+var txNotFoundMessage = regexp.MustCompile(`no such transaction ID|transaction \S+ not found`)
+
+// EnsureCommitted checks whether transaction txId is on ledger of channel. Peers are asked until one of them has
+// the transaction, so peer that is behind does not decide the result. When transaction is found result is returned
+// with its validation code and chaincode response payload, use InvokeResult.Err to check it is valid.
+// ErrTxNotFound is returned when no peer has the transaction, then it is safe to submit it again. Any other error
+// means status is unknown.
+func (c *FabricClient) EnsureCommitted(identity Identity, channelId, txId string, peers []string) (*InvokeResult, error) {
+	peers, _ = c.applyDefaults(peers, "", false)
+	if len(peers) == 0 {
+		return nil, ErrPeerNameNotFound
+	}
+	var first error
+	for _, name := range peers {
+		r, err := c.QueryTransaction(identity, channelId, txId, []string{name})
+		if err == nil {
+			err = r[0].Error
+		}
+		if err == nil {
+			result := &InvokeResult{TxID: txId, Status: common.Status_SUCCESS, ValidationCode: r[0].ValidationCode}
+			if tx := r[0].Transaction; tx != nil && len(tx.Actions) > 0 && tx.Actions[0].Response != nil {
+				result.Payload = tx.Actions[0].Response.Payload
+			}
+			return result, nil
+		}
+		if txNotFoundMessage.MatchString(err.Error()) {
+			continue
+		}
+		logger().Debug("cannot query transaction", "txId", txId, "peer", name, "error", err)
+		if first == nil {
+			first = err
+		}
+	}
+	if first != nil {
+		return nil, first
+	}
+	return nil, ErrTxNotFound
+}
+
+type commitCheckKey struct{}
+
+// WithCommitCheck makes invokes with transaction id set by WithTxID check whether transaction is already on ledger
+// before it is endorsed and send to orderer. Committed transaction is not send again, InvokeResponse.Committed
+// holds its status and InvokeAsync returns it as result. Use it to retry safely after client crashed without
+// knowing whether transaction was submitted. Transaction that is send to orderer but not committed yet is not
+// found, Fabric then rejects the second copy as DUPLICATE_TXID.
+func WithCommitCheck(ctx context.Context) context.Context {
+	return context.WithValue(ctx, commitCheckKey{}, true)
+}
+
+// checkCommitted returns response of transaction already on ledger when commit check is requested in ctx, nil when
+// transaction must be send
+func (c *FabricClient) checkCommitted(ctx context.Context, identity Identity, channelId string, peers []string) (*InvokeResponse, error) {
+	txId := txIdFromContext(ctx)
+	if check, _ := ctx.Value(commitCheckKey{}).(bool); !check || txId == nil {
+		return nil, nil
+	}
+	result, err := c.EnsureCommitted(identity, channelId, txId.TransactionId, peers)
+	if err == ErrTxNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	logger().Info("transaction is already committed, not sending again", "txId", txId.TransactionId,
+		"channel", channelId, "validationCode", result.ValidationCode)
+	return &InvokeResponse{Status: result.Status, TxID: result.TxID, Payload: result.Payload, Committed: result}, nil
+}
@@ -6,11 +6,14 @@ package gohfc
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
 	"io/ioutil"
+
+	"github.com/CognitionFoundry/gohfc/gm"
 )
 
 // Identity is participant public and private key
@@ -18,6 +21,16 @@ type Identity struct {
 	Certificate *x509.Certificate
 	PrivateKey  interface{}
 	MspId       string
+	// Crypto is optional crypto suite used to sign requests made by this identity. If it is nil FabricClient crypto
+	// suite is used. This allows identities from organizations using different crypto to be used in same client.
+	Crypto CryptoSuite
+}
+
+// ReadOnly returns true when identity has no private key. Read only identities cannot sign proposals or transactions,
+// they can only be used to listen for blocks on channels which policies allow it. Certificate can also be nil, in
+// this case deliver requests are send without creator.
+func (i *Identity) ReadOnly() bool {
+	return i.PrivateKey == nil
 }
 
 // EnrollmentId get enrollment id from certificate
@@ -38,6 +51,23 @@ func (i *Identity) ToPem() ([]byte, []byte, error) {
 		privateKey := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b})
 		cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: i.Certificate.Raw})
 		return cert, privateKey, nil
+	case *gm.PrivateKey:
+		b, err := gm.MarshalECPrivateKey(i.PrivateKey.(*gm.PrivateKey))
+		if err != nil {
+			return nil, nil, err
+		}
+		privateKey := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b})
+		cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: i.Certificate.Raw})
+		return cert, privateKey, nil
+	case ed25519.PrivateKey, *ed25519.PrivateKey:
+		key, _ := ed25519Key(i.PrivateKey)
+		b, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		privateKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: b})
+		cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: i.Certificate.Raw})
+		return cert, privateKey, nil
 
 	default:
 		return nil, nil, ErrInvalidKeyType
@@ -57,6 +87,21 @@ func MarshalIdentity(i *Identity) (string, error) {
 		}
 		block := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b})
 		pk = base64.RawStdEncoding.EncodeToString(block)
+	case *gm.PrivateKey:
+		b, err := gm.MarshalECPrivateKey(i.PrivateKey.(*gm.PrivateKey))
+		if err != nil {
+			return "", err
+		}
+		block := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b})
+		pk = base64.RawStdEncoding.EncodeToString(block)
+	case ed25519.PrivateKey, *ed25519.PrivateKey:
+		key, _ := ed25519Key(i.PrivateKey)
+		b, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return "", err
+		}
+		block := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: b})
+		pk = base64.RawStdEncoding.EncodeToString(block)
 
 	default:
 		return "", ErrInvalidKeyType
@@ -89,7 +134,7 @@ func UnmarshalIdentity(data string) (*Identity, error) {
 	if err != nil {
 		return nil, err
 	}
-	cert, err := x509.ParseCertificate(certRaw)
+	cert, err := parseCertificate(certRaw)
 	if err != nil {
 		return nil, err
 	}
@@ -105,7 +150,12 @@ func UnmarshalIdentity(data string) (*Identity, error) {
 	var pk interface{}
 	switch keyPem.Type {
 	case "EC PRIVATE KEY":
-		pk, err = x509.ParseECPrivateKey(keyPem.Bytes)
+		pk, err = parseECPrivateKey(keyPem.Bytes)
+		if err != nil {
+			return nil, ErrInvalidDataForParcelIdentity
+		}
+	case "PRIVATE KEY":
+		pk, err = parsePKCS8PrivateKey(keyPem.Bytes)
 		if err != nil {
 			return nil, ErrInvalidDataForParcelIdentity
 		}
@@ -131,11 +181,11 @@ func LoadCertFromFile(pk, sk string) (*Identity, error) {
 	}
 	cpb, _ := pem.Decode(cf)
 	kpb, _ := pem.Decode(kf)
-	crt, err := x509.ParseCertificate(cpb.Bytes)
+	crt, err := parseCertificate(cpb.Bytes)
 	if err != nil {
 		return nil, err
 	}
-	key, err := x509.ParsePKCS8PrivateKey(kpb.Bytes)
+	key, err := parsePKCS8PrivateKey(kpb.Bytes)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,271 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"hash"
+
+	"github.com/CognitionFoundry/gohfc/gm"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/pkcs12"
+)
+
+// LoadIdentityFromPEM returns identity from PEM encoded certificate and private key, for example read from secret
+// manager. Key can be PKCS #8 or SEC 1 (EC PRIVATE KEY). Encrypted keys return ErrKeyEncrypted, use
+// LoadIdentityFromEncryptedPEM for them. MspId of identity is not set.
+func LoadIdentityFromPEM(certPEM, keyPEM []byte) (*Identity, error) {
+	return LoadIdentityFromEncryptedPEM(certPEM, keyPEM, nil)
+}
+
+// LoadIdentityFromEncryptedPEM is same as LoadIdentityFromPEM, but private key can be encrypted with password:
+// PKCS #8 ENCRYPTED PRIVATE KEY with PBES2 (PBKDF2 with AES-CBC or 3DES), as created by
+// `openssl pkcs8 -topk8 -v2 aes256`, or legacy PEM encryption with DEK-Info header.
+func LoadIdentityFromEncryptedPEM(certPEM, keyPEM, password []byte) (*Identity, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, ErrCertificateMissing
+	}
+	cert, err := parseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, ErrInvalidKeyType
+	}
+	key, err := parsePrivateKeyBlock(keyBlock, password)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{Certificate: cert, PrivateKey: key}, nil
+}
+
+// LoadIdentityFromPKCS12 returns identity from PKCS #12 (.p12, .pfx) bundle with private key and certificate.
+// When bundle has more certificates, the one that matches private key is used. MspId of identity is not set.
+// Bundles with PBES2 encryption (AES with PBKDF2 and SHA-2 MAC, default of OpenSSL 3) and with legacy encryption
+// (3DES or RC2 with SHA-1 MAC, `openssl pkcs12 -export -legacy` or Java keytool) are supported.
+func LoadIdentityFromPKCS12(data []byte, password string) (*Identity, error) {
+	key, certs, err := decodePKCS12(data, password)
+	if err == ErrUnsupportedKeyEncryption {
+		key, certs, err = decodeLegacyPKCS12(data, password)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrInvalidKeyType
+	}
+	if len(certs) == 0 {
+		return nil, ErrCertificateMissing
+	}
+	identity := &Identity{Certificate: certs[0], PrivateKey: key}
+	for _, cert := range certs {
+		if keyMatchesCertificate(key, cert) {
+			identity.Certificate = cert
+			break
+		}
+	}
+	return identity, nil
+}
+
+// decodeLegacyPKCS12 returns private key and certificates of PKCS #12 bundle with legacy encryption
+func decodeLegacyPKCS12(data []byte, password string) (interface{}, []*x509.Certificate, error) {
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	var key interface{}
+	var certs []*x509.Certificate
+	for _, b := range blocks {
+		switch b.Type {
+		case "PRIVATE KEY":
+			// pkcs12 returns EC keys in SEC 1 form under PRIVATE KEY type
+			if key, err = parseECPrivateKey(b.Bytes); err != nil {
+				if key, err = parsePKCS8PrivateKey(b.Bytes); err != nil {
+					return nil, nil, err
+				}
+			}
+		case "CERTIFICATE":
+			cert, err := parseCertificate(b.Bytes)
+			if err != nil {
+				return nil, nil, err
+			}
+			certs = append(certs, cert)
+		}
+	}
+	return key, certs, nil
+}
+
+// parsePrivateKeyBlock parses plain or encrypted private key PEM block
+func parsePrivateKeyBlock(block *pem.Block, password []byte) (interface{}, error) {
+	der := block.Bytes
+	if block.Type == "ENCRYPTED PRIVATE KEY" || x509.IsEncryptedPEMBlock(block) {
+		if len(password) == 0 {
+			return nil, ErrKeyEncrypted
+		}
+		var err error
+		if block.Type == "ENCRYPTED PRIVATE KEY" {
+			der, err = decryptPKCS8(der, password)
+		} else {
+			der, err = x509.DecryptPEMBlock(block, password)
+			if err == x509.IncorrectPasswordError {
+				err = ErrKeyDecryption
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		return parseECPrivateKey(der)
+	case "PRIVATE KEY", "ENCRYPTED PRIVATE KEY":
+		key, err := parsePKCS8PrivateKey(der)
+		if err != nil && block.Type == "ENCRYPTED PRIVATE KEY" {
+			return nil, ErrKeyDecryption
+		}
+		return key, err
+	}
+	return nil, ErrInvalidKeyType
+}
+
+// keyMatchesCertificate returns true when public key of certificate belongs to private key
+func keyMatchesCertificate(key interface{}, cert *x509.Certificate) bool {
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		pub, ok := cert.PublicKey.(*ecdsa.PublicKey)
+		return ok && pub.X.Cmp(k.X) == 0 && pub.Y.Cmp(k.Y) == 0
+	case *gm.PrivateKey:
+		pub, ok := cert.PublicKey.(*gm.PublicKey)
+		return ok && pub.X.Cmp(k.X) == 0 && pub.Y.Cmp(k.Y) == 0
+	case ed25519.PrivateKey:
+		pub, ok := cert.PublicKey.(ed25519.PublicKey)
+		return ok && bytes.Equal(pub, k.Public().(ed25519.PublicKey))
+	}
+	return false
+}
+
+var (
+	oidPBES2      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// maxKdfIterations bounds iteration count of password based key derivation, so crafted key or bundle cannot keep
+// loading busy for hours
+const maxKdfIterations = 10000000
+
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	PRF        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// decryptPKCS8 decrypts EncryptedPrivateKeyInfo encrypted with PBES2 and returns PKCS #8 private key
+func decryptPKCS8(der, password []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, err
+	}
+	return decryptPBES2(info.Algorithm, info.EncryptedData, password)
+}
+
+// decryptPBES2 decrypts data encrypted with PBES2 algorithm
+func decryptPBES2(algorithm pkix.AlgorithmIdentifier, data, password []byte) ([]byte, error) {
+	if !algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, ErrUnsupportedKeyEncryption
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, err
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, ErrUnsupportedKeyEncryption
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, err
+	}
+	if kdf.Iterations <= 0 || kdf.Iterations > maxKdfIterations {
+		return nil, ErrKdfIterations
+	}
+	var prf func() hash.Hash
+	switch {
+	case len(kdf.PRF.Algorithm) == 0, kdf.PRF.Algorithm.Equal(oidHMACSHA1):
+		prf = sha1.New
+	case kdf.PRF.Algorithm.Equal(oidHMACSHA256):
+		prf = sha256.New
+	case kdf.PRF.Algorithm.Equal(oidHMACSHA384):
+		prf = sha512.New384
+	case kdf.PRF.Algorithm.Equal(oidHMACSHA512):
+		prf = sha512.New
+	default:
+		return nil, ErrUnsupportedKeyEncryption
+	}
+	var keyLength int
+	var newCipher func([]byte) (cipher.Block, error)
+	switch scheme := params.EncryptionScheme.Algorithm; {
+	case scheme.Equal(oidAES128CBC):
+		keyLength, newCipher = 16, aes.NewCipher
+	case scheme.Equal(oidAES192CBC):
+		keyLength, newCipher = 24, aes.NewCipher
+	case scheme.Equal(oidAES256CBC):
+		keyLength, newCipher = 32, aes.NewCipher
+	case scheme.Equal(oidDESEDE3CBC):
+		keyLength, newCipher = 24, des.NewTripleDESCipher
+	default:
+		return nil, ErrUnsupportedKeyEncryption
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, err
+	}
+	block, err := newCipher(pbkdf2.Key(password, kdf.Salt, kdf.Iterations, keyLength, prf))
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() || len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return nil, ErrKeyDecryption
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > block.BlockSize() ||
+		!bytes.Equal(plain[len(plain)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, ErrKeyDecryption
+	}
+	return plain[:len(plain)-padding], nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+// ExportedMessage is signed proposal or transaction envelope in form that can be compared with the output of other
+// SDKs (fabric-sdk-node, fabric-sdk-java). Binary fields are base64 encoded in JSON, digests are hex encoded SHA2-256
+// of the exact bytes that were signed. Other SDK can verify Signature over Payload using Creator certificate.
+//
+//	{
+//	  "type": "proposal" | "transaction",
+//	  "txId": "...",
+//	  "channelId": "...",
+//	  "headerType": "ENDORSER_TRANSACTION",
+//	  "creator": {"mspId": "...", "idBytes": "<PEM>"},
+//	  "nonce": "<base64>",
+//	  "payload": "<base64 signed bytes>",
+//	  "payloadDigest": "<hex sha256 of payload>",
+//	  "signature": "<base64 DER ECDSA signature>"
+//	}
+type ExportedMessage struct {
+	Type          string          `json:"type"`
+	TxId          string          `json:"txId"`
+	ChannelId     string          `json:"channelId"`
+	HeaderType    string          `json:"headerType"`
+	Creator       ExportedCreator `json:"creator"`
+	Nonce         []byte          `json:"nonce"`
+	Payload       []byte          `json:"payload"`
+	PayloadDigest string          `json:"payloadDigest"`
+	Signature     []byte          `json:"signature"`
+}
+
+// ExportedCreator is the creator of exported message
+type ExportedCreator struct {
+	MspId   string `json:"mspId"`
+	IdBytes string `json:"idBytes"`
+}
+
+// ToJSON encodes exported message as JSON
+func (e *ExportedMessage) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(e, "", "  ")
+}
+
+// ExportSignedProposal converts signed proposal to ExportedMessage
+func ExportSignedProposal(sp *peer.SignedProposal) (*ExportedMessage, error) {
+	prop := new(peer.Proposal)
+	if err := proto.Unmarshal(sp.ProposalBytes, prop); err != nil {
+		return nil, err
+	}
+	hdr := new(common.Header)
+	if err := proto.Unmarshal(prop.Header, hdr); err != nil {
+		return nil, err
+	}
+	e := &ExportedMessage{Type: "proposal", Payload: sp.ProposalBytes, Signature: sp.Signature}
+	if err := e.fillHeader(hdr); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ExportEnvelope converts signed transaction envelope to ExportedMessage
+func ExportEnvelope(env *common.Envelope) (*ExportedMessage, error) {
+	payload := new(common.Payload)
+	if err := proto.Unmarshal(env.Payload, payload); err != nil {
+		return nil, err
+	}
+	if payload.Header == nil {
+		return nil, fmt.Errorf("payload header is missing")
+	}
+	e := &ExportedMessage{Type: "transaction", Payload: env.Payload, Signature: env.Signature}
+	if err := e.fillHeader(payload.Header); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// CreateSignedProposal creates and signs proposal for chainCode invocation without sending it to peers.
+// Together with ExportSignedProposal it can be used to compare proposals with other SDKs.
+func (c *FabricClient) CreateSignedProposal(identity Identity, chainCode ChainCode) (*peer.SignedProposal, error) {
+	prop, err := createTransactionProposal(identity, chainCode, c.txOptions(chainCode.ChannelId))
+	if err != nil {
+		return nil, err
+	}
+	return signedProposal(prop.proposal, identity, c.cryptoSuite(identity, chainCode.ChannelId))
+}
+
+func (e *ExportedMessage) fillHeader(hdr *common.Header) error {
+	chHeader := new(common.ChannelHeader)
+	if err := proto.Unmarshal(hdr.ChannelHeader, chHeader); err != nil {
+		return err
+	}
+	sigHeader := new(common.SignatureHeader)
+	if err := proto.Unmarshal(hdr.SignatureHeader, sigHeader); err != nil {
+		return err
+	}
+	creator := new(msp.SerializedIdentity)
+	if err := proto.Unmarshal(sigHeader.Creator, creator); err != nil {
+		return err
+	}
+	digest := sha256.Sum256(e.Payload)
+	e.TxId = chHeader.TxId
+	e.ChannelId = chHeader.ChannelId
+	e.HeaderType = common.HeaderType_name[chHeader.Type]
+	e.Creator = ExportedCreator{MspId: creator.Mspid, IdBytes: string(creator.IdBytes)}
+	e.Nonce = sigHeader.Nonce
+	e.PayloadDigest = hex.EncodeToString(digest[:])
+	return nil
+}
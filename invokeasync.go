@@ -0,0 +1,155 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+// InvokeResult is the final result of InvokeAsync
+type InvokeResult struct {
+	TxID string
+	// Status is the status returned from orderer
+	Status common.Status
+	// ValidationCode is the validation code of committed transaction like VALID or MVCC_READ_CONFLICT
+	ValidationCode string
+	BlockHeight    uint64
+	// Error is set when invoke fails or commit status cannot be received
+	Error error
+	// Attempts is how many times transaction was submitted, more than 1 when WithMVCCRetry is used
+	Attempts int
+	// Payload is chaincode response payload returned by endorsing peers
+	Payload []byte
+	// Responses are chaincode responses and endorsements of every endorsing peer, empty when transaction is
+	// endorsed by gateway service
+	Responses []ChaincodeResponse
+}
+
+// Valid returns true when transaction is committed as valid
+func (r *InvokeResult) Valid() bool {
+	return r.Error == nil && r.ValidationCode == "VALID"
+}
+
+// Err returns Error, or *CommitError when transaction is committed as invalid
+func (r *InvokeResult) Err() error {
+	if r.Error != nil {
+		return r.Error
+	}
+	if r.ValidationCode != "VALID" {
+		return &CommitError{TxId: r.TxID, ValidationCode: r.ValidationCode}
+	}
+	return nil
+}
+
+// InvokeFuture is handle for transaction started with InvokeAsync
+type InvokeFuture struct {
+	done   chan struct{}
+	result *InvokeResult
+}
+
+// Done is closed when result is available
+func (f *InvokeFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Result waits for transaction to be committed (or to fail) and returns the result
+func (f *InvokeFuture) Result() *InvokeResult {
+	<-f.done
+	return f.result
+}
+
+// Wait waits for result until ctx is done
+func (f *InvokeFuture) Wait(ctx context.Context) (*InvokeResult, error) {
+	select {
+	case <-f.done:
+		return f.result, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// InvokeAsync execute chainCode same as Invoke, but does not block. Returned future resolves when transaction is
+// committed and validation code is received from eventPeer, or when any step fails.
+// Commit status is received from listener shared with RegisterTxStatusEvent, so many transactions can be pipelined
+// with single connection to eventPeer. Waiting for commit stops when ctx is done.
+// Transactions that fail with read conflict are resubmitted when ctx is created with WithMVCCRetry, and
+// transactions that are not committed in time when ctx is created with WithTxTTL. In this case TxID of the result
+// is the id of the last attempt.
+func (c *FabricClient) InvokeAsync(ctx context.Context, identity Identity, chainCode ChainCode, peers []string, orderer string,
+	eventPeer string) *InvokeFuture {
+	f := &InvokeFuture{done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		f.result = c.invokeWithRetry(ctx, identity, chainCode, peers, orderer, eventPeer)
+	}()
+	return f
+}
+
+func (c *FabricClient) invokeAndWait(ctx context.Context, identity Identity, chainCode ChainCode, peers []string, orderer string,
+	eventPeer string, ttl TxTTL) *InvokeResult {
+	// listener must be running before transaction is send, so commit is not missed
+	l, err := c.txStatusListener(identity, eventPeer, chainCode.ChannelId)
+	if err != nil {
+		return &InvokeResult{Error: err}
+	}
+	start := time.Now()
+	resp, err := c.InvokeWithContext(ctx, identity, chainCode, peers, orderer)
+	if err != nil {
+		return &InvokeResult{Error: err}
+	}
+	if resp.Committed != nil {
+		return resp.Committed
+	}
+	result := &InvokeResult{TxID: resp.TxID, Status: resp.Status, Payload: resp.Payload, Responses: resp.Responses}
+	_, span := startSpan(ctx, "gohfc.CommitWait", "txId", resp.TxID, "channel", chainCode.ChannelId)
+	defer func() {
+		span.SetAttributes("validationCode", result.ValidationCode)
+		endSpan(span, result.Err())
+	}()
+	var expired <-chan time.Time
+	if ttl.TTL > 0 {
+		t := time.NewTimer(ttl.TTL)
+		defer t.Stop()
+		expired = t.C
+	}
+	var commitTimeout <-chan time.Time
+	if wait := c.commitWait(ctx, eventPeer); wait > 0 {
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		commitTimeout = t.C
+	}
+	registration := l.Register(resp.TxID)
+	select {
+	case ev := <-registration:
+		result.ValidationCode = ev.ValidationCode
+		result.BlockHeight = ev.BlockHeight
+		result.Error = ev.Error
+		if ev.Error == nil {
+			metrics().Commit(chainCode.ChannelId, time.Since(start), ev.ValidationCode)
+		}
+	case <-expired:
+		l.Unregister(resp.TxID, registration)
+		result.Error = &TxExpiredError{TxId: resp.TxID, TTL: ttl.TTL}
+	case <-commitTimeout:
+		l.Unregister(resp.TxID, registration)
+		result.Error = &TimeoutError{Node: eventPeer, Err: ErrCommitTimeout}
+	case <-ctx.Done():
+		l.Unregister(resp.TxID, registration)
+		result.Error = ctx.Err()
+	}
+	return result
+}
+
+// commitWait returns CommitWait timeout of event peer overridden by ctx
+func (c *FabricClient) commitWait(ctx context.Context, eventPeer string) time.Duration {
+	var timeouts Timeouts
+	if ep, ok := c.getEventPeer(eventPeer); ok {
+		timeouts = ep.Timeouts
+	}
+	return effectiveTimeouts(ctx, timeouts).CommitWait
+}
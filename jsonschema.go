@@ -0,0 +1,292 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// jsonSchema is subset of JSON Schema (draft 7) used to validate chaincode event payloads: type, enum, const,
+// properties, required, additionalProperties, items, numeric and length limits, pattern, allOf, anyOf and oneOf.
+// References ($ref) are not supported.
+type jsonSchema struct {
+	Types                []string
+	Enum                 []interface{}
+	Const                interface{}
+	HasConst             bool
+	Properties           map[string]*jsonSchema
+	Required             []string
+	AdditionalProperties *jsonSchema
+	NoAdditional         bool
+	Items                *jsonSchema
+	Minimum, Maximum     *float64
+	MinLength, MaxLength *int
+	MinItems, MaxItems   *int
+	Pattern              *regexp.Regexp
+	AllOf, AnyOf, OneOf  []*jsonSchema
+}
+
+func parseJSONSchema(data []byte) (*jsonSchema, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	return compileJSONSchema(raw)
+}
+
+func compileJSONSchema(raw interface{}) (*jsonSchema, error) {
+	s := new(jsonSchema)
+	if b, ok := raw.(bool); ok {
+		if !b {
+			// false schema matches nothing
+			s.AnyOf = []*jsonSchema{}
+		}
+		return s, nil
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema must be object or boolean")
+	}
+	if _, ok := m["$ref"]; ok {
+		return nil, fmt.Errorf("$ref is not supported")
+	}
+	var err error
+	switch t := m["type"].(type) {
+	case string:
+		s.Types = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			name, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("type must be string")
+			}
+			s.Types = append(s.Types, name)
+		}
+	}
+	if e, ok := m["enum"].([]interface{}); ok {
+		s.Enum = e
+	}
+	s.Const, s.HasConst = m["const"]
+	if props, ok := m["properties"].(map[string]interface{}); ok {
+		s.Properties = make(map[string]*jsonSchema)
+		for name, p := range props {
+			if s.Properties[name], err = compileJSONSchema(p); err != nil {
+				return nil, fmt.Errorf("properties/%s: %v", name, err)
+			}
+		}
+	}
+	if req, ok := m["required"].([]interface{}); ok {
+		for _, r := range req {
+			if name, ok := r.(string); ok {
+				s.Required = append(s.Required, name)
+			}
+		}
+	}
+	switch a := m["additionalProperties"].(type) {
+	case bool:
+		s.NoAdditional = !a
+	case map[string]interface{}:
+		if s.AdditionalProperties, err = compileJSONSchema(a); err != nil {
+			return nil, fmt.Errorf("additionalProperties: %v", err)
+		}
+	}
+	if items, ok := m["items"]; ok {
+		if s.Items, err = compileJSONSchema(items); err != nil {
+			return nil, fmt.Errorf("items: %v", err)
+		}
+	}
+	s.Minimum, s.Maximum = schemaNumber(m, "minimum"), schemaNumber(m, "maximum")
+	s.MinLength, s.MaxLength = schemaInt(m, "minLength"), schemaInt(m, "maxLength")
+	s.MinItems, s.MaxItems = schemaInt(m, "minItems"), schemaInt(m, "maxItems")
+	if p, ok := m["pattern"].(string); ok {
+		if s.Pattern, err = regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("pattern: %v", err)
+		}
+	}
+	for _, kw := range []string{"allOf", "anyOf", "oneOf"} {
+		list, ok := m[kw].([]interface{})
+		if !ok {
+			continue
+		}
+		compiled := make([]*jsonSchema, 0, len(list))
+		for i, sub := range list {
+			c, err := compileJSONSchema(sub)
+			if err != nil {
+				return nil, fmt.Errorf("%s/%d: %v", kw, i, err)
+			}
+			compiled = append(compiled, c)
+		}
+		switch kw {
+		case "allOf":
+			s.AllOf = compiled
+		case "anyOf":
+			s.AnyOf = compiled
+		case "oneOf":
+			s.OneOf = compiled
+		}
+	}
+	return s, nil
+}
+
+func schemaNumber(m map[string]interface{}, key string) *float64 {
+	if f, ok := m[key].(float64); ok {
+		return &f
+	}
+	return nil
+}
+
+func schemaInt(m map[string]interface{}, key string) *int {
+	if f, ok := m[key].(float64); ok {
+		i := int(f)
+		return &i
+	}
+	return nil
+}
+
+// validate returns JSON pointer of invalid value and reason when v does not match schema
+func (s *jsonSchema) validate(v interface{}, path string) (string, string, bool) {
+	if len(s.Types) > 0 && !s.matchesType(v) {
+		return path, fmt.Sprintf("expected %s, got %s", strings.Join(s.Types, " or "), jsonTypeName(v)), false
+	}
+	if s.HasConst && !reflect.DeepEqual(v, s.Const) {
+		return path, "value does not match const", false
+	}
+	if s.Enum != nil {
+		found := false
+		for _, e := range s.Enum {
+			if reflect.DeepEqual(v, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return path, "value is not in enum", false
+		}
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for _, r := range s.Required {
+			if _, ok := val[r]; !ok {
+				return path, fmt.Sprintf("missing required property %s", r), false
+			}
+		}
+		for name, pv := range val {
+			p := path + "/" + name
+			if ps, ok := s.Properties[name]; ok {
+				if ip, reason, ok := ps.validate(pv, p); !ok {
+					return ip, reason, false
+				}
+				continue
+			}
+			if s.NoAdditional {
+				return p, "additional property is not allowed", false
+			}
+			if s.AdditionalProperties != nil {
+				if ip, reason, ok := s.AdditionalProperties.validate(pv, p); !ok {
+					return ip, reason, false
+				}
+			}
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(val) < *s.MinItems {
+			return path, fmt.Sprintf("less than %d items", *s.MinItems), false
+		}
+		if s.MaxItems != nil && len(val) > *s.MaxItems {
+			return path, fmt.Sprintf("more than %d items", *s.MaxItems), false
+		}
+		if s.Items != nil {
+			for i, item := range val {
+				if ip, reason, ok := s.Items.validate(item, path+"/"+strconv.Itoa(i)); !ok {
+					return ip, reason, false
+				}
+			}
+		}
+	case string:
+		n := len([]rune(val))
+		if s.MinLength != nil && n < *s.MinLength {
+			return path, fmt.Sprintf("shorter than %d characters", *s.MinLength), false
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return path, fmt.Sprintf("longer than %d characters", *s.MaxLength), false
+		}
+		if s.Pattern != nil && !s.Pattern.MatchString(val) {
+			return path, fmt.Sprintf("does not match pattern %s", s.Pattern), false
+		}
+	case float64:
+		if s.Minimum != nil && val < *s.Minimum {
+			return path, fmt.Sprintf("less than minimum %g", *s.Minimum), false
+		}
+		if s.Maximum != nil && val > *s.Maximum {
+			return path, fmt.Sprintf("greater than maximum %g", *s.Maximum), false
+		}
+	}
+	for _, sub := range s.AllOf {
+		if ip, reason, ok := sub.validate(v, path); !ok {
+			return ip, reason, false
+		}
+	}
+	if s.AnyOf != nil {
+		matched := false
+		for _, sub := range s.AnyOf {
+			if _, _, ok := sub.validate(v, path); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return path, "value does not match any schema of anyOf", false
+		}
+	}
+	if s.OneOf != nil {
+		matched := 0
+		for _, sub := range s.OneOf {
+			if _, _, ok := sub.validate(v, path); ok {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return path, fmt.Sprintf("value matches %d schemas of oneOf", matched), false
+		}
+	}
+	return "", "", true
+}
+
+func (s *jsonSchema) matchesType(v interface{}) bool {
+	name := jsonTypeName(v)
+	for _, t := range s.Types {
+		if t == name || (t == "number" && name == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonTypeName(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if val == math.Trunc(val) && !math.IsInf(val, 0) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Messages of Fabric 2.x _lifecycle queries, they are not part of vendored protos
+
+type queryInstalledChaincodesArgs struct{}
+
+func (m *queryInstalledChaincodesArgs) Reset()         { *m = queryInstalledChaincodesArgs{} }
+func (m *queryInstalledChaincodesArgs) String() string { return proto.CompactTextString(m) }
+func (*queryInstalledChaincodesArgs) ProtoMessage()    {}
+
+type queryInstalledChaincodesResult struct {
+	InstalledChaincodes []*lifecycleInstalledChaincode `protobuf:"bytes,1,rep,name=installed_chaincodes,json=installedChaincodes" json:"installed_chaincodes,omitempty"`
+}
+
+func (m *queryInstalledChaincodesResult) Reset()         { *m = queryInstalledChaincodesResult{} }
+func (m *queryInstalledChaincodesResult) String() string { return proto.CompactTextString(m) }
+func (*queryInstalledChaincodesResult) ProtoMessage()    {}
+
+type lifecycleInstalledChaincode struct {
+	PackageId  string                          `protobuf:"bytes,1,opt,name=package_id,json=packageId" json:"package_id,omitempty"`
+	Label      string                          `protobuf:"bytes,2,opt,name=label" json:"label,omitempty"`
+	References map[string]*lifecycleReferences `protobuf:"bytes,3,rep,name=references" json:"references,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+}
+
+func (m *lifecycleInstalledChaincode) Reset()         { *m = lifecycleInstalledChaincode{} }
+func (m *lifecycleInstalledChaincode) String() string { return proto.CompactTextString(m) }
+func (*lifecycleInstalledChaincode) ProtoMessage()    {}
+
+type lifecycleReferences struct {
+	Chaincodes []*lifecycleChaincode `protobuf:"bytes,1,rep,name=chaincodes" json:"chaincodes,omitempty"`
+}
+
+func (m *lifecycleReferences) Reset()         { *m = lifecycleReferences{} }
+func (m *lifecycleReferences) String() string { return proto.CompactTextString(m) }
+func (*lifecycleReferences) ProtoMessage()    {}
+
+type lifecycleChaincode struct {
+	Name    string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Version string `protobuf:"bytes,2,opt,name=version" json:"version,omitempty"`
+}
+
+func (m *lifecycleChaincode) Reset()         { *m = lifecycleChaincode{} }
+func (m *lifecycleChaincode) String() string { return proto.CompactTextString(m) }
+func (*lifecycleChaincode) ProtoMessage()    {}
+
+type queryChaincodeDefinitionsArgs struct{}
+
+func (m *queryChaincodeDefinitionsArgs) Reset()         { *m = queryChaincodeDefinitionsArgs{} }
+func (m *queryChaincodeDefinitionsArgs) String() string { return proto.CompactTextString(m) }
+func (*queryChaincodeDefinitionsArgs) ProtoMessage()    {}
+
+type queryChaincodeDefinitionsResult struct {
+	ChaincodeDefinitions []*lifecycleDefinition `protobuf:"bytes,1,rep,name=chaincode_definitions,json=chaincodeDefinitions" json:"chaincode_definitions,omitempty"`
+}
+
+func (m *queryChaincodeDefinitionsResult) Reset()         { *m = queryChaincodeDefinitionsResult{} }
+func (m *queryChaincodeDefinitionsResult) String() string { return proto.CompactTextString(m) }
+func (*queryChaincodeDefinitionsResult) ProtoMessage()    {}
+
+type lifecycleDefinition struct {
+	Name                string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Sequence            int64  `protobuf:"varint,2,opt,name=sequence" json:"sequence,omitempty"`
+	Version             string `protobuf:"bytes,3,opt,name=version" json:"version,omitempty"`
+	EndorsementPlugin   string `protobuf:"bytes,4,opt,name=endorsement_plugin,json=endorsementPlugin" json:"endorsement_plugin,omitempty"`
+	ValidationPlugin    string `protobuf:"bytes,5,opt,name=validation_plugin,json=validationPlugin" json:"validation_plugin,omitempty"`
+	ValidationParameter []byte `protobuf:"bytes,6,opt,name=validation_parameter,json=validationParameter,proto3" json:"validation_parameter,omitempty"`
+	// Collections is marshaled common.CollectionConfigPackage
+	Collections  []byte `protobuf:"bytes,7,opt,name=collections,proto3" json:"collections,omitempty"`
+	InitRequired bool   `protobuf:"varint,8,opt,name=init_required,json=initRequired" json:"init_required,omitempty"`
+}
+
+func (m *lifecycleDefinition) Reset()         { *m = lifecycleDefinition{} }
+func (m *lifecycleDefinition) String() string { return proto.CompactTextString(m) }
+func (*lifecycleDefinition) ProtoMessage()    {}
+
+// LifecycleInstalledChaincode is chaincode package installed in peer with _lifecycle
+type LifecycleInstalledChaincode struct {
+	PackageId string
+	Label     string
+	// References are chaincode definitions using the package, by channel
+	References map[string][]*ChainCodeInfo
+}
+
+// LifecycleInstalledResponse holds chaincode packages installed in single peer
+type LifecycleInstalledResponse struct {
+	PeerName  string
+	Error     error
+	Installed []*LifecycleInstalledChaincode
+}
+
+// ChaincodeDefinition is chaincode definition committed in channel with _lifecycle
+type ChaincodeDefinition struct {
+	Name                string
+	Version             string
+	Sequence            int64
+	EndorsementPlugin   string
+	ValidationPlugin    string
+	ValidationParameter []byte
+	// Collections is marshaled common.CollectionConfigPackage, empty when chaincode has no private data collections
+	Collections  []byte
+	InitRequired bool
+}
+
+// ChaincodeDefinitionsResponse holds chaincode definitions of channel returned from single peer
+type ChaincodeDefinitionsResponse struct {
+	PeerName    string
+	Error       error
+	Definitions []*ChaincodeDefinition
+}
+
+// QueryInstalledChaincodes gets chaincode packages installed with _lifecycle of Fabric 2.x in peers, with labels,
+// package ids and chaincode definitions that use them. Chaincodes installed with LSCC are returned by
+// QueryInstalledChainCodes. Identity must be admin of peer organization.
+func (c *FabricClient) QueryInstalledChaincodes(ctx context.Context, identity Identity, peers []string) ([]*LifecycleInstalledResponse, error) {
+	r, err := c.queryLifecycle(ctx, identity, "", "QueryInstalledChaincodes", &queryInstalledChaincodesArgs{}, peers)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]*LifecycleInstalledResponse, len(r))
+	for i, p := range r {
+		ir := &LifecycleInstalledResponse{PeerName: p.PeerName, Error: p.Error}
+		if ir.Error == nil {
+			result := new(queryInstalledChaincodesResult)
+			if err := proto.Unmarshal(p.Payload, result); err != nil {
+				ir.Error = err
+			}
+			for _, ic := range result.InstalledChaincodes {
+				installed := &LifecycleInstalledChaincode{PackageId: ic.PackageId, Label: ic.Label,
+					References: make(map[string][]*ChainCodeInfo, len(ic.References))}
+				for channelId, refs := range ic.References {
+					if refs == nil {
+						continue
+					}
+					for _, cc := range refs.Chaincodes {
+						installed.References[channelId] = append(installed.References[channelId],
+							&ChainCodeInfo{Name: cc.Name, Version: cc.Version, PackageId: ic.PackageId})
+					}
+				}
+				ir.Installed = append(ir.Installed, installed)
+			}
+		}
+		response[i] = ir
+	}
+	return response, nil
+}
+
+// QueryChaincodeDefinitions gets chaincode definitions committed in channel with _lifecycle of Fabric 2.x. This is
+// _lifecycle equivalent of QueryInstantiatedChainCodes.
+func (c *FabricClient) QueryChaincodeDefinitions(ctx context.Context, identity Identity, channelId string, peers []string) ([]*ChaincodeDefinitionsResponse, error) {
+	r, err := c.queryLifecycle(ctx, identity, channelId, "QueryChaincodeDefinitions", &queryChaincodeDefinitionsArgs{}, peers)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]*ChaincodeDefinitionsResponse, len(r))
+	for i, p := range r {
+		dr := &ChaincodeDefinitionsResponse{PeerName: p.PeerName, Error: p.Error}
+		if dr.Error == nil {
+			result := new(queryChaincodeDefinitionsResult)
+			if err := proto.Unmarshal(p.Payload, result); err != nil {
+				dr.Error = err
+			}
+			for _, d := range result.ChaincodeDefinitions {
+				dr.Definitions = append(dr.Definitions, &ChaincodeDefinition{Name: d.Name, Version: d.Version,
+					Sequence: d.Sequence, EndorsementPlugin: d.EndorsementPlugin, ValidationPlugin: d.ValidationPlugin,
+					ValidationParameter: d.ValidationParameter, Collections: d.Collections, InitRequired: d.InitRequired})
+			}
+		}
+		response[i] = dr
+	}
+	return response, nil
+}
+
+// queryLifecycle calls _lifecycle function with marshaled args. Responses with status other than 200 are returned
+// as EndorsementError.
+func (c *FabricClient) queryLifecycle(ctx context.Context, identity Identity, channelId, function string, args proto.Message,
+	peers []string) ([]*QueryResponse, error) {
+	argBytes, err := proto.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	r, err := c.QueryWithContext(ctx, identity, ChainCode{ChannelId: channelId, Name: LifecycleSCC,
+		Type: ChaincodeSpec_GOLANG, Args: []string{function}, ArgBytes: argBytes}, peers)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range r {
+		if p.Error == nil && p.Status != 200 {
+			p.Error = &EndorsementError{Peer: p.PeerName, Status: p.Status, Message: p.Message}
+		}
+	}
+	return r, nil
+}
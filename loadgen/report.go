@@ -0,0 +1,161 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package loadgen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Report holds results of run
+type Report struct {
+	// Duration is time from start of run to the report
+	Duration time.Duration
+	// Dropped is number of requests not sent because all workers were busy
+	Dropped uint64
+	// Total sums all steps
+	Total StepReport
+	// Steps are in scenario order
+	Steps []StepReport
+}
+
+// StepReport holds results of one step. Latencies are of successful requests, for invokes latency ends when
+// orderer accepts transaction.
+type StepReport struct {
+	Name     string
+	Requests int
+	Errors   int
+	// TPS is number of successful requests per second
+	TPS                           float64
+	Min, Mean, P50, P90, P99, Max time.Duration
+	// LastError is error of the last failed request
+	LastError error
+}
+
+// String formats report as table
+func (r *Report) String() string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "step\trequests\terrors\ttps\tmin\tmean\tp50\tp90\tp99\tmax")
+	for _, s := range append(r.Steps, r.Total) {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.1f\t%v\t%v\t%v\t%v\t%v\t%v\n", s.Name, s.Requests, s.Errors, s.TPS,
+			s.Min, s.Mean, s.P50, s.P90, s.P99, s.Max)
+	}
+	w.Flush()
+	fmt.Fprintf(&buf, "duration %v, dropped %d\n", r.Duration.Round(time.Millisecond), r.Dropped)
+	return buf.String()
+}
+
+// collector records results of requests
+type collector struct {
+	mu        sync.Mutex
+	start     time.Time
+	end       time.Time
+	dropped   uint64
+	names     []string
+	latencies [][]time.Duration
+	errors    []int
+	lastError []error
+}
+
+func newCollector(steps []*compiledStep) *collector {
+	c := &collector{start: time.Now(), latencies: make([][]time.Duration, len(steps)), errors: make([]int, len(steps)),
+		lastError: make([]error, len(steps))}
+	for _, s := range steps {
+		c.names = append(c.names, s.Name)
+	}
+	return c
+}
+
+func (c *collector) drop() {
+	c.mu.Lock()
+	c.dropped++
+	c.mu.Unlock()
+}
+
+// reset clears results and starts duration of report
+func (c *collector) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.start, c.end, c.dropped = time.Now(), time.Time{}, 0
+	for i := range c.names {
+		c.latencies[i], c.errors[i], c.lastError[i] = nil, 0, nil
+	}
+}
+
+// finish stops duration of report
+func (c *collector) finish() {
+	c.mu.Lock()
+	c.end = time.Now()
+	c.mu.Unlock()
+}
+
+func (c *collector) record(step int, latency time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		c.errors[step]++
+		c.lastError[step] = err
+		return
+	}
+	c.latencies[step] = append(c.latencies[step], latency)
+}
+
+func (c *collector) report() *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	end := c.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	r := &Report{Duration: end.Sub(c.start), Dropped: c.dropped}
+	var all []time.Duration
+	totalErrors := 0
+	var lastError error
+	for i, name := range c.names {
+		r.Steps = append(r.Steps, stepReport(name, c.latencies[i], c.errors[i], c.lastError[i], r.Duration))
+		all = append(all, c.latencies[i]...)
+		totalErrors += c.errors[i]
+		if c.lastError[i] != nil {
+			lastError = c.lastError[i]
+		}
+	}
+	r.Total = stepReport("total", all, totalErrors, lastError, r.Duration)
+	return r
+}
+
+func stepReport(name string, latencies []time.Duration, errors int, lastError error, elapsed time.Duration) StepReport {
+	s := StepReport{Name: name, Requests: len(latencies) + errors, Errors: errors, LastError: lastError}
+	if len(latencies) == 0 {
+		return s
+	}
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var sum time.Duration
+	for _, l := range sorted {
+		sum += l
+	}
+	s.Min, s.Max = sorted[0], sorted[len(sorted)-1]
+	s.Mean = sum / time.Duration(len(sorted))
+	s.P50, s.P90, s.P99 = percentile(sorted, 50), percentile(sorted, 90), percentile(sorted, 99)
+	if elapsed > 0 {
+		s.TPS = float64(len(sorted)) / elapsed.Seconds()
+	}
+	return s
+}
+
+// percentile returns nearest rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
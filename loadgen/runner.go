@@ -0,0 +1,162 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/CognitionFoundry/gohfc"
+)
+
+// Runner sends requests of scenario through gohfc.Handler
+type Runner struct {
+	handler  gohfc.Handler
+	identity gohfc.Identity
+	scenario Scenario
+	steps    []*compiledStep
+	total    int
+	seq      uint64
+	stepSeq  []uint64
+	stats    *collector
+}
+
+// New validates scenario and creates runner that sends requests with identity. Handler is usually
+// *gohfc.FabricClient.
+func New(handler gohfc.Handler, identity gohfc.Identity, scenario Scenario) (*Runner, error) {
+	steps, err := scenario.compile()
+	if err != nil {
+		return nil, err
+	}
+	r := &Runner{handler: handler, identity: identity, scenario: scenario, steps: steps,
+		stepSeq: make([]uint64, len(steps)), stats: newCollector(steps)}
+	for _, s := range steps {
+		r.total += s.weight
+	}
+	return r, nil
+}
+
+// Run sends requests until scenario duration elapses or ctx is canceled and waits for requests in flight.
+// Requests in flight are canceled only when ctx is canceled.
+func (r *Runner) Run(ctx context.Context) (*Report, error) {
+	r.stats.reset()
+	generate := ctx
+	if r.scenario.Duration > 0 {
+		var cancel context.CancelFunc
+		generate, cancel = context.WithTimeout(ctx, r.scenario.Duration)
+		defer cancel()
+	}
+	jobs := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < r.scenario.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				r.send(ctx)
+			}
+		}()
+	}
+	if r.scenario.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / r.scenario.Rate))
+	loop:
+		for {
+			select {
+			case <-generate.Done():
+				break loop
+			case <-ticker.C:
+				select {
+				case jobs <- struct{}{}:
+				default:
+					r.stats.drop()
+				}
+			}
+		}
+		ticker.Stop()
+	} else {
+	unlimited:
+		for {
+			select {
+			case <-generate.Done():
+				break unlimited
+			case jobs <- struct{}{}:
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	r.stats.finish()
+	return r.stats.report(), nil
+}
+
+// Report returns statistics of running or finished run. It is safe to call from other goroutine to print progress.
+func (r *Runner) Report() *Report {
+	return r.stats.report()
+}
+
+// send sends one request of randomly chosen step
+func (r *Runner) send(ctx context.Context) {
+	i := r.pick()
+	step := r.steps[i]
+	data := TemplateData{Seq: atomic.AddUint64(&r.seq, 1) - 1, StepSeq: atomic.AddUint64(&r.stepSeq[i], 1) - 1}
+	args := make([]string, 0, len(step.args)+1)
+	args = append(args, step.Function)
+	for _, t := range step.args {
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			r.stats.record(i, 0, err)
+			return
+		}
+		args = append(args, buf.String())
+	}
+	chainCode := gohfc.ChainCode{
+		ChannelId: r.scenario.ChannelId,
+		Name:      step.ChainCode,
+		Type:      gohfc.ChaincodeSpec_GOLANG,
+		Args:      args,
+	}
+	start := time.Now()
+	var err error
+	if step.Kind == KindInvoke {
+		_, err = r.handler.InvokeWithContext(ctx, r.identity, chainCode, r.scenario.Peers, r.scenario.Orderer)
+	} else {
+		err = r.query(ctx, chainCode)
+	}
+	r.stats.record(i, time.Since(start), err)
+}
+
+// query returns error of request or first peer error, chaincode response with status 400 and above is error
+func (r *Runner) query(ctx context.Context, chainCode gohfc.ChainCode) error {
+	responses, err := r.handler.QueryWithContext(ctx, r.identity, chainCode, r.scenario.Peers)
+	if err != nil {
+		return err
+	}
+	for _, resp := range responses {
+		if resp.Error != nil {
+			return fmt.Errorf("%s: %v", resp.PeerName, resp.Error)
+		}
+		if resp.Status >= 400 {
+			return fmt.Errorf("%s: chaincode status %d: %s", resp.PeerName, resp.Status, resp.Message)
+		}
+	}
+	return nil
+}
+
+// pick returns index of step chosen by weight
+func (r *Runner) pick() int {
+	n := rand.Intn(r.total)
+	for i, s := range r.steps {
+		if n < s.weight {
+			return i
+		}
+		n -= s.weight
+	}
+	return len(r.steps) - 1
+}
@@ -0,0 +1,214 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+
+// Package loadgen drives load against Fabric network through gohfc, so networks are load tested with the same client
+// stack that applications run in production. Load is described by scenario file:
+//
+//	channel: mychannel
+//	chaincode: assets
+//	rate: 100          # requests per second, 0 sends next request as soon as worker is free
+//	duration: 5m
+//	concurrency: 50    # maximum requests in flight
+//	steps:
+//	  - name: create
+//	    kind: invoke
+//	    function: create
+//	    args: ["asset-{{.Seq}}", "{{randInt 1 1000}}"]
+//	    weight: 2
+//	  - name: read
+//	    kind: query
+//	    function: read
+//	    args: ["asset-{{randInt 0 .Seq}}"]
+//	    weight: 8
+//
+// Step of every request is chosen randomly by weight. Args are text/template templates, see TemplateData for
+// available fields and functions.
+package loadgen
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// KindInvoke submits transaction with Invoke
+	KindInvoke = "invoke"
+	// KindQuery evaluates transaction with Query
+	KindQuery = "query"
+
+	defaultConcurrency = 10
+	// maxRate is the highest rate with ticker interval of at least one nanosecond
+	maxRate = 1e9
+)
+
+var (
+	ErrNoSteps       = errors.New("scenario has no steps")
+	ErrInvalidRate   = errors.New("rate must be between 0 and 1e9 per second")
+	ErrInvalidWeight = errors.New("step weight cannot be negative")
+)
+
+// Scenario describes load. Zero values are replaced with defaults.
+type Scenario struct {
+	ChannelId string `yaml:"channel"`
+	// ChainCode is used by steps without own chaincode
+	ChainCode string `yaml:"chaincode"`
+	// Rate is number of requests started per second. 0 means no limit, every worker sends next request as soon as
+	// previous one is done. Rate cannot be higher than 1e9.
+	Rate float64 `yaml:"rate"`
+	// Duration is how long requests are started, 0 means until context is canceled
+	Duration time.Duration `yaml:"duration"`
+	// Concurrency is maximum number of requests in flight, default 10. When all workers are busy requests of the
+	// rate are dropped and counted in Report.Dropped.
+	Concurrency int `yaml:"concurrency"`
+	// Peers and Orderer are passed to every request. When empty client defaults apply.
+	Peers   []string `yaml:"peers"`
+	Orderer string   `yaml:"orderer"`
+	Steps   []Step   `yaml:"steps"`
+}
+
+// Step is one kind of request in scenario
+type Step struct {
+	// Name identifies step in report, default is function name
+	Name string `yaml:"name"`
+	// Kind is KindInvoke or KindQuery
+	Kind string `yaml:"kind"`
+	// ChainCode overrides scenario chaincode
+	ChainCode string `yaml:"chaincode"`
+	Function  string `yaml:"function"`
+	// Args are templates executed for every request
+	Args []string `yaml:"args"`
+	// Weight is relative share of step in requests, default 1. Step with weight 0 is not sent.
+	Weight *int `yaml:"weight"`
+}
+
+// TemplateData is passed to arg templates. Templates can also use functions randInt min max (random number in
+// [min, max)), randString n (random letters) and unixNano (current time).
+type TemplateData struct {
+	// Seq is sequence number of request in run, starting from 0
+	Seq uint64
+	// StepSeq is sequence number of request in its step, starting from 0
+	StepSeq uint64
+}
+
+var templateFuncs = template.FuncMap{
+	"randInt": func(min, max interface{}) (int64, error) {
+		lo, err := toInt64(min)
+		if err != nil {
+			return 0, err
+		}
+		hi, err := toInt64(max)
+		if err != nil {
+			return 0, err
+		}
+		if hi <= lo {
+			return lo, nil
+		}
+		return lo + rand.Int63n(hi-lo), nil
+	},
+	"randString": func(n int) string {
+		const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = letters[rand.Intn(len(letters))]
+		}
+		return string(b)
+	},
+	"unixNano": func() int64 {
+		return time.Now().UnixNano()
+	},
+}
+
+// toInt64 converts template number, literals are int and TemplateData fields are uint64
+func toInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint64:
+		return int64(n), nil
+	}
+	return 0, fmt.Errorf("randInt: %v is not a number", v)
+}
+
+// LoadScenario reads scenario from yaml file
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseScenario(data)
+}
+
+// ParseScenario parses yaml scenario
+func ParseScenario(data []byte) (*Scenario, error) {
+	s := new(Scenario)
+	if err := yaml.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// compiledStep is step with parsed templates
+type compiledStep struct {
+	Step
+	weight int
+	args   []*template.Template
+}
+
+// compile validates scenario, sets defaults and parses arg templates
+func (s *Scenario) compile() ([]*compiledStep, error) {
+	if s.Rate < 0 || s.Rate > maxRate || math.IsNaN(s.Rate) {
+		return nil, ErrInvalidRate
+	}
+	if s.Concurrency <= 0 {
+		s.Concurrency = defaultConcurrency
+	}
+	steps := make([]*compiledStep, 0, len(s.Steps))
+	total := 0
+	for i, step := range s.Steps {
+		cs := &compiledStep{Step: step, weight: 1}
+		if cs.Name == "" {
+			cs.Name = cs.Function
+		}
+		if cs.ChainCode == "" {
+			cs.ChainCode = s.ChainCode
+		}
+		if step.Weight != nil {
+			if *step.Weight < 0 {
+				return nil, fmt.Errorf("step %d: %v", i, ErrInvalidWeight)
+			}
+			cs.weight = *step.Weight
+		}
+		switch {
+		case cs.Kind != KindInvoke && cs.Kind != KindQuery:
+			return nil, fmt.Errorf("step %d: kind must be %s or %s", i, KindInvoke, KindQuery)
+		case cs.ChainCode == "":
+			return nil, fmt.Errorf("step %d: chaincode is empty", i)
+		case cs.Function == "":
+			return nil, fmt.Errorf("step %d: function is empty", i)
+		}
+		for _, arg := range step.Args {
+			t, err := template.New(cs.Name).Funcs(templateFuncs).Option("missingkey=error").Parse(arg)
+			if err != nil {
+				return nil, fmt.Errorf("step %d: %v", i, err)
+			}
+			cs.args = append(cs.args, t)
+		}
+		total += cs.weight
+		steps = append(steps, cs)
+	}
+	if total == 0 {
+		return nil, ErrNoSteps
+	}
+	return steps, nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// LogLevel is the severity of log message
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "DEBUG"
+	case LogLevelInfo:
+		return "INFO"
+	case LogLevelWarn:
+		return "WARN"
+	case LogLevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+// Logger receives log messages from gohfc. Fields are key value pairs like "peer", "peer0", "txId", id.
+type Logger interface {
+	Debug(msg string, fields ...interface{})
+	Info(msg string, fields ...interface{})
+	Warn(msg string, fields ...interface{})
+	Error(msg string, fields ...interface{})
+}
+
+// LoggerFunc adapts function to Logger. It is the easiest way to connect logger that has no adapter.
+// Adapters for standard library logger and zap are NewStdLogger and NewZapLogger, logrus adapter NewLogrusLogger
+// is available when gohfc is build with `-tags logrus`.
+type LoggerFunc func(level LogLevel, msg string, fields ...interface{})
+
+func (f LoggerFunc) Debug(msg string, fields ...interface{}) { f(LogLevelDebug, msg, fields...) }
+func (f LoggerFunc) Info(msg string, fields ...interface{})  { f(LogLevelInfo, msg, fields...) }
+func (f LoggerFunc) Warn(msg string, fields ...interface{})  { f(LogLevelWarn, msg, fields...) }
+func (f LoggerFunc) Error(msg string, fields ...interface{}) { f(LogLevelError, msg, fields...) }
+
+// NewStdLogger creates Logger that writes messages with level or higher to standard library logger
+// in form `LEVEL message key=value key=value`
+func NewStdLogger(l *log.Logger, level LogLevel) Logger {
+	return LoggerFunc(func(msgLevel LogLevel, msg string, fields ...interface{}) {
+		if msgLevel < level {
+			return
+		}
+		var b strings.Builder
+		b.WriteString(msgLevel.String())
+		b.WriteString(" ")
+		b.WriteString(msg)
+		for i := 0; i < len(fields); i += 2 {
+			if i+1 < len(fields) {
+				fmt.Fprintf(&b, " %v=%v", fields[i], fields[i+1])
+			} else {
+				fmt.Fprintf(&b, " %v", fields[i])
+			}
+		}
+		l.Print(b.String())
+	})
+}
+
+// ZapSugaredLogger is the subset of *zap.SugaredLogger methods used by NewZapLogger
+type ZapSugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// NewZapLogger adapts zap sugared logger: gohfc.SetLogger(gohfc.NewZapLogger(zapLogger.Sugar()))
+func NewZapLogger(l ZapSugaredLogger) Logger {
+	return LoggerFunc(func(level LogLevel, msg string, fields ...interface{}) {
+		switch level {
+		case LogLevelDebug:
+			l.Debugw(msg, fields...)
+		case LogLevelInfo:
+			l.Infow(msg, fields...)
+		case LogLevelWarn:
+			l.Warnw(msg, fields...)
+		default:
+			l.Errorw(msg, fields...)
+		}
+	})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+
+type loggerHolder struct {
+	Logger
+}
+
+var currentLogger atomic.Value
+
+func init() {
+	currentLogger.Store(loggerHolder{nopLogger{}})
+}
+
+// SetLogger sets logger used by all clients in process. By default nothing is logged. Nil disables logging.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = nopLogger{}
+	}
+	currentLogger.Store(loggerHolder{l})
+}
+
+// logger returns current logger
+func logger() Logger {
+	return currentLogger.Load().(loggerHolder).Logger
+}
@@ -0,0 +1,36 @@
+//go:build logrus
+// +build logrus
+
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+
+package gohfc
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NewLogrusLogger adapts logrus logger. It is available when gohfc is build with `-tags logrus`.
+func NewLogrusLogger(l logrus.FieldLogger) Logger {
+	return LoggerFunc(func(level LogLevel, msg string, fields ...interface{}) {
+		f := make(logrus.Fields, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			f[fmt.Sprint(fields[i])] = fields[i+1]
+		}
+		entry := l.WithFields(f)
+		switch level {
+		case LogLevelDebug:
+			entry.Debug(msg)
+		case LogLevelInfo:
+			entry.Info(msg)
+		case LogLevelWarn:
+			entry.Warn(msg)
+		default:
+			entry.Error(msg)
+		}
+	})
+}
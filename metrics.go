@@ -0,0 +1,65 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Metrics receives measurements from gohfc. Prometheus implementation NewPrometheusMetrics is available when gohfc
+// is build with `-tags prometheus`, other monitoring systems can be connected by implementing this interface.
+// Methods are called from many goroutines and must not block.
+type Metrics interface {
+	// ProposalSent is called for every proposal send to peers
+	ProposalSent(channelId, chainCode string)
+	// Endorsement is called when peer responds to proposal or fails
+	Endorsement(peer string, latency time.Duration, err error)
+	// Broadcast is called when orderer responds to transaction or fails
+	Broadcast(orderer string, latency time.Duration, err error)
+	// Commit is called when commit event of transaction submitted with InvokeAsync is received
+	Commit(channelId string, latency time.Duration, validationCode string)
+	// EventLag is called for every full block received from peer with time passed since block transactions
+	// were created
+	EventLag(peer, channelId string, lag time.Duration)
+	// Connect is called on every connection or reconnection to peer or orderer
+	Connect(node string, err error)
+	// PeerAnomaly is called by AnomalyDetector.Findings for every observed peer and kind of anomaly, active is true
+	// when anomaly of kind was found for peer
+	PeerAnomaly(peer, kind string, active bool)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ProposalSent(string, string)              {}
+func (nopMetrics) Endorsement(string, time.Duration, error) {}
+func (nopMetrics) Broadcast(string, time.Duration, error)   {}
+func (nopMetrics) Commit(string, time.Duration, string)     {}
+func (nopMetrics) EventLag(string, string, time.Duration)   {}
+func (nopMetrics) Connect(string, error)                    {}
+func (nopMetrics) PeerAnomaly(string, string, bool)         {}
+
+type metricsHolder struct {
+	Metrics
+}
+
+var currentMetrics atomic.Value
+
+func init() {
+	currentMetrics.Store(metricsHolder{nopMetrics{}})
+}
+
+// SetMetrics sets metrics used by all clients in process. Nil disables metrics.
+func SetMetrics(m Metrics) {
+	if m == nil {
+		m = nopMetrics{}
+	}
+	currentMetrics.Store(metricsHolder{m})
+}
+
+// metrics returns current metrics
+func metrics() Metrics {
+	return currentMetrics.Load().(metricsHolder).Metrics
+}
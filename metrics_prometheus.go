@@ -0,0 +1,107 @@
+//go:build prometheus
+// +build prometheus
+
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusMetrics implements Metrics with Prometheus counters and histograms
+type PrometheusMetrics struct {
+	proposals   *prometheus.CounterVec
+	endorsement *prometheus.HistogramVec
+	broadcast   *prometheus.HistogramVec
+	commit      *prometheus.HistogramVec
+	eventLag    *prometheus.HistogramVec
+	connects    *prometheus.CounterVec
+	anomalies   *prometheus.GaugeVec
+}
+
+// NewPrometheusMetrics creates metrics and register them in reg. Use it with SetMetrics:
+//
+//	m, err := gohfc.NewPrometheusMetrics(prometheus.DefaultRegisterer)
+//	gohfc.SetMetrics(m)
+//
+// Available when gohfc is build with `-tags prometheus`.
+func NewPrometheusMetrics(reg prometheus.Registerer) (*PrometheusMetrics, error) {
+	m := &PrometheusMetrics{
+		proposals: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gohfc", Name: "proposals_total", Help: "Number of proposals send to peers.",
+		}, []string{"channel", "chaincode"}),
+		endorsement: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gohfc", Name: "endorsement_duration_seconds", Help: "Endorsement latency per peer.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"peer", "result"}),
+		broadcast: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gohfc", Name: "broadcast_duration_seconds", Help: "Broadcast latency per orderer.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"orderer", "result"}),
+		commit: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gohfc", Name: "commit_duration_seconds", Help: "Time from submission to commit event.",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 10),
+		}, []string{"channel", "validation_code"}),
+		eventLag: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gohfc", Name: "event_lag_seconds", Help: "Time from transaction creation to block event.",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 10),
+		}, []string{"peer", "channel"}),
+		connects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gohfc", Name: "connects_total", Help: "Number of connections and reconnections to nodes.",
+		}, []string{"node", "result"}),
+		anomalies: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gohfc", Name: "peer_anomaly", Help: "1 when anomaly detector found anomaly of kind for peer.",
+		}, []string{"peer", "kind"}),
+	}
+	for _, c := range []prometheus.Collector{m.proposals, m.endorsement, m.broadcast, m.commit, m.eventLag, m.connects,
+		m.anomalies} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *PrometheusMetrics) ProposalSent(channelId, chainCode string) {
+	m.proposals.WithLabelValues(channelId, chainCode).Inc()
+}
+
+func (m *PrometheusMetrics) Endorsement(peer string, latency time.Duration, err error) {
+	m.endorsement.WithLabelValues(peer, metricResult(err)).Observe(latency.Seconds())
+}
+
+func (m *PrometheusMetrics) Broadcast(orderer string, latency time.Duration, err error) {
+	m.broadcast.WithLabelValues(orderer, metricResult(err)).Observe(latency.Seconds())
+}
+
+func (m *PrometheusMetrics) Commit(channelId string, latency time.Duration, validationCode string) {
+	m.commit.WithLabelValues(channelId, validationCode).Observe(latency.Seconds())
+}
+
+func (m *PrometheusMetrics) EventLag(peer, channelId string, lag time.Duration) {
+	m.eventLag.WithLabelValues(peer, channelId).Observe(lag.Seconds())
+}
+
+func (m *PrometheusMetrics) Connect(node string, err error) {
+	m.connects.WithLabelValues(node, metricResult(err)).Inc()
+}
+
+func (m *PrometheusMetrics) PeerAnomaly(peer, kind string, active bool) {
+	value := 0.0
+	if active {
+		value = 1
+	}
+	m.anomalies.WithLabelValues(peer, kind).Set(value)
+}
+
+func metricResult(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
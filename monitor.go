@@ -0,0 +1,91 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+// ChainInfoReport is the result of single check made from `MonitorChainInfo`
+type ChainInfoReport struct {
+	ChannelId string
+	Time      time.Time
+	// Peers holds chain info for every monitored peer
+	Peers []*QueryChannelInfoResponse
+	// MaxHeight is the highest block height reported from all peers
+	MaxHeight uint64
+	// Lagging are names of peers that are behind MaxHeight more than allowed lag or returned error
+	Lagging []string
+}
+
+// GetChainInfo get current block height, current hash and previous hash for channel from single peer
+func (c *FabricClient) GetChainInfo(identity Identity, channelId string, peer string) (*common.BlockchainInfo, error) {
+	r, err := c.QueryChainInfo(identity, channelId, []string{peer})
+	if err != nil {
+		return nil, err
+	}
+	if r[0].Error != nil {
+		return nil, r[0].Error
+	}
+	return r[0].Info, nil
+}
+
+// MonitorChainInfo periodically query chain info from peers and send report to reports channel on every interval.
+// Peer is considered lagging when its height is lower than the highest height more than maxLag blocks.
+// It is responsibility of the caller to read reports channel. To stop monitoring cancel the context.
+func (c *FabricClient) MonitorChainInfo(ctx context.Context, identity Identity, channelId string, peers []string,
+	interval time.Duration, maxLag uint64, reports chan<- ChainInfoReport) error {
+	execPeers := c.getPeers(peers)
+	if len(peers) != len(execPeers) {
+		return ErrPeerNameNotFound
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			report := c.chainInfoReport(identity, channelId, peers, maxLag)
+			select {
+			case reports <- report:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (c *FabricClient) chainInfoReport(identity Identity, channelId string, peers []string, maxLag uint64) ChainInfoReport {
+	report := ChainInfoReport{ChannelId: channelId, Time: time.Now()}
+	r, err := c.QueryChainInfo(identity, channelId, peers)
+	if err != nil {
+		for _, p := range peers {
+			report.Peers = append(report.Peers, &QueryChannelInfoResponse{PeerName: p, Error: err})
+			report.Lagging = append(report.Lagging, p)
+		}
+		c.observeChainInfo(report)
+		return report
+	}
+	report.Peers = r
+	for _, p := range r {
+		if p.Error == nil && p.Info.GetHeight() > report.MaxHeight {
+			report.MaxHeight = p.Info.GetHeight()
+		}
+	}
+	for _, p := range r {
+		if p.Error != nil || report.MaxHeight-p.Info.GetHeight() > maxLag {
+			report.Lagging = append(report.Lagging, p.PeerName)
+		}
+	}
+	c.observeChainInfo(report)
+	return report
+}
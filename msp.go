@@ -0,0 +1,199 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// MSP is content of local MSP directory created by cryptogen or Fabric CA client
+type MSP struct {
+	MspId string
+	// Identity is signing identity from signcerts and keystore. When keystore has no key matching certificate,
+	// identity is read only, private key can be set later, for example to Signer.
+	Identity          *Identity
+	CACerts           []*x509.Certificate
+	IntermediateCerts []*x509.Certificate
+	AdminCerts        []*x509.Certificate
+	// TlsCACerts and TlsIntermediateCerts are PEM encoded TLS certificates of organization
+	TlsCACerts           [][]byte
+	TlsIntermediateCerts [][]byte
+}
+
+// LoadMSP reads MSP directory with signcerts, keystore, cacerts, intermediatecerts, admincerts, tlscacerts and
+// tlsintermediatecerts folders. Only signcerts is required. Identity gets mspId.
+func LoadMSP(dir, mspId string) (*MSP, error) {
+	m := &MSP{MspId: mspId}
+	signCerts, err := readMspCerts(dir, "signcerts")
+	if err != nil {
+		return nil, err
+	}
+	if len(signCerts) == 0 {
+		return nil, fmt.Errorf("msp %s: %v", dir, ErrCertificateMissing)
+	}
+	cert, err := parseCertificate(signCerts[0])
+	if err != nil {
+		return nil, fmt.Errorf("msp %s: %v", dir, err)
+	}
+	m.Identity = &Identity{Certificate: cert, MspId: mspId}
+	if m.Identity.PrivateKey, err = findMspKey(filepath.Join(dir, "keystore"), cert); err != nil {
+		return nil, fmt.Errorf("msp %s: %v", dir, err)
+	}
+	for _, certs := range []struct {
+		folder string
+		out    *[]*x509.Certificate
+	}{{"cacerts", &m.CACerts}, {"intermediatecerts", &m.IntermediateCerts}, {"admincerts", &m.AdminCerts}} {
+		der, err := readMspCerts(dir, certs.folder)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range der {
+			c, err := parseCertificate(d)
+			if err != nil {
+				return nil, fmt.Errorf("msp %s/%s: %v", dir, certs.folder, err)
+			}
+			*certs.out = append(*certs.out, c)
+		}
+	}
+	for _, tls := range []struct {
+		folder string
+		out    *[][]byte
+	}{{"tlscacerts", &m.TlsCACerts}, {"tlsintermediatecerts", &m.TlsIntermediateCerts}} {
+		der, err := readMspCerts(dir, tls.folder)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range der {
+			*tls.out = append(*tls.out, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: d}))
+		}
+	}
+	return m, nil
+}
+
+// TlsRootCerts returns PEM encoded TLS CA and intermediate certificates of MSP, as used for TlsCert of peers and
+// orderers
+func (m *MSP) TlsRootCerts() string {
+	return string(bytes.Join(append(append([][]byte{}, m.TlsCACerts...), m.TlsIntermediateCerts...), nil))
+}
+
+// readMspCerts returns DER certificates from all PEM files in folder of MSP directory, in file name order. Missing
+// folder has no certificates.
+func readMspCerts(dir, folder string) ([][]byte, error) {
+	files, err := readMspFolder(filepath.Join(dir, folder))
+	if err != nil {
+		return nil, err
+	}
+	var certs [][]byte
+	for _, data := range files {
+		for {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			if block.Type == "CERTIFICATE" {
+				certs = append(certs, block.Bytes)
+			}
+		}
+	}
+	return certs, nil
+}
+
+// readMspFolder reads regular files of folder in name order
+func readMspFolder(folder string) ([][]byte, error) {
+	paths, err := mspFolderFiles(folder)
+	if err != nil {
+		return nil, err
+	}
+	var files [][]byte
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, data)
+	}
+	return files, nil
+}
+
+// mspFolderFiles returns paths of regular files of folder in name order, missing folder has no files
+func mspFolderFiles(folder string) ([]string, error) {
+	entries, err := ioutil.ReadDir(folder)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			files = append(files, filepath.Join(folder, e.Name()))
+		}
+	}
+	return files, nil
+}
+
+// mspTlsFiles returns paths of TLS CA and intermediate certificate files of MSP directory
+func mspTlsFiles(dir string) ([]string, error) {
+	var files []string
+	for _, folder := range []string{"tlscacerts", "tlsintermediatecerts"} {
+		paths, err := mspFolderFiles(filepath.Join(dir, folder))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, paths...)
+	}
+	return files, nil
+}
+
+// findMspKey returns private key from keystore that matches certificate, nil when there is none. Key files are
+// named by cryptogen as <ski>_sk and by Fabric CA client as <ski>_sk or priv_sk, any PEM key file is checked.
+func findMspKey(keystore string, cert *x509.Certificate) (interface{}, error) {
+	files, err := readMspFolder(keystore)
+	if err != nil {
+		return nil, err
+	}
+	for _, data := range files {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			continue
+		}
+		key, err := parsePrivateKeyBlock(block, nil)
+		if err != nil {
+			continue
+		}
+		if keyMatchesCertificate(key, cert) {
+			return key, nil
+		}
+	}
+	return nil, nil
+}
+
+// mspTlsRootCerts returns PEM encoded TLS CA and intermediate certificates of MSP directory
+func mspTlsRootCerts(dir string) (string, error) {
+	var certs []byte
+	for _, folder := range []string{"tlscacerts", "tlsintermediatecerts"} {
+		der, err := readMspCerts(dir, folder)
+		if err != nil {
+			return "", err
+		}
+		for _, d := range der {
+			certs = append(certs, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: d})...)
+		}
+	}
+	if len(certs) == 0 {
+		return "", fmt.Errorf("msp %s has no tlscacerts", dir)
+	}
+	return string(certs), nil
+}
@@ -0,0 +1,228 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrObjectNotFound is returned by S3Client when object does not exist
+	ErrObjectNotFound = errors.New("object not found")
+	// ErrPreconditionFailed is returned by S3Client when conditional write fails because object was changed
+	ErrPreconditionFailed = errors.New("object precondition failed")
+)
+
+// S3Client is minimal client for S3 compatible object stores (AWS S3, Google Cloud Storage XML API with HMAC keys,
+// MinIO, Ceph). Requests are signed with AWS signature version 4.
+type S3Client struct {
+	// Endpoint is base url like https://s3.eu-west-1.amazonaws.com or https://storage.googleapis.com
+	Endpoint string
+	// Region is the signing region, for example eu-west-1. Google Cloud Storage accepts "auto".
+	Region          string
+	Bucket          string
+	AccessKeyId     string
+	SecretAccessKey string
+	// VirtualHostStyle addresses bucket as subdomain of endpoint host instead of first path segment
+	VirtualHostStyle bool
+	// GoogleStorage uses object generation instead of etag for conditional writes, as required by Google Cloud
+	// Storage XML API
+	GoogleStorage bool
+	// Client is used for requests, http.DefaultClient if nil
+	Client *http.Client
+}
+
+// GetObject downloads object. Returned version (etag, or generation for GoogleStorage) can be used for
+// conditional PutObject.
+func (s *S3Client) GetObject(ctx context.Context, key string) ([]byte, string, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.checkStatus(resp, key, data); err != nil {
+		return nil, "", err
+	}
+	return data, s.version(resp), nil
+}
+
+// PutObject uploads object. If ifMatch is not empty object is written only if its current version is ifMatch,
+// "*" as ifNoneMatch writes object only if it does not exist. ErrPreconditionFailed is returned when condition
+// is not met. Returns version of the new object.
+func (s *S3Client) PutObject(ctx context.Context, key string, data []byte, ifMatch, ifNoneMatch string) (string, error) {
+	headers := make(map[string]string)
+	switch {
+	case s.GoogleStorage && ifMatch != "":
+		headers["X-Goog-If-Generation-Match"] = ifMatch
+	case s.GoogleStorage && ifNoneMatch == "*":
+		headers["X-Goog-If-Generation-Match"] = "0"
+	default:
+		if ifMatch != "" {
+			headers["If-Match"] = ifMatch
+		}
+		if ifNoneMatch != "" {
+			headers["If-None-Match"] = ifNoneMatch
+		}
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, data, headers)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if err := s.checkStatus(resp, key, body); err != nil {
+		return "", err
+	}
+	return s.version(resp), nil
+}
+
+func (s *S3Client) version(resp *http.Response) string {
+	if s.GoogleStorage {
+		return resp.Header.Get("X-Goog-Generation")
+	}
+	return resp.Header.Get("ETag")
+}
+
+func (s *S3Client) checkStatus(resp *http.Response, key string, body []byte) error {
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return ErrObjectNotFound
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return ErrPreconditionFailed
+	default:
+		return fmt.Errorf("object store returned status %d for %s: %s", resp.StatusCode, key, bytes.TrimSpace(body))
+	}
+}
+
+func (s *S3Client) do(ctx context.Context, method, key string, body []byte, headers map[string]string) (*http.Response, error) {
+	u, err := url.Parse(strings.TrimRight(s.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if s.VirtualHostStyle {
+		u.Host = s.Bucket + "." + u.Host
+		u.Path = "/" + key
+	} else {
+		u.Path = "/" + s.Bucket + "/" + key
+	}
+	u.RawPath = s3EscapePath(u.Path)
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	s.sign(req, body, time.Now().UTC())
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// sign adds AWS signature version 4 headers to req
+func (s *S3Client) sign(req *http.Request, body []byte, now time.Time) {
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	signAWSv4(req, body, now, s.Region, "s3", s.AccessKeyId, s.SecretAccessKey)
+}
+
+// signAWSv4 adds AWS signature version 4 headers to req for service in region. All headers of req are signed.
+func signAWSv4(req *http.Request, body []byte, now time.Time, region, service, accessKeyId, secretAccessKey string) {
+	payloadHash := sha256.Sum256(body)
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyId, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3EscapePath escapes path as required by signature version 4, every byte except unreserved characters and '/'
+func s3EscapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c == '/' || c == '-' || c == '_' || c == '.' || c == '~' ||
+			('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, strings.Replace(url.QueryEscape(k), "+", "%20", -1)+"="+
+				strings.Replace(url.QueryEscape(v), "+", "%20", -1))
+		}
+	}
+	return strings.Join(parts, "&")
+}
@@ -10,10 +10,8 @@ import (
 	"github.com/hyperledger/fabric/protos/orderer"
 	"context"
 	"fmt"
-	"google.golang.org/grpc/credentials"
-	"github.com/golang/protobuf/proto"
 	"time"
-	"google.golang.org/grpc/keepalive"
+	"sync"
 )
 
 // Orderer expose API's to communicate with orderers.
@@ -22,144 +20,245 @@ type Orderer struct {
 	Uri    string
 	Opts   []grpc.DialOption
 	caPath string
+	// target is gRPC dial target when it differs from Uri
+	target string
 	con    *grpc.ClientConn
 	client orderer.AtomicBroadcastClient
-}
+	// connMu guards con and client
+	connMu *sync.Mutex
+	// clientTls is client TLS certificate presented on connections to orderer, nil when orderer is not dialed with TLS
+	clientTls *clientTls
 
-const timeout = 5
+	// OperationsUrl is base url of orderer operations endpoint. Optional.
+	OperationsUrl string
+	// Timeouts limit dial, broadcast and deliver. Optional.
+	Timeouts Timeouts
+}
 
 // Broadcast Broadcast envelope to orderer for execution.
 func (o *Orderer) Broadcast(envelope *common.Envelope) (*orderer.BroadcastResponse, error) {
-	if o.con == nil {
-		c, err := grpc.Dial(o.Uri, o.Opts...)
-		if err != nil {
-			return nil, fmt.Errorf("cannot connect to orderer: %s err is: %v", o.Name, err)
-		}
-		o.con = c
-		o.client = orderer.NewAtomicBroadcastClient(o.con)
-	}
-	bcc, err := o.client.Broadcast(context.Background())
+	return o.broadcast(context.Background(), envelope)
+}
+
+// broadcast sends envelope to orderer. Request is canceled when ctx is done.
+func (o *Orderer) broadcast(ctx context.Context, envelope *common.Envelope) (*orderer.BroadcastResponse, error) {
+	ctx, span := startSpan(ctx, "gohfc.Broadcast", "orderer", o.Name, "endpoint", o.Uri)
+	response, err := o.sendEnvelope(ctx, envelope)
+	endSpan(span, err)
+	return response, err
+}
+
+func (o *Orderer) sendEnvelope(ctx context.Context, envelope *common.Envelope) (*orderer.BroadcastResponse, error) {
+	timeouts := effectiveTimeouts(ctx, o.Timeouts)
+	client, err := o.broadcastClient(ctx, timeouts.Dial)
 	if err != nil {
 		return nil, err
 	}
+	ctx, cancel := withTimeout(ctx, timeouts.Broadcast)
+	defer cancel()
+	bcc, err := client.Broadcast(ctx)
+	if err != nil {
+		return nil, rpcError(o.Name, err)
+	}
 	defer bcc.CloseSend()
+	start := time.Now()
 	bcc.Send(envelope)
 	response, err := bcc.Recv()
 	if err != nil {
-		return nil, err
+		logger().Warn("broadcast failed", "orderer", o.Name, "endpoint", o.Uri, "duration", time.Since(start), "error", err)
+		metrics().Broadcast(o.Name, time.Since(start), err)
+		return nil, rpcError(o.Name, err)
 	}
+	return o.broadcastResponse(response, start)
+}
+
+// broadcastResponse logs and records acknowledgement of orderer for single envelope sent at start. Status other
+// than SUCCESS is returned as BroadcastError.
+func (o *Orderer) broadcastResponse(response *orderer.BroadcastResponse, start time.Time) (*orderer.BroadcastResponse, error) {
+	logger().Debug("broadcast finished", "orderer", o.Name, "endpoint", o.Uri, "duration", time.Since(start),
+		"status", response.Status, "info", response.Info)
 	if response.Status != common.Status_SUCCESS {
-		return nil, fmt.Errorf("unexpected status: %v", response.Status)
+		err := &BroadcastError{Orderer: o.Name, Status: response.Status, Info: response.Info}
+		metrics().Broadcast(o.Name, time.Since(start), err)
+		return nil, err
 	}
-
-	return response, err
+	metrics().Broadcast(o.Name, time.Since(start), nil)
+	return response, nil
 }
 
 // Deliver delivers envelope to orderer. Please note that new connection will be created on every call of Deliver.
 func (o *Orderer) Deliver(envelope *common.Envelope) (*common.Block, error) {
+	return o.deliver(context.Background(), envelope)
+}
 
-	connection, err := grpc.Dial(o.Uri, o.Opts...)
+// deliver sends seek envelope to orderer and returns the last block received. Whole request is limited by
+// Deliver timeout.
+func (o *Orderer) deliver(ctx context.Context, envelope *common.Envelope) (*common.Block, error) {
+	timeout := effectiveTimeouts(ctx, o.Timeouts).Deliver
+	if timeout <= 0 {
+		timeout = defaultDeliverTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var block *common.Block
+	err := o.deliverBlocks(ctx, envelope, func(b *common.Block) error {
+		block = b
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("cannot connect to orderer: %s err is: %v", o.Name, err)
+		return nil, err
+	}
+	return block, nil
+}
+
+// deliverBlocks sends seek envelope to orderer and calls fn with every block received, until orderer sends status
+// or fn returns error. New connection is created for every request.
+func (o *Orderer) deliverBlocks(ctx context.Context, envelope *common.Envelope, fn func(*common.Block) error) error {
+	dialCtx, cancel := withTimeout(ctx, effectiveTimeouts(ctx, o.Timeouts).Dial)
+	connection, err := grpc.DialContext(dialCtx, o.dialTarget(), o.Opts...)
+	cancel()
+	if err != nil {
+		return &ConnectionError{Node: o.Name, Err: err}
 	}
 	defer connection.Close()
 
-	dk, err := orderer.NewAtomicBroadcastClient(connection).Deliver(context.Background())
+	ctx, cancel = context.WithCancel(ctx)
+	defer cancel()
+	dk, err := orderer.NewAtomicBroadcastClient(connection).Deliver(ctx)
 	if err != nil {
-		return nil, err
+		return rpcError(o.Name, err)
 	}
 	if err := dk.Send(envelope); err != nil {
-		return nil, err
+		return rpcError(o.Name, err)
 	}
-	var block *common.Block
-	timer := time.NewTimer(time.Second * time.Duration(timeout))
-	defer timer.Stop()
 	for {
-		select {
-		case <-timer.C:
-			return nil, ErrOrdererTimeout
-		default:
-			response, err := dk.Recv()
-			if err != nil {
-				return nil, err
+		response, err := dk.Recv()
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return &TimeoutError{Node: o.Name, Err: ErrOrdererTimeout}
+			}
+			return rpcError(o.Name, err)
+		}
+		switch t := response.Type.(type) {
+		case *orderer.DeliverResponse_Status:
+			if t.Status == common.Status_SUCCESS {
+				return nil
 			}
-			switch t := response.Type.(type) {
-			case *orderer.DeliverResponse_Status:
-				if t.Status == common.Status_SUCCESS {
-					return block, nil
-				} else {
-					return nil, fmt.Errorf("orderer response with status: %v", t.Status)
-				}
-			case *orderer.DeliverResponse_Block:
-				block = response.GetBlock()
-
-			default:
-				return nil, fmt.Errorf("unknown response type from orderer: %s", t)
+			return &DeliverError{Node: o.Name, Status: t.Status}
+		case *orderer.DeliverResponse_Block:
+			if err := fn(t.Block); err != nil {
+				return err
 			}
+		default:
+			return fmt.Errorf("unknown response type from orderer: %s", t)
 		}
 	}
 }
 
-func (o *Orderer) getGenesisBlock(identity Identity, crypto CryptoSuite, channelId string) (*common.Block, error) {
-
-	seekInfo := &orderer.SeekInfo{
-		Start:    &orderer.SeekPosition{Type: &orderer.SeekPosition_Specified{Specified: &orderer.SeekSpecified{Number: 0}}},
-		Stop:     &orderer.SeekPosition{Type: &orderer.SeekPosition_Specified{Specified: &orderer.SeekSpecified{Number: 0}}},
-		Behavior: orderer.SeekInfo_BLOCK_UNTIL_READY,
-	}
-	seekInfoBytes, err := proto.Marshal(seekInfo)
-	if err != nil {
-		return nil, err
+// broadcastClient returns client of orderer broadcast connection, orderer is dialed when it is not connected yet.
+// It is safe for concurrent use.
+func (o *Orderer) broadcastClient(ctx context.Context, dialTimeout time.Duration) (orderer.AtomicBroadcastClient, error) {
+	mu := nodeConnMu(o.connMu)
+	mu.Lock()
+	defer mu.Unlock()
+	if o.con == nil {
+		dialCtx, cancel := withTimeout(ctx, dialTimeout)
+		err := o.connect(dialCtx)
+		cancel()
+		if err != nil {
+			return nil, &ConnectionError{Node: o.Name, Err: err}
+		}
 	}
+	return o.client, nil
+}
 
-	creator, err := marshalProtoIdentity(identity)
-	if err != nil {
-		return nil, err
-	}
-	txId, err := newTransactionId(creator)
-	if err != nil {
-		return nil, err
+// dialTarget returns gRPC target orderer is dialed with
+func (o *Orderer) dialTarget() string {
+	if o.target != "" {
+		return o.target
 	}
+	return o.Uri
+}
+
+// connected returns true when orderer has open broadcast connection
+func (o *Orderer) connected() bool {
+	mu := nodeConnMu(o.connMu)
+	mu.Lock()
+	defer mu.Unlock()
+	return o.con != nil
+}
 
-	headerBytes, err := channelHeader(common.HeaderType_DELIVER_SEEK_INFO, txId, channelId, 0, nil)
-	signatureHeaderBytes, err := signatureHeader(creator, txId)
+// connect dials orderer and creates broadcast client. Caller must hold connection mutex.
+func (o *Orderer) connect(ctx context.Context) error {
+	start := time.Now()
+	c, err := grpc.DialContext(ctx, o.dialTarget(), o.Opts...)
 	if err != nil {
-		return nil, err
+		logger().Warn("cannot connect to orderer", "orderer", o.Name, "endpoint", o.Uri, "error", err)
+		metrics().Connect(o.Name, err)
+		return fmt.Errorf("cannot connect to orderer: %s err is: %v", o.Name, err)
 	}
-	header := header(signatureHeaderBytes, headerBytes)
-	payloadBytes, err := payload(header, seekInfoBytes)
-	if err != nil {
-		return nil, err
+	logger().Debug("connected to orderer", "orderer", o.Name, "endpoint", o.Uri, "duration", time.Since(start))
+	metrics().Connect(o.Name, nil)
+	o.con = c
+	o.client = orderer.NewAtomicBroadcastClient(o.con)
+	return nil
+}
+
+// closeConnection closes broadcast connection to orderer if there is one
+func (o *Orderer) closeConnection() {
+	mu := nodeConnMu(o.connMu)
+	mu.Lock()
+	defer mu.Unlock()
+	if o.con != nil {
+		o.con.Close()
+		o.con, o.client = nil, nil
 	}
-	payloadSignedBytes, err := crypto.Sign(payloadBytes, identity.PrivateKey)
+}
+
+func (o *Orderer) getGenesisBlock(identity Identity, crypto CryptoSuite, channelId string, opts txOptions) (*common.Block, error) {
+	env, err := seekEnvelope(identity, crypto, channelId, seekBlock(0), seekBlock(0), orderer.SeekInfo_BLOCK_UNTIL_READY, opts)
 	if err != nil {
 		return nil, err
 	}
-	env := &common.Envelope{Payload: payloadBytes, Signature: payloadSignedBytes}
 	return o.Deliver(env)
 }
 
-// NewOrdererFromConfig create new Orderer from config
+// NewOrdererFromConfig create new Orderer from config. Orderer does not present client TLS certificate, orderers
+// created by FabricClient present certificate of the client.
 func NewOrdererFromConfig(conf OrdererConfig) (*Orderer, error) {
-	o := Orderer{Uri: conf.Host, caPath: conf.TlsPath}
+	return newOrdererFromConfig("", conf, newClientTls(nil))
+}
+
+// tlsCertHash returns hash of client TLS certificate presented on connection to orderer
+func (o *Orderer) tlsCertHash() []byte {
+	return o.clientTls.certHash()
+}
+
+// newOrdererFromConfig creates orderer named name that presents client certificate ct on standard TLS connections
+func newOrdererFromConfig(name string, conf OrdererConfig, ct *clientTls) (*Orderer, error) {
+	target, transportOpts, err := hostTransport(conf.Host, conf.ServerName)
+	if err != nil {
+		return nil, err
+	}
+	o := Orderer{Name: name, Uri: conf.Host, target: target, caPath: conf.TlsPath, Opts: transportOpts,
+		OperationsUrl: conf.OperationsUrl, connMu: new(sync.Mutex)}
+	if conf.UseTLS && o.caPath == "" && conf.TlsCert == "" && conf.MspPath != "" {
+		if conf.TlsCert, err = mspTlsRootCerts(conf.MspPath); err != nil {
+			return nil, fmt.Errorf("cannot read orderer %s credentials err is: %v", o.Name, err)
+		}
+	}
 	if !conf.UseTLS {
-		o.Opts = []grpc.DialOption{grpc.WithInsecure()}
-	} else if o.caPath != "" {
-		creds, err := credentials.NewClientTLSFromFile(o.caPath, "")
+		o.Opts = append(o.Opts, grpc.WithInsecure())
+	} else if o.caPath != "" || conf.TlsCert != "" {
+		creds, binding, err := nodeTransportCredentials(conf.TlsType, conf.TlsCert, o.caPath, conf.GmTls,
+			nodeTls{name: nodeName(name, conf.Host), client: ct, serverName: tlsServerName(conf.Host, conf.ServerName),
+				mspId: conf.MspId})
 		if err != nil {
 			return nil, fmt.Errorf("cannot read orderer %s credentials err is: %v", o.Name, err)
 		}
+		o.clientTls = binding
 		o.Opts = append(o.Opts, grpc.WithTransportCredentials(creds))
 	}
-	o.Opts = append(o.Opts,
-		grpc.WithKeepaliveParams(keepalive.ClientParameters{
-			Time:                time.Duration(1) * time.Minute,
-			Timeout:             time.Duration(20) * time.Second,
-			PermitWithoutStream: true,
-		}),
-		grpc.WithBlock(),
-		grpc.WithDefaultCallOptions(
-			grpc.MaxCallRecvMsgSize(maxRecvMsgSize),
-			grpc.MaxCallSendMsgSize(maxSendMsgSize)))
+	o.Opts = append(o.Opts, conf.Grpc.dialOptions()...)
 	return &o, nil
 }
@@ -0,0 +1,104 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/orderer"
+)
+
+// GetGenesisBlock fetches block 0 of channel from orderer deliver service. Genesis block is needed to join peers
+// to channel. Orderer waits for the block when channel was just created.
+func (c *FabricClient) GetGenesisBlock(ctx context.Context, identity Identity, channelId, ordererName string) (*common.Block, error) {
+	return c.getOrdererBlock(ctx, identity, channelId, ordererName, seekBlock(0), orderer.SeekInfo_BLOCK_UNTIL_READY)
+}
+
+// GetBlockFromOrderer fetches block number of channel from orderer. Block that does not exist yet is returned as
+// DeliverError with NOT_FOUND status.
+func (c *FabricClient) GetBlockFromOrderer(ctx context.Context, identity Identity, channelId, ordererName string, number uint64) (*common.Block, error) {
+	return c.getOrdererBlock(ctx, identity, channelId, ordererName, seekBlock(number), orderer.SeekInfo_FAIL_IF_NOT_READY)
+}
+
+// GetNewestBlockFromOrderer fetches the last block of channel from orderer
+func (c *FabricClient) GetNewestBlockFromOrderer(ctx context.Context, identity Identity, channelId, ordererName string) (*common.Block, error) {
+	return c.getOrdererBlock(ctx, identity, channelId, ordererName, newest, orderer.SeekInfo_FAIL_IF_NOT_READY)
+}
+
+// GetBlocksFromOrderer fetches blocks from..to (inclusive) of channel from orderer and calls fn for every block in
+// order. Orderer waits for blocks that are not yet committed, so request is limited only by ctx, not by Deliver
+// timeout. Fetching stops when fn returns error, this error is returned.
+func (c *FabricClient) GetBlocksFromOrderer(ctx context.Context, identity Identity, channelId, ordererName string, from, to uint64,
+	fn func(*common.Block) error) error {
+	if from > to {
+		return fmt.Errorf("from: %d cannot be bigger than to: %d", from, to)
+	}
+	ord, ok := c.getOrderer(ordererName)
+	if !ok {
+		return ErrInvalidOrdererName
+	}
+	env, err := seekEnvelope(identity, c.cryptoSuite(identity, channelId), channelId, seekBlock(from), seekBlock(to),
+		orderer.SeekInfo_BLOCK_UNTIL_READY, c.txOptions(channelId).sentTo(ord))
+	if err != nil {
+		return err
+	}
+	return ord.deliverBlocks(ctx, env, fn)
+}
+
+func (c *FabricClient) getOrdererBlock(ctx context.Context, identity Identity, channelId, ordererName string,
+	position *orderer.SeekPosition, behavior orderer.SeekInfo_SeekBehavior) (*common.Block, error) {
+	ord, ok := c.getOrderer(ordererName)
+	if !ok {
+		return nil, ErrInvalidOrdererName
+	}
+	env, err := seekEnvelope(identity, c.cryptoSuite(identity, channelId), channelId, position, position, behavior,
+		c.txOptions(channelId).sentTo(ord))
+	if err != nil {
+		return nil, err
+	}
+	return ord.deliver(ctx, env)
+}
+
+// seekBlock returns position of block number
+func seekBlock(number uint64) *orderer.SeekPosition {
+	return &orderer.SeekPosition{Type: &orderer.SeekPosition_Specified{Specified: &orderer.SeekSpecified{Number: number}}}
+}
+
+// seekEnvelope creates deliver request for blocks start..stop signed by identity
+func seekEnvelope(identity Identity, crypto CryptoSuite, channelId string, start, stop *orderer.SeekPosition,
+	behavior orderer.SeekInfo_SeekBehavior, opts txOptions) (*common.Envelope, error) {
+	seekInfoBytes, err := proto.Marshal(&orderer.SeekInfo{Start: start, Stop: stop, Behavior: behavior})
+	if err != nil {
+		return nil, err
+	}
+	creator, err := marshalProtoIdentity(identity)
+	if err != nil {
+		return nil, err
+	}
+	txId, err := newTransactionId(creator, opts)
+	if err != nil {
+		return nil, err
+	}
+	headerBytes, err := channelHeader(common.HeaderType_DELIVER_SEEK_INFO, txId, channelId, 0, nil, opts.tlsCertHash)
+	if err != nil {
+		return nil, err
+	}
+	signatureHeaderBytes, err := signatureHeader(creator, txId)
+	if err != nil {
+		return nil, err
+	}
+	payloadBytes, err := payload(header(signatureHeaderBytes, headerBytes), seekInfoBytes)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := crypto.Sign(payloadBytes, identity.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &common.Envelope{Payload: payloadBytes, Signature: signature}, nil
+}
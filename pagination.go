@@ -0,0 +1,116 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+)
+
+// Pagination is page request of chaincode query that calls GetQueryResultWithPagination or
+// GetStateByRangeWithPagination. PageSize and Bookmark are appended to chaincode arguments in this order.
+type Pagination struct {
+	PageSize int32
+	// Bookmark is bookmark of previous page, empty for the first page
+	Bookmark string
+}
+
+// QueryPage is single page of paginated chaincode query. Chaincode must return JSON object with records and
+// QueryResponseMetadata of shim, either as fields fetchedRecordsCount and bookmark or in responseMetadata object.
+type QueryPage struct {
+	PeerName string
+	// Request is pagination used for this page
+	Request Pagination
+	// Records are records as returned by chaincode, use Decode to unmarshal them
+	Records             json.RawMessage
+	FetchedRecordsCount int32
+	// Bookmark is passed to the next page request
+	Bookmark string
+}
+
+// pageMetadata is QueryResponseMetadata of shim in chaincode response
+type pageMetadata struct {
+	FetchedRecordsCount int32  `json:"fetchedRecordsCount"`
+	Bookmark            string `json:"bookmark"`
+}
+
+type pagePayload struct {
+	Records json.RawMessage `json:"records"`
+	pageMetadata
+	ResponseMetadata *pageMetadata `json:"responseMetadata"`
+}
+
+// Decode unmarshal records of page into out
+func (p *QueryPage) Decode(out interface{}) error {
+	if len(p.Records) == 0 {
+		return nil
+	}
+	return json.Unmarshal(p.Records, out)
+}
+
+// Last returns true when there are no more pages
+func (p *QueryPage) Last() bool {
+	return p.Bookmark == "" || p.Bookmark == p.Request.Bookmark || p.FetchedRecordsCount < p.Request.PageSize
+}
+
+// Next returns pagination of the next page
+func (p *QueryPage) Next() Pagination {
+	return Pagination{PageSize: p.Request.PageSize, Bookmark: p.Bookmark}
+}
+
+// QueryPage execute paginated chainCode query to peers and returns page from the first successful response.
+// If no peer returns successful response, error from the first peer is returned, *QueryPayloadError when
+// response is not a page.
+func (c *FabricClient) QueryPage(ctx context.Context, identity Identity, chainCode ChainCode, page Pagination,
+	peers []string) (*QueryPage, error) {
+	if page.PageSize <= 0 {
+		return nil, ErrInvalidPageSize
+	}
+	chainCode.Args = append(append([]string(nil), chainCode.Args...), strconv.Itoa(int(page.PageSize)), page.Bookmark)
+	responses, err := c.QueryWithContext(ctx, identity, chainCode, peers)
+	if err != nil {
+		return nil, err
+	}
+	var firstErr error
+	for _, r := range responses {
+		payload := new(pagePayload)
+		err := decodeQueryJSON(r, payload)
+		if err == nil {
+			result := &QueryPage{PeerName: r.PeerName, Request: page, Records: payload.Records,
+				FetchedRecordsCount: payload.FetchedRecordsCount, Bookmark: payload.Bookmark}
+			if m := payload.ResponseMetadata; m != nil {
+				result.FetchedRecordsCount, result.Bookmark = m.FetchedRecordsCount, m.Bookmark
+			}
+			return result, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		return nil, ErrNoValidEndorsementFound
+	}
+	return nil, firstErr
+}
+
+// QueryAllPages calls QueryPage until the last page and calls fn with every page. It stops when fn returns error.
+func (c *FabricClient) QueryAllPages(ctx context.Context, identity Identity, chainCode ChainCode, pageSize int32,
+	peers []string, fn func(page *QueryPage) error) error {
+	request := Pagination{PageSize: pageSize}
+	for {
+		page, err := c.QueryPage(ctx, identity, chainCode, request, peers)
+		if err != nil {
+			return err
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+		if page.Last() {
+			return nil
+		}
+		request = page.Next()
+	}
+}
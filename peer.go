@@ -9,9 +9,8 @@ import (
 	"github.com/hyperledger/fabric/protos/peer"
 	"context"
 	"fmt"
-	"google.golang.org/grpc/credentials"
 	"time"
-	"google.golang.org/grpc/keepalive"
+	"sync"
 )
 
 // Peer expose API's to communicate with peer
@@ -21,8 +20,19 @@ type Peer struct {
 	MspId  string
 	Opts   []grpc.DialOption
 	caPath string
+	// target is gRPC dial target when it differs from Uri
+	target string
 	conn   *grpc.ClientConn
 	client peer.EndorserClient
+	// connMu guards conn and client
+	connMu *sync.Mutex
+	// clientTls is client TLS certificate presented on connections to peer, nil when peer is not dialed with TLS
+	clientTls *clientTls
+
+	// OperationsUrl is base url of peer operations endpoint. Optional.
+	OperationsUrl string
+	// Timeouts limit dial and endorsement, for event peers also dial and commit wait. Optional.
+	Timeouts Timeouts
 }
 
 // PeerResponse is response from peer transaction request
@@ -34,46 +44,148 @@ type PeerResponse struct {
 
 // Endorse sends single transaction to single peer.
 func (p *Peer) Endorse(resp chan *PeerResponse, prop *peer.SignedProposal) {
+	p.endorse(context.Background(), resp, prop, proposalTxId(prop))
+}
+
+// endorse sends single transaction to single peer. Request is canceled when ctx is done. txId is used for logging.
+func (p *Peer) endorse(ctx context.Context, resp chan *PeerResponse, prop *peer.SignedProposal, txId string) {
+	ctx, span := startSpan(ctx, "gohfc.Endorse", "peer", p.Name, "endpoint", p.Uri)
+	defer span.End()
+	timeouts := effectiveTimeouts(ctx, p.Timeouts)
+	_, client, err := p.endorserConnection(ctx, timeouts.Dial)
+	if err != nil {
+		span.RecordError(err)
+		resp <- &PeerResponse{Response: nil, Err: err, Name: p.Name}
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, timeouts.Endorsement)
+	defer cancel()
+	start := time.Now()
+	proposalResp, err := client.ProcessProposal(ctx, prop)
+	metrics().Endorsement(p.Name, time.Since(start), err)
+	if err != nil {
+		logger().Warn("proposal failed", "txId", txId, "peer", p.Name, "endpoint", p.Uri, "duration", time.Since(start),
+			"error", err)
+		span.RecordError(err)
+		resp <- &PeerResponse{Response: nil, Name: p.Name, Err: rpcError(p.Name, err)}
+		return
+	}
+	logger().Debug("proposal endorsed", "txId", txId, "peer", p.Name, "endpoint", p.Uri, "duration", time.Since(start),
+		"status", proposalResp.GetResponse().GetStatus())
+	span.SetAttributes("status", proposalResp.GetResponse().GetStatus())
+	resp <- &PeerResponse{Response: proposalResp, Name: p.Name, Err: nil}
+}
+
+// connectMu guards connections of peers and orderers that were not created by NewPeerFromConfig or
+// NewOrdererFromConfig
+var connectMu sync.Mutex
+
+// nodeConnMu returns mutex that guards connection of peer or orderer
+func nodeConnMu(mu *sync.Mutex) *sync.Mutex {
+	if mu == nil {
+		return &connectMu
+	}
+	return mu
+}
+
+// endorserConnection returns connection and endorser client of peer, peer is dialed when it is not connected yet.
+// It is safe for concurrent use.
+func (p *Peer) endorserConnection(ctx context.Context, dialTimeout time.Duration) (*grpc.ClientConn, peer.EndorserClient, error) {
+	mu := nodeConnMu(p.connMu)
+	mu.Lock()
+	defer mu.Unlock()
 	if p.conn == nil {
-		conn, err := grpc.Dial(p.Uri, p.Opts...)
+		dialCtx, cancel := withTimeout(ctx, dialTimeout)
+		err := p.connect(dialCtx)
+		cancel()
 		if err != nil {
-			resp <- &PeerResponse{Response: nil, Err: err, Name: p.Name}
-			return
+			return nil, nil, &ConnectionError{Node: p.Name, Err: err}
 		}
-		p.conn = conn
-		p.client = peer.NewEndorserClient(p.conn)
 	}
+	return p.conn, p.client, nil
+}
 
-	proposalResp, err := p.client.ProcessProposal(context.Background(), prop)
+// dialTarget returns gRPC target peer is dialed with
+func (p *Peer) dialTarget() string {
+	if p.target != "" {
+		return p.target
+	}
+	return p.Uri
+}
+
+// connected returns true when peer has open connection
+func (p *Peer) connected() bool {
+	mu := nodeConnMu(p.connMu)
+	mu.Lock()
+	defer mu.Unlock()
+	return p.conn != nil
+}
+
+// connect dials peer and creates endorser client. Caller must hold connection mutex.
+func (p *Peer) connect(ctx context.Context) error {
+	start := time.Now()
+	conn, err := grpc.DialContext(ctx, p.dialTarget(), p.Opts...)
 	if err != nil {
-		resp <- &PeerResponse{Response: nil, Name: p.Name, Err: err}
-		return
+		logger().Warn("cannot connect to peer", "peer", p.Name, "endpoint", p.Uri, "error", err)
+		metrics().Connect(p.Name, err)
+		return err
+	}
+	metrics().Connect(p.Name, nil)
+	logger().Debug("connected to peer", "peer", p.Name, "endpoint", p.Uri, "duration", time.Since(start))
+	p.conn = conn
+	p.client = peer.NewEndorserClient(p.conn)
+	return nil
+}
+
+// closeConnection closes connection to peer if there is one
+func (p *Peer) closeConnection() {
+	mu := nodeConnMu(p.connMu)
+	mu.Lock()
+	defer mu.Unlock()
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn, p.client = nil, nil
 	}
-	resp <- &PeerResponse{Response: proposalResp, Name: p.Name, Err: nil}
 }
 
-// NewPeerFromConfig creates new peer from provided config
+// NewPeerFromConfig creates new peer from provided config. Peer does not present client TLS certificate, peers
+// created by FabricClient present certificate of the client.
 func NewPeerFromConfig(conf PeerConfig) (*Peer, error) {
-	p := Peer{Uri: conf.Host, caPath: conf.TlsPath}
+	return newPeerFromConfig("", conf, newClientTls(nil))
+}
+
+// tlsCertHash returns hash of client TLS certificate presented on connection to peer
+func (p *Peer) tlsCertHash() []byte {
+	return p.clientTls.certHash()
+}
+
+// newPeerFromConfig creates peer named name that presents client certificate ct on standard TLS connections
+func newPeerFromConfig(name string, conf PeerConfig, ct *clientTls) (*Peer, error) {
+	target, transportOpts, err := hostTransport(conf.Host, conf.ServerName)
+	if err != nil {
+		return nil, err
+	}
+	p := Peer{Name: name, Uri: conf.Host, MspId: conf.MspId, target: target, caPath: conf.TlsPath, Opts: transportOpts,
+		OperationsUrl: conf.OperationsUrl, connMu: new(sync.Mutex)}
+	if conf.UseTLS && p.caPath == "" && conf.TlsCert == "" && conf.MspPath != "" {
+		if conf.TlsCert, err = mspTlsRootCerts(conf.MspPath); err != nil {
+			return nil, fmt.Errorf("cannot read peer %s credentials err is: %v", p.Name, err)
+		}
+	}
 	if !conf.UseTLS {
-		p.Opts = []grpc.DialOption{grpc.WithInsecure()}
-	} else if p.caPath != "" {
-		creds, err := credentials.NewClientTLSFromFile(p.caPath, "")
+		p.Opts = append(p.Opts, grpc.WithInsecure())
+	} else if p.caPath != "" || conf.TlsCert != "" {
+		creds, binding, err := nodeTransportCredentials(conf.TlsType, conf.TlsCert, p.caPath, conf.GmTls,
+			nodeTls{name: nodeName(name, conf.Host), client: ct, serverName: tlsServerName(conf.Host, conf.ServerName),
+				mspId: conf.MspId})
 		if err != nil {
 			return nil, fmt.Errorf("cannot read peer %s credentials err is: %v", p.Name, err)
 		}
+		p.clientTls = binding
 		p.Opts = append(p.Opts, grpc.WithTransportCredentials(creds))
 	}
 
-	p.Opts = append(p.Opts,
-		grpc.WithKeepaliveParams(keepalive.ClientParameters{
-			Time:                time.Duration(1) * time.Minute,
-			Timeout:             time.Duration(20) * time.Second,
-			PermitWithoutStream: true,
-		}),
-		grpc.WithBlock(),
-		grpc.WithDefaultCallOptions(
-			grpc.MaxCallRecvMsgSize(maxRecvMsgSize),
-			grpc.MaxCallSendMsgSize(maxSendMsgSize)))
+	p.Opts = append(p.Opts, conf.Grpc.dialOptions()...)
 	return &p, nil
 }
@@ -0,0 +1,247 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"hash"
+	"unicode/utf16"
+)
+
+var (
+	oidDataContent       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedContent  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+	oidKeyBag            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidShroudedKeyBag    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidX509Certificate   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidDigestSHA1        = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidDigestSHA256      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidDigestSHA384      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidDigestSHA512      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	pkcs12MacKeyMaterial = byte(3)
+)
+
+// PKCS #12 structures of RFC 7292
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type safeBag struct {
+	Id         asn1.ObjectIdentifier
+	Value      asn1.RawValue   `asn1:"tag:0,explicit"`
+	Attributes []asn1.RawValue `asn1:"set,optional"`
+}
+
+type certBag struct {
+	Id   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+// decodePKCS12 returns private key and certificates of PKCS #12 bundle with PBES2 encrypted or plain contents.
+// ErrUnsupportedKeyEncryption is returned for bundles with legacy encryption.
+func decodePKCS12(data []byte, password string) (interface{}, []*x509.Certificate, error) {
+	var pfx pfxPdu
+	if _, err := asn1.Unmarshal(data, &pfx); err != nil {
+		// BER encoded bundles are left to legacy decoder that converts them to DER
+		return nil, nil, ErrUnsupportedKeyEncryption
+	}
+	if !pfx.AuthSafe.ContentType.Equal(oidDataContent) {
+		return nil, nil, ErrUnsupportedKeyEncryption
+	}
+	var authSafe []byte
+	if _, err := asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return nil, nil, err
+	}
+	if len(pfx.MacData.Mac.Algorithm.Algorithm) > 0 {
+		if err := verifyPKCS12Mac(&pfx.MacData, authSafe, password); err != nil {
+			return nil, nil, err
+		}
+	}
+	var contents []contentInfo
+	if _, err := asn1.Unmarshal(authSafe, &contents); err != nil {
+		return nil, nil, err
+	}
+	var key interface{}
+	var certs []*x509.Certificate
+	for _, ci := range contents {
+		var safeContents []byte
+		switch {
+		case ci.ContentType.Equal(oidDataContent):
+			if _, err := asn1.Unmarshal(ci.Content.Bytes, &safeContents); err != nil {
+				return nil, nil, err
+			}
+		case ci.ContentType.Equal(oidEncryptedContent):
+			var ed encryptedData
+			if _, err := asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil {
+				return nil, nil, err
+			}
+			var err error
+			eci := ed.EncryptedContentInfo
+			if safeContents, err = decryptPBES2(eci.ContentEncryptionAlgorithm, eci.EncryptedContent,
+				[]byte(password)); err != nil {
+				return nil, nil, err
+			}
+		default:
+			return nil, nil, ErrUnsupportedKeyEncryption
+		}
+		var bags []safeBag
+		if _, err := asn1.Unmarshal(safeContents, &bags); err != nil {
+			return nil, nil, ErrKeyDecryption
+		}
+		for _, bag := range bags {
+			switch {
+			case bag.Id.Equal(oidKeyBag), bag.Id.Equal(oidShroudedKeyBag):
+				der := bag.Value.Bytes
+				if bag.Id.Equal(oidShroudedKeyBag) {
+					var err error
+					if der, err = decryptPKCS8(der, []byte(password)); err != nil {
+						return nil, nil, err
+					}
+				}
+				k, err := parsePKCS8PrivateKey(der)
+				if err != nil {
+					return nil, nil, err
+				}
+				key = k
+			case bag.Id.Equal(oidCertBag):
+				var cb certBag
+				if _, err := asn1.Unmarshal(bag.Value.Bytes, &cb); err != nil {
+					return nil, nil, err
+				}
+				if !cb.Id.Equal(oidX509Certificate) {
+					continue
+				}
+				cert, err := parseCertificate(cb.Data)
+				if err != nil {
+					return nil, nil, err
+				}
+				certs = append(certs, cert)
+			}
+		}
+	}
+	return key, certs, nil
+}
+
+// verifyPKCS12Mac checks integrity of authenticated safe with HMAC keyed by password
+func verifyPKCS12Mac(mac *macData, authSafe []byte, password string) error {
+	var newHash func() hash.Hash
+	var blockSize int
+	switch alg := mac.Mac.Algorithm.Algorithm; {
+	case alg.Equal(oidDigestSHA1):
+		newHash, blockSize = sha1.New, 64
+	case alg.Equal(oidDigestSHA256):
+		newHash, blockSize = sha256.New, 64
+	case alg.Equal(oidDigestSHA384):
+		newHash, blockSize = sha512.New384, 128
+	case alg.Equal(oidDigestSHA512):
+		newHash, blockSize = sha512.New, 128
+	default:
+		return ErrUnsupportedKeyEncryption
+	}
+	if mac.Iterations <= 0 || mac.Iterations > maxKdfIterations {
+		return ErrKdfIterations
+	}
+	key := pkcs12KDF(newHash, blockSize, pkcs12MacKeyMaterial, bmpPassword(password), mac.MacSalt, mac.Iterations,
+		newHash().Size())
+	h := hmac.New(newHash, key)
+	h.Write(authSafe)
+	if !hmac.Equal(h.Sum(nil), mac.Mac.Digest) {
+		return ErrKeyDecryption
+	}
+	return nil
+}
+
+// bmpPassword returns password as null terminated big endian UTF-16 string used by PKCS #12 key derivation
+func bmpPassword(password string) []byte {
+	s := utf16.Encode([]rune(password))
+	b := make([]byte, 0, 2*len(s)+2)
+	for _, c := range s {
+		b = append(b, byte(c>>8), byte(c))
+	}
+	return append(b, 0, 0)
+}
+
+// pkcs12KDF derives size bytes of key material of type id as defined in RFC 7292 appendix B.2
+func pkcs12KDF(newHash func() hash.Hash, v int, id byte, password, salt []byte, iterations, size int) []byte {
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		out := make([]byte, v*((len(b)+v-1)/v))
+		for i := range out {
+			out[i] = b[i%len(b)]
+		}
+		return out
+	}
+	d := make([]byte, v)
+	for i := range d {
+		d[i] = id
+	}
+	I := append(fill(salt), fill(password)...)
+	var out []byte
+	for len(out) < size {
+		h := newHash()
+		h.Write(d)
+		h.Write(I)
+		a := h.Sum(nil)
+		for i := 1; i < iterations; i++ {
+			h.Reset()
+			h.Write(a)
+			a = h.Sum(a[:0])
+		}
+		out = append(out, a...)
+		b := make([]byte, v)
+		for i := range b {
+			b[i] = a[i%len(a)]
+		}
+		// I_j = (I_j + B + 1) mod 2^(8v) for every v bytes block of I
+		for j := 0; j < len(I); j += v {
+			carry := 1
+			for k := v - 1; k >= 0; k-- {
+				sum := int(I[j+k]) + int(b[k]) + carry
+				I[j+k] = byte(sum)
+				carry = sum >> 8
+			}
+		}
+	}
+	return out[:size]
+}
@@ -40,59 +40,129 @@ func defaultPolicy(mspid string) (*common.SignaturePolicyEnvelope, error) {
 	return p, nil
 }
 
-func CollectionConfigToPolicy(col []CollectionConfig) ([]*common.CollectionConfig, error) {
-	// validation. Same names are not allowed, min/max peer count must be =>0, at least one org
-	collectionNames := make(map[string]bool)
-	for _, c := range col {
-		if len(c.Name) < 1 {
-			return nil, ErrCollectionNameMissing
+// Validate checks collection config before it is send to peers
+func (c CollectionConfig) Validate() error {
+	if len(c.Name) < 1 {
+		return ErrCollectionNameMissing
+	}
+	if c.RequiredPeersCount < 0 {
+		return ErrRequiredPeerCountNegative
+	}
+	if c.MaximumPeersCount < 0 {
+		return ErrMaxPeerCountNegative
+	}
+	if c.MaximumPeersCount < c.RequiredPeersCount {
+		return ErrMaxPeerCountLestThanMinimum
+	}
+	if e := c.EndorsementPolicy; e != nil {
+		if (e.SignaturePolicy == "") == (e.ChannelConfigPolicy == "") {
+			return ErrCollectionEndorsementPolicy
 		}
-		if _, ok := collectionNames[c.Name]; ok {
-			return nil, ErrCollectionNameExists
+		if e.SignaturePolicy != "" {
+			if _, err := ParsePolicy(e.SignaturePolicy); err != nil {
+				return err
+			}
 		}
-
-		if c.RequiredPeersCount < 0 {
-			return nil, ErrRequiredPeerCountNegative
+	}
+	if len(c.Policy) > 0 {
+		_, err := ParsePolicy(c.Policy)
+		return err
+	}
+	if len(c.Organizations) == 0 {
+		return ErrAtLeastOneOrgNeeded
+	}
+	for _, org := range c.Organizations {
+		if len(org) == 0 {
+			return ErrOrganizationNameMissing
 		}
+	}
+	return nil
+}
 
-		if c.MaximumPeersCount < 0 {
-			return nil, ErrMaxPeerCountNegative
+// CollectionConfigToPolicy validates collections and converts them to collection configs. BlockToLive,
+// MemberOnlyRead, MemberOnlyWrite and EndorsementPolicy are not part of vendored Fabric protos and are missing in
+// result, MarshalCollectionsConfig includes them.
+func CollectionConfigToPolicy(col []CollectionConfig) ([]*common.CollectionConfig, error) {
+	static, err := staticCollectionConfigs(col)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*common.CollectionConfig, 0, len(static))
+	for _, s := range static {
+		collection := &common.CollectionConfig{
+			Payload: &common.CollectionConfig_StaticCollectionConfig{
+				StaticCollectionConfig: &common.StaticCollectionConfig{
+					Name:              s.Name,
+					RequiredPeerCount: s.RequiredPeerCount,
+					MaximumPeerCount:  s.MaximumPeerCount,
+					MemberOrgsPolicy:  s.MemberOrgsPolicy,
+				},
+			},
 		}
+		result = append(result, collection)
+	}
+	return result, nil
+}
 
-		if c.MaximumPeersCount < c.RequiredPeersCount {
-			return nil, ErrMaxPeerCountLestThanMinimum
-		}
-		if len(c.Organizations) == 0 {
-			return nil, ErrAtLeastOneOrgNeeded
-		}
+// MarshalCollectionsConfig validates collections and returns them as marshaled CollectionConfigPackage, as it is
+// sent to peers on instantiate, upgrade or approve of chaincode definition.
+func MarshalCollectionsConfig(col []CollectionConfig) ([]byte, error) {
+	static, err := staticCollectionConfigs(col)
+	if err != nil {
+		return nil, err
+	}
+	pkg := &collectionConfigPackage{Config: make([]*collectionConfig, len(static))}
+	for i, s := range static {
+		pkg.Config[i] = &collectionConfig{StaticCollectionConfig: s}
+	}
+	return proto.Marshal(pkg)
+}
 
-		for _, org := range c.Organizations {
-			if len(org) == 0 {
-				return nil, ErrOrganizationNameMissing
-			}
+func staticCollectionConfigs(col []CollectionConfig) ([]*staticCollectionConfig, error) {
+	// validation. Same names are not allowed, min/max peer count must be =>0, at least one org
+	collectionNames := make(map[string]bool)
+	for _, c := range col {
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := collectionNames[c.Name]; ok {
+			return nil, ErrCollectionNameExists
 		}
 		collectionNames[c.Name] = true
 	}
 
-	result := make([]*common.CollectionConfig, 0, len(col))
+	result := make([]*staticCollectionConfig, 0, len(col))
 	for _, c := range col {
-		sig, err := signedByAnyOfGivenRole(msp.MSPRole_MEMBER, c.Organizations)
+		var sig *common.SignaturePolicyEnvelope
+		var err error
+		if len(c.Policy) > 0 {
+			sig, err = ParsePolicy(c.Policy)
+		} else {
+			sig, err = signedByAnyOfGivenRole(msp.MSPRole_MEMBER, c.Organizations)
+		}
 		if err != nil {
 			return nil, err
 		}
-		collection := &common.CollectionConfig{
-			Payload: &common.CollectionConfig_StaticCollectionConfig{
-				StaticCollectionConfig: &common.StaticCollectionConfig{
-					Name:              c.Name,
-					RequiredPeerCount: c.RequiredPeersCount,
-					MaximumPeerCount:  c.MaximumPeersCount,
-					MemberOrgsPolicy: &common.CollectionPolicyConfig{
-						Payload: &common.CollectionPolicyConfig_SignaturePolicy{
-							SignaturePolicy: sig,
-						},
-					},
+		collection := &staticCollectionConfig{
+			Name:              c.Name,
+			RequiredPeerCount: c.RequiredPeersCount,
+			MaximumPeerCount:  c.MaximumPeersCount,
+			MemberOrgsPolicy: &common.CollectionPolicyConfig{
+				Payload: &common.CollectionPolicyConfig_SignaturePolicy{
+					SignaturePolicy: sig,
 				},
 			},
+			BlockToLive:     c.BlockToLive,
+			MemberOnlyRead:  c.MemberOnlyRead,
+			MemberOnlyWrite: c.MemberOnlyWrite,
+		}
+		if e := c.EndorsementPolicy; e != nil {
+			collection.EndorsementPolicy = &applicationPolicy{ChannelConfigPolicyReference: e.ChannelConfigPolicy}
+			if e.SignaturePolicy != "" {
+				if collection.EndorsementPolicy.SignaturePolicy, err = ParsePolicy(e.SignaturePolicy); err != nil {
+					return nil, err
+				}
+			}
 		}
 		result = append(result, collection)
 	}
@@ -0,0 +1,263 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+)
+
+// ChannelWritersPolicy is the policy orderer checks before accepting transaction to the channel
+const ChannelWritersPolicy = "/Channel/Writers"
+
+// PolicyError is returned when identity does not satisfy channel policy
+type PolicyError struct {
+	Policy string
+	MspId  string
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%v: identity from %s does not satisfy %s: %s", ErrPolicyNotSatisfied, e.MspId, e.Policy, e.Reason)
+}
+
+// Is allows errors.Is(err, ErrPolicyNotSatisfied) to match PolicyError
+func (e *PolicyError) Is(target error) bool {
+	return errors.Is(ErrPolicyNotSatisfied, target)
+}
+
+type writersCheckKey struct{}
+
+// WithWritersCheck attach channel config to ctx. InvokeWithContext and InvokeWithQuorum check that signing identity
+// satisfies ChannelWritersPolicy before proposal is send, so missing permission is reported with PolicyError
+// instead of FORBIDDEN status from orderer after endorsement. Check is done only for transactions on the channel
+// of config. Channel config can be fetched with GetConfigBlock and reused for many invocations.
+func WithWritersCheck(ctx context.Context, config *ChannelConfig) context.Context {
+	return context.WithValue(ctx, writersCheckKey{}, config)
+}
+
+// checkWritersFromContext checks identity if ctx has channel config for channelId attached
+func checkWritersFromContext(ctx context.Context, channelId string, identity Identity) error {
+	config, ok := ctx.Value(writersCheckKey{}).(*ChannelConfig)
+	if !ok || config == nil || config.ChannelId != channelId {
+		return nil
+	}
+	return config.CheckWriter(identity)
+}
+
+// CheckWriter checks that identity satisfies ChannelWritersPolicy
+func (cc *ChannelConfig) CheckWriter(identity Identity) error {
+	return cc.EvaluatePolicy(ChannelWritersPolicy, identity)
+}
+
+// EvaluatePolicy checks that signature of identity alone satisfies policy with full path like /Channel/Writers or
+// /Channel/Application/Org1MSP/Admins. Returns *PolicyError with the reason when it does not.
+func (cc *ChannelConfig) EvaluatePolicy(path string, identity Identity) error {
+	if identity.Certificate == nil {
+		return &PolicyError{Policy: path, MspId: identity.MspId, Reason: "identity has no certificate"}
+	}
+	if reason := cc.evaluatePolicy(path, identity); reason != "" {
+		return &PolicyError{Policy: path, MspId: identity.MspId, Reason: reason}
+	}
+	return nil
+}
+
+// evaluatePolicy returns reason why policy is not satisfied or empty string if it is
+func (cc *ChannelConfig) evaluatePolicy(path string, identity Identity) string {
+	policy, ok := cc.Policies[path]
+	if !ok {
+		return fmt.Sprintf("policy %s is not defined", path)
+	}
+	switch {
+	case policy.Signature != nil:
+		used := []bool{false}
+		var reasons []string
+		if cc.evaluateSignatureRule(policy.Signature.Rule, policy.Signature.Identities, identity, used, &reasons) {
+			return ""
+		}
+		expr, err := PolicyToString(policy.Signature)
+		if err != nil {
+			expr = "signature policy"
+		}
+		if len(reasons) > 0 {
+			return fmt.Sprintf("%s requires %s (%s)", path, expr, strings.Join(reasons, "; "))
+		}
+		return fmt.Sprintf("%s requires %s", path, expr)
+	case policy.ImplicitMeta != nil:
+		return cc.evaluateImplicitMeta(path, policy.ImplicitMeta, identity)
+	default:
+		return fmt.Sprintf("policy %s has unsupported type %s", path, policy.Type)
+	}
+}
+
+// evaluateImplicitMeta evaluates sub policies of all groups directly below the group of path
+func (cc *ChannelConfig) evaluateImplicitMeta(path string, policy *common.ImplicitMetaPolicy, identity Identity) string {
+	group := path[:strings.LastIndex(path, "/")]
+	var subPaths []string
+	for p := range cc.Policies {
+		if !strings.HasPrefix(p, group+"/") {
+			continue
+		}
+		rest := strings.TrimPrefix(p, group+"/")
+		if !strings.HasSuffix(rest, "/"+policy.SubPolicy) {
+			continue
+		}
+		child := strings.TrimSuffix(rest, "/"+policy.SubPolicy)
+		if child != "" && !strings.Contains(child, "/") {
+			subPaths = append(subPaths, p)
+		}
+	}
+	sort.Strings(subPaths)
+	satisfied := 0
+	var reasons []string
+	for _, p := range subPaths {
+		if reason := cc.evaluatePolicy(p, identity); reason != "" {
+			reasons = append(reasons, reason)
+		} else {
+			satisfied++
+		}
+	}
+	var required int
+	switch policy.Rule {
+	case common.ImplicitMetaPolicy_ANY:
+		required = 1
+	case common.ImplicitMetaPolicy_ALL:
+		required = len(subPaths)
+	case common.ImplicitMetaPolicy_MAJORITY:
+		required = len(subPaths)/2 + 1
+	}
+	if satisfied >= required && len(subPaths) > 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s requires %s %s of %d groups, %d satisfied [%s]", path, policy.Rule, policy.SubPolicy,
+		len(subPaths), satisfied, strings.Join(reasons, "; "))
+}
+
+// evaluateSignatureRule evaluates rule same way as Fabric does, every identity can satisfy only one SignedBy rule
+func (cc *ChannelConfig) evaluateSignatureRule(rule *common.SignaturePolicy, principals []*msp.MSPPrincipal, identity Identity, used []bool, reasons *[]string) bool {
+	switch t := rule.Type.(type) {
+	case *common.SignaturePolicy_SignedBy:
+		if used[0] {
+			return false
+		}
+		if t.SignedBy < 0 || int(t.SignedBy) >= len(principals) {
+			*reasons = append(*reasons, fmt.Sprintf("principal index %d out of range", t.SignedBy))
+			return false
+		}
+		if reason := cc.matchPrincipal(principals[t.SignedBy], identity); reason != "" {
+			*reasons = append(*reasons, reason)
+			return false
+		}
+		used[0] = true
+		return true
+	case *common.SignaturePolicy_NOutOf_:
+		verified := int32(0)
+		branch := make([]bool, len(used))
+		for _, r := range t.NOutOf.Rules {
+			copy(branch, used)
+			if cc.evaluateSignatureRule(r, principals, identity, branch, reasons) {
+				verified++
+				copy(used, branch)
+			}
+		}
+		return verified >= t.NOutOf.N
+	}
+	return false
+}
+
+// matchPrincipal returns reason why identity is not principal or empty string if it is
+func (cc *ChannelConfig) matchPrincipal(principal *msp.MSPPrincipal, identity Identity) string {
+	switch principal.PrincipalClassification {
+	case msp.MSPPrincipal_ROLE:
+		role := new(msp.MSPRole)
+		if err := proto.Unmarshal(principal.Principal, role); err != nil {
+			return fmt.Sprintf("invalid role principal: %v", err)
+		}
+		if reason := cc.matchMember(role.MspIdentifier, identity); reason != "" {
+			return reason
+		}
+		switch role.Role {
+		case msp.MSPRole_MEMBER:
+			return ""
+		case msp.MSPRole_ADMIN:
+			org, _ := cc.Org(role.MspIdentifier)
+			for _, admin := range org.Admins {
+				if block, _ := pem.Decode(admin); block != nil && bytes.Equal(block.Bytes, identity.Certificate.Raw) {
+					return ""
+				}
+			}
+			if hasOU(identity, "admin") {
+				return ""
+			}
+			return fmt.Sprintf("identity is not admin of %s", role.MspIdentifier)
+		case msp.MSPRole_CLIENT, msp.MSPRole_PEER:
+			ou := strings.ToLower(role.Role.String())
+			if hasOU(identity, ou) {
+				return ""
+			}
+			return fmt.Sprintf("identity certificate has no %s OU", ou)
+		default:
+			return fmt.Sprintf("unsupported role %s", role.Role)
+		}
+	case msp.MSPPrincipal_ORGANIZATION_UNIT:
+		unit := new(msp.OrganizationUnit)
+		if err := proto.Unmarshal(principal.Principal, unit); err != nil {
+			return fmt.Sprintf("invalid organization unit principal: %v", err)
+		}
+		if reason := cc.matchMember(unit.MspIdentifier, identity); reason != "" {
+			return reason
+		}
+		if hasOU(identity, unit.OrganizationalUnitIdentifier) {
+			return ""
+		}
+		return fmt.Sprintf("identity certificate has no %s OU", unit.OrganizationalUnitIdentifier)
+	case msp.MSPPrincipal_IDENTITY:
+		id := new(msp.SerializedIdentity)
+		if err := proto.Unmarshal(principal.Principal, id); err != nil {
+			return fmt.Sprintf("invalid identity principal: %v", err)
+		}
+		if block, _ := pem.Decode(id.IdBytes); id.Mspid == identity.MspId && block != nil &&
+			bytes.Equal(block.Bytes, identity.Certificate.Raw) {
+			return ""
+		}
+		return "identity is not the identity required by policy"
+	default:
+		return fmt.Sprintf("unsupported principal %s", principal.PrincipalClassification)
+	}
+}
+
+// matchMember returns reason why identity is not valid member of MSP or empty string if it is
+func (cc *ChannelConfig) matchMember(mspId string, identity Identity) string {
+	if identity.MspId != mspId {
+		return fmt.Sprintf("identity is not member of %s", mspId)
+	}
+	org, ok := cc.Org(mspId)
+	if !ok {
+		return fmt.Sprintf("organization %s is not member of channel %s", mspId, cc.ChannelId)
+	}
+	if err := verifyCertificateChain(org, identity.Certificate, time.Now()); err != nil {
+		return fmt.Sprintf("identity certificate is not valid for %s: %v", mspId, err)
+	}
+	return ""
+}
+
+func hasOU(identity Identity, ou string) bool {
+	for _, u := range identity.Certificate.Subject.OrganizationalUnit {
+		if strings.EqualFold(u, ou) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,415 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+)
+
+// ParsePolicy parse endorsement policy in the same format used by peer CLI, for example:
+//
+//	AND('Org1MSP.member', OR('Org2MSP.admin', 'Org3MSP.peer'))
+//	OutOf(2, 'Org1MSP.member', 'Org2MSP.member', 'Org3MSP.member')
+//
+// Supported roles are member, admin, client and peer. Principals are quoted with single or double quotes.
+func ParsePolicy(policy string) (*common.SignaturePolicyEnvelope, error) {
+	p := &policyParser{input: policy, principals: make(map[string]int32)}
+	rule, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos != len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos:])
+	}
+	return &common.SignaturePolicyEnvelope{Version: 0, Rule: rule, Identities: p.identities}, nil
+}
+
+type policyParser struct {
+	input      string
+	pos        int
+	identities []*msp.MSPPrincipal
+	// principals maps principal string to its index in identities, so same principal is used only once
+	principals map[string]int32
+}
+
+func (p *policyParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid policy at position %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *policyParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *policyParser) consume(c byte) error {
+	p.skipSpaces()
+	if p.pos >= len(p.input) || p.input[p.pos] != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *policyParser) parseExpression() (*common.SignaturePolicy, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return nil, p.errorf("unexpected end of policy")
+	}
+	if c := p.input[p.pos]; c == '\'' || c == '"' {
+		return p.parsePrincipal()
+	}
+	start := p.pos
+	for p.pos < len(p.input) && unicode.IsLetter(rune(p.input[p.pos])) {
+		p.pos++
+	}
+	name := p.input[start:p.pos]
+	if err := p.consume('('); err != nil {
+		return nil, err
+	}
+	var n int32
+	switch strings.ToLower(name) {
+	case "and", "or":
+	case "outof":
+		p.skipSpaces()
+		numStart := p.pos
+		for p.pos < len(p.input) && unicode.IsDigit(rune(p.input[p.pos])) {
+			p.pos++
+		}
+		num, err := strconv.Atoi(p.input[numStart:p.pos])
+		if err != nil {
+			return nil, p.errorf("OutOf expects number as first argument")
+		}
+		n = int32(num)
+		if err := p.consume(','); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid policy: unknown operator %q", name)
+	}
+	var rules []*common.SignaturePolicy
+	for {
+		rule, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+		p.skipSpaces()
+		if p.pos < len(p.input) && p.input[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if err := p.consume(')'); err != nil {
+			return nil, err
+		}
+		break
+	}
+	switch strings.ToLower(name) {
+	case "and":
+		n = int32(len(rules))
+	case "or":
+		n = 1
+	}
+	if n < 1 || int(n) > len(rules) {
+		return nil, fmt.Errorf("invalid policy: %s requires between 1 and %d signatures, got %d", name, len(rules), n)
+	}
+	return &common.SignaturePolicy{
+		Type: &common.SignaturePolicy_NOutOf_{
+			NOutOf: &common.SignaturePolicy_NOutOf{N: n, Rules: rules},
+		},
+	}, nil
+}
+
+func (p *policyParser) parsePrincipal() (*common.SignaturePolicy, error) {
+	quote := p.input[p.pos]
+	p.pos++
+	end := strings.IndexByte(p.input[p.pos:], quote)
+	if end < 0 {
+		return nil, p.errorf("unterminated principal")
+	}
+	principal := p.input[p.pos : p.pos+end]
+	p.pos += end + 1
+
+	idx, ok := p.principals[principal]
+	if !ok {
+		dot := strings.LastIndex(principal, ".")
+		if dot < 1 || dot == len(principal)-1 {
+			return nil, fmt.Errorf("invalid policy: principal %q must be in MSPID.role format", principal)
+		}
+		role, err := parsePolicyRole(principal[dot+1:])
+		if err != nil {
+			return nil, err
+		}
+		marshalPrincipal, err := proto.Marshal(&msp.MSPRole{Role: role, MspIdentifier: principal[:dot]})
+		if err != nil {
+			return nil, err
+		}
+		idx = int32(len(p.identities))
+		p.identities = append(p.identities, &msp.MSPPrincipal{
+			PrincipalClassification: msp.MSPPrincipal_ROLE,
+			Principal:               marshalPrincipal,
+		})
+		p.principals[principal] = idx
+	}
+	return &common.SignaturePolicy{Type: &common.SignaturePolicy_SignedBy{SignedBy: idx}}, nil
+}
+
+func parsePolicyRole(role string) (msp.MSPRole_MSPRoleType, error) {
+	switch strings.ToLower(role) {
+	case "member":
+		return msp.MSPRole_MEMBER, nil
+	case "admin":
+		return msp.MSPRole_ADMIN, nil
+	case "client":
+		return msp.MSPRole_CLIENT, nil
+	case "peer":
+		return msp.MSPRole_PEER, nil
+	default:
+		return 0, fmt.Errorf("invalid policy: unknown role %q", role)
+	}
+}
+
+// PolicyPrincipal returns principal for policy expression like 'Org1MSP.member'
+func PolicyPrincipal(mspId, role string) string {
+	return "'" + mspId + "." + role + "'"
+}
+
+// PolicyAnd returns policy expression that requires all rules to be satisfied
+func PolicyAnd(rules ...string) string {
+	return "AND(" + strings.Join(rules, ", ") + ")"
+}
+
+// PolicyOr returns policy expression that requires any of the rules to be satisfied
+func PolicyOr(rules ...string) string {
+	return "OR(" + strings.Join(rules, ", ") + ")"
+}
+
+// PolicyOutOf returns policy expression that requires n of the rules to be satisfied
+func PolicyOutOf(n int, rules ...string) string {
+	return "OutOf(" + strconv.Itoa(n) + ", " + strings.Join(rules, ", ") + ")"
+}
+
+// PolicyToString converts signature policy to expression that can be parsed with ParsePolicy.
+// Only role principals are supported.
+func PolicyToString(policy *common.SignaturePolicyEnvelope) (string, error) {
+	principals := make([]string, len(policy.Identities))
+	for i, id := range policy.Identities {
+		role, err := principalRole(id)
+		if err != nil {
+			return "", err
+		}
+		principals[i] = PolicyPrincipal(role.MspIdentifier, strings.ToLower(role.Role.String()))
+	}
+	return ruleToString(policy.Rule, principals)
+}
+
+func ruleToString(rule *common.SignaturePolicy, principals []string) (string, error) {
+	switch r := rule.GetType().(type) {
+	case *common.SignaturePolicy_SignedBy:
+		if r.SignedBy < 0 || int(r.SignedBy) >= len(principals) {
+			return "", fmt.Errorf("invalid policy: signed by unknown identity %d", r.SignedBy)
+		}
+		return principals[r.SignedBy], nil
+	case *common.SignaturePolicy_NOutOf_:
+		rules := make([]string, len(r.NOutOf.Rules))
+		for i, sub := range r.NOutOf.Rules {
+			s, err := ruleToString(sub, principals)
+			if err != nil {
+				return "", err
+			}
+			rules[i] = s
+		}
+		switch {
+		case int(r.NOutOf.N) == len(rules):
+			return PolicyAnd(rules...), nil
+		case r.NOutOf.N == 1:
+			return PolicyOr(rules...), nil
+		default:
+			return PolicyOutOf(int(r.NOutOf.N), rules...), nil
+		}
+	default:
+		return "", fmt.Errorf("invalid policy: unknown rule type")
+	}
+}
+
+func principalRole(id *msp.MSPPrincipal) (*msp.MSPRole, error) {
+	if id.PrincipalClassification != msp.MSPPrincipal_ROLE {
+		return nil, fmt.Errorf("invalid policy: principal classification %s is not supported", id.PrincipalClassification)
+	}
+	role := new(msp.MSPRole)
+	if err := proto.Unmarshal(id.Principal, role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// PolicyDefinition is JSON form of signature policy, same as the one used in fabric-sdk-node:
+//
+//	{
+//	  "identities": [
+//	    {"role": {"name": "member", "mspId": "Org1MSP"}},
+//	    {"role": {"name": "member", "mspId": "Org2MSP"}}
+//	  ],
+//	  "policy": {"2-of": [{"signed-by": 0}, {"signed-by": 1}]}
+//	}
+type PolicyDefinition struct {
+	Identities []PolicyIdentity `json:"identities"`
+	Policy     *PolicyRule      `json:"policy"`
+}
+
+// PolicyIdentity is identity in PolicyDefinition
+type PolicyIdentity struct {
+	Role PolicyRole `json:"role"`
+}
+
+// PolicyRole is MSP role in PolicyIdentity
+type PolicyRole struct {
+	Name  string `json:"name"`
+	MspId string `json:"mspId"`
+}
+
+// PolicyRule is rule in PolicyDefinition. Either SignedBy is set, or N and Rules.
+type PolicyRule struct {
+	SignedBy *int32
+	N        int32
+	Rules    []*PolicyRule
+}
+
+// MarshalJSON encodes rule as {"signed-by": idx} or {"N-of": [rules]}
+func (r *PolicyRule) MarshalJSON() ([]byte, error) {
+	if r.SignedBy != nil {
+		return json.Marshal(map[string]int32{"signed-by": *r.SignedBy})
+	}
+	return json.Marshal(map[string][]*PolicyRule{fmt.Sprintf("%d-of", r.N): r.Rules})
+}
+
+// UnmarshalJSON decodes rule from {"signed-by": idx} or {"N-of": [rules]}
+func (r *PolicyRule) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 1 {
+		return fmt.Errorf("invalid policy: rule must have exactly one key")
+	}
+	for key, value := range raw {
+		if key == "signed-by" {
+			var idx int32
+			if err := json.Unmarshal(value, &idx); err != nil {
+				return err
+			}
+			r.SignedBy = &idx
+			return nil
+		}
+		if !strings.HasSuffix(key, "-of") {
+			return fmt.Errorf("invalid policy: unknown rule %q", key)
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(key, "-of"))
+		if err != nil {
+			return fmt.Errorf("invalid policy: unknown rule %q", key)
+		}
+		r.N = int32(n)
+		return json.Unmarshal(value, &r.Rules)
+	}
+	return nil
+}
+
+// ParsePolicyJSON converts JSON form of policy (see PolicyDefinition) to signature policy
+func ParsePolicyJSON(data []byte) (*common.SignaturePolicyEnvelope, error) {
+	def := new(PolicyDefinition)
+	if err := json.Unmarshal(data, def); err != nil {
+		return nil, err
+	}
+	if def.Policy == nil {
+		return nil, fmt.Errorf("invalid policy: policy is missing")
+	}
+	identities := make([]*msp.MSPPrincipal, len(def.Identities))
+	for i, id := range def.Identities {
+		role, err := parsePolicyRole(id.Role.Name)
+		if err != nil {
+			return nil, err
+		}
+		principal, err := proto.Marshal(&msp.MSPRole{Role: role, MspIdentifier: id.Role.MspId})
+		if err != nil {
+			return nil, err
+		}
+		identities[i] = &msp.MSPPrincipal{PrincipalClassification: msp.MSPPrincipal_ROLE, Principal: principal}
+	}
+	rule, err := policyRuleToProto(def.Policy, len(identities))
+	if err != nil {
+		return nil, err
+	}
+	return &common.SignaturePolicyEnvelope{Version: 0, Rule: rule, Identities: identities}, nil
+}
+
+func policyRuleToProto(r *PolicyRule, identities int) (*common.SignaturePolicy, error) {
+	if r.SignedBy != nil {
+		if *r.SignedBy < 0 || int(*r.SignedBy) >= identities {
+			return nil, fmt.Errorf("invalid policy: signed by unknown identity %d", *r.SignedBy)
+		}
+		return &common.SignaturePolicy{Type: &common.SignaturePolicy_SignedBy{SignedBy: *r.SignedBy}}, nil
+	}
+	if r.N < 1 || int(r.N) > len(r.Rules) {
+		return nil, fmt.Errorf("invalid policy: %d-of requires between 1 and %d signatures", r.N, len(r.Rules))
+	}
+	rules := make([]*common.SignaturePolicy, len(r.Rules))
+	for i, sub := range r.Rules {
+		rule, err := policyRuleToProto(sub, identities)
+		if err != nil {
+			return nil, err
+		}
+		rules[i] = rule
+	}
+	return &common.SignaturePolicy{
+		Type: &common.SignaturePolicy_NOutOf_{NOutOf: &common.SignaturePolicy_NOutOf{N: r.N, Rules: rules}},
+	}, nil
+}
+
+// PolicyToJSON converts signature policy to JSON form (see PolicyDefinition)
+func PolicyToJSON(policy *common.SignaturePolicyEnvelope) ([]byte, error) {
+	def := &PolicyDefinition{Identities: make([]PolicyIdentity, len(policy.Identities))}
+	for i, id := range policy.Identities {
+		role, err := principalRole(id)
+		if err != nil {
+			return nil, err
+		}
+		def.Identities[i] = PolicyIdentity{Role: PolicyRole{Name: strings.ToLower(role.Role.String()), MspId: role.MspIdentifier}}
+	}
+	rule, err := protoToPolicyRule(policy.Rule)
+	if err != nil {
+		return nil, err
+	}
+	def.Policy = rule
+	return json.Marshal(def)
+}
+
+func protoToPolicyRule(rule *common.SignaturePolicy) (*PolicyRule, error) {
+	switch r := rule.GetType().(type) {
+	case *common.SignaturePolicy_SignedBy:
+		idx := r.SignedBy
+		return &PolicyRule{SignedBy: &idx}, nil
+	case *common.SignaturePolicy_NOutOf_:
+		result := &PolicyRule{N: r.NOutOf.N, Rules: make([]*PolicyRule, len(r.NOutOf.Rules))}
+		for i, sub := range r.NOutOf.Rules {
+			converted, err := protoToPolicyRule(sub)
+			if err != nil {
+				return nil, err
+			}
+			result.Rules[i] = converted
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("invalid policy: unknown rule type")
+	}
+}
@@ -0,0 +1,199 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/CognitionFoundry/gohfc/blockparser"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+// QueryBlockResponse holds block returned from particular peer
+type QueryBlockResponse struct {
+	PeerName string
+	Error    error
+	// RawBlock is block as returned from peer
+	RawBlock *common.Block
+	// Block is decoded block
+	Block *blockparser.Block
+}
+
+// QueryBlockByNumber get block with particular number from channel in peer/s
+func (c *FabricClient) QueryBlockByNumber(identity Identity, channelId string, number uint64, peers []string) ([]*QueryBlockResponse, error) {
+	return c.queryBlock(identity, channelId, []string{"GetBlockByNumber", channelId, strconv.FormatUint(number, 10)}, nil, peers)
+}
+
+// QueryBlockByHash get block with particular hash from channel in peer/s
+func (c *FabricClient) QueryBlockByHash(identity Identity, channelId string, hash []byte, peers []string) ([]*QueryBlockResponse, error) {
+	return c.queryBlock(identity, channelId, []string{"GetBlockByHash", channelId}, hash, peers)
+}
+
+// QueryBlockByTxID get block containing transaction with particular id from channel in peer/s
+func (c *FabricClient) QueryBlockByTxID(identity Identity, channelId string, txId string, peers []string) ([]*QueryBlockResponse, error) {
+	return c.queryBlock(identity, channelId, []string{"GetBlockByTxID", channelId, txId}, nil, peers)
+}
+
+// QueryTransaction get transaction with particular id from channel in peer/s. Response holds validation code and
+// decoded transaction with chaincode input, endorsements and read/write sets.
+func (c *FabricClient) QueryTransaction(identity Identity, channelId string, txId string, peers []string) ([]*QueryTransactionResponse, error) {
+	r, err := c.queryQscc(identity, channelId, []string{"GetTransactionByID", channelId, txId}, nil, peers)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]*QueryTransactionResponse, len(r))
+	for idx, p := range r {
+		qtr := QueryTransactionResponse{PeerName: p.Name, Error: p.Err}
+		if p.Err == nil {
+			code, tx, err := decodeTransaction(p.Response.Response.GetPayload())
+			if err != nil {
+				qtr.Error = err
+			}
+			qtr.StatusCode = code
+			qtr.Transaction = tx
+			if tx != nil {
+				qtr.ValidationCode = tx.ValidationCode
+			}
+		}
+		response[idx] = &qtr
+	}
+	return response, nil
+}
+
+// QueryChainInfo get height, current block hash and previous block hash of channel from peer/s with QSCC
+// GetChainInfo. Responses with status other than 200 are returned as errors.
+func (c *FabricClient) QueryChainInfo(identity Identity, channelId string, peers []string) ([]*QueryChannelInfoResponse, error) {
+	r, err := c.queryQscc(identity, channelId, []string{"GetChainInfo", channelId}, nil, peers)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]*QueryChannelInfoResponse, len(r))
+	for idx, p := range r {
+		info := QueryChannelInfoResponse{PeerName: p.Name, Error: p.Err}
+		if p.Err == nil {
+			bci := new(common.BlockchainInfo)
+			if err := proto.Unmarshal(p.Response.Response.GetPayload(), bci); err != nil {
+				info.Error = err
+			} else {
+				info.Info = bci
+			}
+		}
+		response[idx] = &info
+	}
+	return response, nil
+}
+
+// ProcessedTransaction is committed transaction with the parts of its first chaincode action that are usually
+// needed, decoded from QSCC response
+type ProcessedTransaction struct {
+	TxId string
+	// ValidationCode is validation code name like VALID or MVCC_READ_CONFLICT
+	ValidationCode string
+	Timestamp      time.Time
+	Creator        *blockparser.Identity
+	ChaincodeName  string
+	// Input is chaincode input, function name followed by arguments
+	Input         [][]byte
+	Endorsers     []*blockparser.Identity
+	ReadWriteSets []*blockparser.NsReadWriteSet
+	Response      *blockparser.Response
+	Event         *blockparser.ChaincodeEvent
+	// Transaction is the whole decoded transaction with all actions
+	Transaction *blockparser.Transaction
+}
+
+// Valid returns true when transaction was committed as valid
+func (t *ProcessedTransaction) Valid() bool {
+	return t.ValidationCode == "VALID"
+}
+
+// GetTransactionByID gets committed transaction with txId from peer with QSCC and decodes it
+func (c *FabricClient) GetTransactionByID(identity Identity, channelId, txId, peer string) (*ProcessedTransaction, error) {
+	r, err := c.QueryTransaction(identity, channelId, txId, []string{peer})
+	if err != nil {
+		return nil, err
+	}
+	if r[0].Error != nil {
+		return nil, r[0].Error
+	}
+	tx := r[0].Transaction
+	result := &ProcessedTransaction{TxId: tx.TxId, ValidationCode: r[0].ValidationCode, Timestamp: tx.Timestamp,
+		Creator: tx.Creator, Transaction: tx}
+	if len(tx.Actions) > 0 {
+		action := tx.Actions[0]
+		result.ChaincodeName = action.ChaincodeName
+		result.Input = action.Input
+		result.ReadWriteSets = action.ReadWriteSets
+		result.Response = action.Response
+		result.Event = action.Event
+		for _, e := range action.Endorsements {
+			result.Endorsers = append(result.Endorsers, e.Creator)
+		}
+	}
+	return result, nil
+}
+
+// queryBlock sends block query to peers. When peer does not have the block, it is taken from archive peers or
+// block store, in this case PeerName of the response is the name of archive peer or "blockstore".
+func (c *FabricClient) queryBlock(identity Identity, channelId string, args []string, argBytes []byte, peers []string) ([]*QueryBlockResponse, error) {
+	r, err := c.queryQscc(identity, channelId, args, argBytes, peers)
+	if err != nil {
+		return nil, err
+	}
+	response := decodeBlockResponses(r)
+	c.fallbackBlocks(identity, channelId, args, argBytes, response)
+	return response, nil
+}
+
+// decodeBlockResponses decodes blocks from qscc responses
+func decodeBlockResponses(r []*PeerResponse) []*QueryBlockResponse {
+	response := make([]*QueryBlockResponse, len(r))
+	for idx, p := range r {
+		qbr := QueryBlockResponse{PeerName: p.Name, Error: p.Err}
+		if p.Err == nil {
+			block := new(common.Block)
+			if err := proto.Unmarshal(p.Response.Response.GetPayload(), block); err != nil {
+				qbr.Error = err
+			} else {
+				qbr.RawBlock = block
+				qbr.Block, qbr.Error = blockparser.ParseBlock(block)
+			}
+		}
+		response[idx] = &qbr
+	}
+	return response
+}
+
+// queryQscc sends query to QSCC. Responses with status different than 200 are returned as errors.
+func (c *FabricClient) queryQscc(identity Identity, channelId string, args []string, argBytes []byte, peers []string) ([]*PeerResponse, error) {
+	execPeers := c.getPeers(peers)
+	if len(peers) != len(execPeers) {
+		return nil, ErrPeerNameNotFound
+	}
+	chainCode := ChainCode{
+		ChannelId: channelId,
+		Name:      QSCC,
+		Type:      ChaincodeSpec_GOLANG,
+		Args:      args,
+		ArgBytes:  argBytes,
+	}
+	prop, err := createQueryProposal(identity, chainCode, c.txOptions(channelId))
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := signedProposal(prop.proposal, identity, c.cryptoSuite(identity, channelId))
+	if err != nil {
+		return nil, err
+	}
+	r := sendToPeers(execPeers, proposal)
+	for _, p := range r {
+		if p.Err == nil && p.Response.Response.GetStatus() != 200 {
+			p.Err = &EndorsementError{Peer: p.Name, Status: p.Response.Response.GetStatus(), Message: p.Response.Response.GetMessage()}
+		}
+	}
+	return r, nil
+}
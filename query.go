@@ -0,0 +1,68 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// QueryPayloadError is returned when chaincode response cannot be used as result of the query.
+// It holds the raw payload and the peer that returned it, so the caller can inspect what was returned.
+type QueryPayloadError struct {
+	PeerName string
+	Status   int32
+	Message  string
+	Payload  []byte
+	Err      error
+}
+
+func (e *QueryPayloadError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("peer %s returned invalid payload: %v payload: %q", e.PeerName, e.Err, e.Payload)
+	}
+	return fmt.Sprintf("peer %s returned status %d message: %s payload: %q", e.PeerName, e.Status, e.Message, e.Payload)
+}
+
+// QueryJSON execute chainCode query to peers, checks response status and unmarshal JSON payload from the first
+// successful response into out.
+// If no peer returns successful response, error from the first peer is returned.
+func (c *FabricClient) QueryJSON(identity Identity, chainCode ChainCode, peers []string, out interface{}) error {
+	responses, err := c.Query(identity, chainCode, peers)
+	if err != nil {
+		return err
+	}
+	var firstErr error
+	for _, r := range responses {
+		err := decodeQueryJSON(r, out)
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		return ErrNoValidEndorsementFound
+	}
+	return firstErr
+}
+
+func decodeQueryJSON(r *QueryResponse, out interface{}) error {
+	if r.Error != nil {
+		return r.Error
+	}
+	if r.Response == nil || r.Response.Response == nil {
+		return &QueryPayloadError{PeerName: r.PeerName, Err: fmt.Errorf("empty response")}
+	}
+	resp := r.Response.Response
+	if resp.Status != 200 {
+		return &QueryPayloadError{PeerName: r.PeerName, Status: resp.Status, Message: resp.Message, Payload: resp.Payload}
+	}
+	if err := json.Unmarshal(resp.Payload, out); err != nil {
+		return &QueryPayloadError{PeerName: r.PeerName, Status: resp.Status, Message: resp.Message, Payload: resp.Payload, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+// QueryInto execute chainCode query to peers and returns JSON payload from the first successful response
+// decoded into T. On failure error is *QueryPayloadError holding the raw payload when peer responded.
+// Available only when compiled with Go >= 1.18. For older versions use FabricClient.QueryJSON.
+func QueryInto[T any](c *FabricClient, identity Identity, chainCode ChainCode, peers []string) (T, error) {
+	var result T
+	if err := c.QueryJSON(identity, chainCode, peers, &result); err != nil {
+		var empty T
+		return empty, err
+	}
+	return result, nil
+}
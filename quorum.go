@@ -0,0 +1,200 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+// QuorumInvokeResponse is the response from InvokeWithQuorum
+type QuorumInvokeResponse struct {
+	Status common.Status
+	TxID   string
+	// Endorsers are peers whose endorsements are included in transaction
+	Endorsers []string
+	// Mismatched are groups of peers that returned successful response different from endorsers response,
+	// collected until quorum was reached
+	Mismatched []*EndorsementGroup
+	// Failed are peers that returned error or non 200 status until quorum was reached
+	Failed []*PeerResponse
+	// Committed is set when WithCommitCheck found transaction already on ledger, then it was not send again
+	Committed *InvokeResult
+}
+
+// EndorsementQuorumError is returned when required number of matching endorsements cannot be collected
+type EndorsementQuorumError struct {
+	Quorum int
+	// Groups are peers grouped by identical successful response
+	Groups []*EndorsementGroup
+	// Failed are peers that returned error or non 200 status
+	Failed []*PeerResponse
+}
+
+func (e *EndorsementQuorumError) Error() string {
+	groups := make([]string, len(e.Groups))
+	for i, g := range e.Groups {
+		groups[i] = fmt.Sprintf("[%s]", strings.Join(g.Peers, ","))
+	}
+	failed := make([]string, len(e.Failed))
+	for i, f := range e.Failed {
+		if f.Err != nil {
+			failed[i] = fmt.Sprintf("%s: %v", f.Name, f.Err)
+		} else {
+			failed[i] = fmt.Sprintf("%s: status %d %s", f.Name, f.Response.Response.Status, f.Response.Response.Message)
+		}
+	}
+	return fmt.Sprintf("%v: quorum %d, matching groups %s, failed [%s]", ErrEndorsementQuorumNotReached, e.Quorum,
+		strings.Join(groups, " "), strings.Join(failed, "; "))
+}
+
+// Is allows errors.Is(err, ErrEndorsementQuorumNotReached) to match EndorsementQuorumError. When peers returned
+// different successful responses it also matches ErrEndorsementMismatch from gohfc/errors.
+func (e *EndorsementQuorumError) Is(target error) bool {
+	return target == ErrEndorsementQuorumNotReached || (len(e.Groups) > 1 && errors.Is(ErrEndorsementsDoNotMatch, target))
+}
+
+// InvokeWithQuorum sends proposal to all peers in parallel and sends transaction to orderer as soon as quorum
+// peers returned matching successful responses. Requests to remaining peers are canceled.
+// Quorum less than 1 or greater than number of peers means all peers must endorse.
+// Note that collected endorsements must still satisfy chaincode endorsement policy. WithCommitCheck is applied
+// as in InvokeWithContext.
+func (c *FabricClient) InvokeWithQuorum(ctx context.Context, identity Identity, chainCode ChainCode, peers []string, orderer string, quorum int) (*QuorumInvokeResponse, error) {
+	ctx, peers, orderer, cancel := applyRouting(ctx, peers, orderer)
+	defer cancel()
+	peers, orderer = c.applyDefaults(peers, orderer, true)
+	identity, release, err := c.enforceTenant(ctx, identity, chainCode.ChannelId, chainCode.Name, peers, orderer)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	ord, ok := c.getOrderer(orderer)
+	if !ok {
+		return nil, ErrInvalidOrdererName
+	}
+	execPeers := c.getPeers(peers)
+	if len(peers) != len(execPeers) {
+		return nil, ErrPeerNameNotFound
+	}
+	if err := checkWritersFromContext(ctx, chainCode.ChannelId, identity); err != nil {
+		return nil, err
+	}
+	committed, err := c.checkCommitted(ctx, identity, chainCode.ChannelId, peers)
+	if err != nil {
+		return nil, err
+	}
+	if committed != nil {
+		return &QuorumInvokeResponse{Status: committed.Status, TxID: committed.TxID, Committed: committed.Committed}, nil
+	}
+	prop, err := createTransactionProposalWithId(identity, chainCode, txIdFromContext(ctx),
+		c.txOptions(chainCode.ChannelId))
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := signedProposal(prop.proposal, identity, c.cryptoSuite(identity, chainCode.ChannelId))
+	if err != nil {
+		return nil, err
+	}
+	metrics().ProposalSent(chainCode.ChannelId, chainCode.Name)
+	result, err := sendToPeersQuorum(ctx, execPeers, proposal, quorum)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyEndorsementsFromContext(ctx, result.endorsements); err != nil {
+		return nil, err
+	}
+	transaction, err := createTransaction(prop, result.endorsements)
+	if err != nil {
+		return nil, err
+	}
+	signedTransaction, err := c.cryptoSuite(identity, chainCode.ChannelId).Sign(transaction, identity.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := broadcastEnvelope(ctx, ord, &common.Envelope{Payload: transaction, Signature: signedTransaction})
+	if err != nil {
+		return nil, err
+	}
+	return &QuorumInvokeResponse{
+		Status:     reply.Status,
+		TxID:       prop.transactionId,
+		Endorsers:  result.group.Peers,
+		Mismatched: result.mismatched,
+		Failed:     result.failed,
+	}, nil
+}
+
+type quorumResult struct {
+	endorsements []*PeerResponse
+	group        *EndorsementGroup
+	mismatched   []*EndorsementGroup
+	failed       []*PeerResponse
+}
+
+// sendToPeersQuorum send proposal to all peers and returns as soon as quorum peers returned identical successful
+// responses, or with *EndorsementQuorumError when quorum cannot be reached anymore.
+func sendToPeersQuorum(ctx context.Context, peers []*Peer, prop *peer.SignedProposal, quorum int) (*quorumResult, error) {
+	l := len(peers)
+	if quorum < 1 || quorum > l {
+		quorum = l
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// buffered so peers that answer after quorum is reached do not block
+	ch := make(chan *PeerResponse, l)
+	txId := proposalTxId(prop)
+	for _, p := range peers {
+		go p.endorse(ctx, ch, prop, txId)
+	}
+	var groups []*EndorsementGroup
+	endorsements := make(map[*EndorsementGroup][]*PeerResponse)
+	var failed []*PeerResponse
+	largest := 0
+	for i := 0; i < l; i++ {
+		r := <-ch
+		if r.Err != nil || r.Response.Response.Status != 200 {
+			failed = append(failed, r)
+		} else {
+			var group *EndorsementGroup
+			for _, g := range groups {
+				if bytes.Equal(g.payload, r.Response.Payload) {
+					group = g
+					break
+				}
+			}
+			if group == nil {
+				hash := sha256.Sum256(r.Response.Payload)
+				group = &EndorsementGroup{PayloadHash: hex.EncodeToString(hash[:]), payload: r.Response.Payload}
+				groups = append(groups, group)
+			}
+			group.Peers = append(group.Peers, r.Name)
+			endorsements[group] = append(endorsements[group], r)
+			if len(group.Peers) > largest {
+				largest = len(group.Peers)
+			}
+			if len(group.Peers) >= quorum {
+				result := &quorumResult{endorsements: endorsements[group], group: group, failed: failed}
+				for _, g := range groups {
+					if g != group {
+						result.mismatched = append(result.mismatched, g)
+					}
+				}
+				return result, nil
+			}
+		}
+		if largest+l-i-1 < quorum {
+			break
+		}
+	}
+	return nil, &EndorsementQuorumError{Quorum: quorum, Groups: groups, Failed: failed}
+}
@@ -0,0 +1,233 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+)
+
+// WatchConfig watches config file in path and all TLS certificates and keys referenced from it, including client TLS
+// and GM TLS certificates and TLS certificates in MSP directories of nodes, and rebuilds peers, orderers and event
+// peers when any of them is changed. This allows TLS certificates to be rotated or peer and orderer endpoints to be
+// changed without restarting the application.
+// Files are checked every interval. Crypto settings are not reloaded.
+// Errors during reload are send to errs (if not nil) and previous configuration stays active.
+// To stop watching cancel the context. Interval must be greater than zero.
+func (c *FabricClient) WatchConfig(ctx context.Context, path string, interval time.Duration, errs chan<- error) error {
+	if interval <= 0 {
+		return ErrInvalidWatchInterval
+	}
+	config, err := NewClientConfig(path)
+	if err != nil {
+		return err
+	}
+	last, err := configFingerprint(path, config)
+	if err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				config, err := NewClientConfig(path)
+				if err != nil {
+					sendReloadError(errs, err)
+					continue
+				}
+				current, err := configFingerprint(path, config)
+				if err != nil {
+					sendReloadError(errs, err)
+					continue
+				}
+				if current == last {
+					continue
+				}
+				if err := c.Reload(*config); err != nil {
+					sendReloadError(errs, err)
+					continue
+				}
+				last = current
+			}
+		}
+	}()
+	return nil
+}
+
+// Reload replace peers, orderers and event peers with ones created from config.
+// Connections of the replaced peers and orderers are closed. Requests that are already in progress will fail.
+// Channel options, including hash of channels, and client TLS certificate are replaced with channels and clientTls
+// sections of config.
+func (c *FabricClient) Reload(config ClientConfig) error {
+	clientCert, err := config.ClientTls.certificate()
+	if err != nil {
+		return fmt.Errorf("invalid client TLS certificate: %v", err)
+	}
+	ct := c.tlsState()
+	peers, eventPeers, orderers, err := nodesFromConfig(config, ct)
+	if err != nil {
+		return err
+	}
+	if err := validateChannelOptions(config.Channels); err != nil {
+		return err
+	}
+	if err := c.SetDefaults(config.Defaults); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	oldPeers, oldOrderers, oldEventPeers := c.Peers, c.Orderers, c.EventPeers
+	c.Peers, c.Orderers, c.EventPeers = peers, orderers, eventPeers
+	c.archivePeers = config.Archive.Peers
+	c.channels = config.Channels
+	// orderers added by SyncConsenters are replaced too, next sync adds them again
+	c.consenters, c.syncedOrderers = nil, nil
+	c.mu.Unlock()
+	ct.setCertificate(clientCert)
+
+	for _, p := range oldPeers {
+		p.closeConnection()
+	}
+	for _, p := range oldEventPeers {
+		p.closeConnection()
+	}
+	for _, o := range oldOrderers {
+		o.closeConnection()
+	}
+	return nil
+}
+
+// WatchCertFromFile watches certificate (pk) and private key (sk) files and send new Identity to ch every time any
+// of them is changed. This is useful when enrollment certificates are rotated by external tool.
+// To stop watching cancel the context. Interval must be greater than zero.
+func WatchCertFromFile(ctx context.Context, pk, sk, mspId string, interval time.Duration, ch chan<- *Identity, errs chan<- error) error {
+	if interval <= 0 {
+		return ErrInvalidWatchInterval
+	}
+	last, err := filesFingerprint([]string{pk, sk})
+	if err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				current, err := filesFingerprint([]string{pk, sk})
+				if err != nil {
+					sendReloadError(errs, err)
+					continue
+				}
+				if current == last {
+					continue
+				}
+				identity, err := LoadCertFromFile(pk, sk)
+				if err != nil {
+					sendReloadError(errs, err)
+					continue
+				}
+				identity.MspId = mspId
+				last = current
+				select {
+				case ch <- identity:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// configFingerprint calculates hash of config file and all TLS certificate and key files referenced from config
+func configFingerprint(path string, config *ClientConfig) ([sha256.Size]byte, error) {
+	files := make([]string, 0)
+	if config.ClientTls.Cert == "" && config.ClientTls.CertPath != "" {
+		files = append(files, config.ClientTls.CertPath)
+	}
+	if config.ClientTls.Key == "" && config.ClientTls.KeyPath != "" {
+		files = append(files, config.ClientTls.KeyPath)
+	}
+	for _, p := range config.Peers {
+		nodeFiles, err := nodeTlsFiles(p.UseTLS, p.TlsPath, p.TlsCert, p.MspPath, p.GmTls)
+		if err != nil {
+			return [sha256.Size]byte{}, err
+		}
+		files = append(files, nodeFiles...)
+	}
+	for _, p := range config.EventPeers {
+		nodeFiles, err := nodeTlsFiles(p.UseTLS, p.TlsPath, p.TlsCert, p.MspPath, p.GmTls)
+		if err != nil {
+			return [sha256.Size]byte{}, err
+		}
+		files = append(files, nodeFiles...)
+	}
+	for _, o := range config.Orderers {
+		nodeFiles, err := nodeTlsFiles(o.UseTLS, o.TlsPath, o.TlsCert, o.MspPath, o.GmTls)
+		if err != nil {
+			return [sha256.Size]byte{}, err
+		}
+		files = append(files, nodeFiles...)
+	}
+	// map iteration order is random, so files must be sorted to get stable fingerprint
+	sort.Strings(files)
+	return filesFingerprint(append([]string{path}, files...))
+}
+
+// nodeTlsFiles returns TLS root certificate files and GM TLS client certificate and key files of node. Roots are
+// read from tlscacerts and tlsintermediatecerts of mspPath when tlsPath and tlsCert are empty.
+func nodeTlsFiles(useTLS bool, tlsPath, tlsCert, mspPath string, gm GmTlsConfig) ([]string, error) {
+	if !useTLS {
+		return nil, nil
+	}
+	var files []string
+	if tlsPath == "" && tlsCert == "" && mspPath != "" {
+		var err error
+		if files, err = mspTlsFiles(mspPath); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range []string{tlsPath, gm.SignCertPath, gm.SignKeyPath, gm.EncCertPath, gm.EncKeyPath} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+func filesFingerprint(files []string) ([sha256.Size]byte, error) {
+	h := sha256.New()
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return [sha256.Size]byte{}, err
+		}
+		h.Write([]byte(f))
+		h.Write(data)
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+func sendReloadError(errs chan<- error, err error) {
+	if errs == nil {
+		return
+	}
+	select {
+	case errs <- err:
+	default:
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import "context"
+
+// InvokeRequest selects chaincode for single call. Chaincode can be user chaincode written in any language or system
+// chaincode like LSCC, QSCC or CSCC.
+type InvokeRequest struct {
+	ChannelId string
+	Chaincode string
+	// Version is optional, peers execute instantiated version of chaincode
+	Version string
+	// Type is chaincode language, default is ChaincodeSpec_GOLANG. System chaincodes are GOLANG.
+	Type ChainCodeType
+	// Fcn is chaincode function, it is send as first argument
+	Fcn       string
+	Args      []string
+	Transient map[string][]byte
+	// IsInit marks invocation as chaincode initialization
+	IsInit bool
+}
+
+// ChainCode returns ChainCode for request
+func (r InvokeRequest) ChainCode() ChainCode {
+	cc := ChainCode{
+		ChannelId:    r.ChannelId,
+		Name:         r.Chaincode,
+		Version:      r.Version,
+		Type:         r.Type,
+		Args:         append([]string{r.Fcn}, r.Args...),
+		TransientMap: r.Transient,
+		IsInit:       r.IsInit,
+	}
+	if cc.Type == ChaincodeSpec_UNDEFINED {
+		cc.Type = ChaincodeSpec_GOLANG
+	}
+	return cc
+}
+
+// QueryWithRequest is same as QueryWithContext, chaincode is selected by request
+func (c *FabricClient) QueryWithRequest(ctx context.Context, identity Identity, req InvokeRequest, peers []string) ([]*QueryResponse, error) {
+	return c.QueryWithContext(ctx, identity, req.ChainCode(), peers)
+}
+
+// InvokeWithRequest is same as InvokeWithContext, chaincode is selected by request
+func (c *FabricClient) InvokeWithRequest(ctx context.Context, identity Identity, req InvokeRequest, peers []string, orderer string) (*InvokeResponse, error) {
+	return c.InvokeWithContext(ctx, identity, req.ChainCode(), peers, orderer)
+}
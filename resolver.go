@@ -0,0 +1,125 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// Resolver is source of peer, event peer and orderer endpoints. It allows addresses to come from service registries
+// like Consul or etcd instead of static config file.
+// Only Peers, EventPeers and Orderers from returned ClientConfig are used, crypto settings are ignored.
+type Resolver interface {
+	// Resolve returns current endpoints
+	Resolve(ctx context.Context) (*ClientConfig, error)
+	// Watch sends new endpoints to updates every time they are changed, until ctx is canceled.
+	// Watch must not block, watching must be done in separate goroutine.
+	Watch(ctx context.Context, updates chan<- *ClientConfig) error
+}
+
+// UseResolver resolves endpoints using r, replaces current peers, event peers and orderers with them and keeps
+// applying changes reported from r until ctx is canceled.
+// Errors from applying changes are send to errs (if not nil) and previous endpoints stay active.
+func (c *FabricClient) UseResolver(ctx context.Context, r Resolver, errs chan<- error) error {
+	config, err := r.Resolve(ctx)
+	if err != nil {
+		return err
+	}
+	if err := c.Reload(*config); err != nil {
+		return err
+	}
+	updates := make(chan *ClientConfig)
+	if err := r.Watch(ctx, updates); err != nil {
+		return err
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case config := <-updates:
+				if config == nil {
+					continue
+				}
+				if err := c.Reload(*config); err != nil {
+					sendReloadError(errs, err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// StaticResolver always resolve to the same endpoints and never reports changes
+type StaticResolver struct {
+	Config ClientConfig
+}
+
+// Resolve returns static config
+func (s *StaticResolver) Resolve(ctx context.Context) (*ClientConfig, error) {
+	config := s.Config
+	return &config, nil
+}
+
+// Watch does nothing, static endpoints never change
+func (s *StaticResolver) Watch(ctx context.Context, updates chan<- *ClientConfig) error {
+	return nil
+}
+
+// PollingResolver calls ResolveFunc every Interval and reports endpoints when they are different from the previous
+// ones. It is the easiest way to integrate registry that has only lookup API.
+type PollingResolver struct {
+	ResolveFunc func(ctx context.Context) (*ClientConfig, error)
+	Interval    time.Duration
+	// Errors receives errors from ResolveFunc during watching. Optional.
+	Errors chan<- error
+}
+
+// Resolve calls ResolveFunc
+func (p *PollingResolver) Resolve(ctx context.Context) (*ClientConfig, error) {
+	return p.ResolveFunc(ctx)
+}
+
+// Watch polls ResolveFunc every Interval and sends changed endpoints to updates
+func (p *PollingResolver) Watch(ctx context.Context, updates chan<- *ClientConfig) error {
+	last, err := p.ResolveFunc(ctx)
+	if err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(p.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				current, err := p.ResolveFunc(ctx)
+				if err != nil {
+					sendReloadError(p.Errors, err)
+					continue
+				}
+				if sameEndpoints(last, current) {
+					continue
+				}
+				last = current
+				select {
+				case updates <- current:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func sameEndpoints(a, b *ClientConfig) bool {
+	return reflect.DeepEqual(a.Peers, b.Peers) &&
+		reflect.DeepEqual(a.EventPeers, b.EventPeers) &&
+		reflect.DeepEqual(a.Orderers, b.Orderers)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"time"
+)
+
+// MVCCRetry controls automatic resubmission of transactions committed with MVCC_READ_CONFLICT or
+// PHANTOM_READ_CONFLICT
+type MVCCRetry struct {
+	// Retries is maximum number of resubmissions after the first attempt
+	Retries int
+	// Backoff is the wait before first resubmission, it grows linearly with every next one
+	Backoff time.Duration
+}
+
+type mvccRetryKey struct{}
+
+// WithMVCCRetry enables automatic resubmission for InvokeAsync. Transaction that commits with read conflict is
+// endorsed again (with new transaction id) and send to orderer, up to retry.Retries times.
+// Only use it for chaincode functions that are safe to execute again, which is the case for functions that
+// failed with read conflict, since their writes were not applied.
+func WithMVCCRetry(ctx context.Context, retry MVCCRetry) context.Context {
+	return context.WithValue(ctx, mvccRetryKey{}, retry)
+}
+
+// invokeWithRetry calls invokeAndWait and repeat it while transaction ends with read conflict or expires and
+// retries are left
+func (c *FabricClient) invokeWithRetry(ctx context.Context, identity Identity, chainCode ChainCode, peers []string, orderer string,
+	eventPeer string) *InvokeResult {
+	retry, _ := ctx.Value(mvccRetryKey{}).(MVCCRetry)
+	ttl := txTTLFromContext(ctx)
+	var result *InvokeResult
+	conflicts, expirations := 0, 0
+	for attempt := 1; ; attempt++ {
+		result = c.invokeAndWait(ctx, identity, chainCode, peers, orderer, eventPeer, ttl)
+		result.Attempts = attempt
+		ctx = withoutTxID(ctx)
+		if IsTxExpired(result.Error) && expirations < ttl.Resubmits {
+			expirations++
+			continue
+		}
+		if conflicts >= retry.Retries || !IsMVCCConflict(result.Err()) {
+			return result
+		}
+		conflicts++
+		if retry.Backoff > 0 {
+			t := time.NewTimer(retry.Backoff * time.Duration(conflicts))
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return result
+			}
+		}
+	}
+}
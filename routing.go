@@ -0,0 +1,54 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"time"
+)
+
+// Routing holds per request overrides for peers, orderer and timeout.
+// Empty fields do not override values passed to the method.
+type Routing struct {
+	Peers   []string
+	Orderer string
+	Timeout time.Duration
+}
+
+type routingKey struct{}
+
+// WithRouting attach routing overrides to ctx. Methods that accept context (QueryWithContext, InvokeWithContext)
+// use peers, orderer and timeout from it instead of the ones passed as arguments. This allows routing decisions
+// to be made in middleware, far from the place where the request is executed.
+func WithRouting(ctx context.Context, routing Routing) context.Context {
+	return context.WithValue(ctx, routingKey{}, routing)
+}
+
+// RoutingFromContext returns routing attached to ctx
+func RoutingFromContext(ctx context.Context) (Routing, bool) {
+	routing, ok := ctx.Value(routingKey{}).(Routing)
+	return routing, ok
+}
+
+// applyRouting overrides peers and orderer with routing from ctx, and apply timeout if any.
+// Returned cancel func must always be called.
+func applyRouting(ctx context.Context, peers []string, orderer string) (context.Context, []string, string, context.CancelFunc) {
+	routing, ok := RoutingFromContext(ctx)
+	if !ok {
+		return ctx, peers, orderer, func() {}
+	}
+	if len(routing.Peers) > 0 {
+		peers = routing.Peers
+	}
+	if len(routing.Orderer) > 0 {
+		orderer = routing.Orderer
+	}
+	if routing.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, routing.Timeout)
+		return ctx, peers, orderer, cancel
+	}
+	return ctx, peers, orderer, func() {}
+}
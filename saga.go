@@ -0,0 +1,96 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+// SagaStep is single invocation in saga. Fabric does not support atomic transactions across multiple chaincodes
+// (or multiple invocations of the same chaincode), so saga executes steps one by one and when any of the steps fails
+// compensation of all previously executed steps is invoked in reverse order.
+type SagaStep struct {
+	// Name is used only to identify step in result
+	Name string
+	// ChainCode is the invocation for this step
+	ChainCode ChainCode
+	// Compensation is invocation that reverts this step. It is optional, steps without compensation are skipped
+	// when saga is rolled back.
+	Compensation *ChainCode
+}
+
+// SagaStepResult holds result of single saga step and its compensation (if any)
+type SagaStepResult struct {
+	Name             string
+	TxID             string
+	Status           common.Status
+	Error            error
+	Compensated      bool
+	CompensationTxID string
+	CompensationErr  error
+}
+
+// SagaResult holds aggregated result from `RunSaga`
+type SagaResult struct {
+	// Steps are results for every executed step in execution order
+	Steps []*SagaStepResult
+	// Error is the error from the failed step. Nil if all steps are successful.
+	Error error
+	// Compensated is true when saga failed and all executed steps that have compensation were compensated successfully
+	Compensated bool
+}
+
+// RunSaga invokes steps in order using Invoke. When any step fails, compensation for all previously successful steps
+// is invoked in reverse order and execution stops.
+// Please note that same as Invoke success means that transaction is accepted from orderer, not that it is committed.
+// If commit status must be checked, steps must be executed separately and commit events must be observed.
+func (c *FabricClient) RunSaga(identity Identity, steps []SagaStep, peers []string, orderer string) *SagaResult {
+	result := &SagaResult{Steps: make([]*SagaStepResult, 0, len(steps))}
+	for _, step := range steps {
+		stepResult := &SagaStepResult{Name: step.Name}
+		result.Steps = append(result.Steps, stepResult)
+		resp, err := c.Invoke(identity, step.ChainCode, peers, orderer)
+		if err == nil && resp.Status != common.Status_SUCCESS {
+			err = fmt.Errorf("unexpected status: %v", resp.Status)
+		}
+		if resp != nil {
+			stepResult.TxID = resp.TxID
+			stepResult.Status = resp.Status
+		}
+		if err != nil {
+			stepResult.Error = err
+			result.Error = fmt.Errorf("saga step %s failed: %v", step.Name, err)
+			result.Compensated = c.compensateSaga(identity, steps, result.Steps[:len(result.Steps)-1], peers, orderer)
+			return result
+		}
+	}
+	return result
+}
+
+// compensateSaga invokes compensation for executed steps in reverse order. Returns true if all compensations succeed.
+func (c *FabricClient) compensateSaga(identity Identity, steps []SagaStep, executed []*SagaStepResult, peers []string, orderer string) bool {
+	success := true
+	for i := len(executed) - 1; i >= 0; i-- {
+		if steps[i].Compensation == nil {
+			continue
+		}
+		resp, err := c.Invoke(identity, *steps[i].Compensation, peers, orderer)
+		if err == nil && resp.Status != common.Status_SUCCESS {
+			err = fmt.Errorf("unexpected status: %v", resp.Status)
+		}
+		if resp != nil {
+			executed[i].CompensationTxID = resp.TxID
+		}
+		if err != nil {
+			executed[i].CompensationErr = err
+			success = false
+			continue
+		}
+		executed[i].Compensated = true
+	}
+	return success
+}
@@ -0,0 +1,346 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SchemaTypeAvro is default schema type of Confluent schema registry
+	SchemaTypeAvro     = "AVRO"
+	SchemaTypeJSON     = "JSON"
+	SchemaTypeProtobuf = "PROTOBUF"
+)
+
+// Schema is schema registered in schema registry
+type Schema struct {
+	Id      int    `json:"id"`
+	Subject string `json:"subject,omitempty"`
+	Version int    `json:"version,omitempty"`
+	// Type is AVRO, JSON or PROTOBUF
+	Type   string `json:"schemaType,omitempty"`
+	Schema string `json:"schema"`
+}
+
+// SchemaCodec validates and deserializes payload according to schema. Codec for JSON schemas is built in, codecs for
+// Avro and Protobuf schemas can be registered with SchemaRegistry.RegisterCodec.
+type SchemaCodec interface {
+	Decode(schema *Schema, payload []byte) (interface{}, error)
+}
+
+// SchemaValidationError is returned when event payload does not match schema
+type SchemaValidationError struct {
+	Subject  string
+	SchemaId int
+	// Path is JSON pointer to invalid value, empty for the whole payload
+	Path   string
+	Reason string
+}
+
+func (e *SchemaValidationError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%v: subject %s schema %d: %s", ErrSchemaValidation, e.Subject, e.SchemaId, e.Reason)
+	}
+	return fmt.Sprintf("%v: subject %s schema %d: %s: %s", ErrSchemaValidation, e.Subject, e.SchemaId, e.Path, e.Reason)
+}
+
+// Is allows errors.Is to match ErrSchemaValidation
+func (e *SchemaValidationError) Is(target error) bool {
+	return target == ErrSchemaValidation
+}
+
+// SchemaRegistry is client for Confluent compatible schema registry REST API. Schemas are cached, schemas by id
+// forever as they cannot change, latest version of subject for CacheTTL.
+type SchemaRegistry struct {
+	// Url of schema registry, for example http://localhost:8081
+	Url string
+	// Username and Password are used for basic auth, for Confluent Cloud they are API key and secret
+	Username string
+	Password string
+	// CacheTTL is how long latest schema of subject is cached, default is 5 minutes
+	CacheTTL time.Duration
+	// Client is used for requests, http.DefaultClient if nil
+	Client *http.Client
+
+	mu       sync.Mutex
+	byId     map[int]*Schema
+	latest   map[string]cachedSchema
+	codecs   map[string]SchemaCodec
+	compiled map[int]*jsonSchema
+	// fetching are keys of schemas fetched in background
+	fetching map[string]bool
+}
+
+type cachedSchema struct {
+	schema  *Schema
+	fetched time.Time
+}
+
+// NewSchemaRegistry creates schema registry client for url
+func NewSchemaRegistry(url string) *SchemaRegistry {
+	return &SchemaRegistry{Url: url}
+}
+
+// RegisterCodec sets codec for schema type, for example SchemaTypeAvro. It replaces built in JSON codec when
+// schemaType is SchemaTypeJSON.
+func (r *SchemaRegistry) RegisterCodec(schemaType string, codec SchemaCodec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.codecs == nil {
+		r.codecs = make(map[string]SchemaCodec)
+	}
+	r.codecs[schemaType] = codec
+}
+
+// LatestSchema returns latest version of schema registered under subject
+func (r *SchemaRegistry) LatestSchema(ctx context.Context, subject string) (*Schema, error) {
+	r.mu.Lock()
+	c, ok := r.latest[subject]
+	r.mu.Unlock()
+	if ok && time.Since(c.fetched) < r.cacheTTL() {
+		return c.schema, nil
+	}
+	s := new(Schema)
+	if err := r.get(ctx, "/subjects/"+url.PathEscape(subject)+"/versions/latest", s); err != nil {
+		return nil, err
+	}
+	r.normalize(s)
+	r.mu.Lock()
+	if r.latest == nil {
+		r.latest = make(map[string]cachedSchema)
+	}
+	r.latest[subject] = cachedSchema{schema: s, fetched: time.Now()}
+	r.mu.Unlock()
+	return s, nil
+}
+
+// SchemaById returns schema with global id
+func (r *SchemaRegistry) SchemaById(ctx context.Context, id int) (*Schema, error) {
+	r.mu.Lock()
+	s, ok := r.byId[id]
+	r.mu.Unlock()
+	if ok {
+		return s, nil
+	}
+	s = new(Schema)
+	if err := r.get(ctx, fmt.Sprintf("/schemas/ids/%d", id), s); err != nil {
+		return nil, err
+	}
+	s.Id = id
+	r.normalize(s)
+	r.mu.Lock()
+	if r.byId == nil {
+		r.byId = make(map[int]*Schema)
+	}
+	r.byId[id] = s
+	r.mu.Unlock()
+	return s, nil
+}
+
+// Decode validates and deserializes payload with schema of subject. Payloads in Confluent wire format (magic byte 0
+// and 4 byte schema id) are decoded with schema with that id, other payloads with latest schema of subject.
+func (r *SchemaRegistry) Decode(ctx context.Context, subject string, payload []byte) (interface{}, error) {
+	var schema *Schema
+	var err error
+	if id, ok := wireSchemaId(payload); ok {
+		schema, err = r.SchemaById(ctx, id)
+		payload = payload[5:]
+	} else {
+		schema, err = r.LatestSchema(ctx, subject)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.decode(subject, schema, payload)
+}
+
+// DecodeCached is Decode that uses only cached schemas and never waits for schema registry. Schema that is not
+// cached is fetched in background and ErrSchemaNotCached is returned, expired latest schema of subject is used
+// while it is refreshed in background.
+func (r *SchemaRegistry) DecodeCached(subject string, payload []byte) (interface{}, error) {
+	var schema *Schema
+	if id, ok := wireSchemaId(payload); ok {
+		r.mu.Lock()
+		schema = r.byId[id]
+		r.mu.Unlock()
+		if schema == nil {
+			r.fetch(fmt.Sprintf("id:%d", id), func(ctx context.Context) error {
+				_, err := r.SchemaById(ctx, id)
+				return err
+			})
+			return nil, ErrSchemaNotCached
+		}
+		payload = payload[5:]
+	} else {
+		r.mu.Lock()
+		c, ok := r.latest[subject]
+		r.mu.Unlock()
+		if !ok || time.Since(c.fetched) >= r.cacheTTL() {
+			r.Prefetch(subject)
+		}
+		if !ok {
+			return nil, ErrSchemaNotCached
+		}
+		schema = c.schema
+	}
+	return r.decode(subject, schema, payload)
+}
+
+// Prefetch fetches latest schemas of subjects in background, so DecodeCached finds them in cache
+func (r *SchemaRegistry) Prefetch(subjects ...string) {
+	for _, subject := range subjects {
+		subject := subject
+		r.fetch("subject:"+subject, func(ctx context.Context) error {
+			_, err := r.LatestSchema(ctx, subject)
+			return err
+		})
+	}
+}
+
+// fetch runs fn in background unless fetch with the same key is in progress
+func (r *SchemaRegistry) fetch(key string, fn func(ctx context.Context) error) {
+	r.mu.Lock()
+	if r.fetching[key] {
+		r.mu.Unlock()
+		return
+	}
+	if r.fetching == nil {
+		r.fetching = make(map[string]bool)
+	}
+	r.fetching[key] = true
+	r.mu.Unlock()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := fn(ctx); err != nil {
+			logger().Warn("cannot fetch schema", "registry", r.Url, "schema", key, "error", err)
+		}
+		r.mu.Lock()
+		delete(r.fetching, key)
+		r.mu.Unlock()
+	}()
+}
+
+// wireSchemaId returns schema id of payload in Confluent wire format
+func wireSchemaId(payload []byte) (int, bool) {
+	if len(payload) >= 5 && payload[0] == 0 {
+		return int(binary.BigEndian.Uint32(payload[1:5])), true
+	}
+	return 0, false
+}
+
+// decode validates and deserializes payload without wire format header with schema
+func (r *SchemaRegistry) decode(subject string, schema *Schema, payload []byte) (interface{}, error) {
+	r.mu.Lock()
+	codec, ok := r.codecs[schema.Type]
+	r.mu.Unlock()
+	if ok {
+		return codec.Decode(schema, payload)
+	}
+	if schema.Type != SchemaTypeJSON {
+		return nil, fmt.Errorf("%v: %s, register codec with RegisterCodec", ErrSchemaTypeNotSupported, schema.Type)
+	}
+	js, err := r.compile(schema)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return nil, &SchemaValidationError{Subject: subject, SchemaId: schema.Id, Reason: err.Error()}
+	}
+	if path, reason, ok := js.validate(v, ""); !ok {
+		return nil, &SchemaValidationError{Subject: subject, SchemaId: schema.Id, Path: path, Reason: reason}
+	}
+	return v, nil
+}
+
+func (r *SchemaRegistry) compile(schema *Schema) (*jsonSchema, error) {
+	r.mu.Lock()
+	js, ok := r.compiled[schema.Id]
+	r.mu.Unlock()
+	if ok {
+		return js, nil
+	}
+	js, err := parseJSONSchema([]byte(schema.Schema))
+	if err != nil {
+		return nil, fmt.Errorf("schema %d: %v", schema.Id, err)
+	}
+	r.mu.Lock()
+	if r.compiled == nil {
+		r.compiled = make(map[int]*jsonSchema)
+	}
+	r.compiled[schema.Id] = js
+	r.mu.Unlock()
+	return js, nil
+}
+
+func (r *SchemaRegistry) cacheTTL() time.Duration {
+	if r.CacheTTL <= 0 {
+		return 5 * time.Minute
+	}
+	return r.CacheTTL
+}
+
+func (r *SchemaRegistry) normalize(s *Schema) {
+	if s.Type == "" {
+		s.Type = SchemaTypeAvro
+	}
+}
+
+func (r *SchemaRegistry) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(r.Url, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if r.Username != "" {
+		req.SetBasicAuth(r.Username, r.Password)
+	}
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrSchemaNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Code    int    `json:"error_code"`
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return fmt.Errorf("schema registry returned status %d: %d %s", resp.StatusCode, e.Code, e.Message)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// SchemaEventDecoder returns EventDecoder that validates and deserializes event payloads with schema registered
+// under subject. Register it in EventDecoderRegistry for chaincode events:
+//
+//	decoders.Register("mycc", "transfer", gohfc.SchemaEventDecoder(registry, "mycc-transfer"))
+//
+// Payload that does not match schema is delivered with *SchemaValidationError in DecodeError. Decoder does not wait
+// for schema registry, see DecodeCached. Latest schema of subject is prefetched when decoder is created, events
+// that arrive before schema they need is fetched have ErrSchemaNotCached in DecodeError.
+func SchemaEventDecoder(registry *SchemaRegistry, subject string) EventDecoder {
+	registry.Prefetch(subject)
+	return EventDecoderFunc(func(payload []byte) (interface{}, error) {
+		return registry.DecodeCached(subject, payload)
+	})
+}
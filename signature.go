@@ -0,0 +1,120 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"hash"
+	"math/big"
+
+	"github.com/CognitionFoundry/gohfc/gm"
+	"golang.org/x/crypto/sha3"
+)
+
+// IsLowS returns true when S value of DER encoded ECDSA signature is not greater than half of curve order.
+// Fabric accepts only low-S signatures.
+func IsLowS(key *ecdsa.PublicKey, signature []byte) (bool, error) {
+	sig, err := unmarshalECDSASignature(signature)
+	if err != nil {
+		return false, err
+	}
+	return sig.S.Cmp(halfOrder(key)) <= 0, nil
+}
+
+// SignatureToLowS converts DER encoded ECDSA signature to equivalent low-S signature. Low-S signature is returned
+// unchanged.
+func SignatureToLowS(key *ecdsa.PublicKey, signature []byte) ([]byte, error) {
+	sig, err := unmarshalECDSASignature(signature)
+	if err != nil {
+		return nil, err
+	}
+	if sig.S.Cmp(halfOrder(key)) <= 0 {
+		return signature, nil
+	}
+	sig.S.Sub(key.Params().N, sig.S)
+	return asn1.Marshal(*sig)
+}
+
+// VerifySignature verifies signature over msg the way Fabric MSP does: ECDSA signatures over SHA2-256 digest that
+// must be low-S, SM2 signatures with SM3 and default user id, and pure Ed25519 signatures.
+// key is public key or *x509.Certificate.
+func VerifySignature(key interface{}, msg, signature []byte) (bool, error) {
+	return verifySignature(key, msg, signature, sha256.New)
+}
+
+// VerifySignatureWithHash is same as VerifySignature, but ECDSA digest is computed with signature hash family of MSP,
+// SHA2 or SHA3 like in OrgConfig.SignatureHashFamily. Empty hashFamily is SHA2, hash names like SHA3-256 are
+// accepted too.
+func VerifySignatureWithHash(key interface{}, msg, signature []byte, hashFamily string) (bool, error) {
+	var hashFunction func() hash.Hash
+	switch hashFamily {
+	case "", "SHA2":
+		hashFunction = sha256.New
+	case "SHA3":
+		hashFunction = sha3.New256
+	default:
+		var err error
+		if hashFunction, err = newHashFunction(hashFamily); err != nil {
+			return false, err
+		}
+	}
+	return verifySignature(key, msg, signature, hashFunction)
+}
+
+func verifySignature(key interface{}, msg, signature []byte, hashFunction func() hash.Hash) (bool, error) {
+	if cert, ok := key.(*x509.Certificate); ok {
+		key = cert.PublicKey
+	}
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		sig, err := unmarshalECDSASignature(signature)
+		if err != nil {
+			return false, err
+		}
+		if sig.S.Cmp(halfOrder(k)) > 0 {
+			return false, ErrSignatureNotLowS
+		}
+		h := hashFunction()
+		h.Write(msg)
+		return ecdsa.Verify(k, h.Sum(nil), sig.R, sig.S), nil
+	case *gm.PublicKey:
+		sig, err := unmarshalECDSASignature(signature)
+		if err != nil {
+			return false, err
+		}
+		return gm.Verify(k, gm.DefaultUID, msg, sig.R, sig.S), nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, msg, signature), nil
+	default:
+		return false, fmt.Errorf("%v: %T", ErrInvalidKeyType, key)
+	}
+}
+
+func unmarshalECDSASignature(signature []byte) (*eCDSASignature, error) {
+	sig := new(eCDSASignature)
+	rest, err := asn1.Unmarshal(signature, sig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %v", err)
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("invalid signature encoding: trailing data")
+	}
+	if sig.R == nil || sig.S == nil || sig.R.Sign() <= 0 || sig.S.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid signature: R and S must be positive")
+	}
+	return sig, nil
+}
+
+func halfOrder(key *ecdsa.PublicKey) *big.Int {
+	if half, ok := ecCurveHalfOrders[key.Curve]; ok {
+		return half
+	}
+	return new(big.Int).Rsh(key.Params().N, 1)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Signer is ECDSA private key kept outside of process, like key in HashiCorp Vault or cloud KMS. Signer can be
+// PrivateKey of Identity used with ecdsa crypto suite: message is hashed by crypto suite and only digest is send to
+// Signer, which returns ASN.1 DER signature. High S values are normalized by gohfc. Signer is crypto.Signer, so
+// it also signs certificate requests when identity is enrolled with FabricCA.
+type Signer interface {
+	crypto.Signer
+}
+
+// signDigest signs digest with signer and returns low S signature
+func signDigest(signer Signer, digest []byte) ([]byte, error) {
+	pub, ok := signer.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return nil, ErrInvalidKeyType
+	}
+	signature, err := signer.Sign(rand.Reader, digest, digestHash(digest))
+	if err != nil {
+		return nil, err
+	}
+	sig, err := unmarshalECDSASignature(signature)
+	if err != nil {
+		return nil, err
+	}
+	if sig.S.Cmp(halfOrder(pub)) == 1 {
+		sig.S.Sub(pub.Params().N, sig.S)
+	}
+	return asn1.Marshal(*sig)
+}
+
+// digestHash returns hash function of SHA-2 digest by its size, zero for other sizes
+func digestHash(digest []byte) crypto.Hash {
+	switch len(digest) {
+	case 32:
+		return crypto.SHA256
+	case 48:
+		return crypto.SHA384
+	case 64:
+		return crypto.SHA512
+	}
+	return 0
+}
+
+// parsePublicKeyPEM parses PKIX public key in PEM
+func parsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("public key is not PEM encoded")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// defaultSignerTimeout is timeout of requests to signing services when http client is not set
+const defaultSignerTimeout = 10 * time.Second
+
+// signerClient returns client or default client of signing services
+func signerClient(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return &http.Client{Timeout: defaultSignerTimeout}
+}
+
+// callSigningService sends JSON request to signing service and decodes JSON response into out. Response with
+// status other than 2xx is error with response body.
+func callSigningService(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("signing service %s returned status %d: %s", req.URL.Host, resp.StatusCode,
+			bytes.TrimSpace(body))
+	}
+	return json.Unmarshal(body, out)
+}
+
+// jsonBody returns JSON encoded request body
+func jsonBody(v interface{}) (*bytes.Reader, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
@@ -0,0 +1,198 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMetadataEndpoint is EC2 instance metadata service
+	defaultMetadataEndpoint = "http://169.254.169.254"
+	// awsCredentialsRefresh is how long before expiration temporary credentials are refreshed
+	awsCredentialsRefresh = 5 * time.Minute
+)
+
+// awsCredentials are static or temporary AWS credentials, Expiration is zero for static ones
+type awsCredentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+// awsCredentialsProvider caches credentials of AWS signer and refreshes temporary credentials before they expire
+type awsCredentialsProvider struct {
+	mu     sync.Mutex
+	creds  awsCredentials
+	fetch  func() (awsCredentials, error)
+	client *http.Client
+	now    func() time.Time
+}
+
+// newAWSCredentialsProvider resolves credentials like AWS SDK default chain: config, AWS_* environment variables,
+// web identity token (AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN, used by EKS service accounts) and EC2 instance
+// role from instance metadata service
+func newAWSCredentialsProvider(config AWSKMSSignerConfig, client *http.Client) *awsCredentialsProvider {
+	p := &awsCredentialsProvider{client: client, now: time.Now}
+	static := awsCredentials{AccessKeyId: config.AccessKeyId, SecretAccessKey: config.SecretAccessKey,
+		SessionToken: config.SessionToken}
+	if static.AccessKeyId == "" {
+		static = awsCredentials{AccessKeyId: os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"), SessionToken: os.Getenv("AWS_SESSION_TOKEN")}
+	}
+	switch {
+	case static.AccessKeyId != "":
+		p.creds = static
+	case os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE") != "" && os.Getenv("AWS_ROLE_ARN") != "":
+		endpoint := config.STSEndpoint
+		if endpoint == "" {
+			endpoint = "https://sts." + config.Region + ".amazonaws.com"
+		}
+		p.fetch = func() (awsCredentials, error) {
+			return p.webIdentity(endpoint, os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"), os.Getenv("AWS_ROLE_ARN"),
+				os.Getenv("AWS_ROLE_SESSION_NAME"))
+		}
+	default:
+		endpoint := config.MetadataEndpoint
+		if endpoint == "" {
+			endpoint = defaultMetadataEndpoint
+		}
+		p.fetch = func() (awsCredentials, error) {
+			return p.instanceRole(endpoint)
+		}
+	}
+	return p
+}
+
+// credentials returns cached credentials, temporary credentials are fetched again when they are about to expire
+func (p *awsCredentialsProvider) credentials() (awsCredentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fetch == nil {
+		return p.creds, nil
+	}
+	if p.creds.AccessKeyId != "" && p.now().Add(awsCredentialsRefresh).Before(p.creds.Expiration) {
+		return p.creds, nil
+	}
+	creds, err := p.fetch()
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("cannot get aws credentials: %w", err)
+	}
+	p.creds = creds
+	return creds, nil
+}
+
+// webIdentity exchanges web identity token for role credentials with STS AssumeRoleWithWebIdentity
+func (p *awsCredentialsProvider) webIdentity(endpoint, tokenFile, roleArn, sessionName string) (awsCredentials, error) {
+	token, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	if sessionName == "" {
+		sessionName = fmt.Sprintf("gohfc-%d", p.now().UnixNano())
+	}
+	form := url.Values{"Action": {"AssumeRoleWithWebIdentity"}, "Version": {"2011-06-15"}, "RoleArn": {roleArn},
+		"RoleSessionName": {sessionName}, "WebIdentityToken": {strings.TrimSpace(string(token))}}
+	req, err := http.NewRequest(http.MethodPost, endpoint+"/", strings.NewReader(form.Encode()))
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	body, err := p.do(req)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	var resp struct {
+		Credentials struct {
+			AccessKeyId     string
+			SecretAccessKey string
+			SessionToken    string
+			Expiration      time.Time
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.Unmarshal(body, &resp); err != nil {
+		return awsCredentials{}, err
+	}
+	c := resp.Credentials
+	return awsCredentials{AccessKeyId: c.AccessKeyId, SecretAccessKey: c.SecretAccessKey,
+		SessionToken: c.SessionToken, Expiration: c.Expiration}, nil
+}
+
+// instanceRole returns credentials of EC2 instance role from instance metadata service (IMDSv2)
+func (p *awsCredentialsProvider) instanceRole(endpoint string) (awsCredentials, error) {
+	req, err := http.NewRequest(http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	token, err := p.do(req)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	get := func(path string) ([]byte, error) {
+		req, err := http.NewRequest(http.MethodGet, endpoint+"/latest/meta-data/iam/security-credentials/"+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-aws-ec2-metadata-token", string(token))
+		return p.do(req)
+	}
+	roles, err := get("")
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	role := strings.TrimSpace(strings.SplitN(string(roles), "\n", 2)[0])
+	if role == "" {
+		return awsCredentials{}, fmt.Errorf("instance has no iam role")
+	}
+	body, err := get(url.PathEscape(role))
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	var resp struct {
+		Code            string
+		AccessKeyId     string
+		SecretAccessKey string
+		Token           string
+		Expiration      time.Time
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return awsCredentials{}, err
+	}
+	if resp.Code != "Success" {
+		return awsCredentials{}, fmt.Errorf("instance metadata returned credentials with code %s", resp.Code)
+	}
+	return awsCredentials{AccessKeyId: resp.AccessKeyId, SecretAccessKey: resp.SecretAccessKey,
+		SessionToken: resp.Token, Expiration: resp.Expiration}, nil
+}
+
+// do sends request to credentials service and returns response body, status other than 2xx is error
+func (p *awsCredentialsProvider) do(req *http.Request) ([]byte, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("%s returned status %d: %s", req.URL.Host, resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return body, nil
+}
@@ -0,0 +1,220 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AWSKMSSignerConfig selects asymmetric ECC_NIST_P256 or ECC_NIST_P384 signing key of AWS KMS
+type AWSKMSSignerConfig struct {
+	Region string
+	// KeyId is key id, key ARN or alias
+	KeyId string
+	// AccessKeyId, SecretAccessKey and SessionToken are credentials. When empty they are read from
+	// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables, then from web identity
+	// token (AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN) and at last from EC2 instance role. Temporary
+	// credentials are refreshed before they expire.
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	// Endpoint overrides KMS endpoint, like VPC endpoint or local KMS for tests
+	Endpoint string
+	// STSEndpoint overrides STS endpoint used for web identity credentials
+	STSEndpoint string
+	// MetadataEndpoint overrides EC2 instance metadata service endpoint
+	MetadataEndpoint string
+	// Client is used for requests, default client has 10s timeout
+	Client *http.Client
+}
+
+// AWSKMSSigner signs digests with AWS KMS key. Requests are signed with AWS Signature Version 4.
+type AWSKMSSigner struct {
+	config AWSKMSSignerConfig
+	client *http.Client
+	creds  *awsCredentialsProvider
+	public crypto.PublicKey
+	// now is clock used for request signatures
+	now func() time.Time
+}
+
+// NewAWSKMSSigner reads public key of KMS key and returns signer
+func NewAWSKMSSigner(config AWSKMSSignerConfig) (*AWSKMSSigner, error) {
+	if config.Endpoint == "" {
+		config.Endpoint = "https://kms." + config.Region + ".amazonaws.com"
+	}
+	client := signerClient(config.Client)
+	s := &AWSKMSSigner{config: config, client: client, creds: newAWSCredentialsProvider(config, client), now: time.Now}
+	var resp struct {
+		PublicKey string
+	}
+	if err := s.call("GetPublicKey", map[string]string{"KeyId": config.KeyId}, &resp); err != nil {
+		return nil, err
+	}
+	der, err := base64.StdEncoding.DecodeString(resp.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if s.public, err = x509.ParsePKIXPublicKey(der); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Public returns public key of signer
+func (s *AWSKMSSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+// Sign signs digest with KMS key, opts selects signing algorithm
+func (s *AWSKMSSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	var algorithm string
+	switch opts.HashFunc() {
+	case crypto.SHA256:
+		algorithm = "ECDSA_SHA_256"
+	case crypto.SHA384:
+		algorithm = "ECDSA_SHA_384"
+	case crypto.SHA512:
+		algorithm = "ECDSA_SHA_512"
+	default:
+		return nil, fmt.Errorf("aws kms signer does not support hash %v", opts.HashFunc())
+	}
+	var resp struct {
+		Signature string
+	}
+	err := s.call("Sign", map[string]string{"KeyId": s.config.KeyId, "MessageType": "DIGEST",
+		"Message": base64.StdEncoding.EncodeToString(digest), "SigningAlgorithm": algorithm}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Signature)
+}
+
+// call calls KMS action with JSON protocol
+func (s *AWSKMSSigner) call(action string, input interface{}, out interface{}) error {
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.config.Endpoint+"/", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	creds, err := s.creds.credentials()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+	signAWSv4(req, payload, s.now().UTC(), s.config.Region, "kms", creds.AccessKeyId, creds.SecretAccessKey)
+	return callSigningService(s.client, req, out)
+}
+
+// GCPKMSSignerConfig selects asymmetric EC_SIGN_P256_SHA256 or EC_SIGN_P384_SHA384 key version of Google Cloud KMS
+type GCPKMSSignerConfig struct {
+	// KeyVersion is resource name like
+	// projects/p/locations/l/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1
+	KeyVersion string
+	// Token returns OAuth2 access token for requests, like token source of Google application default credentials
+	Token func(ctx context.Context) (string, error)
+	// Endpoint overrides KMS endpoint, default is https://cloudkms.googleapis.com
+	Endpoint string
+	// Client is used for requests, default client has 10s timeout
+	Client *http.Client
+}
+
+// GCPKMSSigner signs digests with Google Cloud KMS key version
+type GCPKMSSigner struct {
+	config GCPKMSSignerConfig
+	client *http.Client
+	public crypto.PublicKey
+}
+
+// NewGCPKMSSigner reads public key of key version and returns signer
+func NewGCPKMSSigner(config GCPKMSSignerConfig) (*GCPKMSSigner, error) {
+	if config.Token == nil {
+		return nil, fmt.Errorf("gcp kms signer needs token source")
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://cloudkms.googleapis.com"
+	}
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+	s := &GCPKMSSigner{config: config, client: signerClient(config.Client)}
+	var resp struct {
+		Pem string `json:"pem"`
+	}
+	if err := s.call(http.MethodGet, "/publicKey", nil, &resp); err != nil {
+		return nil, err
+	}
+	var err error
+	if s.public, err = parsePublicKeyPEM([]byte(resp.Pem)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Public returns public key of signer
+func (s *GCPKMSSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+// Sign signs digest with KMS key version, opts must match digest algorithm of key
+func (s *GCPKMSSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	var field string
+	switch opts.HashFunc() {
+	case crypto.SHA256:
+		field = "sha256"
+	case crypto.SHA384:
+		field = "sha384"
+	case crypto.SHA512:
+		field = "sha512"
+	default:
+		return nil, fmt.Errorf("gcp kms signer does not support hash %v", opts.HashFunc())
+	}
+	body, err := jsonBody(map[string]interface{}{
+		"digest": map[string]string{field: base64.StdEncoding.EncodeToString(digest)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Signature string `json:"signature"`
+	}
+	if err := s.call(http.MethodPost, ":asymmetricSign", body, &resp); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Signature)
+}
+
+func (s *GCPKMSSigner) call(method, suffix string, body io.Reader, out interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultSignerTimeout)
+	defer cancel()
+	token, err := s.config.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, s.config.Endpoint+"/v1/"+s.config.KeyVersion+suffix, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return callSigningService(s.client, req, out)
+}
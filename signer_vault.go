@@ -0,0 +1,134 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"crypto"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// VaultSignerConfig selects ECDSA key of HashiCorp Vault transit secrets engine
+type VaultSignerConfig struct {
+	// Address is Vault address like https://vault.example.com:8200
+	Address string
+	Token   string
+	// Namespace is Vault Enterprise namespace, optional
+	Namespace string
+	// Mount is path of transit engine, default is transit
+	Mount string
+	// Key is name of transit key, type must be ecdsa-p256, ecdsa-p384 or ecdsa-p521
+	Key string
+	// Client is used for requests, default client has 10s timeout
+	Client *http.Client
+}
+
+// VaultSigner signs digests with key of Vault transit engine. Signatures are made with key version that was the
+// latest when signer was created, so certificate of identity keeps matching after key rotation in Vault.
+type VaultSigner struct {
+	config  VaultSignerConfig
+	client  *http.Client
+	public  crypto.PublicKey
+	version int
+}
+
+// NewVaultSigner reads public key of the latest version of transit key and returns signer
+func NewVaultSigner(config VaultSignerConfig) (*VaultSigner, error) {
+	if config.Mount == "" {
+		config.Mount = "transit"
+	}
+	config.Address = strings.TrimRight(config.Address, "/")
+	s := &VaultSigner{config: config, client: signerClient(config.Client)}
+	var resp struct {
+		Data struct {
+			LatestVersion int `json:"latest_version"`
+			Keys          map[string]struct {
+				PublicKey string `json:"public_key"`
+			} `json:"keys"`
+		} `json:"data"`
+	}
+	req, err := s.request(http.MethodGet, "/keys/"+config.Key, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := callSigningService(s.client, req, &resp); err != nil {
+		return nil, err
+	}
+	key, ok := resp.Data.Keys[strconv.Itoa(resp.Data.LatestVersion)]
+	if !ok {
+		return nil, fmt.Errorf("vault key %s has no version %d", config.Key, resp.Data.LatestVersion)
+	}
+	if s.public, err = parsePublicKeyPEM([]byte(key.PublicKey)); err != nil {
+		return nil, err
+	}
+	s.version = resp.Data.LatestVersion
+	return s, nil
+}
+
+// Public returns public key of signer
+func (s *VaultSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+// Sign signs digest with transit key, opts selects hash algorithm of digest
+func (s *VaultSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	var algorithm string
+	switch opts.HashFunc() {
+	case crypto.SHA256:
+		algorithm = "sha2-256"
+	case crypto.SHA384:
+		algorithm = "sha2-384"
+	case crypto.SHA512:
+		algorithm = "sha2-512"
+	default:
+		return nil, fmt.Errorf("vault signer does not support hash %v", opts.HashFunc())
+	}
+	body, err := jsonBody(map[string]interface{}{
+		"input":                base64.StdEncoding.EncodeToString(digest),
+		"prehashed":            true,
+		"marshaling_algorithm": "asn1",
+		"key_version":          s.version,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := s.request(http.MethodPost, "/sign/"+s.config.Key+"/"+algorithm, body)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Data struct {
+			Signature string `json:"signature"`
+		} `json:"data"`
+	}
+	if err := callSigningService(s.client, req, &resp); err != nil {
+		return nil, err
+	}
+	// signature is vault:v<version>:<base64>
+	parts := strings.SplitN(resp.Data.Signature, ":", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid vault signature %q", resp.Data.Signature)
+	}
+	return base64.StdEncoding.DecodeString(parts[2])
+}
+
+func (s *VaultSigner) request(method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, s.config.Address+"/v1/"+s.config.Mount+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", s.config.Token)
+	if s.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.config.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"google.golang.org/grpc"
+)
+
+// Messages of Fabric 2.3+ snapshot service, they are not part of vendored protos
+
+type snapshotRequest struct {
+	SignatureHeader *common.SignatureHeader `protobuf:"bytes,1,opt,name=signature_header,json=signatureHeader" json:"signature_header,omitempty"`
+	ChannelId       string                  `protobuf:"bytes,2,opt,name=channel_id,json=channelId" json:"channel_id,omitempty"`
+	BlockNumber     uint64                  `protobuf:"varint,3,opt,name=block_number,json=blockNumber" json:"block_number,omitempty"`
+}
+
+func (m *snapshotRequest) Reset()         { *m = snapshotRequest{} }
+func (m *snapshotRequest) String() string { return proto.CompactTextString(m) }
+func (*snapshotRequest) ProtoMessage()    {}
+
+type snapshotQuery struct {
+	SignatureHeader *common.SignatureHeader `protobuf:"bytes,1,opt,name=signature_header,json=signatureHeader" json:"signature_header,omitempty"`
+	ChannelId       string                  `protobuf:"bytes,2,opt,name=channel_id,json=channelId" json:"channel_id,omitempty"`
+}
+
+func (m *snapshotQuery) Reset()         { *m = snapshotQuery{} }
+func (m *snapshotQuery) String() string { return proto.CompactTextString(m) }
+func (*snapshotQuery) ProtoMessage()    {}
+
+type signedSnapshotRequest struct {
+	Request   []byte `protobuf:"bytes,1,opt,name=request,proto3" json:"request,omitempty"`
+	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *signedSnapshotRequest) Reset()         { *m = signedSnapshotRequest{} }
+func (m *signedSnapshotRequest) String() string { return proto.CompactTextString(m) }
+func (*signedSnapshotRequest) ProtoMessage()    {}
+
+type queryPendingSnapshotsResponse struct {
+	BlockNumbers []uint64 `protobuf:"varint,1,rep,packed,name=block_numbers,json=blockNumbers" json:"block_numbers,omitempty"`
+}
+
+func (m *queryPendingSnapshotsResponse) Reset()         { *m = queryPendingSnapshotsResponse{} }
+func (m *queryPendingSnapshotsResponse) String() string { return proto.CompactTextString(m) }
+func (*queryPendingSnapshotsResponse) ProtoMessage()    {}
+
+// snapshotEmpty is google.protobuf.Empty
+type snapshotEmpty struct{}
+
+func (m *snapshotEmpty) Reset()         { *m = snapshotEmpty{} }
+func (m *snapshotEmpty) String() string { return proto.CompactTextString(m) }
+func (*snapshotEmpty) ProtoMessage()    {}
+
+// GenerateSnapshot requests ledger snapshot of channel in peer at blockNumber (Fabric 2.3+). 0 means the last
+// committed block. Snapshot is generated asynchronously when peer commits the block, into snapshot directory of
+// peer. Identity must be admin of peer organization.
+func (c *FabricClient) GenerateSnapshot(ctx context.Context, identity Identity, peer, channelId string, blockNumber uint64) error {
+	return c.snapshotCall(ctx, identity, peer, channelId, "Generate", func(header *common.SignatureHeader) proto.Message {
+		return &snapshotRequest{SignatureHeader: header, ChannelId: channelId, BlockNumber: blockNumber}
+	}, new(snapshotEmpty))
+}
+
+// CancelSnapshot cancels pending snapshot request of channel in peer at blockNumber
+func (c *FabricClient) CancelSnapshot(ctx context.Context, identity Identity, peer, channelId string, blockNumber uint64) error {
+	return c.snapshotCall(ctx, identity, peer, channelId, "Cancel", func(header *common.SignatureHeader) proto.Message {
+		return &snapshotRequest{SignatureHeader: header, ChannelId: channelId, BlockNumber: blockNumber}
+	}, new(snapshotEmpty))
+}
+
+// PendingSnapshots returns block numbers of pending snapshot requests of channel in peer
+func (c *FabricClient) PendingSnapshots(ctx context.Context, identity Identity, peer, channelId string) ([]uint64, error) {
+	resp := new(queryPendingSnapshotsResponse)
+	err := c.snapshotCall(ctx, identity, peer, channelId, "QueryPendings", func(header *common.SignatureHeader) proto.Message {
+		return &snapshotQuery{SignatureHeader: header, ChannelId: channelId}
+	}, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.BlockNumbers, nil
+}
+
+// snapshotCall signs request created by newRequest and sends it to snapshot service of peer
+func (c *FabricClient) snapshotCall(ctx context.Context, identity Identity, peerName, channelId, method string,
+	newRequest func(*common.SignatureHeader) proto.Message, reply proto.Message) error {
+	if identity.ReadOnly() {
+		return ErrReadOnlyIdentity
+	}
+	p := c.getPeers([]string{peerName})
+	if len(p) != 1 {
+		return ErrPeerNameNotFound
+	}
+	creator, err := marshalProtoIdentity(identity)
+	if err != nil {
+		return err
+	}
+	nonce, err := generateRandomBytes(nonceSize)
+	if err != nil {
+		return err
+	}
+	request, err := proto.Marshal(newRequest(&common.SignatureHeader{Creator: creator, Nonce: nonce}))
+	if err != nil {
+		return err
+	}
+	signature, err := c.cryptoSuite(identity, channelId).Sign(request, identity.PrivateKey)
+	if err != nil {
+		return err
+	}
+	conn, _, err := p[0].endorserConnection(ctx, effectiveTimeouts(ctx, p[0].Timeouts).Dial)
+	if err != nil {
+		return err
+	}
+	err = grpc.Invoke(ctx, "/protos.Snapshot/"+method, &signedSnapshotRequest{Request: request, Signature: signature},
+		reply, conn)
+	return rpcError(peerName, err)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+
+	"github.com/CognitionFoundry/gohfc/blockparser"
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+// StateQuery selects keys of namespace (chaincode) which values are reconstructed. Empty Keys selects all keys.
+type StateQuery struct {
+	Namespace string
+	Keys      []string
+}
+
+// StateValue is value of key at some block height
+type StateValue struct {
+	Value []byte
+	// Version is block and transaction number where value was written
+	Version blockparser.Version
+	TxId    string
+}
+
+// HistoricalState is state of selected keys as of block BlockNumber, reconstructed from write sets of valid
+// transactions. Deleted keys and keys that were never written are not present.
+type HistoricalState struct {
+	BlockNumber uint64
+	// Values are values by namespace and key
+	Values map[string]map[string]*StateValue
+}
+
+// Get returns value of key in namespace
+func (s *HistoricalState) Get(namespace, key string) (*StateValue, bool) {
+	v, ok := s.Values[namespace][key]
+	return v, ok
+}
+
+// StateAtBlock reconstructs values of keys selected by queries as they were after block blockNumber was committed,
+// by replaying blocks from genesis block from peer. It answers "state as of block N" questions without chaincode
+// support. Private data is not reconstructed, as blocks hold only its hashes. Replaying long chains is slow, use
+// ReplayState with iterator over QSCC and archive peers when peer does not have all blocks.
+func (c *FabricClient) StateAtBlock(ctx context.Context, identity Identity, peer, channelId string, blockNumber uint64,
+	queries ...StateQuery) (*HistoricalState, error) {
+	it, err := c.NewBlockIterator(ctx, identity, peer, channelId, BlockIteratorConfig{From: 0, To: blockNumber})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	return ReplayState(it, queries...)
+}
+
+// ReplayState reconstructs values of keys selected by queries from all blocks returned by iterator. Iterator must
+// start with genesis block, state is as of the last block returned by iterator.
+func ReplayState(it *BlockIterator, queries ...StateQuery) (*HistoricalState, error) {
+	selected := make(map[string]map[string]bool, len(queries))
+	for _, q := range queries {
+		keys := make(map[string]bool, len(q.Keys))
+		for _, k := range q.Keys {
+			keys[k] = true
+		}
+		selected[q.Namespace] = keys
+	}
+	parser := &blockparser.Parser{HeaderTypes: []common.HeaderType{common.HeaderType_ENDORSER_TRANSACTION}}
+	state := &HistoricalState{Values: make(map[string]map[string]*StateValue)}
+	for {
+		raw, err := it.Next()
+		if err == ErrNoMoreBlocks {
+			return state, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		block, err := parser.ParseBlock(raw)
+		if err != nil {
+			return nil, err
+		}
+		state.BlockNumber = block.Number
+		for txNum, tx := range block.Transactions {
+			if tx.ValidationCode != "VALID" {
+				continue
+			}
+			for _, action := range tx.Actions {
+				for _, rw := range action.ReadWriteSets {
+					keys, ok := selected[rw.Namespace]
+					if !ok {
+						continue
+					}
+					for _, w := range rw.Writes {
+						if len(keys) > 0 && !keys[w.Key] {
+							continue
+						}
+						state.apply(rw.Namespace, w, blockparser.Version{BlockNum: block.Number, TxNum: uint64(txNum)}, tx.TxId)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (s *HistoricalState) apply(namespace string, w *blockparser.KVWrite, version blockparser.Version, txId string) {
+	if w.IsDelete {
+		delete(s.Values[namespace], w.Key)
+		return
+	}
+	if s.Values[namespace] == nil {
+		s.Values[namespace] = make(map[string]*StateValue)
+	}
+	s.Values[namespace][w.Key] = &StateValue{Value: w.Value, Version: version, TxId: txId}
+}
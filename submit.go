@@ -0,0 +1,52 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// SubmitPayloadError is returned by SubmitAndGetJSON when transaction is committed as valid, but chaincode response
+// cannot be unmarshalled. Transaction is committed, so it must not be submitted again.
+type SubmitPayloadError struct {
+	TxId    string
+	Payload []byte
+	Err     error
+}
+
+func (e *SubmitPayloadError) Error() string {
+	return fmt.Sprintf("transaction %s is committed, but response is invalid: %v payload: %q", e.TxId, e.Err, e.Payload)
+}
+
+func (e *SubmitPayloadError) Unwrap() error {
+	return e.Err
+}
+
+// SubmitAndGetJSON execute chainCode, waits until transaction is committed and unmarshal JSON payload returned by
+// chaincode into out. out is not changed when transaction is not committed as valid.
+// Returned error is *EndorsementError when peers reject proposal, *CommitError when transaction is committed as
+// invalid, *TxExpiredError when commit is not received within TTL and *SubmitPayloadError when transaction is valid
+// but payload is not JSON of out type. If out is nil or chaincode returns empty payload nothing is unmarshalled.
+// ctx options of InvokeAsync, like WithMVCCRetry and WithTxTTL, are applied. Result is returned also with error,
+// when transaction was submitted.
+func (c *FabricClient) SubmitAndGetJSON(ctx context.Context, identity Identity, chainCode ChainCode, peers []string,
+	orderer string, eventPeer string, out interface{}) (*InvokeResult, error) {
+	result := c.invokeWithRetry(ctx, identity, chainCode, peers, orderer, eventPeer)
+	if err := result.Err(); err != nil {
+		if result.TxID == "" {
+			return nil, err
+		}
+		return result, err
+	}
+	if out == nil || len(result.Payload) == 0 {
+		return result, nil
+	}
+	if err := json.Unmarshal(result.Payload, out); err != nil {
+		return result, &SubmitPayloadError{TxId: result.TxID, Payload: result.Payload, Err: err}
+	}
+	return result, nil
+}
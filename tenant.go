@@ -0,0 +1,210 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Tenant is customer of gateway that exposes one Fabric network to many customers. Requests of tenant are signed
+// with tenant identity and limited to allowed channels, chaincodes, peers and orderers. Empty lists allow all.
+type Tenant struct {
+	Id       string
+	Identity Identity
+	// Channels tenant can query and invoke
+	Channels []string
+	// Chaincodes tenant can query and invoke, in any allowed channel
+	Chaincodes []string
+	// Peers and Orderers requests of tenant can be send to
+	Peers    []string
+	Orderers []string
+	// RequestsPerSecond limits request rate of tenant, 0 means no limit. Burst is maximum number of requests above
+	// the rate, default is 1.
+	RequestsPerSecond float64
+	Burst             int
+	// MaxConcurrent limits number of requests of tenant executed at the same time, 0 means no limit
+	MaxConcurrent int
+}
+
+// TenantError is returned when request of tenant is rejected
+type TenantError struct {
+	Tenant string
+	Reason string
+	Err    error
+}
+
+func (e *TenantError) Error() string {
+	return fmt.Sprintf("%v: tenant %s: %s", e.Err, e.Tenant, e.Reason)
+}
+
+// Is allows errors.Is to match ErrTenantNotFound, ErrTenantNotAllowed and ErrTenantQuotaExceeded
+func (e *TenantError) Is(target error) bool {
+	return errors.Is(e.Err, target)
+}
+
+// TenantRegistry holds tenants of FabricClient. It is safe for concurrent use, tenants can be added and removed
+// while client is used.
+type TenantRegistry struct {
+	mu      sync.RWMutex
+	tenants map[string]*tenantState
+}
+
+type tenantState struct {
+	Tenant
+	mu       sync.Mutex
+	tokens   float64
+	last     time.Time
+	inFlight int
+}
+
+// NewTenantRegistry creates registry with tenants
+func NewTenantRegistry(tenants ...Tenant) (*TenantRegistry, error) {
+	r := &TenantRegistry{tenants: make(map[string]*tenantState)}
+	for _, t := range tenants {
+		if err := r.Add(t); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Add adds or replaces tenant. Rate limit state of replaced tenant is reset.
+func (r *TenantRegistry) Add(t Tenant) error {
+	if t.Id == "" {
+		return fmt.Errorf("tenant id is empty")
+	}
+	if t.Identity.ReadOnly() {
+		return fmt.Errorf("tenant %s identity has no private key", t.Id)
+	}
+	if t.Burst < 1 {
+		t.Burst = 1
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tenants == nil {
+		r.tenants = make(map[string]*tenantState)
+	}
+	r.tenants[t.Id] = &tenantState{Tenant: t, tokens: float64(t.Burst), last: time.Now()}
+	return nil
+}
+
+// Remove removes tenant, its requests are rejected with ErrTenantNotFound
+func (r *TenantRegistry) Remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tenants, id)
+}
+
+// Get returns tenant by id
+func (r *TenantRegistry) Get(id string) (Tenant, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.tenants[id]
+	if !ok {
+		return Tenant{}, false
+	}
+	return t.Tenant, true
+}
+
+type tenantKey struct{}
+
+// WithTenant attach tenant id to ctx. QueryWithContext, InvokeWithContext and InvokeWithQuorum sign requests with
+// tenant identity instead of the identity passed as argument, and reject requests to channels, chaincodes, peers and
+// orderers tenant is not allowed to use, or that exceed tenant rate limits. Tenants are registered in
+// FabricClient.Tenants.
+func WithTenant(ctx context.Context, tenantId string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantId)
+}
+
+// TenantFromContext returns tenant id attached to ctx
+func TenantFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok
+}
+
+// enforceTenant returns identity of tenant attached to ctx after checking access and quotas. Returned release func
+// must be called when request is finished. Without tenant in ctx identity is returned unchanged.
+func (c *FabricClient) enforceTenant(ctx context.Context, identity Identity, channelId, chaincode string,
+	peers []string, orderer string) (Identity, func(), error) {
+	id, ok := TenantFromContext(ctx)
+	if !ok {
+		return identity, func() {}, nil
+	}
+	var t *tenantState
+	if c.Tenants != nil {
+		c.Tenants.mu.RLock()
+		t = c.Tenants.tenants[id]
+		c.Tenants.mu.RUnlock()
+	}
+	if t == nil {
+		return identity, nil, &TenantError{Tenant: id, Reason: "tenant is not registered", Err: ErrTenantNotFound}
+	}
+	notAllowed := func(kind, name string) error {
+		return &TenantError{Tenant: id, Reason: fmt.Sprintf("%s %s is not allowed", kind, name), Err: ErrTenantNotAllowed}
+	}
+	if !allowed(t.Channels, channelId) {
+		return identity, nil, notAllowed("channel", channelId)
+	}
+	if !allowed(t.Chaincodes, chaincode) {
+		return identity, nil, notAllowed("chaincode", chaincode)
+	}
+	for _, p := range peers {
+		if !allowed(t.Peers, p) {
+			return identity, nil, notAllowed("peer", p)
+		}
+	}
+	if orderer != "" && !allowed(t.Orderers, orderer) {
+		return identity, nil, notAllowed("orderer", orderer)
+	}
+	if err := t.acquire(time.Now()); err != nil {
+		return identity, nil, err
+	}
+	return t.Identity, t.release, nil
+}
+
+// acquire takes token from rate limit bucket and concurrency slot
+func (t *tenantState) acquire(now time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.MaxConcurrent > 0 && t.inFlight >= t.MaxConcurrent {
+		return &TenantError{Tenant: t.Id, Reason: fmt.Sprintf("%d concurrent requests", t.inFlight), Err: ErrTenantQuotaExceeded}
+	}
+	if t.RequestsPerSecond > 0 {
+		t.tokens += now.Sub(t.last).Seconds() * t.RequestsPerSecond
+		if t.tokens > float64(t.Burst) {
+			t.tokens = float64(t.Burst)
+		}
+		t.last = now
+		if t.tokens < 1 {
+			return &TenantError{Tenant: t.Id, Reason: fmt.Sprintf("rate limit of %g requests per second", t.RequestsPerSecond),
+				Err: ErrTenantQuotaExceeded}
+		}
+		t.tokens--
+	}
+	t.inFlight++
+	return nil
+}
+
+func (t *tenantState) release() {
+	t.mu.Lock()
+	t.inFlight--
+	t.mu.Unlock()
+}
+
+func allowed(list []string, name string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, l := range list {
+		if l == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"time"
+)
+
+// defaultDeliverTimeout limits block requests to orderer deliver service when Timeouts.Deliver is not set
+const defaultDeliverTimeout = 5 * time.Second
+
+// Timeouts limits duration of every kind of operation. Zero value means no limit other than context deadline,
+// except Deliver which defaults to 5 seconds.
+type Timeouts struct {
+	// Dial is time to establish connection to peer, event peer or orderer
+	Dial time.Duration `yaml:"dial"`
+	// Endorsement is time to wait for proposal response from single peer
+	Endorsement time.Duration `yaml:"endorsement"`
+	// Broadcast is time to wait until orderer accepts transaction
+	Broadcast time.Duration `yaml:"broadcast"`
+	// CommitWait is time InvokeAsync waits for commit event of transaction
+	CommitWait time.Duration `yaml:"commitWait"`
+	// Deliver is time to wait for blocks requested from orderer deliver service, like channel genesis block
+	Deliver time.Duration `yaml:"deliver"`
+}
+
+type timeoutsKey struct{}
+
+// WithTimeouts overrides timeouts of peers and orderers for requests made with ctx. Only non zero fields override.
+func WithTimeouts(ctx context.Context, timeouts Timeouts) context.Context {
+	return context.WithValue(ctx, timeoutsKey{}, timeouts)
+}
+
+// effectiveTimeouts returns node timeouts overridden by timeouts attached to ctx
+func effectiveTimeouts(ctx context.Context, node Timeouts) Timeouts {
+	if ctx == nil {
+		return node
+	}
+	override, ok := ctx.Value(timeoutsKey{}).(Timeouts)
+	if !ok {
+		return node
+	}
+	if override.Dial > 0 {
+		node.Dial = override.Dial
+	}
+	if override.Endorsement > 0 {
+		node.Endorsement = override.Endorsement
+	}
+	if override.Broadcast > 0 {
+		node.Broadcast = override.Broadcast
+	}
+	if override.CommitWait > 0 {
+		node.CommitWait = override.CommitWait
+	}
+	if override.Deliver > 0 {
+		node.Deliver = override.Deliver
+	}
+	return node
+}
+
+// withTimeout returns ctx limited by d, zero d returns ctx unchanged. Returned cancel func must always be called.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
@@ -0,0 +1,141 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"sync"
+)
+
+// ClientTlsConfig holds client TLS certificate and key for peers and orderers that require client authentication.
+// Certificate and key can be provided as path to file or as PEM encoded content, content takes precedence.
+type ClientTlsConfig struct {
+	Cert     string `yaml:"cert"`
+	CertPath string `yaml:"certPath"`
+	Key      string `yaml:"key"`
+	KeyPath  string `yaml:"keyPath"`
+}
+
+// certificate loads client certificate, nil when config is empty
+func (c ClientTlsConfig) certificate() (*tls.Certificate, error) {
+	if c.Cert == "" && c.CertPath == "" {
+		return nil, nil
+	}
+	certPEM, err := pemContent(c.Cert, c.CertPath)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := pemContent(c.Key, c.KeyPath)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+func pemContent(content, path string) ([]byte, error) {
+	if content != "" {
+		return []byte(content), nil
+	}
+	if path == "" {
+		return nil, errors.New("client TLS certificate and key are both required")
+	}
+	return ioutil.ReadFile(path)
+}
+
+// clientTls is client TLS certificate of FabricClient presented to its peers and orderers, hash of the
+// certificate bound to requests, TLS roots trusted by client from channel configs and certificates presented by nodes.
+// Nodes keep pointer to it, so certificate set on client is used by their new connections.
+type clientTls struct {
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	hash []byte
+	// discovered are TLS roots of organizations by MSP id, see TrustChannelTlsRoots
+	discovered map[string]*orgTlsRoots
+	// observed are the last TLS certificates presented by nodes by node name, see CheckCertExpiry
+	observed map[string]*x509.Certificate
+	// expiry is set by SetCertExpiryHandler, nil is default
+	expiry *certExpiryHolder
+}
+
+func newClientTls(cert *tls.Certificate) *clientTls {
+	t := new(clientTls)
+	t.setCertificate(cert)
+	return t
+}
+
+func (t *clientTls) setCertificate(cert *tls.Certificate) {
+	var hash []byte
+	if cert != nil && len(cert.Certificate) > 0 {
+		hash = ComputeTlsCertHash(cert.Certificate[0])
+	}
+	t.mu.Lock()
+	t.cert = cert
+	t.hash = hash
+	t.mu.Unlock()
+}
+
+// certHash returns hash of certificate, nil for nil t or when there is no certificate
+func (t *clientTls) certHash() []byte {
+	if t == nil {
+		return nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.hash
+}
+
+// certificate is used by TLS handshake when node asks for client certificate
+func (t *clientTls) certificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if t == nil {
+		return &tls.Certificate{}, nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.cert == nil {
+		// empty certificate lets node decide if connection without client authentication is allowed
+		return &tls.Certificate{}, nil
+	}
+	return t.cert, nil
+}
+
+// SetClientTlsCertificate sets certificate presented by standard TLS connections of client to peers and orderers and
+// binds requests to it: SHA-256 hash of certificate is put in TlsCertHash of channel header, as Fabric requires when
+// client authentication is enabled. Nil removes certificate and binding. Certificate is also set from clientTls
+// section of config by NewFabricClientFromConfig and Reload. Connections that are already open keep certificate
+// they were opened with.
+func (c *FabricClient) SetClientTlsCertificate(cert *tls.Certificate) {
+	c.tlsState().setCertificate(cert)
+}
+
+// TlsCertHash returns hash of client TLS certificate put in channel headers, nil when requests are not bound
+func (c *FabricClient) TlsCertHash() []byte {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.clientTls.certHash()
+}
+
+// tlsState returns client TLS certificate of client, it is created for clients that were not created from config
+func (c *FabricClient) tlsState() *clientTls {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clientTls == nil {
+		c.clientTls = newClientTls(nil)
+	}
+	return c.clientTls
+}
+
+// ComputeTlsCertHash returns hash of DER encoded client TLS certificate, as computed by Fabric
+func ComputeTlsCertHash(certDER []byte) []byte {
+	h := sha256.Sum256(certDER)
+	return h[:]
+}
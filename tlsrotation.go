@@ -0,0 +1,179 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// orgTlsRoots are TLS root and intermediate certificates of organization from channel configs. Pool is replaced
+// when certificates are added, so it can be used without lock.
+type orgTlsRoots struct {
+	roots         *x509.CertPool
+	intermediates []*x509.Certificate
+	known         map[[32]byte]bool
+}
+
+// TrustChannelTlsRoots adds TLS root and intermediate certificates of all organizations in channel config to
+// certificates trusted for standard TLS connections of client to peers and orderers, in addition to configured
+// certificates. Node that rotates its TLS certificate to one issued by another CA of its organization is then accepted
+// on reconnect without changing client config. Nodes with MspId in config trust only roots of their organization.
+func (c *FabricClient) TrustChannelTlsRoots(config *ChannelConfig) error {
+	ct := c.tlsState()
+	for _, orgs := range []map[string]*OrgConfig{config.ApplicationOrgs, config.OrdererOrgs} {
+		for _, org := range orgs {
+			if err := ct.addDiscoveredTlsCerts(org.MspId, org.TlsRootCerts, true); err != nil {
+				return fmt.Errorf("organization %s: %v", org.Name, err)
+			}
+			if err := ct.addDiscoveredTlsCerts(org.MspId, org.TlsIntermediateCerts, false); err != nil {
+				return fmt.Errorf("organization %s: %v", org.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (t *clientTls) addDiscoveredTlsCerts(mspId string, certs [][]byte, root bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.discovered == nil {
+		t.discovered = make(map[string]*orgTlsRoots)
+	}
+	org, ok := t.discovered[mspId]
+	if !ok {
+		org = &orgTlsRoots{roots: x509.NewCertPool(), known: make(map[[32]byte]bool)}
+		t.discovered[mspId] = org
+	}
+	for _, data := range certs {
+		if block, _ := pem.Decode(data); block != nil {
+			data = block.Bytes
+		}
+		cert, err := x509.ParseCertificate(data)
+		if err != nil {
+			return err
+		}
+		fp := sha256.Sum256(cert.Raw)
+		if org.known[fp] {
+			continue
+		}
+		org.known[fp] = true
+		if root {
+			roots := org.roots.Clone()
+			roots.AddCert(cert)
+			org.roots = roots
+		} else {
+			org.intermediates = append(org.intermediates, cert)
+		}
+	}
+	return nil
+}
+
+// discoveredTlsRoots returns roots discovered for organization, or for all organizations when mspId is empty
+func (t *clientTls) discoveredTlsRoots(mspId string) []orgTlsRoots {
+	if t == nil {
+		return nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if mspId != "" {
+		if org, ok := t.discovered[mspId]; ok {
+			return []orgTlsRoots{*org}
+		}
+		return nil
+	}
+	orgs := make([]orgTlsRoots, 0, len(t.discovered))
+	for _, org := range t.discovered {
+		orgs = append(orgs, *org)
+	}
+	return orgs
+}
+
+// nodeTls is TLS settings of connections to single node
+type nodeTls struct {
+	// name is name of node in client config
+	name string
+	// client is TLS state of client the node belongs to
+	client *clientTls
+	// serverName is name node certificate is verified against
+	serverName string
+	// mspId is MSP id of node organization, optional
+	mspId string
+}
+
+// nodeTlsVerifier verifies TLS certificate of single node against configured roots, and against roots from
+// channel configs when that fails. It remembers the last certificate of node to log rotations.
+type nodeTlsVerifier struct {
+	roots       *x509.CertPool
+	node        nodeTls
+	mu          sync.Mutex
+	fingerprint string
+}
+
+// newNodeTlsCredentials creates TLS credentials that verify node certificate with nodeTlsVerifier
+func newNodeTlsCredentials(roots *x509.CertPool, node nodeTls) credentials.TransportCredentials {
+	v := &nodeTlsVerifier{roots: roots, node: node}
+	// standard verification is replaced with VerifyConnection, which does the same checks with additional roots
+	return credentials.NewTLS(&tls.Config{InsecureSkipVerify: true, VerifyConnection: v.verify,
+		GetClientCertificate: node.client.certificate})
+}
+
+// verify checks certificate chain and that certificate is issued for configured server name of node, which is
+// matched against IP addresses of certificate when node is dialed by IP
+func (v *nodeTlsVerifier) verify(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("node did not present TLS certificate")
+	}
+	leaf := cs.PeerCertificates[0]
+	opts := x509.VerifyOptions{DNSName: v.node.serverName, Roots: v.roots, Intermediates: x509.NewCertPool()}
+	for _, c := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	trustedBy := "configured roots"
+	_, err := leaf.Verify(opts)
+	if err != nil {
+		if !v.verifyDiscovered(leaf, opts) {
+			return err
+		}
+		trustedBy = "channel config roots"
+	}
+	fp := sha256.Sum256(leaf.Raw)
+	fingerprint := hex.EncodeToString(fp[:])
+	v.mu.Lock()
+	previous := v.fingerprint
+	v.fingerprint = fingerprint
+	v.mu.Unlock()
+	if previous != "" && previous != fingerprint {
+		logger().Info("TLS certificate of node rotated", "node", v.node.name, "subject", leaf.Subject.CommonName,
+			"previous", previous, "current", fingerprint, "notAfter", leaf.NotAfter, "trustedBy", trustedBy)
+	}
+	v.node.client.checkNodeTlsExpiry(v.node.name, leaf)
+	return nil
+}
+
+// verifyDiscovered returns true when leaf is issued by organization of node, or by any organization when node
+// organization is not known, according to roots discovered by client in channel configs
+func (v *nodeTlsVerifier) verifyDiscovered(leaf *x509.Certificate, opts x509.VerifyOptions) bool {
+	presented := opts.Intermediates
+	for _, org := range v.node.client.discoveredTlsRoots(v.node.mspId) {
+		opts.Roots = org.roots
+		opts.Intermediates = presented.Clone()
+		for _, c := range org.intermediates {
+			opts.Intermediates.AddCert(c)
+		}
+		if _, err := leaf.Verify(opts); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+const (
+	TopologyFormatJSON = "json"
+	TopologyFormatDOT  = "dot"
+)
+
+// Topology describes network as it is seen from the client. Peers and orderers come from client config, channels,
+// organizations and chaincodes are discovered from peers.
+type Topology struct {
+	Peers    []*TopologyNode    `json:"peers"`
+	Orderers []*TopologyNode    `json:"orderers"`
+	Channels []*TopologyChannel `json:"channels"`
+	// Errors holds errors from discovery. Discovery continues when some of the peers cannot be queried.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// TopologyNode is peer or orderer from client config
+type TopologyNode struct {
+	Name  string `json:"name"`
+	Uri   string `json:"uri"`
+	MspId string `json:"mspId,omitempty"`
+	// Channels are channels joined from peer. Empty for orderers.
+	Channels []string `json:"channels,omitempty"`
+}
+
+// TopologyChannel is channel discovered from peers
+type TopologyChannel struct {
+	Name string `json:"name"`
+	// Peers are names of configured peers joined to this channel
+	Peers []string `json:"peers"`
+	// Orgs are MSP ids of application organizations from channel config
+	Orgs []string `json:"orgs,omitempty"`
+	// OrdererAddresses are orderer addresses from channel config
+	OrdererAddresses []string             `json:"ordererAddresses,omitempty"`
+	ChainCodes       []*TopologyChainCode `json:"chainCodes,omitempty"`
+}
+
+// TopologyChainCode is chaincode instantiated on channel
+type TopologyChainCode struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Path    string `json:"path,omitempty"`
+}
+
+// DiscoverTopology queries all configured peers for joined channels, and for every channel gets its config and
+// instantiated chaincodes from the first peer joined to it.
+func (c *FabricClient) DiscoverTopology(identity Identity) (*Topology, error) {
+	c.mu.RLock()
+	peers := make([]*Peer, 0, len(c.Peers))
+	for _, p := range c.Peers {
+		peers = append(peers, p)
+	}
+	orderers := make([]*Orderer, 0, len(c.Orderers))
+	for _, o := range c.Orderers {
+		orderers = append(orderers, o)
+	}
+	c.mu.RUnlock()
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Name < peers[j].Name })
+	sort.Slice(orderers, func(i, j int) bool { return orderers[i].Name < orderers[j].Name })
+
+	t := new(Topology)
+	for _, o := range orderers {
+		t.Orderers = append(t.Orderers, &TopologyNode{Name: o.Name, Uri: o.Uri})
+	}
+	channels := make(map[string]*TopologyChannel)
+	for _, p := range peers {
+		node := &TopologyNode{Name: p.Name, Uri: p.Uri, MspId: p.MspId}
+		t.Peers = append(t.Peers, node)
+		r, err := c.QueryChannels(identity, []string{p.Name})
+		if err == nil {
+			err = r[0].Error
+		}
+		if err != nil {
+			t.Errors = append(t.Errors, fmt.Sprintf("peer %s: cannot query channels: %v", p.Name, err))
+			continue
+		}
+		node.Channels = r[0].Channels
+		for _, ch := range r[0].Channels {
+			tc, ok := channels[ch]
+			if !ok {
+				tc = &TopologyChannel{Name: ch}
+				channels[ch] = tc
+				t.Channels = append(t.Channels, tc)
+			}
+			tc.Peers = append(tc.Peers, p.Name)
+		}
+	}
+	sort.Slice(t.Channels, func(i, j int) bool { return t.Channels[i].Name < t.Channels[j].Name })
+
+	for _, ch := range t.Channels {
+		peer := ch.Peers[0]
+		if _, config, err := c.GetConfigBlock(identity, ch.Name, peer); err != nil {
+			t.Errors = append(t.Errors, fmt.Sprintf("channel %s: cannot get config from peer %s: %v", ch.Name, peer, err))
+		} else {
+			for _, org := range config.ApplicationOrgs {
+				ch.Orgs = append(ch.Orgs, org.MspId)
+			}
+			sort.Strings(ch.Orgs)
+			ch.OrdererAddresses = config.OrdererAddresses
+		}
+		r, err := c.QueryInstantiatedChainCodes(identity, ch.Name, []string{peer})
+		if err == nil {
+			err = r[0].Error
+		}
+		if err != nil {
+			t.Errors = append(t.Errors, fmt.Sprintf("channel %s: cannot query chaincodes from peer %s: %v", ch.Name, peer, err))
+			continue
+		}
+		for _, cc := range r[0].ChainCodes {
+			ch.ChainCodes = append(ch.ChainCodes, &TopologyChainCode{Name: cc.Name, Version: cc.Version, Path: cc.Path})
+		}
+	}
+	return t, nil
+}
+
+// ExportTopology discovers network topology and returns it in requested format.
+// Supported formats are TopologyFormatJSON and TopologyFormatDOT (Graphviz).
+func (c *FabricClient) ExportTopology(identity Identity, format string) ([]byte, error) {
+	t, err := c.DiscoverTopology(identity)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case TopologyFormatJSON:
+		return t.ToJSON()
+	case TopologyFormatDOT:
+		return t.ToDOT(), nil
+	default:
+		return nil, ErrUnsupportedTopologyFormat
+	}
+}
+
+// ToJSON encodes topology as JSON
+func (t *Topology) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(t, "", "  ")
+}
+
+// ToDOT encodes topology as Graphviz DOT graph. Peers are grouped in clusters by MSP id, channels are connected to
+// joined peers and to instantiated chaincodes.
+func (t *Topology) ToDOT() []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteString("digraph fabric {\n")
+	buf.WriteString("  rankdir=LR;\n")
+
+	orgs := make(map[string][]*TopologyNode)
+	var mspIds []string
+	for _, p := range t.Peers {
+		if _, ok := orgs[p.MspId]; !ok {
+			mspIds = append(mspIds, p.MspId)
+		}
+		orgs[p.MspId] = append(orgs[p.MspId], p)
+	}
+	sort.Strings(mspIds)
+	for i, mspId := range mspIds {
+		fmt.Fprintf(buf, "  subgraph cluster_org%d {\n    label=%q;\n", i, mspId)
+		for _, p := range orgs[mspId] {
+			fmt.Fprintf(buf, "    %q [shape=box, label=%q];\n", "peer:"+p.Name, p.Name+"\n"+p.Uri)
+		}
+		buf.WriteString("  }\n")
+	}
+	if len(t.Orderers) > 0 {
+		buf.WriteString("  subgraph cluster_orderers {\n    label=\"orderers\";\n")
+		for _, o := range t.Orderers {
+			fmt.Fprintf(buf, "    %q [shape=hexagon, label=%q];\n", "orderer:"+o.Name, o.Name+"\n"+o.Uri)
+		}
+		buf.WriteString("  }\n")
+	}
+	for _, ch := range t.Channels {
+		chNode := "channel:" + ch.Name
+		fmt.Fprintf(buf, "  %q [shape=ellipse, label=%q];\n", chNode, ch.Name)
+		for _, p := range ch.Peers {
+			fmt.Fprintf(buf, "  %q -> %q;\n", "peer:"+p, chNode)
+		}
+		for _, cc := range ch.ChainCodes {
+			ccNode := "chaincode:" + ch.Name + ":" + cc.Name
+			fmt.Fprintf(buf, "  %q [shape=component, label=%q];\n", ccNode, cc.Name+":"+cc.Version)
+			fmt.Fprintf(buf, "  %q -> %q;\n", chNode, ccNode)
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,70 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Tracer creates spans for transaction lifecycle: proposal build, endorsement per peer, broadcast and commit wait.
+// Spans are children of span in context passed to *WithContext methods and InvokeAsync.
+// OpenTelemetry implementation NewOtelTracer is available when gohfc is build with `-tags otel`.
+type Tracer interface {
+	// Start starts span with name and attributes given as key value pairs
+	Start(ctx context.Context, name string, attributes ...interface{}) (context.Context, Span)
+}
+
+// Span is single traced operation
+type Span interface {
+	// SetAttributes adds key value pairs to span
+	SetAttributes(attributes ...interface{})
+	// RecordError marks span as failed
+	RecordError(err error)
+	End()
+}
+
+type nopTracer struct{}
+
+func (nopTracer) Start(ctx context.Context, _ string, _ ...interface{}) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...interface{}) {}
+func (nopSpan) RecordError(error)            {}
+func (nopSpan) End()                         {}
+
+type tracerHolder struct {
+	Tracer
+}
+
+var currentTracer atomic.Value
+
+func init() {
+	currentTracer.Store(tracerHolder{nopTracer{}})
+}
+
+// SetTracer sets tracer used by all clients in process. Nil disables tracing.
+func SetTracer(t Tracer) {
+	if t == nil {
+		t = nopTracer{}
+	}
+	currentTracer.Store(tracerHolder{t})
+}
+
+// startSpan starts span with current tracer
+func startSpan(ctx context.Context, name string, attributes ...interface{}) (context.Context, Span) {
+	return currentTracer.Load().(tracerHolder).Start(ctx, name, attributes...)
+}
+
+// endSpan records err if any and ends span
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
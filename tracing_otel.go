@@ -0,0 +1,76 @@
+//go:build otel
+// +build otel
+
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewOtelTracer adapts OpenTelemetry tracer. Use it with SetTracer:
+//
+//	gohfc.SetTracer(gohfc.NewOtelTracer(otel.Tracer("gohfc")))
+//
+// Available when gohfc is build with `-tags otel`.
+func NewOtelTracer(t trace.Tracer) Tracer {
+	return otelTracer{t}
+}
+
+type otelTracer struct {
+	tracer trace.Tracer
+}
+
+func (t otelTracer) Start(ctx context.Context, name string, attributes ...interface{}) (context.Context, Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(otelAttributes(attributes)...))
+	return ctx, otelSpan{span}
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) SetAttributes(attributes ...interface{}) {
+	s.span.SetAttributes(otelAttributes(attributes)...)
+}
+
+func (s otelSpan) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s otelSpan) End() {
+	s.span.End()
+}
+
+func otelAttributes(kv []interface{}) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		key := fmt.Sprint(kv[i])
+		switch v := kv[i+1].(type) {
+		case string:
+			attrs = append(attrs, attribute.String(key, v))
+		case int:
+			attrs = append(attrs, attribute.Int(key, v))
+		case int32:
+			attrs = append(attrs, attribute.Int64(key, int64(v)))
+		case int64:
+			attrs = append(attrs, attribute.Int64(key, v))
+		case uint64:
+			attrs = append(attrs, attribute.Int64(key, int64(v)))
+		case bool:
+			attrs = append(attrs, attribute.Bool(key, v))
+		default:
+			attrs = append(attrs, attribute.String(key, fmt.Sprint(v)))
+		}
+	}
+	return attrs
+}
@@ -10,12 +10,23 @@ import (
 	"encoding/pem"
 	"crypto/rand"
 	"crypto/sha256"
+	"hash"
 	"encoding/hex"
 	"github.com/hyperledger/fabric/protos/common"
 	"github.com/golang/protobuf/ptypes"
 	"time"
 	"github.com/hyperledger/fabric/protos/peer"
-	"bytes"
+	"github.com/CognitionFoundry/gohfc/blockparser"
+	"context"
+)
+
+const (
+	// chaincodeInvocationSpecSpecTag is wire tag of ChaincodeInvocationSpec.chaincode_spec (field 1, length delimited)
+	chaincodeInvocationSpecSpecTag = 1<<3 | 2
+	// chaincodeSpecInputTag is wire tag of ChaincodeSpec.input (field 3, length delimited)
+	chaincodeSpecInputTag = 3<<3 | 2
+	// chaincodeInputIsInitTag is wire tag of ChaincodeInput.is_init (field 3, varint) from Fabric 2.x
+	chaincodeInputIsInitTag = 3 << 3
 )
 
 // TransactionId represents transaction identifier. TransactionId is the unique transaction number.
@@ -30,6 +41,53 @@ type QueryResponse struct {
 	PeerName string
 	Error    error
 	Response *peer.ProposalResponse
+
+	// Status, Message and Payload are chaincode response from Response. Message carries error detail when
+	// chaincode returns shim.Error.
+	Status  int32
+	Message string
+	Payload []byte
+}
+
+// ChaincodeResponse is chaincode response returned by single peer
+type ChaincodeResponse struct {
+	PeerName string
+	Status   int32
+	Message  string
+	Payload  []byte
+	// Endorser is serialized identity of endorsing peer and Signature is its signature over proposal response
+	// payload and Endorser. Both are empty when peer did not endorse.
+	Endorser  []byte
+	Signature []byte
+	// Response is full proposal response of peer, ProposalResponsePayload carries proposal hash and read/write set
+	Response *peer.ProposalResponse
+}
+
+// newChaincodeResponse returns chaincode response from proposal response of peer
+func newChaincodeResponse(peerName string, resp *peer.ProposalResponse) ChaincodeResponse {
+	r := ChaincodeResponse{PeerName: peerName, Response: resp}
+	if resp != nil && resp.Response != nil {
+		r.Status = resp.Response.Status
+		r.Message = resp.Response.Message
+		r.Payload = resp.Response.Payload
+	}
+	if resp != nil && resp.Endorsement != nil {
+		r.Endorser = resp.Endorsement.Endorser
+		r.Signature = resp.Endorsement.Signature
+	}
+	return r
+}
+
+// EndorserMspId returns MSP id of endorsing peer, empty when peer did not endorse
+func (r ChaincodeResponse) EndorserMspId() string {
+	if len(r.Endorser) == 0 {
+		return ""
+	}
+	id := new(msp.SerializedIdentity)
+	if err := proto.Unmarshal(r.Endorser, id); err != nil {
+		return ""
+	}
+	return id.Mspid
 }
 
 // InvokeResponse represent result from invoke operation. Please note that this is the result of simulation,
@@ -38,19 +96,57 @@ type InvokeResponse struct {
 	Status common.Status
 	// TxID is transaction id. This id can be used to track transactions and their status
 	TxID string
+	// Payload is chaincode response payload returned by endorsing peers
+	Payload []byte
+	// Message is chaincode response message returned by endorsing peers
+	Message string
+	// Responses are chaincode responses of every endorsing peer
+	Responses []ChaincodeResponse
+	// Info is additional information orderer returned with its acknowledgement, usually empty
+	Info string
+	// Committed is set when WithCommitCheck found transaction already on ledger, then it was not send again and
+	// Responses are empty
+	Committed *InvokeResult
 }
 
 // QueryTransactionResponse holds data from `client.QueryTransaction`
-// TODO it is not fully implemented!
 type QueryTransactionResponse struct {
 	PeerName   string
 	Error      error
 	StatusCode int32
+	// ValidationCode is the name of StatusCode like VALID or MVCC_READ_CONFLICT
+	ValidationCode string
+	// Transaction is decoded transaction
+	Transaction *blockparser.Transaction
+}
+
+// ReadWriteSet decodes keys read and written during simulation of this query. Returns nil if there is no response.
+func (q *QueryResponse) ReadWriteSet() ([]*blockparser.NsReadWriteSet, error) {
+	if q.Response == nil {
+		return nil, nil
+	}
+	return ProposalResponseReadWriteSet(q.Response)
+}
+
+// ProposalResponseReadWriteSet decodes keys read and written by chaincode during simulation from endorser response.
+// Hashed read/write sets for private data collections are included.
+func ProposalResponseReadWriteSet(resp *peer.ProposalResponse) ([]*blockparser.NsReadWriteSet, error) {
+	prp := new(peer.ProposalResponsePayload)
+	if err := proto.Unmarshal(resp.Payload, prp); err != nil {
+		return nil, err
+	}
+	action := new(peer.ChaincodeAction)
+	if err := proto.Unmarshal(prp.Extension, action); err != nil {
+		return nil, err
+	}
+	return blockparser.ParseReadWriteSet(action.Results)
 }
 
 type transactionProposal struct {
 	proposal      []byte
 	transactionId string
+	// readOnly marks proposals created for queries. Such proposals must never be send to orderer.
+	readOnly bool
 }
 
 // marshalProtoIdentity creates SerializedIdentity from certificate and MSPid
@@ -87,7 +183,9 @@ func header(signatureHeader, channelHeader []byte) (*common.Header) {
 	return header
 }
 
-func channelHeader(headerType common.HeaderType, tx *TransactionId, channelId string, epoch uint64, extension *peer.ChaincodeHeaderExtension) ([]byte, error) {
+// channelHeader creates channel header bound to client TLS certificate with tlsCertHash
+func channelHeader(headerType common.HeaderType, tx *TransactionId, channelId string, epoch uint64, extension *peer.ChaincodeHeaderExtension,
+	tlsCertHash []byte) ([]byte, error) {
 	ts, err := ptypes.TimestampProto(time.Now())
 	if err != nil {
 		return nil, err
@@ -105,6 +203,7 @@ func channelHeader(headerType common.HeaderType, tx *TransactionId, channelId st
 		Epoch:     epoch,
 	}
 	payloadChannelHeader.TxId = tx.TransactionId
+	payloadChannelHeader.TlsCertHash = tlsCertHash
 	if extension != nil {
 		serExt, err := proto.Marshal(extension)
 		if err != nil {
@@ -123,16 +222,27 @@ func payload(header *common.Header, data []byte) ([]byte, error) {
 	return proto.Marshal(p)
 }
 
-// newTransactionId generate new transaction id from creator and random bytes
-func newTransactionId(creator []byte) (*TransactionId, error) {
-	nonce, err := generateRandomBytes(24)
+// newTransactionId generate new transaction id from creator and nonce of nonce source, using hash of client options
+func newTransactionId(creator []byte, opts txOptions) (*TransactionId, error) {
+	nonce, err := newNonce(opts.nonce)
 	if err != nil {
 		return nil, err
 	}
-	id := generateTxId(nonce, creator)
+	return transactionIdFromNonce(creator, nonce, opts.hash)
+}
+
+// transactionIdFromNonce returns transaction id of creator and nonce, nil h is SHA2-256
+func transactionIdFromNonce(creator []byte, nonce []byte, h func() hash.Hash) (*TransactionId, error) {
+	if h == nil {
+		h = sha256.New
+	}
+	id := generateTxId(nonce, creator, h)
 	return &TransactionId{Creator: creator, Nonce: nonce, TransactionId: id}, nil
 }
 
+// nonceSize is the size of nonce in signature header, same as in Fabric
+const nonceSize = 24
+
 // generateRandomBytes get random bytes from crypto/random
 func generateRandomBytes(len int) ([]byte, error) {
 	b := make([]byte, len)
@@ -143,19 +253,21 @@ func generateRandomBytes(len int) ([]byte, error) {
 	return b, nil
 }
 
-// sha256 is hardcoded in hyperledger
-func generateTxId(nonce, creator []byte) string {
-	f := sha256.New()
+// sha256 is default in hyperledger, networks using other hash families configure it per channel
+func generateTxId(nonce, creator []byte, h func() hash.Hash) string {
+	f := h()
 	f.Write(append(nonce, creator...))
 	return hex.EncodeToString(f.Sum(nil))
 }
 
 func chainCodeInvocationSpec(chainCode ChainCode) ([]byte, error) {
-
+	if chainCode.IsInit {
+		return initInvocationSpec(chainCode)
+	}
 	invocation := &peer.ChaincodeInvocationSpec{
 		ChaincodeSpec: &peer.ChaincodeSpec{
 			Type:        peer.ChaincodeSpec_Type(chainCode.Type),
-			ChaincodeId: &peer.ChaincodeID{Name: chainCode.Name},
+			ChaincodeId: &peer.ChaincodeID{Name: chainCode.Name, Version: chainCode.Version},
 			Input:       &peer.ChaincodeInput{Args: chainCode.toChainCodeArgs()},
 		},
 	}
@@ -166,6 +278,38 @@ func chainCodeInvocationSpec(chainCode ChainCode) ([]byte, error) {
 	return invocationBytes, nil
 }
 
+// initInvocationSpec creates invocation spec with is_init flag set in chaincode input.
+// is_init (field 3 of ChaincodeInput) exists only in Fabric 2.x protos, so it is appended to marshaled input manually
+// and input is added to marshaled spec as raw bytes.
+func initInvocationSpec(chainCode ChainCode) ([]byte, error) {
+	input, err := proto.Marshal(&peer.ChaincodeInput{Args: chainCode.toChainCodeArgs()})
+	if err != nil {
+		return nil, err
+	}
+	input = append(input, chaincodeInputIsInitTag, 1)
+	spec := proto.NewBuffer(nil)
+	if err := spec.Marshal(&peer.ChaincodeSpec{
+		Type:        peer.ChaincodeSpec_Type(chainCode.Type),
+		ChaincodeId: &peer.ChaincodeID{Name: chainCode.Name, Version: chainCode.Version},
+	}); err != nil {
+		return nil, err
+	}
+	if err := spec.EncodeVarint(chaincodeSpecInputTag); err != nil {
+		return nil, err
+	}
+	if err := spec.EncodeRawBytes(input); err != nil {
+		return nil, err
+	}
+	invocation := proto.NewBuffer(nil)
+	if err := invocation.EncodeVarint(chaincodeInvocationSpecSpecTag); err != nil {
+		return nil, err
+	}
+	if err := invocation.EncodeRawBytes(spec.Bytes()); err != nil {
+		return nil, err
+	}
+	return invocation.Bytes(), nil
+}
+
 func proposal(header, payload []byte) ([]byte, error) {
 	prop := new(peer.Proposal)
 	prop.Header = header
@@ -179,6 +323,9 @@ func proposal(header, payload []byte) ([]byte, error) {
 }
 
 func signedProposal(prop []byte, identity Identity, crypt CryptoSuite) (*peer.SignedProposal, error) {
+	if identity.ReadOnly() {
+		return nil, ErrReadOnlyIdentity
+	}
 	sb, err := crypt.Sign(prop, identity.PrivateKey)
 	if err != nil {
 		return nil, err
@@ -186,15 +333,38 @@ func signedProposal(prop []byte, identity Identity, crypt CryptoSuite) (*peer.Si
 	return &peer.SignedProposal{ProposalBytes: prop, Signature: sb}, nil
 }
 
+// proposalTxId returns transaction id from channel header of signed proposal, empty when it cannot be decoded
+func proposalTxId(prop *peer.SignedProposal) string {
+	proposal := new(peer.Proposal)
+	if err := proto.Unmarshal(prop.GetProposalBytes(), proposal); err != nil {
+		return ""
+	}
+	header := new(common.Header)
+	if err := proto.Unmarshal(proposal.Header, header); err != nil {
+		return ""
+	}
+	channelHeader := new(common.ChannelHeader)
+	if err := proto.Unmarshal(header.ChannelHeader, channelHeader); err != nil {
+		return ""
+	}
+	return channelHeader.TxId
+}
+
 // sendToPeers send proposal to all peers in the list for endorsement asynchronously and wait for there response.
 // there is no difference in what order results will e returned and is `p.Endorse()` guarantee that there will be
 // response, so no need of complex synchronisation and wait groups
 func sendToPeers(peers []*Peer, prop *peer.SignedProposal) []*PeerResponse {
+	return sendToPeersWithContext(context.Background(), peers, prop)
+}
+
+// sendToPeersWithContext is same as sendToPeers but requests are canceled when ctx is done
+func sendToPeersWithContext(ctx context.Context, peers []*Peer, prop *peer.SignedProposal) []*PeerResponse {
 	ch := make(chan *PeerResponse)
 	l := len(peers)
 	resp := make([]*PeerResponse, 0, l)
+	txId := proposalTxId(prop)
 	for _, p := range peers {
-		go p.Endorse(ch, prop)
+		go p.endorse(ctx, ch, prop, txId)
 	}
 	for i := 0; i < l; i++ {
 		resp = append(resp, <-ch)
@@ -203,7 +373,13 @@ func sendToPeers(peers []*Peer, prop *peer.SignedProposal) []*PeerResponse {
 	return resp
 }
 
-func createTransactionProposal(identity Identity, cc ChainCode) (*transactionProposal, error) {
+func createTransactionProposal(identity Identity, cc ChainCode, opts txOptions) (*transactionProposal, error) {
+	return createTransactionProposalWithId(identity, cc, nil, opts)
+}
+
+// createTransactionProposalWithId is same as createTransactionProposal, but precomputed transaction id is used
+// when txId is not nil
+func createTransactionProposalWithId(identity Identity, cc ChainCode, txId *TransactionId, opts txOptions) (*transactionProposal, error) {
 	spec, err := chainCodeInvocationSpec(cc)
 	if err != nil {
 		return nil, err
@@ -212,13 +388,17 @@ func createTransactionProposal(identity Identity, cc ChainCode) (*transactionPro
 	if err != nil {
 		return nil, err
 	}
-	txId, err := newTransactionId(creator)
+	if txId == nil {
+		txId, err = newTransactionId(creator, opts)
+	} else {
+		err = checkTxId(txId, creator, opts.hash)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	extension := &peer.ChaincodeHeaderExtension{ChaincodeId: &peer.ChaincodeID{Name: cc.Name}}
-	channelHeader, err := channelHeader(common.HeaderType_ENDORSER_TRANSACTION, txId, cc.ChannelId, 0, extension)
+	extension := &peer.ChaincodeHeaderExtension{ChaincodeId: &peer.ChaincodeID{Name: cc.Name, Version: cc.Version}}
+	channelHeader, err := channelHeader(common.HeaderType_ENDORSER_TRANSACTION, txId, cc.ChannelId, 0, extension, opts.tlsCertHash)
 	if err != nil {
 		return nil, err
 	}
@@ -244,6 +424,16 @@ func createTransactionProposal(identity Identity, cc ChainCode) (*transactionPro
 	return &transactionProposal{proposal: proposal, transactionId: txId.TransactionId}, nil
 }
 
+// createQueryProposal creates proposal for read only operation. Transaction cannot be created from this proposal.
+func createQueryProposal(identity Identity, cc ChainCode, opts txOptions) (*transactionProposal, error) {
+	prop, err := createTransactionProposal(identity, cc, opts)
+	if err != nil {
+		return nil, err
+	}
+	prop.readOnly = true
+	return prop, nil
+}
+
 func decodeChainCodeQueryResponse(data []byte) ([]*peer.ChaincodeInfo, error) {
 	response := new(peer.ChaincodeQueryResponse)
 	err := proto.Unmarshal(data, response)
@@ -253,34 +443,33 @@ func decodeChainCodeQueryResponse(data []byte) ([]*peer.ChaincodeInfo, error) {
 	return response.GetChaincodes(), nil
 }
 
-func createTransaction(proposal []byte, endorsement []*PeerResponse) ([]byte, error) {
+func createTransaction(prop *transactionProposal, endorsement []*PeerResponse) ([]byte, error) {
+	if prop.readOnly {
+		return nil, ErrReadOnlyProposal
+	}
 	var propResp *peer.ProposalResponse
-	var pl []byte
 	mEndorsements := make([]*peer.Endorsement, 0, len(endorsement))
 	for _, e := range endorsement {
 		if e.Err == nil && e.Response.Response.Status == 200 {
 			propResp = e.Response
 			mEndorsements = append(mEndorsements, e.Response.Endorsement)
-			if pl == nil {
-				pl = e.Response.Payload
-			}
 		} else {
 			if e.Err != nil {
-				return nil, e.Err
+				return nil, &EndorsementError{Peer: e.Name, Err: e.Err}
 			}
-			return nil, ErrBadTransactionStatus
-		}
-		if bytes.Compare(pl, e.Response.Payload) != 0 {
-			return nil, ErrEndorsementsDoNotMatch
+			return nil, &EndorsementError{Peer: e.Name, Status: e.Response.Response.Status, Message: e.Response.Response.Message}
 		}
 	}
+	if err := checkEndorsementsMatch(endorsement); err != nil {
+		return nil, err
+	}
 
 	// at least one is OK
 	if len(mEndorsements) < 1 {
 		return nil, ErrNoValidEndorsementFound
 	}
 
-	originalProposal, err := getProposal(proposal)
+	originalProposal, err := getProposal(prop.proposal)
 	if err != nil {
 		return nil, err
 	}
@@ -354,13 +543,16 @@ func getChainCodeProposalPayload(bytes []byte) (*peer.ChaincodeProposalPayload,
 	return cpp, err
 }
 
-// TODO not fully implemented!
-func decodeTransaction(payload []byte) (int32, error) {
+// decodeTransaction decodes ProcessedTransaction returned from QSCC and returns validation code and decoded transaction
+func decodeTransaction(payload []byte) (int32, *blockparser.Transaction, error) {
 	transaction := new(peer.ProcessedTransaction)
 	if err := proto.Unmarshal(payload, transaction); err != nil {
-		return 0, err
+		return 0, nil, err
 	}
-	//DecodeBlockEnvelope(transaction.TransactionEnvelope)
-	return transaction.ValidationCode, nil
-
+	tx, err := blockparser.ParseEnvelope(transaction.TransactionEnvelope)
+	if err != nil {
+		return transaction.ValidationCode, nil, err
+	}
+	tx.ValidationCode = peer.TxValidationCode_name[transaction.ValidationCode]
+	return transaction.ValidationCode, tx, nil
 }
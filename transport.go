@@ -0,0 +1,151 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	// unixScheme is host prefix for unix domain sockets like unix:///var/run/peer.sock
+	unixScheme = "unix://"
+	// inProcessScheme is host prefix for in process listeners registered with RegisterInProcessListener
+	inProcessScheme = "inproc://"
+	// localTarget is gRPC target used with custom dialers. Dialer ignores it, it is only used as default authority,
+	// so TLS over socket can be verified against localhost certificate.
+	localTarget = "passthrough:///localhost"
+
+	defaultKeepaliveTime    = time.Minute
+	defaultKeepaliveTimeout = 20 * time.Second
+	defaultMaxMsgSize       = 100 * 1024 * 1024
+)
+
+var (
+	inProcessMu        sync.RWMutex
+	inProcessListeners = make(map[string]func() (net.Conn, error))
+)
+
+// RegisterInProcessListener registers dial function under name. Peers and orderers with host inproc://name
+// connect using this function instead of network. It allows hermetic tests with in memory gRPC servers,
+// for example with bufconn:
+//
+//	lis := bufconn.Listen(1024 * 1024)
+//	go server.Serve(lis)
+//	gohfc.RegisterInProcessListener("peer0", lis.Dial)
+//
+// Listeners are shared by all clients in process.
+func RegisterInProcessListener(name string, dial func() (net.Conn, error)) {
+	inProcessMu.Lock()
+	defer inProcessMu.Unlock()
+	inProcessListeners[name] = dial
+}
+
+// UnregisterInProcessListener removes listener registered with RegisterInProcessListener
+func UnregisterInProcessListener(name string) {
+	inProcessMu.Lock()
+	defer inProcessMu.Unlock()
+	delete(inProcessListeners, name)
+}
+
+// hostTransport returns gRPC target and dial options for host. Hosts in address:port notation are dialed directly,
+// unix:// and inproc:// hosts get custom dialer. Host itself is kept as node Uri for logs and matching with
+// addresses from channel config. Non empty serverName is used as authority of connection and name TLS certificate
+// of node is verified against.
+func hostTransport(host, serverName string) (string, []grpc.DialOption, error) {
+	target, opts, err := hostDialer(host)
+	if err != nil {
+		return "", nil, err
+	}
+	if serverName != "" {
+		opts = append(opts, grpc.WithAuthority(serverName))
+	}
+	return target, opts, nil
+}
+
+// nodeName returns name of node in logs and certificate checks, host for nodes created without name
+func nodeName(name, host string) string {
+	if name != "" {
+		return name
+	}
+	return host
+}
+
+// tlsServerName returns name TLS certificate of node at host is verified against: serverName when it is set,
+// otherwise host name or IP address of host, localhost for unix:// and inproc:// hosts
+func tlsServerName(host, serverName string) string {
+	if serverName != "" {
+		return serverName
+	}
+	if strings.HasPrefix(host, unixScheme) || strings.HasPrefix(host, inProcessScheme) {
+		return "localhost"
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// hostDialer returns gRPC target and custom dialer for host
+func hostDialer(host string) (string, []grpc.DialOption, error) {
+	switch {
+	case strings.HasPrefix(host, unixScheme):
+		path := strings.TrimPrefix(host, unixScheme)
+		if path == "" {
+			return "", nil, fmt.Errorf("unix socket path is empty in %s", host)
+		}
+		return localTarget, []grpc.DialOption{grpc.WithDialer(func(_ string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout("unix", path, timeout)
+		})}, nil
+	case strings.HasPrefix(host, inProcessScheme):
+		name := strings.TrimPrefix(host, inProcessScheme)
+		return localTarget, []grpc.DialOption{grpc.WithDialer(func(string, time.Duration) (net.Conn, error) {
+			inProcessMu.RLock()
+			dial, ok := inProcessListeners[name]
+			inProcessMu.RUnlock()
+			if !ok {
+				return nil, fmt.Errorf("in process listener %s is not registered", name)
+			}
+			return dial()
+		})}, nil
+	}
+	return host, nil, nil
+}
+
+// dialOptions returns keepalive, blocking dial and message size options of connection
+func (c GrpcConfig) dialOptions() []grpc.DialOption {
+	params := keepalive.ClientParameters{
+		Time:                c.KeepaliveTime,
+		Timeout:             c.KeepaliveTimeout,
+		PermitWithoutStream: true,
+	}
+	if params.Time <= 0 {
+		params.Time = defaultKeepaliveTime
+	}
+	if params.Timeout <= 0 {
+		params.Timeout = defaultKeepaliveTimeout
+	}
+	if c.KeepalivePermitWithoutStream != nil {
+		params.PermitWithoutStream = *c.KeepalivePermitWithoutStream
+	}
+	maxRecv, maxSend := c.MaxRecvMsgSize, c.MaxSendMsgSize
+	if maxRecv <= 0 {
+		maxRecv = defaultMaxMsgSize
+	}
+	if maxSend <= 0 {
+		maxSend = defaultMaxMsgSize
+	}
+	return []grpc.DialOption{
+		grpc.WithKeepaliveParams(params),
+		grpc.WithBlock(),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxRecv), grpc.MaxCallSendMsgSize(maxSend)),
+	}
+}
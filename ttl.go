@@ -0,0 +1,34 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"time"
+)
+
+// TxTTL limits how long InvokeAsync waits for commit of transaction
+type TxTTL struct {
+	// TTL is maximum time from sending transaction to orderer until its commit event is received
+	TTL time.Duration
+	// Resubmits is how many times expired transaction is endorsed again and send with new transaction id.
+	// Expired transaction can still be committed later, so resubmit only transactions that are safe to apply twice
+	// or that are protected by chaincode logic (for example by checking unique business key).
+	Resubmits int
+}
+
+type txTTLKey struct{}
+
+// WithTxTTL sets TTL for transactions submitted with InvokeAsync. When commit event is not received in time,
+// result Error is *TxExpiredError and transaction is optionally resubmitted.
+func WithTxTTL(ctx context.Context, ttl TxTTL) context.Context {
+	return context.WithValue(ctx, txTTLKey{}, ttl)
+}
+
+// txTTLFromContext returns TTL from ctx, zero TTL means no limit
+func txTTLFromContext(ctx context.Context) TxTTL {
+	ttl, _ := ctx.Value(txTTLKey{}).(TxTTL)
+	return ttl
+}
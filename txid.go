@@ -0,0 +1,130 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"bytes"
+	"context"
+	"hash"
+)
+
+// NonceSource returns nonce of new transaction. Nonce must be unique for every transaction of creator.
+type NonceSource func() ([]byte, error)
+
+// SetNonceSource sets source of nonces used for transactions created by client. By default nonces are 24 random
+// bytes from crypto/rand. Nil restores default.
+func (c *FabricClient) SetNonceSource(source NonceSource) {
+	c.mu.Lock()
+	c.nonceSource = source
+	c.mu.Unlock()
+}
+
+// randomNonce is default nonce source
+func randomNonce() ([]byte, error) {
+	return generateRandomBytes(nonceSize)
+}
+
+// newNonce returns nonce from source, nil source is randomNonce
+func newNonce(source NonceSource) ([]byte, error) {
+	if source == nil {
+		source = randomNonce
+	}
+	nonce, err := source()
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) == 0 {
+		return nil, ErrNonceEmpty
+	}
+	return nonce, nil
+}
+
+// txOptions are client settings applied when transactions are created
+type txOptions struct {
+	// hash is hash function of transaction ids, nil is SHA2-256
+	hash func() hash.Hash
+	// tlsCertHash binds request to client TLS certificate of connection it is sent over
+	tlsCertHash []byte
+	// nonce is nonce source of transactions, nil is randomNonce
+	nonce NonceSource
+}
+
+// txOptions returns settings of client for transactions in channel. Requests are bound to client TLS certificate
+// of client, requests sent to single node must be bound to certificate of its connection with sentTo.
+func (c *FabricClient) txOptions(channelId string) txOptions {
+	h, _ := c.channelHash(channelId)
+	c.mu.RLock()
+	nonce := c.nonceSource
+	c.mu.RUnlock()
+	return txOptions{hash: h, tlsCertHash: c.TlsCertHash(), nonce: nonce}
+}
+
+// sentTo binds request to client TLS certificate presented on connection to node
+func (o txOptions) sentTo(node interface{ tlsCertHash() []byte }) txOptions {
+	o.tlsCertHash = node.tlsCertHash()
+	return o
+}
+
+// NewTxID computes transaction id of identity before transaction is created, so it can be stored by caller before
+// submission. When nonce is nil random nonce is used. Id is SHA2-256 of nonce and creator, for channels with other
+// hash or client nonce source use FabricClient.NewTxID. Pass result to WithTxID to invoke with this id.
+func NewTxID(identity Identity, nonce []byte) (*TransactionId, error) {
+	return newTxID(identity, nonce, txOptions{})
+}
+
+// NewTxID is same as package NewTxID, but hash configured for channelId in client and nonce source of client, see
+// SetNonceSource, are used
+func (c *FabricClient) NewTxID(identity Identity, channelId string, nonce []byte) (*TransactionId, error) {
+	return newTxID(identity, nonce, c.txOptions(channelId))
+}
+
+func newTxID(identity Identity, nonce []byte, opts txOptions) (*TransactionId, error) {
+	creator, err := marshalProtoIdentity(identity)
+	if err != nil {
+		return nil, err
+	}
+	if nonce == nil {
+		return newTransactionId(creator, opts)
+	}
+	return transactionIdFromNonce(creator, nonce, opts.hash)
+}
+
+type txIDKey struct{}
+
+// WithTxID makes invokes with ctx use precomputed transaction id. Id must be created by NewTxID or FabricClient.NewTxID
+// for the same identity and channel, otherwise invoke fails with ErrTxIDMismatch. Transaction id can be used only
+// once, so resubmissions made by WithMVCCRetry and WithTxTTL get new ids.
+func WithTxID(ctx context.Context, txId *TransactionId) context.Context {
+	return context.WithValue(ctx, txIDKey{}, txId)
+}
+
+// withoutTxID removes precomputed transaction id from ctx
+func withoutTxID(ctx context.Context) context.Context {
+	if txIdFromContext(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, txIDKey{}, (*TransactionId)(nil))
+}
+
+// txIdFromContext returns precomputed transaction id or nil
+func txIdFromContext(ctx context.Context) *TransactionId {
+	txId, _ := ctx.Value(txIDKey{}).(*TransactionId)
+	return txId
+}
+
+// checkTxId checks that precomputed transaction id belongs to creator and was computed with hash of channel
+func checkTxId(txId *TransactionId, creator []byte, h func() hash.Hash) error {
+	if !bytes.Equal(txId.Creator, creator) || len(txId.Nonce) == 0 {
+		return ErrTxIDMismatch
+	}
+	expected, err := transactionIdFromNonce(creator, txId.Nonce, h)
+	if err != nil {
+		return err
+	}
+	if expected.TransactionId != txId.TransactionId {
+		return ErrTxIDMismatch
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"sync"
+)
+
+// txStatusCacheSize is how many recently committed transactions TxStatusListener remembers. It allows registration
+// made shortly after transaction is committed (for example after Invoke returns) to still receive the status.
+const txStatusCacheSize = 10000
+
+// TxStatusEvent is send when registered transaction is committed
+type TxStatusEvent struct {
+	TxId        string
+	ChannelId   string
+	BlockHeight uint64
+	// ValidationCode is the validation code name like VALID or MVCC_READ_CONFLICT
+	ValidationCode string
+	// Error is set when listening fails before transaction is committed
+	Error error
+}
+
+// Valid returns true when transaction is committed as valid
+func (e TxStatusEvent) Valid() bool {
+	return e.Error == nil && e.ValidationCode == "VALID"
+}
+
+// Err returns Error, or *CommitError when transaction is committed as invalid
+func (e TxStatusEvent) Err() error {
+	if e.Error != nil {
+		return e.Error
+	}
+	if e.ValidationCode != "VALID" {
+		return &CommitError{TxId: e.TxId, ValidationCode: e.ValidationCode}
+	}
+	return nil
+}
+
+// TxStatusListener listens for filtered blocks on single channel and notifies registered transactions when they are
+// committed. One listener can serve any number of registrations.
+type TxStatusListener struct {
+	channelId string
+	mu        sync.Mutex
+	waiting   map[string][]chan TxStatusEvent
+	recent    map[string]TxStatusEvent
+	order     []string
+	err       error
+	done      chan struct{}
+}
+
+// NewTxStatusListener starts listening for filtered blocks from eventPeer on channelId.
+// Listening stops when ctx is canceled.
+func (c *FabricClient) NewTxStatusListener(ctx context.Context, identity Identity, eventPeer, channelId string) (*TxStatusListener, error) {
+	ep, ok := c.getEventPeer(eventPeer)
+	if !ok {
+		return nil, ErrPeerNameNotFound
+	}
+	listener, err := NewEventListener(ctx, c.cryptoSuite(identity, channelId), identity, *ep, channelId, EventTypeFiltered)
+	if err != nil {
+		return nil, err
+	}
+	if err := listener.SeekNewest(); err != nil {
+		return nil, err
+	}
+	l := &TxStatusListener{
+		channelId: channelId,
+		waiting:   make(map[string][]chan TxStatusEvent),
+		recent:    make(map[string]TxStatusEvent),
+		done:      make(chan struct{}),
+	}
+	// buffered so the final error from canceled stream does not block after run returns
+	blocks := make(chan EventBlockResponse, 1)
+	listener.Listen(blocks)
+	go l.run(ctx, blocks)
+	return l, nil
+}
+
+// Register returns channel that receives single event when transaction with txId is committed.
+// If transaction was committed recently, event is send immediately. Registration is removed when event is send,
+// caller that stops waiting before, for example because transaction was rejected or expired, must call Unregister.
+func (l *TxStatusListener) Register(txId string) <-chan TxStatusEvent {
+	ch := make(chan TxStatusEvent, 1)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if ev, ok := l.recent[txId]; ok {
+		ch <- ev
+		return ch
+	}
+	if l.err != nil {
+		ch <- TxStatusEvent{TxId: txId, ChannelId: l.channelId, Error: l.err}
+		return ch
+	}
+	l.waiting[txId] = append(l.waiting[txId], ch)
+	return ch
+}
+
+// Unregister removes registration of ch returned by Register for txId. Channel does not receive event afterwards.
+func (l *TxStatusListener) Unregister(txId string, ch <-chan TxStatusEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	chs := l.waiting[txId]
+	for i, c := range chs {
+		if (<-chan TxStatusEvent)(c) == ch {
+			chs = append(chs[:i:i], chs[i+1:]...)
+			break
+		}
+	}
+	if len(chs) == 0 {
+		delete(l.waiting, txId)
+	} else {
+		l.waiting[txId] = chs
+	}
+}
+
+// Done is closed when listener stops
+func (l *TxStatusListener) Done() <-chan struct{} {
+	return l.done
+}
+
+func (l *TxStatusListener) run(ctx context.Context, blocks <-chan EventBlockResponse) {
+	defer close(l.done)
+	for {
+		select {
+		case <-ctx.Done():
+			l.stop(ctx.Err())
+			return
+		case block := <-blocks:
+			if block.Error != nil {
+				l.stop(block.Error)
+				return
+			}
+			l.mu.Lock()
+			for _, tx := range block.Transactions {
+				ev := TxStatusEvent{TxId: tx.Id, ChannelId: l.channelId, BlockHeight: block.BlockHeight, ValidationCode: tx.Status}
+				for _, ch := range l.waiting[tx.Id] {
+					ch <- ev
+				}
+				delete(l.waiting, tx.Id)
+				l.remember(ev)
+			}
+			l.mu.Unlock()
+		}
+	}
+}
+
+// remember adds event to recent events, removing the oldest one when cache is full. Must be called with lock held.
+func (l *TxStatusListener) remember(ev TxStatusEvent) {
+	if _, ok := l.recent[ev.TxId]; ok {
+		return
+	}
+	if len(l.order) >= txStatusCacheSize {
+		delete(l.recent, l.order[0])
+		l.order = l.order[1:]
+	}
+	l.recent[ev.TxId] = ev
+	l.order = append(l.order, ev.TxId)
+}
+
+// stop notifies all waiting registrations with err
+func (l *TxStatusListener) stop(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.err = err
+	for txId, chs := range l.waiting {
+		for _, ch := range chs {
+			ch <- TxStatusEvent{TxId: txId, ChannelId: l.channelId, Error: err}
+		}
+	}
+	l.waiting = make(map[string][]chan TxStatusEvent)
+}
+
+// RegisterTxStatusEvent returns channel that receives validation code when transaction with txId is committed in
+// channelId. Listener for eventPeer and channel is created on first registration and shared between registrations.
+// If shared listener fails it is recreated on next registration. Shared listeners run until Close is called.
+// Caller that stops waiting before event is received must call UnregisterTxStatusEvent, otherwise registration stays
+// in shared listener.
+func (c *FabricClient) RegisterTxStatusEvent(identity Identity, eventPeer, channelId, txId string) (<-chan TxStatusEvent, error) {
+	l, err := c.txStatusListener(identity, eventPeer, channelId)
+	if err != nil {
+		return nil, err
+	}
+	return l.Register(txId), nil
+}
+
+// UnregisterTxStatusEvent removes registration of ch returned by RegisterTxStatusEvent
+func (c *FabricClient) UnregisterTxStatusEvent(eventPeer, channelId, txId string, ch <-chan TxStatusEvent) {
+	c.txMu.Lock()
+	l, ok := c.txListeners[eventPeer+"/"+channelId]
+	c.txMu.Unlock()
+	if ok {
+		l.Unregister(txId, ch)
+	}
+}
+
+// txStatusListener returns shared listener for eventPeer and channel, creating it if needed
+func (c *FabricClient) txStatusListener(identity Identity, eventPeer, channelId string) (*TxStatusListener, error) {
+	key := eventPeer + "/" + channelId
+	c.txMu.Lock()
+	defer c.txMu.Unlock()
+	if c.closed {
+		return nil, ErrClientClosed
+	}
+	if c.txListenersCtx == nil {
+		c.txListenersCtx, c.txListenersCancel = context.WithCancel(context.Background())
+	}
+	l, ok := c.txListeners[key]
+	if ok {
+		select {
+		case <-l.Done():
+			ok = false
+		default:
+		}
+	}
+	if !ok {
+		var err error
+		l, err = c.NewTxStatusListener(c.txListenersCtx, identity, eventPeer, channelId)
+		if err != nil {
+			return nil, err
+		}
+		if c.txListeners == nil {
+			c.txListeners = make(map[string]*TxStatusListener)
+		}
+		c.txListeners[key] = l
+	}
+	return l, nil
+}
@@ -0,0 +1,264 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/CognitionFoundry/gohfc/gm"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+// EndorserVerificationError is returned when endorsement signature or endorser certificate is not valid
+// according to channel MSP configuration
+type EndorserVerificationError struct {
+	Peer  string
+	MspId string
+	Err   error
+}
+
+func (e *EndorserVerificationError) Error() string {
+	return fmt.Sprintf("%v: peer %s (%s): %v", ErrEndorserVerificationFailed, e.Peer, e.MspId, e.Err)
+}
+
+// Is allows errors.Is(err, ErrEndorserVerificationFailed) to match EndorserVerificationError
+func (e *EndorserVerificationError) Is(target error) bool {
+	return target == ErrEndorserVerificationFailed
+}
+
+type verificationKey struct{}
+
+// WithEndorsementVerification attach channel config to ctx. InvokeWithContext and InvokeWithQuorum verify
+// endorsements against it with VerifyEndorsement before transaction is send to orderer.
+// Channel config can be fetched with GetConfigBlock and reused for many invocations.
+func WithEndorsementVerification(ctx context.Context, config *ChannelConfig) context.Context {
+	return context.WithValue(ctx, verificationKey{}, config)
+}
+
+// verifyEndorsementsFromContext verifies successful endorsements if ctx has channel config attached
+func verifyEndorsementsFromContext(ctx context.Context, endorsements []*PeerResponse) error {
+	config, ok := ctx.Value(verificationKey{}).(*ChannelConfig)
+	if !ok || config == nil {
+		return nil
+	}
+	return VerifyEndorsements(config, endorsements)
+}
+
+// VerifyEndorsements verifies all successful endorsements. Failed responses are skipped.
+func VerifyEndorsements(config *ChannelConfig, endorsements []*PeerResponse) error {
+	for _, e := range endorsements {
+		if e.Err != nil || e.Response == nil {
+			continue
+		}
+		if err := VerifyEndorsement(config, e.Name, e.Response); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifyEndorsement checks that endorser belongs to organization in channel, that its certificate is issued by
+// organization CA, is currently valid and not revoked, and that endorsement signature over response payload is valid.
+func VerifyEndorsement(config *ChannelConfig, peerName string, resp *peer.ProposalResponse) error {
+	if resp.Endorsement == nil {
+		return &EndorserVerificationError{Peer: peerName, Err: fmt.Errorf("response has no endorsement")}
+	}
+	endorser := new(msp.SerializedIdentity)
+	if err := proto.Unmarshal(resp.Endorsement.Endorser, endorser); err != nil {
+		return &EndorserVerificationError{Peer: peerName, Err: err}
+	}
+	fail := func(err error) error {
+		return &EndorserVerificationError{Peer: peerName, MspId: endorser.Mspid, Err: err}
+	}
+	org, ok := config.Org(endorser.Mspid)
+	if !ok {
+		return fail(fmt.Errorf("organization is not member of channel %s", config.ChannelId))
+	}
+	cert, err := parsePemCertificate(endorser.IdBytes)
+	if err != nil {
+		return fail(err)
+	}
+	if err := verifyCertificateChain(org, cert, time.Now()); err != nil {
+		return fail(err)
+	}
+	signed := append(append([]byte{}, resp.Payload...), resp.Endorsement.Endorser...)
+	valid, err := VerifySignatureWithHash(cert, signed, resp.Endorsement.Signature, org.SignatureHashFamily)
+	if err != nil {
+		return fail(err)
+	}
+	if !valid {
+		return fail(fmt.Errorf("invalid signature"))
+	}
+	return nil
+}
+
+// OrdererSignatureError is returned when block is not correctly signed by ordering service organization
+type OrdererSignatureError struct {
+	Block uint64
+	MspId string
+	Err   error
+}
+
+func (e *OrdererSignatureError) Error() string {
+	return fmt.Sprintf("%v: block %d (%s): %v", ErrOrdererSignatureInvalid, e.Block, e.MspId, e.Err)
+}
+
+// Is allows errors.Is(err, ErrOrdererSignatureInvalid) to match OrdererSignatureError
+func (e *OrdererSignatureError) Is(target error) bool {
+	return target == ErrOrdererSignatureInvalid
+}
+
+// VerifyBlockSignatures checks that block has at least one signature in SIGNATURES metadata and that every signature
+// is valid and made by member of orderer organization of channel. Signer certificates are checked at block creation
+// time when it is known.
+func VerifyBlockSignatures(config *ChannelConfig, block *common.Block) error {
+	if block == nil || block.Header == nil {
+		return &OrdererSignatureError{Err: fmt.Errorf("block is empty")}
+	}
+	number := block.Header.Number
+	if block.Metadata == nil || len(block.Metadata.Metadata) <= int(common.BlockMetadataIndex_SIGNATURES) {
+		return &OrdererSignatureError{Block: number, Err: fmt.Errorf("block has no signatures")}
+	}
+	metadata := new(common.Metadata)
+	if err := proto.Unmarshal(block.Metadata.Metadata[common.BlockMetadataIndex_SIGNATURES], metadata); err != nil {
+		return &OrdererSignatureError{Block: number, Err: err}
+	}
+	if len(metadata.Signatures) == 0 {
+		return &OrdererSignatureError{Block: number, Err: fmt.Errorf("block has no signatures")}
+	}
+	headerBytes, err := blockHeaderBytes(block.Header)
+	if err != nil {
+		return &OrdererSignatureError{Block: number, Err: err}
+	}
+	for _, s := range metadata.Signatures {
+		sigHeader := new(common.SignatureHeader)
+		if err := proto.Unmarshal(s.SignatureHeader, sigHeader); err != nil {
+			return &OrdererSignatureError{Block: number, Err: err}
+		}
+		signer := new(msp.SerializedIdentity)
+		if err := proto.Unmarshal(sigHeader.Creator, signer); err != nil {
+			return &OrdererSignatureError{Block: number, Err: err}
+		}
+		fail := func(err error) error {
+			return &OrdererSignatureError{Block: number, MspId: signer.Mspid, Err: err}
+		}
+		var org *OrgConfig
+		for _, o := range config.OrdererOrgs {
+			if o.MspId == signer.Mspid {
+				org = o
+			}
+		}
+		if org == nil {
+			return fail(fmt.Errorf("signer is not member of orderer organization"))
+		}
+		cert, err := parsePemCertificate(signer.IdBytes)
+		if err != nil {
+			return fail(err)
+		}
+		if err := verifyCertificateChain(org, cert, blockTime(block)); err != nil {
+			return fail(err)
+		}
+		signed := append(append(append([]byte{}, metadata.Value...), s.SignatureHeader...), headerBytes...)
+		valid, err := VerifySignatureWithHash(cert, signed, s.Signature, org.SignatureHashFamily)
+		if err != nil {
+			return fail(err)
+		}
+		if !valid {
+			return fail(fmt.Errorf("invalid signature"))
+		}
+	}
+	return nil
+}
+
+// blockTime returns timestamp of the first transaction in block, or current time when it is not available
+func blockTime(block *common.Block) time.Time {
+	if block.Data == nil || len(block.Data.Data) == 0 {
+		return time.Now()
+	}
+	env := new(common.Envelope)
+	payload := new(common.Payload)
+	header := new(common.ChannelHeader)
+	if proto.Unmarshal(block.Data.Data[0], env) != nil || proto.Unmarshal(env.Payload, payload) != nil ||
+		payload.Header == nil || proto.Unmarshal(payload.Header.ChannelHeader, header) != nil || header.Timestamp == nil {
+		return time.Now()
+	}
+	return time.Unix(header.Timestamp.Seconds, int64(header.Timestamp.Nanos))
+}
+
+// verifyCertificateChain checks cert against organization root and intermediate certificates and revocation lists.
+// Certificates signed with SM2 are verified with gm package, Go standard library does not support them.
+func verifyCertificateChain(org *OrgConfig, cert *x509.Certificate, now time.Time) error {
+	var err error
+	if _, ok := cert.PublicKey.(*gm.PublicKey); ok || gm.IsSM2Signed(cert) {
+		err = gm.VerifyChain(cert, pemCertificates(org.RootCerts), pemCertificates(org.IntermediateCerts), now)
+	} else {
+		roots := x509.NewCertPool()
+		for _, c := range org.RootCerts {
+			roots.AppendCertsFromPEM(c)
+		}
+		intermediates := x509.NewCertPool()
+		for _, c := range org.IntermediateCerts {
+			intermediates.AppendCertsFromPEM(c)
+		}
+		_, err = cert.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   now,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+	}
+	if err != nil {
+		return err
+	}
+	for _, raw := range org.RevocationList {
+		crl, err := x509.ParseCRL(raw)
+		if err != nil {
+			return fmt.Errorf("invalid revocation list: %v", err)
+		}
+		for _, revoked := range crl.TBSCertList.RevokedCertificates {
+			if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return fmt.Errorf("certificate %s is revoked", cert.SerialNumber)
+			}
+		}
+	}
+	return nil
+}
+
+// pemCertificates parses all certificates from PEM encoded data, including SM2 certificates. Blocks that are not
+// certificates are skipped.
+func pemCertificates(data [][]byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for _, d := range data {
+		for {
+			var block *pem.Block
+			block, d = pem.Decode(d)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			if cert, err := parseCertificate(block.Bytes); err == nil {
+				certs = append(certs, cert)
+			}
+		}
+	}
+	return certs
+}
+
+func parsePemCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("certificate is not PEM encoded")
+	}
+	return parseCertificate(block.Bytes)
+}
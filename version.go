@@ -0,0 +1,182 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+const modulePath = "github.com/CognitionFoundry/gohfc"
+
+// version is used when module version is not available from build info. It can be set at build time with
+// -ldflags "-X github.com/CognitionFoundry/gohfc.version=..."
+var version = "devel"
+
+// fabricProtosVersion is the Fabric release of vendored protos
+const fabricProtosVersion = "1.2"
+
+// BuildInformation describes gohfc build
+type BuildInformation struct {
+	// Version of gohfc module
+	Version string `json:"version"`
+	// Revision is VCS revision when gohfc is built as main module
+	Revision string `json:"revision,omitempty"`
+	// GoVersion is Go version used to build the binary
+	GoVersion string `json:"goVersion"`
+	// FabricProtos is Fabric release from which protos are vendored
+	FabricProtos string `json:"fabricProtos"`
+}
+
+// Version returns gohfc version
+func Version() string {
+	return BuildInfo().Version
+}
+
+// BuildInfo returns gohfc version and build information
+func BuildInfo() BuildInformation {
+	result := BuildInformation{Version: version, GoVersion: runtime.Version(), FabricProtos: fabricProtosVersion}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return result
+	}
+	if info.Main.Path == modulePath {
+		if info.Main.Version != "" && info.Main.Version != "(devel)" {
+			result.Version = info.Main.Version
+		}
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				result.Revision = s.Value
+			}
+		}
+		return result
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			if dep.Replace != nil && dep.Replace.Version != "" {
+				dep = dep.Replace
+			}
+			if dep.Version != "" {
+				result.Version = dep.Version
+			}
+			break
+		}
+	}
+	return result
+}
+
+// NodeVersion is version reported by operations endpoint of peer or orderer
+type NodeVersion struct {
+	Version   string `json:"Version"`
+	CommitSHA string `json:"CommitSHA"`
+}
+
+// GetNodeVersion reads version from /version of peer or orderer operations endpoint (Fabric 1.4+), for example
+// https://peer0.example.com:9443. If client is nil http.DefaultClient is used.
+func GetNodeVersion(ctx context.Context, operationsUrl string, client *http.Client) (*NodeVersion, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(operationsUrl, "/")+"/version", nil)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("operations endpoint %s returned status %d", operationsUrl, resp.StatusCode)
+	}
+	v := new(NodeVersion)
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// CompatibilityWarning describes known incompatibility between gohfc and Fabric version of node
+type CompatibilityWarning struct {
+	Node          string
+	Orderer       bool
+	FabricVersion string
+	Message       string
+}
+
+func (w CompatibilityWarning) String() string {
+	return fmt.Sprintf("%s (Fabric %s): %s", w.Node, w.FabricVersion, w.Message)
+}
+
+// compatibilityRule matches node versions in [from, to) range, empty to means no upper bound
+type compatibilityRule struct {
+	orderer  bool
+	from, to string
+	message  string
+}
+
+var compatibilityRules = []compatibilityRule{
+	{orderer: false, from: "0", to: "1.1",
+		message: "peer supports only legacy event hub, ListenForFullBlock and ListenForFilteredBlock require deliver service from Fabric 1.1"},
+	{orderer: false, from: "2.0",
+		message: "legacy event hub is removed, chaincode install and instantiate use LSCC which does not work in channels with V2_0 application capability"},
+	{orderer: true, from: "3.0",
+		message: "system channel is removed, CreateUpdateChannel cannot create channels, use channel participation API"},
+}
+
+// CheckCompatibility returns warnings for known incompatible combinations of gohfc and Fabric version of node.
+// Version that cannot be parsed produces no warnings.
+func CheckCompatibility(node string, orderer bool, fabricVersion string) []CompatibilityWarning {
+	v, ok := parseFabricVersion(fabricVersion)
+	if !ok {
+		return nil
+	}
+	var warnings []CompatibilityWarning
+	for _, rule := range compatibilityRules {
+		if rule.orderer != orderer {
+			continue
+		}
+		from, _ := parseFabricVersion(rule.from)
+		if compareVersions(v, from) < 0 {
+			continue
+		}
+		if rule.to != "" {
+			to, _ := parseFabricVersion(rule.to)
+			if compareVersions(v, to) >= 0 {
+				continue
+			}
+		}
+		warnings = append(warnings, CompatibilityWarning{Node: node, Orderer: orderer, FabricVersion: fabricVersion,
+			Message: rule.message})
+	}
+	return warnings
+}
+
+// parseFabricVersion parses versions like 2.2.0, v1.4.12 or 2.5.0-beta to [2 2 0]
+func parseFabricVersion(s string) ([]int, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+ "); i >= 0 {
+		s = s[:i]
+	}
+	if s == "" {
+		return nil, false
+	}
+	parts := strings.Split(s, ".")
+	result := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, false
+		}
+		result[i] = n
+	}
+	return result, true
+}
@@ -0,0 +1,153 @@
+/*
+Copyright: Cognition Foundry. All Rights Reserved.
+License: Apache License Version 2.0
+*/
+package gohfc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WarmUpResult is the result of connecting to single peer or orderer
+type WarmUpResult struct {
+	Name    string
+	Orderer bool
+	// EventPeer is set for event peers checked by HealthCheck
+	EventPeer bool
+	Duration  time.Duration
+	Error     error
+	// FabricVersion is version read from operations endpoint, empty if OperationsUrl is not set or not reachable
+	FabricVersion string
+	// Warnings are known incompatibilities of node Fabric version
+	Warnings []CompatibilityWarning
+	// Health is status from operations endpoint, set by health check when OperationsUrl is set and reachable
+	Health *NodeHealth
+}
+
+// WarmUpReport is readiness report from WarmUp
+type WarmUpReport struct {
+	Results []*WarmUpResult
+}
+
+// Ready returns true if all peers and orderers are connected
+func (r *WarmUpReport) Ready() bool {
+	for _, res := range r.Results {
+		if res.Error != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed returns results for peers and orderers that cannot be connected
+func (r *WarmUpReport) Failed() []*WarmUpResult {
+	var failed []*WarmUpResult
+	for _, res := range r.Results {
+		if res.Error != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// WarmUp connects to all peers and orderers that are not yet connected, so first request does not have to wait
+// for connection to be established. At most parallelism connections are dialed at the same time, if parallelism
+// is less than 1 all nodes are dialed at once. Dialing stops when ctx is canceled.
+// WarmUp must be called before client is used from other goroutines.
+func (c *FabricClient) WarmUp(ctx context.Context, parallelism int) *WarmUpReport {
+	c.mu.RLock()
+	var jobs []func() *WarmUpResult
+	for _, p := range c.Peers {
+		if p.connected() {
+			continue
+		}
+		p := p
+		jobs = append(jobs, func() *WarmUpResult {
+			start := time.Now()
+			_, _, err := p.endorserConnection(ctx, effectiveTimeouts(ctx, p.Timeouts).Dial)
+			result := &WarmUpResult{Name: p.Name, Duration: time.Since(start), Error: err}
+			if err == nil {
+				result.checkVersion(ctx, p.OperationsUrl)
+			}
+			return result
+		})
+	}
+	for _, o := range c.Orderers {
+		if o.connected() {
+			continue
+		}
+		o := o
+		jobs = append(jobs, func() *WarmUpResult {
+			start := time.Now()
+			_, err := o.broadcastClient(ctx, effectiveTimeouts(ctx, o.Timeouts).Dial)
+			result := &WarmUpResult{Name: o.Name, Orderer: true, Duration: time.Since(start), Error: err}
+			if err == nil {
+				result.checkVersion(ctx, o.OperationsUrl)
+			}
+			return result
+		})
+	}
+	c.mu.RUnlock()
+	return c.runWarmUp(jobs, parallelism)
+}
+
+// runWarmUp runs at most parallelism jobs at the same time and stores report
+func (c *FabricClient) runWarmUp(jobs []func() *WarmUpResult, parallelism int) *WarmUpReport {
+	if parallelism < 1 || parallelism > len(jobs) {
+		parallelism = len(jobs)
+	}
+	report := &WarmUpReport{Results: make([]*WarmUpResult, len(jobs))}
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, job func() *WarmUpResult) {
+			defer wg.Done()
+			report.Results[i] = job()
+			<-sem
+		}(i, job)
+	}
+	wg.Wait()
+	c.mu.Lock()
+	c.warmUpReport = report
+	c.mu.Unlock()
+	return report
+}
+
+// WarmUpReport returns report from the last WarmUp or nil if WarmUp was never called
+func (c *FabricClient) WarmUpReport() *WarmUpReport {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.warmUpReport
+}
+
+// checkVersion reads node version from operations endpoint and logs known incompatibilities. Version is optional,
+// so errors are only logged.
+func (r *WarmUpResult) checkVersion(ctx context.Context, operationsUrl string) {
+	if operationsUrl == "" {
+		return
+	}
+	v, err := GetNodeVersion(ctx, operationsUrl, nil)
+	if err != nil {
+		logger().Debug("cannot read node version", "node", r.Name, "error", err)
+		return
+	}
+	r.FabricVersion = v.Version
+	r.Warnings = CheckCompatibility(r.Name, r.Orderer, v.Version)
+	for _, w := range r.Warnings {
+		logger().Warn("incompatible fabric version", "node", w.Node, "version", w.FabricVersion,
+			"sdkVersion", Version(), "reason", w.Message)
+	}
+}
+
+// Warnings returns compatibility warnings of all nodes
+func (r *WarmUpReport) Warnings() []CompatibilityWarning {
+	var warnings []CompatibilityWarning
+	for _, res := range r.Results {
+		warnings = append(warnings, res.Warnings...)
+	}
+	return warnings
+}